@@ -0,0 +1,182 @@
+// Command bqmigrate applies the BigQuery migrations in a directory.
+//
+//	bqmigrate -dir ./migrations -config bq.yaml status
+//	bqmigrate -dir ./migrations up [-target 4] [-dry-run]
+//	bqmigrate -dir ./migrations down [-steps 1 | -target 2] [-dry-run]
+//	bqmigrate -version
+//
+// The BigQuery connection is read from the config file and from environment
+// variables prefixed with BQMIGRATE_, for example BQMIGRATE_DATABASE__DATASET_ID.
+// When an audit section is configured, up and down runs are recorded in the
+// audit trail.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/audittrail"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient/migrations"
+	"github.com/grid-stream-org/go-commons/pkg/cli"
+)
+
+type Config struct {
+	Database *bqclient.Config `koanf:"database" json:"database"`
+	// Table overrides the migrations tracking table.
+	Table string `koanf:"table" json:"table"`
+	// Audit records migration runs when set.
+	Audit *audittrail.Config `koanf:"audit" json:"audit"`
+}
+
+var (
+	dir    string
+	dryRun bool
+	target int
+	steps  int
+)
+
+func main() {
+	cli.Run("bqmigrate", run,
+		cli.WithUsage("[flags] status|up|down"),
+		cli.WithFlags(func(fs *flag.FlagSet) {
+			fs.StringVar(&dir, "dir", "migrations", "directory containing migration files")
+			fs.BoolVar(&dryRun, "dry-run", false, "print migrations without running them")
+			fs.IntVar(&target, "target", 0, "version to migrate up or down to")
+			fs.IntVar(&steps, "steps", 1, "number of migrations to revert with down")
+		}),
+	)
+}
+
+func run(ctx context.Context, cfg *Config) error {
+	env, _ := cli.FromContext(ctx)
+	if len(env.Args) != 1 {
+		return cli.Usagef("expected one command")
+	}
+	if cfg.Database == nil {
+		cfg.Database = &bqclient.Config{}
+	}
+
+	ms, err := migrations.Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	client, err := bqclient.New(ctx, cfg.Database, bqclient.WithLogger(env.Log))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	m, err := migrations.New(client, &migrations.Config{DatasetID: cfg.Database.DatasetID, Table: cfg.Table}, ms, env.Log)
+	if err != nil {
+		return err
+	}
+
+	var opts []migrations.Option
+	if dryRun {
+		opts = append(opts, migrations.WithDryRun())
+	}
+	if target > 0 {
+		opts = append(opts, migrations.WithTarget(target))
+	}
+
+	var audit *audittrail.Writer
+	if cfg.Audit != nil && !dryRun {
+		if audit, err = audittrail.New(cfg.Audit, client, env.Log); err != nil {
+			return err
+		}
+		defer func() {
+			if err := audit.Close(context.WithoutCancel(ctx)); err != nil {
+				env.Log.Error("writing audit trail", "error", err)
+			}
+		}()
+	}
+
+	switch cmd := env.Args[0]; cmd {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+	case "up":
+		applied, err := m.Up(ctx, opts...)
+		printResult("applied", applied, dryRun)
+		recordRun(ctx, audit, cfg.Database.DatasetID, "migration.up", applied, err)
+		return err
+	case "down":
+		reverted, err := m.Down(ctx, steps, opts...)
+		printResult("reverted", reverted, dryRun)
+		recordRun(ctx, audit, cfg.Database.DatasetID, "migration.down", reverted, err)
+		return err
+	default:
+		return cli.Usagef("unknown command %q", cmd)
+	}
+	return nil
+}
+
+func recordRun(ctx context.Context, audit *audittrail.Writer, dataset, action string, ms []migrations.Migration, runErr error) {
+	if audit == nil || (len(ms) == 0 && runErr == nil) {
+		return
+	}
+	versions := make([]int, len(ms))
+	for i, m := range ms {
+		versions[i] = m.Version
+	}
+	e := audittrail.Event{
+		Actor:      actor(),
+		Action:     action,
+		Resource:   "dataset",
+		ResourceID: dataset,
+		Metadata:   map[string]any{"versions": versions},
+	}
+	if runErr != nil {
+		e = e.Failed(runErr)
+	}
+	if err := audit.Record(ctx, e); err != nil {
+		cli.Logger(ctx).Error("recording audit event", "error", err)
+	}
+}
+
+// actor is the local user running the command.
+func actor() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return strings.TrimSpace(os.Getenv("USER"))
+}
+
+func printStatus(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED\t")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.Applied {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Modified {
+			applied += " (modified)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t\n", s.Version, s.Name, applied)
+	}
+	w.Flush()
+}
+
+func printResult(verb string, ms []migrations.Migration, dryRun bool) {
+	if dryRun {
+		verb = "would have " + verb
+	}
+	for _, m := range ms {
+		fmt.Printf("%s %d_%s\n", verb, m.Version, m.Name)
+	}
+	if len(ms) == 0 {
+		fmt.Println("nothing to do")
+	}
+}
@@ -0,0 +1,325 @@
+// Package anonymize pseudonymizes and masks customer-identifying fields
+// before records are logged, exported to GCS or shared with research
+// partners. Fields opt in with an `anonymize` struct tag:
+//
+//	type Project struct {
+//		ID       string `anonymize:"hash"`
+//		Phone    string `anonymize:"mask=4"`
+//		Location string `anonymize:"redact"`
+//	}
+//
+//	a, err := anonymize.New(cfg)
+//	...
+//	safe, err := anonymize.Copy(a, project)
+//	log.Info("project enrolled", "project", a.Value(project))
+//
+// Actions:
+//
+//	hash     replace the value with its keyed pseudonym
+//	mask     replace every character with *
+//	mask=N   replace every character but the last N with *
+//	redact   replace the value with its zero value
+//
+// Pseudonyms are an HMAC-SHA256 of the value, so the same input always maps
+// to the same pseudonym under the same key and exported tables still join on
+// hashed IDs. Without the key they cannot be reversed or recomputed from a
+// guessed value. hash and mask apply to strings, including strings behind
+// pointers and in slices, arrays and map values; redact applies to any type.
+// Untagged structs are walked recursively. Values must not contain cycles.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultLength is the default pseudonym length in hex characters.
+	DefaultLength = 16
+	// MinKeyLength is the minimum key length in bytes.
+	MinKeyLength = 16
+)
+
+const tagName = "anonymize"
+
+type Config struct {
+	// Key is the HMAC secret, usually a secrets reference such as
+	// gcp-sm://project/anonymize-key. Rotating it changes every pseudonym.
+	Key string `koanf:"key" json:"key" envconfig:"key"`
+	// Length is the pseudonym length in hex characters, between 8 and 64.
+	// It defaults to DefaultLength.
+	Length int `koanf:"length" json:"length" envconfig:"length"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("anonymize configuration required")
+	}
+	if len(c.Key) < MinKeyLength {
+		return errors.Errorf("anonymize key must be at least %d bytes", MinKeyLength)
+	}
+	if c.Length != 0 && (c.Length < 8 || c.Length > 2*sha256.Size) {
+		return errors.Errorf("anonymize length must be between 8 and %d", 2*sha256.Size)
+	}
+	return nil
+}
+
+type Anonymizer struct {
+	key    []byte
+	length int
+}
+
+func New(cfg *Config) (*Anonymizer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Anonymizer{key: []byte(cfg.Key), length: cfg.Length}
+	if a.length == 0 {
+		a.length = DefaultLength
+	}
+	return a, nil
+}
+
+// Pseudonym returns the keyed pseudonym of s. The empty string stays empty so
+// missing values remain distinguishable.
+func (a *Anonymizer) Pseudonym(s string) string {
+	if s == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))[:a.length]
+}
+
+// Mask replaces every character of s but the last keep with *.
+func Mask(s string, keep int) string {
+	runes := []rune(s)
+	n := max(len(runes)-max(keep, 0), 0)
+	return strings.Repeat("*", n) + string(runes[n:])
+}
+
+// Copy returns a deep copy of v with its tagged fields anonymized. v itself
+// is not modified. v is usually a struct, a pointer to one or a slice of
+// them.
+func Copy[T any](a *Anonymizer, v T) (T, error) {
+	out, err := a.copy("", reflect.ValueOf(&v).Elem(), rule{})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return out.Interface().(T), nil
+}
+
+// Value returns a slog.LogValuer that logs an anonymized copy of v. If v
+// cannot be anonymized it logs a placeholder instead, never v itself.
+func (a *Anonymizer) Value(v any) slog.LogValuer {
+	return logValue{a: a, v: v}
+}
+
+type logValue struct {
+	a *Anonymizer
+	v any
+}
+
+func (l logValue) LogValue() slog.Value {
+	out, err := Copy(l.a, l.v)
+	if err != nil {
+		return slog.StringValue("!ANONYMIZE ERROR: " + err.Error())
+	}
+	return slog.AnyValue(out)
+}
+
+type action int
+
+const (
+	none action = iota
+	hash
+	mask
+	redact
+)
+
+type rule struct {
+	action action
+	keep   int
+}
+
+type field struct {
+	index int
+	name  string
+	rule  rule
+	err   error
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+func fieldsOf(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+
+	var fs []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := field{index: i, name: sf.Name}
+		f.rule, f.err = parseRule(sf.Tag.Get(tagName))
+		if f.err == nil && (f.rule.action == hash || f.rule.action == mask) && !holdsString(sf.Type) {
+			f.err = errors.Errorf("%s requires a string, got %s", sf.Tag.Get(tagName), sf.Type)
+		}
+		fs = append(fs, f)
+	}
+
+	fieldCache.Store(t, fs)
+	return fs
+}
+
+func parseRule(tag string) (rule, error) {
+	name, arg, hasArg := strings.Cut(strings.TrimSpace(tag), "=")
+	switch {
+	case name == "" || name == "-":
+		return rule{}, nil
+	case name == "hash" && !hasArg:
+		return rule{action: hash}, nil
+	case name == "redact" && !hasArg:
+		return rule{action: redact}, nil
+	case name == "mask" && !hasArg:
+		return rule{action: mask}, nil
+	case name == "mask":
+		keep, err := strconv.Atoi(arg)
+		if err != nil || keep < 0 {
+			return rule{}, errors.Errorf("invalid anonymize tag %q: mask needs a non-negative count", tag)
+		}
+		return rule{action: mask, keep: keep}, nil
+	default:
+		return rule{}, errors.Errorf("invalid anonymize tag %q", tag)
+	}
+}
+
+// holdsString reports whether t is a string or a container of strings.
+func holdsString(t reflect.Type) bool {
+	for {
+		switch t.Kind() {
+		case reflect.String:
+			return true
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return false
+		}
+	}
+}
+
+func (a *Anonymizer) copy(path string, v reflect.Value, r rule) (reflect.Value, error) {
+	if r.action == redact {
+		return reflect.Zero(v.Type()), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if r.action == none {
+			return v, nil
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(a.apply(r, v.String()))
+		return out, nil
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := a.copy(path, v.Elem(), r)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := a.copy(path, v.Elem(), r)
+		if err != nil {
+			return v, err
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(elem)
+		return out, nil
+	case reflect.Struct:
+		// Unexported fields are copied as they are.
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for _, f := range fieldsOf(v.Type()) {
+			fpath := joinPath(path, f.name)
+			if f.err != nil {
+				return v, errors.Wrapf(f.err, "anonymize: %s", fpath)
+			}
+			fv, err := a.copy(fpath, v.Field(f.index), f.rule)
+			if err != nil {
+				return v, err
+			}
+			out.Field(f.index).Set(fv)
+		}
+		return out, nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		return out, a.copyElems(path, v, out, r)
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		return out, a.copyElems(path, v, out, r)
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			ev, err := a.copy(path+"["+fmt.Sprint(iter.Key().Interface())+"]", iter.Value(), r)
+			if err != nil {
+				return v, err
+			}
+			out.SetMapIndex(iter.Key(), ev)
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (a *Anonymizer) copyElems(path string, v, out reflect.Value, r rule) error {
+	for i := range v.Len() {
+		ev, err := a.copy(path+"["+strconv.Itoa(i)+"]", v.Index(i), r)
+		if err != nil {
+			return err
+		}
+		out.Index(i).Set(ev)
+	}
+	return nil
+}
+
+func (a *Anonymizer) apply(r rule, s string) string {
+	if r.action == hash {
+		return a.Pseudonym(s)
+	}
+	return Mask(s, r.keep)
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
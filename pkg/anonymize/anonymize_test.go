@@ -0,0 +1,192 @@
+package anonymize
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/stretchr/testify/suite"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+
+type contact struct {
+	Email string            `anonymize:"hash"`
+	Phone *string           `anonymize:"mask=4"`
+	Notes []string          `anonymize:"redact"`
+	Tags  map[string]string `anonymize:"mask"`
+	Kind  string
+}
+
+type customer struct {
+	ID       string `anonymize:"hash"`
+	Name     string `anonymize:"redact"`
+	Age      int    `anonymize:"redact"`
+	Contacts []contact
+	Primary  *contact
+	Extra    any
+	internal string
+}
+
+type AnonymizeTestSuite struct {
+	suite.Suite
+	a *Anonymizer
+}
+
+func (s *AnonymizeTestSuite) SetupTest() {
+	var err error
+	s.a, err = New(&Config{Key: testKey})
+	s.Require().NoError(err)
+}
+
+func (s *AnonymizeTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Key: testKey}},
+		{name: "custom length", cfg: &Config{Key: testKey, Length: 64}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing key", cfg: &Config{}, expectError: true},
+		{name: "short key", cfg: &Config{Key: "secret"}, expectError: true},
+		{name: "short length", cfg: &Config{Key: testKey, Length: 4}, expectError: true},
+		{name: "long length", cfg: &Config{Key: testKey, Length: 65}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *AnonymizeTestSuite) TestPseudonym() {
+	p := s.a.Pseudonym("proj_123")
+	s.Len(p, DefaultLength)
+	s.Equal(p, s.a.Pseudonym("proj_123"), "deterministic")
+	s.NotEqual(p, s.a.Pseudonym("proj_124"))
+	s.Empty(s.a.Pseudonym(""))
+
+	other, err := New(&Config{Key: strings.Repeat("k", MinKeyLength), Length: 32})
+	s.Require().NoError(err)
+	s.Len(other.Pseudonym("proj_123"), 32)
+	s.NotEqual(p, other.Pseudonym("proj_123")[:DefaultLength], "keyed")
+}
+
+func (s *AnonymizeTestSuite) TestMask() {
+	s.Equal("********1234", Mask("555-867-1234", 4))
+	s.Equal("****", Mask("abcd", 0))
+	s.Equal("ab", Mask("ab", 4))
+	s.Equal("**é", Mask("caé", 1))
+	s.Equal("", Mask("", 2))
+}
+
+func (s *AnonymizeTestSuite) TestCopy() {
+	phone := "555-867-1234"
+	in := customer{
+		ID:   "cust_1",
+		Name: "Ada Lovelace",
+		Age:  36,
+		Contacts: []contact{
+			{Email: "ada@example.com", Phone: &phone, Notes: []string{"gate code 1234"}, Tags: map[string]string{"role": "owner"}, Kind: "home"},
+		},
+		Primary:  &contact{Email: "ada@example.com", Kind: "work"},
+		Extra:    contact{Email: "babbage@example.com"},
+		internal: "kept",
+	}
+
+	out, err := Copy(s.a, in)
+	s.Require().NoError(err)
+
+	s.Equal(s.a.Pseudonym("cust_1"), out.ID)
+	s.Empty(out.Name)
+	s.Zero(out.Age)
+	s.Equal("kept", out.internal)
+
+	c := out.Contacts[0]
+	s.Equal(s.a.Pseudonym("ada@example.com"), c.Email)
+	s.Equal("********1234", *c.Phone)
+	s.Nil(c.Notes)
+	s.Equal(map[string]string{"role": "*****"}, c.Tags)
+	s.Equal("home", c.Kind)
+	s.Equal(c.Email, out.Primary.Email, "same input, same pseudonym")
+	s.Equal(s.a.Pseudonym("babbage@example.com"), out.Extra.(contact).Email)
+
+	// The input is untouched.
+	s.Equal("cust_1", in.ID)
+	s.Equal("555-867-1234", phone)
+	s.Equal("ada@example.com", in.Contacts[0].Email)
+	s.Equal("ada@example.com", in.Primary.Email)
+	s.Equal("owner", in.Contacts[0].Tags["role"])
+
+	ptr, err := Copy(s.a, &in)
+	s.Require().NoError(err)
+	s.NotSame(&in, ptr)
+	s.Equal(out.ID, ptr.ID)
+
+	list, err := Copy(s.a, []customer{in, {}})
+	s.Require().NoError(err)
+	s.Len(list, 2)
+	s.Empty(list[1].ID)
+}
+
+func (s *AnonymizeTestSuite) TestModels() {
+	p := &models.Project{ID: "proj_1", UtilityID: "u1", UserID: "user_1", Location: "12 Main St"}
+	d := models.DERData{DERID: "der_1", ProjectID: "proj_1"}
+
+	safeP, err := Copy(s.a, p)
+	s.Require().NoError(err)
+	safeD, err := Copy(s.a, d)
+	s.Require().NoError(err)
+
+	s.Equal(safeP.ID, safeD.ProjectID, "hashed IDs still join")
+	s.Equal("u1", safeP.UtilityID)
+	s.NotEqual("user_1", safeP.UserID)
+	s.Empty(safeP.Location)
+}
+
+func (s *AnonymizeTestSuite) TestInvalidTags() {
+	_, err := Copy(s.a, struct {
+		N int `anonymize:"hash"`
+	}{})
+	s.ErrorContains(err, "N")
+
+	_, err = Copy(s.a, struct {
+		S string `anonymize:"mask=x"`
+	}{})
+	s.Error(err)
+
+	_, err = Copy(s.a, struct {
+		S string `anonymize:"scramble"`
+	}{})
+	s.Error(err)
+}
+
+func (s *AnonymizeTestSuite) TestValue() {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	log.Info("enrolled", "customer", s.a.Value(customer{ID: "cust_1", Name: "Ada Lovelace"}))
+	s.Contains(buf.String(), s.a.Pseudonym("cust_1"))
+	s.NotContains(buf.String(), "cust_1")
+	s.NotContains(buf.String(), "Ada")
+
+	buf.Reset()
+	log.Info("bad", "v", s.a.Value(struct {
+		Secret int `anonymize:"hash"`
+	}{Secret: 424242}))
+	s.Contains(buf.String(), "ANONYMIZE ERROR")
+	s.NotContains(buf.String(), "424242")
+}
+
+func TestAnonymizeTestSuite(t *testing.T) {
+	suite.Run(t, new(AnonymizeTestSuite))
+}
@@ -0,0 +1,296 @@
+// Package audittrail records who did what, and when, to a dedicated BigQuery
+// table. It is shared by the API services and the admin CLI so every change
+// to projects, DR events and configuration lands in one queryable log.
+//
+// Record is asynchronous: events are batched and streamed in the background,
+// and Close flushes whatever is pending, so it must run on shutdown:
+//
+//	audit, err := audittrail.New(cfg, bq, log)
+//	...
+//	defer audit.Close(context.WithoutCancel(ctx))
+//
+//	audit.Record(ctx, audittrail.Event{
+//	    Action:     "project.update",
+//	    Resource:   "project",
+//	    ResourceID: projectID,
+//	    Metadata:   map[string]any{"fields": []string{"capacity_kw"}},
+//	})
+//
+// The actor and request ID are taken from ctx when the event leaves them
+// empty. Batches that still fail after retries are logged in full, so no
+// event is lost without a trace in the service logs.
+package audittrail
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/batcher"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/httpmiddleware"
+	"github.com/grid-stream-org/go-commons/pkg/id"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultTable         = "audit_log"
+	DefaultMaxItems      = 500
+	DefaultFlushInterval = 5 * time.Second
+	DefaultQueueSize     = 1000
+)
+
+// ErrClosed is returned by Record after Close.
+var ErrClosed = batcher.ErrClosed
+
+type Outcome string
+
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+	Denied  Outcome = "denied"
+)
+
+// Event is one audited action.
+type Event struct {
+	// ID and Time are set by Record when empty.
+	ID   string
+	Time time.Time
+	// Actor is who acted, such as a user ID or service account. It defaults
+	// to the subject of the auth.Claims in ctx.
+	Actor string
+	// Action is a dotted verb such as "project.update" or "migration.up".
+	Action     string
+	Resource   string
+	ResourceID string
+	// Outcome defaults to Success.
+	Outcome Outcome
+	Reason  string
+	// RequestID defaults to the request ID in ctx.
+	RequestID string
+	Metadata  map[string]any
+}
+
+// Failed returns a copy of e with a Failure outcome and err as the reason.
+func (e Event) Failed(err error) Event {
+	e.Outcome = Failure
+	if err != nil {
+		e.Reason = err.Error()
+	}
+	return e
+}
+
+// row is the table schema. Metadata is stored as a JSON string.
+type row struct {
+	ID         string    `bigquery:"id"`
+	Time       time.Time `bigquery:"time"`
+	Service    string    `bigquery:"service"`
+	Actor      string    `bigquery:"actor"`
+	Action     string    `bigquery:"action"`
+	Resource   string    `bigquery:"resource"`
+	ResourceID string    `bigquery:"resource_id"`
+	Outcome    string    `bigquery:"outcome"`
+	Reason     string    `bigquery:"reason"`
+	RequestID  string    `bigquery:"request_id"`
+	Metadata   string    `bigquery:"metadata"`
+}
+
+type Config struct {
+	// Table must be allowed by the client. Defaults to DefaultTable.
+	Table string `koanf:"table" json:"table" envconfig:"table"`
+	// Service identifies the writer, such as "projects-api" or "bqmigrate".
+	Service string `koanf:"service" json:"service" envconfig:"service"`
+	// Batch defaults to DefaultMaxItems, DefaultFlushInterval and
+	// DefaultQueueSize.
+	Batch *batcher.Config `koanf:"batch" json:"batch" envconfig:"batch"`
+	Retry retry.Config    `koanf:"retry" json:"retry" envconfig:"retry"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("audit trail configuration required")
+	}
+	if c.Service == "" {
+		return errors.New("audit trail service required")
+	}
+	if c.Batch != nil {
+		if err := c.Batch.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.Retry.Validate()
+}
+
+type Option func(*Writer)
+
+// WithMetrics counts events by result: written or failed.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(w *Writer) {
+		events, err := m.NewCounterVec("audittrail", "events_total", "Audit events by result.", "result")
+		if err != nil {
+			w.log.Warn("registering audit trail metrics", "error", err)
+			return
+		}
+		w.events = events
+	}
+}
+
+// WithClock replaces time.Now for Event.Time.
+func WithClock(now func() time.Time) Option {
+	return func(w *Writer) {
+		w.now = now
+	}
+}
+
+type Writer struct {
+	cfg       *Config
+	client    bqclient.BQClient
+	table     string
+	tableName string
+	log       *slog.Logger
+	now       func() time.Time
+	events    *prometheus.CounterVec
+	batch     *batcher.Batcher[*row]
+}
+
+func New(cfg *Config, client bqclient.BQClient, log *slog.Logger, opts ...Option) (*Writer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("audit trail bigquery client required")
+	}
+
+	name := cfg.Table
+	if name == "" {
+		name = DefaultTable
+	}
+	table, err := client.TableName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{cfg: cfg, client: client, table: table, tableName: name, log: log, now: time.Now}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	batchCfg := cfg.Batch
+	if batchCfg == nil {
+		batchCfg = &batcher.Config{MaxItems: DefaultMaxItems, FlushInterval: DefaultFlushInterval, QueueSize: DefaultQueueSize}
+	}
+	w.batch, err = batcher.New(batchCfg, w.write, log, batcher.WithErrorHandler(func([]*row, error) {
+		// write has already logged the events.
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Record queues e. It only blocks, until ctx is done, when the queue is full
+// while a batch is being written.
+func (w *Writer) Record(ctx context.Context, e Event) error {
+	r := &row{
+		ID:         e.ID,
+		Time:       e.Time,
+		Service:    w.cfg.Service,
+		Actor:      e.Actor,
+		Action:     e.Action,
+		Resource:   e.Resource,
+		ResourceID: e.ResourceID,
+		Outcome:    string(e.Outcome),
+		Reason:     e.Reason,
+		RequestID:  e.RequestID,
+	}
+	if r.Action == "" {
+		return errors.New("audit event action required")
+	}
+	if r.ID == "" {
+		r.ID = id.NewULID()
+	}
+	if r.Time.IsZero() {
+		r.Time = w.now()
+	}
+	r.Time = r.Time.UTC()
+	if r.Actor == "" {
+		if claims, ok := auth.FromContext(ctx); ok {
+			r.Actor = claims.Subject
+		}
+	}
+	if r.Outcome == "" {
+		r.Outcome = string(Success)
+	}
+	if r.RequestID == "" {
+		r.RequestID = httpmiddleware.RequestIDFromContext(ctx)
+	}
+	if len(e.Metadata) > 0 {
+		b, err := json.Marshal(e.Metadata)
+		if err != nil {
+			return errors.Wrap(err, "encoding audit metadata")
+		}
+		r.Metadata = string(b)
+	}
+	return w.batch.Add(ctx, r)
+}
+
+// Flush writes every event recorded so far.
+func (w *Writer) Flush(ctx context.Context) error {
+	return w.batch.Flush(ctx)
+}
+
+// Close stops accepting events and writes everything pending. It returns the
+// error of that final write; the events are logged if it fails.
+func (w *Writer) Close(ctx context.Context) error {
+	return w.batch.Close(ctx)
+}
+
+func (w *Writer) write(ctx context.Context, rows []*row) error {
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		return w.client.StreamPut(ctx, w.tableName, rows)
+	}, w.cfg.Retry.Options()...)
+	if err != nil {
+		for _, r := range rows {
+			w.log.Error("audit event not written",
+				"id", r.ID, "time", r.Time, "service", r.Service, "actor", r.Actor,
+				"action", r.Action, "resource", r.Resource, "resource_id", r.ResourceID,
+				"outcome", r.Outcome, "reason", r.Reason, "request_id", r.RequestID,
+				"metadata", r.Metadata, "error", err)
+		}
+		w.count("failed", len(rows))
+		return errors.Wrapf(err, "writing %d audit events", len(rows))
+	}
+	w.count("written", len(rows))
+	return nil
+}
+
+func (w *Writer) count(result string, n int) {
+	if w.events != nil {
+		w.events.WithLabelValues(result).Add(float64(n))
+	}
+}
+
+// CreateTable creates the audit table if it does not exist.
+func (w *Writer) CreateTable(ctx context.Context) error {
+	err := bqclient.Exec(ctx, w.client, `CREATE TABLE IF NOT EXISTS `+w.table+` (
+        id STRING NOT NULL,
+        time TIMESTAMP NOT NULL,
+        service STRING NOT NULL,
+        actor STRING,
+        action STRING NOT NULL,
+        resource STRING,
+        resource_id STRING,
+        outcome STRING NOT NULL,
+        reason STRING,
+        request_id STRING,
+        metadata JSON
+    )
+    PARTITION BY DATE(time)
+    CLUSTER BY resource, action`, nil)
+	return errors.Wrap(err, "creating audit table")
+}
@@ -0,0 +1,182 @@
+package audittrail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/batcher"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/httpmiddleware"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeBQ records streamed rows and fails the first failures calls.
+type fakeBQ struct {
+	bqclient.BQClient
+	failures int
+
+	mu     sync.Mutex
+	calls  int
+	tables []string
+	rows   []*row
+}
+
+func (f *fakeBQ) StreamPut(_ context.Context, table string, data any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		return errors.New("bigquery unavailable")
+	}
+	f.tables = append(f.tables, table)
+	f.rows = append(f.rows, data.([]*row)...)
+	return nil
+}
+
+func (f *fakeBQ) TableName(table string) (string, error) {
+	return "ops." + table, nil
+}
+
+func (f *fakeBQ) written() []*row {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*row(nil), f.rows...)
+}
+
+type AuditTrailTestSuite struct {
+	suite.Suite
+	now  time.Time
+	logs *bytes.Buffer
+	log  *slog.Logger
+}
+
+func (s *AuditTrailTestSuite) SetupTest() {
+	s.now = time.Date(2026, 5, 4, 9, 30, 0, 0, time.UTC)
+	s.logs = &bytes.Buffer{}
+	s.log = slog.New(slog.NewJSONHandler(s.logs, nil))
+}
+
+func (s *AuditTrailTestSuite) writer(bq *fakeBQ, cfg *Config, opts ...Option) *Writer {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	cfg.Service = "projects-api"
+	opts = append([]Option{WithClock(func() time.Time { return s.now })}, opts...)
+	w, err := New(cfg, bq, s.log, opts...)
+	s.Require().NoError(err)
+	return w
+}
+
+func (s *AuditTrailTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Service: "api"}},
+		{name: "custom batch", cfg: &Config{Service: "api", Batch: &batcher.Config{MaxItems: 10}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing service", cfg: &Config{}, expectError: true},
+		{name: "bad batch", cfg: &Config{Service: "api", Batch: &batcher.Config{}}, expectError: true},
+		{name: "bad retry", cfg: &Config{Service: "api", Retry: retry.Config{Jitter: 2}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *AuditTrailTestSuite) TestRecordDefaults() {
+	bq := &fakeBQ{}
+	w := s.writer(bq, nil)
+
+	var ctx context.Context
+	httpmiddleware.RequestID()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx = auth.NewContext(ctx, &auth.Claims{Subject: "user-42"})
+
+	s.Require().NoError(w.Record(ctx, Event{
+		Action:     "project.update",
+		Resource:   "project",
+		ResourceID: "proj_1",
+		Metadata:   map[string]any{"fields": []string{"capacity_kw"}},
+	}))
+	s.Require().NoError(w.Record(ctx, Event{Action: "project.delete", Actor: "admin", Time: s.now.Add(time.Hour)}.Failed(errors.New("forbidden"))))
+	s.Error(w.Record(ctx, Event{}), "action is required")
+	s.Require().NoError(w.Close(context.Background()))
+
+	rows := bq.written()
+	s.Require().Len(rows, 2)
+	s.Equal([]string{DefaultTable}, bq.tables, "one batch")
+
+	r := rows[0]
+	s.NotEmpty(r.ID)
+	s.Equal(s.now, r.Time)
+	s.Equal("projects-api", r.Service)
+	s.Equal("user-42", r.Actor)
+	s.Equal(string(Success), r.Outcome)
+	s.NotEmpty(r.RequestID)
+	s.JSONEq(`{"fields":["capacity_kw"]}`, r.Metadata)
+
+	r = rows[1]
+	s.Equal("admin", r.Actor)
+	s.Equal(string(Failure), r.Outcome)
+	s.Equal("forbidden", r.Reason)
+	s.Equal(s.now.Add(time.Hour), r.Time)
+	s.Empty(r.Metadata)
+
+	s.ErrorIs(w.Record(ctx, Event{Action: "late"}), ErrClosed)
+}
+
+func (s *AuditTrailTestSuite) TestRetry() {
+	bq := &fakeBQ{failures: 1}
+	w := s.writer(bq, &Config{Retry: retry.Config{MaxAttempts: 2, InitialInterval: time.Millisecond}})
+	s.Require().NoError(w.Record(context.Background(), Event{Action: "project.create"}))
+	s.Require().NoError(w.Close(context.Background()))
+	s.Len(bq.written(), 1)
+}
+
+func (s *AuditTrailTestSuite) TestFailedWriteIsLogged() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "audittrail"})
+	s.Require().NoError(err)
+	bq := &fakeBQ{failures: 10}
+	w := s.writer(bq, &Config{
+		Batch: &batcher.Config{MaxItems: 2},
+		Retry: retry.Config{MaxAttempts: 1},
+	}, WithMetrics(m))
+
+	ctx := context.Background()
+	s.Require().NoError(w.Record(ctx, Event{Action: "project.create", ResourceID: "proj_1"}))
+	s.Require().NoError(w.Record(ctx, Event{Action: "project.create", ResourceID: "proj_2"}))
+	s.Require().NoError(w.Record(ctx, Event{Action: "project.create", ResourceID: "proj_3"}))
+	s.Error(w.Close(ctx), "the final flush error is returned")
+
+	s.Empty(bq.written())
+	for _, id := range []string{"proj_1", "proj_2", "proj_3"} {
+		s.Contains(s.logs.String(), `"resource_id":"`+id+`"`)
+	}
+	s.Equal(float64(3), testutil.ToFloat64(w.events.WithLabelValues("failed")))
+}
+
+func TestAuditTrailTestSuite(t *testing.T) {
+	suite.Run(t, new(AuditTrailTestSuite))
+}
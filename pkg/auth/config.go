@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"encoding/json"
+	"os"
+
+	firebase "firebase.google.com/go/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+)
+
+const (
+	credsTypeServiceAccount  = "service_account"
+	credsTypeExternalAccount = "external_account"
+)
+
+// Config describes where Google credentials come from. CredsPath may point at
+// a service account key or at an external account (workload identity
+// federation) configuration; when empty, Application Default Credentials are
+// used. ServiceAccountID names the service account whose IAM signBlob
+// permission is used to sign custom tokens when the credentials carry no
+// private key, which is always the case for external accounts.
+type Config struct {
+	CredsPath        string `koanf:"creds_path" json:"creds_path" envconfig:"creds_path"`
+	ProjectID        string `koanf:"project_id" json:"project_id" envconfig:"project_id"`
+	ServiceAccountID string `koanf:"service_account_id" json:"service_account_id" envconfig:"service_account_id"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("auth configuration required")
+	}
+	if c.CredsPath == "" {
+		return nil
+	}
+
+	credsType, err := CredentialsType(c.CredsPath)
+	if err != nil {
+		return err
+	}
+
+	switch credsType {
+	case credsTypeServiceAccount, credsTypeExternalAccount, "authorized_user", "impersonated_service_account":
+		return nil
+	default:
+		return errors.Errorf("unsupported credentials type: %s", credsType)
+	}
+}
+
+// ClientOptions returns the Google API client options for these credentials.
+func (c *Config) ClientOptions() []option.ClientOption {
+	var opts []option.ClientOption
+	if c.CredsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(c.CredsPath))
+	}
+	return opts
+}
+
+// IsExternalAccount reports whether the configured credentials are a workload
+// identity federation configuration rather than a long-lived key.
+func (c *Config) IsExternalAccount() bool {
+	if c.CredsPath == "" {
+		return false
+	}
+	credsType, err := CredentialsType(c.CredsPath)
+	return err == nil && credsType == credsTypeExternalAccount
+}
+
+func (c *Config) firebaseConfig() *firebase.Config {
+	if c.ProjectID == "" && c.ServiceAccountID == "" {
+		return nil
+	}
+	return &firebase.Config{
+		ProjectID:        c.ProjectID,
+		ServiceAccountID: c.ServiceAccountID,
+	}
+}
+
+// CredentialsType returns the "type" field of a Google credentials file, such
+// as "service_account" or "external_account".
+func CredentialsType(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	var f struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return "", errors.Wrapf(err, "parsing credentials file %s", path)
+	}
+	if f.Type == "" {
+		return "", errors.Errorf("credentials file %s has no type", path)
+	}
+	return f.Type, nil
+}
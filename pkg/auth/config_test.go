@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCredsFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "creds.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials file: %v", err)
+	}
+	return path
+}
+
+func TestConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contents    string
+		expectError bool
+	}{
+		{"service account", `{"type": "service_account"}`, false},
+		{"external account", `{"type": "external_account", "audience": "aud"}`, false},
+		{"unsupported type", `{"type": "api_key"}`, true},
+		{"missing type", `{}`, true},
+		{"malformed json", `{`, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{CredsPath: writeCredsFile(t, tc.contents)}
+			err := cfg.Validate()
+			if tc.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tc.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+
+	if err := (&Config{}).Validate(); err != nil {
+		t.Errorf("Expected empty config to use ADC, got %v", err)
+	}
+
+	var nilCfg *Config
+	if err := nilCfg.Validate(); err == nil {
+		t.Error("Expected error for nil config")
+	}
+}
+
+func TestConfigIsExternalAccount(t *testing.T) {
+	external := &Config{CredsPath: writeCredsFile(t, `{"type": "external_account"}`)}
+	if !external.IsExternalAccount() {
+		t.Error("Expected external account credentials to be detected")
+	}
+
+	key := &Config{CredsPath: writeCredsFile(t, `{"type": "service_account"}`)}
+	if key.IsExternalAccount() {
+		t.Error("Did not expect service account key to be an external account")
+	}
+
+	if (&Config{}).IsExternalAccount() {
+		t.Error("Did not expect ADC to be an external account")
+	}
+}
+
+func TestNewTokenManagerWithConfigRequiresServiceAccount(t *testing.T) {
+	cfg := &Config{CredsPath: writeCredsFile(t, `{"type": "external_account"}`)}
+	if _, err := NewTokenManagerWithConfig("svc", cfg); err == nil {
+		t.Error("Expected error when signing with external account credentials and no service account")
+	}
+}
+
+func TestConfigClientOptions(t *testing.T) {
+	if opts := (&Config{}).ClientOptions(); len(opts) != 0 {
+		t.Errorf("Expected no options for ADC, got %d", len(opts))
+	}
+	if opts := (&Config{CredsPath: "creds.json"}).ClientOptions(); len(opts) != 1 {
+		t.Errorf("Expected 1 option, got %d", len(opts))
+	}
+}
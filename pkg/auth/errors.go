@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"strings"
+
+	firebaseAuth "firebase.google.com/go/v4/auth"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+)
+
+// Sentinel errors returned by the verification and refresh paths. Match them
+// with errors.Is to map failures to the correct HTTP or gRPC status.
+var (
+	ErrTokenExpired    = gserrors.New(gserrors.Unauthenticated, "token expired")
+	ErrTokenRevoked    = gserrors.New(gserrors.Unauthenticated, "token revoked")
+	ErrInvalidAudience = gserrors.New(gserrors.Unauthenticated, "invalid token audience")
+	ErrUnauthenticated = gserrors.New(gserrors.Unauthenticated, "unauthenticated")
+)
+
+// authError pairs one of the sentinel errors with the underlying cause so that
+// errors.Is matches the sentinel while the original error stays reachable.
+type authError struct {
+	kind  error
+	cause error
+}
+
+func (e *authError) Error() string {
+	return e.kind.Error() + ": " + e.cause.Error()
+}
+
+func (e *authError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *authError) Unwrap() error {
+	return e.cause
+}
+
+// ErrorCode returns the code of the sentinel the error wraps.
+func (e *authError) ErrorCode() gserrors.Code {
+	return gserrors.CodeOf(e.kind)
+}
+
+func newAuthError(kind, cause error) error {
+	return errors.WithStack(&authError{kind: kind, cause: cause})
+}
+
+// classify maps an error from the Firebase SDK onto the auth error taxonomy.
+// Errors that already carry a sentinel are returned unchanged, as are errors
+// that say nothing about the token, such as a failure to fetch signing
+// certificates, so that callers do not report an outage as a bad token.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, kind := range []error{ErrTokenExpired, ErrTokenRevoked, ErrInvalidAudience, ErrUnauthenticated} {
+		if errors.Is(err, kind) {
+			return err
+		}
+	}
+
+	switch {
+	case firebaseAuth.IsIDTokenExpired(err):
+		return newAuthError(ErrTokenExpired, err)
+	case firebaseAuth.IsIDTokenRevoked(err), firebaseAuth.IsUserDisabled(err):
+		return newAuthError(ErrTokenRevoked, err)
+	case firebaseAuth.IsIDTokenInvalid(err) && isAudienceError(err):
+		return newAuthError(ErrInvalidAudience, err)
+	case firebaseAuth.IsIDTokenInvalid(err), firebaseAuth.IsTenantIDMismatch(err):
+		return newAuthError(ErrUnauthenticated, err)
+	default:
+		return err
+	}
+}
+
+// isAudienceError reports whether an invalid token error was caused by an
+// audience mismatch. The Firebase SDK does not expose a dedicated error code
+// for this case, so the message is inspected instead.
+func isAudienceError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "'aud'") || strings.Contains(msg, "audience")
+}
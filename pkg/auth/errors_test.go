@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+)
+
+func TestClassify(t *testing.T) {
+	if err := classify(nil); err != nil {
+		t.Fatalf("Expected nil error, got %v", err)
+	}
+
+	cause := errors.New("fetching certificates: connection refused")
+	err := classify(cause)
+	if err != cause {
+		t.Errorf("Expected unrelated error to pass through unchanged, got %v", err)
+	}
+	if errors.Is(err, ErrUnauthenticated) {
+		t.Error("Did not expect ErrUnauthenticated to match")
+	}
+	if code := gserrors.CodeOf(err); code != gserrors.Internal {
+		t.Errorf("Expected code %s, got %s", gserrors.Internal, code)
+	}
+}
+
+func TestClassifyPreservesKind(t *testing.T) {
+	testCases := []struct {
+		name string
+		kind error
+	}{
+		{"expired", ErrTokenExpired},
+		{"revoked", ErrTokenRevoked},
+		{"audience", ErrInvalidAudience},
+		{"unauthenticated", ErrUnauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := classify(newAuthError(tc.kind, errors.New("cause")))
+			if !errors.Is(err, tc.kind) {
+				t.Errorf("Expected %v to be preserved, got %v", tc.kind, err)
+			}
+			if !errors.Is(classify(errors.Wrap(err, "refresh")), tc.kind) {
+				t.Errorf("Expected wrapped %v to be preserved", tc.kind)
+			}
+			if code := gserrors.CodeOf(err); code != gserrors.CodeOf(tc.kind) {
+				t.Errorf("Expected code %s, got %s", gserrors.CodeOf(tc.kind), code)
+			}
+		})
+	}
+}
+
+func TestAuthErrorMessage(t *testing.T) {
+	err := newAuthError(ErrTokenRevoked, errors.New("user disabled"))
+	if err.Error() != "token revoked: user disabled" {
+		t.Errorf("Unexpected error message: %s", err.Error())
+	}
+}
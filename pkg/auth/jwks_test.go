@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "grid-stream"
+)
+
+type jwksFixture struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	server *httptest.Server
+}
+
+func newJWKSFixture(t *testing.T) *jwksFixture {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate EC key: %v", err)
+	}
+
+	enc := base64.RawURLEncoding
+	body, err := json.Marshal(map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa-1",
+				"alg": "RS256",
+				"n":   enc.EncodeToString(rsaKey.N.Bytes()),
+				"e":   enc.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec-1",
+				"alg": "ES256",
+				"crv": "P-256",
+				"x":   enc.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   enc.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal JWKS: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	return &jwksFixture{rsaKey: rsaKey, ecKey: ecKey, server: server}
+}
+
+func (f *jwksFixture) sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":     "utility-42",
+		"iss":     testIssuer,
+		"aud":     testAudience,
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"utility": "acme",
+	}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	f := newJWKSFixture(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	v, err := NewJWKSVerifier(ctx, &JWKSConfig{
+		URL:      f.server.URL,
+		Issuer:   testIssuer,
+		Audience: testAudience,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create verifier: %v", err)
+	}
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "someone-else"
+
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	hmacToken.Header["kid"] = "rsa-1"
+	hmacSigned, _ := hmacToken.SignedString([]byte("secret"))
+
+	testCases := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"valid RS256", f.sign(t, jwt.SigningMethodRS256, "rsa-1", f.rsaKey, validClaims()), nil},
+		{"valid ES256", f.sign(t, jwt.SigningMethodES256, "ec-1", f.ecKey, validClaims()), nil},
+		{"expired", f.sign(t, jwt.SigningMethodRS256, "rsa-1", f.rsaKey, expired), ErrTokenExpired},
+		{"wrong audience", f.sign(t, jwt.SigningMethodRS256, "rsa-1", f.rsaKey, wrongAudience), ErrInvalidAudience},
+		{"wrong issuer", f.sign(t, jwt.SigningMethodRS256, "rsa-1", f.rsaKey, wrongIssuer), ErrUnauthenticated},
+		{"disallowed algorithm", hmacSigned, ErrUnauthenticated},
+		{"garbage", "not-a-token", ErrUnauthenticated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := v.Verify(ctx, tc.token)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if claims.Subject != "utility-42" {
+				t.Errorf("Expected subject utility-42, got %s", claims.Subject)
+			}
+			if claims.Audience != testAudience {
+				t.Errorf("Expected audience %s, got %s", testAudience, claims.Audience)
+			}
+			if claims.Extra["utility"] != "acme" {
+				t.Errorf("Expected custom claim to be preserved, got %v", claims.Extra)
+			}
+			if claims.ExpiresAt.Before(time.Now()) {
+				t.Error("Expected expiry in the future")
+			}
+		})
+	}
+}
+
+func TestJWKSConfigValidate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         *JWKSConfig
+		expectError bool
+	}{
+		{"valid", &JWKSConfig{URL: "https://x", Issuer: "i", Audience: "a"}, false},
+		{"nil", nil, true},
+		{"missing url", &JWKSConfig{Issuer: "i", Audience: "a"}, true},
+		{"missing issuer", &JWKSConfig{URL: "https://x", Audience: "a"}, true},
+		{"missing audience", &JWKSConfig{URL: "https://x", Issuer: "i"}, true},
+		{"negative refresh", &JWKSConfig{URL: "https://x", Issuer: "i", Audience: "a", RefreshInterval: -time.Second}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectError != (err != nil) {
+				t.Errorf("Expected error=%v, got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -8,7 +8,7 @@ import (
 
 	firebase "firebase.google.com/go/v4"
 	firebaseAuth "firebase.google.com/go/v4/auth"
-	"google.golang.org/api/option"
+	"github.com/pkg/errors"
 )
 
 // TokenManager handles Firebase custom token generation and caching.
@@ -29,18 +29,25 @@ type tokenManager struct {
 // NewTokenManager creates a TokenManager for service-to-service authentication.
 // The serviceID parameter is used to identify your service in Firebase logs.
 func NewTokenManager(serviceID string, credentialsFile ...string) (TokenManager, error) {
-	var opts []option.ClientOption
-
-	if len(credentialsFile) > 0 && credentialsFile[0] != "" {
-		opts = append(opts, option.WithCredentialsFile(credentialsFile[0]))
+	cfg := &Config{}
+	if len(credentialsFile) > 0 {
+		cfg.CredsPath = credentialsFile[0]
 	}
+	return NewTokenManagerWithConfig(serviceID, cfg)
+}
 
-	app, err := firebase.NewApp(context.Background(), nil, opts...)
-	if err != nil {
+// NewTokenManagerWithConfig creates a TokenManager from an explicit Config.
+// External account credentials cannot sign tokens locally, so they require
+// cfg.ServiceAccountID to be set.
+func NewTokenManagerWithConfig(serviceID string, cfg *Config) (TokenManager, error) {
+	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+	if cfg.IsExternalAccount() && cfg.ServiceAccountID == "" {
+		return nil, errors.New("service account ID required to sign tokens with external account credentials")
+	}
 
-	auth, err := app.Auth(context.Background())
+	auth, err := newFirebaseAuth(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -51,6 +58,19 @@ func NewTokenManager(serviceID string, credentialsFile ...string) (TokenManager,
 	}, nil
 }
 
+func newFirebaseAuth(cfg *Config) (*firebaseAuth.Client, error) {
+	app, err := firebase.NewApp(context.Background(), cfg.firebaseConfig(), cfg.ClientOptions()...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	auth, err := app.Auth(context.Background())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return auth, nil
+}
+
 // GetToken returns a valid Firebase custom token.
 func (tm *tokenManager) GetToken() (string, error) {
 	tm.mu.RLock()
@@ -70,7 +90,7 @@ func (tm *tokenManager) Refresh() (string, error) {
 
 	token, err := tm.auth.CustomToken(context.Background(), tm.serviceID)
 	if err != nil {
-		return "", err
+		return "", classify(err)
 	}
 
 	tm.token = token
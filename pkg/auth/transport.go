@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Transport is an http.RoundTripper that sets a bearer token from Tokens on
+// every request. If the server answers 401, the token is refreshed and the
+// request retried once, provided its body can be replayed.
+type Transport struct {
+	Tokens TokenManager
+	// Base is the underlying transport. http.DefaultTransport is used when nil.
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Tokens.GetToken()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base().RoundTrip(authorize(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	token, err = t.Tokens.Refresh()
+	if err != nil {
+		return resp, nil
+	}
+
+	retry := authorize(req, token)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+
+	resp, err = t.base().RoundTrip(retry)
+	return resp, errors.WithStack(err)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// authorize clones req, as RoundTrippers must not modify the request, and
+// sets its Authorization header.
+func authorize(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
@@ -0,0 +1,98 @@
+// Package avro decodes and encodes Avro binary data, such as the rows returned
+// by BigQuery Storage read sessions, using parsed and cached schemas.
+package avro
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	hamba "github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+)
+
+// Codec decodes and encodes values for a single schema. Structs are mapped
+// to record fields with avro struct tags; BigQuery TIMESTAMP columns decode
+// into time.Time and nullable columns into pointers.
+type Codec struct {
+	schema hamba.Schema
+}
+
+var codecs sync.Map // schema string -> *Codec
+
+// Parse returns the Codec for schema, parsing it only the first time a given
+// schema string is seen. Read sessions for the same table share a schema, so
+// repeated calls are cheap.
+func Parse(schema string) (*Codec, error) {
+	if c, ok := codecs.Load(schema); ok {
+		return c.(*Codec), nil
+	}
+
+	s, err := hamba.Parse(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing avro schema")
+	}
+
+	c, _ := codecs.LoadOrStore(schema, &Codec{schema: s})
+	return c.(*Codec), nil
+}
+
+func MustParse(schema string) *Codec {
+	c, err := Parse(schema)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Codec) Schema() hamba.Schema {
+	return c.schema
+}
+
+// Decode decodes a single record from data into dst.
+func (c *Codec) Decode(data []byte, dst any) error {
+	return errors.WithStack(hamba.Unmarshal(c.schema, data, dst))
+}
+
+func (c *Codec) Encode(v any) ([]byte, error) {
+	data, err := hamba.Marshal(c.schema, v)
+	return data, errors.WithStack(err)
+}
+
+// DecodeRows decodes a block of back-to-back records, the layout of
+// AvroRows.SerializedBinaryRows in a read session response.
+func DecodeRows[T any](c *Codec, data []byte) ([]T, error) {
+	r := hamba.NewReader(nil, 0).Reset(data)
+
+	var rows []T
+	for {
+		// Peek reports io.EOF only once every byte has been consumed.
+		if r.Peek(); errors.Is(r.Error, io.EOF) {
+			return rows, nil
+		}
+
+		var row T
+		r.ReadVal(c.schema, &row)
+		if r.Error != nil {
+			return nil, errors.Wrapf(r.Error, "decoding row %d", len(rows))
+		}
+		rows = append(rows, row)
+	}
+}
+
+// DecodeMaps is DecodeRows for callers without a matching struct.
+func (c *Codec) DecodeMaps(data []byte) ([]map[string]any, error) {
+	return DecodeRows[map[string]any](c, data)
+}
+
+// EncodeRows encodes rows back to back, the inverse of DecodeRows.
+func EncodeRows[T any](c *Codec, rows []T) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := hamba.NewEncoderForSchema(c.schema, &buf)
+	for i, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return nil, errors.Wrapf(err, "encoding row %d", i)
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package avro
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// derDataSchema mirrors the schema BigQuery returns for a read session on
+// the der_data table.
+const derDataSchema = `{
+	"type": "record",
+	"name": "__root__",
+	"fields": [
+		{"name": "der_id", "type": ["null", "string"]},
+		{"name": "project_id", "type": ["null", "string"]},
+		{"name": "current_output", "type": ["null", "double"]},
+		{"name": "timestamp", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]}
+	]
+}`
+
+type derData struct {
+	DERID         *string    `avro:"der_id"`
+	ProjectID     *string    `avro:"project_id"`
+	CurrentOutput *float64   `avro:"current_output"`
+	Timestamp     *time.Time `avro:"timestamp"`
+}
+
+type AvroTestSuite struct {
+	suite.Suite
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func (s *AvroTestSuite) rows() []derData {
+	ts := time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC)
+	return []derData{
+		{DERID: ptr("der-1"), ProjectID: ptr("p1"), CurrentOutput: ptr(4.2), Timestamp: &ts},
+		{DERID: ptr("der-2"), ProjectID: ptr("p1")},
+	}
+}
+
+func (s *AvroTestSuite) TestRoundTripRows() {
+	c, err := Parse(derDataSchema)
+	s.Require().NoError(err)
+
+	data, err := EncodeRows(c, s.rows())
+	s.Require().NoError(err)
+
+	got, err := DecodeRows[derData](c, data)
+	s.Require().NoError(err)
+	s.Require().Len(got, 2)
+	s.Equal("der-1", *got[0].DERID)
+	s.Equal(4.2, *got[0].CurrentOutput)
+	s.True(s.rows()[0].Timestamp.Equal(*got[0].Timestamp))
+	s.Nil(got[1].CurrentOutput)
+	s.Nil(got[1].Timestamp)
+}
+
+func (s *AvroTestSuite) TestDecodeMaps() {
+	c := MustParse(derDataSchema)
+	data, err := EncodeRows(c, s.rows())
+	s.Require().NoError(err)
+
+	maps, err := c.DecodeMaps(data)
+	s.Require().NoError(err)
+	s.Len(maps, 2)
+	s.Contains(maps[0], "der_id")
+	s.Nil(maps[1]["current_output"])
+}
+
+func (s *AvroTestSuite) TestSingleRecord() {
+	c := MustParse(derDataSchema)
+	data, err := c.Encode(s.rows()[0])
+	s.Require().NoError(err)
+
+	var got derData
+	s.NoError(c.Decode(data, &got))
+	s.Equal("p1", *got.ProjectID)
+}
+
+func (s *AvroTestSuite) TestTruncatedRows() {
+	c := MustParse(derDataSchema)
+	data, err := EncodeRows(c, s.rows())
+	s.Require().NoError(err)
+
+	_, err = DecodeRows[derData](c, data[:len(data)-3])
+	s.Error(err)
+}
+
+func (s *AvroTestSuite) TestFileRoundTrip() {
+	c := MustParse(derDataSchema)
+
+	var buf bytes.Buffer
+	s.Require().NoError(WriteFile(&buf, c, s.rows()))
+
+	got, err := ReadFile[derData](&buf)
+	s.Require().NoError(err)
+	s.Require().Len(got, 2)
+	s.Equal("der-2", *got[1].DERID)
+
+	_, err = ReadFile[derData](bytes.NewReader([]byte("not avro")))
+	s.Error(err)
+}
+
+func (s *AvroTestSuite) TestParseCaches() {
+	a, err := Parse(derDataSchema)
+	s.Require().NoError(err)
+	b, err := Parse(derDataSchema)
+	s.Require().NoError(err)
+	s.Same(a, b)
+
+	_, err = Parse(`{"type": "record"}`)
+	s.Error(err)
+}
+
+func TestAvroSuite(t *testing.T) {
+	suite.Run(t, new(AvroTestSuite))
+}
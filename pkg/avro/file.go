@@ -0,0 +1,42 @@
+package avro
+
+import (
+	"io"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/pkg/errors"
+)
+
+// WriteFile writes rows to w as a deflate-compressed Avro object container
+// file, the format BigQuery loads and exports natively.
+func WriteFile[T any](w io.Writer, c *Codec, rows []T) error {
+	enc, err := ocf.NewEncoderWithSchema(c.schema, w, ocf.WithCodec(ocf.Deflate))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for i, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return errors.Wrapf(err, "encoding row %d", i)
+		}
+	}
+	return errors.WithStack(enc.Close())
+}
+
+// ReadFile reads every row of an object container file using the schema
+// embedded in its header.
+func ReadFile[T any](r io.Reader) ([]T, error) {
+	dec, err := ocf.NewDecoder(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var rows []T
+	for dec.HasNext() {
+		var row T
+		if err := dec.Decode(&row); err != nil {
+			return nil, errors.Wrapf(err, "decoding row %d", len(rows))
+		}
+		rows = append(rows, row)
+	}
+	return rows, errors.WithStack(dec.Error())
+}
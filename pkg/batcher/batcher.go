@@ -0,0 +1,254 @@
+// Package batcher groups items into batches that are flushed by count, byte
+// size or age.
+package batcher
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrClosed = errors.New("batcher closed")
+
+// FlushFunc receives each batch. The slice is not reused after it returns.
+type FlushFunc[T any] func(ctx context.Context, batch []T) error
+
+type Config struct {
+	// MaxItems flushes once a batch holds this many items.
+	MaxItems int `koanf:"max_items" json:"max_items" envconfig:"max_items"`
+	// MaxBytes flushes once the summed item sizes reach this many bytes. It
+	// requires WithSizeFunc.
+	MaxBytes int `koanf:"max_bytes" json:"max_bytes" envconfig:"max_bytes"`
+	// FlushInterval flushes a non-empty batch this long after its first item.
+	FlushInterval time.Duration `koanf:"flush_interval" json:"flush_interval" envconfig:"flush_interval"`
+	// QueueSize is how many items Add can buffer while a flush is running
+	// before it blocks.
+	QueueSize int `koanf:"queue_size" json:"queue_size" envconfig:"queue_size"`
+}
+
+type Option[T any] func(*Batcher[T])
+
+type flushRequest struct {
+	reply chan error
+	final bool
+}
+
+type Batcher[T any] struct {
+	cfg     *Config
+	flush   FlushFunc[T]
+	log     *slog.Logger
+	sizeOf  func(T) int
+	onError func(batch []T, err error)
+
+	items   chan T
+	flushes chan flushRequest
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+
+	batch []T
+	bytes int
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("batcher configuration required")
+	}
+	if c.MaxItems < 0 || c.MaxBytes < 0 || c.FlushInterval < 0 || c.QueueSize < 0 {
+		return errors.New("batcher limits must not be negative")
+	}
+	if c.MaxItems == 0 && c.MaxBytes == 0 && c.FlushInterval == 0 {
+		return errors.New("batcher requires max items, max bytes or flush interval")
+	}
+	return nil
+}
+
+// WithSizeFunc reports the size in bytes of an item for Config.MaxBytes.
+func WithSizeFunc[T any](fn func(T) int) Option[T] {
+	return func(b *Batcher[T]) {
+		b.sizeOf = fn
+	}
+}
+
+// WithErrorHandler is called when a flush triggered by a limit or the
+// interval fails. Failed batches are logged and dropped when no handler is
+// set. Errors from Flush and Close are returned to the caller instead.
+func WithErrorHandler[T any](fn func(batch []T, err error)) Option[T] {
+	return func(b *Batcher[T]) {
+		b.onError = fn
+	}
+}
+
+func New[T any](cfg *Config, flush FlushFunc[T], log *slog.Logger, opts ...Option[T]) (*Batcher[T], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	b := &Batcher[T]{
+		cfg:     cfg,
+		flush:   flush,
+		log:     log,
+		items:   make(chan T, cfg.QueueSize),
+		flushes: make(chan flushRequest),
+		done:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if cfg.MaxBytes > 0 && b.sizeOf == nil {
+		return nil, errors.New("batcher max bytes requires a size func")
+	}
+
+	go b.run()
+	return b, nil
+}
+
+// Add queues item. Flushes run on a single goroutine, so while one is in
+// progress and the queue is full Add blocks until ctx is done, applying
+// backpressure to producers.
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrClosed
+	}
+
+	select {
+	case b.items <- item:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// Flush flushes all items added before the call and returns the flush error.
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	return b.request(ctx, false)
+}
+
+// Close stops accepting items, flushes everything queued, and returns the
+// error of that final flush.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	return b.request(ctx, true)
+}
+
+func (b *Batcher[T]) request(ctx context.Context, final bool) error {
+	req := flushRequest{reply: make(chan error, 1), final: final}
+	select {
+	case b.flushes <- req:
+	case <-b.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+
+	select {
+	case err := <-req.reply:
+		return err
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func (b *Batcher[T]) run() {
+	defer close(b.done)
+
+	var (
+		timer  *time.Timer
+		timerC <-chan time.Time
+	)
+	resetTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, timerC = nil, nil
+	}
+
+	for {
+		select {
+		case item := <-b.items:
+			b.append(item)
+			if len(b.batch) == 1 && b.cfg.FlushInterval > 0 {
+				timer = time.NewTimer(b.cfg.FlushInterval)
+				timerC = timer.C
+			}
+			if b.full() {
+				resetTimer()
+				b.flushFull()
+			}
+		case <-timerC:
+			timer, timerC = nil, nil
+			b.flushFull()
+		case req := <-b.flushes:
+			resetTimer()
+			b.drainQueue()
+			req.reply <- b.flushBatch(context.Background())
+			if req.final {
+				return
+			}
+		}
+	}
+}
+
+func (b *Batcher[T]) append(item T) {
+	b.batch = append(b.batch, item)
+	if b.sizeOf != nil {
+		b.bytes += b.sizeOf(item)
+	}
+}
+
+func (b *Batcher[T]) full() bool {
+	return (b.cfg.MaxItems > 0 && len(b.batch) >= b.cfg.MaxItems) ||
+		(b.cfg.MaxBytes > 0 && b.bytes >= b.cfg.MaxBytes)
+}
+
+// drainQueue moves already queued items into the batch so that Flush and
+// Close cover everything added before them. Limits still split the batch.
+func (b *Batcher[T]) drainQueue() {
+	for {
+		select {
+		case item := <-b.items:
+			b.append(item)
+			if b.full() {
+				b.flushFull()
+			}
+		default:
+			return
+		}
+	}
+}
+
+// flushFull flushes a batch that reached a limit or the interval, reporting
+// failures through the error handler since there is no caller to return to.
+func (b *Batcher[T]) flushFull() {
+	batch := b.batch
+	if err := b.flushBatch(context.Background()); err != nil {
+		if b.onError != nil {
+			b.onError(batch, err)
+		} else {
+			b.log.Error("batch flush failed", "items", len(batch), "error", err)
+		}
+	}
+}
+
+func (b *Batcher[T]) flushBatch(ctx context.Context) error {
+	if len(b.batch) == 0 {
+		return nil
+	}
+	batch := b.batch
+	b.batch = nil
+	b.bytes = 0
+	return b.flush(ctx, batch)
+}
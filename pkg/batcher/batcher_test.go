@@ -0,0 +1,147 @@
+package batcher
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatcherTestSuite struct {
+	suite.Suite
+	log     *slog.Logger
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (s *BatcherTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.batches = nil
+}
+
+func (s *BatcherTestSuite) flush(_ context.Context, batch []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, batch)
+	return nil
+}
+
+func (s *BatcherTestSuite) flushed() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string{}, s.batches...)
+}
+
+func (s *BatcherTestSuite) TestFlushOnCount() {
+	b, err := New(&Config{MaxItems: 2}, s.flush, s.log)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	for _, item := range []string{"a", "b", "c", "d", "e"} {
+		s.NoError(b.Add(ctx, item))
+	}
+	s.NoError(b.Close(ctx))
+	s.Equal([][]string{{"a", "b"}, {"c", "d"}, {"e"}}, s.flushed())
+	s.ErrorIs(b.Add(ctx, "f"), ErrClosed)
+}
+
+func (s *BatcherTestSuite) TestFlushOnBytes() {
+	b, err := New(&Config{MaxBytes: 5}, s.flush, s.log, WithSizeFunc(func(v string) int { return len(v) }))
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	for _, item := range []string{"abc", "de", "f", "ghijk", "l"} {
+		s.NoError(b.Add(ctx, item))
+	}
+	s.NoError(b.Close(ctx))
+	s.Equal([][]string{{"abc", "de"}, {"f", "ghijk"}, {"l"}}, s.flushed())
+}
+
+func (s *BatcherTestSuite) TestFlushOnInterval() {
+	b, err := New(&Config{MaxItems: 100, FlushInterval: 20 * time.Millisecond}, s.flush, s.log)
+	s.Require().NoError(err)
+
+	s.NoError(b.Add(context.Background(), "a"))
+	s.Eventually(func() bool { return len(s.flushed()) == 1 }, time.Second, 5*time.Millisecond)
+	s.NoError(b.Close(context.Background()))
+	s.Equal([][]string{{"a"}}, s.flushed())
+}
+
+func (s *BatcherTestSuite) TestExplicitFlush() {
+	b, err := New(&Config{MaxItems: 100, QueueSize: 10}, s.flush, s.log)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	s.NoError(b.Add(ctx, "a"))
+	s.NoError(b.Add(ctx, "b"))
+	s.NoError(b.Flush(ctx))
+	s.Equal([][]string{{"a", "b"}}, s.flushed())
+	s.NoError(b.Close(ctx))
+	s.ErrorIs(b.Flush(ctx), ErrClosed)
+}
+
+func (s *BatcherTestSuite) TestBackpressure() {
+	release := make(chan struct{})
+	b, err := New(&Config{MaxItems: 1, QueueSize: 1}, func(context.Context, []string) error {
+		<-release
+		return nil
+	}, s.log)
+	s.Require().NoError(err)
+
+	s.NoError(b.Add(context.Background(), "a"))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.NoError(b.Add(ctx, "b"))
+	s.ErrorIs(b.Add(ctx, "c"), context.DeadlineExceeded)
+
+	close(release)
+	s.NoError(b.Close(context.Background()))
+}
+
+func (s *BatcherTestSuite) TestErrorHandler() {
+	var failed [][]string
+	b, err := New(&Config{MaxItems: 1}, func(context.Context, []string) error {
+		return errors.New("bigquery unavailable")
+	}, s.log, WithErrorHandler(func(batch []string, err error) {
+		failed = append(failed, batch)
+	}))
+	s.Require().NoError(err)
+
+	s.NoError(b.Add(context.Background(), "a"))
+	s.NoError(b.Close(context.Background()))
+	s.Equal([][]string{{"a"}}, failed)
+}
+
+func (s *BatcherTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "count", cfg: &Config{MaxItems: 500}},
+		{name: "interval", cfg: &Config{FlushInterval: time.Second}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "no limits", cfg: &Config{QueueSize: 10}, expectError: true},
+		{name: "negative", cfg: &Config{MaxItems: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestBatcherSuite(t *testing.T) {
+	suite.Run(t, new(BatcherTestSuite))
+}
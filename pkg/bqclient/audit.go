@@ -0,0 +1,102 @@
+package bqclient
+
+import (
+	"log/slog"
+	"maps"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	createdAtColumn = "created_at"
+	updatedAtColumn = "updated_at"
+)
+
+type Option func(*bqClient)
+
+// WithAuditColumns fills in the created_at and updated_at columns that a
+// row declares. Put sets either field of its struct that is zero, and
+// Update and UpdateMulti set updated_at when the updates map holds it as
+// nil or the zero time. Update never clears created_at: a zero value in the
+// map, as TypedClient.Update sends for an unset field, is dropped. Only
+// time.Time fields are filled, and the caller's values are not modified.
+func WithAuditColumns() Option {
+	return func(c *bqClient) {
+		c.audit = true
+	}
+}
+
+// WithLogger sets the logger that receives warnings such as retried jobs.
+// Without it they are discarded.
+func WithLogger(log *slog.Logger) Option {
+	return func(c *bqClient) {
+		if log != nil {
+			c.log = log
+		}
+	}
+}
+
+// WithClock replaces time.Now for audit columns.
+func WithClock(now func() time.Time) Option {
+	return func(c *bqClient) {
+		c.now = now
+	}
+}
+
+// stampRow returns data, or a copy of it with its audit columns filled.
+func (c *bqClient) stampRow(data any) any {
+	if !c.audit {
+		return data
+	}
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+
+	var row reflect.Value
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("bigquery"), ",")
+		if !f.IsExported() || f.Type != reflect.TypeOf(time.Time{}) ||
+			(name != createdAtColumn && name != updatedAtColumn) || !v.Field(i).IsZero() {
+			continue
+		}
+		if !row.IsValid() {
+			row = reflect.New(v.Type()).Elem()
+			row.Set(v)
+		}
+		row.Field(i).Set(reflect.ValueOf(c.now().UTC()))
+	}
+	if !row.IsValid() {
+		return data
+	}
+	return row.Addr().Interface()
+}
+
+// stampUpdates returns updates, or a copy of it with its audit columns
+// filled.
+func (c *bqClient) stampUpdates(updates map[string]any) map[string]any {
+	if !c.audit {
+		return updates
+	}
+	created, hasCreated := updates[createdAtColumn]
+	updated, hasUpdated := updates[updatedAtColumn]
+	if (!hasCreated || !zeroTime(created)) && (!hasUpdated || !zeroTime(updated)) {
+		return updates
+	}
+
+	updates = maps.Clone(updates)
+	if hasCreated && zeroTime(created) {
+		delete(updates, createdAtColumn)
+	}
+	if hasUpdated && zeroTime(updated) {
+		updates[updatedAtColumn] = c.now().UTC()
+	}
+	return updates
+}
+
+func zeroTime(v any) bool {
+	t, ok := v.(time.Time)
+	return v == nil || (ok && t.IsZero())
+}
@@ -0,0 +1,530 @@
+// Package bqclienttest provides an in-memory bqclient.BQClient for unit
+// tests of code that stores rows in BigQuery:
+//
+//	bq := bqclienttest.New()
+//	svc := NewService(bq)
+//	...
+//	rows := bq.Rows(models.TableProjects)
+//
+// Rows are kept per table as maps of bigquery-tagged columns and addressed
+// by their id column, so Put, Get, Update, Upsert, Delete, their Multi
+// forms, List and CopyTable behave like the real client. Methods that run
+// SQL or use the Storage API or Cloud Storage return ErrUnsupported; tests
+// that need them can embed *Client in a type of their own and override
+// them. Tables keep no history, so bqclient.WithSnapshotTime is ignored.
+package bqclienttest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/pkg/errors"
+)
+
+var ErrUnsupported = errors.New("not supported by bqclienttest")
+
+// Row is a table row keyed by column name.
+type Row = map[string]bigquery.Value
+
+type Client struct {
+	mu     sync.Mutex
+	tables map[string][]Row
+}
+
+var _ bqclient.BQClient = (*Client)(nil)
+
+func New() *Client {
+	return &Client{tables: map[string][]Row{}}
+}
+
+// Rows returns a copy of the rows of table in insertion order.
+func (c *Client) Rows(table string) []Row {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rows := make([]Row, len(c.tables[table]))
+	for i, row := range c.tables[table] {
+		rows[i] = copyRow(row)
+	}
+	return rows
+}
+
+func (c *Client) Put(ctx context.Context, table string, data any) error {
+	return c.StreamPut(ctx, table, data)
+}
+
+func (c *Client) StreamPut(_ context.Context, table string, data any) error {
+	rows, err := toRows(data)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tables[table] = append(c.tables[table], rows...)
+	return nil
+}
+
+func (c *Client) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
+	if len(inputs) == 0 {
+		return errors.New("inputs cannot be empty")
+	}
+	for table, data := range inputs {
+		if err := c.StreamPut(ctx, table, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) Get(_ context.Context, table string, id string, dst any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, row := range c.tables[table] {
+		if row["id"] == id {
+			return load(reflect.ValueOf(dst), row)
+		}
+	}
+	return bqclient.ErrNotFound
+}
+
+func (c *Client) GetMulti(_ context.Context, table string, ids []string, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("dst must be a pointer to a slice, got %T", dst)
+	}
+
+	c.mu.Lock()
+	var found []Row
+	for _, row := range c.tables[table] {
+		for _, id := range ids {
+			if row["id"] == id {
+				found = append(found, copyRow(row))
+				break
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	sort.SliceStable(found, func(i, j int) bool {
+		return fmt.Sprint(found[i]["id"]) < fmt.Sprint(found[j]["id"])
+	})
+	rows := reflect.MakeSlice(v.Elem().Type(), len(found), len(found))
+	for i, row := range found {
+		if err := load(rows.Index(i).Addr(), row); err != nil {
+			return err
+		}
+	}
+	v.Elem().Set(rows)
+	return nil
+}
+
+// List matches filter and sorts rows as the real client does. Columns are
+// compared as strings, numbers, bools or time.Time; a column absent from a
+// row is NULL.
+func (c *Client) List(_ context.Context, table string, filter map[string]any, opts bqclient.ListOptions, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("dst must be a pointer to a slice, got %T", dst)
+	}
+	if opts.Limit < 0 || opts.Offset < 0 {
+		return errors.New("limit and offset must not be negative")
+	}
+	if opts.Offset > 0 && opts.Limit == 0 {
+		return errors.New("query offset requires a limit")
+	}
+
+	c.mu.Lock()
+	var found []Row
+	for _, row := range c.tables[table] {
+		if matches(row, filter) {
+			found = append(found, copyRow(row))
+		}
+	}
+	c.mu.Unlock()
+
+	for i := len(opts.OrderBy) - 1; i >= 0; i-- {
+		col, dir, _ := strings.Cut(opts.OrderBy[i], " ")
+		desc := strings.EqualFold(strings.TrimSpace(dir), "DESC")
+		sort.SliceStable(found, func(i, j int) bool {
+			if desc {
+				return compare(found[j][col], found[i][col]) < 0
+			}
+			return compare(found[i][col], found[j][col]) < 0
+		})
+	}
+	found = found[min(opts.Offset, len(found)):]
+	if opts.Limit > 0 {
+		found = found[:min(opts.Limit, len(found))]
+	}
+
+	rows := reflect.MakeSlice(v.Elem().Type(), len(found), len(found))
+	for i, row := range found {
+		if err := load(rows.Index(i).Addr(), row); err != nil {
+			return err
+		}
+	}
+	v.Elem().Set(rows)
+	return nil
+}
+
+func (c *Client) Count(_ context.Context, table string, filter map[string]any) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int64
+	for _, row := range c.tables[table] {
+		if matches(row, filter) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (c *Client) Exists(ctx context.Context, table string, id string) (bool, error) {
+	n, err := c.Count(ctx, table, map[string]any{"id": id})
+	return n > 0, err
+}
+
+// Update sets columns of the row with the given id. Like the UPDATE it
+// stands in for, it does nothing when there is no such row.
+func (c *Client) Update(_ context.Context, table string, id string, updates map[string]any) error {
+	if len(updates) == 0 {
+		return errors.New("no updates provided")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, row := range c.tables[table] {
+		if row["id"] == id {
+			for col, val := range updates {
+				row[col] = val
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	if len(updates) == 0 {
+		return errors.New("no updates provided")
+	}
+	for _, id := range ids {
+		if err := c.Update(ctx, table, id, updates); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) Upsert(_ context.Context, table string, id string, data any) error {
+	rows, err := toRows(data)
+	if err != nil {
+		return err
+	}
+	if len(rows) != 1 {
+		return errors.Errorf("upsert needs one row, got %d", len(rows))
+	}
+	upsert := rows[0]
+	upsert["id"] = id
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, row := range c.tables[table] {
+		if row["id"] == id {
+			c.tables[table][i] = upsert
+			return nil
+		}
+	}
+	c.tables[table] = append(c.tables[table], upsert)
+	return nil
+}
+
+func (c *Client) Delete(_ context.Context, table string, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rows := c.tables[table][:0]
+	for _, row := range c.tables[table] {
+		if row["id"] != id {
+			rows = append(rows, row)
+		}
+	}
+	c.tables[table] = rows
+	return nil
+}
+
+func (c *Client) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	for _, id := range ids {
+		if err := c.Delete(ctx, table, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureTable creates table empty if it does not exist.
+func (c *Client) EnsureTable(_ context.Context, table string, _ any, _ ...bqclient.TableOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.tables[table]; !ok {
+		c.tables[table] = []Row{}
+	}
+	return nil
+}
+
+func (c *Client) CopyTable(_ context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rows := make([]Row, len(c.tables[srcTable]))
+	for i, row := range c.tables[srcTable] {
+		rows[i] = copyRow(row)
+	}
+	switch writeDisposition {
+	case bigquery.WriteAppend:
+		c.tables[dstTable] = append(c.tables[dstTable], rows...)
+	case bigquery.WriteTruncate:
+		c.tables[dstTable] = rows
+	default:
+		if len(c.tables[dstTable]) > 0 {
+			return errors.Errorf("table %s is not empty", dstTable)
+		}
+		c.tables[dstTable] = rows
+	}
+	return nil
+}
+
+func (c *Client) Query(context.Context, string, []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
+	return nil, errors.Wrap(ErrUnsupported, "Query")
+}
+
+func (c *Client) QueryRow(context.Context, string, []bigquery.QueryParameter, any) error {
+	return errors.Wrap(ErrUnsupported, "QueryRow")
+}
+
+func (c *Client) QueryPage(context.Context, string, []bigquery.QueryParameter, int, string, any) (string, error) {
+	return "", errors.Wrap(ErrUnsupported, "QueryPage")
+}
+
+func (c *Client) DryRun(context.Context, string, []bigquery.QueryParameter) (*bigquery.JobStatistics, error) {
+	return nil, errors.Wrap(ErrUnsupported, "DryRun")
+}
+
+func (c *Client) ExecScript(context.Context, string, []bigquery.QueryParameter) error {
+	return errors.Wrap(ErrUnsupported, "ExecScript")
+}
+
+func (c *Client) LoadFromGCS(context.Context, string, string, bigquery.DataFormat, ...bqclient.LoadOption) error {
+	return errors.Wrap(ErrUnsupported, "LoadFromGCS")
+}
+
+func (c *Client) ExtractToGCS(context.Context, string, string, bigquery.DataFormat) error {
+	return errors.Wrap(ErrUnsupported, "ExtractToGCS")
+}
+
+func (c *Client) StreamRead(context.Context, string, *bqclient.ReadFilter) (<-chan []byte, <-chan error) {
+	return failedStream[[]byte](errors.Wrap(ErrUnsupported, "StreamRead"))
+}
+
+func (c *Client) StreamReadRows(context.Context, string, *bqclient.ReadFilter, func() any) (<-chan any, <-chan error) {
+	return failedStream[any](errors.Wrap(ErrUnsupported, "StreamReadRows"))
+}
+
+func (c *Client) NewStreamWriter(context.Context, string, ...bqclient.StreamWriterOption) (*bqclient.StreamWriter, error) {
+	return nil, errors.Wrap(ErrUnsupported, "NewStreamWriter")
+}
+
+// TableName returns table as is, since the in-memory tables have no
+// dataset.
+func (c *Client) TableName(table string) (string, error) {
+	return table, nil
+}
+
+func (c *Client) Close() error {
+	return nil
+}
+
+func failedStream[T any](err error) (<-chan T, <-chan error) {
+	data := make(chan T)
+	errs := make(chan error, 1)
+	errs <- err
+	close(data)
+	close(errs)
+	return data, errs
+}
+
+// toRows converts a struct, a pointer to one, or a slice of either to rows
+// of its bigquery-tagged fields.
+func toRows(data any) ([]Row, error) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		v = reflect.ValueOf([]any{data})
+	}
+
+	rows := make([]Row, v.Len())
+	for i := range v.Len() {
+		elem := reflect.Indirect(reflect.ValueOf(v.Index(i).Interface()))
+		if elem.Kind() != reflect.Struct {
+			return nil, errors.Errorf("row %d: expected a struct, got %s", i, elem.Kind())
+		}
+		row := Row{}
+		for j, name := range columns(elem.Type()) {
+			if name != "" {
+				row[name] = elem.Field(j).Interface()
+			}
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// load copies row into dst, a pointer to a struct or to a Row.
+func load(dst reflect.Value, row Row) error {
+	if dst.Kind() != reflect.Pointer || dst.IsNil() {
+		return errors.Errorf("dst must be a non-nil pointer, got %s", dst.Type())
+	}
+	v := dst.Elem()
+	if v.Type() == reflect.TypeOf(Row{}) {
+		v.Set(reflect.ValueOf(copyRow(row)))
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return errors.Errorf("cannot load a row into %s", v.Type())
+	}
+
+	for i, name := range columns(v.Type()) {
+		val, ok := row[name]
+		if name == "" || !ok || val == nil {
+			continue
+		}
+		rv, field := reflect.ValueOf(val), v.Field(i)
+		switch {
+		case rv.Type().AssignableTo(field.Type()):
+			field.Set(rv)
+		case rv.Type().ConvertibleTo(field.Type()):
+			field.Set(rv.Convert(field.Type()))
+		default:
+			return errors.Errorf("column %s: cannot load %s into %s", name, rv.Type(), field.Type())
+		}
+	}
+	return nil
+}
+
+// columns returns the column name of each field of t, or "" for fields
+// without a bigquery tag.
+func columns(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("bigquery"), ",")
+		if f.IsExported() && name != "-" {
+			names[i] = name
+		}
+	}
+	return names
+}
+
+// matches reports whether row meets every condition of a List filter.
+func matches(row Row, filter map[string]any) bool {
+	for col, want := range filter {
+		got := row[col]
+		wv := reflect.ValueOf(want)
+		switch {
+		case want == nil:
+			if got != nil {
+				return false
+			}
+		case wv.Kind() == reflect.Slice && wv.Type().Elem().Kind() != reflect.Uint8:
+			found := false
+			for i := range wv.Len() {
+				if got != nil && compare(got, wv.Index(i).Interface()) == 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		default:
+			if got == nil || compare(got, want) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compare orders column values, with NULL first.
+func compare(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if at, ok := a.(time.Time); ok {
+		if bt, ok := b.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case av.Kind() == reflect.String && bv.Kind() == reflect.String:
+		return strings.Compare(av.String(), bv.String())
+	case av.Kind() == reflect.Bool && bv.Kind() == reflect.Bool:
+		return cmp.Compare(boolInt(av.Bool()), boolInt(bv.Bool()))
+	}
+	if af, ok := number(av); ok {
+		if bf, ok := number(bv); ok {
+			return cmp.Compare(af, bf)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func number(v reflect.Value) (float64, bool) {
+	switch {
+	case v.CanInt():
+		return float64(v.Int()), true
+	case v.CanUint():
+		return float64(v.Uint()), true
+	case v.CanFloat():
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func copyRow(row Row) Row {
+	out := make(Row, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
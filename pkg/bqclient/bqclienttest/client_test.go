@@ -0,0 +1,146 @@
+package bqclienttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	bq  *Client
+}
+
+func (s *ClientTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.bq = New()
+}
+
+func (s *ClientTestSuite) project(id string) *models.Project {
+	return &models.Project{ID: id, UtilityID: "u1", UserID: "user-" + id, ConnectionStartAt: time.Unix(0, 0).UTC()}
+}
+
+func (s *ClientTestSuite) TestPutGet() {
+	s.Require().NoError(s.bq.Put(s.ctx, models.TableProjects, s.project("p1")))
+
+	var p models.Project
+	s.Require().NoError(s.bq.Get(s.ctx, models.TableProjects, "p1", &p))
+	s.Equal(*s.project("p1"), p)
+
+	s.ErrorIs(s.bq.Get(s.ctx, models.TableProjects, "p2", &p), bqclient.ErrNotFound)
+}
+
+func (s *ClientTestSuite) TestGetMulti() {
+	s.Require().NoError(s.bq.StreamPut(s.ctx, models.TableProjects, []*models.Project{s.project("p3"), s.project("p1"), s.project("p2")}))
+
+	var ps []*models.Project
+	s.Require().NoError(s.bq.GetMulti(s.ctx, models.TableProjects, []string{"p3", "p1", "p9"}, &ps))
+	s.Require().Len(ps, 2)
+	s.Equal("p1", ps[0].ID)
+	s.Equal("p3", ps[1].ID)
+}
+
+func (s *ClientTestSuite) TestUpdateUpsertDelete() {
+	s.Require().NoError(s.bq.Put(s.ctx, models.TableProjects, s.project("p1")))
+
+	s.Require().NoError(s.bq.Update(s.ctx, models.TableProjects, "p1", map[string]any{"location": "Halifax"}))
+	s.Equal("Halifax", s.bq.Rows(models.TableProjects)[0]["location"])
+
+	s.Require().NoError(s.bq.Upsert(s.ctx, models.TableProjects, "p1", s.project("ignored")))
+	s.Require().NoError(s.bq.Upsert(s.ctx, models.TableProjects, "p2", s.project("ignored")))
+	rows := s.bq.Rows(models.TableProjects)
+	s.Require().Len(rows, 2)
+	s.Equal("p1", rows[0]["id"])
+	s.Equal("", rows[0]["location"])
+	s.Equal("p2", rows[1]["id"])
+
+	s.Require().NoError(s.bq.Delete(s.ctx, models.TableProjects, "p1"))
+	rows = s.bq.Rows(models.TableProjects)
+	s.Require().Len(rows, 1)
+	s.Equal("p2", rows[0]["id"])
+}
+
+func (s *ClientTestSuite) TestMulti() {
+	s.Require().NoError(s.bq.StreamPut(s.ctx, models.TableProjects, []*models.Project{s.project("p1"), s.project("p2"), s.project("p3")}))
+
+	s.Require().NoError(s.bq.UpdateMulti(s.ctx, models.TableProjects, []string{"p1", "p3"}, map[string]any{"location": "Halifax"}))
+	s.Require().NoError(s.bq.DeleteMulti(s.ctx, models.TableProjects, []string{"p2", "p3"}))
+
+	rows := s.bq.Rows(models.TableProjects)
+	s.Require().Len(rows, 1)
+	s.Equal("p1", rows[0]["id"])
+	s.Equal("Halifax", rows[0]["location"])
+}
+
+func (s *ClientTestSuite) TestList() {
+	p1, p2, p3 := s.project("p1"), s.project("p2"), s.project("p3")
+	p1.Location, p2.Location, p3.Location = "Halifax", "Toronto", "Halifax"
+	p1.ConnectionStartAt = time.Unix(100, 0).UTC()
+	s.Require().NoError(s.bq.StreamPut(s.ctx, models.TableProjects, []*models.Project{p1, p2, p3}))
+
+	var ps []models.Project
+	s.Require().NoError(s.bq.List(s.ctx, models.TableProjects, map[string]any{"location": "Halifax"},
+		bqclient.ListOptions{OrderBy: []string{"connection_start_at DESC"}}, &ps))
+	s.Require().Len(ps, 2)
+	s.Equal("p1", ps[0].ID)
+	s.Equal("p3", ps[1].ID)
+
+	s.Require().NoError(s.bq.List(s.ctx, models.TableProjects, map[string]any{"id": []string{"p2", "p3"}},
+		bqclient.ListOptions{OrderBy: []string{"id"}, Limit: 1, Offset: 1}, &ps))
+	s.Require().Len(ps, 1)
+	s.Equal("p3", ps[0].ID)
+
+	n, err := s.bq.Count(s.ctx, models.TableProjects, map[string]any{"location": "Halifax"})
+	s.Require().NoError(err)
+	s.Equal(int64(2), n)
+
+	found, err := s.bq.Exists(s.ctx, models.TableProjects, "p2")
+	s.Require().NoError(err)
+	s.True(found)
+	found, err = s.bq.Exists(s.ctx, models.TableProjects, "p9")
+	s.Require().NoError(err)
+	s.False(found)
+}
+
+func (s *ClientTestSuite) TestCopyTable() {
+	s.Require().NoError(s.bq.Put(s.ctx, models.TableProjects, s.project("p1")))
+
+	s.Require().NoError(s.bq.CopyTable(s.ctx, models.TableProjects, "projects_snapshot", bigquery.WriteEmpty))
+	s.Error(s.bq.CopyTable(s.ctx, models.TableProjects, "projects_snapshot", bigquery.WriteEmpty))
+	s.Require().NoError(s.bq.CopyTable(s.ctx, models.TableProjects, "projects_snapshot", bigquery.WriteAppend))
+	s.Len(s.bq.Rows("projects_snapshot"), 2)
+	s.Require().NoError(s.bq.CopyTable(s.ctx, models.TableProjects, "projects_snapshot", bigquery.WriteTruncate))
+	s.Len(s.bq.Rows("projects_snapshot"), 1)
+
+	s.Require().NoError(s.bq.Delete(s.ctx, models.TableProjects, "p1"))
+	s.Equal("p1", s.bq.Rows("projects_snapshot")[0]["id"])
+}
+
+func (s *ClientTestSuite) TestTyped() {
+	projects := bqclient.Typed[models.Project](s.bq, models.TableProjects)
+	s.Require().NoError(projects.Put(s.ctx, *s.project("p1")))
+
+	p, err := projects.Get(s.ctx, "p1")
+	s.Require().NoError(err)
+	s.Equal("user-p1", p.UserID)
+}
+
+func (s *ClientTestSuite) TestUnsupported() {
+	_, err := s.bq.Query(s.ctx, "SELECT 1", nil)
+	s.ErrorIs(err, ErrUnsupported)
+
+	data, errs := s.bq.StreamRead(s.ctx, models.TableDERData, nil)
+	for range data {
+	}
+	s.ErrorIs(<-errs, ErrUnsupported)
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
@@ -0,0 +1,186 @@
+package bqclient
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// QueryBuilder assembles a parameterised SELECT. Each ? in a Where
+// condition becomes a query parameter, so values never reach the SQL text:
+//
+//	query, params, err := bqclient.Select("id", "current_output").
+//		From(models.TableDERData).
+//		Where("project_id = ?", projectID).
+//		Where("timestamp >= ?", since).
+//		OrderBy("timestamp DESC").
+//		Limit(100).
+//		Build(cfg.DatasetID)
+//	...
+//	it, err := bq.Query(ctx, query, params)
+//
+// Selected and ordered columns must be plain identifiers; anything more
+// involved is better written as a query by hand. Errors are reported by
+// Build.
+type QueryBuilder struct {
+	columns []string
+	// aggregate replaces columns with an expression built by this package,
+	// which is not checked as an identifier.
+	aggregate string
+	table     string
+	where     []string
+	args      []any
+	orderBy   []string
+	limit     int
+	offset    int
+	asOf      time.Time
+	err       error
+}
+
+// Select starts a query of columns, or of every column when none are given.
+func Select(columns ...string) *QueryBuilder {
+	b := &QueryBuilder{}
+	for _, col := range columns {
+		if col != "*" && !tableName.MatchString(col) {
+			b.fail(errors.Errorf("invalid column %q", col))
+		}
+	}
+	b.columns = columns
+	return b
+}
+
+// SelectCount starts a query of the number of matching rows, selected as n.
+func SelectCount() *QueryBuilder {
+	return &QueryBuilder{aggregate: "COUNT(*) AS n"}
+}
+
+func (b *QueryBuilder) From(table string) *QueryBuilder {
+	if !tableName.MatchString(table) {
+		b.fail(errors.Wrapf(errInvalidTable, "table %q", table))
+	}
+	b.table = table
+	return b
+}
+
+// Where adds a condition, combined with any others by AND, with one arg for
+// each ? it contains. A slice arg can be matched with IN UNNEST(?).
+func (b *QueryBuilder) Where(cond string, args ...any) *QueryBuilder {
+	if n := strings.Count(cond, "?"); n != len(args) {
+		b.fail(errors.Errorf("condition %q has %d placeholders but %d args", cond, n, len(args)))
+	}
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// OrderBy sorts by columns, each optionally followed by ASC or DESC.
+func (b *QueryBuilder) OrderBy(columns ...string) *QueryBuilder {
+	for _, col := range columns {
+		name, dir, _ := strings.Cut(col, " ")
+		dir = strings.ToUpper(strings.TrimSpace(dir))
+		if !tableName.MatchString(name) || (dir != "" && dir != "ASC" && dir != "DESC") {
+			b.fail(errors.Errorf("invalid order %q", col))
+		}
+	}
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+func (b *QueryBuilder) Limit(n int) *QueryBuilder {
+	if n < 0 {
+		b.fail(errors.New("limit must not be negative"))
+	}
+	b.limit = n
+	return b
+}
+
+func (b *QueryBuilder) Offset(n int) *QueryBuilder {
+	if n < 0 {
+		b.fail(errors.New("offset must not be negative"))
+	}
+	b.offset = n
+	return b
+}
+
+// AsOf reads the table as it was at t, passed as the snapshot_time
+// parameter.
+func (b *QueryBuilder) AsOf(t time.Time) *QueryBuilder {
+	b.asOf = t
+	return b
+}
+
+func (b *QueryBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Build returns the query against datasetID and its parameters, named p1,
+// p2 and so on in the order of the ?s, then snapshot_time for AsOf.
+func (b *QueryBuilder) Build(datasetID string) (string, []bigquery.QueryParameter, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if b.table == "" {
+		return "", nil, errors.New("query table required")
+	}
+	if b.offset > 0 && b.limit == 0 {
+		return "", nil, errors.New("query offset requires a limit")
+	}
+
+	columns := "*"
+	switch {
+	case b.aggregate != "":
+		columns = b.aggregate
+	case len(b.columns) > 0:
+		columns = strings.Join(b.columns, ", ")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s FROM %s.%s", columns, datasetID, b.table)
+	if !b.asOf.IsZero() {
+		fmt.Fprintf(&sb, " FOR SYSTEM_TIME AS OF @%s", snapshotParam)
+	}
+
+	params := make([]bigquery.QueryParameter, len(b.args))
+	n := 0
+	for i, cond := range b.where {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		if len(b.where) > 1 {
+			sb.WriteString("(")
+		}
+		for _, r := range cond {
+			if r != '?' {
+				sb.WriteRune(r)
+				continue
+			}
+			params[n] = bigquery.QueryParameter{Name: fmt.Sprintf("p%d", n+1), Value: b.args[n]}
+			n++
+			fmt.Fprintf(&sb, "@p%d", n)
+		}
+		if len(b.where) > 1 {
+			sb.WriteString(")")
+		}
+	}
+
+	if len(b.orderBy) > 0 {
+		fmt.Fprintf(&sb, " ORDER BY %s", strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", b.limit)
+	}
+	if b.offset > 0 {
+		fmt.Fprintf(&sb, " OFFSET %d", b.offset)
+	}
+	if !b.asOf.IsZero() {
+		params = append(params, bigquery.QueryParameter{Name: snapshotParam, Value: b.asOf})
+	}
+	return sb.String(), params, nil
+}
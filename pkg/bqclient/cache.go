@@ -0,0 +1,105 @@
+package bqclient
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/cache"
+	"github.com/pkg/errors"
+)
+
+// CacheConfig enables caching the rows read by QueryRow, Get and GetMulti,
+// keyed by query text, parameters and destination type. Any write through
+// the client clears the cache; writes made elsewhere are seen once entries
+// expire.
+type CacheConfig struct {
+	TTL time.Duration `koanf:"ttl" json:"ttl" envconfig:"ttl"`
+	// MaxEntries bounds memory by dropping the least recently read results
+	// first. Zero means unbounded.
+	MaxEntries int `koanf:"max_entries" json:"max_entries" envconfig:"max_entries"`
+}
+
+func (c *CacheConfig) Validate() error {
+	if c.TTL <= 0 {
+		return errors.New("cache ttl must be greater than 0")
+	}
+	if c.MaxEntries < 0 {
+		return errors.New("cache max entries must not be negative")
+	}
+	return nil
+}
+
+// cached fills dst, a pointer, from the cache, or with load and caches the
+// result. Errors, including ErrNotFound, are not cached.
+func (c *bqClient) cached(query string, params []bigquery.QueryParameter, dst any, load func() error) error {
+	if c.cache == nil {
+		return load()
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return load()
+	}
+	key := cacheKey(query, params, v.Type())
+	if hit, ok := c.cache.Get(key); ok {
+		v.Elem().Set(clone(hit))
+		return nil
+	}
+
+	if err := load(); err != nil {
+		return err
+	}
+	c.cache.Set(key, clone(v.Elem()))
+	return nil
+}
+
+// invalidate clears the cache after a write.
+func (c *bqClient) invalidate() {
+	if c.cache != nil {
+		c.cache.Purge()
+	}
+}
+
+func cacheKey(query string, params []bigquery.QueryParameter, dst reflect.Type) string {
+	var sb strings.Builder
+	sb.WriteString(dst.String())
+	sb.WriteByte(0)
+	sb.WriteString(query)
+	for _, p := range params {
+		fmt.Fprintf(&sb, "\x00%s=%#v", p.Name, p.Value)
+	}
+	return sb.String()
+}
+
+// clone copies v so the cached value and the caller's do not share a slice
+// or map. Values they point to are still shared.
+func clone(v reflect.Value) reflect.Value {
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Slice:
+		if !v.IsNil() {
+			out.Set(reflect.AppendSlice(reflect.MakeSlice(v.Type(), 0, v.Len()), v))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			out.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			iter := v.MapRange()
+			for iter.Next() {
+				out.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+	default:
+		out.Set(v)
+	}
+	return out
+}
+
+func newCache(cfg *CacheConfig) *cache.LRU[string, reflect.Value] {
+	if cfg == nil {
+		return nil
+	}
+	return cache.New[string, reflect.Value](cfg.MaxEntries, cfg.TTL)
+}
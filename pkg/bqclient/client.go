@@ -2,132 +2,217 @@ package bqclient
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	storage "cloud.google.com/go/bigquery/storage/apiv1"
 	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"github.com/grid-stream-org/go-commons/pkg/avro"
+	"github.com/grid-stream-org/go-commons/pkg/cache"
+	"github.com/grid-stream-org/go-commons/pkg/concurrency"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/pagination"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
 	"github.com/matthew-collett/go-ctag/ctag"
 	"github.com/pkg/errors"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-const (
-	tableProjects        = "projects"
-	tableContracts       = "contracts"
-	tableDERMetadata     = "der_metadata"
-	tableDERData         = "der_data"
-	tableProjectAverages = "project_averages"
-	tableUtilities       = "utilities"
-	tableDREvents        = "dr_events"
-)
-
-var validTables = map[string]bool{
-	tableProjects:        true,
-	tableContracts:       true,
-	tableDERMetadata:     true,
-	tableDERData:         true,
-	tableProjectAverages: true,
-	tableUtilities:       true,
-	tableDREvents:        true,
-}
+// streamPutConcurrency bounds how many tables StreamPutAll writes at once.
+const streamPutConcurrency = 4
 
 type BQClient interface {
 	Put(ctx context.Context, table string, data any) error
-	StreamRead(ctx context.Context, table string, projectIDs []string) (<-chan []byte, <-chan error)
+	StreamRead(ctx context.Context, table string, filter *ReadFilter) (<-chan []byte, <-chan error)
+	StreamReadRows(ctx context.Context, table string, filter *ReadFilter, newRow func() any) (<-chan any, <-chan error)
 	StreamPut(ctx context.Context, table string, data any) error
 	StreamPutAll(ctx context.Context, inputs map[string][]any) error
+	NewStreamWriter(ctx context.Context, table string, opts ...StreamWriterOption) (*StreamWriter, error)
 	Query(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.RowIterator, error)
 	QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error
+	QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string, dst any) (string, error)
+	DryRun(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.JobStatistics, error)
+	ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error
 	Update(ctx context.Context, table string, id string, updates map[string]interface{}) error
+	UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error
+	Upsert(ctx context.Context, table string, id string, data any) error
 	Delete(ctx context.Context, table string, id string) error
+	DeleteMulti(ctx context.Context, table string, ids []string) error
 	Get(ctx context.Context, table string, id string, dst any) error
+	GetMulti(ctx context.Context, table string, ids []string, dst any) error
+	List(ctx context.Context, table string, filter map[string]any, opts ListOptions, dst any) error
+	Count(ctx context.Context, table string, filter map[string]any) (int64, error)
+	Exists(ctx context.Context, table string, id string) (bool, error)
+	EnsureTable(ctx context.Context, table string, model any, opts ...TableOption) error
+	LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...LoadOption) error
+	ExtractToGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat) error
+	CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error
+	TableName(table string) (string, error)
 	Close() error
 }
 
 type Config struct {
 	ProjectID string `koanf:"project_id" json:"project_id" envconfig:"project_id"`
 	DatasetID string `koanf:"dataset_id" json:"dataset_id" envconfig:"dataset_id"`
+	// CredsPath names a credentials file and CredsJSON holds its contents,
+	// for platforms that inject secrets as environment variables. With
+	// neither, Application Default Credentials are used, such as the
+	// workload identity of a Cloud Run service or GKE pod.
 	CredsPath string `koanf:"creds_path" json:"creds_path" envconfig:"creds_path"`
+	CredsJSON string `koanf:"creds_json" json:"creds_json" envconfig:"creds_json"`
+
+	// Tables are allowed in addition to the models tables and those added
+	// with RegisterTable.
+	Tables []string `koanf:"tables" json:"tables" envconfig:"tables"`
+
+	// ReadStreams is the most streams a StreamRead session is split into,
+	// read concurrently. BigQuery may return fewer, and rows from different
+	// streams arrive interleaved. It defaults to 1, which keeps table order,
+	// and can be overridden per call with WithReadStreams.
+	ReadStreams int `koanf:"read_streams" json:"read_streams" envconfig:"read_streams"`
+
+	// Retry is the policy for queries, streaming inserts and opening read
+	// streams that fail with a transient error such as rateLimitExceeded or
+	// backendError.
+	Retry retry.Config `koanf:"retry" json:"retry" envconfig:"retry"`
+
+	// JobLabels are set on every query job, for example service and env, so
+	// billing exports can attribute cost. JobIDPrefix starts every job ID.
+	// Both can be overridden per call with WithJobLabels and
+	// WithJobIDPrefix.
+	JobLabels   map[string]string `koanf:"job_labels" json:"job_labels" envconfig:"job_labels"`
+	JobIDPrefix string            `koanf:"job_id_prefix" json:"job_id_prefix" envconfig:"job_id_prefix"`
+
+	// Cache, when set, caches row reads in memory. It is off by default.
+	Cache *CacheConfig `koanf:"cache" json:"cache" envconfig:"cache"`
+
+	// PageTokenSecret signs the page tokens of QueryPage, which fails
+	// without it. Every replica of a service must use the same secret.
+	PageTokenSecret string `koanf:"page_token_secret" json:"page_token_secret" envconfig:"page_token_secret"`
+
+	// EmulatorHost and EmulatorGRPCHost point the client at a local BigQuery
+	// emulator's REST and Storage Read ports, as started by testutil.BigQuery.
+	// They must be set together. Credentials are not used when they are set.
+	EmulatorHost     string `koanf:"emulator_host" json:"emulator_host" envconfig:"emulator_host"`
+	EmulatorGRPCHost string `koanf:"emulator_grpc_host" json:"emulator_grpc_host" envconfig:"emulator_grpc_host"`
 }
 
 type bqClient struct {
 	cfg        *Config
+	log        *slog.Logger
 	client     *bigquery.Client
 	readClient *storage.BigQueryReadClient
+	cache      *cache.LRU[string, reflect.Value]
+	pages      *pagination.Codec
+	audit      bool
+	now        func() time.Time
+
+	writeMu     sync.Mutex
+	writeClient *managedwriter.Client
 }
 
 var (
-	errInvalidTable = errors.New("invalid table name")
-	ErrNotFound     = errors.New("no rows returned")
+	errInvalidTable = gserrors.New(gserrors.InvalidInput, "invalid table name")
+	ErrNotFound     = gserrors.New(gserrors.NotFound, "no rows returned")
 )
 
-func validateTableName(table string) error {
-	if !validTables[table] {
-		return errors.Wrapf(errInvalidTable, "table %s not found in schema", table)
-	}
-	return nil
-}
-
-func New(ctx context.Context, cfg *Config) (BQClient, error) {
+// New connects to the project and dataset of cfg.
+func New(ctx context.Context, cfg *Config, opts ...Option) (BQClient, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
 
-	client, err := bigquery.NewClient(ctx, cfg.ProjectID, option.WithCredentialsFile(cfg.CredsPath))
+	client, err := bigquery.NewClient(ctx, cfg.ProjectID, cfg.clientOptions()...)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
-	readClient, err := storage.NewBigQueryReadClient(ctx, option.WithCredentialsFile(cfg.CredsPath))
+	readClient, err := storage.NewBigQueryReadClient(ctx, cfg.readClientOptions()...)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
 
 	c := &bqClient{
 		cfg:        cfg,
+		log:        slog.New(slog.NewTextHandler(io.Discard, nil)),
 		client:     client,
 		readClient: readClient,
+		cache:      newCache(cfg.Cache),
+		now:        time.Now,
+	}
+	if cfg.PageTokenSecret != "" {
+		if c.pages, err = pagination.NewCodec(cfg.PageTokenSecret); err != nil {
+			return nil, err
+		}
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
 	return c, nil
 }
 
 func (c *bqClient) execute(ctx context.Context, query string, params []bigquery.QueryParameter, needsResults bool) (*bigquery.RowIterator, error) {
-	q := c.client.Query(query)
-	q.Parameters = params
-
-	if needsResults {
-		return q.Read(ctx)
-	}
-
-	job, err := q.Run(ctx)
+	q, err := c.newQuery(ctx, query, params)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 
-	status, err := job.Wait(ctx)
-	if err != nil {
-		return nil, errors.WithStack(err)
+	if needsResults {
+		var it *bigquery.RowIterator
+		err = c.retry(ctx, "query", func(ctx context.Context) error {
+			var err error
+			it, err = q.Read(ctx)
+			return err
+		})
+		return it, err
 	}
 
-	if err := status.Err(); err != nil {
-		return nil, errors.WithStack(err)
+	err = c.runJob(ctx, "query", q.Run)
+	c.invalidate()
+	if err != nil {
+		return nil, err
 	}
 
 	return nil, nil
 }
 
 func (c *bqClient) Put(ctx context.Context, table string, data any) error {
-	if err := validateTableName(table); err != nil {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+
+	query, params, err := InsertQuery(c.cfg.DatasetID+"."+table, "", c.stampRow(data))
+	if err != nil {
 		return err
 	}
 
+	_, err = c.execute(ctx, query, params, false)
+	return err
+}
+
+// InsertQuery builds a parameterised INSERT of data's bigquery-tagged fields
+// into table, a name qualified with its dataset such as one returned by
+// TableName. Parameter names are prefixed with prefix so that several
+// inserts can share one multi-statement query.
+func InsertQuery(table, prefix string, data any) (string, []bigquery.QueryParameter, error) {
 	tags, err := ctag.GetTags("bigquery", data)
 	if err != nil {
-		return errors.WithStack(err)
+		return "", nil, errors.WithStack(err)
 	}
 
 	var fields []string
@@ -136,37 +221,31 @@ func (c *bqClient) Put(ctx context.Context, table string, data any) error {
 
 	for _, tag := range tags {
 		fields = append(fields, tag.Name)
-		placeholders = append(placeholders, fmt.Sprintf("@%s", tag.Name))
+		placeholders = append(placeholders, fmt.Sprintf("@%s%s", prefix, tag.Name))
 		params = append(params, bigquery.QueryParameter{
-			Name:  tag.Name,
+			Name:  prefix + tag.Name,
 			Value: tag.Field,
 		})
 	}
 
 	query := fmt.Sprintf(`
-        INSERT INTO %s.%s
+        INSERT INTO %s
         (%s)
         VALUES
         (%s)`,
-		c.cfg.DatasetID,
 		table,
 		strings.Join(fields, ", "),
 		strings.Join(placeholders, ", "),
 	)
-
-	_, err = c.execute(ctx, query, params, false)
-	return err
+	return query, params, nil
 }
 
 func (c *bqClient) StreamPut(ctx context.Context, table string, data any) error {
-	if err := validateTableName(table); err != nil {
+	if err := c.validateTable(table); err != nil {
 		return err
 	}
 
-	if err := c.inserter(table).Put(ctx, data); err != nil {
-		return errors.WithStack(err)
-	}
-	return nil
+	return c.insert(ctx, table, data)
 }
 
 func (c *bqClient) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
@@ -174,49 +253,55 @@ func (c *bqClient) StreamPutAll(ctx context.Context, inputs map[string][]any) er
 		return errors.New("inputs cannot be empty")
 	}
 
-	for table, data := range inputs {
-		if err := validateTableName(table); err != nil {
+	tables := make([]string, 0, len(inputs))
+	for table := range inputs {
+		if err := c.validateTable(table); err != nil {
 			return err
 		}
-
-		if err := c.inserter(table).Put(ctx, data); err != nil {
-			return errors.WithStack(err)
-		}
+		tables = append(tables, table)
 	}
-	return nil
+	sort.Strings(tables)
+
+	return concurrency.ForEachLimit(ctx, tables, streamPutConcurrency, func(ctx context.Context, table string) error {
+		return c.insert(ctx, table, inputs[table])
+	})
 }
 
 func (c *bqClient) Query(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
-	return c.execute(ctx, query, params, true)
+	return c.execute(ctx, query, withSnapshot(ctx, query, params), true)
 }
 
 func (c *bqClient) QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error {
-	it, err := c.execute(ctx, query, params, true)
-	if err != nil {
-		return err
-	}
+	params = withSnapshot(ctx, query, params)
+	return c.cached(query, params, dst, func() error {
+		it, err := c.execute(ctx, query, params, true)
+		if err != nil {
+			return err
+		}
 
-	if err := it.Next(dst); err != nil {
-		if err == iterator.Done {
-			return ErrNotFound
+		if err := it.Next(dst); err != nil {
+			if err == iterator.Done {
+				return ErrNotFound
+			}
+			return errors.WithStack(err)
 		}
-		return errors.WithStack(err)
-	}
-	return nil
+		return nil
+	})
 }
 
 func (c *bqClient) Get(ctx context.Context, table string, id string, dst any) error {
-	if err := validateTableName(table); err != nil {
+	if err := c.validateTable(table); err != nil {
 		return err
 	}
 
 	query := fmt.Sprintf(`
         SELECT *
-        FROM %s.%s
+        FROM %s.%s%s
         WHERE id = @id
         LIMIT 1`,
 		c.cfg.DatasetID,
 		table,
+		asOf(ctx),
 	)
 
 	params := []bigquery.QueryParameter{
@@ -226,11 +311,88 @@ func (c *bqClient) Get(ctx context.Context, table string, id string, dst any) er
 	return c.QueryRow(ctx, query, params, dst)
 }
 
+// GetMulti reads the rows with the given ids in one query into the slice dst
+// points to, in id order. Ids with no row are left out, so callers that need
+// every id should compare lengths.
+func (c *bqClient) GetMulti(ctx context.Context, table string, ids []string, dst any) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("dst must be a pointer to a slice, got %T", dst)
+	}
+	rows := reflect.MakeSlice(v.Elem().Type(), 0, len(ids))
+	if len(ids) == 0 {
+		v.Elem().Set(rows)
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+        SELECT *
+        FROM %s.%s%s
+        WHERE id IN UNNEST(@ids)
+        ORDER BY id`,
+		c.cfg.DatasetID,
+		table,
+		asOf(ctx),
+	)
+
+	params := withSnapshot(ctx, query, []bigquery.QueryParameter{
+		{Name: "ids", Value: ids},
+	})
+
+	return c.cached(query, params, dst, func() error {
+		it, err := c.execute(ctx, query, params, true)
+		if err != nil {
+			return err
+		}
+		rows, err = appendRows(it, rows, false)
+		if err != nil {
+			return err
+		}
+		v.Elem().Set(rows)
+		return nil
+	})
+}
+
+// appendRows reads every row of it onto the slice s, whose elements are
+// structs or pointers to structs. With page set it stops once the fetched
+// page is used up, leaving the iterator's token naming the page after.
+func appendRows(it *bigquery.RowIterator, s reflect.Value, page bool) (reflect.Value, error) {
+	elem := s.Type().Elem()
+	isPtr := elem.Kind() == reflect.Pointer
+	if isPtr {
+		elem = elem.Elem()
+	}
+
+	for {
+		row := reflect.New(elem)
+		err := it.Next(row.Interface())
+		if err == iterator.Done {
+			return s, nil
+		}
+		if err != nil {
+			return s, errors.WithStack(err)
+		}
+		if isPtr {
+			s = reflect.Append(s, row)
+		} else {
+			s = reflect.Append(s, row.Elem())
+		}
+		if page && it.PageInfo().Remaining() == 0 {
+			return s, nil
+		}
+	}
+}
+
 func (c *bqClient) Update(ctx context.Context, table string, id string, updates map[string]any) error {
-	if err := validateTableName(table); err != nil {
+	if err := c.validateTable(table); err != nil {
 		return err
 	}
 
+	updates = c.stampUpdates(updates)
 	setStatements := make([]string, 0, len(updates))
 	params := []bigquery.QueryParameter{
 		{Name: "id", Value: id},
@@ -257,8 +419,123 @@ func (c *bqClient) Update(ctx context.Context, table string, id string, updates
 	return err
 }
 
+// UpdateMulti applies updates to every row whose id is in ids, in one DML
+// job.
+func (c *bqClient) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return errors.New("no updates provided")
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	updates = c.stampUpdates(updates)
+	fields := slices.Sorted(maps.Keys(updates))
+	setStatements := make([]string, len(fields))
+	params := []bigquery.QueryParameter{
+		{Name: "ids", Value: ids},
+	}
+
+	for i, field := range fields {
+		if field == "ids" {
+			return errors.New("cannot update a column named ids")
+		}
+		setStatements[i] = fmt.Sprintf("%s = @%s", field, field)
+		params = append(params, bigquery.QueryParameter{
+			Name:  field,
+			Value: updates[field],
+		})
+	}
+
+	query := fmt.Sprintf(`
+        UPDATE %s.%s
+        SET %s
+        WHERE id IN UNNEST(@ids)`,
+		c.cfg.DatasetID,
+		table,
+		strings.Join(setStatements, ", "),
+	)
+
+	_, err := c.execute(ctx, query, params, false)
+	return err
+}
+
+// Upsert inserts data as the row with the given id, or replaces the
+// bigquery-tagged columns of that row if it already exists.
+func (c *bqClient) Upsert(ctx context.Context, table string, id string, data any) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+
+	query, params, err := MergeQuery(c.cfg.DatasetID, table, id, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.execute(ctx, query, params, false)
+	return err
+}
+
+// MergeQuery builds a parameterised MERGE of data's bigquery-tagged fields
+// into the row of datasetID.table whose id is id, updating it when it exists
+// and inserting it otherwise. An id field in data is ignored in favour of id.
+func MergeQuery(datasetID, table, id string, data any) (string, []bigquery.QueryParameter, error) {
+	tags, err := ctag.GetTags("bigquery", data)
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	selects := []string{"@id AS id"}
+	fields := []string{"id"}
+	values := []string{"S.id"}
+	var sets []string
+	params := []bigquery.QueryParameter{
+		{Name: "id", Value: id},
+	}
+
+	for _, tag := range tags {
+		if tag.Name == "id" {
+			continue
+		}
+		selects = append(selects, fmt.Sprintf("@%s AS %s", tag.Name, tag.Name))
+		fields = append(fields, tag.Name)
+		values = append(values, "S."+tag.Name)
+		sets = append(sets, fmt.Sprintf("%s = S.%s", tag.Name, tag.Name))
+		params = append(params, bigquery.QueryParameter{
+			Name:  tag.Name,
+			Value: tag.Field,
+		})
+	}
+
+	update := ""
+	if len(sets) > 0 {
+		update = fmt.Sprintf(`
+        WHEN MATCHED THEN
+          UPDATE SET %s`, strings.Join(sets, ", "))
+	}
+
+	query := fmt.Sprintf(`
+        MERGE %s.%s T
+        USING (SELECT %s) S
+        ON T.id = S.id%s
+        WHEN NOT MATCHED THEN
+          INSERT (%s)
+          VALUES (%s)`,
+		datasetID,
+		table,
+		strings.Join(selects, ", "),
+		update,
+		strings.Join(fields, ", "),
+		strings.Join(values, ", "),
+	)
+	return query, params, nil
+}
+
 func (c *bqClient) Delete(ctx context.Context, table string, id string) error {
-	if err := validateTableName(table); err != nil {
+	if err := c.validateTable(table); err != nil {
 		return err
 	}
 
@@ -277,87 +554,225 @@ func (c *bqClient) Delete(ctx context.Context, table string, id string) error {
 	return err
 }
 
-func (c *bqClient) StreamRead(ctx context.Context, table string, projectIDs []string) (<-chan []byte, <-chan error) {
+// DeleteMulti deletes every row whose id is in ids, in one DML job.
+func (c *bqClient) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(`
+        DELETE FROM %s.%s
+        WHERE id IN UNNEST(@ids)`,
+		c.cfg.DatasetID,
+		table,
+	)
+
+	params := []bigquery.QueryParameter{
+		{Name: "ids", Value: ids},
+	}
+
+	_, err := c.execute(ctx, query, params, false)
+	return err
+}
+
+func (c *bqClient) StreamRead(ctx context.Context, table string, filter *ReadFilter) (<-chan []byte, <-chan error) {
 	dataChan := make(chan []byte, 100)
 	errChan := make(chan error, 1)
 
-	if err := validateTableName(table); err != nil {
+	session, err := c.readSession(ctx, table, filter)
+	if err != nil {
 		errChan <- err
 		close(dataChan)
 		close(errChan)
 		return dataChan, errChan
 	}
 
-	// Create the project_id filter condition
-	filter := ""
-	if len(projectIDs) > 0 {
-		quoted := make([]string, len(projectIDs))
-		for i, id := range projectIDs {
-			quoted[i] = fmt.Sprintf("'%s'", id)
+	go func() {
+		defer close(dataChan)
+		defer close(errChan)
+		err := c.readStreams(ctx, session, func(ctx context.Context, res *storagepb.ReadRowsResponse) error {
+			select {
+			case dataChan <- res.GetAvroRows().GetSerializedBinaryRows():
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil {
+			errChan <- err
 		}
-		filter = fmt.Sprintf("project_id IN (%s)", strings.Join(quoted, ","))
+	}()
+	return dataChan, errChan
+}
+
+// StreamReadRows is StreamRead with each row decoded, using the session's
+// Avro schema, into a value from newRow: a pointer to a struct whose fields
+// are matched to columns by bigquery tag, as with Query. A nil newRow sends
+// each row as a map[string]any.
+func (c *bqClient) StreamReadRows(ctx context.Context, table string, filter *ReadFilter, newRow func() any) (<-chan any, <-chan error) {
+	rowChan := make(chan any, 100)
+	errChan := make(chan error, 1)
+
+	session, err := c.readSession(ctx, table, filter)
+	var codec *avro.Codec
+	if err == nil {
+		codec, err = avro.Parse(session.GetAvroSchema().GetSchema())
+	}
+	if err != nil {
+		errChan <- err
+		close(rowChan)
+		close(errChan)
+		return rowChan, errChan
+	}
+
+	go func() {
+		defer close(rowChan)
+		defer close(errChan)
+		err := c.readStreams(ctx, session, func(ctx context.Context, res *storagepb.ReadRowsResponse) error {
+			return sendRows(ctx, codec, res.GetAvroRows().GetSerializedBinaryRows(), newRow, rowChan)
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+	return rowChan, errChan
+}
+
+// sendRows decodes block onto rows. Decoding failures are marked
+// retry.Permanent since reading the block again gives the same result.
+func sendRows(ctx context.Context, codec *avro.Codec, block []byte, newRow func() any, rows chan<- any) error {
+	maps, err := codec.DecodeMaps(block)
+	if err != nil {
+		return retry.Permanent(errors.Wrap(err, "decoding rows"))
+	}
+	for _, m := range maps {
+		var row any = m
+		if newRow != nil {
+			row = newRow()
+			if err := loadRow(row, m); err != nil {
+				return retry.Permanent(errors.Wrap(err, "decoding rows"))
+			}
+		}
+		select {
+		case rows <- row:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+type readStreamsKey struct{}
+
+// WithReadStreams returns a copy of ctx whose StreamRead calls use at most n
+// streams instead of Config.ReadStreams. A single stream keeps table order,
+// which callers that resume by row count depend on.
+func WithReadStreams(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, readStreamsKey{}, n)
+}
+
+// readStreamCount returns the stream limit for ctx.
+func (c *bqClient) readStreamCount(ctx context.Context) int32 {
+	n, ok := ctx.Value(readStreamsKey{}).(int)
+	if !ok {
+		n = c.cfg.ReadStreams
+	}
+	return int32(max(n, 1))
+}
+
+// readSession creates an Avro read session on table of up to ReadStreams
+// streams, restricted to the rows matching filter and read at the snapshot
+// time of ctx, if any.
+func (c *bqClient) readSession(ctx context.Context, table string, filter *ReadFilter) (*storagepb.ReadSession, error) {
+	if err := c.validateTable(table); err != nil {
+		return nil, err
+	}
+
+	restriction, err := filter.RowRestriction()
+	if err != nil {
+		return nil, gserrors.Wrap(err, gserrors.InvalidInput, "invalid read filter")
+	}
+
+	var modifiers *storagepb.ReadSession_TableModifiers
+	if t, ok := snapshotFrom(ctx); ok {
+		modifiers = &storagepb.ReadSession_TableModifiers{SnapshotTime: timestamppb.New(t)}
 	}
 
 	parent := fmt.Sprintf("projects/%s", c.cfg.ProjectID)
 	tablePath := fmt.Sprintf("projects/%s/datasets/%s/tables/%s",
 		c.cfg.ProjectID, c.cfg.DatasetID, table)
 
-	session, err := c.readClient.CreateReadSession(ctx, &storagepb.CreateReadSessionRequest{
-		Parent: parent,
-		ReadSession: &storagepb.ReadSession{
-			Table:      tablePath,
-			DataFormat: storagepb.DataFormat_AVRO,
-			ReadOptions: &storagepb.ReadSession_TableReadOptions{
-				RowRestriction: filter, // Apply the filter here
+	var session *storagepb.ReadSession
+	err = c.retry(ctx, "read_session", func(ctx context.Context) error {
+		var err error
+		session, err = c.readClient.CreateReadSession(ctx, &storagepb.CreateReadSessionRequest{
+			Parent: parent,
+			ReadSession: &storagepb.ReadSession{
+				Table:      tablePath,
+				DataFormat: storagepb.DataFormat_AVRO,
+				ReadOptions: &storagepb.ReadSession_TableReadOptions{
+					RowRestriction: restriction,
+				},
+				TableModifiers: modifiers,
 			},
-		},
-		MaxStreamCount: 1,
+			MaxStreamCount: c.readStreamCount(ctx),
+		})
+		return err
 	})
-
-	// Rest of the function remains the same as your original StreamRead
 	if err != nil {
-		errChan <- err
-		close(dataChan)
-		close(errChan)
-		return dataChan, errChan
+		return nil, err
 	}
 
 	if len(session.Streams) == 0 {
-		errChan <- errors.New("no streams in session")
-		close(dataChan)
-		close(errChan)
-		return dataChan, errChan
+		return nil, errors.New("no streams in session")
 	}
+	return session, nil
+}
 
-	go func() {
-		defer close(dataChan)
-		defer close(errChan)
-		streamReader, err := c.readClient.ReadRows(ctx, &storagepb.ReadRowsRequest{
-			ReadStream: session.Streams[0].Name,
+// readStreams reads every stream of session concurrently, passing each
+// response to fn, which must be safe for concurrent use. The first error
+// stops all streams. Since fn blocks its stream, a slow consumer slows the
+// reads rather than buffering them.
+func (c *bqClient) readStreams(ctx context.Context, session *storagepb.ReadSession, fn func(context.Context, *storagepb.ReadRowsResponse) error) error {
+	return concurrency.ForEachLimit(ctx, session.Streams, 0, func(ctx context.Context, stream *storagepb.ReadStream) error {
+		return c.readStream(ctx, stream.Name, fn)
+	})
+}
+
+// readStream passes every response of the read stream to fn until the
+// stream ends, fn fails, or ctx is done.
+func (c *bqClient) readStream(ctx context.Context, stream string, fn func(context.Context, *storagepb.ReadRowsResponse) error) error {
+	var streamReader storagepb.BigQueryRead_ReadRowsClient
+	err := c.retry(ctx, "read_rows", func(ctx context.Context) error {
+		var err error
+		streamReader, err = c.readClient.ReadRows(ctx, &storagepb.ReadRowsRequest{
+			ReadStream: stream,
 		})
-		if err != nil {
-			errChan <- err
-			return
-		}
-		for {
-			select {
-			case <-ctx.Done():
-				errChan <- ctx.Err()
-				return
-			default:
-				res, err := streamReader.Recv()
-				if err == io.EOF {
-					return
-				}
-				if err != nil {
-					errChan <- err
-					return
-				}
-				dataChan <- res.GetAvroRows().GetSerializedBinaryRows()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			res, err := streamReader.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := fn(ctx, res); err != nil {
+				return err
 			}
 		}
-	}()
-	return dataChan, errChan
+	}
 }
 
 func (c *bqClient) Close() error {
@@ -369,6 +784,14 @@ func (c *bqClient) Close() error {
 		return errors.WithStack(err)
 	}
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.writeClient != nil {
+		if err := c.writeClient.Close(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
 	return nil
 }
 
@@ -382,12 +805,88 @@ func (c *Config) Validate() error {
 	if c.DatasetID == "" {
 		return errors.New("database dataset ID required")
 	}
-	if c.CredsPath == "" {
-		return errors.New("database creds path required")
+	if c.CredsPath != "" && c.CredsJSON != "" {
+		return errors.New("database creds path and creds json are mutually exclusive")
+	}
+	if c.CredsJSON != "" && !json.Valid([]byte(c.CredsJSON)) {
+		return errors.New("database creds json is not valid JSON")
+	}
+	if c.ReadStreams < 0 {
+		return errors.New("database read streams must not be negative")
+	}
+	if (c.EmulatorHost == "") != (c.EmulatorGRPCHost == "") {
+		return errors.New("database emulator host and emulator grpc host must be set together")
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return errors.Wrap(err, "database retry")
+	}
+	if err := validateJobLabels(c.JobLabels); err != nil {
+		return errors.Wrap(err, "database")
+	}
+	if err := validateJobIDPrefix(c.JobIDPrefix); err != nil {
+		return errors.Wrap(err, "database")
+	}
+	if c.Cache != nil {
+		if err := c.Cache.Validate(); err != nil {
+			return errors.Wrap(err, "database")
+		}
+	}
+	if c.PageTokenSecret != "" && len(c.PageTokenSecret) < 16 {
+		return errors.New("database page token secret must be at least 16 bytes")
+	}
+	for _, table := range c.Tables {
+		if !tableName.MatchString(table) {
+			return errors.Errorf("database table %q is not a valid table name", table)
+		}
 	}
 	return nil
 }
 
+func (c *Config) clientOptions() []option.ClientOption {
+	if c.EmulatorHost != "" {
+		return []option.ClientOption{
+			option.WithEndpoint("http://" + c.EmulatorHost),
+			option.WithoutAuthentication(),
+		}
+	}
+	return c.credentialsOptions()
+}
+
+// readClientOptions are the options of the Storage Read and Write clients.
+func (c *Config) readClientOptions() []option.ClientOption {
+	if c.EmulatorHost != "" {
+		return []option.ClientOption{
+			option.WithEndpoint(c.EmulatorGRPCHost),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+		}
+	}
+	return c.credentialsOptions()
+}
+
+// credentialsOptions returns no options for Application Default
+// Credentials.
+func (c *Config) credentialsOptions() []option.ClientOption {
+	switch {
+	case c.CredsPath != "":
+		return []option.ClientOption{option.WithCredentialsFile(c.CredsPath)}
+	case c.CredsJSON != "":
+		return []option.ClientOption{option.WithCredentialsJSON([]byte(c.CredsJSON))}
+	default:
+		return nil
+	}
+}
+
+// insert streams data into table, retrying the whole request on a transient
+// error. Rows of a retried request may be inserted twice.
+func (c *bqClient) insert(ctx context.Context, table string, data any) error {
+	err := c.retry(ctx, "insert", func(ctx context.Context) error {
+		return c.inserter(table).Put(ctx, data)
+	})
+	c.invalidate()
+	return errors.WithStack(err)
+}
+
 func (c *bqClient) inserter(table string) *bigquery.Inserter {
 	inserter := c.client.Dataset(c.cfg.DatasetID).Table(table).Inserter()
 	inserter.SkipInvalidRows = false
@@ -0,0 +1,1090 @@
+package bqclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/grid-stream-org/go-commons/pkg/pagination"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// recordingClient records the calls made through a TypedClient.
+type recordingClient struct {
+	BQClient
+	table   string
+	id      string
+	updates map[string]any
+}
+
+func (c *recordingClient) Get(ctx context.Context, table string, id string, dst any) error {
+	c.table, c.id = table, id
+	dst.(*models.ProjectAverage).ProjectID = "p1"
+	return nil
+}
+
+func (c *recordingClient) Update(ctx context.Context, table string, id string, updates map[string]any) error {
+	c.table, c.id, c.updates = table, id, updates
+	return nil
+}
+
+// streamClient serves fixed results for instrumentation tests.
+type streamClient struct {
+	BQClient
+	blocks [][]byte
+	err    error
+}
+
+func (c *streamClient) StreamPut(ctx context.Context, table string, data any) error {
+	return c.err
+}
+
+func (c *streamClient) StreamRead(ctx context.Context, table string, filter *ReadFilter) (<-chan []byte, <-chan error) {
+	data := make(chan []byte, len(c.blocks))
+	errs := make(chan error, 1)
+	for _, b := range c.blocks {
+		data <- b
+	}
+	close(data)
+	errs <- nil
+	close(errs)
+	return data, errs
+}
+
+type ClientTestSuite struct {
+	suite.Suite
+}
+
+// normalize collapses the whitespace of a generated query.
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func (s *ClientTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json"}},
+		{name: "emulator", cfg: &Config{ProjectID: "grid", DatasetID: "prod", EmulatorHost: "localhost:9050", EmulatorGRPCHost: "localhost:9060"}},
+		{name: "emulator without grpc host", cfg: &Config{ProjectID: "grid", DatasetID: "prod", EmulatorHost: "localhost:9050"}, expectError: true},
+		{name: "emulator grpc host only", cfg: &Config{ProjectID: "grid", DatasetID: "prod", EmulatorGRPCHost: "localhost:9060"}, expectError: true},
+		{name: "read streams", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", ReadStreams: 8}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing project", cfg: &Config{DatasetID: "prod", CredsPath: "creds.json"}, expectError: true},
+		{name: "missing dataset", cfg: &Config{ProjectID: "grid", CredsPath: "creds.json"}, expectError: true},
+		{name: "application default credentials", cfg: &Config{ProjectID: "grid", DatasetID: "prod"}},
+		{name: "creds json", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsJSON: `{"type":"service_account"}`}},
+		{name: "creds path and json", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", CredsJSON: `{}`}, expectError: true},
+		{name: "invalid creds json", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsJSON: "creds.json"}, expectError: true},
+		{name: "tables", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", Tables: []string{"meter_readings"}}},
+		{name: "invalid table", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", Tables: []string{"readings; DROP TABLE projects"}}, expectError: true},
+		{name: "negative read streams", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", ReadStreams: -1}, expectError: true},
+		{name: "cache", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", Cache: &CacheConfig{TTL: time.Minute}}},
+		{name: "cache without ttl", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", Cache: &CacheConfig{MaxEntries: 10}}, expectError: true},
+		{name: "job labels", cfg: &Config{ProjectID: "grid", DatasetID: "prod", JobLabels: map[string]string{"service": "batcher", "env": "prod"}, JobIDPrefix: "batcher_"}},
+		{name: "invalid job label", cfg: &Config{ProjectID: "grid", DatasetID: "prod", JobLabels: map[string]string{"Service": "batcher"}}, expectError: true},
+		{name: "invalid job id prefix", cfg: &Config{ProjectID: "grid", DatasetID: "prod", JobIDPrefix: "batcher/"}, expectError: true},
+		{name: "page token secret", cfg: &Config{ProjectID: "grid", DatasetID: "prod", PageTokenSecret: "0123456789abcdef"}},
+		{name: "short page token secret", cfg: &Config{ProjectID: "grid", DatasetID: "prod", PageTokenSecret: "secret"}, expectError: true},
+		{name: "invalid retry", cfg: &Config{ProjectID: "grid", DatasetID: "prod", CredsPath: "creds.json", Retry: retry.Config{Jitter: 2}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestNewDefaultLogger() {
+	cfg := &Config{ProjectID: "grid", DatasetID: "prod", EmulatorHost: "localhost:9050", EmulatorGRPCHost: "localhost:9060"}
+	client, err := New(context.Background(), cfg)
+	s.Require().NoError(err)
+	defer client.Close()
+	s.NotNil(client.(*bqClient).log)
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client, err = New(context.Background(), cfg, WithLogger(log))
+	s.Require().NoError(err)
+	defer client.Close()
+	s.Same(log, client.(*bqClient).log)
+}
+
+func (s *ClientTestSuite) TestReadStreamCount() {
+	c := &bqClient{cfg: &Config{ReadStreams: 8}}
+	ctx := context.Background()
+	s.EqualValues(8, c.readStreamCount(ctx))
+	s.EqualValues(1, c.readStreamCount(WithReadStreams(ctx, 1)))
+	s.EqualValues(1, (&bqClient{cfg: &Config{}}).readStreamCount(ctx))
+}
+
+func (s *ClientTestSuite) TestTableRegistry() {
+	c := &bqClient{cfg: &Config{Tables: []string{"meter_readings"}}}
+	s.NoError(c.validateTable(models.TableProjects))
+	s.NoError(c.validateTable("meter_readings"))
+	s.ErrorIs(c.validateTable("tariffs"), errInvalidTable)
+	s.ErrorIs((&bqClient{cfg: &Config{}}).validateTable("meter_readings"), errInvalidTable)
+
+	RegisterTable("tariffs")
+	s.NoError(c.validateTable("tariffs"))
+	s.NoError((&bqClient{cfg: &Config{}}).validateTable("tariffs"))
+
+	s.Panics(func() { RegisterTable("tariffs`; --") })
+
+	c.cfg.DatasetID = "prod"
+	name, err := c.TableName("meter_readings")
+	s.NoError(err)
+	s.Equal("prod.meter_readings", name)
+	_, err = c.TableName("readings")
+	s.ErrorIs(err, errInvalidTable)
+}
+
+func (s *ClientTestSuite) TestMergeQuery() {
+	der := models.DERMetadata{ID: "ignored", ProjectID: "p1", Type: "solar", NameplateCapacity: 10}
+	query, params, err := MergeQuery("grid", "der_metadata", "der-1", der)
+	s.Require().NoError(err)
+
+	s.Contains(normalize(query), "MERGE grid.der_metadata T USING (SELECT @id AS id, @project_id AS project_id, @type AS type,")
+	s.Contains(normalize(query), "ON T.id = S.id WHEN MATCHED THEN UPDATE SET project_id = S.project_id, type = S.type,")
+	s.Contains(normalize(query), "WHEN NOT MATCHED THEN INSERT (id, project_id, type,")
+	s.Contains(normalize(query), "VALUES (S.id, S.project_id, S.type,")
+	s.NotContains(normalize(query), "id = S.id,")
+
+	s.Equal("id", params[0].Name)
+	s.Equal("der-1", params[0].Value)
+	s.Equal("project_id", params[1].Name)
+	s.Equal("p1", params[1].Value)
+}
+
+func (s *ClientTestSuite) TestMergeQueryIDOnly() {
+	query, params, err := MergeQuery("grid", "projects", "p1", struct {
+		ID string `bigquery:"id"`
+	}{ID: "p1"})
+	s.Require().NoError(err)
+	s.NotContains(query, "WHEN MATCHED")
+	s.Contains(normalize(query), "INSERT (id) VALUES (S.id)")
+	s.Len(params, 1)
+}
+
+func (s *ClientTestSuite) TestGetMultiDestination() {
+	c := &bqClient{cfg: &Config{DatasetID: "grid"}}
+	ctx := context.Background()
+
+	var contract models.Contract
+	s.ErrorContains(c.GetMulti(ctx, models.TableContracts, []string{"c1"}, &contract), "pointer to a slice")
+	s.ErrorIs(c.GetMulti(ctx, "contract", []string{"c1"}, &[]models.Contract{}), errInvalidTable)
+
+	contracts := []models.Contract{{ID: "stale"}}
+	s.Require().NoError(c.GetMulti(ctx, models.TableContracts, nil, &contracts))
+	s.Empty(contracts)
+}
+
+func (s *ClientTestSuite) TestTyped() {
+	rc := &recordingClient{}
+	averages := Typed[models.ProjectAverage](rc, models.TableProjectAverages)
+	ctx := context.Background()
+
+	avg, err := averages.Get(ctx, "a1")
+	s.Require().NoError(err)
+	s.Equal("p1", avg.ProjectID)
+	s.Equal(models.TableProjectAverages, rc.table)
+	s.Equal("a1", rc.id)
+
+	der := Typed[models.DERMetadata](rc, models.TableDERMetadata)
+	s.Require().NoError(der.Update(ctx, "der-1", models.DERMetadata{ID: "other", ProjectID: "p2", Type: "battery"}))
+	s.Equal("der-1", rc.id)
+	s.Equal("p2", rc.updates["project_id"])
+	s.Equal("battery", rc.updates["type"])
+	s.NotContains(rc.updates, "id")
+}
+
+func (s *ClientTestSuite) TestEncodeRows() {
+	schema, err := bigquery.InferSchema(models.DERData{})
+	s.Require().NoError(err)
+	desc, err := messageDescriptor(schema)
+	s.Require().NoError(err)
+
+	ts := time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC)
+	data, err := encodeRows(schema, desc, []models.DERData{
+		{DERID: "der-1", ProjectID: "p1", Timestamp: ts, CurrentOutput: 4.2, IsOnline: true},
+		{DERID: "der-2", ProjectID: "p1", Timestamp: ts},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(data, 2)
+
+	msg := dynamicpb.NewMessage(desc)
+	s.Require().NoError(proto.Unmarshal(data[0], msg))
+	field := func(name string) protoreflect.Value {
+		return msg.Get(desc.Fields().ByName(protoreflect.Name(name)))
+	}
+	s.Equal("der-1", field("der_id").String())
+	s.Equal(ts.UnixMicro(), field("timestamp").Int())
+	s.Equal(4.2, field("current_output").Float())
+	s.True(field("is_online").Bool())
+
+	single, err := encodeRows(schema, desc, &models.DERData{DERID: "der-3", Timestamp: ts})
+	s.Require().NoError(err)
+	s.Len(single, 1)
+}
+
+func (s *ClientTestSuite) TestEncodeRowsNulls() {
+	type reading struct {
+		DERID string                 `bigquery:"der_id"`
+		Note  bigquery.NullString    `bigquery:"note"`
+		Day   bigquery.NullDate      `bigquery:"day"`
+		Tags  []string               `bigquery:"tags"`
+		When  bigquery.NullTimestamp `bigquery:"when"`
+	}
+	schema, err := bigquery.InferSchema(reading{})
+	s.Require().NoError(err)
+	desc, err := messageDescriptor(schema)
+	s.Require().NoError(err)
+
+	data, err := encodeRows(schema, desc, reading{
+		DERID: "der-1",
+		Day:   bigquery.NullDate{Date: civil.Date{Year: 1970, Month: time.January, Day: 11}, Valid: true},
+		Tags:  []string{"a", "b"},
+	})
+	s.Require().NoError(err)
+
+	msg := dynamicpb.NewMessage(desc)
+	s.Require().NoError(proto.Unmarshal(data[0], msg))
+	fields := desc.Fields()
+	s.False(msg.Has(fields.ByName("note")))
+	s.False(msg.Has(fields.ByName("when")))
+	s.Equal(int64(10), msg.Get(fields.ByName("day")).Int())
+	s.Equal(2, msg.Get(fields.ByName("tags")).List().Len())
+}
+
+func (s *ClientTestSuite) TestLoadRow() {
+	ts := time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC)
+	var der models.DERData
+	s.Require().NoError(loadRow(&der, map[string]any{
+		"der_id":         "der-1",
+		"project_id":     "p1",
+		"timestamp":      ts,
+		"current_output": 4.2,
+		"baseline":       nil,
+		"unknown":        "ignored",
+	}))
+	s.Equal("der-1", der.DERID)
+	s.Equal(ts, der.Timestamp)
+	s.Equal(4.2, der.CurrentOutput)
+
+	type site struct {
+		Name string `bigquery:"name"`
+	}
+	type reading struct {
+		Count  int      `bigquery:"count"`
+		Note   *string  `bigquery:"note"`
+		Site   site     `bigquery:"site"`
+		Tags   []string `bigquery:"tags"`
+		Secret string   `bigquery:"-"`
+	}
+	var r reading
+	s.Require().NoError(loadRow(&r, map[string]any{
+		"count":  int64(3),
+		"note":   "hi",
+		"site":   map[string]any{"name": "north"},
+		"tags":   []any{"a", "b"},
+		"secret": "x",
+	}))
+	s.Equal(reading{Count: 3, Note: &[]string{"hi"}[0], Site: site{Name: "north"}, Tags: []string{"a", "b"}}, r)
+
+	s.ErrorContains(loadRow(&r, map[string]any{"count": "three"}), "column count")
+	s.Error(loadRow(r, map[string]any{}))
+
+	var m map[string]any
+	s.Require().NoError(loadRow(&m, map[string]any{"count": int64(1)}))
+	s.Equal(int64(1), m["count"])
+}
+
+func (s *ClientTestSuite) TestTableMetadata() {
+	md, err := tableMetadata(models.DERData{},
+		WithTimePartitioning("timestamp", bigquery.DayPartitioningType),
+		WithPartitionExpiration(90*24*time.Hour),
+		WithClustering("project_id", "der_id"),
+		WithDescription("DER readings"),
+	)
+	s.Require().NoError(err)
+	s.Equal("der_id", md.Schema[0].Name)
+	s.Equal(bigquery.TimestampFieldType, md.Schema[2].Type)
+	s.Equal("timestamp", md.TimePartitioning.Field)
+	s.Equal(90*24*time.Hour, md.TimePartitioning.Expiration)
+	s.Equal([]string{"project_id", "der_id"}, md.Clustering.Fields)
+	s.Equal("DER readings", md.Description)
+
+	_, err = tableMetadata(models.DERData{}, WithClustering("a", "b", "c", "d", "e"))
+	s.Error(err)
+	_, err = tableMetadata("der_data")
+	s.Error(err)
+}
+
+func (s *ClientTestSuite) TestEnsureTable() {
+	var created []string
+	exists := map[string]bool{"projects": true}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/projects/grid/datasets/prod/tables"
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, prefix+"/"):
+			table := strings.TrimPrefix(r.URL.Path, prefix+"/")
+			if !exists[table] {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":{"code":404,"message":"Not found"}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"tableReference":{"projectId":"grid","datasetId":"prod","tableId":%q}}`, table)
+		case r.Method == http.MethodPost && r.URL.Path == prefix:
+			var body struct {
+				TableReference   struct{ TableID string }
+				TimePartitioning struct{ Field string }
+			}
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+			created = append(created, body.TableReference.TableID+":"+body.TimePartitioning.Field)
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+	ctx := context.Background()
+
+	s.Require().NoError(c.EnsureTable(ctx, models.TableProjects, models.Project{}))
+	s.Require().NoError(c.EnsureTable(ctx, models.TableDERData, models.DERData{},
+		WithTimePartitioning("timestamp", bigquery.DayPartitioningType)))
+	s.Equal([]string{"der_data:timestamp"}, created)
+	s.ErrorIs(c.EnsureTable(ctx, "tariffs_v2", models.DERData{}), errInvalidTable)
+}
+
+func (s *ClientTestSuite) TestIsTransient() {
+	testCases := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "rate limited", err: &googleapi.Error{Code: 429}, transient: true},
+		{name: "unavailable", err: &googleapi.Error{Code: 503}, transient: true},
+		{name: "backend error", err: &googleapi.Error{Code: 400, Errors: []googleapi.ErrorItem{{Reason: "backendError"}}}, transient: true},
+		{name: "wrapped", err: errors.Wrap(&googleapi.Error{Code: 500}, "query"), transient: true},
+		{name: "job rate limited", err: &bigquery.Error{Reason: "jobRateLimitExceeded"}, transient: true},
+		{name: "grpc unavailable", err: status.Error(codes.Unavailable, "unavailable"), transient: true},
+		{name: "not found", err: &googleapi.Error{Code: 404}},
+		{name: "invalid query", err: &bigquery.Error{Reason: "invalidQuery"}},
+		{name: "grpc invalid argument", err: status.Error(codes.InvalidArgument, "bad filter")},
+		{name: "canceled", err: context.Canceled},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.transient, isTransient(tc.err))
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestStreamPutRetries() {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/projects/grid/datasets/prod/tables/projects/insertAll", r.URL.Path)
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":429,"message":"slow down"}}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{
+		cfg:    &Config{ProjectID: "grid", DatasetID: "prod", Retry: retry.Config{InitialInterval: time.Millisecond}},
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		client: client,
+	}
+
+	s.Require().NoError(c.StreamPut(context.Background(), "projects", &models.Project{ID: "p1"}))
+	s.Equal(2, calls)
+}
+
+func (s *ClientTestSuite) TestQueryBuilder() {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query, params, err := Select("id", "current_output").
+		From("der_data").
+		Where("project_id = ?", "p1").
+		Where("timestamp BETWEEN ? AND ?", since, since.Add(time.Hour)).
+		OrderBy("timestamp DESC", "id").
+		Limit(10).
+		Offset(20).
+		Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT id, current_output FROM prod.der_data WHERE (project_id = @p1) AND (timestamp BETWEEN @p2 AND @p3) ORDER BY timestamp DESC, id LIMIT 10 OFFSET 20", query)
+	s.Equal([]bigquery.QueryParameter{
+		{Name: "p1", Value: "p1"},
+		{Name: "p2", Value: since},
+		{Name: "p3", Value: since.Add(time.Hour)},
+	}, params)
+
+	query, params, err = Select().From("projects").Where("id IN UNNEST(?)", []string{"a", "b"}).Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT * FROM prod.projects WHERE id IN UNNEST(@p1)", query)
+	s.Len(params, 1)
+
+	query, _, err = SelectCount().From("contracts").Where("status = ?", "active").Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT COUNT(*) AS n FROM prod.contracts WHERE status = @p1", query)
+
+	invalid := map[string]*QueryBuilder{
+		"no table":       Select("id"),
+		"column":         Select("id; DROP TABLE projects").From("projects"),
+		"table":          Select().From("projects p"),
+		"args":           Select().From("projects").Where("id = ? OR id = ?", "a"),
+		"order":          Select().From("projects").OrderBy("id SIDEWAYS"),
+		"negative limit": Select().From("projects").Limit(-1),
+		"offset only":    Select().From("projects").Offset(5),
+	}
+	for name, b := range invalid {
+		s.Run(name, func() {
+			_, _, err := b.Build("prod")
+			s.Error(err)
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestListQuery() {
+	b, err := listQuery("contracts", map[string]any{
+		"status":     []string{"active", "pending"},
+		"project_id": "p1",
+		"ended_at":   nil,
+	}, ListOptions{OrderBy: []string{"start_date DESC"}, Limit: 50, Offset: 100})
+	s.Require().NoError(err)
+	query, params, err := b.Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT * FROM prod.contracts WHERE (ended_at IS NULL) AND (project_id = @p1) AND (status IN UNNEST(@p2)) ORDER BY start_date DESC LIMIT 50 OFFSET 100", query)
+	s.Equal([]bigquery.QueryParameter{
+		{Name: "p1", Value: "p1"},
+		{Name: "p2", Value: []string{"active", "pending"}},
+	}, params)
+
+	b, err = listQuery("projects", nil, ListOptions{})
+	s.Require().NoError(err)
+	query, _, err = b.Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT * FROM prod.projects", query)
+
+	_, err = listQuery("projects", map[string]any{"id = id OR 1": 1}, ListOptions{})
+	s.Error(err)
+	b, err = listQuery("projects", nil, ListOptions{OrderBy: []string{"id; DROP"}})
+	s.Require().NoError(err)
+	_, _, err = b.Build("prod")
+	s.Error(err)
+}
+
+func (s *ClientTestSuite) TestReadFilter() {
+	since := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+	restriction, err := Filter("project_id", In, []string{"p1", `x') OR TRUE OR ('`, `a\b`}).
+		And("timestamp", Ge, since).
+		And("day", Eq, civil.Date{Year: 2024, Month: 1, Day: 1}).
+		And("current_output", Gt, 1.5).
+		And("retries", Le, 3).
+		And("online", Ne, false).
+		RowRestriction()
+	s.Require().NoError(err)
+	s.Equal(`project_id IN ('p1', 'x\') OR TRUE OR (\'', 'a\\b') AND `+
+		`timestamp >= TIMESTAMP '2024-01-01 12:30:00+00:00' AND `+
+		`day = DATE '2024-01-01' AND current_output > 1.5 AND retries <= 3 AND online != FALSE`, restriction)
+
+	base := Filter("project_id", Eq, "p1")
+	first, err := base.And("online", Eq, true).RowRestriction()
+	s.Require().NoError(err)
+	second, err := base.And("retries", Gt, 0).RowRestriction()
+	s.Require().NoError(err)
+	s.Equal("project_id = 'p1' AND online = TRUE", first)
+	s.Equal("project_id = 'p1' AND retries > 0", second)
+
+	var none *ReadFilter
+	restriction, err = none.RowRestriction()
+	s.Require().NoError(err)
+	s.Empty(restriction)
+
+	invalid := map[string]*ReadFilter{
+		"field":    Filter("id OR 1=1", Eq, "a"),
+		"operator": Filter("id", Operator("LIKE"), "a"),
+		"value":    Filter("id", Eq, struct{}{}),
+		"in value": Filter("id", In, "a"),
+		"empty in": Filter("id", In, []string{}),
+		"later":    Filter("id", Eq, "a").And("id", Eq, nil),
+		"nan":      Filter("current_output", Gt, math.NaN()),
+		"inf":      Filter("current_output", In, []float64{1, math.Inf(-1)}),
+	}
+	for name, f := range invalid {
+		s.Run(name, func() {
+			_, err := f.RowRestriction()
+			s.Error(err)
+		})
+	}
+}
+
+func (s *ClientTestSuite) TestQueryPage() {
+	pages := map[string]string{
+		"":   `"rows":[{"f":[{"v":"a"}]},{"f":[{"v":"b"}]}],"pageToken":"p2"`,
+		"p2": `"rows":[{"f":[{"v":"c"}]}]`,
+	}
+	var runs int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1","location":"US"},"configuration":{"query":{"query":"SELECT id FROM prod.projects"}},"status":{"state":"DONE"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			runs++
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/queries/job1":
+			s.Equal("US", r.URL.Query().Get("location"))
+			page := r.URL.Query().Get("pageToken")
+			s.Contains(pages, page)
+			fmt.Fprintf(w, `{"jobComplete":true,"totalRows":"3","schema":{"fields":[{"name":"id","type":"STRING"}]},%s}`, pages[page])
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+	typed := Typed[struct {
+		ID string `bigquery:"id"`
+	}](c, "projects")
+	ctx := context.Background()
+
+	_, _, err = typed.QueryPage(ctx, "SELECT id FROM prod.projects", nil, 2, "")
+	s.ErrorIs(err, errNoPageSecret)
+
+	c.pages, err = pagination.NewCodec("0123456789abcdef")
+	s.Require().NoError(err)
+	rows, next, err := typed.QueryPage(ctx, "SELECT id FROM prod.projects", nil, 2, "")
+	s.Require().NoError(err)
+	s.Len(rows, 2)
+	s.Equal("b", rows[1].ID)
+	s.NotEmpty(next)
+
+	rows, next, err = typed.QueryPage(ctx, "", nil, 2, next)
+	s.Require().NoError(err)
+	s.Len(rows, 1)
+	s.Equal("c", rows[0].ID)
+	s.Empty(next)
+	s.Equal(1, runs)
+
+	_, _, err = typed.QueryPage(ctx, "", nil, 2, "not a token")
+	s.ErrorIs(err, errInvalidPageToken)
+
+	other, err := pagination.NewCodec("fedcba9876543210")
+	s.Require().NoError(err)
+	forged, err := pagination.Encode(other, pageCursor{Job: "job1", Location: "US", Page: "p2"})
+	s.Require().NoError(err)
+	_, _, err = typed.QueryPage(ctx, "", nil, 2, forged)
+	s.ErrorIs(err, errInvalidPageToken)
+}
+
+func (s *ClientTestSuite) TestDryRun() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Equal("/projects/grid/jobs", r.URL.Path)
+		var body struct {
+			Configuration struct{ DryRun bool }
+		}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		s.True(body.Configuration.DryRun)
+		fmt.Fprint(w, `{"jobReference":{"projectId":"grid"},"configuration":{"dryRun":true,"query":{"query":"SELECT"}},"status":{"state":"DONE"},"statistics":{"totalBytesProcessed":"1099511627776","query":{"totalBytesProcessed":"1099511627776"}}}`)
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+
+	stats, err := c.DryRun(context.Background(), "SELECT * FROM prod.der_data", nil)
+	s.Require().NoError(err)
+	s.EqualValues(1<<40, stats.TotalBytesProcessed)
+	s.InDelta(OnDemandPricePerTiB, EstimateCost(stats.TotalBytesProcessed), 1e-9)
+}
+
+func (s *ClientTestSuite) TestCache() {
+	var queries, jobs int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/queries":
+			queries++
+			fmt.Fprintf(w, `{"jobComplete":true,"totalRows":"1","schema":{"fields":[{"name":"id","type":"STRING"}]},"rows":[{"f":[{"v":"u%d"}]}]}`, queries)
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			jobs++
+			fmt.Fprint(w, `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"query":{"query":"DELETE"}},"status":{"state":"DONE"}}`)
+		case r.URL.Path == "/projects/grid/queries/job1":
+			fmt.Fprint(w, `{"jobComplete":true,"totalRows":"0"}`)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"query":{"query":"DELETE"}},"status":{"state":"DONE"}}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	cfg := &Config{ProjectID: "grid", DatasetID: "prod", Cache: &CacheConfig{TTL: time.Minute}}
+	c := &bqClient{cfg: cfg, client: client, cache: newCache(cfg.Cache)}
+	ctx := context.Background()
+
+	type utility struct {
+		ID string `bigquery:"id"`
+	}
+	var u utility
+	s.Require().NoError(c.Get(ctx, "utilities", "u", &u))
+	s.Equal("u1", u.ID)
+	u = utility{}
+	s.Require().NoError(c.Get(ctx, "utilities", "u", &u))
+	s.Equal("u1", u.ID)
+	s.Equal(1, queries)
+
+	var row map[string]bigquery.Value
+	s.Require().NoError(c.Get(ctx, "utilities", "u", &row))
+	s.Equal(2, queries, "results are cached per destination type")
+
+	s.Require().NoError(c.Delete(ctx, "utilities", "u"))
+	s.Equal(1, jobs)
+	s.Require().NoError(c.Get(ctx, "utilities", "u", &u))
+	s.Equal("u3", u.ID)
+}
+
+func (s *ClientTestSuite) TestInstrument() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc", Env: "dev"})
+	s.Require().NoError(err)
+	inner := &streamClient{blocks: [][]byte{make([]byte, 10), make([]byte, 5)}}
+	c, err := Instrument(inner, m)
+	s.Require().NoError(err)
+	ctx := context.Background()
+
+	s.Require().NoError(c.StreamPut(ctx, "der_data", []models.DERData{{}, {}, {}}))
+	inner.err = &googleapi.Error{Code: 503}
+	s.Error(c.StreamPut(ctx, "der_data", []models.DERData{{}}))
+	inner.err = ErrNotFound
+	s.Error(c.StreamPut(ctx, "der_data", []models.DERData{{}}))
+
+	data, errs := c.StreamRead(ctx, "der_data", nil)
+	for range data {
+	}
+	s.NoError(<-errs)
+
+	in := c.(*instrumented)
+	s.Equal(1.0, testutil.ToFloat64(in.operations.WithLabelValues("stream_put", "ok")))
+	s.Equal(1.0, testutil.ToFloat64(in.operations.WithLabelValues("stream_put", "transient")))
+	s.Equal(1.0, testutil.ToFloat64(in.operations.WithLabelValues("stream_put", "not_found")))
+	s.Equal(1.0, testutil.ToFloat64(in.operations.WithLabelValues("stream_read", "ok")))
+	s.Equal(3.0, testutil.ToFloat64(in.inserted.WithLabelValues("der_data")))
+	s.Equal(15.0, testutil.ToFloat64(in.readBytes.WithLabelValues("der_data")))
+}
+
+func (s *ClientTestSuite) TestTrace() {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	inner := &streamClient{blocks: [][]byte{make([]byte, 10), make([]byte, 5)}}
+	c := Trace(inner, tp, "prod")
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "handler")
+	s.Require().NoError(c.StreamPut(ctx, "der_data", []models.DERData{{}, {}}))
+	inner.err = ErrNotFound
+	s.Error(c.StreamPut(ctx, "der_data", []models.DERData{{}}))
+	data, errs := c.StreamRead(ctx, "der_data", nil)
+	for range data {
+	}
+	s.NoError(<-errs)
+	parent.End()
+
+	spans := recorder.Ended()
+	s.Require().Len(spans, 4)
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		out := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes() {
+			out[kv.Key] = kv.Value
+		}
+		return out
+	}
+
+	put := spans[0]
+	s.Equal("bq.StreamPut", put.Name())
+	s.Equal(parent.SpanContext().SpanID(), put.Parent().SpanID())
+	s.Equal("prod", attrs(put)["db.namespace"].AsString())
+	s.Equal("der_data", attrs(put)["db.collection.name"].AsString())
+	s.EqualValues(2, attrs(put)["bq.rows"].AsInt64())
+	s.Equal(otelcodes.Error, spans[1].Status().Code)
+
+	read := spans[2]
+	s.Equal("bq.StreamRead", read.Name())
+	s.EqualValues(15, attrs(read)["bq.bytes"].AsInt64())
+}
+
+func (s *ClientTestSuite) TestJobLabels() {
+	type request struct {
+		JobReference  struct{ JobID string }
+		Configuration struct{ Labels map[string]string }
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = request{}
+		s.Require().NoError(json.NewDecoder(r.Body).Decode(&got))
+		fmt.Fprint(w, `{"jobReference":{"projectId":"grid"},"configuration":{"dryRun":true,"query":{"query":"SELECT"}},"status":{"state":"DONE"},"statistics":{}}`)
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{
+		ProjectID:   "grid",
+		DatasetID:   "prod",
+		JobLabels:   map[string]string{"service": "batcher", "env": "dev"},
+		JobIDPrefix: "batcher_",
+	}, client: client}
+	ctx := context.Background()
+
+	_, err = c.DryRun(ctx, "SELECT 1", nil)
+	s.Require().NoError(err)
+	s.Equal(map[string]string{"service": "batcher", "env": "dev"}, got.Configuration.Labels)
+	s.True(strings.HasPrefix(got.JobReference.JobID, "batcher_"), got.JobReference.JobID)
+
+	ctx = WithJobIDPrefix(WithJobLabels(ctx, map[string]string{"env": "prod", "job": "backfill"}), "backfill_")
+	_, err = c.DryRun(ctx, "SELECT 1", nil)
+	s.Require().NoError(err)
+	s.Equal(map[string]string{"service": "batcher", "env": "prod", "job": "backfill"}, got.Configuration.Labels)
+	s.True(strings.HasPrefix(got.JobReference.JobID, "backfill_"), got.JobReference.JobID)
+
+	_, err = c.DryRun(WithJobLabels(ctx, map[string]string{"Bad": "x"}), "SELECT 1", nil)
+	s.Error(err)
+}
+
+func (s *ClientTestSuite) TestAuditColumns() {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-time.Hour)
+	type row struct {
+		ID        string    `bigquery:"id"`
+		CreatedAt time.Time `bigquery:"created_at"`
+		UpdatedAt time.Time `bigquery:"updated_at"`
+	}
+
+	off := &bqClient{now: time.Now}
+	in := &row{ID: "r1"}
+	s.Same(in, off.stampRow(in))
+
+	c := &bqClient{}
+	WithAuditColumns()(c)
+	WithClock(func() time.Time { return now })(c)
+
+	got := c.stampRow(row{ID: "r1"}).(*row)
+	s.Equal(row{ID: "r1", CreatedAt: now, UpdatedAt: now}, *got)
+
+	in = &row{ID: "r1", CreatedAt: created}
+	got = c.stampRow(in).(*row)
+	s.Equal(row{ID: "r1", CreatedAt: created, UpdatedAt: now}, *got)
+	s.True(in.UpdatedAt.IsZero())
+
+	noAudit := &struct {
+		ID string `bigquery:"id"`
+	}{ID: "r1"}
+	s.Same(noAudit, c.stampRow(noAudit))
+
+	updates := map[string]any{"location": "Halifax", "created_at": time.Time{}, "updated_at": nil}
+	s.Equal(map[string]any{"location": "Halifax", "updated_at": now}, c.stampUpdates(updates))
+	s.Len(updates, 3)
+
+	updates = map[string]any{"location": "Halifax"}
+	s.Equal(updates, c.stampUpdates(updates))
+	updates = map[string]any{"updated_at": created}
+	s.Equal(updates, c.stampUpdates(updates))
+}
+
+func (s *ClientTestSuite) TestMulti() {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"query":{"query":"DML"}},"status":{"state":"DONE"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			var body struct {
+				Configuration struct {
+					Query struct{ Query string }
+				}
+			}
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+			queries = append(queries, normalize(body.Configuration.Query.Query))
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/queries/job1":
+			fmt.Fprint(w, `{"jobComplete":true,"totalRows":"0"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+	ctx := context.Background()
+
+	ids := []string{"e1", "e2"}
+	s.Require().NoError(c.UpdateMulti(ctx, "dr_events", ids, map[string]any{"status": "expired", "end_time": time.Unix(0, 0)}))
+	s.Require().NoError(c.DeleteMulti(ctx, "dr_events", ids))
+	s.Require().NoError(c.DeleteMulti(ctx, "dr_events", nil))
+	s.Error(c.UpdateMulti(ctx, "dr_events", ids, nil))
+	s.Equal([]string{
+		"UPDATE prod.dr_events SET end_time = @end_time, status = @status WHERE id IN UNNEST(@ids)",
+		"DELETE FROM prod.dr_events WHERE id IN UNNEST(@ids)",
+	}, queries)
+}
+
+func (s *ClientTestSuite) TestExecScript() {
+	const script = `BEGIN TRANSACTION; INSERT INTO prod.projects (id) VALUES (@id); INSERT INTO prod.contracts (id) VALUES (@id); COMMIT TRANSACTION;`
+	var parent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"query":{"query":"SCRIPT"}},` +
+			`"status":{"state":"DONE","errorResult":{"reason":"invalidQuery","message":"Not found: Table grid:prod.contracts"}}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			fmt.Fprint(w, job)
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/grid/jobs":
+			parent = r.URL.Query().Get("parentJobId")
+			fmt.Fprint(w, `{"jobs":[`+
+				`{"jobReference":{"projectId":"grid","jobId":"child2"},"status":{"state":"DONE","errorResult":{"reason":"invalidQuery","message":"Not found: Table grid:prod.contracts"}},`+
+				`"statistics":{"scriptStatistics":{"stackFrames":[{"startLine":1,"startColumn":66,"text":"INSERT INTO prod.contracts (id) VALUES (@id)"}]}}},`+
+				`{"jobReference":{"projectId":"grid","jobId":"child1"},"status":{"state":"DONE"}}]}`)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/queries/job1":
+			fmt.Fprint(w, `{"jobComplete":true,"totalRows":"0"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client, log: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	err = c.ExecScript(context.Background(), script, []bigquery.QueryParameter{{Name: "id", Value: "p1"}})
+	var scriptErr *ScriptError
+	s.Require().ErrorAs(err, &scriptErr)
+	s.Equal("job1", parent)
+	s.Require().Len(scriptErr.Statements, 1)
+	stmt := scriptErr.Statements[0]
+	s.Equal(int64(1), stmt.Line)
+	s.Equal(int64(66), stmt.Column)
+	s.Equal("INSERT INTO prod.contracts (id) VALUES (@id)", stmt.Text)
+	s.ErrorContains(err, "line 1, column 66")
+	s.ErrorContains(err, "prod.contracts")
+}
+
+func (s *ClientTestSuite) TestLoadFromGCS() {
+	type request struct {
+		Configuration struct {
+			Labels map[string]string
+			Load   struct {
+				SourceUris       []string
+				SourceFormat     string
+				SkipLeadingRows  int64
+				WriteDisposition string
+				DestinationTable struct{ ProjectID, DatasetID, TableID string }
+			}
+		}
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"load":{}},"status":{"state":"DONE"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&got))
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod", JobLabels: map[string]string{"job": "backfill"}}, client: client}
+	ctx := context.Background()
+
+	s.Require().NoError(c.LoadFromGCS(ctx, models.TableDERData, "gs://grid-backfill/der_data/*.csv", bigquery.CSV,
+		WithSkipLeadingRows(1), WithLoadWriteDisposition(bigquery.WriteTruncate)))
+	load := got.Configuration.Load
+	s.Equal([]string{"gs://grid-backfill/der_data/*.csv"}, load.SourceUris)
+	s.Equal("CSV", load.SourceFormat)
+	s.Equal(int64(1), load.SkipLeadingRows)
+	s.Equal("WRITE_TRUNCATE", load.WriteDisposition)
+	s.Equal("der_data", load.DestinationTable.TableID)
+	s.Equal("prod", load.DestinationTable.DatasetID)
+	s.Equal(map[string]string{"job": "backfill"}, got.Configuration.Labels)
+
+	s.Require().NoError(c.LoadFromGCS(ctx, models.TableDERData, "gs://grid-backfill/der_data/*.avro", bigquery.Avro))
+	s.Equal("WRITE_APPEND", got.Configuration.Load.WriteDisposition)
+
+	s.ErrorIs(c.LoadFromGCS(ctx, "der", "gs://grid-backfill/x.avro", bigquery.Avro), errInvalidTable)
+}
+
+func (s *ClientTestSuite) TestExtractToGCS() {
+	var got struct {
+		Configuration struct {
+			Extract struct {
+				DestinationUris   []string
+				DestinationFormat string
+				SourceTable       struct{ DatasetID, TableID string }
+			}
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"extract":{}},"status":{"state":"DONE"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&got))
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+
+	s.Require().NoError(c.ExtractToGCS(context.Background(), models.TableProjectAverages, "gs://grid-exports/averages/*.parquet", bigquery.Parquet))
+	extract := got.Configuration.Extract
+	s.Equal([]string{"gs://grid-exports/averages/*.parquet"}, extract.DestinationUris)
+	s.Equal("PARQUET", extract.DestinationFormat)
+	s.Equal("prod", extract.SourceTable.DatasetID)
+	s.Equal(models.TableProjectAverages, extract.SourceTable.TableID)
+}
+
+func (s *ClientTestSuite) TestCopyTable() {
+	var got struct {
+		Configuration struct {
+			Copy struct {
+				SourceTables     []struct{ DatasetID, TableID string }
+				DestinationTable struct{ DatasetID, TableID string }
+				WriteDisposition string
+			}
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const job = `{"jobReference":{"projectId":"grid","jobId":"job1"},"configuration":{"copy":{}},"status":{"state":"DONE"}}`
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/grid/jobs":
+			s.Require().NoError(json.NewDecoder(r.Body).Decode(&got))
+			fmt.Fprint(w, job)
+		case r.URL.Path == "/projects/grid/jobs/job1":
+			fmt.Fprint(w, job)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := bigquery.NewClient(context.Background(), "grid",
+		option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	c := &bqClient{cfg: &Config{ProjectID: "grid", DatasetID: "prod"}, client: client}
+	ctx := context.Background()
+
+	s.Require().NoError(c.CopyTable(ctx, models.TableDERData, "der_data_20240601", bigquery.WriteEmpty))
+	cp := got.Configuration.Copy
+	s.Require().Len(cp.SourceTables, 1)
+	s.Equal(models.TableDERData, cp.SourceTables[0].TableID)
+	s.Equal("der_data_20240601", cp.DestinationTable.TableID)
+	s.Equal("prod", cp.DestinationTable.DatasetID)
+	s.Equal("WRITE_EMPTY", cp.WriteDisposition)
+
+	s.ErrorIs(c.CopyTable(ctx, "der", "der_copy", bigquery.WriteEmpty), errInvalidTable)
+	s.ErrorIs(c.CopyTable(ctx, models.TableDERData, "der_data; DROP", bigquery.WriteEmpty), errInvalidTable)
+}
+
+func (s *ClientTestSuite) TestSnapshotTime() {
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	snap := WithSnapshotTime(ctx, at)
+
+	query, params, err := Select("id").From("projects").Where("utility_id = ?", "u1").AsOf(at).Build("prod")
+	s.Require().NoError(err)
+	s.Equal("SELECT id FROM prod.projects FOR SYSTEM_TIME AS OF @snapshot_time WHERE utility_id = @p1", query)
+	s.Equal([]bigquery.QueryParameter{
+		{Name: "p1", Value: "u1"},
+		{Name: "snapshot_time", Value: at},
+	}, params)
+
+	s.Empty(asOf(ctx))
+	s.Equal(" FOR SYSTEM_TIME AS OF @snapshot_time", asOf(snap))
+	s.Empty(asOf(WithSnapshotTime(ctx, time.Time{})))
+
+	const q = "SELECT * FROM prod.projects FOR SYSTEM_TIME AS OF @snapshot_time WHERE id = @id"
+	own := []bigquery.QueryParameter{{Name: "id", Value: "p1"}}
+	s.Equal(own, withSnapshot(ctx, q, own))
+	s.Equal(own, withSnapshot(snap, "SELECT * FROM prod.projects WHERE id = @id", own))
+	s.Equal(append(own, bigquery.QueryParameter{Name: "snapshot_time", Value: at}), withSnapshot(snap, q, own))
+	s.Len(own, 1)
+	explicit := []bigquery.QueryParameter{{Name: "snapshot_time", Value: at.Add(-time.Hour)}}
+	s.Equal(explicit, withSnapshot(snap, q, explicit))
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
@@ -0,0 +1,54 @@
+package bqclient
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// OnDemandPricePerTiB is BigQuery's on-demand query price in US dollars.
+const OnDemandPricePerTiB = 6.25
+
+// DryRun validates query without running it and returns its statistics,
+// whose TotalBytesProcessed is what the query would scan:
+//
+//	stats, err := bq.DryRun(ctx, query, params)
+//	...
+//	if stats.TotalBytesProcessed > maxScan {
+//		return errors.Errorf("query would scan %d bytes, $%.2f", stats.TotalBytesProcessed,
+//			bqclient.EstimateCost(stats.TotalBytesProcessed))
+//	}
+func (c *bqClient) DryRun(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.JobStatistics, error) {
+	q, err := c.newQuery(ctx, query, withSnapshot(ctx, query, params))
+	if err != nil {
+		return nil, err
+	}
+	q.DryRun = true
+
+	var job *bigquery.Job
+	err = c.retry(ctx, "dry_run", func(ctx context.Context) error {
+		var err error
+		job, err = q.Run(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if status.Statistics == nil {
+		return nil, errors.New("dry run returned no statistics")
+	}
+	return status.Statistics, nil
+}
+
+// EstimateCost returns the on-demand price in US dollars of scanning bytes.
+// BigQuery bills at least 10 MiB per table referenced, which is not
+// accounted for.
+func EstimateCost(bytes int64) float64 {
+	return float64(bytes) / (1 << 40) * OnDemandPricePerTiB
+}
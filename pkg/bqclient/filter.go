@@ -0,0 +1,168 @@
+package bqclient
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/pkg/errors"
+)
+
+// Operator compares a column with a value in a ReadFilter.
+type Operator string
+
+const (
+	Eq Operator = "="
+	Ne Operator = "!="
+	Lt Operator = "<"
+	Le Operator = "<="
+	Gt Operator = ">"
+	Ge Operator = ">="
+	// In matches any element of a slice value.
+	In Operator = "IN"
+)
+
+// ReadFilter restricts the rows returned by StreamRead and StreamReadRows.
+// Values are rendered as escaped literals, never spliced in as SQL:
+//
+//	filter := bqclient.Filter("project_id", bqclient.In, projectIDs).
+//		And("timestamp", bqclient.Ge, since)
+//	data, errs := bq.StreamRead(ctx, models.TableDERData, filter)
+//
+// Values may be strings, integers, floats, bools, time.Time, civil.Date,
+// or for In a slice of those. A nil *ReadFilter reads every row. Errors
+// are reported when the filter is used.
+type ReadFilter struct {
+	conds []string
+	err   error
+}
+
+// Filter starts a filter with a single condition.
+func Filter(field string, op Operator, value any) *ReadFilter {
+	return (&ReadFilter{}).And(field, op, value)
+}
+
+// And returns a copy of f with a condition that rows must also meet, so a
+// base filter can be extended more than once. A nil f starts a new filter.
+func (f *ReadFilter) And(field string, op Operator, value any) *ReadFilter {
+	if f == nil {
+		f = &ReadFilter{}
+	}
+	if f.err != nil {
+		return f
+	}
+	cond, err := condition(field, op, value)
+	if err != nil {
+		return &ReadFilter{err: errors.Wrapf(err, "filter on %s", field)}
+	}
+	return &ReadFilter{conds: append(slices.Clip(f.conds), cond)}
+}
+
+// RowRestriction returns the filter as a Storage Read API row restriction,
+// or "" for a nil filter.
+func (f *ReadFilter) RowRestriction() (string, error) {
+	if f == nil {
+		return "", nil
+	}
+	if f.err != nil {
+		return "", f.err
+	}
+	return strings.Join(f.conds, " AND "), nil
+}
+
+func condition(field string, op Operator, value any) (string, error) {
+	if !tableName.MatchString(field) {
+		return "", errors.New("invalid field name")
+	}
+
+	switch op {
+	case Eq, Ne, Lt, Le, Gt, Ge:
+		lit, err := literal(value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", field, op, lit), nil
+	case In:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return "", errors.Errorf("IN needs a slice, got %T", value)
+		}
+		if v.Len() == 0 {
+			return "", errors.New("IN needs at least one value")
+		}
+		lits := make([]string, v.Len())
+		for i := range v.Len() {
+			lit, err := literal(v.Index(i).Interface())
+			if err != nil {
+				return "", err
+			}
+			lits[i] = lit
+		}
+		return fmt.Sprintf("%s IN (%s)", field, strings.Join(lits, ", ")), nil
+	default:
+		return "", errors.Errorf("unknown operator %q", op)
+	}
+}
+
+// literal renders value as a GoogleSQL literal.
+func literal(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return quote(v), nil
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v)), nil
+	case time.Time:
+		return "TIMESTAMP " + quote(v.UTC().Format("2006-01-02 15:04:05.999999-07:00")), nil
+	case civil.Date:
+		return "DATE " + quote(v.String()), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.CanInt():
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case rv.CanUint():
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case rv.CanFloat():
+		// GoogleSQL has no literal for NaN or infinities.
+		if f := rv.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", errors.Errorf("unsupported float value %v", f)
+		}
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64), nil
+	}
+	return "", errors.Errorf("unsupported value type %T", value)
+}
+
+// quote returns s as a single-quoted string literal with backslashes,
+// quotes and control characters escaped.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
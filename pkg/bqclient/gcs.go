@@ -0,0 +1,91 @@
+package bqclient
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// LoadOption configures a load job run by LoadFromGCS.
+type LoadOption func(*bigquery.Loader)
+
+// WithLoadWriteDisposition sets what a load does to rows already in the
+// table. It defaults to bigquery.WriteAppend.
+func WithLoadWriteDisposition(d bigquery.TableWriteDisposition) LoadOption {
+	return func(l *bigquery.Loader) {
+		l.WriteDisposition = d
+	}
+}
+
+// WithSkipLeadingRows skips header rows at the top of each CSV file.
+func WithSkipLeadingRows(n int64) LoadOption {
+	return func(l *bigquery.Loader) {
+		if ref, ok := l.Src.(*bigquery.GCSReference); ok {
+			ref.SkipLeadingRows = n
+		}
+	}
+}
+
+// WithLoadSchema reads files that carry no schema, such as CSV and JSON,
+// with schema rather than the table's. bigquery.InferSchema builds one from
+// a model struct.
+func WithLoadSchema(schema bigquery.Schema) LoadOption {
+	return func(l *bigquery.Loader) {
+		if ref, ok := l.Src.(*bigquery.GCSReference); ok {
+			ref.Schema = schema
+		}
+	}
+}
+
+// LoadFromGCS appends the files at gcsURI, which may end in a * wildcard,
+// to table and waits for the load job to finish:
+//
+//	err := bq.LoadFromGCS(ctx, models.TableDERData,
+//		"gs://grid-backfill/der_data/2023-*.avro", bigquery.Avro)
+func (c *bqClient) LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...LoadOption) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+	labels, jobID, err := c.jobConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	src := bigquery.NewGCSReference(gcsURI)
+	src.SourceFormat = format
+	loader := c.client.Dataset(c.cfg.DatasetID).Table(table).LoaderFrom(src)
+	loader.WriteDisposition = bigquery.WriteAppend
+	loader.Labels = labels
+	loader.JobIDConfig = jobID
+	for _, opt := range opts {
+		opt(loader)
+	}
+
+	defer c.invalidate()
+	return c.runJob(ctx, "load", loader.Run)
+}
+
+// ExtractToGCS exports table to gcsURI in format, one of bigquery.CSV,
+// bigquery.Avro, bigquery.Parquet or bigquery.JSON, and waits for the
+// extract job to finish. Tables over 1 GB must be exported to a URI with a
+// * wildcard, which BigQuery replaces with a file number:
+//
+//	err := bq.ExtractToGCS(ctx, models.TableProjectAverages,
+//		"gs://grid-exports/project_averages/*.csv", bigquery.CSV)
+func (c *bqClient) ExtractToGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+	labels, jobID, err := c.jobConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	dst := bigquery.NewGCSReference(gcsURI)
+	dst.DestinationFormat = format
+	extractor := c.client.Dataset(c.cfg.DatasetID).Table(table).ExtractorTo(dst)
+	extractor.Labels = labels
+	extractor.JobIDConfig = jobID
+
+	return c.runJob(ctx, "extract", extractor.Run)
+}
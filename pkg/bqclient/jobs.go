@@ -0,0 +1,132 @@
+package bqclient
+
+import (
+	"context"
+	"maps"
+	"regexp"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+)
+
+var (
+	labelKey    = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,62}$`)
+	labelValue  = regexp.MustCompile(`^[a-z0-9_-]{0,63}$`)
+	jobIDPrefix = regexp.MustCompile(`^[A-Za-z0-9_-]{0,256}$`)
+)
+
+type jobOptionsKey struct{}
+
+// jobOptions label the query jobs of one call.
+type jobOptions struct {
+	labels map[string]string
+	prefix string
+}
+
+// WithJobLabels returns a copy of ctx whose BigQuery jobs carry labels in
+// addition to Config.JobLabels, overriding those with the same key.
+// Invalid labels make the call fail.
+func WithJobLabels(ctx context.Context, labels map[string]string) context.Context {
+	opts := jobOptionsFrom(ctx)
+	merged := maps.Clone(opts.labels)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	maps.Copy(merged, labels)
+	opts.labels = merged
+	return context.WithValue(ctx, jobOptionsKey{}, opts)
+}
+
+// WithJobIDPrefix returns a copy of ctx whose BigQuery job IDs start with
+// prefix instead of Config.JobIDPrefix.
+func WithJobIDPrefix(ctx context.Context, prefix string) context.Context {
+	opts := jobOptionsFrom(ctx)
+	opts.prefix = prefix
+	return context.WithValue(ctx, jobOptionsKey{}, opts)
+}
+
+func jobOptionsFrom(ctx context.Context) jobOptions {
+	opts, _ := ctx.Value(jobOptionsKey{}).(jobOptions)
+	return opts
+}
+
+func validateJobLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if !labelKey.MatchString(k) {
+			return errors.Errorf("invalid job label key %q", k)
+		}
+		if !labelValue.MatchString(v) {
+			return errors.Errorf("invalid job label value %q for %s", v, k)
+		}
+	}
+	return nil
+}
+
+func validateJobIDPrefix(prefix string) error {
+	if !jobIDPrefix.MatchString(prefix) {
+		return errors.Errorf("invalid job ID prefix %q", prefix)
+	}
+	return nil
+}
+
+// newQuery returns a query labelled from the config and ctx.
+func (c *bqClient) newQuery(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.Query, error) {
+	labels, jobID, err := c.jobConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := c.client.Query(query)
+	q.Parameters = params
+	q.Labels = labels
+	q.JobIDConfig = jobID
+	return q, nil
+}
+
+// jobConfig returns the labels and job ID settings of a job started with
+// ctx.
+func (c *bqClient) jobConfig(ctx context.Context) (map[string]string, bigquery.JobIDConfig, error) {
+	opts := jobOptionsFrom(ctx)
+	if err := validateJobLabels(opts.labels); err != nil {
+		return nil, bigquery.JobIDConfig{}, err
+	}
+	prefix := c.cfg.JobIDPrefix
+	if opts.prefix != "" {
+		if err := validateJobIDPrefix(opts.prefix); err != nil {
+			return nil, bigquery.JobIDConfig{}, err
+		}
+		prefix = opts.prefix
+	}
+
+	var labels map[string]string
+	if len(c.cfg.JobLabels) > 0 || len(opts.labels) > 0 {
+		labels = maps.Clone(c.cfg.JobLabels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, opts.labels)
+	}
+	var jobID bigquery.JobIDConfig
+	if prefix != "" {
+		jobID = bigquery.JobIDConfig{JobID: prefix, AddJobIDSuffix: true}
+	}
+	return labels, jobID, nil
+}
+
+// runJob starts a job with run and waits for it. A job that failed applied
+// nothing, so it is safe to run again. One we lost track of while waiting
+// may have succeeded and is not.
+func (c *bqClient) runJob(ctx context.Context, op string, run func(ctx context.Context) (*bigquery.Job, error)) error {
+	err := c.retry(ctx, op, func(ctx context.Context) error {
+		job, err := run(ctx)
+		if err != nil {
+			return err
+		}
+		status, err := job.Wait(ctx)
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		return status.Err()
+	})
+	return errors.WithStack(err)
+}
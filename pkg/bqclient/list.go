@@ -0,0 +1,159 @@
+package bqclient
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// ListOptions sort and bound the rows returned by List.
+type ListOptions struct {
+	// OrderBy lists columns, each optionally followed by ASC or DESC.
+	OrderBy []string
+	// Limit caps the rows returned; zero means no cap.
+	Limit int
+	// Offset skips rows before the first returned and requires a Limit.
+	Offset int
+}
+
+// List sets dst, a pointer to a slice, to the rows of table matching every
+// column = value pair of filter:
+//
+//	var contracts []models.Contract
+//	err := bq.List(ctx, models.TableContracts,
+//		map[string]any{"project_id": projectID, "status": []string{"active", "pending"}},
+//		bqclient.ListOptions{OrderBy: []string{"start_date DESC"}, Limit: 50},
+//		&contracts)
+//
+// A slice value matches any of its elements and a nil value matches NULL.
+// Values are passed as query parameters.
+func (c *bqClient) List(ctx context.Context, table string, filter map[string]any, opts ListOptions, dst any) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("dst must be a pointer to a slice, got %T", dst)
+	}
+
+	b, err := listQuery(table, filter, opts)
+	if err != nil {
+		return err
+	}
+	if t, ok := snapshotFrom(ctx); ok {
+		b.AsOf(t)
+	}
+	query, params, err := b.Build(c.cfg.DatasetID)
+	if err != nil {
+		return err
+	}
+
+	return c.cached(query, params, dst, func() error {
+		it, err := c.execute(ctx, query, params, true)
+		if err != nil {
+			return err
+		}
+		rows, err := appendRows(it, reflect.MakeSlice(v.Elem().Type(), 0, 0), false)
+		if err != nil {
+			return err
+		}
+		v.Elem().Set(rows)
+		return nil
+	})
+}
+
+// Count returns how many rows of table match filter, as for List.
+func (c *bqClient) Count(ctx context.Context, table string, filter map[string]any) (int64, error) {
+	if err := c.validateTable(table); err != nil {
+		return 0, err
+	}
+
+	b := SelectCount().From(table)
+	if err := where(b, filter); err != nil {
+		return 0, err
+	}
+	if t, ok := snapshotFrom(ctx); ok {
+		b.AsOf(t)
+	}
+	query, params, err := b.Build(c.cfg.DatasetID)
+	if err != nil {
+		return 0, err
+	}
+
+	var row struct {
+		N int64 `bigquery:"n"`
+	}
+	if err := c.QueryRow(ctx, query, params, &row); err != nil {
+		return 0, err
+	}
+	return row.N, nil
+}
+
+// Exists reports whether table has a row with the given id.
+func (c *bqClient) Exists(ctx context.Context, table string, id string) (bool, error) {
+	if err := c.validateTable(table); err != nil {
+		return false, err
+	}
+
+	query := fmt.Sprintf(`
+        SELECT EXISTS(SELECT 1 FROM %s.%s%s WHERE id = @id) AS found`,
+		c.cfg.DatasetID,
+		table,
+		asOf(ctx),
+	)
+
+	params := []bigquery.QueryParameter{
+		{Name: "id", Value: id},
+	}
+
+	var row struct {
+		Found bool `bigquery:"found"`
+	}
+	if err := c.QueryRow(ctx, query, params, &row); err != nil {
+		return false, err
+	}
+	return row.Found, nil
+}
+
+// listQuery builds the query of List.
+func listQuery(table string, filter map[string]any, opts ListOptions) (*QueryBuilder, error) {
+	b := Select().From(table)
+	if err := where(b, filter); err != nil {
+		return nil, err
+	}
+	if len(opts.OrderBy) > 0 {
+		b.OrderBy(opts.OrderBy...)
+	}
+	return b.Limit(opts.Limit).Offset(opts.Offset), nil
+}
+
+// where adds a condition to b for each column of filter, in sorted order
+// so that equal filters share cache entries.
+func where(b *QueryBuilder, filter map[string]any) error {
+	columns := make([]string, 0, len(filter))
+	for col := range filter {
+		columns = append(columns, col)
+	}
+	slices.Sort(columns)
+
+	for _, col := range columns {
+		if !tableName.MatchString(col) {
+			return errors.Errorf("invalid filter column %q", col)
+		}
+		val := filter[col]
+		switch rv := reflect.ValueOf(val); {
+		case val == nil:
+			b.Where(col + " IS NULL")
+		case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8:
+			b.Where(col+" IN UNNEST(?)", val)
+		default:
+			b.Where(col+" = ?", val)
+		}
+	}
+	return nil
+}
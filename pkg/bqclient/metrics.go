@@ -0,0 +1,299 @@
+package bqclient
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type instrumented struct {
+	BQClient
+	operations *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+	inserted   *prometheus.CounterVec
+	readBytes  *prometheus.CounterVec
+	readRows   *prometheus.CounterVec
+}
+
+// Instrument wraps c so that every operation is recorded in m:
+//
+//   - <namespace>_bq_operations_total by op and result, which is ok,
+//     transient for errors worth retrying, or the gserrors code of any
+//     other error
+//   - <namespace>_bq_operation_duration_seconds by op; for the StreamRead
+//     methods, until the stream ends
+//   - <namespace>_bq_rows_inserted_total by table
+//   - <namespace>_bq_stream_read_bytes_total and
+//     <namespace>_bq_stream_read_rows_total by table
+//
+// NewStreamWriter is passed through; its appends are not recorded.
+func Instrument(c BQClient, m *metrics.Metrics) (BQClient, error) {
+	ops, err := m.NewCounterVec("bq", "operations_total", "BigQuery operations by operation and result.", "op", "result")
+	if err != nil {
+		return nil, err
+	}
+	duration, err := m.NewHistogramVec("bq", "operation_duration_seconds", "BigQuery operation latency.", prometheus.DefBuckets, "op")
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := m.NewCounterVec("bq", "rows_inserted_total", "Rows inserted into BigQuery by table.", "table")
+	if err != nil {
+		return nil, err
+	}
+	readBytes, err := m.NewCounterVec("bq", "stream_read_bytes_total", "Bytes read through the Storage Read API by table.", "table")
+	if err != nil {
+		return nil, err
+	}
+	readRows, err := m.NewCounterVec("bq", "stream_read_rows_total", "Rows read through the Storage Read API by table.", "table")
+	if err != nil {
+		return nil, err
+	}
+	return &instrumented{
+		BQClient:   c,
+		operations: ops,
+		duration:   duration,
+		inserted:   inserted,
+		readBytes:  readBytes,
+		readRows:   readRows,
+	}, nil
+}
+
+func (c *instrumented) observe(op string, start time.Time, err error) {
+	c.duration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	c.operations.WithLabelValues(op, resultOf(err)).Inc()
+}
+
+func resultOf(err error) string {
+	switch {
+	case err == nil:
+		return "ok"
+	case isTransient(err):
+		return "transient"
+	default:
+		return string(gserrors.CodeOf(err))
+	}
+}
+
+// rowCount returns how many rows data holds for an insert.
+func rowCount(data any) int {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return v.Len()
+	}
+	return 1
+}
+
+func (c *instrumented) Put(ctx context.Context, table string, data any) error {
+	start := time.Now()
+	err := c.BQClient.Put(ctx, table, data)
+	c.observe("put", start, err)
+	if err == nil {
+		c.inserted.WithLabelValues(table).Inc()
+	}
+	return err
+}
+
+func (c *instrumented) StreamPut(ctx context.Context, table string, data any) error {
+	start := time.Now()
+	err := c.BQClient.StreamPut(ctx, table, data)
+	c.observe("stream_put", start, err)
+	if err == nil {
+		c.inserted.WithLabelValues(table).Add(float64(rowCount(data)))
+	}
+	return err
+}
+
+func (c *instrumented) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
+	start := time.Now()
+	err := c.BQClient.StreamPutAll(ctx, inputs)
+	c.observe("stream_put_all", start, err)
+	if err == nil {
+		for table, rows := range inputs {
+			c.inserted.WithLabelValues(table).Add(float64(len(rows)))
+		}
+	}
+	return err
+}
+
+func (c *instrumented) StreamRead(ctx context.Context, table string, filter *ReadFilter) (<-chan []byte, <-chan error) {
+	start := time.Now()
+	data, errs := c.BQClient.StreamRead(ctx, table, filter)
+	return forward(ctx, data, errs, func(block []byte) {
+		c.readBytes.WithLabelValues(table).Add(float64(len(block)))
+	}, func(err error) {
+		c.observe("stream_read", start, err)
+	})
+}
+
+func (c *instrumented) StreamReadRows(ctx context.Context, table string, filter *ReadFilter, newRow func() any) (<-chan any, <-chan error) {
+	start := time.Now()
+	rows, errs := c.BQClient.StreamReadRows(ctx, table, filter, newRow)
+	return forward(ctx, rows, errs, func(any) {
+		c.readRows.WithLabelValues(table).Inc()
+	}, func(err error) {
+		c.observe("stream_read_rows", start, err)
+	})
+}
+
+// forward relays a StreamRead result, calling each for every value and done
+// with the stream's error once it ends. Values are dropped once ctx is done
+// so that the inner stream is still drained.
+func forward[T any](ctx context.Context, data <-chan T, errs <-chan error, each func(T), done func(error)) (<-chan T, <-chan error) {
+	out := make(chan T)
+	outErrs := make(chan error, 1)
+	go func() {
+		defer close(outErrs)
+		defer close(out)
+		for v := range data {
+			each(v)
+			select {
+			case out <- v:
+			case <-ctx.Done():
+			}
+		}
+		err := <-errs
+		done(err)
+		if err != nil {
+			outErrs <- err
+		}
+	}()
+	return out, outErrs
+}
+
+func (c *instrumented) Query(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
+	start := time.Now()
+	it, err := c.BQClient.Query(ctx, query, params)
+	c.observe("query", start, err)
+	return it, err
+}
+
+func (c *instrumented) QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error {
+	start := time.Now()
+	err := c.BQClient.QueryRow(ctx, query, params, dst)
+	c.observe("query_row", start, err)
+	return err
+}
+
+func (c *instrumented) QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string, dst any) (string, error) {
+	start := time.Now()
+	next, err := c.BQClient.QueryPage(ctx, query, params, pageSize, pageToken, dst)
+	c.observe("query_page", start, err)
+	return next, err
+}
+
+func (c *instrumented) DryRun(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.JobStatistics, error) {
+	start := time.Now()
+	stats, err := c.BQClient.DryRun(ctx, query, params)
+	c.observe("dry_run", start, err)
+	return stats, err
+}
+
+func (c *instrumented) ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error {
+	start := time.Now()
+	err := c.BQClient.ExecScript(ctx, script, params)
+	c.observe("exec_script", start, err)
+	return err
+}
+
+func (c *instrumented) Update(ctx context.Context, table string, id string, updates map[string]any) error {
+	start := time.Now()
+	err := c.BQClient.Update(ctx, table, id, updates)
+	c.observe("update", start, err)
+	return err
+}
+
+func (c *instrumented) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	start := time.Now()
+	err := c.BQClient.UpdateMulti(ctx, table, ids, updates)
+	c.observe("update_multi", start, err)
+	return err
+}
+
+func (c *instrumented) Upsert(ctx context.Context, table string, id string, data any) error {
+	start := time.Now()
+	err := c.BQClient.Upsert(ctx, table, id, data)
+	c.observe("upsert", start, err)
+	return err
+}
+
+func (c *instrumented) Delete(ctx context.Context, table string, id string) error {
+	start := time.Now()
+	err := c.BQClient.Delete(ctx, table, id)
+	c.observe("delete", start, err)
+	return err
+}
+
+func (c *instrumented) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	start := time.Now()
+	err := c.BQClient.DeleteMulti(ctx, table, ids)
+	c.observe("delete_multi", start, err)
+	return err
+}
+
+func (c *instrumented) Get(ctx context.Context, table string, id string, dst any) error {
+	start := time.Now()
+	err := c.BQClient.Get(ctx, table, id, dst)
+	c.observe("get", start, err)
+	return err
+}
+
+func (c *instrumented) GetMulti(ctx context.Context, table string, ids []string, dst any) error {
+	start := time.Now()
+	err := c.BQClient.GetMulti(ctx, table, ids, dst)
+	c.observe("get_multi", start, err)
+	return err
+}
+
+func (c *instrumented) List(ctx context.Context, table string, filter map[string]any, opts ListOptions, dst any) error {
+	start := time.Now()
+	err := c.BQClient.List(ctx, table, filter, opts, dst)
+	c.observe("list", start, err)
+	return err
+}
+
+func (c *instrumented) Count(ctx context.Context, table string, filter map[string]any) (int64, error) {
+	start := time.Now()
+	n, err := c.BQClient.Count(ctx, table, filter)
+	c.observe("count", start, err)
+	return n, err
+}
+
+func (c *instrumented) Exists(ctx context.Context, table string, id string) (bool, error) {
+	start := time.Now()
+	found, err := c.BQClient.Exists(ctx, table, id)
+	c.observe("exists", start, err)
+	return found, err
+}
+
+func (c *instrumented) EnsureTable(ctx context.Context, table string, model any, opts ...TableOption) error {
+	start := time.Now()
+	err := c.BQClient.EnsureTable(ctx, table, model, opts...)
+	c.observe("ensure_table", start, err)
+	return err
+}
+
+func (c *instrumented) LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...LoadOption) error {
+	start := time.Now()
+	err := c.BQClient.LoadFromGCS(ctx, table, gcsURI, format, opts...)
+	c.observe("load_from_gcs", start, err)
+	return err
+}
+
+func (c *instrumented) ExtractToGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat) error {
+	start := time.Now()
+	err := c.BQClient.ExtractToGCS(ctx, table, gcsURI, format)
+	c.observe("extract_to_gcs", start, err)
+	return err
+}
+
+func (c *instrumented) CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	start := time.Now()
+	err := c.BQClient.CopyTable(ctx, srcTable, dstTable, writeDisposition)
+	c.observe("copy_table", start, err)
+	return err
+}
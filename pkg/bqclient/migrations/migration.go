@@ -0,0 +1,144 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DatasetPlaceholder in migration SQL is replaced with the configured
+// dataset ID, so the same files apply to every environment.
+const DatasetPlaceholder = "{{dataset}}"
+
+// Migration is one versioned schema change. Down is optional; a migration
+// without it cannot be reverted.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the Up script so edits to applied migrations are
+// detected.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads migrations from the root of fsys. Files are named
+// <version>_<name>.up.sql and, optionally, <version>_<name>.down.sql, for
+// example 0003_add_dr_events.up.sql. Other files are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "reading migrations")
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(m[1])
+		b, err := fs.ReadFile(fsys, path.Join(".", e.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", e.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, errors.Errorf("migration %d has files with different names: %s and %s", version, mig.Name, m[2])
+		}
+
+		if m[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, errors.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []Migration
+)
+
+// Register adds migrations defined in Go to the package registry, for
+// services that keep them beside the code that needs them rather than in
+// SQL files:
+//
+//	func init() {
+//		migrations.Register(migrations.Migration{
+//			Version: 4,
+//			Name:    "add_dr_events",
+//			Up:      "CREATE TABLE {{dataset}}.dr_events (...)",
+//		})
+//	}
+//
+// It panics if a version is registered twice.
+func Register(migrations ...Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, m := range migrations {
+		if slices.ContainsFunc(registry, func(r Migration) bool { return r.Version == m.Version }) {
+			panic("migrations: version " + strconv.Itoa(m.Version) + " registered twice")
+		}
+		registry = append(registry, m)
+	}
+}
+
+// Registered returns the registered migrations in version order, to pass to
+// New alone or appended to those from Load.
+func Registered() []Migration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	ms := slices.Clone(registry)
+	slices.SortFunc(ms, func(a, b Migration) int { return a.Version - b.Version })
+	return ms
+}
+
+func validate(migrations []Migration) error {
+	seen := map[int]bool{}
+	for _, m := range migrations {
+		if m.Version <= 0 {
+			return errors.Errorf("migration %s: version must be greater than 0", m.Name)
+		}
+		if seen[m.Version] {
+			return errors.Errorf("duplicate migration version %d", m.Version)
+		}
+		if strings.TrimSpace(m.Up) == "" {
+			return errors.Errorf("migration %d has no up script", m.Version)
+		}
+		seen[m.Version] = true
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeStore struct {
+	records map[int]record
+	execs   []string
+	failOn  string
+}
+
+func (f *fakeStore) ensure(ctx context.Context) error { return nil }
+
+func (f *fakeStore) applied(ctx context.Context) (map[int]record, error) {
+	out := map[int]record{}
+	for k, v := range f.records {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (f *fakeStore) exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error {
+	if f.failOn != "" && sql == f.failOn {
+		return errors.New("boom")
+	}
+	f.execs = append(f.execs, sql)
+	return nil
+}
+
+func (f *fakeStore) insert(ctx context.Context, r record) error {
+	f.records[int(r.Version)] = r
+	return nil
+}
+
+func (f *fakeStore) remove(ctx context.Context, version int) error {
+	delete(f.records, version)
+	return nil
+}
+
+type MigrationsTestSuite struct {
+	suite.Suite
+	store *fakeStore
+	m     *Migrator
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_projects", Up: "CREATE TABLE {{dataset}}.projects (id STRING)", Down: "DROP TABLE {{dataset}}.projects"},
+	{Version: 2, Name: "create_dr_events", Up: "CREATE TABLE {{dataset}}.dr_events (id STRING)", Down: "DROP TABLE {{dataset}}.dr_events"},
+	{Version: 3, Name: "add_column", Up: "ALTER TABLE {{dataset}}.projects ADD COLUMN name STRING"},
+}
+
+func (s *MigrationsTestSuite) SetupTest() {
+	s.store = &fakeStore{records: map[int]record{}}
+	m, err := newMigrator(&Config{DatasetID: "gs"}, s.store, testMigrations, logger.Default())
+	s.Require().NoError(err)
+	s.m = m
+}
+
+func (s *MigrationsTestSuite) TestLoad() {
+	fsys := fstest.MapFS{
+		"0002_create_dr_events.up.sql":  {Data: []byte("CREATE TABLE b")},
+		"0001_create_projects.up.sql":   {Data: []byte("CREATE TABLE a")},
+		"0001_create_projects.down.sql": {Data: []byte("DROP TABLE a")},
+		"README.md":                     {Data: []byte("ignored")},
+	}
+
+	ms, err := Load(fsys)
+	s.Require().NoError(err)
+	s.Require().Len(ms, 2)
+	s.Equal(Migration{Version: 1, Name: "create_projects", Up: "CREATE TABLE a", Down: "DROP TABLE a"}, ms[0])
+	s.Equal(2, ms[1].Version)
+	s.Empty(ms[1].Down)
+
+	_, err = Load(fstest.MapFS{"0001_a.down.sql": {Data: []byte("DROP TABLE a")}})
+	s.Error(err)
+
+	_, err = Load(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("CREATE TABLE a")},
+		"0001_b.down.sql": {Data: []byte("DROP TABLE a")},
+	})
+	s.Error(err)
+}
+
+func (s *MigrationsTestSuite) TestRegister() {
+	Register(testMigrations[2], testMigrations[0])
+	s.T().Cleanup(func() { registry = nil })
+
+	ms := Registered()
+	s.Require().Len(ms, 2)
+	s.Equal(1, ms[0].Version)
+	s.Equal(3, ms[1].Version)
+	s.Panics(func() { Register(testMigrations[0]) })
+
+	m, err := newMigrator(&Config{DatasetID: "gs"}, s.store, Registered(), logger.Default())
+	s.Require().NoError(err)
+	applied, err := m.Up(context.Background())
+	s.Require().NoError(err)
+	s.Len(applied, 2)
+}
+
+func (s *MigrationsTestSuite) TestNewRejectsDuplicates() {
+	_, err := newMigrator(&Config{DatasetID: "gs"}, s.store, []Migration{testMigrations[0], testMigrations[0]}, logger.Default())
+	s.Error(err)
+}
+
+func (s *MigrationsTestSuite) TestConfigValidate() {
+	tests := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{DatasetID: "gs"}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing dataset", cfg: &Config{}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			err := tt.cfg.Validate()
+			if tt.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *MigrationsTestSuite) TestUp() {
+	ctx := context.Background()
+
+	applied, err := s.m.Up(ctx)
+	s.Require().NoError(err)
+	s.Len(applied, 3)
+	s.Equal("CREATE TABLE gs.projects (id STRING)", s.store.execs[0])
+	s.Len(s.store.records, 3)
+
+	applied, err = s.m.Up(ctx)
+	s.Require().NoError(err)
+	s.Empty(applied)
+}
+
+func (s *MigrationsTestSuite) TestUpTargetAndDryRun() {
+	ctx := context.Background()
+
+	applied, err := s.m.Up(ctx, WithDryRun())
+	s.Require().NoError(err)
+	s.Len(applied, 3)
+	s.Empty(s.store.execs)
+	s.Empty(s.store.records)
+
+	applied, err = s.m.Up(ctx, WithTarget(2))
+	s.Require().NoError(err)
+	s.Len(applied, 2)
+
+	statuses, err := s.m.Status(ctx)
+	s.Require().NoError(err)
+	s.True(statuses[1].Applied)
+	s.False(statuses[2].Applied)
+}
+
+func (s *MigrationsTestSuite) TestUpStopsOnFailure() {
+	s.store.failOn = "CREATE TABLE gs.dr_events (id STRING)"
+
+	applied, err := s.m.Up(context.Background())
+	s.Error(err)
+	s.Len(applied, 1)
+	s.Len(s.store.records, 1)
+}
+
+func (s *MigrationsTestSuite) TestUpDetectsModified() {
+	ctx := context.Background()
+	_, err := s.m.Up(ctx, WithTarget(1))
+	s.Require().NoError(err)
+
+	r := s.store.records[1]
+	r.Checksum = "stale"
+	s.store.records[1] = r
+
+	statuses, err := s.m.Status(ctx)
+	s.Require().NoError(err)
+	s.True(statuses[0].Modified)
+
+	_, err = s.m.Up(ctx)
+	s.Error(err)
+}
+
+func (s *MigrationsTestSuite) TestDown() {
+	ctx := context.Background()
+	_, err := s.m.Up(ctx, WithTarget(2))
+	s.Require().NoError(err)
+
+	reverted, err := s.m.Down(ctx, 1)
+	s.Require().NoError(err)
+	s.Require().Len(reverted, 1)
+	s.Equal(2, reverted[0].Version)
+	s.Equal("DROP TABLE gs.dr_events", s.store.execs[len(s.store.execs)-1])
+	s.Len(s.store.records, 1)
+
+	_, err = s.m.Up(ctx, WithTarget(2))
+	s.Require().NoError(err)
+	reverted, err = s.m.Down(ctx, 0, WithTarget(1))
+	s.Require().NoError(err)
+	s.Require().Len(reverted, 1)
+	s.Equal(2, reverted[0].Version)
+
+	_, err = s.m.Up(ctx, WithTarget(2))
+	s.Require().NoError(err)
+
+	reverted, err = s.m.Down(ctx, 5)
+	s.Require().NoError(err)
+	s.Len(reverted, 2)
+	s.Empty(s.store.records)
+}
+
+func (s *MigrationsTestSuite) TestDownWithoutScript() {
+	ctx := context.Background()
+	_, err := s.m.Up(ctx)
+	s.Require().NoError(err)
+
+	_, err = s.m.Down(ctx, 1)
+	s.Error(err)
+	s.Len(s.store.records, 3)
+}
+
+func TestMigrationsSuite(t *testing.T) {
+	suite.Run(t, new(MigrationsTestSuite))
+}
@@ -0,0 +1,299 @@
+// Package migrations applies versioned DDL migrations to a BigQuery dataset
+// and records which versions have run in a tracking table, replacing
+// hand-run SQL scripts. Migrations are usually embedded in the service:
+//
+//	//go:embed migrations/*.sql
+//	var files embed.FS
+//
+//	sub, _ := fs.Sub(files, "migrations")
+//	ms, err := migrations.Load(sub)
+//	m, err := migrations.New(client, &migrations.Config{DatasetID: "grid_stream"}, ms, log)
+//	applied, err := m.Up(ctx)
+//
+// Migrations can also be defined in Go with Register and passed to New
+// from Registered.
+//
+// BigQuery DDL is not transactional, so a migration that fails part way may
+// need manual cleanup; keep each migration to a single statement where
+// possible.
+package migrations
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+const DefaultTable = "schema_migrations"
+
+type Config struct {
+	DatasetID string `koanf:"dataset_id" json:"dataset_id" envconfig:"dataset_id"`
+	// Table records applied versions. Defaults to DefaultTable.
+	Table string `koanf:"table" json:"table" envconfig:"table"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("migrations configuration required")
+	}
+	if c.DatasetID == "" {
+		return errors.New("migrations dataset ID required")
+	}
+	return nil
+}
+
+// Status is the state of one known migration.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	// Modified is set when an applied migration's Up script no longer matches
+	// what was run.
+	Modified bool
+}
+
+type Option func(*runOptions)
+
+type runOptions struct {
+	dryRun bool
+	target int
+}
+
+// WithDryRun logs and returns the migrations that would run without
+// executing them.
+func WithDryRun() Option {
+	return func(o *runOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithTarget stops Up after the given version, or Down once only versions up
+// to and including it remain applied.
+func WithTarget(version int) Option {
+	return func(o *runOptions) {
+		o.target = version
+	}
+}
+
+type Migrator struct {
+	cfg        *Config
+	store      store
+	migrations []Migration
+	log        *slog.Logger
+}
+
+// record is a row of the tracking table.
+type record struct {
+	Version   int64     `bigquery:"version"`
+	Name      string    `bigquery:"name"`
+	Checksum  string    `bigquery:"checksum"`
+	AppliedAt time.Time `bigquery:"applied_at"`
+}
+
+// store abstracts the tracking table and statement execution.
+type store interface {
+	ensure(ctx context.Context) error
+	applied(ctx context.Context) (map[int]record, error)
+	exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error
+	insert(ctx context.Context, r record) error
+	remove(ctx context.Context, version int) error
+}
+
+func New(client bqclient.BQClient, cfg *Config, migrations []Migration, log *slog.Logger) (*Migrator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	table := cfg.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	return newMigrator(cfg, &bqStore{client: client, table: cfg.DatasetID + "." + table}, migrations, log)
+}
+
+func newMigrator(cfg *Config, s store, migrations []Migration, log *slog.Logger) (*Migrator, error) {
+	if err := validate(migrations); err != nil {
+		return nil, err
+	}
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return a.Version - b.Version })
+	return &Migrator{cfg: cfg, store: s, migrations: sorted, log: log}, nil
+}
+
+// Status reports every known migration in version order.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.store.ensure(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.store.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = Status{Migration: mig}
+		if r, ok := applied[mig.Version]; ok {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = r.AppliedAt
+			statuses[i].Modified = r.Checksum != mig.Checksum()
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies pending migrations in version order and returns those applied.
+// It refuses to run if an applied migration has since been modified.
+func (m *Migrator) Up(ctx context.Context, opts ...Option) ([]Migration, error) {
+	o := runOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range statuses {
+		if s.Modified {
+			return nil, errors.Errorf("migration %d_%s was modified after being applied", s.Version, s.Name)
+		}
+		if !s.Applied && (o.target == 0 || s.Version <= o.target) {
+			pending = append(pending, s.Migration)
+		}
+	}
+
+	var done []Migration
+	for _, mig := range pending {
+		m.log.Info("applying migration", "version", mig.Version, "name", mig.Name, "dry_run", o.dryRun)
+		if !o.dryRun {
+			if err := m.store.exec(ctx, m.render(mig.Up), nil); err != nil {
+				return done, errors.Wrapf(err, "applying migration %d_%s", mig.Version, mig.Name)
+			}
+			err := m.store.insert(ctx, record{
+				Version:   int64(mig.Version),
+				Name:      mig.Name,
+				Checksum:  mig.Checksum(),
+				AppliedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return done, errors.Wrapf(err, "recording migration %d_%s", mig.Version, mig.Name)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+// Down reverts the most recently applied migrations, newest first. steps
+// limits how many are reverted; WithTarget reverts down to a version instead.
+func (m *Migrator) Down(ctx context.Context, steps int, opts ...Option) ([]Migration, error) {
+	o := runOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var revert []Migration
+	for i := len(statuses) - 1; i >= 0; i-- {
+		s := statuses[i]
+		if !s.Applied {
+			continue
+		}
+		if o.target > 0 && s.Version <= o.target {
+			break
+		}
+		if o.target == 0 && len(revert) == steps {
+			break
+		}
+		if strings.TrimSpace(s.Down) == "" {
+			return nil, errors.Errorf("migration %d_%s has no down script", s.Version, s.Name)
+		}
+		revert = append(revert, s.Migration)
+	}
+
+	var done []Migration
+	for _, mig := range revert {
+		m.log.Info("reverting migration", "version", mig.Version, "name", mig.Name, "dry_run", o.dryRun)
+		if !o.dryRun {
+			if err := m.store.exec(ctx, m.render(mig.Down), nil); err != nil {
+				return done, errors.Wrapf(err, "reverting migration %d_%s", mig.Version, mig.Name)
+			}
+			if err := m.store.remove(ctx, mig.Version); err != nil {
+				return done, errors.Wrapf(err, "unrecording migration %d_%s", mig.Version, mig.Name)
+			}
+		}
+		done = append(done, mig)
+	}
+	return done, nil
+}
+
+func (m *Migrator) render(sql string) string {
+	return strings.ReplaceAll(sql, DatasetPlaceholder, m.cfg.DatasetID)
+}
+
+type bqStore struct {
+	client bqclient.BQClient
+	table  string
+}
+
+func (s *bqStore) ensure(ctx context.Context) error {
+	return s.exec(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+        version INT64 NOT NULL,
+        name STRING NOT NULL,
+        checksum STRING NOT NULL,
+        applied_at TIMESTAMP NOT NULL
+    )`, nil)
+}
+
+func (s *bqStore) applied(ctx context.Context) (map[int]record, error) {
+	it, err := s.client.Query(ctx, `SELECT version, name, checksum, applied_at FROM `+s.table, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+
+	applied := map[int]record{}
+	for {
+		var r record
+		err := it.Next(&r)
+		if err == iterator.Done {
+			return applied, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading applied migrations")
+		}
+		applied[int(r.Version)] = r
+	}
+}
+
+func (s *bqStore) exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error {
+	return bqclient.Exec(ctx, s.client, sql, params)
+}
+
+func (s *bqStore) insert(ctx context.Context, r record) error {
+	return s.exec(ctx, `INSERT INTO `+s.table+` (version, name, checksum, applied_at)
+        VALUES (@version, @name, @checksum, @applied_at)`, []bigquery.QueryParameter{
+		{Name: "version", Value: r.Version},
+		{Name: "name", Value: r.Name},
+		{Name: "checksum", Value: r.Checksum},
+		{Name: "applied_at", Value: r.AppliedAt},
+	})
+}
+
+func (s *bqStore) remove(ctx context.Context, version int) error {
+	return s.exec(ctx, `DELETE FROM `+s.table+` WHERE version = @version`, []bigquery.QueryParameter{
+		{Name: "version", Value: int64(version)},
+	})
+}
@@ -0,0 +1,98 @@
+package bqclient
+
+import (
+	"context"
+	"reflect"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/pagination"
+	"github.com/pkg/errors"
+)
+
+var (
+	errInvalidPageToken = gserrors.New(gserrors.InvalidInput, "invalid page token")
+	errNoPageSecret     = errors.New("database page token secret required for QueryPage")
+)
+
+// pageCursor locates a page of a finished query job's results.
+type pageCursor struct {
+	Job      string `json:"j"`
+	Location string `json:"l,omitempty"`
+	Page     string `json:"p"`
+}
+
+// QueryPage reads one page of up to pageSize rows of query into dst, a
+// pointer to a slice of structs, and returns the token of the next page, or
+// "" after the last. With an empty pageToken the query is run; with a token
+// from an earlier call the page is read from that call's results, and query
+// and params are ignored. Pages may hold fewer than pageSize rows.
+//
+// Tokens are signed with Config.PageTokenSecret, and a token that was not
+// issued by a client sharing the secret is rejected. A token stays valid as
+// long as BigQuery keeps the job's results, normally a day.
+func (c *bqClient) QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string, dst any) (string, error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return "", errors.Errorf("dst must be a pointer to a slice, got %T", dst)
+	}
+	if pageSize <= 0 {
+		return "", errors.New("page size must be positive")
+	}
+	if c.pages == nil {
+		return "", errNoPageSecret
+	}
+
+	job, token, err := c.pageJob(ctx, query, withSnapshot(ctx, query, params), pageToken)
+	if err != nil {
+		return "", err
+	}
+	it, err := job.Read(ctx)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	it.PageInfo().MaxSize = pageSize
+	it.PageInfo().Token = token.Page
+
+	rows, err := appendRows(it, reflect.MakeSlice(v.Elem().Type(), 0, pageSize), true)
+	if err != nil {
+		return "", err
+	}
+	v.Elem().Set(rows)
+
+	if it.PageInfo().Token == "" {
+		return "", nil
+	}
+	token.Page = it.PageInfo().Token
+	return pagination.Encode(c.pages, token)
+}
+
+// pageJob runs query, or looks up the job named by pageToken.
+func (c *bqClient) pageJob(ctx context.Context, query string, params []bigquery.QueryParameter, pageToken string) (*bigquery.Job, pageCursor, error) {
+	if pageToken != "" {
+		token, err := pagination.Decode[pageCursor](c.pages, pageToken)
+		if err != nil || token.Job == "" {
+			return nil, token, errInvalidPageToken
+		}
+		job, err := c.client.JobFromIDLocation(ctx, token.Job, token.Location)
+		if err != nil {
+			return nil, token, errors.WithStack(err)
+		}
+		return job, token, nil
+	}
+
+	q, err := c.newQuery(ctx, query, params)
+	if err != nil {
+		return nil, pageCursor{}, err
+	}
+	var job *bigquery.Job
+	err = c.retry(ctx, "query", func(ctx context.Context) error {
+		var err error
+		job, err = q.Run(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, pageCursor{}, errors.WithStack(err)
+	}
+	return job, pageCursor{Job: job.ID(), Location: job.Location()}, nil
+}
@@ -0,0 +1,64 @@
+package bqclient
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// transientReasons are the BigQuery error reasons worth retrying.
+var transientReasons = map[string]bool{
+	"backendError":         true,
+	"internalError":        true,
+	"rateLimitExceeded":    true,
+	"jobRateLimitExceeded": true,
+}
+
+// isTransient reports whether err is a BigQuery error that may succeed if
+// retried: a rate limit or backend failure from the REST API or a job, or
+// an unavailable Storage API.
+func isTransient(err error) bool {
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) {
+		switch gerr.Code {
+		case 429, 500, 502, 503, 504:
+			return true
+		}
+		for _, e := range gerr.Errors {
+			if transientReasons[e.Reason] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var berr *bigquery.Error
+	if errors.As(err, &berr) {
+		return transientReasons[berr.Reason]
+	}
+
+	if s, ok := status.FromError(errors.Cause(err)); ok {
+		switch s.Code() {
+		case codes.Unavailable, codes.ResourceExhausted:
+			return true
+		}
+	}
+	return false
+}
+
+// retry calls fn with the configured policy, retrying transient errors.
+// op names the call in the log.
+func (c *bqClient) retry(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	return retry.Do(ctx, fn, append(c.cfg.Retry.Options(),
+		retry.WithRetryIf(isTransient),
+		retry.WithOnRetry(func(attempt int, err error, delay time.Duration) {
+			c.log.Warn("retrying bigquery operation", "op", op, "attempt", attempt, "delay", delay, "error", err)
+		}),
+	)...)
+}
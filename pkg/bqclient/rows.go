@@ -0,0 +1,115 @@
+package bqclient
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var fieldCache sync.Map // reflect.Type -> map[string][]int
+
+// columnsOf maps the lower-cased column name of each exported field of t to
+// its index. Names come from the bigquery tag, or the field name without
+// one, and match case-insensitively as they do for Query.
+func columnsOf(t reflect.Type) map[string][]int {
+	if cols, ok := fieldCache.Load(t); ok {
+		return cols.(map[string][]int)
+	}
+
+	cols := map[string][]int{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("bigquery"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		cols[strings.ToLower(name)] = sf.Index
+	}
+
+	fieldCache.Store(t, cols)
+	return cols
+}
+
+// loadRow copies the columns of a decoded row into dst, a pointer to a
+// struct or to a map[string]any. Columns without a field are ignored and
+// NULLs leave the field zero.
+func loadRow(dst any, row map[string]any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.Errorf("row destination must be a non-nil pointer, got %T", dst)
+	}
+	if m, ok := dst.(*map[string]any); ok {
+		*m = row
+		return nil
+	}
+	if v.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("row destination must point to a struct, got %T", dst)
+	}
+	return loadStruct(v.Elem(), row)
+}
+
+func loadStruct(v reflect.Value, row map[string]any) error {
+	cols := columnsOf(v.Type())
+	for name, val := range row {
+		index, ok := cols[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		if err := setValue(v.FieldByIndex(index), val); err != nil {
+			return errors.Wrapf(err, "column %s", name)
+		}
+	}
+	return nil
+}
+
+func setValue(f reflect.Value, val any) error {
+	if val == nil {
+		f.SetZero()
+		return nil
+	}
+	if f.Kind() == reflect.Pointer {
+		p := reflect.New(f.Type().Elem())
+		if err := setValue(p.Elem(), val); err != nil {
+			return err
+		}
+		f.Set(p)
+		return nil
+	}
+
+	rv := reflect.ValueOf(val)
+	switch {
+	case rv.Type().AssignableTo(f.Type()):
+		f.Set(rv)
+	case f.Kind() == reflect.Struct && rv.Kind() == reflect.Map:
+		m, ok := val.(map[string]any)
+		if !ok {
+			return errors.Errorf("cannot load %T into %s", val, f.Type())
+		}
+		return loadStruct(f, m)
+	case f.Kind() == reflect.Slice && rv.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(f.Type(), rv.Len(), rv.Len())
+		for i := range rv.Len() {
+			if err := setValue(s.Index(i), rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+	case isNumber(rv.Kind()) && isNumber(f.Kind()):
+		f.Set(rv.Convert(f.Type()))
+	default:
+		return errors.Errorf("cannot load %T into %s", val, f.Type())
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return reflect.Int <= k && k <= reflect.Float64
+}
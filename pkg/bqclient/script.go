@@ -0,0 +1,138 @@
+package bqclient
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// StatementError is the failure of one statement of a script.
+type StatementError struct {
+	Line   int64
+	Column int64
+	// Text is the statement as written in the script.
+	Text string
+	Err  error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// ScriptError is returned by ExecScript when a script fails. Statements
+// holds the failed statements in script order, when BigQuery reports them.
+type ScriptError struct {
+	Err        error
+	Statements []*StatementError
+}
+
+func (e *ScriptError) Error() string {
+	if len(e.Statements) == 0 {
+		return "script failed: " + e.Err.Error()
+	}
+	msgs := make([]string, len(e.Statements))
+	for i, s := range e.Statements {
+		msgs[i] = s.Error()
+	}
+	return "script failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ExecScript runs a multi-statement script, such as writes to several
+// tables wrapped in BEGIN TRANSACTION and COMMIT TRANSACTION, and waits for
+// it to finish:
+//
+//	err := bq.ExecScript(ctx, `
+//		BEGIN TRANSACTION;
+//		INSERT INTO prod.projects (id, utility_id) VALUES (@id, @utility_id);
+//		INSERT INTO prod.contracts (id, project_id) VALUES (@contract_id, @id);
+//		COMMIT TRANSACTION;`, params)
+//
+// A failed script returns a *ScriptError. Scripts are not retried, since
+// statements outside a transaction may have applied before a failure.
+func (c *bqClient) ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error {
+	q, err := c.newQuery(ctx, script, params)
+	if err != nil {
+		return err
+	}
+
+	defer c.invalidate()
+	job, err := q.Run(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := status.Err(); err != nil {
+		return &ScriptError{Err: err, Statements: c.statementErrors(ctx, job)}
+	}
+	return nil
+}
+
+// Exec runs a single DDL or DML statement through c.Query and waits for it
+// to finish. Unlike ExecScript it is retried like any other query, so the
+// statement must be safe to repeat.
+func Exec(ctx context.Context, c BQClient, sql string, params []bigquery.QueryParameter) error {
+	it, err := c.Query(ctx, sql, params)
+	if err != nil {
+		return err
+	}
+	// Reading waits for the job, so statement errors surface here.
+	var row []bigquery.Value
+	if err := it.Next(&row); err != nil && err != iterator.Done {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// statementErrors returns the failures of the child jobs of a script, or
+// nil if they cannot be listed.
+func (c *bqClient) statementErrors(ctx context.Context, script *bigquery.Job) []*StatementError {
+	it := c.client.Jobs(ctx)
+	it.ProjectID = script.ProjectID()
+	it.ParentJobID = script.ID()
+
+	var failed []*StatementError
+	for {
+		job, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			c.log.Warn("listing script statements", "job", script.ID(), "error", err)
+			return nil
+		}
+		status := job.LastStatus()
+		if status == nil || status.Err() == nil {
+			continue
+		}
+		se := &StatementError{Err: status.Err()}
+		if stats := status.Statistics; stats != nil && stats.ScriptStatistics != nil && len(stats.ScriptStatistics.StackFrames) > 0 {
+			frame := stats.ScriptStatistics.StackFrames[0]
+			se.Line, se.Column, se.Text = frame.StartLine, frame.StartColumn, frame.Text
+		}
+		failed = append(failed, se)
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		if failed[i].Line != failed[j].Line {
+			return failed[i].Line < failed[j].Line
+		}
+		return failed[i].Column < failed[j].Column
+	})
+	return failed
+}
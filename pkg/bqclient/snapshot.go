@@ -0,0 +1,58 @@
+package bqclient
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// snapshotParam names the query parameter holding the snapshot time.
+const snapshotParam = "snapshot_time"
+
+type snapshotKey struct{}
+
+// WithSnapshotTime returns a copy of ctx whose reads see tables as they
+// were at t, which BigQuery allows up to seven days back:
+//
+//	ctx = bqclient.WithSnapshotTime(ctx, time.Now().Add(-time.Hour))
+//	err := bq.Get(ctx, models.TableProjects, id, &before)
+//
+// Get, GetMulti, List, Count, Exists and the StreamRead methods read at t
+// on their own. SQL given to Query, QueryRow, QueryPage and DryRun refers
+// to t as @snapshot_time, as in FOR SYSTEM_TIME AS OF @snapshot_time. Writes
+// are not affected.
+func WithSnapshotTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, t)
+}
+
+func snapshotFrom(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(snapshotKey{}).(time.Time)
+	return t, ok && !t.IsZero()
+}
+
+// asOf returns the clause that follows a table name to read it at the
+// snapshot time of ctx, if any.
+func asOf(ctx context.Context) string {
+	if _, ok := snapshotFrom(ctx); !ok {
+		return ""
+	}
+	return " FOR SYSTEM_TIME AS OF @" + snapshotParam
+}
+
+// withSnapshot adds the snapshot time of ctx to params when query refers to
+// it and params do not already hold it.
+func withSnapshot(ctx context.Context, query string, params []bigquery.QueryParameter) []bigquery.QueryParameter {
+	t, ok := snapshotFrom(ctx)
+	if !ok || !strings.Contains(query, "@"+snapshotParam) {
+		return params
+	}
+	for _, p := range params {
+		if p.Name == snapshotParam {
+			return params
+		}
+	}
+	return append(slices.Clip(params), bigquery.QueryParameter{Name: snapshotParam, Value: t})
+}
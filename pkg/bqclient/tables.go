@@ -0,0 +1,175 @@
+package bqclient
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// Table names are interpolated into queries, so only plain identifiers are
+// accepted.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var (
+	tablesMu    sync.RWMutex
+	validTables = map[string]bool{}
+)
+
+func init() {
+	RegisterTable(models.Tables()...)
+}
+
+// RegisterTable allows every client to use the named tables, for services
+// with tables of their own. It is meant to be called from init and panics
+// on a name that is not a plain identifier. Tables for a single client can
+// instead be listed in Config.Tables.
+func RegisterTable(tables ...string) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+
+	for _, table := range tables {
+		if !tableName.MatchString(table) {
+			panic("bqclient: invalid table name " + table)
+		}
+		validTables[table] = true
+	}
+}
+
+func (c *bqClient) validateTable(table string) error {
+	tablesMu.RLock()
+	ok := validTables[table]
+	tablesMu.RUnlock()
+
+	if !ok && !slices.Contains(c.cfg.Tables, table) {
+		return errors.Wrapf(errInvalidTable, "table %s not found in schema", table)
+	}
+	return nil
+}
+
+// TableName returns table qualified with the client's dataset, for SQL run
+// through Query. It fails for a table the client does not allow.
+func (c *bqClient) TableName(table string) (string, error) {
+	if err := c.validateTable(table); err != nil {
+		return "", err
+	}
+	return c.cfg.DatasetID + "." + table, nil
+}
+
+// TableOption configures a table created by EnsureTable.
+type TableOption func(*bigquery.TableMetadata)
+
+// WithTimePartitioning partitions the table by field, a TIMESTAMP or DATE
+// column, at the given granularity. An empty field partitions by ingestion
+// time.
+func WithTimePartitioning(field string, typ bigquery.TimePartitioningType) TableOption {
+	return func(md *bigquery.TableMetadata) {
+		md.TimePartitioning = &bigquery.TimePartitioning{Field: field, Type: typ}
+	}
+}
+
+// WithPartitionExpiration deletes partitions once they are older than d. It
+// requires WithTimePartitioning.
+func WithPartitionExpiration(d time.Duration) TableOption {
+	return func(md *bigquery.TableMetadata) {
+		if md.TimePartitioning != nil {
+			md.TimePartitioning.Expiration = d
+		}
+	}
+}
+
+// WithClustering clusters the table by up to four columns.
+func WithClustering(fields ...string) TableOption {
+	return func(md *bigquery.TableMetadata) {
+		md.Clustering = &bigquery.Clustering{Fields: fields}
+	}
+}
+
+func WithDescription(description string) TableOption {
+	return func(md *bigquery.TableMetadata) {
+		md.Description = description
+	}
+}
+
+// EnsureTable creates table with a schema inferred from model, a struct
+// with bigquery tags, unless it already exists. An existing table is left
+// as it is, even if its schema differs.
+func (c *bqClient) EnsureTable(ctx context.Context, table string, model any, opts ...TableOption) error {
+	if err := c.validateTable(table); err != nil {
+		return err
+	}
+
+	md, err := tableMetadata(model, opts...)
+	if err != nil {
+		return err
+	}
+
+	t := c.client.Dataset(c.cfg.DatasetID).Table(table)
+	if _, err := t.Metadata(ctx); err == nil {
+		return nil
+	} else if !isHTTPStatus(err, http.StatusNotFound) {
+		return errors.Wrapf(err, "checking table %s", table)
+	}
+
+	if err := t.Create(ctx, md); err != nil && !isHTTPStatus(err, http.StatusConflict) {
+		return errors.Wrapf(err, "creating table %s", table)
+	}
+	return nil
+}
+
+// CopyTable copies srcTable to dstTable, creating it if needed, and waits
+// for the copy job to finish. writeDisposition says what to do when dstTable
+// has rows: bigquery.WriteEmpty fails, WriteTruncate replaces them and
+// WriteAppend adds to them. dstTable need not be registered, so that
+// snapshots can be taken under any name:
+//
+//	err := bq.CopyTable(ctx, models.TableDERData, "der_data_20240601", bigquery.WriteEmpty)
+func (c *bqClient) CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	if err := c.validateTable(srcTable); err != nil {
+		return err
+	}
+	if !tableName.MatchString(dstTable) {
+		return errors.Wrapf(errInvalidTable, "table %q", dstTable)
+	}
+	labels, jobID, err := c.jobConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	dataset := c.client.Dataset(c.cfg.DatasetID)
+	copier := dataset.Table(dstTable).CopierFrom(dataset.Table(srcTable))
+	copier.WriteDisposition = writeDisposition
+	copier.Labels = labels
+	copier.JobIDConfig = jobID
+
+	defer c.invalidate()
+	return c.runJob(ctx, "copy", copier.Run)
+}
+
+func tableMetadata(model any, opts ...TableOption) (*bigquery.TableMetadata, error) {
+	schema, err := bigquery.InferSchema(model)
+	if err != nil {
+		return nil, errors.Wrapf(err, "inferring schema of %T", model)
+	}
+
+	md := &bigquery.TableMetadata{Schema: schema}
+	for _, opt := range opts {
+		opt(md)
+	}
+	if md.Clustering != nil && len(md.Clustering.Fields) > 4 {
+		return nil, errors.New("tables can be clustered by at most four columns")
+	}
+	return md, nil
+}
+
+func isHTTPStatus(err error, code int) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == code
+}
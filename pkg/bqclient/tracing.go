@@ -0,0 +1,242 @@
+package bqclient
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/grid-stream-org/go-commons/pkg/bqclient"
+
+var (
+	rowsKey  = attribute.Key("bq.rows")
+	bytesKey = attribute.Key("bq.bytes")
+)
+
+type traced struct {
+	BQClient
+	tracer    trace.Tracer
+	datasetID string
+}
+
+// Trace wraps c so that every operation runs in a span named bq.<Method>,
+// a child of the span in the caller's context. Spans carry the dataset,
+// the table or query text, and the rows inserted or bytes and rows
+// streamed; failed operations record their error. A nil tp uses the global
+// provider installed by otel.Setup.
+func Trace(c BQClient, tp trace.TracerProvider, datasetID string) BQClient {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &traced{BQClient: c, tracer: tp.Tracer(tracerName), datasetID: datasetID}
+}
+
+func (c *traced) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String(string(semconv.DBSystemKey), "bigquery"),
+		semconv.DBNamespace(c.datasetID),
+		semconv.DBOperationName(op),
+	)
+	return c.tracer.Start(ctx, "bq."+op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (c *traced) Put(ctx context.Context, table string, data any) error {
+	ctx, span := c.start(ctx, "Put", semconv.DBCollectionName(table), rowsKey.Int(1))
+	err := c.BQClient.Put(ctx, table, data)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) StreamPut(ctx context.Context, table string, data any) error {
+	ctx, span := c.start(ctx, "StreamPut", semconv.DBCollectionName(table), rowsKey.Int(rowCount(data)))
+	err := c.BQClient.StreamPut(ctx, table, data)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
+	rows := 0
+	for _, data := range inputs {
+		rows += len(data)
+	}
+	ctx, span := c.start(ctx, "StreamPutAll", rowsKey.Int(rows))
+	err := c.BQClient.StreamPutAll(ctx, inputs)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) StreamRead(ctx context.Context, table string, filter *ReadFilter) (<-chan []byte, <-chan error) {
+	ctx, span := c.start(ctx, "StreamRead", semconv.DBCollectionName(table))
+	data, errs := c.BQClient.StreamRead(ctx, table, filter)
+	var n int64
+	return forward(ctx, data, errs, func(block []byte) {
+		n += int64(len(block))
+	}, func(err error) {
+		span.SetAttributes(bytesKey.Int64(n))
+		endSpan(span, err)
+	})
+}
+
+func (c *traced) StreamReadRows(ctx context.Context, table string, filter *ReadFilter, newRow func() any) (<-chan any, <-chan error) {
+	ctx, span := c.start(ctx, "StreamReadRows", semconv.DBCollectionName(table))
+	rows, errs := c.BQClient.StreamReadRows(ctx, table, filter, newRow)
+	var n int64
+	return forward(ctx, rows, errs, func(any) {
+		n++
+	}, func(err error) {
+		span.SetAttributes(rowsKey.Int64(n))
+		endSpan(span, err)
+	})
+}
+
+func (c *traced) Query(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
+	ctx, span := c.start(ctx, "Query", semconv.DBQueryText(query))
+	it, err := c.BQClient.Query(ctx, query, params)
+	if err == nil {
+		span.SetAttributes(rowsKey.Int64(int64(it.TotalRows)))
+	}
+	endSpan(span, err)
+	return it, err
+}
+
+func (c *traced) QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error {
+	ctx, span := c.start(ctx, "QueryRow", semconv.DBQueryText(query))
+	err := c.BQClient.QueryRow(ctx, query, params, dst)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string, dst any) (string, error) {
+	ctx, span := c.start(ctx, "QueryPage", semconv.DBQueryText(query))
+	next, err := c.BQClient.QueryPage(ctx, query, params, pageSize, pageToken, dst)
+	endSpan(span, err)
+	return next, err
+}
+
+func (c *traced) DryRun(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.JobStatistics, error) {
+	ctx, span := c.start(ctx, "DryRun", semconv.DBQueryText(query))
+	stats, err := c.BQClient.DryRun(ctx, query, params)
+	if err == nil {
+		span.SetAttributes(bytesKey.Int64(stats.TotalBytesProcessed))
+	}
+	endSpan(span, err)
+	return stats, err
+}
+
+func (c *traced) Update(ctx context.Context, table string, id string, updates map[string]any) error {
+	ctx, span := c.start(ctx, "Update", semconv.DBCollectionName(table))
+	err := c.BQClient.Update(ctx, table, id, updates)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	ctx, span := c.start(ctx, "UpdateMulti", semconv.DBCollectionName(table), rowsKey.Int(len(ids)))
+	err := c.BQClient.UpdateMulti(ctx, table, ids, updates)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) Upsert(ctx context.Context, table string, id string, data any) error {
+	ctx, span := c.start(ctx, "Upsert", semconv.DBCollectionName(table))
+	err := c.BQClient.Upsert(ctx, table, id, data)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) Delete(ctx context.Context, table string, id string) error {
+	ctx, span := c.start(ctx, "Delete", semconv.DBCollectionName(table))
+	err := c.BQClient.Delete(ctx, table, id)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	ctx, span := c.start(ctx, "DeleteMulti", semconv.DBCollectionName(table), rowsKey.Int(len(ids)))
+	err := c.BQClient.DeleteMulti(ctx, table, ids)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) Get(ctx context.Context, table string, id string, dst any) error {
+	ctx, span := c.start(ctx, "Get", semconv.DBCollectionName(table))
+	err := c.BQClient.Get(ctx, table, id, dst)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) GetMulti(ctx context.Context, table string, ids []string, dst any) error {
+	ctx, span := c.start(ctx, "GetMulti", semconv.DBCollectionName(table))
+	err := c.BQClient.GetMulti(ctx, table, ids, dst)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) List(ctx context.Context, table string, filter map[string]any, opts ListOptions, dst any) error {
+	ctx, span := c.start(ctx, "List", semconv.DBCollectionName(table))
+	err := c.BQClient.List(ctx, table, filter, opts, dst)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) Count(ctx context.Context, table string, filter map[string]any) (int64, error) {
+	ctx, span := c.start(ctx, "Count", semconv.DBCollectionName(table))
+	n, err := c.BQClient.Count(ctx, table, filter)
+	endSpan(span, err)
+	return n, err
+}
+
+func (c *traced) Exists(ctx context.Context, table string, id string) (bool, error) {
+	ctx, span := c.start(ctx, "Exists", semconv.DBCollectionName(table))
+	found, err := c.BQClient.Exists(ctx, table, id)
+	endSpan(span, err)
+	return found, err
+}
+
+func (c *traced) ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error {
+	ctx, span := c.start(ctx, "ExecScript", semconv.DBQueryText(script))
+	err := c.BQClient.ExecScript(ctx, script, params)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) EnsureTable(ctx context.Context, table string, model any, opts ...TableOption) error {
+	ctx, span := c.start(ctx, "EnsureTable", semconv.DBCollectionName(table))
+	err := c.BQClient.EnsureTable(ctx, table, model, opts...)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...LoadOption) error {
+	ctx, span := c.start(ctx, "LoadFromGCS", semconv.DBCollectionName(table))
+	err := c.BQClient.LoadFromGCS(ctx, table, gcsURI, format, opts...)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) ExtractToGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat) error {
+	ctx, span := c.start(ctx, "ExtractToGCS", semconv.DBCollectionName(table))
+	err := c.BQClient.ExtractToGCS(ctx, table, gcsURI, format)
+	endSpan(span, err)
+	return err
+}
+
+func (c *traced) CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	ctx, span := c.start(ctx, "CopyTable", semconv.DBCollectionName(srcTable))
+	err := c.BQClient.CopyTable(ctx, srcTable, dstTable, writeDisposition)
+	endSpan(span, err)
+	return err
+}
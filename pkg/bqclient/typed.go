@@ -0,0 +1,93 @@
+package bqclient
+
+import (
+	"context"
+	"reflect"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/matthew-collett/go-ctag/ctag"
+	"github.com/pkg/errors"
+)
+
+// TypedClient reads and writes rows of one table as T, a struct mapped to
+// its columns by bigquery tags, so callers need no casts:
+//
+//	averages := bqclient.Typed[models.ProjectAverage](bq, models.TableProjectAverages)
+//	avg, err := averages.Get(ctx, id)
+type TypedClient[T any] struct {
+	client BQClient
+	table  string
+}
+
+func Typed[T any](c BQClient, table string) *TypedClient[T] {
+	return &TypedClient[T]{client: c, table: table}
+}
+
+func (t *TypedClient[T]) Get(ctx context.Context, id string) (T, error) {
+	var row T
+	err := t.client.Get(ctx, t.table, id, &row)
+	return row, err
+}
+
+func (t *TypedClient[T]) GetMulti(ctx context.Context, ids []string) ([]T, error) {
+	var rows []T
+	err := t.client.GetMulti(ctx, t.table, ids, &rows)
+	return rows, err
+}
+
+// List returns every row of query, which would normally select from the
+// client's table.
+func (t *TypedClient[T]) List(ctx context.Context, query string, params []bigquery.QueryParameter) ([]T, error) {
+	it, err := t.client.Query(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := appendRows(it, reflect.ValueOf([]T{}), false)
+	if err != nil {
+		return nil, err
+	}
+	return rows.Interface().([]T), nil
+}
+
+// ListWhere returns the rows matching filter; see BQClient.List.
+func (t *TypedClient[T]) ListWhere(ctx context.Context, filter map[string]any, opts ListOptions) ([]T, error) {
+	var rows []T
+	err := t.client.List(ctx, t.table, filter, opts, &rows)
+	return rows, err
+}
+
+// QueryPage returns one page of query and the token of the next; see
+// BQClient.QueryPage.
+func (t *TypedClient[T]) QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string) ([]T, string, error) {
+	var rows []T
+	next, err := t.client.QueryPage(ctx, query, params, pageSize, pageToken, &rows)
+	return rows, next, err
+}
+
+func (t *TypedClient[T]) Put(ctx context.Context, row T) error {
+	return t.client.Put(ctx, t.table, row)
+}
+
+// Update sets every column of the row with the given id from row, except id
+// itself.
+func (t *TypedClient[T]) Update(ctx context.Context, id string, row T) error {
+	tags, err := ctag.GetTags("bigquery", row)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	updates := make(map[string]any, len(tags))
+	for _, tag := range tags {
+		if tag.Name != "id" {
+			updates[tag.Name] = tag.Field
+		}
+	}
+	return t.client.Update(ctx, t.table, id, updates)
+}
+
+func (t *TypedClient[T]) Upsert(ctx context.Context, id string, row T) error {
+	return t.client.Upsert(ctx, t.table, id, row)
+}
+
+func (t *TypedClient[T]) Delete(ctx context.Context, id string) error {
+	return t.client.Delete(ctx, t.table, id)
+}
@@ -0,0 +1,347 @@
+package bqclient
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"cloud.google.com/go/civil"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// StreamType selects the delivery semantics of a StreamWriter.
+type StreamType string
+
+const (
+	// PendingStream buffers appended rows until Commit makes them visible
+	// atomically. It is the default.
+	PendingStream StreamType = "pending"
+	// CommittedStream makes rows visible as soon as each Append succeeds.
+	CommittedStream StreamType = "committed"
+)
+
+type StreamWriterOption func(*StreamWriter)
+
+func WithStreamType(t StreamType) StreamWriterOption {
+	return func(w *StreamWriter) {
+		w.typ = t
+	}
+}
+
+// StreamWriter appends rows to a table through the Storage Write API. Each
+// Append is written at an explicit offset, so a retried append is never
+// applied twice; with a PendingStream nothing is visible until Commit.
+//
+//	w, err := bq.NewStreamWriter(ctx, models.TableDERData)
+//	...
+//	defer w.Close()
+//	if err := w.Append(ctx, readings); err != nil {
+//		return err
+//	}
+//	return w.Commit(ctx)
+//
+// TIME, DATETIME and NUMERIC columns are not supported. A StreamWriter is
+// safe for concurrent use, but appends are serialised.
+type StreamWriter struct {
+	client *managedwriter.Client
+	stream *managedwriter.ManagedStream
+	desc   protoreflect.MessageDescriptor
+	schema bigquery.Schema
+	parent string
+	typ    StreamType
+
+	mu        sync.Mutex
+	offset    int64
+	finalized bool
+}
+
+func (c *bqClient) NewStreamWriter(ctx context.Context, table string, opts ...StreamWriterOption) (*StreamWriter, error) {
+	if err := c.validateTable(table); err != nil {
+		return nil, err
+	}
+
+	w := &StreamWriter{
+		parent: managedwriter.TableParentFromParts(c.cfg.ProjectID, c.cfg.DatasetID, table),
+		typ:    PendingStream,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	var streamType managedwriter.StreamType
+	switch w.typ {
+	case PendingStream:
+		streamType = managedwriter.PendingStream
+	case CommittedStream:
+		streamType = managedwriter.CommittedStream
+	default:
+		return nil, errors.Errorf("unknown stream type %q", w.typ)
+	}
+
+	client, err := c.writer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.client = client
+
+	meta, err := c.client.Dataset(c.cfg.DatasetID).Table(table).Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading schema of %s", table)
+	}
+	w.schema = meta.Schema
+	w.desc, err = messageDescriptor(meta.Schema)
+	if err != nil {
+		return nil, err
+	}
+	dp, err := adapt.NormalizeDescriptor(w.desc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	w.stream, err = client.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(w.parent),
+		managedwriter.WithType(streamType),
+		managedwriter.WithSchemaDescriptor(dp),
+		managedwriter.EnableWriteRetries(true),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening write stream on %s", table)
+	}
+	return w, nil
+}
+
+// writer returns the Storage Write client, creating it on first use so
+// clients that never stream writes do not hold the connection.
+func (c *bqClient) writer(ctx context.Context) (*managedwriter.Client, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.writeClient == nil {
+		client, err := managedwriter.NewClient(ctx, c.cfg.ProjectID, c.cfg.readClientOptions()...)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		c.writeClient = client
+	}
+	return c.writeClient, nil
+}
+
+// Append writes rows, a struct, a pointer to one, or a slice of either,
+// mapping fields to columns by their bigquery tags. It returns once the
+// rows are acknowledged.
+func (w *StreamWriter) Append(ctx context.Context, rows any) error {
+	data, err := encodeRows(w.schema, w.desc, rows)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.finalized {
+		return errors.New("stream writer already finalized")
+	}
+
+	res, err := w.stream.AppendRows(ctx, data, managedwriter.WithOffset(w.offset))
+	if err != nil {
+		return errors.Wrap(err, "appending rows")
+	}
+	if _, err := res.GetResult(ctx); err != nil {
+		return errors.Wrap(err, "appending rows")
+	}
+	w.offset += int64(len(data))
+	return nil
+}
+
+// Finalize stops the stream accepting appends and returns how many rows it
+// holds. Pending rows still need Commit.
+func (w *StreamWriter) Finalize(ctx context.Context) (int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.finalize(ctx)
+}
+
+func (w *StreamWriter) finalize(ctx context.Context) (int64, error) {
+	if w.finalized {
+		return w.offset, nil
+	}
+	n, err := w.stream.Finalize(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "finalizing write stream")
+	}
+	w.finalized = true
+	return n, nil
+}
+
+// Commit finalizes the stream and, for a PendingStream, makes every row
+// appended to it visible at once.
+func (w *StreamWriter) Commit(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.finalize(ctx); err != nil {
+		return err
+	}
+	if w.typ != PendingStream {
+		return nil
+	}
+
+	res, err := w.client.BatchCommitWriteStreams(ctx, &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       w.parent,
+		WriteStreams: []string{w.stream.StreamName()},
+	})
+	if err != nil {
+		return errors.Wrap(err, "committing write stream")
+	}
+	if errs := res.GetStreamErrors(); len(errs) > 0 {
+		return errors.Errorf("committing write stream: %s", errs[0].GetErrorMessage())
+	}
+	return nil
+}
+
+// Close releases the stream. Rows of an uncommitted PendingStream are
+// discarded.
+func (w *StreamWriter) Close() error {
+	return errors.WithStack(w.stream.Close())
+}
+
+func messageDescriptor(schema bigquery.Schema) (protoreflect.MessageDescriptor, error) {
+	ts, err := adapt.BQSchemaToStorageTableSchema(schema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	d, err := adapt.StorageSchemaToProto2Descriptor(ts, "root")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, errors.New("schema did not convert to a message descriptor")
+	}
+	return md, nil
+}
+
+// encodeRows serialises each row as a message of desc.
+func encodeRows(schema bigquery.Schema, desc protoreflect.MessageDescriptor, rows any) ([][]byte, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		v = reflect.ValueOf([]any{rows})
+	}
+
+	data := make([][]byte, v.Len())
+	for i := range v.Len() {
+		values, _, err := (&bigquery.StructSaver{Schema: schema, Struct: v.Index(i).Interface()}).Save()
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding row %d", i)
+		}
+		msg := dynamicpb.NewMessage(desc)
+		if err := setFields(msg, values); err != nil {
+			return nil, errors.Wrapf(err, "encoding row %d", i)
+		}
+		if data[i], err = proto.Marshal(msg); err != nil {
+			return nil, errors.Wrapf(err, "encoding row %d", i)
+		}
+	}
+	return data, nil
+}
+
+func setFields(msg *dynamicpb.Message, values map[string]bigquery.Value) error {
+	fields := msg.Descriptor().Fields()
+	for name, val := range values {
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			return errors.Errorf("column %s not in table schema", name)
+		}
+		if fd.IsList() {
+			rv := reflect.ValueOf(val)
+			list := msg.Mutable(fd).List()
+			for i := range rv.Len() {
+				pv, ok, err := protoValue(fd, list.NewElement, rv.Index(i).Interface())
+				if err != nil {
+					return errors.Wrapf(err, "column %s", name)
+				}
+				if ok {
+					list.Append(pv)
+				}
+			}
+			continue
+		}
+		pv, ok, err := protoValue(fd, func() protoreflect.Value { return msg.NewField(fd) }, val)
+		if err != nil {
+			return errors.Wrapf(err, "column %s", name)
+		}
+		if ok {
+			msg.Set(fd, pv)
+		}
+	}
+	return nil
+}
+
+var epoch = civil.Date{Year: 1970, Month: time.January, Day: 1}
+
+// protoValue converts a value produced by bigquery.StructSaver to fd's
+// kind. It reports false for NULL.
+func protoValue(fd protoreflect.FieldDescriptor, newMessage func() protoreflect.Value, val any) (protoreflect.Value, bool, error) {
+	switch v := val.(type) {
+	case nil:
+		return protoreflect.Value{}, false, nil
+	case time.Time:
+		return protoreflect.ValueOfInt64(v.UnixMicro()), true, nil
+	case civil.Date:
+		return protoreflect.ValueOfInt32(int32(v.DaysSince(epoch))), true, nil
+	case map[string]bigquery.Value:
+		mv := newMessage()
+		if err := setFields(mv.Message().(*dynamicpb.Message), v); err != nil {
+			return protoreflect.Value{}, false, err
+		}
+		return mv, true, nil
+	}
+
+	rv := reflect.ValueOf(val)
+	// bigquery.NullString and friends hold the value in their first field.
+	if rv.Kind() == reflect.Struct {
+		if valid := rv.FieldByName("Valid"); valid.IsValid() && valid.Kind() == reflect.Bool {
+			if !valid.Bool() {
+				return protoreflect.Value{}, false, nil
+			}
+			return protoValue(fd, newMessage, rv.Field(0).Interface())
+		}
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if rv.Kind() == reflect.String {
+			return protoreflect.ValueOfString(rv.String()), true, nil
+		}
+	case protoreflect.BoolKind:
+		if rv.Kind() == reflect.Bool {
+			return protoreflect.ValueOfBool(rv.Bool()), true, nil
+		}
+	case protoreflect.DoubleKind:
+		if rv.CanFloat() {
+			return protoreflect.ValueOfFloat64(rv.Float()), true, nil
+		}
+	case protoreflect.Int64Kind:
+		if rv.CanInt() {
+			return protoreflect.ValueOfInt64(rv.Int()), true, nil
+		}
+		if rv.CanUint() {
+			return protoreflect.ValueOfInt64(int64(rv.Uint())), true, nil
+		}
+	case protoreflect.BytesKind:
+		if b, ok := val.([]byte); ok {
+			return protoreflect.ValueOfBytes(b), true, nil
+		}
+	}
+	return protoreflect.Value{}, false, errors.Errorf("cannot write %T as %s", val, fd.Kind())
+}
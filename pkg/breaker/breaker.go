@@ -0,0 +1,350 @@
+// Package breaker provides a circuit breaker for wrapping calls to remote
+// dependencies such as BigQuery, the validator service and HTTP APIs.
+package breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	ErrOpen            = errors.New("circuit breaker is open")
+	ErrTooManyRequests = errors.New("circuit breaker is half-open and at its request limit")
+
+	// errPanicked is recorded for a call whose function panicked.
+	errPanicked = errors.New("circuit breaker call panicked")
+)
+
+const (
+	DefaultFailureThreshold    = 5
+	DefaultSuccessThreshold    = 1
+	DefaultOpenTimeout         = 30 * time.Second
+	DefaultHalfOpenMaxRequests = 1
+)
+
+type Config struct {
+	FailureThreshold    int           `koanf:"failure_threshold" json:"failure_threshold" envconfig:"failure_threshold"`
+	SuccessThreshold    int           `koanf:"success_threshold" json:"success_threshold" envconfig:"success_threshold"`
+	OpenTimeout         time.Duration `koanf:"open_timeout" json:"open_timeout" envconfig:"open_timeout"`
+	HalfOpenMaxRequests int           `koanf:"half_open_max_requests" json:"half_open_max_requests" envconfig:"half_open_max_requests"`
+}
+
+func (c *Config) Validate() error {
+	if c.FailureThreshold < 0 {
+		return errors.New("failure threshold must not be negative")
+	}
+	if c.SuccessThreshold < 0 {
+		return errors.New("success threshold must not be negative")
+	}
+	if c.OpenTimeout < 0 {
+		return errors.New("open timeout must not be negative")
+	}
+	if c.HalfOpenMaxRequests < 0 {
+		return errors.New("half-open max requests must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) withDefaults() Config {
+	out := Config{
+		FailureThreshold:    DefaultFailureThreshold,
+		SuccessThreshold:    DefaultSuccessThreshold,
+		OpenTimeout:         DefaultOpenTimeout,
+		HalfOpenMaxRequests: DefaultHalfOpenMaxRequests,
+	}
+	if c == nil {
+		return out
+	}
+	if c.FailureThreshold > 0 {
+		out.FailureThreshold = c.FailureThreshold
+	}
+	if c.SuccessThreshold > 0 {
+		out.SuccessThreshold = c.SuccessThreshold
+	}
+	if c.OpenTimeout > 0 {
+		out.OpenTimeout = c.OpenTimeout
+	}
+	if c.HalfOpenMaxRequests > 0 {
+		out.HalfOpenMaxRequests = c.HalfOpenMaxRequests
+	}
+	return out
+}
+
+// Counts is a snapshot of a breaker's counters. Successes and Failures
+// include calls that finished after a state change, though those do not
+// count toward the consecutive counts.
+type Counts struct {
+	Requests             uint64
+	Successes            uint64
+	Failures             uint64
+	Rejections           uint64
+	ConsecutiveFailures  int
+	ConsecutiveSuccesses int
+}
+
+type Option func(*Breaker)
+
+// WithOnStateChange registers a callback invoked on every state transition.
+// It is called without the breaker lock held.
+func WithOnStateChange(fn func(name string, from, to State)) Option {
+	return func(b *Breaker) {
+		b.onStateChange = fn
+	}
+}
+
+// WithIsFailure decides which errors count against the breaker. By default
+// every error except context cancellation is a failure.
+func WithIsFailure(fn func(error) bool) Option {
+	return func(b *Breaker) {
+		b.isFailure = fn
+	}
+}
+
+// WithMetrics records calls by breaker and result (success, failure or
+// rejected) and the current state of each breaker, as 0 for closed, 1 for
+// open and 2 for half-open.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(b *Breaker) {
+		calls, err := m.NewCounterVec("breaker", "calls_total", "Circuit breaker calls by result.", "breaker", "result")
+		if err != nil {
+			logger.Default().Warn("registering breaker metrics", "breaker", b.name, "error", err)
+			return
+		}
+		state, err := m.NewGaugeVec("breaker", "state", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", "breaker")
+		if err != nil {
+			logger.Default().Warn("registering breaker metrics", "breaker", b.name, "error", err)
+			return
+		}
+		b.metrics = &breakerMetrics{
+			calls: calls.MustCurryWith(prometheus.Labels{"breaker": b.name}),
+			state: state.WithLabelValues(b.name),
+		}
+	}
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	name          string
+	cfg           Config
+	onStateChange func(name string, from, to State)
+	isFailure     func(error) bool
+	now           func() time.Time
+	metrics       *breakerMetrics
+
+	mu       sync.Mutex
+	state    State
+	openedAt time.Time
+	// generation changes on every transition. Calls are tagged with the
+	// generation they started in, and the outcome of a call that started
+	// before the last transition does not affect the state.
+	generation uint64
+	inFlight   int
+	counts     Counts
+}
+
+type breakerMetrics struct {
+	calls *prometheus.CounterVec
+	state prometheus.Gauge
+}
+
+// New creates a closed breaker. Zero fields in cfg use the package defaults.
+func New(name string, cfg *Config, opts ...Option) *Breaker {
+	b := &Breaker{
+		name:      name,
+		cfg:       cfg.withDefaults(),
+		isFailure: defaultIsFailure,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if b.metrics != nil {
+		b.metrics.state.Set(float64(StateClosed))
+	}
+	return b
+}
+
+func defaultIsFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, moving an open breaker to half-open once
+// its open timeout has elapsed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	state, transition := b.currentState()
+	b.mu.Unlock()
+	b.notify(transition)
+	return state
+}
+
+func (b *Breaker) Counts() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.counts
+}
+
+// Execute runs fn if the breaker allows it and records the outcome. When the
+// breaker rejects the call, fn is not run and ErrOpen or ErrTooManyRequests
+// is returned.
+func (b *Breaker) Execute(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := Call(ctx, b, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Call is the value-returning form of Breaker.Execute. A panic in fn is
+// recorded as a failure before it propagates.
+func Call[T any](ctx context.Context, b *Breaker, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	generation, err := b.before()
+	if err != nil {
+		return v, err
+	}
+
+	err = errPanicked
+	defer func() { b.after(generation, err) }()
+	v, err = fn(ctx)
+	return v, err
+}
+
+type transition struct {
+	from, to State
+}
+
+func (b *Breaker) before() (uint64, error) {
+	b.mu.Lock()
+	state, t := b.currentState()
+
+	var err error
+	switch state {
+	case StateOpen:
+		err = errors.Wrapf(ErrOpen, "breaker %s", b.name)
+	case StateHalfOpen:
+		if b.inFlight >= b.cfg.HalfOpenMaxRequests {
+			err = errors.Wrapf(ErrTooManyRequests, "breaker %s", b.name)
+		}
+	}
+
+	if err != nil {
+		b.counts.Rejections++
+		b.record("rejected")
+	} else {
+		b.counts.Requests++
+		b.inFlight++
+	}
+	generation := b.generation
+	b.mu.Unlock()
+
+	b.notify(t)
+	return generation, err
+}
+
+func (b *Breaker) after(generation uint64, err error) {
+	b.mu.Lock()
+	failed := err != nil && b.isFailure(err)
+	if failed {
+		b.counts.Failures++
+		b.record("failure")
+	} else {
+		b.counts.Successes++
+		b.record("success")
+	}
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+	b.inFlight--
+
+	var t *transition
+	if failed {
+		b.counts.ConsecutiveFailures++
+		b.counts.ConsecutiveSuccesses = 0
+		if b.state == StateHalfOpen || (b.state == StateClosed && b.counts.ConsecutiveFailures >= b.cfg.FailureThreshold) {
+			t = b.setState(StateOpen)
+		}
+	} else {
+		b.counts.ConsecutiveSuccesses++
+		b.counts.ConsecutiveFailures = 0
+		if b.state == StateHalfOpen && b.counts.ConsecutiveSuccesses >= b.cfg.SuccessThreshold {
+			t = b.setState(StateClosed)
+		}
+	}
+	b.mu.Unlock()
+
+	b.notify(t)
+}
+
+// currentState must be called with b.mu held.
+func (b *Breaker) currentState() (State, *transition) {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		return StateHalfOpen, b.setState(StateHalfOpen)
+	}
+	return b.state, nil
+}
+
+// setState must be called with b.mu held.
+func (b *Breaker) setState(to State) *transition {
+	from := b.state
+	if from == to {
+		return nil
+	}
+
+	b.state = to
+	b.generation++
+	b.inFlight = 0
+	b.counts.ConsecutiveFailures = 0
+	b.counts.ConsecutiveSuccesses = 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.metrics != nil {
+		b.metrics.state.Set(float64(to))
+	}
+	return &transition{from: from, to: to}
+}
+
+// record must be called with b.mu held.
+func (b *Breaker) record(result string) {
+	if b.metrics != nil {
+		b.metrics.calls.WithLabelValues(result).Inc()
+	}
+}
+
+func (b *Breaker) notify(t *transition) {
+	if t != nil && b.onStateChange != nil {
+		b.onStateChange(b.name, t.from, t.to)
+	}
+}
@@ -0,0 +1,236 @@
+package breaker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+var errBoom = errors.New("boom")
+
+type BreakerTestSuite struct {
+	suite.Suite
+	ctx         context.Context
+	now         time.Time
+	transitions []string
+}
+
+func (s *BreakerTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.now = time.Now()
+	s.transitions = nil
+}
+
+func (s *BreakerTestSuite) newBreaker(cfg *Config, opts ...Option) *Breaker {
+	opts = append(opts, WithOnStateChange(func(name string, from, to State) {
+		s.transitions = append(s.transitions, from.String()+"->"+to.String())
+	}))
+	b := New("test", cfg, opts...)
+	b.now = func() time.Time { return s.now }
+	return b
+}
+
+func fail(ctx context.Context) error    { return errBoom }
+func succeed(ctx context.Context) error { return nil }
+
+func (s *BreakerTestSuite) TestOpensAfterThreshold() {
+	b := s.newBreaker(&Config{FailureThreshold: 3, OpenTimeout: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		s.ErrorIs(b.Execute(s.ctx, fail), errBoom)
+	}
+	s.Equal(StateClosed, b.State())
+
+	s.NoError(b.Execute(s.ctx, succeed), "success resets the consecutive count")
+	for i := 0; i < 3; i++ {
+		s.ErrorIs(b.Execute(s.ctx, fail), errBoom)
+	}
+	s.Equal(StateOpen, b.State())
+
+	called := false
+	err := b.Execute(s.ctx, func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	s.ErrorIs(err, ErrOpen)
+	s.False(called)
+	s.Equal(uint64(1), b.Counts().Rejections)
+	s.Equal([]string{"closed->open"}, s.transitions)
+}
+
+func (s *BreakerTestSuite) TestHalfOpenRecovery() {
+	b := s.newBreaker(&Config{FailureThreshold: 1, SuccessThreshold: 2, OpenTimeout: time.Minute})
+
+	s.Error(b.Execute(s.ctx, fail))
+	s.Equal(StateOpen, b.State())
+
+	s.now = s.now.Add(time.Minute)
+	s.Equal(StateHalfOpen, b.State())
+
+	s.NoError(b.Execute(s.ctx, succeed))
+	s.Equal(StateHalfOpen, b.State())
+	s.NoError(b.Execute(s.ctx, succeed))
+	s.Equal(StateClosed, b.State())
+
+	s.Equal([]string{"closed->open", "open->half-open", "half-open->closed"}, s.transitions)
+}
+
+func (s *BreakerTestSuite) TestHalfOpenFailureReopens() {
+	b := s.newBreaker(&Config{FailureThreshold: 1, OpenTimeout: time.Minute})
+
+	s.Error(b.Execute(s.ctx, fail))
+	s.now = s.now.Add(time.Minute)
+	s.Error(b.Execute(s.ctx, fail))
+	s.Equal(StateOpen, b.State())
+}
+
+func (s *BreakerTestSuite) TestHalfOpenRequestLimit() {
+	b := s.newBreaker(&Config{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	s.Error(b.Execute(s.ctx, fail))
+	s.now = s.now.Add(time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_ = b.Execute(s.ctx, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+
+	<-started
+	s.ErrorIs(b.Execute(s.ctx, succeed), ErrTooManyRequests)
+	close(release)
+	wg.Wait()
+	s.Equal(StateClosed, b.State())
+}
+
+func (s *BreakerTestSuite) TestSlowCallAcrossTransitions() {
+	b := s.newBreaker(&Config{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Execute(s.ctx, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return errBoom
+		})
+	}()
+
+	<-started
+	s.Error(b.Execute(s.ctx, fail))
+	s.now = s.now.Add(time.Minute)
+	s.Equal(StateHalfOpen, b.State())
+
+	// The call started while closed neither takes the half-open slot nor
+	// reopens the breaker when it fails.
+	probe := make(chan struct{})
+	go func() {
+		done <- b.Execute(s.ctx, func(ctx context.Context) error {
+			<-probe
+			return nil
+		})
+	}()
+	s.Eventually(func() bool { return b.Counts().Requests == 3 }, time.Second, time.Millisecond)
+	close(release)
+	s.ErrorIs(<-done, errBoom)
+	s.Equal(StateHalfOpen, b.State())
+
+	close(probe)
+	s.NoError(<-done)
+	s.Equal(StateClosed, b.State())
+	s.Equal(uint64(2), b.Counts().Failures)
+	s.Equal([]string{"closed->open", "open->half-open", "half-open->closed"}, s.transitions)
+}
+
+func (s *BreakerTestSuite) TestHalfOpenPanic() {
+	b := s.newBreaker(&Config{FailureThreshold: 1, OpenTimeout: time.Minute, HalfOpenMaxRequests: 1})
+	s.Error(b.Execute(s.ctx, fail))
+	s.now = s.now.Add(time.Minute)
+
+	s.Panics(func() {
+		_ = b.Execute(s.ctx, func(ctx context.Context) error { panic("boom") })
+	})
+	s.Equal(StateOpen, b.State())
+
+	// The panicking call released its half-open slot.
+	s.now = s.now.Add(time.Minute)
+	s.NoError(b.Execute(s.ctx, succeed))
+	s.Equal(StateClosed, b.State())
+}
+
+func (s *BreakerTestSuite) TestIsFailure() {
+	b := s.newBreaker(&Config{FailureThreshold: 1}, WithIsFailure(func(err error) bool {
+		return !errors.Is(err, errBoom)
+	}))
+
+	s.ErrorIs(b.Execute(s.ctx, fail), errBoom)
+	s.Equal(StateClosed, b.State())
+
+	s.ErrorIs(b.Execute(s.ctx, func(ctx context.Context) error { return context.Canceled }), context.Canceled)
+	s.Equal(StateOpen, b.State())
+}
+
+func (s *BreakerTestSuite) TestCall() {
+	b := s.newBreaker(nil)
+	v, err := Call(s.ctx, b, func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	s.NoError(err)
+	s.Equal("ok", v)
+	s.Equal(uint64(1), b.Counts().Successes)
+}
+
+func (s *BreakerTestSuite) TestMetrics() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "breaker"})
+	s.Require().NoError(err)
+	b := s.newBreaker(&Config{FailureThreshold: 1, OpenTimeout: time.Minute}, WithMetrics(m))
+
+	s.Equal(float64(StateClosed), testutil.ToFloat64(b.metrics.state))
+	s.NoError(b.Execute(s.ctx, succeed))
+	s.Error(b.Execute(s.ctx, fail))
+	s.ErrorIs(b.Execute(s.ctx, succeed), ErrOpen)
+	s.Equal(float64(StateOpen), testutil.ToFloat64(b.metrics.state))
+
+	s.now = s.now.Add(time.Minute)
+	s.Equal(StateHalfOpen, b.State())
+	s.Equal(float64(StateHalfOpen), testutil.ToFloat64(b.metrics.state))
+
+	for _, result := range []string{"success", "failure", "rejected"} {
+		s.Equal(1.0, testutil.ToFloat64(b.metrics.calls.WithLabelValues(result)), result)
+	}
+}
+
+func (s *BreakerTestSuite) TestRegistry() {
+	r := NewRegistry(&Config{FailureThreshold: 1})
+	a := r.Get("bigquery")
+	s.Same(a, r.Get("bigquery"))
+	s.NotSame(a, r.Get("validator"))
+	s.Len(r.Breakers(), 2)
+
+	s.Error(a.Execute(s.ctx, fail))
+	s.Equal(StateOpen, r.Get("bigquery").State())
+	s.Equal(StateClosed, r.Get("validator").State())
+}
+
+func (s *BreakerTestSuite) TestConfigValidate() {
+	s.NoError((&Config{}).Validate())
+	s.Error((&Config{FailureThreshold: -1}).Validate())
+	s.Error((&Config{OpenTimeout: -time.Second}).Validate())
+}
+
+func TestBreakerSuite(t *testing.T) {
+	suite.Run(t, new(BreakerTestSuite))
+}
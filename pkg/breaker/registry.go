@@ -0,0 +1,43 @@
+package breaker
+
+import (
+	"maps"
+	"sync"
+)
+
+// Registry hands out one Breaker per name, creating breakers on first use
+// with a shared Config and options.
+type Registry struct {
+	cfg      *Config
+	opts     []Option
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+func NewRegistry(cfg *Config, opts ...Option) *Registry {
+	return &Registry{
+		cfg:      cfg,
+		opts:     opts,
+		breakers: map[string]*Breaker{},
+	}
+}
+
+// Get returns the breaker registered under name, creating it if needed.
+func (r *Registry) Get(name string) *Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[name]
+	if !ok {
+		b = New(name, r.cfg, r.opts...)
+		r.breakers[name] = b
+	}
+	return b
+}
+
+// Breakers returns a copy of the registered breakers keyed by name.
+func (r *Registry) Breakers() map[string]*Breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.breakers)
+}
@@ -0,0 +1,175 @@
+// Package buildinfo reports the version, commit and build time of the running
+// binary. Release builds stamp them with ldflags:
+//
+//	go build -ldflags "\
+//	  -X github.com/grid-stream-org/go-commons/pkg/buildinfo.Version=v1.4.0 \
+//	  -X github.com/grid-stream-org/go-commons/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/grid-stream-org/go-commons/pkg/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Anything left unset falls back to the module and VCS metadata Go embeds in
+// every binary, so `go install` and `go run` builds still report something
+// useful.
+package buildinfo
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+)
+
+// Set with -ldflags -X.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+const (
+	Path = "/version"
+
+	unknown = "unknown"
+)
+
+type Info struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	BuildTime time.Time `json:"build_time"`
+	Dirty     bool      `json:"dirty,omitempty"`
+	GoVersion string    `json:"go_version"`
+	Platform  string    `json:"platform"`
+	Module    string    `json:"module,omitempty"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build information for the running binary. It is computed
+// once.
+func Get() Info {
+	once.Do(func() {
+		bi, _ := debug.ReadBuildInfo()
+		info = read(bi, Version, Commit, BuildTime)
+	})
+	return info
+}
+
+func read(bi *debug.BuildInfo, version, commit, buildTime string) Info {
+	i := Info{
+		Version:   version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if t, err := time.Parse(time.RFC3339, buildTime); err == nil {
+		i.BuildTime = t.UTC()
+	}
+
+	if bi != nil {
+		i.Module = bi.Main.Path
+		if bi.GoVersion != "" {
+			i.GoVersion = bi.GoVersion
+		}
+		if i.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			i.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if i.Commit == "" {
+					i.Commit = s.Value
+				}
+			case "vcs.time":
+				if t, err := time.Parse(time.RFC3339, s.Value); err == nil && i.BuildTime.IsZero() {
+					i.BuildTime = t.UTC()
+				}
+			case "vcs.modified":
+				i.Dirty = s.Value == "true"
+			}
+		}
+	}
+
+	if i.Version == "" {
+		i.Version = unknown
+	}
+	if i.Commit == "" {
+		i.Commit = unknown
+	}
+	return i
+}
+
+// ShortCommit returns the first 12 characters of the commit hash.
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+func (i Info) String() string {
+	s := fmt.Sprintf("%s (commit %s", i.Version, i.ShortCommit())
+	if i.Dirty {
+		s += "-dirty"
+	}
+	if !i.BuildTime.IsZero() {
+		s += ", built " + i.BuildTime.Format(time.RFC3339)
+	}
+	return s + ", " + i.GoVersion + " " + i.Platform + ")"
+}
+
+// LogAttr groups the build information under "build" for structured logs.
+func (i Info) LogAttr() slog.Attr {
+	return slog.Group("build",
+		slog.String("version", i.Version),
+		slog.String("commit", i.ShortCommit()),
+		slog.String("go_version", i.GoVersion),
+	)
+}
+
+// Logger returns log with the build information attached to every record.
+func Logger(log *slog.Logger) *slog.Logger {
+	return log.With(Get().LogAttr())
+}
+
+// Register adds the conventional build_info gauge, always 1, labelled with
+// the version, commit and Go version so dashboards can join on it.
+func Register(m *metrics.Metrics) error {
+	g, err := m.NewGaugeVec("", "build_info", "Build information about the running binary.", "version", "commit", "go_version")
+	if err != nil {
+		return errors.Wrap(err, "registering build info")
+	}
+	i := Get()
+	g.WithLabelValues(i.Version, i.ShortCommit(), i.GoVersion).Set(1)
+	return nil
+}
+
+// Handler serves the build information as JSON, typically at Path next to
+// the health endpoints.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Get())
+	})
+}
+
+// AddFlag registers a -version flag on fs. After parsing, call Print and
+// exit when it is set.
+func AddFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("version", false, "print version information and exit")
+}
+
+// Print writes a one-line version summary prefixed with the program name.
+func Print(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, Get())
+}
@@ -0,0 +1,115 @@
+package buildinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type BuildInfoTestSuite struct {
+	suite.Suite
+}
+
+func (s *BuildInfoTestSuite) TestReadLdflags() {
+	i := read(nil, "v1.4.0", "0123456789abcdef0123", "2024-11-02T10:00:00Z")
+	s.Equal("v1.4.0", i.Version)
+	s.Equal("0123456789ab", i.ShortCommit())
+	s.Equal(time.Date(2024, 11, 2, 10, 0, 0, 0, time.UTC), i.BuildTime)
+	s.NotEmpty(i.GoVersion)
+	s.NotEmpty(i.Platform)
+}
+
+func (s *BuildInfoTestSuite) TestReadBuildInfoFallback() {
+	bi := &debug.BuildInfo{
+		GoVersion: "go1.23.2",
+		Main:      debug.Module{Path: "github.com/grid-stream-org/aggregator", Version: "v0.9.1"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "feedface"},
+			{Key: "vcs.time", Value: "2024-10-01T08:30:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	i := read(bi, "", "", "")
+	s.Equal("v0.9.1", i.Version)
+	s.Equal("feedface", i.Commit)
+	s.True(i.Dirty)
+	s.Equal("go1.23.2", i.GoVersion)
+	s.Equal("github.com/grid-stream-org/aggregator", i.Module)
+	s.Equal(time.Date(2024, 10, 1, 8, 30, 0, 0, time.UTC), i.BuildTime)
+
+	i = read(bi, "v1.0.0", "abc", "")
+	s.Equal("v1.0.0", i.Version)
+	s.Equal("abc", i.Commit)
+}
+
+func (s *BuildInfoTestSuite) TestReadUnknown() {
+	i := read(&debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, "", "", "")
+	s.Equal(unknown, i.Version)
+	s.Equal(unknown, i.Commit)
+	s.True(i.BuildTime.IsZero())
+	s.NotContains(i.String(), "built")
+}
+
+func (s *BuildInfoTestSuite) TestString() {
+	i := Info{Version: "v1.4.0", Commit: "feedface", Dirty: true, GoVersion: "go1.23.2", Platform: "linux/amd64"}
+	s.Equal("v1.4.0 (commit feedface-dirty, go1.23.2 linux/amd64)", i.String())
+}
+
+func (s *BuildInfoTestSuite) TestLogger() {
+	var buf bytes.Buffer
+	Logger(slog.New(slog.NewJSONHandler(&buf, nil))).Info("started")
+
+	var rec map[string]any
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &rec))
+	build, ok := rec["build"].(map[string]any)
+	s.Require().True(ok)
+	s.Equal(Get().Version, build["version"])
+}
+
+func (s *BuildInfoTestSuite) TestRegister() {
+	m, err := metrics.New(&metrics.Config{Namespace: "gridstream", Service: "aggregator"})
+	s.Require().NoError(err)
+	s.Require().NoError(Register(m))
+	s.Require().NoError(Register(m))
+
+	n, err := testutil.GatherAndCount(m.Registry(), "gridstream_build_info")
+	s.Require().NoError(err)
+	s.Equal(1, n)
+}
+
+func (s *BuildInfoTestSuite) TestHandler() {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	s.Equal(http.StatusOK, rec.Code)
+
+	var i Info
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &i))
+	s.Equal(Get().Version, i.Version)
+	s.Equal(Get().GoVersion, i.GoVersion)
+}
+
+func (s *BuildInfoTestSuite) TestFlag() {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	version := AddFlag(fs)
+	s.Require().NoError(fs.Parse([]string{"-version"}))
+	s.True(*version)
+
+	var buf bytes.Buffer
+	Print(&buf, "bqmigrate")
+	s.Contains(buf.String(), "bqmigrate "+Get().Version)
+}
+
+func TestBuildInfoSuite(t *testing.T) {
+	suite.Run(t, new(BuildInfoTestSuite))
+}
@@ -0,0 +1,78 @@
+// Package cache provides a byte-oriented cache abstraction with in-memory and
+// Redis implementations, plus typed helpers for loading values through it.
+// For values that never leave the process, LRU is a generic TTL and size
+// bounded cache that avoids the encoding round trip.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrMiss is returned by Get when the key is absent or expired.
+var ErrMiss = errors.New("cache miss")
+
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key. A ttl of zero means the entry does not
+	// expire, though it may still be evicted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Close() error
+}
+
+// LoadTimeout bounds a load shared by GetOrLoad, which is not cancelled
+// along with the context of the caller that started it.
+const LoadTimeout = 30 * time.Second
+
+var loads singleflight.Group
+
+// GetOrLoad returns the JSON-decoded value cached under key, calling load and
+// caching its result on a miss. Concurrent misses for the same key on the same
+// cache share a single call to load, which runs until it finishes or
+// LoadTimeout passes even if the caller that started it gives up, so other
+// callers still get its result. Cache read and write failures fall back to
+// load rather than failing the request.
+func GetOrLoad[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+
+	if data, err := c.Get(ctx, key); err == nil {
+		var v T
+		if err := json.Unmarshal(data, &v); err == nil {
+			return v, nil
+		}
+	}
+
+	// Loads are shared per type as well, since callers may decode the same
+	// key into different types.
+	ch := loads.DoChan(fmt.Sprintf("%p/%v/%s", c, reflect.TypeFor[T](), key), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), LoadTimeout)
+		defer cancel()
+		v, err := load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if data, err := json.Marshal(v); err == nil {
+			_ = c.Set(ctx, key, data, ttl)
+		}
+		return v, nil
+	})
+	select {
+	case <-ctx.Done():
+		return zero, errors.WithStack(ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// Val is nil rather than a T when T is an interface and load
+		// returned nil.
+		v, _ := res.Val.(T)
+		return v, nil
+	}
+}
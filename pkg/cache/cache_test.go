@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type project struct {
+	ID      string `json:"id"`
+	Utility string `json:"utility"`
+}
+
+type CacheTestSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.redis = miniredis.RunT(s.T())
+}
+
+func (s *CacheTestSuite) caches() map[string]Cache {
+	rc, err := NewRedis(context.Background(), &RedisConfig{Addr: s.redis.Addr(), KeyPrefix: "test:"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.Require().NoError(err)
+	return map[string]Cache{
+		"memory": NewMemory(10),
+		"redis":  rc,
+	}
+}
+
+func (s *CacheTestSuite) TestGetSetDelete() {
+	for name, c := range s.caches() {
+		s.Run(name, func() {
+			ctx := context.Background()
+			defer c.Close()
+
+			_, err := c.Get(ctx, "a")
+			s.ErrorIs(err, ErrMiss)
+
+			s.NoError(c.Set(ctx, "a", []byte("1"), time.Minute))
+			data, err := c.Get(ctx, "a")
+			s.NoError(err)
+			s.Equal([]byte("1"), data)
+
+			s.NoError(c.Delete(ctx, "a"))
+			_, err = c.Get(ctx, "a")
+			s.ErrorIs(err, ErrMiss)
+		})
+	}
+}
+
+func (s *CacheTestSuite) TestRedisKeyPrefix() {
+	c := s.caches()["redis"]
+	defer c.Close()
+
+	s.NoError(c.Set(context.Background(), "a", []byte("1"), time.Minute))
+	s.True(s.redis.Exists("test:a"))
+	s.Equal(time.Minute, s.redis.TTL("test:a"))
+}
+
+func (s *CacheTestSuite) TestMemoryExpiry() {
+	c := NewMemory(0).(*memoryCache)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.NoError(c.Set(ctx, "a", []byte("1"), time.Second))
+	s.NoError(c.Set(ctx, "b", []byte("2"), 0))
+
+	now = now.Add(2 * time.Second)
+	_, err := c.Get(ctx, "a")
+	s.ErrorIs(err, ErrMiss)
+	_, err = c.Get(ctx, "b")
+	s.NoError(err)
+}
+
+func (s *CacheTestSuite) TestMemoryEviction() {
+	c := NewMemory(2)
+	ctx := context.Background()
+
+	s.NoError(c.Set(ctx, "a", []byte("1"), 0))
+	s.NoError(c.Set(ctx, "b", []byte("2"), 0))
+	_, err := c.Get(ctx, "a")
+	s.NoError(err)
+	s.NoError(c.Set(ctx, "c", []byte("3"), 0))
+
+	_, err = c.Get(ctx, "b")
+	s.ErrorIs(err, ErrMiss)
+	_, err = c.Get(ctx, "a")
+	s.NoError(err)
+}
+
+func (s *CacheTestSuite) TestGetOrLoad() {
+	c := NewMemory(10)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) (*project, error) {
+		calls.Add(1)
+		<-release
+		return &project{ID: "p1", Utility: "u1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]*project, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := GetOrLoad(ctx, c, "project:p1", time.Minute, load)
+			s.NoError(err)
+			results[i] = v
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	s.Equal(int32(1), calls.Load())
+	for _, v := range results {
+		s.Equal(&project{ID: "p1", Utility: "u1"}, v)
+	}
+
+	v, err := GetOrLoad(ctx, c, "project:p1", time.Minute, load)
+	s.NoError(err)
+	s.Equal("u1", v.Utility)
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *CacheTestSuite) TestGetOrLoadCallerCancels() {
+	c := NewMemory(10)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	load := func(ctx context.Context) (int, error) {
+		once.Do(func() { close(started) })
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := GetOrLoad(ctx, c, "k", time.Minute, load)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan int, 1)
+	go func() {
+		v, err := GetOrLoad(context.Background(), c, "k", time.Minute, load)
+		s.NoError(err)
+		second <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	s.ErrorIs(<-first, context.Canceled)
+	close(release)
+	s.Equal(7, <-second)
+}
+
+func (s *CacheTestSuite) TestGetOrLoadTypes() {
+	c := NewMemory(10)
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan int, 1)
+	go func() {
+		v, err := GetOrLoad(ctx, c, "k", time.Minute, func(context.Context) (int, error) {
+			close(started)
+			<-release
+			return 7, nil
+		})
+		s.NoError(err)
+		done <- v
+	}()
+	<-started
+
+	// A load of another type is not shared with the one in flight.
+	v, err := GetOrLoad(ctx, c, "k", time.Minute, func(context.Context) (string, error) {
+		return "seven", nil
+	})
+	s.NoError(err)
+	s.Equal("seven", v)
+	close(release)
+	s.Equal(7, <-done)
+
+	a, err := GetOrLoad(ctx, c, "nil", time.Minute, func(context.Context) (any, error) {
+		return nil, nil
+	})
+	s.NoError(err)
+	s.Nil(a)
+}
+
+func (s *CacheTestSuite) TestGetOrLoadError() {
+	c := NewMemory(10)
+	_, err := GetOrLoad(context.Background(), c, "k", time.Minute, func(context.Context) (int, error) {
+		return 0, errors.New("bigquery unavailable")
+	})
+	s.Error(err)
+
+	_, err = c.Get(context.Background(), "k")
+	s.ErrorIs(err, ErrMiss)
+}
+
+func (s *CacheTestSuite) TestInstrument() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc", Env: "dev"})
+	s.Require().NoError(err)
+
+	c, err := Instrument(NewMemory(10), m, "projects")
+	s.Require().NoError(err)
+	ctx := context.Background()
+
+	_, _ = c.Get(ctx, "a")
+	s.NoError(c.Set(ctx, "a", []byte("1"), 0))
+	_, _ = c.Get(ctx, "a")
+
+	ops := c.(*instrumented).operations
+	s.Equal(1.0, testutil.ToFloat64(ops.WithLabelValues("projects", "get", "miss")))
+	s.Equal(1.0, testutil.ToFloat64(ops.WithLabelValues("projects", "get", "hit")))
+	s.Equal(1.0, testutil.ToFloat64(ops.WithLabelValues("projects", "set", "ok")))
+}
+
+func (s *CacheTestSuite) TestRedisConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *RedisConfig
+		expectError bool
+	}{
+		{name: "valid", cfg: &RedisConfig{Addr: "localhost:6379"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing addr", cfg: &RedisConfig{}, expectError: true},
+		{name: "negative db", cfg: &RedisConfig{Addr: "localhost:6379", DB: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
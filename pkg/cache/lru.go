@@ -0,0 +1,392 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EvictReason says why an entry left an LRU.
+type EvictReason string
+
+const (
+	EvictCapacity EvictReason = "capacity"
+	EvictExpired  EvictReason = "expired"
+	EvictDeleted  EvictReason = "deleted"
+)
+
+// Stats are cumulative LRU counters.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Evictions   uint64
+	Expirations uint64
+	Loads       uint64
+	LoadErrors  uint64
+}
+
+// Entry is a snapshot of one LRU entry. ExpiresAt is zero for entries that
+// do not expire.
+type Entry[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time
+}
+
+type LRUOption[K comparable, V any] func(*LRU[K, V])
+
+// WithEvictCallback calls fn after an entry is evicted, expires or is
+// deleted. It runs without the LRU's lock held, so it may use the LRU.
+func WithEvictCallback[K comparable, V any](fn func(key K, value V, reason EvictReason)) LRUOption[K, V] {
+	return func(c *LRU[K, V]) {
+		c.onEvict = fn
+	}
+}
+
+// WithLoader sets the function GetOrLoad calls on a miss.
+func WithLoader[K comparable, V any](fn func(ctx context.Context, key K) (V, error)) LRUOption[K, V] {
+	return func(c *LRU[K, V]) {
+		c.loader = fn
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock[K comparable, V any](now func() time.Time) LRUOption[K, V] {
+	return func(c *LRU[K, V]) {
+		c.now = now
+	}
+}
+
+// LRU is a typed in-process cache that evicts the least recently used entry
+// once maxEntries is reached and drops entries once their TTL has passed. It
+// is safe for concurrent use.
+type LRU[K comparable, V any] struct {
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+	onEvict    func(K, V, EvictReason)
+	loader     func(context.Context, K) (V, error)
+
+	mu      sync.Mutex
+	ll      *list.List
+	items   map[K]*list.Element
+	expiry  expiryHeap[K, V]
+	calls   map[K]*loadCall[V]
+	stats   Stats
+	evicted []evicted[K, V]
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+	// index is the entry's position in the expiry heap, or -1.
+	index int
+}
+
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// New returns an LRU holding at most maxEntries entries, each expiring ttl
+// after it is set. Zero for either means no limit.
+func New[K comparable, V any](maxEntries int, ttl time.Duration, opts ...LRUOption[K, V]) *LRU[K, V] {
+	c := &LRU[K, V]{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		ll:         list.New(),
+		items:      make(map[K]*list.Element),
+		calls:      make(map[K]*loadCall[V]),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get returns the value for key and marks it as recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.lookup(key)
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.ll.MoveToFront(c.items[key])
+	return e.value, true
+}
+
+// Peek returns the value for key without marking it as used or counting a
+// hit or miss.
+func (c *LRU[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	e, ok := c.lookup(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores value under key with the LRU's TTL.
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL stores value under key, expiring after ttl. A ttl of zero
+// means the entry does not expire.
+func (c *LRU[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.unlock()
+	c.set(key, value, ttl)
+}
+
+// AddIfAbsent stores value under key unless an unexpired entry exists, and
+// reports whether it did. An existing entry is not marked as used.
+func (c *LRU[K, V]) AddIfAbsent(key K, value V) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	if _, ok := c.lookup(key); ok {
+		return false
+	}
+	c.set(key, value, c.ttl)
+	return true
+}
+
+// Delete removes key and reports whether it was present.
+func (c *LRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		c.remove(el, EvictDeleted)
+	}
+	return ok
+}
+
+// Len returns the number of unexpired entries.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.expire()
+	return c.ll.Len()
+}
+
+// Entries returns a snapshot of the unexpired entries, least recently used
+// first.
+func (c *LRU[K, V]) Entries() []Entry[K, V] {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.expire()
+	out := make([]Entry[K, V], 0, c.ll.Len())
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(*lruEntry[K, V])
+		out = append(out, Entry[K, V]{Key: e.key, Value: e.value, ExpiresAt: e.expiresAt})
+	}
+	return out
+}
+
+// Purge removes every entry without calling the evict callback.
+func (c *LRU[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ll.Init()
+	c.items = make(map[K]*list.Element)
+	c.expiry = nil
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// GetOrLoad returns the value for key, calling the loader set with
+// WithLoader on a miss and caching its result. Concurrent misses for the
+// same key share one call. Loader errors are returned and not cached.
+func (c *LRU[K, V]) GetOrLoad(ctx context.Context, key K) (V, error) {
+	var zero V
+	if c.loader == nil {
+		return zero, errors.New("cache has no loader")
+	}
+	if v, ok := c.Get(key); ok {
+		return v, nil
+	}
+
+	c.mu.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		call = &loadCall[V]{done: make(chan struct{})}
+		c.calls[key] = call
+		c.stats.Loads++
+	}
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return zero, errors.WithStack(ctx.Err())
+		}
+	}
+
+	call.value, call.err = c.loader(ctx, key)
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if call.err != nil {
+		c.stats.LoadErrors++
+	} else {
+		c.set(key, call.value, c.ttl)
+	}
+	c.unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+// unlock releases the lock and then runs the evict callback for entries
+// removed while it was held.
+func (c *LRU[K, V]) unlock() {
+	pending := c.evicted
+	c.evicted = nil
+	c.mu.Unlock()
+
+	if c.onEvict == nil {
+		return
+	}
+	for _, e := range pending {
+		c.onEvict(e.key, e.value, e.reason)
+	}
+}
+
+// lookup returns the unexpired entry for key, removing it if it expired.
+func (c *LRU[K, V]) lookup(key K) (*lruEntry[K, V], bool) {
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry[K, V])
+	if !e.expiresAt.IsZero() && !c.now().Before(e.expiresAt) {
+		c.remove(el, EvictExpired)
+		return nil, false
+	}
+	return e, true
+}
+
+func (c *LRU[K, V]) set(key K, value V, ttl time.Duration) {
+	c.expire()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*lruEntry[K, V])
+		e.value = value
+		c.setExpiry(e, expiresAt)
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	e := &lruEntry[K, V]{key: key, value: value, index: -1}
+	c.items[key] = c.ll.PushFront(e)
+	c.setExpiry(e, expiresAt)
+	if c.maxEntries > 0 && c.ll.Len() > c.maxEntries {
+		c.remove(c.ll.Back(), EvictCapacity)
+	}
+}
+
+func (c *LRU[K, V]) setExpiry(e *lruEntry[K, V], expiresAt time.Time) {
+	e.expiresAt = expiresAt
+	switch {
+	case e.index >= 0 && expiresAt.IsZero():
+		heap.Remove(&c.expiry, e.index)
+	case e.index >= 0:
+		heap.Fix(&c.expiry, e.index)
+	case !expiresAt.IsZero():
+		heap.Push(&c.expiry, e)
+	}
+}
+
+// expire removes every entry whose TTL has passed.
+func (c *LRU[K, V]) expire() {
+	now := c.now()
+	for len(c.expiry) > 0 && !now.Before(c.expiry[0].expiresAt) {
+		c.remove(c.items[c.expiry[0].key], EvictExpired)
+	}
+}
+
+func (c *LRU[K, V]) remove(el *list.Element, reason EvictReason) {
+	e := el.Value.(*lruEntry[K, V])
+	c.ll.Remove(el)
+	delete(c.items, e.key)
+	if e.index >= 0 {
+		heap.Remove(&c.expiry, e.index)
+	}
+
+	switch reason {
+	case EvictCapacity:
+		c.stats.Evictions++
+	case EvictExpired:
+		c.stats.Expirations++
+	}
+	if c.onEvict != nil {
+		c.evicted = append(c.evicted, evicted[K, V]{key: e.key, value: e.value, reason: reason})
+	}
+}
+
+// expiryHeap orders entries by expiry time.
+type expiryHeap[K comparable, V any] []*lruEntry[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K, V]) Push(x any) {
+	e := x.(*lruEntry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *expiryHeap[K, V]) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	e.index = -1
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type LRUTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *LRUTestSuite) SetupTest() {
+	s.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func (s *LRUTestSuite) clock() LRUOption[string, int] {
+	return WithClock[string, int](func() time.Time { return s.now })
+}
+
+func (s *LRUTestSuite) TestEviction() {
+	type eviction struct {
+		key    string
+		reason EvictReason
+	}
+	var evictions []eviction
+	c := New(2, 0, s.clock(), WithEvictCallback(func(key string, _ int, reason EvictReason) {
+		evictions = append(evictions, eviction{key, reason})
+	}))
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, ok := c.Get("a")
+	s.True(ok)
+	c.Set("c", 3)
+
+	_, ok = c.Get("b")
+	s.False(ok, "b was least recently used")
+	s.True(c.Delete("a"))
+	s.False(c.Delete("a"))
+
+	s.Equal([]eviction{{"b", EvictCapacity}, {"a", EvictDeleted}}, evictions)
+	s.Equal(Stats{Hits: 1, Misses: 1, Evictions: 1}, c.Stats())
+}
+
+func (s *LRUTestSuite) TestTTL() {
+	var expired []string
+	c := New(0, time.Minute, s.clock(), WithEvictCallback(func(key string, _ int, reason EvictReason) {
+		if reason == EvictExpired {
+			expired = append(expired, key)
+		}
+	}))
+
+	c.Set("a", 1)
+	c.SetWithTTL("b", 2, 2*time.Minute)
+	c.SetWithTTL("forever", 3, 0)
+	s.Equal(3, c.Len())
+
+	s.now = s.now.Add(time.Minute)
+	_, ok := c.Get("a")
+	s.False(ok)
+	v, ok := c.Peek("b")
+	s.True(ok)
+	s.Equal(2, v)
+
+	// Resetting an entry restarts its TTL.
+	c.Set("b", 4)
+	s.now = s.now.Add(30 * time.Second)
+	s.Equal([]Entry[string, int]{
+		{Key: "forever", Value: 3},
+		{Key: "b", Value: 4, ExpiresAt: s.now.Add(30 * time.Second)},
+	}, c.Entries())
+
+	s.now = s.now.Add(time.Hour)
+	s.Equal(1, c.Len())
+	s.Equal([]string{"a", "b"}, expired)
+	s.Equal(uint64(2), c.Stats().Expirations)
+}
+
+func (s *LRUTestSuite) TestAddIfAbsent() {
+	c := New(2, time.Minute, s.clock())
+
+	s.True(c.AddIfAbsent("a", 1))
+	s.False(c.AddIfAbsent("a", 2))
+	v, _ := c.Peek("a")
+	s.Equal(1, v)
+
+	// AddIfAbsent does not refresh recency, so a is evicted first.
+	s.True(c.AddIfAbsent("b", 1))
+	s.False(c.AddIfAbsent("a", 3))
+	s.True(c.AddIfAbsent("c", 1))
+	_, ok := c.Peek("a")
+	s.False(ok)
+
+	s.now = s.now.Add(time.Minute)
+	s.True(c.AddIfAbsent("b", 5))
+}
+
+func (s *LRUTestSuite) TestGetOrLoad() {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	c := New(0, time.Minute, s.clock(), WithLoader(func(ctx context.Context, key string) (int, error) {
+		calls.Add(1)
+		<-release
+		if key == "bad" {
+			return 0, errors.New("boom")
+		}
+		return len(key), nil
+	}))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.GetOrLoad(context.Background(), "abc")
+			s.NoError(err)
+			s.Equal(3, v)
+		}()
+	}
+	s.Eventually(func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	v, err := c.GetOrLoad(context.Background(), "abc")
+	s.NoError(err)
+	s.Equal(3, v)
+	s.EqualValues(1, calls.Load())
+
+	_, err = c.GetOrLoad(context.Background(), "bad")
+	s.EqualError(err, "boom")
+	_, ok := c.Peek("bad")
+	s.False(ok)
+
+	stats := c.Stats()
+	s.Equal(uint64(2), stats.Loads)
+	s.Equal(uint64(1), stats.LoadErrors)
+
+	_, err = New[string, int](0, 0).GetOrLoad(context.Background(), "x")
+	s.Error(err)
+}
+
+func TestLRUTestSuite(t *testing.T) {
+	suite.Run(t, new(LRUTestSuite))
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+type memoryCache struct {
+	lru *LRU[string, []byte]
+	now func() time.Time
+}
+
+// NewMemory returns an in-process cache that evicts the least recently used
+// entry once maxEntries is reached. A maxEntries of zero means unbounded.
+func NewMemory(maxEntries int) Cache {
+	c := &memoryCache{now: time.Now}
+	c.lru = New(maxEntries, 0, WithClock[string, []byte](func() time.Time { return c.now() }))
+	return c
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, error) {
+	v, ok := c.lru.Get(key)
+	if !ok {
+		return nil, ErrMiss
+	}
+	return v, nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.lru.SetWithTTL(key, value, ttl)
+	return nil
+}
+
+func (c *memoryCache) Delete(_ context.Context, key string) error {
+	c.lru.Delete(key)
+	return nil
+}
+
+func (c *memoryCache) Close() error {
+	c.lru.Purge()
+	return nil
+}
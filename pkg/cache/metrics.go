@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type instrumented struct {
+	Cache
+	name       string
+	operations *prometheus.CounterVec
+}
+
+// Instrument wraps c so that every Get, Set and Delete is counted in the
+// <namespace>_cache_operations_total metric, labelled by cache name, operation and result
+// (hit, miss, ok or error).
+func Instrument(c Cache, m *metrics.Metrics, name string) (Cache, error) {
+	ops, err := m.NewCounterVec("cache", "operations_total", "Cache operations by cache, operation and result.", "cache", "op", "result")
+	if err != nil {
+		return nil, err
+	}
+	return &instrumented{Cache: c, name: name, operations: ops}, nil
+}
+
+func (c *instrumented) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.Cache.Get(ctx, key)
+	switch {
+	case err == nil:
+		c.operations.WithLabelValues(c.name, "get", "hit").Inc()
+	case errors.Is(err, ErrMiss):
+		c.operations.WithLabelValues(c.name, "get", "miss").Inc()
+	default:
+		c.operations.WithLabelValues(c.name, "get", "error").Inc()
+	}
+	return data, err
+}
+
+func (c *instrumented) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	err := c.Cache.Set(ctx, key, value, ttl)
+	c.operations.WithLabelValues(c.name, "set", result(err)).Inc()
+	return err
+}
+
+func (c *instrumented) Delete(ctx context.Context, key string) error {
+	err := c.Cache.Delete(ctx, key)
+	c.operations.WithLabelValues(c.name, "delete", result(err)).Inc()
+	return err
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisConfig struct {
+	Addr      string            `koanf:"addr" json:"addr" envconfig:"addr"`
+	Username  string            `koanf:"username" json:"username" envconfig:"username"`
+	Password  string            `koanf:"password" json:"password" envconfig:"password"`
+	DB        int               `koanf:"db" json:"db" envconfig:"db"`
+	KeyPrefix string            `koanf:"key_prefix" json:"key_prefix" envconfig:"key_prefix"`
+	TLS       *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+}
+
+type redisCache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func (c *RedisConfig) Validate() error {
+	if c == nil {
+		return errors.New("redis configuration required")
+	}
+	if c.Addr == "" {
+		return errors.New("redis addr required")
+	}
+	if c.DB < 0 {
+		return errors.New("redis db must not be negative")
+	}
+	return c.TLS.Validate()
+}
+
+// NewRedis connects to Redis and verifies the connection with a PING.
+func NewRedis(ctx context.Context, cfg *RedisConfig, log *slog.Logger) (Cache, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:      cfg.Addr,
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+		DB:        cfg.DB,
+		TLSConfig: tlsCfg,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, errors.Wrapf(err, "connecting to redis %s", cfg.Addr)
+	}
+
+	log.Info("redis cache connected", "addr", cfg.Addr, "db", cfg.DB)
+
+	return &redisCache{client: client, prefix: cfg.KeyPrefix}, nil
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrMiss
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return errors.WithStack(c.client.Set(ctx, c.prefix+key, value, ttl).Err())
+}
+
+func (c *redisCache) Delete(ctx context.Context, key string) error {
+	return errors.WithStack(c.client.Del(ctx, c.prefix+key).Err())
+}
+
+func (c *redisCache) Close() error {
+	return errors.WithStack(c.client.Close())
+}
@@ -0,0 +1,406 @@
+// Package calendar handles utility-local time: which IANA timezone a
+// utility operates in, its local billing days, time-of-use periods such as
+// peak and off-peak, and converting DR-event windows between UTC storage and
+// local display.
+//
+// Timestamps are stored in UTC; a Utility turns them into local answers:
+//
+//	cal, err := calendar.New(cfg)
+//	...
+//	u, err := cal.Utility("coned")
+//	day := u.BillingDay(reading.Timestamp)
+//	if u.Classify(reading.Timestamp) == calendar.Peak { ... }
+//
+// Zone data is embedded, so results do not depend on the host's zoneinfo.
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/grid-stream-org/go-commons/pkg/drevent"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/pkg/errors"
+)
+
+// DateLayout formats billing dates.
+const DateLayout = "2006-01-02"
+
+var ErrUnknownUtility = gserrors.New(gserrors.NotFound, "unknown utility")
+
+// Period names a time-of-use period. Utilities may define their own beyond
+// these.
+type Period string
+
+const (
+	Peak     Period = "peak"
+	Shoulder Period = "shoulder"
+	// OffPeak is the period of any time no configured period covers, and of
+	// weekends and holidays unless a period names them explicitly.
+	OffPeak Period = "off_peak"
+)
+
+// Weekdays are the days a PeriodConfig applies to when it names none.
+var Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+type PeriodConfig struct {
+	Name Period `koanf:"name" json:"name" envconfig:"name"`
+	// Start and End are local times of day as offsets from midnight. A period
+	// with End before Start wraps past midnight.
+	Start time.Duration `koanf:"start" json:"start" envconfig:"start"`
+	End   time.Duration `koanf:"end" json:"end" envconfig:"end"`
+	// Weekdays defaults to Monday through Friday.
+	Weekdays []time.Weekday `koanf:"weekdays" json:"weekdays" envconfig:"weekdays"`
+	// Months limits the period to a season. Empty means every month.
+	Months []time.Month `koanf:"months" json:"months" envconfig:"months"`
+	// Holidays applies the period on holidays, which are otherwise off-peak.
+	Holidays bool `koanf:"holidays" json:"holidays" envconfig:"holidays"`
+}
+
+func (c *PeriodConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("calendar period name required")
+	}
+	if c.Start < 0 || c.Start >= 24*time.Hour || c.End <= 0 || c.End > 24*time.Hour || c.Start == c.End {
+		return errors.Errorf("calendar period %s must start and end within the day", c.Name)
+	}
+	for _, wd := range c.Weekdays {
+		if wd < time.Sunday || wd > time.Saturday {
+			return errors.Errorf("calendar period %s has invalid weekday %d", c.Name, wd)
+		}
+	}
+	for _, m := range c.Months {
+		if m < time.January || m > time.December {
+			return errors.Errorf("calendar period %s has invalid month %d", c.Name, m)
+		}
+	}
+	return nil
+}
+
+type UtilityConfig struct {
+	// Timezone is an IANA name such as "America/New_York".
+	Timezone string `koanf:"timezone" json:"timezone" envconfig:"timezone"`
+	// BillingDayStart is when the local billing day begins, as an offset from
+	// midnight. Most utilities use midnight.
+	BillingDayStart time.Duration `koanf:"billing_day_start" json:"billing_day_start" envconfig:"billing_day_start"`
+	// Periods are checked in order; the first match classifies a time.
+	Periods []PeriodConfig `koanf:"periods" json:"periods" envconfig:"periods"`
+	// Holidays are local dates in DateLayout.
+	Holidays []string `koanf:"holidays" json:"holidays" envconfig:"holidays"`
+}
+
+func (c *UtilityConfig) Validate() error {
+	if c == nil {
+		return errors.New("utility calendar configuration required")
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil || c.Timezone == "" {
+		return errors.Errorf("invalid utility timezone %q", c.Timezone)
+	}
+	if c.BillingDayStart < 0 || c.BillingDayStart >= 24*time.Hour {
+		return errors.New("billing day start must be within the day")
+	}
+	for i := range c.Periods {
+		if err := c.Periods[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for _, h := range c.Holidays {
+		if _, err := time.Parse(DateLayout, h); err != nil {
+			return errors.Errorf("invalid holiday %q, want YYYY-MM-DD", h)
+		}
+	}
+	return nil
+}
+
+type Config struct {
+	// Utilities maps utility IDs to their calendars.
+	Utilities map[string]*UtilityConfig `koanf:"utilities" json:"utilities" envconfig:"utilities"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("calendar configuration required")
+	}
+	for id, u := range c.Utilities {
+		if err := u.Validate(); err != nil {
+			return errors.Wrapf(err, "utility %s", id)
+		}
+	}
+	return nil
+}
+
+// Calendar holds the calendars of all configured utilities.
+type Calendar struct {
+	utilities map[string]*Utility
+}
+
+func New(cfg *Config) (*Calendar, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Calendar{utilities: make(map[string]*Utility, len(cfg.Utilities))}
+	for id, ucfg := range cfg.Utilities {
+		u, err := NewUtility(id, ucfg)
+		if err != nil {
+			return nil, err
+		}
+		c.utilities[id] = u
+	}
+	return c, nil
+}
+
+// Utility returns the calendar of utility id, or ErrUnknownUtility.
+func (c *Calendar) Utility(id string) (*Utility, error) {
+	u, ok := c.utilities[id]
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownUtility, id)
+	}
+	return u, nil
+}
+
+// LocalEvent returns e in the timezone of its utility.
+func (c *Calendar) LocalEvent(e drevent.Event) (drevent.Event, error) {
+	u, err := c.Utility(e.UtilityID)
+	if err != nil {
+		return e, err
+	}
+	return u.LocalEvent(e), nil
+}
+
+// IDs returns the configured utility IDs in order.
+func (c *Calendar) IDs() []string {
+	ids := make([]string, 0, len(c.utilities))
+	for id := range c.utilities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Utility is one utility's calendar.
+type Utility struct {
+	ID       string
+	loc      *time.Location
+	dayStart time.Duration
+	periods  []period
+	holidays map[string]bool
+}
+
+type period struct {
+	PeriodConfig
+	weekdays [7]bool
+	months   [13]bool
+}
+
+func NewUtility(id string, cfg *UtilityConfig) (*Utility, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "utility %s", id)
+	}
+	loc, _ := time.LoadLocation(cfg.Timezone)
+	u := &Utility{ID: id, loc: loc, dayStart: cfg.BillingDayStart, holidays: map[string]bool{}}
+	for _, h := range cfg.Holidays {
+		u.holidays[h] = true
+	}
+	for _, pc := range cfg.Periods {
+		p := period{PeriodConfig: pc}
+		weekdays := pc.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = Weekdays
+		}
+		for _, wd := range weekdays {
+			p.weekdays[wd] = true
+		}
+		for _, m := range pc.Months {
+			p.months[m] = true
+		}
+		u.periods = append(u.periods, p)
+	}
+	return u, nil
+}
+
+func (u *Utility) Location() *time.Location {
+	return u.loc
+}
+
+// Local returns t in the utility's timezone.
+func (u *Utility) Local(t time.Time) time.Time {
+	return t.In(u.loc)
+}
+
+// Date returns the local calendar date of t in DateLayout.
+func (u *Utility) Date(t time.Time) string {
+	return t.In(u.loc).Format(DateLayout)
+}
+
+// ParseLocal parses a wall-clock value, such as a form input, as local time.
+func (u *Utility) ParseLocal(layout, value string) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, value, u.loc)
+	return t, errors.WithStack(err)
+}
+
+// IsHoliday reports whether t falls on a configured local holiday.
+func (u *Utility) IsHoliday(t time.Time) bool {
+	return u.holidays[u.Date(t)]
+}
+
+// BillingDay returns the local billing day containing t. On DST transition
+// days it is 23 or 25 hours long.
+func (u *Utility) BillingDay(t time.Time) timeutil.Window {
+	local := t.In(u.loc)
+	y, m, d := local.Date()
+	if local.Before(u.at(y, m, d, u.dayStart)) {
+		d--
+	}
+	return timeutil.Window{Start: u.at(y, m, d, u.dayStart), End: u.at(y, m, d+1, u.dayStart)}
+}
+
+// BillingDate returns the date, in DateLayout, of the billing day containing
+// t. A billing day that starts before midnight is named after its start.
+func (u *Utility) BillingDate(t time.Time) string {
+	return u.BillingDay(t).Start.Format(DateLayout)
+}
+
+// BillingDays returns the billing days overlapping [from, to).
+func (u *Utility) BillingDays(from, to time.Time) []timeutil.Window {
+	var days []timeutil.Window
+	for day := u.BillingDay(from); day.Start.Before(to); day = u.BillingDay(day.End) {
+		days = append(days, day)
+	}
+	return days
+}
+
+// Classify returns the time-of-use period of t.
+func (u *Utility) Classify(t time.Time) Period {
+	local := t.In(u.loc)
+	holiday := u.IsHoliday(local)
+	tod := sinceMidnight(local)
+	for i := range u.periods {
+		p := &u.periods[i]
+		if holiday && !p.Holidays {
+			continue
+		}
+		if p.matches(local, tod) {
+			return p.Name
+		}
+	}
+	return OffPeak
+}
+
+func (p *period) matches(local time.Time, tod time.Duration) bool {
+	day := local
+	if p.End < p.Start && tod < p.End {
+		// Early morning hours of a period that began the previous evening.
+		day = local.AddDate(0, 0, -1)
+	}
+	if !p.weekdays[day.Weekday()] {
+		return false
+	}
+	if len(p.Months) > 0 && !p.months[day.Month()] {
+		return false
+	}
+	if p.End < p.Start {
+		return tod >= p.Start || tod < p.End
+	}
+	return tod >= p.Start && tod < p.End
+}
+
+// Segment is a stretch of time within one period.
+type Segment struct {
+	timeutil.Window
+	Period Period
+}
+
+// Segments splits w at period boundaries, for example to bill a DR event
+// that runs from shoulder into peak hours. Adjacent segments always have
+// different periods.
+func (u *Utility) Segments(w timeutil.Window) []Segment {
+	var out []Segment
+	for cur := w.Start; cur.Before(w.End); {
+		next := u.nextBoundary(cur, w.End)
+		p := u.Classify(cur)
+		if n := len(out); n > 0 && out[n-1].Period == p {
+			out[n-1].End = next
+		} else {
+			out = append(out, Segment{Window: timeutil.Window{Start: cur, End: next}, Period: p})
+		}
+		cur = next
+	}
+	return out
+}
+
+// nextBoundary returns the first instant after t, capped at limit, where
+// the period could change: a period start or end, or local midnight.
+func (u *Utility) nextBoundary(t, limit time.Time) time.Time {
+	local := t.In(u.loc)
+	y, m, d := local.Date()
+	next := u.at(y, m, d+1, 0)
+	for i := range u.periods {
+		for _, tod := range []time.Duration{u.periods[i].Start, u.periods[i].End} {
+			if b := u.at(y, m, d, tod); b.After(t) && b.Before(next) {
+				next = b
+			}
+		}
+	}
+	if next.After(limit) {
+		return limit
+	}
+	return next
+}
+
+// ToLocal returns w with both ends in the utility's timezone, for display.
+func (u *Utility) ToLocal(w timeutil.Window) timeutil.Window {
+	return timeutil.Window{Start: w.Start.In(u.loc), End: w.End.In(u.loc)}
+}
+
+// LocalEvent returns e with its times in the utility's timezone, for display.
+func (u *Utility) LocalEvent(e drevent.Event) drevent.Event {
+	e.Start, e.End = e.Start.In(u.loc), e.End.In(u.loc)
+	return e
+}
+
+// ToUTC returns w with both ends in UTC, for storage.
+func ToUTC(w timeutil.Window) timeutil.Window {
+	return timeutil.Window{Start: w.Start.UTC(), End: w.End.UTC()}
+}
+
+// LocalWindow builds a window from a local date in DateLayout and local
+// times of day, such as a DR event entered as 2026-07-01 17:00 to 20:00.
+// An end at or before the start is on the next day.
+func (u *Utility) LocalWindow(date string, start, end time.Duration) (timeutil.Window, error) {
+	day, err := time.ParseInLocation(DateLayout, date, u.loc)
+	if err != nil {
+		return timeutil.Window{}, errors.WithStack(err)
+	}
+	y, m, d := day.Date()
+	w := timeutil.Window{Start: u.at(y, m, d, start), End: u.at(y, m, d, end)}
+	if !w.End.After(w.Start) {
+		w.End = u.at(y, m, d+1, end)
+	}
+	return w, nil
+}
+
+// FormatWindow renders w in local time, such as
+// "2026-07-01 17:00-20:00 EDT" or "2026-07-01 22:00 EDT - 2026-07-02 02:00 EDT".
+func (u *Utility) FormatWindow(w timeutil.Window) string {
+	start, end := w.Start.In(u.loc), w.End.In(u.loc)
+	if start.Format(DateLayout) == end.Format(DateLayout) && start.Format("MST") == end.Format("MST") {
+		return fmt.Sprintf("%s %s-%s %s", start.Format(DateLayout), start.Format("15:04"), end.Format("15:04"), start.Format("MST"))
+	}
+	return start.Format(DateLayout+" 15:04 MST") + " - " + end.Format(DateLayout+" 15:04 MST")
+}
+
+// at returns the instant of local time of day tod on the given date. Days
+// and hours overflow as in time.Date; a time in a DST gap is normalized by
+// time.Date.
+func (u *Utility) at(y int, m time.Month, d int, tod time.Duration) time.Time {
+	h, rest := tod/time.Hour, tod%time.Hour
+	return time.Date(y, m, d, int(h), 0, 0, int(rest), u.loc)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
@@ -0,0 +1,199 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/drevent"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type CalendarTestSuite struct {
+	suite.Suite
+	cal *Calendar
+	ny  *time.Location
+}
+
+func (s *CalendarTestSuite) SetupTest() {
+	var err error
+	s.ny, err = time.LoadLocation("America/New_York")
+	s.Require().NoError(err)
+
+	s.cal, err = New(&Config{Utilities: map[string]*UtilityConfig{
+		"coned": {
+			Timezone: "America/New_York",
+			Periods: []PeriodConfig{
+				{Name: Peak, Start: 14 * time.Hour, End: 19 * time.Hour, Months: []time.Month{time.June, time.July, time.August, time.September}},
+				{Name: Shoulder, Start: 8 * time.Hour, End: 22 * time.Hour},
+			},
+			Holidays: []string{"2026-07-03"},
+		},
+		"night": {
+			Timezone:        "America/Los_Angeles",
+			BillingDayStart: 6 * time.Hour,
+			Periods: []PeriodConfig{
+				{Name: "super_off_peak", Start: 22 * time.Hour, End: 6 * time.Hour, Weekdays: []time.Weekday{time.Friday}},
+			},
+		},
+	}})
+	s.Require().NoError(err)
+}
+
+func (s *CalendarTestSuite) utility(id string) *Utility {
+	u, err := s.cal.Utility(id)
+	s.Require().NoError(err)
+	return u
+}
+
+func (s *CalendarTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "empty", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "Europe/Berlin"}}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "nil utility", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": nil}}, expectError: true},
+		{name: "missing timezone", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {}}}, expectError: true},
+		{name: "unknown timezone", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "Mars/Olympus"}}}, expectError: true},
+		{name: "bad billing start", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "UTC", BillingDayStart: 25 * time.Hour}}}, expectError: true},
+		{name: "bad holiday", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "UTC", Holidays: []string{"07/04/2026"}}}}, expectError: true},
+		{name: "empty period", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "UTC", Periods: []PeriodConfig{{Name: Peak, Start: time.Hour, End: time.Hour}}}}}, expectError: true},
+		{name: "bad month", cfg: &Config{Utilities: map[string]*UtilityConfig{"u1": {Timezone: "UTC", Periods: []PeriodConfig{{Name: Peak, End: time.Hour, Months: []time.Month{13}}}}}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *CalendarTestSuite) TestUnknownUtility() {
+	_, err := s.cal.Utility("nope")
+	s.ErrorIs(err, ErrUnknownUtility)
+	s.Equal(gserrors.NotFound, gserrors.CodeOf(err))
+	s.Equal([]string{"coned", "night"}, s.cal.IDs())
+}
+
+func (s *CalendarTestSuite) TestBillingDay() {
+	u := s.utility("coned")
+
+	// 02:30 UTC is still the previous local day in New York.
+	t := time.Date(2026, 7, 2, 2, 30, 0, 0, time.UTC)
+	s.Equal("2026-07-01", u.BillingDate(t))
+	day := u.BillingDay(t)
+	s.Equal(time.Date(2026, 7, 1, 0, 0, 0, 0, s.ny), day.Start)
+	s.Equal(24*time.Hour, day.Duration())
+
+	// DST days are 23 and 25 hours long.
+	s.Equal(23*time.Hour, u.BillingDay(time.Date(2026, 3, 8, 12, 0, 0, 0, time.UTC)).Duration())
+	s.Equal(25*time.Hour, u.BillingDay(time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)).Duration())
+
+	days := u.BillingDays(time.Date(2026, 3, 7, 12, 0, 0, 0, s.ny), time.Date(2026, 3, 9, 0, 0, 0, 0, s.ny))
+	s.Require().Len(days, 2)
+	s.Equal(days[0].End, days[1].Start)
+}
+
+func (s *CalendarTestSuite) TestBillingDayStart() {
+	u := s.utility("night")
+	la := u.Location()
+
+	s.Equal("2026-07-01", u.BillingDate(time.Date(2026, 7, 2, 5, 59, 0, 0, la)))
+	s.Equal("2026-07-02", u.BillingDate(time.Date(2026, 7, 2, 6, 0, 0, 0, la)))
+	s.Equal(timeutil.Window{
+		Start: time.Date(2026, 7, 2, 6, 0, 0, 0, la),
+		End:   time.Date(2026, 7, 3, 6, 0, 0, 0, la),
+	}, u.BillingDay(time.Date(2026, 7, 2, 23, 0, 0, 0, la)))
+}
+
+func (s *CalendarTestSuite) TestClassify() {
+	u := s.utility("coned")
+	testCases := []struct {
+		name string
+		at   time.Time
+		want Period
+	}{
+		{name: "summer weekday afternoon", at: time.Date(2026, 7, 1, 15, 0, 0, 0, s.ny), want: Peak},
+		{name: "peak end is exclusive", at: time.Date(2026, 7, 1, 19, 0, 0, 0, s.ny), want: Shoulder},
+		{name: "winter afternoon", at: time.Date(2026, 1, 14, 15, 0, 0, 0, s.ny), want: Shoulder},
+		{name: "night", at: time.Date(2026, 7, 1, 23, 0, 0, 0, s.ny), want: OffPeak},
+		{name: "weekend", at: time.Date(2026, 7, 4, 15, 0, 0, 0, s.ny), want: OffPeak},
+		{name: "holiday", at: time.Date(2026, 7, 3, 15, 0, 0, 0, s.ny), want: OffPeak},
+		{name: "utc input", at: time.Date(2026, 7, 1, 19, 0, 0, 0, time.UTC), want: Peak},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.want, u.Classify(tc.at))
+		})
+	}
+}
+
+func (s *CalendarTestSuite) TestClassifyOvernight() {
+	u := s.utility("night")
+	la := u.Location()
+	s.Equal(Period("super_off_peak"), u.Classify(time.Date(2026, 7, 3, 23, 0, 0, 0, la)), "Friday night")
+	s.Equal(Period("super_off_peak"), u.Classify(time.Date(2026, 7, 4, 5, 0, 0, 0, la)), "early Saturday belongs to Friday's period")
+	s.Equal(OffPeak, u.Classify(time.Date(2026, 7, 4, 23, 0, 0, 0, la)), "Saturday night")
+}
+
+func (s *CalendarTestSuite) TestSegments() {
+	u := s.utility("coned")
+	w, err := u.LocalWindow("2026-07-01", 13*time.Hour, 23*time.Hour)
+	s.Require().NoError(err)
+
+	segs := u.Segments(w)
+	s.Require().Len(segs, 4)
+	want := []struct {
+		period     Period
+		start, end int
+	}{
+		{Shoulder, 13, 14},
+		{Peak, 14, 19},
+		{Shoulder, 19, 22},
+		{OffPeak, 22, 23},
+	}
+	for i, seg := range segs {
+		s.Equal(want[i].period, seg.Period)
+		s.Equal(time.Date(2026, 7, 1, want[i].start, 0, 0, 0, s.ny), seg.Start)
+		s.Equal(time.Date(2026, 7, 1, want[i].end, 0, 0, 0, s.ny), seg.End)
+	}
+}
+
+func (s *CalendarTestSuite) TestDREventConversion() {
+	u := s.utility("coned")
+	w, err := u.LocalWindow("2026-07-01", 17*time.Hour, 20*time.Hour)
+	s.Require().NoError(err)
+
+	stored := ToUTC(w)
+	s.Equal(time.UTC, stored.Start.Location())
+	s.Equal(time.Date(2026, 7, 1, 21, 0, 0, 0, time.UTC), stored.Start)
+	s.Equal("2026-07-01 17:00-20:00 EDT", u.FormatWindow(stored))
+
+	e, err := s.cal.LocalEvent(drevent.Event{ID: "e1", UtilityID: "coned", Start: stored.Start, End: stored.End})
+	s.Require().NoError(err)
+	s.Equal(17, e.Start.Hour())
+	s.True(e.Start.Equal(stored.Start))
+
+	overnight, err := u.LocalWindow("2026-11-01", 22*time.Hour, 2*time.Hour)
+	s.Require().NoError(err)
+	s.Equal(4*time.Hour, overnight.Duration())
+	s.Equal("2026-11-01 22:00 EST - 2026-11-02 02:00 EST", u.FormatWindow(overnight))
+
+	parsed, err := u.ParseLocal("2006-01-02 15:04", "2026-01-15 08:00")
+	s.Require().NoError(err)
+	s.Equal(time.Date(2026, 1, 15, 13, 0, 0, 0, time.UTC), parsed.UTC())
+}
+
+func TestCalendarTestSuite(t *testing.T) {
+	suite.Run(t, new(CalendarTestSuite))
+}
@@ -0,0 +1,127 @@
+// Package checkpoint persists named progress markers, such as Kafka offsets,
+// stream read positions and change feed watermarks, so consumers resume
+// where they left off after a restart.
+//
+// A Store saves checkpoints directly. A Committer sits in front of one and
+// batches saves for consumers that advance too quickly to write every step:
+//
+//	c := checkpoint.NewCommitter(store, "der_data-backfill", time.Second, log)
+//	go c.Run(ctx)
+//	...
+//	c.Mark(checkpoint.Checkpoint{Offset: row})
+package checkpoint
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+)
+
+// DefaultInterval is how often a Committer saves when given no interval.
+const DefaultInterval = 5 * time.Second
+
+var ErrNotFound = gserrors.New(gserrors.NotFound, "checkpoint not found")
+
+// Checkpoint records how far a named consumer has got. Consumers use
+// whichever fields suit them: a numeric Offset, an opaque Cursor such as a
+// page token, and a Watermark below which all data has been processed.
+type Checkpoint struct {
+	Name      string    `firestore:"name" json:"name"`
+	Offset    int64     `firestore:"offset" json:"offset"`
+	Cursor    string    `firestore:"cursor" json:"cursor,omitempty"`
+	Watermark time.Time `firestore:"watermark" json:"watermark"`
+	UpdatedAt time.Time `firestore:"updated_at" json:"updated_at"`
+}
+
+// Store persists checkpoints by name. Load returns ErrNotFound for a name
+// that has never been saved.
+type Store interface {
+	Load(ctx context.Context, name string) (*Checkpoint, error)
+	Save(ctx context.Context, cp *Checkpoint) error
+	Delete(ctx context.Context, name string) error
+}
+
+// LoadOrZero loads name from s, returning a zero Checkpoint for it if none
+// has been saved.
+func LoadOrZero(ctx context.Context, s Store, name string) (*Checkpoint, error) {
+	cp, err := s.Load(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return &Checkpoint{Name: name}, nil
+	}
+	return cp, err
+}
+
+// Committer batches checkpoint saves for one name, writing the latest
+// marked checkpoint every interval and when Run returns.
+type Committer struct {
+	store    Store
+	name     string
+	interval time.Duration
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	pending *Checkpoint
+}
+
+// NewCommitter returns a Committer saving to store under name. An interval
+// that is not positive means DefaultInterval.
+func NewCommitter(store Store, name string, interval time.Duration, log *slog.Logger) *Committer {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Committer{store: store, name: name, interval: interval, log: log}
+}
+
+// Mark records cp as the latest progress. It is saved on the next flush.
+func (c *Committer) Mark(cp Checkpoint) {
+	cp.Name = c.name
+	c.mu.Lock()
+	c.pending = &cp
+	c.mu.Unlock()
+}
+
+// Flush saves the latest marked checkpoint, if any. On failure it is kept
+// for the next flush unless a newer one has been marked since.
+func (c *Committer) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	cp := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	if cp == nil {
+		return nil
+	}
+
+	if err := c.store.Save(ctx, cp); err != nil {
+		c.mu.Lock()
+		if c.pending == nil {
+			c.pending = cp
+		}
+		c.mu.Unlock()
+		return errors.Wrapf(err, "saving checkpoint %s", c.name)
+	}
+	return nil
+}
+
+// Run flushes every interval until ctx is done, then flushes once more with
+// a short timeout so the final position is not lost.
+func (c *Committer) Run(ctx context.Context) error {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			return c.Flush(flushCtx)
+		case <-t.C:
+			if err := c.Flush(ctx); err != nil {
+				c.log.Warn("checkpoint flush failed", "name", c.name, "error", err)
+			}
+		}
+	}
+}
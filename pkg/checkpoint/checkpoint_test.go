@@ -0,0 +1,164 @@
+package checkpoint
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gcsclient"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeGCS keeps objects in a map.
+type fakeGCS struct {
+	gcsclient.GCSClient
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeGCS) Upload(_ context.Context, _, object string, data []byte, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[object] = data
+	return nil
+}
+
+func (f *fakeGCS) Download(_ context.Context, bucket, object string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[object]
+	if !ok {
+		return nil, errors.Wrapf(gcsclient.ErrNotFound, "gs://%s/%s", bucket, object)
+	}
+	return data, nil
+}
+
+func (f *fakeGCS) Delete(_ context.Context, bucket, object string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.objects[object]; !ok {
+		return errors.Wrapf(gcsclient.ErrNotFound, "gs://%s/%s", bucket, object)
+	}
+	delete(f.objects, object)
+	return nil
+}
+
+// failingStore fails every Save until ok is set.
+type failingStore struct {
+	*MemoryStore
+	mu sync.Mutex
+	ok bool
+}
+
+func (s *failingStore) Save(ctx context.Context, cp *Checkpoint) error {
+	s.mu.Lock()
+	ok := s.ok
+	s.mu.Unlock()
+	if !ok {
+		return errors.New("unavailable")
+	}
+	return s.MemoryStore.Save(ctx, cp)
+}
+
+type CheckpointTestSuite struct {
+	suite.Suite
+}
+
+func (s *CheckpointTestSuite) stores() map[string]Store {
+	file, err := File(s.T().TempDir())
+	s.Require().NoError(err)
+	return map[string]Store{
+		"file":   file,
+		"gcs":    GCS(&fakeGCS{objects: map[string][]byte{}}, "bucket", "checkpoints"),
+		"memory": NewMemoryStore(),
+	}
+}
+
+func (s *CheckpointTestSuite) TestStores() {
+	ctx := context.Background()
+	watermark := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for name, store := range s.stores() {
+		s.Run(name, func() {
+			_, err := store.Load(ctx, "kafka/readings:0")
+			s.ErrorIs(err, ErrNotFound)
+			cp, err := LoadOrZero(ctx, store, "kafka/readings:0")
+			s.Require().NoError(err)
+			s.Equal(&Checkpoint{Name: "kafka/readings:0"}, cp)
+
+			s.Require().NoError(store.Save(ctx, &Checkpoint{Name: "kafka/readings:0", Offset: 42, Cursor: "tok", Watermark: watermark}))
+			cp, err = store.Load(ctx, "kafka/readings:0")
+			s.Require().NoError(err)
+			s.Equal(int64(42), cp.Offset)
+			s.Equal("tok", cp.Cursor)
+			s.True(watermark.Equal(cp.Watermark))
+			s.False(cp.UpdatedAt.IsZero())
+
+			s.Error(store.Save(ctx, &Checkpoint{}))
+			s.Require().NoError(store.Delete(ctx, "kafka/readings:0"))
+			s.NoError(store.Delete(ctx, "kafka/readings:0"))
+			_, err = store.Load(ctx, "kafka/readings:0")
+			s.ErrorIs(err, ErrNotFound)
+		})
+	}
+}
+
+func (s *CheckpointTestSuite) TestCommitter() {
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	c := NewCommitter(store, "backfill", time.Hour, logger.Default())
+	ctx := context.Background()
+
+	s.NoError(c.Flush(ctx), "nothing marked")
+
+	c.Mark(Checkpoint{Offset: 1})
+	s.Error(c.Flush(ctx))
+
+	// The failed checkpoint is retried on the next flush.
+	store.ok = true
+	s.Require().NoError(c.Flush(ctx))
+	cp, err := store.Load(ctx, "backfill")
+	s.Require().NoError(err)
+	s.Equal(int64(1), cp.Offset)
+
+	c.Mark(Checkpoint{Offset: 2})
+	c.Mark(Checkpoint{Offset: 3})
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	s.NoError(c.Run(runCtx))
+	cp, err = store.Load(ctx, "backfill")
+	s.Require().NoError(err)
+	s.Equal(int64(3), cp.Offset)
+}
+
+func (s *CheckpointTestSuite) TestCommitterInterval() {
+	store := NewMemoryStore()
+	c := NewCommitter(store, "feed", 5*time.Millisecond, logger.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	c.Mark(Checkpoint{Cursor: "page-2"})
+	s.Eventually(func() bool {
+		cp, err := store.Load(context.Background(), "feed")
+		return err == nil && cp.Cursor == "page-2"
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	s.NoError(<-done)
+}
+
+func (s *CheckpointTestSuite) TestCommitterDefaultInterval() {
+	c := NewCommitter(NewMemoryStore(), "feed", 0, logger.Default())
+	s.Equal(DefaultInterval, c.interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.NoError(c.Run(ctx))
+}
+
+func TestCheckpointTestSuite(t *testing.T) {
+	suite.Run(t, new(CheckpointTestSuite))
+}
@@ -0,0 +1,215 @@
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	gsfirestore "github.com/grid-stream-org/go-commons/pkg/firestore"
+	"github.com/grid-stream-org/go-commons/pkg/gcsclient"
+	"github.com/pkg/errors"
+)
+
+// stamp returns a copy of cp with UpdatedAt set to now.
+func stamp(cp *Checkpoint) *Checkpoint {
+	out := *cp
+	out.UpdatedAt = time.Now().UTC()
+	return &out
+}
+
+func validName(name string) error {
+	if name == "" {
+		return errors.New("checkpoint name required")
+	}
+	return nil
+}
+
+type fileStore struct {
+	dir string
+}
+
+// File stores each checkpoint as a JSON file in dir, which is created if
+// missing.
+func File(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (s *fileStore) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".json")
+}
+
+func (s *fileStore) Load(_ context.Context, name string) (*Checkpoint, error) {
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errors.Wrapf(ErrNotFound, "checkpoint %s", name)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, errors.Wrapf(err, "decoding checkpoint %s", name)
+	}
+	return cp, nil
+}
+
+// Save writes to a temporary file and renames it so a crash never leaves a
+// truncated checkpoint behind.
+func (s *fileStore) Save(_ context.Context, cp *Checkpoint) error {
+	if err := validName(cp.Name); err != nil {
+		return err
+	}
+	data, err := json.Marshal(stamp(cp))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	target := s.path(cp.Name)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(target)+".*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), target))
+}
+
+func (s *fileStore) Delete(_ context.Context, name string) error {
+	err := os.Remove(s.path(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return errors.WithStack(err)
+}
+
+type firestoreStore struct {
+	checkpoints *gsfirestore.Collection[Checkpoint]
+}
+
+// Firestore stores one document per checkpoint name in collection.
+func Firestore(c gsfirestore.FirestoreClient, collection string) Store {
+	return &firestoreStore{checkpoints: gsfirestore.NewCollection[Checkpoint](c, collection)}
+}
+
+func (s *firestoreStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	cp, err := s.checkpoints.Get(ctx, name)
+	if errors.Is(err, gsfirestore.ErrNotFound) {
+		return nil, errors.Wrapf(ErrNotFound, "checkpoint %s", name)
+	}
+	return cp, err
+}
+
+func (s *firestoreStore) Save(ctx context.Context, cp *Checkpoint) error {
+	if err := validName(cp.Name); err != nil {
+		return err
+	}
+	return s.checkpoints.Set(ctx, cp.Name, stamp(cp))
+}
+
+func (s *firestoreStore) Delete(ctx context.Context, name string) error {
+	return s.checkpoints.Delete(ctx, name)
+}
+
+type gcsStore struct {
+	client gcsclient.GCSClient
+	bucket string
+	prefix string
+}
+
+// GCS stores each checkpoint as a JSON object under prefix in bucket.
+func GCS(client gcsclient.GCSClient, bucket, prefix string) Store {
+	return &gcsStore{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s *gcsStore) object(name string) string {
+	return path.Join(s.prefix, url.PathEscape(name)+".json")
+}
+
+func (s *gcsStore) Load(ctx context.Context, name string) (*Checkpoint, error) {
+	data, err := s.client.Download(ctx, s.bucket, s.object(name))
+	if errors.Is(err, gcsclient.ErrNotFound) {
+		return nil, errors.Wrapf(ErrNotFound, "checkpoint %s", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, errors.Wrapf(err, "decoding checkpoint %s", name)
+	}
+	return cp, nil
+}
+
+func (s *gcsStore) Save(ctx context.Context, cp *Checkpoint) error {
+	if err := validName(cp.Name); err != nil {
+		return err
+	}
+	data, err := json.Marshal(stamp(cp))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.client.Upload(ctx, s.bucket, s.object(cp.Name), data, "application/json")
+}
+
+func (s *gcsStore) Delete(ctx context.Context, name string) error {
+	err := s.client.Delete(ctx, s.bucket, s.object(name))
+	if errors.Is(err, gcsclient.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// MemoryStore holds checkpoints in memory, for tests and local development.
+type MemoryStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{checkpoints: map[string]Checkpoint{}}
+}
+
+func (s *MemoryStore) Load(_ context.Context, name string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cp, ok := s.checkpoints[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrNotFound, "checkpoint %s", name)
+	}
+	return &cp, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, cp *Checkpoint) error {
+	if err := validName(cp.Name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoints[cp.Name] = *stamp(cp)
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.checkpoints, name)
+	return nil
+}
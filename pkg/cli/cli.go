@@ -0,0 +1,261 @@
+// Package cli bootstraps grid-stream binaries: it parses flags, loads the
+// typed config with pkg/config, builds the logger, installs signal handling
+// with sigctx and handles -version, then calls the app.
+//
+//	type Config struct {
+//		Logger   *logger.Config   `koanf:"logger" json:"logger"`
+//		Database *bqclient.Config `koanf:"database" json:"database"`
+//	}
+//
+//	func main() {
+//		cli.Run("aggregator", func(ctx context.Context, cfg *Config) error {
+//			log := cli.Logger(ctx)
+//			...
+//		})
+//	}
+//
+// Config is layered as described in pkg/config: defaults, including the
+// profile defaults for the environment named by GS_ENV (prod when unset),
+// the file given by -config and its profile file, environment variables
+// prefixed with the upper-cased app name (for example
+// AGGREGATOR_DATABASE__PROJECT_ID), then any flags registered with
+// WithFlags whose names match config keys. The flags -config and -version
+// are reserved. Every log record carries the environment as env.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/grid-stream-org/go-commons/pkg/buildinfo"
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/sigctx"
+	"github.com/pkg/errors"
+)
+
+// Exit codes returned by Exec.
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// UsageError reports a command line mistake. Exec prints the usage and exits
+// with ExitUsage.
+type UsageError struct {
+	Msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+// Usagef returns a *UsageError with a formatted message.
+func Usagef(format string, args ...any) error {
+	return &UsageError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// Env is what Exec set up for the app, available through FromContext.
+type Env struct {
+	Name        string
+	Environment config.Env
+	Log         *slog.Logger
+	// Args are the positional arguments left after flags.
+	Args []string
+}
+
+type envKey struct{}
+
+// FromContext returns the Env of the running app, if any.
+func FromContext(ctx context.Context) (*Env, bool) {
+	env, ok := ctx.Value(envKey{}).(*Env)
+	return env, ok
+}
+
+// Logger returns the app's logger, or logger.Default outside an app.
+func Logger(ctx context.Context) *slog.Logger {
+	if env, ok := FromContext(ctx); ok {
+		return env.Log
+	}
+	return logger.Default()
+}
+
+type Option func(*options)
+
+type options struct {
+	args      []string
+	envPrefix *string
+	defaults  map[string]any
+	flags     func(fs *flag.FlagSet)
+	usage     string
+	stdout    io.Writer
+	stderr    io.Writer
+}
+
+// WithArgs replaces os.Args[1:], mainly for tests.
+func WithArgs(args []string) Option {
+	return func(o *options) {
+		o.args = args
+	}
+}
+
+// WithEnvPrefix overrides the environment variable prefix. An empty prefix
+// disables environment loading.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) {
+		o.envPrefix = &prefix
+	}
+}
+
+// WithDefaults sets default config values keyed by their dotted koanf path.
+func WithDefaults(defaults map[string]any) Option {
+	return func(o *options) {
+		o.defaults = defaults
+	}
+}
+
+// WithFlags registers extra flags. Flags named after a dotted config key,
+// such as -logger.level, override that key when set.
+func WithFlags(fn func(fs *flag.FlagSet)) Option {
+	return func(o *options) {
+		o.flags = fn
+	}
+}
+
+// WithUsage sets the synopsis printed after the app name in usage output,
+// for example "[flags] status|up|down".
+func WithUsage(usage string) Option {
+	return func(o *options) {
+		o.usage = usage
+	}
+}
+
+// WithOutput redirects version and usage output, mainly for tests.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(o *options) {
+		o.stdout = stdout
+		o.stderr = stderr
+	}
+}
+
+// Run calls Exec and exits the process with its result.
+func Run[T any](name string, app func(ctx context.Context, cfg *T) error, opts ...Option) {
+	os.Exit(Exec(name, app, opts...))
+}
+
+// Exec runs app and returns the process exit code. An app that stops because
+// of SIGINT or SIGTERM exits with ExitOK.
+func Exec[T any](name string, app func(ctx context.Context, cfg *T) error, opts ...Option) int {
+	o := &options{
+		args:   os.Args[1:],
+		usage:  "[flags]",
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(o.stderr)
+	cfgPath := fs.String("config", "", "YAML or JSON config file")
+	version := buildinfo.AddFlag(fs)
+	if o.flags != nil {
+		o.flags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s %s\n", name, o.usage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(o.args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if *version {
+		buildinfo.Print(o.stdout, name)
+		return ExitOK
+	}
+
+	cfg := new(T)
+	env := config.Environment()
+	loggerCfg := findLoggerConfig(cfg)
+	if err := config.Load(cfg,
+		config.WithEnvironment(env),
+		config.WithDefaults(o.defaults),
+		config.WithFile(*cfgPath),
+		config.WithEnvPrefix(o.prefix(name)),
+		config.WithFlags(fs),
+	); err != nil {
+		fmt.Fprintf(o.stderr, "%s: %v\n", name, err)
+		return ExitError
+	}
+
+	log := logger.Default()
+	if lc := loggerCfg(); lc != nil {
+		l, err := logger.New(lc, nil)
+		if err != nil {
+			fmt.Fprintf(o.stderr, "%s: %v\n", name, err)
+			return ExitError
+		}
+		log = l
+	}
+	log = buildinfo.Logger(log).With("env", env.String())
+
+	ctx, cancel := sigctx.New(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, envKey{}, &Env{Name: name, Environment: env, Log: log, Args: fs.Args()})
+
+	err := app(ctx, cfg)
+	var usageErr *UsageError
+	var sigErr *sigctx.SignalError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(o.stderr, "%s: %v\n", name, err)
+		fs.Usage()
+		return ExitUsage
+	case errors.As(err, &sigErr), errors.Is(err, context.Canceled) && errors.As(ctx.Err(), &sigErr):
+		log.Info("stopped", "signal", sigErr.Signal.String())
+		return ExitOK
+	default:
+		log.Error(name+" failed", "error", err)
+		return ExitError
+	}
+}
+
+func (o *options) prefix(name string) string {
+	if o.envPrefix != nil {
+		return *o.envPrefix
+	}
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+// findLoggerConfig looks for a top-level *logger.Config field in cfg and
+// returns a func reading it after loading. Its level and format come from
+// the logger's profile defaults.
+func findLoggerConfig(cfg any) func() *logger.Config {
+	v := reflect.ValueOf(cfg).Elem()
+	if v.Kind() != reflect.Struct {
+		return func() *logger.Config { return nil }
+	}
+	loggerType := reflect.TypeOf(&logger.Config{})
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		if f.Type != loggerType || !f.IsExported() {
+			continue
+		}
+		field := v.Field(i)
+		return func() *logger.Config { return field.Interface().(*logger.Config) }
+	}
+	return func() *logger.Config { return nil }
+}
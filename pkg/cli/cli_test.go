@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/sigctx"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type testConfig struct {
+	Logger *logger.Config `koanf:"logger" json:"logger"`
+	Site   string         `koanf:"site" json:"site"`
+	Limit  int            `koanf:"limit" json:"limit"`
+}
+
+type CLITestSuite struct {
+	suite.Suite
+	stdout *bytes.Buffer
+	stderr *bytes.Buffer
+}
+
+func (s *CLITestSuite) SetupTest() {
+	s.stdout = &bytes.Buffer{}
+	s.stderr = &bytes.Buffer{}
+}
+
+func (s *CLITestSuite) exec(args []string, app func(ctx context.Context, cfg *testConfig) error, opts ...Option) int {
+	opts = append([]Option{WithArgs(args), WithOutput(s.stdout, s.stderr)}, opts...)
+	return Exec("gs-tool", app, opts...)
+}
+
+func (s *CLITestSuite) TestConfigLayers() {
+	path := filepath.Join(s.T().TempDir(), "cfg.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte("site: file\nlimit: 1\nlogger:\n  level: WARN\n"), 0o600))
+	s.T().Setenv("GS_TOOL_LIMIT", "2")
+	s.T().Setenv(config.EnvVar, "production")
+
+	var got *testConfig
+	var env *Env
+	code := s.exec([]string{"-config", path, "-site", "flag", "extra"}, func(ctx context.Context, cfg *testConfig) error {
+		got = cfg
+		env, _ = FromContext(ctx)
+		s.NotNil(Logger(ctx))
+		return nil
+	}, WithFlags(func(fs *flag.FlagSet) {
+		fs.String("site", "", "site name")
+	}))
+
+	s.Equal(ExitOK, code)
+	s.Equal("flag", got.Site)
+	s.Equal(2, got.Limit)
+	s.Equal("WARN", got.Logger.Level)
+	s.Equal("json", got.Logger.Format, "logger profile defaults fill unset fields")
+	s.Equal("gs-tool", env.Name)
+	s.Equal(config.Prod, env.Environment)
+	s.Equal([]string{"extra"}, env.Args)
+}
+
+func (s *CLITestSuite) TestVersion() {
+	code := s.exec([]string{"-version"}, func(context.Context, *testConfig) error {
+		s.Fail("app should not run")
+		return nil
+	})
+	s.Equal(ExitOK, code)
+	s.Contains(s.stdout.String(), "gs-tool ")
+}
+
+func (s *CLITestSuite) TestExitCodes() {
+	testCases := []struct {
+		name string
+		args []string
+		err  error
+		want int
+	}{
+		{name: "bad flag", args: []string{"-nope"}, want: ExitUsage},
+		{name: "help", args: []string{"-h"}, want: ExitOK},
+		{name: "invalid config", args: []string{"-config", "missing.yaml"}, want: ExitError},
+		{name: "app error", err: errors.New("boom"), want: ExitError},
+		{name: "usage error", err: Usagef("unknown command %q", "x"), want: ExitUsage},
+		{name: "signal", err: errors.WithStack(&sigctx.SignalError{Signal: syscall.SIGTERM}), want: ExitOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			code := s.exec(tc.args, func(context.Context, *testConfig) error { return tc.err })
+			s.Equal(tc.want, code)
+		})
+	}
+	s.Contains(s.stderr.String(), "usage: gs-tool [flags]")
+}
+
+func (s *CLITestSuite) TestEnvironment() {
+	s.T().Setenv(config.EnvVar, "")
+	var got *testConfig
+	code := s.exec(nil, func(_ context.Context, cfg *testConfig) error {
+		got = cfg
+		return nil
+	})
+	s.Equal(ExitOK, code)
+	s.Equal("INFO", got.Logger.Level, "prod when GS_ENV is unset")
+	s.Equal("json", got.Logger.Format)
+
+	s.T().Setenv(config.EnvVar, "dev")
+	code = s.exec(nil, func(_ context.Context, cfg *testConfig) error {
+		got = cfg
+		return nil
+	})
+	s.Equal(ExitOK, code)
+	s.Equal("DEBUG", got.Logger.Level)
+	s.Equal("text", got.Logger.Format)
+
+	s.T().Setenv(config.EnvVar, "qa")
+	code = s.exec(nil, func(context.Context, *testConfig) error { return nil })
+	s.Equal(ExitError, code)
+	s.Contains(s.stderr.String(), `unknown environment "qa"`)
+}
+
+func (s *CLITestSuite) TestEnvPrefix() {
+	s.T().Setenv("CUSTOM_SITE", "env")
+	var site string
+	code := s.exec(nil, func(_ context.Context, cfg *testConfig) error {
+		site = cfg.Site
+		return nil
+	}, WithEnvPrefix("CUSTOM_"), WithDefaults(map[string]any{"limit": 5}))
+	s.Equal(ExitOK, code)
+	s.Equal("env", site)
+}
+
+func (s *CLITestSuite) TestNoLoggerField() {
+	type plain struct {
+		Name string `koanf:"name" json:"name"`
+	}
+	code := Exec("plain", func(ctx context.Context, cfg *plain) error {
+		s.NotNil(Logger(ctx))
+		return nil
+	}, WithArgs(nil), WithOutput(s.stdout, s.stderr))
+	s.Equal(ExitOK, code)
+}
+
+func TestCLITestSuite(t *testing.T) {
+	suite.Run(t, new(CLITestSuite))
+}
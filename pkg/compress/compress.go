@@ -0,0 +1,210 @@
+// Package compress provides pooled gzip and zstd streams, one-shot helpers
+// for small payloads such as MQTT messages, and HTTP Accept-Encoding
+// negotiation.
+//
+//	w, err := compress.NewWriter(f, compress.Zstd)
+//	...
+//	defer w.Close()
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Encoding is a content coding, named as in HTTP Content-Encoding.
+type Encoding string
+
+const (
+	Identity Encoding = "identity"
+	Gzip     Encoding = "gzip"
+	Zstd     Encoding = "zstd"
+)
+
+var ErrTooLarge = errors.New("decompressed data exceeds limit")
+
+// ParseEncoding returns the Encoding named s. An empty s is Identity.
+func ParseEncoding(s string) (Encoding, error) {
+	switch enc := Encoding(s); enc {
+	case "":
+		return Identity, nil
+	case Identity, Gzip, Zstd:
+		return enc, nil
+	default:
+		return "", errors.Errorf("unsupported encoding %q", s)
+	}
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	gzipReaders sync.Pool
+	zstdWriters = sync.Pool{New: func() any {
+		zw, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return zw
+	}}
+	zstdReaders = sync.Pool{New: func() any {
+		zr, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		return zr
+	}}
+)
+
+// Writer compresses to an underlying writer. Close flushes the stream and
+// returns the encoder to a pool; it does not close the underlying writer.
+type Writer struct {
+	w  io.Writer
+	gz *gzip.Writer
+	zw *zstd.Encoder
+}
+
+// NewWriter returns a Writer that compresses to w with enc.
+func NewWriter(w io.Writer, enc Encoding) (*Writer, error) {
+	switch enc {
+	case Identity, "":
+		return &Writer{w: w}, nil
+	case Gzip:
+		gz := gzipWriters.Get().(*gzip.Writer)
+		gz.Reset(w)
+		return &Writer{w: gz, gz: gz}, nil
+	case Zstd:
+		zw := zstdWriters.Get().(*zstd.Encoder)
+		zw.Reset(w)
+		return &Writer{w: zw, zw: zw}, nil
+	default:
+		return nil, errors.Errorf("unsupported encoding %q", enc)
+	}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return 0, errors.New("write to closed compress writer")
+	}
+	return w.w.Write(p)
+}
+
+// Flush writes any buffered data so the reader can decode everything written
+// so far, as streaming responses need.
+func (w *Writer) Flush() error {
+	switch {
+	case w.gz != nil:
+		return errors.WithStack(w.gz.Flush())
+	case w.zw != nil:
+		return errors.WithStack(w.zw.Flush())
+	}
+	return nil
+}
+
+func (w *Writer) Close() error {
+	var err error
+	switch {
+	case w.gz != nil:
+		err = w.gz.Close()
+		w.gz.Reset(io.Discard)
+		gzipWriters.Put(w.gz)
+	case w.zw != nil:
+		err = w.zw.Close()
+		w.zw.Reset(nil)
+		zstdWriters.Put(w.zw)
+	}
+	w.w, w.gz, w.zw = nil, nil, nil
+	return errors.WithStack(err)
+}
+
+// Reader decompresses from an underlying reader. Close returns the decoder to
+// a pool; it does not close the underlying reader.
+type Reader struct {
+	r  io.Reader
+	gz *gzip.Reader
+	zr *zstd.Decoder
+}
+
+// NewReader returns a Reader that decompresses r with enc.
+func NewReader(r io.Reader, enc Encoding) (*Reader, error) {
+	switch enc {
+	case Identity, "":
+		return &Reader{r: r}, nil
+	case Gzip:
+		gz, _ := gzipReaders.Get().(*gzip.Reader)
+		if gz == nil {
+			gz = new(gzip.Reader)
+		}
+		if err := gz.Reset(r); err != nil {
+			gzipReaders.Put(gz)
+			return nil, errors.Wrap(err, "reading gzip header")
+		}
+		return &Reader{r: gz, gz: gz}, nil
+	case Zstd:
+		zr := zstdReaders.Get().(*zstd.Decoder)
+		if err := zr.Reset(r); err != nil {
+			zstdReaders.Put(zr)
+			return nil, errors.WithStack(err)
+		}
+		return &Reader{r: zr, zr: zr}, nil
+	default:
+		return nil, errors.Errorf("unsupported encoding %q", enc)
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		return 0, errors.New("read from closed compress reader")
+	}
+	return r.r.Read(p)
+}
+
+func (r *Reader) Close() error {
+	switch {
+	case r.gz != nil:
+		gzipReaders.Put(r.gz)
+	case r.zr != nil:
+		_ = r.zr.Reset(nil)
+		zstdReaders.Put(r.zr)
+	}
+	r.r, r.gz, r.zr = nil, nil, nil
+	return nil
+}
+
+// Compress returns b compressed with enc.
+func Compress(enc Encoding, b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, enc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		_ = w.Close()
+		return nil, errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns b decompressed with enc. If limit is positive and the
+// output would exceed it, ErrTooLarge is returned, guarding against
+// decompression bombs in untrusted payloads.
+func Decompress(enc Encoding, b []byte, limit int64) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(b), enc)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var src io.Reader = r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(src)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, errors.Wrapf(ErrTooLarge, "limit %d bytes", limit)
+	}
+	return out, nil
+}
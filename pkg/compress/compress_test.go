@@ -0,0 +1,112 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CompressTestSuite struct {
+	suite.Suite
+}
+
+func (s *CompressTestSuite) TestRoundTrip() {
+	payload := []byte(strings.Repeat(`{"der_id":"d1","power_kw":4.2}`, 200))
+	for _, enc := range []Encoding{Identity, Gzip, Zstd} {
+		s.Run(string(enc), func() {
+			// Twice, so the second pass reuses pooled encoders.
+			for range 2 {
+				b, err := Compress(enc, payload)
+				s.Require().NoError(err)
+				if enc != Identity {
+					s.Less(len(b), len(payload))
+				}
+
+				out, err := Decompress(enc, b, 0)
+				s.Require().NoError(err)
+				s.Equal(payload, out)
+			}
+		})
+	}
+}
+
+func (s *CompressTestSuite) TestStreamFlush() {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Zstd)
+	s.Require().NoError(err)
+
+	_, err = io.WriteString(w, "event: reading\n\n")
+	s.Require().NoError(err)
+	s.Require().NoError(w.Flush())
+
+	// Everything flushed so far decodes before the stream is closed.
+	r, err := NewReader(bytes.NewReader(buf.Bytes()), Zstd)
+	s.Require().NoError(err)
+	got := make([]byte, 16)
+	_, err = io.ReadFull(r, got)
+	s.Require().NoError(err)
+	s.Equal("event: reading\n\n", string(got))
+	s.NoError(r.Close())
+
+	s.NoError(w.Close())
+	_, err = w.Write([]byte("late"))
+	s.Error(err)
+}
+
+func (s *CompressTestSuite) TestDecompressLimit() {
+	b, err := Compress(Gzip, make([]byte, 1<<20))
+	s.Require().NoError(err)
+
+	_, err = Decompress(Gzip, b, 1024)
+	s.ErrorIs(err, ErrTooLarge)
+	out, err := Decompress(Gzip, b, 1<<20)
+	s.NoError(err)
+	s.Len(out, 1<<20)
+
+	_, err = Decompress(Gzip, []byte("not gzip"), 0)
+	s.Error(err)
+}
+
+func (s *CompressTestSuite) TestParseEncoding() {
+	enc, err := ParseEncoding("")
+	s.NoError(err)
+	s.Equal(Identity, enc)
+	enc, err = ParseEncoding("zstd")
+	s.NoError(err)
+	s.Equal(Zstd, enc)
+	_, err = ParseEncoding("br")
+	s.Error(err)
+	_, err = NewWriter(io.Discard, "br")
+	s.Error(err)
+}
+
+func (s *CompressTestSuite) TestNegotiate() {
+	testCases := []struct {
+		name   string
+		header string
+		want   Encoding
+	}{
+		{name: "empty", header: "", want: Identity},
+		{name: "gzip only", header: "gzip, deflate", want: Gzip},
+		{name: "server preference on tie", header: "gzip, zstd", want: Zstd},
+		{name: "client q wins", header: "zstd;q=0.5, gzip", want: Gzip},
+		{name: "refused", header: "zstd;q=0, gzip;q=0", want: Identity},
+		{name: "wildcard", header: "br, *;q=0.1", want: Zstd},
+		{name: "wildcard excludes listed", header: "zstd;q=0, *", want: Gzip},
+		{name: "case and spaces", header: " GZIP ; Q=0.9 ", want: Gzip},
+		{name: "bad q", header: "zstd;q=x, gzip", want: Gzip},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.want, Negotiate(tc.header, Zstd, Gzip))
+		})
+	}
+}
+
+func TestCompressTestSuite(t *testing.T) {
+	suite.Run(t, new(CompressTestSuite))
+}
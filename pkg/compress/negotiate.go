@@ -0,0 +1,58 @@
+package compress
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Negotiate picks the encoding to respond with given a request's
+// Accept-Encoding header. Among supported encodings the client accepts, the
+// one with the highest q-value wins, with ties going to the earlier entry in
+// supported. Identity is returned when nothing else is acceptable.
+func Negotiate(acceptEncoding string, supported ...Encoding) Encoding {
+	weights := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := parseQ(params)
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := Identity, 0.0
+	for _, enc := range supported {
+		if enc == Identity {
+			continue
+		}
+		q, ok := weights[string(enc)]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+func parseQ(params string) float64 {
+	for _, p := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(k), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || q < 0 {
+			return 0
+		}
+		return min(q, 1)
+	}
+	return 1
+}
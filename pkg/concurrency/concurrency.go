@@ -0,0 +1,136 @@
+// Package concurrency provides bounded parallelism helpers: a weighted
+// semaphore and ForEachLimit/MapLimit for fanning work out over a slice.
+//
+//	err := concurrency.ForEachLimit(ctx, tables, 4, func(ctx context.Context, table string) error {
+//		return backfill(ctx, table)
+//	})
+package concurrency
+
+import (
+	"context"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+)
+
+// Semaphore limits the total weight of work in flight.
+type Semaphore struct {
+	sem  *semaphore.Weighted
+	size int64
+	used atomic.Int64
+}
+
+// NewSemaphore returns a semaphore with capacity size.
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{sem: semaphore.NewWeighted(size), size: size}
+}
+
+// Acquire blocks until weight w is available or ctx is done. Requests for
+// more than the semaphore's size fail immediately.
+func (s *Semaphore) Acquire(ctx context.Context, w int64) error {
+	if w > s.size {
+		return errors.Errorf("semaphore weight %d exceeds size %d", w, s.size)
+	}
+	if err := s.sem.Acquire(ctx, w); err != nil {
+		return errors.WithStack(err)
+	}
+	s.used.Add(w)
+	return nil
+}
+
+// TryAcquire acquires weight w without blocking, reporting whether it
+// succeeded.
+func (s *Semaphore) TryAcquire(w int64) bool {
+	if !s.sem.TryAcquire(w) {
+		return false
+	}
+	s.used.Add(w)
+	return true
+}
+
+// Release returns weight w to the semaphore.
+func (s *Semaphore) Release(w int64) {
+	s.used.Add(-w)
+	s.sem.Release(w)
+}
+
+// Do runs fn while holding weight w.
+func (s *Semaphore) Do(ctx context.Context, w int64, fn func(ctx context.Context) error) error {
+	if err := s.Acquire(ctx, w); err != nil {
+		return err
+	}
+	defer s.Release(w)
+	return fn(ctx)
+}
+
+// Size returns the semaphore's capacity.
+func (s *Semaphore) Size() int64 {
+	return s.size
+}
+
+// InUse returns the weight currently held.
+func (s *Semaphore) InUse() int64 {
+	return s.used.Load()
+}
+
+// ForEachLimit calls fn for every item with at most n calls in flight. The
+// first error cancels the context passed to the remaining calls, no further
+// items are started, and that error is returned. A panic in fn is returned
+// as an error. n <= 0 means no limit.
+func ForEachLimit[T any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) error) error {
+	g, gctx := errgroup.WithContext(ctx)
+	if n > 0 {
+		g.SetLimit(n)
+	}
+	for _, item := range items {
+		if gctx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			// Go may have blocked on the limit while another call failed.
+			if gctx.Err() != nil {
+				return nil
+			}
+			return safeCall(func() error { return fn(gctx, item) })
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	// Items may have been skipped because the caller's context ended.
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// MapLimit is ForEachLimit for functions that return a value. Results are in
+// the same order as items.
+func MapLimit[T, R any](ctx context.Context, items []T, n int, fn func(ctx context.Context, item T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	err := ForEachLimit(ctx, indexes, n, func(ctx context.Context, i int) error {
+		r, err := fn(ctx, items[i])
+		results[i] = r
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func safeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("concurrency: panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,95 @@
+package concurrency
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConcurrencyTestSuite struct {
+	suite.Suite
+}
+
+func (s *ConcurrencyTestSuite) TestSemaphore() {
+	sem := NewSemaphore(3)
+	s.Require().NoError(sem.Acquire(context.Background(), 2))
+	s.False(sem.TryAcquire(2))
+	s.True(sem.TryAcquire(1))
+	s.Equal(int64(3), sem.InUse())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(sem.Acquire(ctx, 1), context.DeadlineExceeded)
+	s.Error(sem.Acquire(context.Background(), 4))
+
+	sem.Release(3)
+	s.Zero(sem.InUse())
+	s.NoError(sem.Do(context.Background(), 3, func(ctx context.Context) error {
+		s.Equal(int64(3), sem.InUse())
+		return nil
+	}))
+	s.Zero(sem.InUse())
+}
+
+func (s *ConcurrencyTestSuite) TestForEachLimit() {
+	var running, peak, done atomic.Int32
+	items := make([]int, 20)
+	err := ForEachLimit(context.Background(), items, 3, func(ctx context.Context, _ int) error {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		running.Add(-1)
+		done.Add(1)
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(int32(20), done.Load())
+	s.LessOrEqual(peak.Load(), int32(3))
+}
+
+func (s *ConcurrencyTestSuite) TestForEachLimitError() {
+	var started atomic.Int32
+	err := ForEachLimit(context.Background(), []int{1, 2, 3, 4, 5, 6}, 1, func(ctx context.Context, i int) error {
+		started.Add(1)
+		if i == 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	s.EqualError(err, "boom")
+	s.Equal(int32(2), started.Load())
+
+	err = ForEachLimit(context.Background(), []int{1}, 0, func(ctx context.Context, i int) error {
+		panic("oops")
+	})
+	s.ErrorContains(err, "panic: oops")
+}
+
+func (s *ConcurrencyTestSuite) TestForEachLimitCancelled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := ForEachLimit(ctx, []int{1, 2}, 1, func(ctx context.Context, i int) error { return nil })
+	s.ErrorIs(err, context.Canceled)
+}
+
+func (s *ConcurrencyTestSuite) TestMapLimit() {
+	out, err := MapLimit(context.Background(), []string{"a", "bb", "ccc"}, 2, func(ctx context.Context, v string) (int, error) {
+		time.Sleep(time.Duration(3-len(v)) * time.Millisecond)
+		return len(v), nil
+	})
+	s.Require().NoError(err)
+	s.Equal([]int{1, 2, 3}, out)
+}
+
+func TestConcurrencyTestSuite(t *testing.T) {
+	suite.Run(t, new(ConcurrencyTestSuite))
+}
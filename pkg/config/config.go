@@ -0,0 +1,229 @@
+// Package config loads typed configuration structs from files, environment
+// variables and command-line flags.
+//
+// Sources are layered with the following precedence, lowest first:
+//
+//  1. values already set on the destination struct, profile defaults and
+//     WithDefaults
+//  2. the YAML or JSON file given to WithFile, then its profile file
+//  3. environment variables matching WithEnvPrefix
+//  4. flags explicitly set on the FlagSet given to WithFlags
+//
+// Profiles are loaded with WithEnvironment, usually for the environment
+// named by GS_ENV:
+//
+//	err := config.Load(&cfg,
+//		config.WithFile("config.yaml"), // then config.prod.yaml, if present
+//		config.WithEnvironment(config.Environment()),
+//	)
+//
+// Keys follow the koanf struct tags used by every Config in go-commons, with
+// nested structs separated by ".". Environment variables use "__" as the
+// nesting separator, so GS_DATABASE__PROJECT_ID sets database.project_id when
+// the prefix is "GS_".
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/env"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	delim    = "."
+	envDelim = "__"
+	tagName  = "koanf"
+)
+
+type Option func(*options)
+
+type options struct {
+	defaults  map[string]any
+	filePath  string
+	envPrefix string
+	flags     *flag.FlagSet
+	env       Env
+}
+
+// WithDefaults sets default values keyed by their dotted koanf path.
+func WithDefaults(defaults map[string]any) Option {
+	return func(o *options) {
+		o.defaults = defaults
+	}
+}
+
+// WithFile loads a YAML or JSON file, chosen by its extension. An empty path
+// is ignored so callers can pass an optional flag value straight through.
+func WithFile(path string) Option {
+	return func(o *options) {
+		o.filePath = path
+	}
+}
+
+// WithEnvPrefix loads environment variables starting with prefix.
+func WithEnvPrefix(prefix string) Option {
+	return func(o *options) {
+		o.envPrefix = prefix
+	}
+}
+
+// WithFlags loads flags that were explicitly set on fs. Flag names are used as
+// dotted keys, for example -logger.level. The FlagSet must already be parsed.
+func WithFlags(fs *flag.FlagSet) Option {
+	return func(o *options) {
+		o.flags = fs
+	}
+}
+
+// Loader reads configuration from the configured sources.
+type Loader struct {
+	opts options
+}
+
+func New(opts ...Option) *Loader {
+	l := &Loader{}
+	for _, opt := range opts {
+		opt(&l.opts)
+	}
+	return l
+}
+
+// Load is shorthand for New(opts...).Load(dst).
+func Load(dst any, opts ...Option) error {
+	return New(opts...).Load(dst)
+}
+
+// Load merges all sources and unmarshals the result into dst, which must be a
+// pointer to a struct. Fields already set on dst are kept unless a source
+// overrides them. The result is then validated as described in ValidateAll.
+func (l *Loader) Load(dst any) error {
+	k, err := l.read(reflect.TypeOf(dst))
+	if err != nil {
+		return err
+	}
+
+	if err := unmarshal(k, dst); err != nil {
+		return err
+	}
+
+	return validateConfig(dst)
+}
+
+// read merges the sources. t is the destination type, whose fields supply
+// the profile defaults.
+func (l *Loader) read(t reflect.Type) (*koanf.Koanf, error) {
+	k := koanf.New(delim)
+
+	if l.opts.env != "" {
+		if _, err := ParseEnv(string(l.opts.env)); err != nil {
+			return nil, err
+		}
+		if err := k.Load(confmap.Provider(profileDefaults(t, l.opts.env), delim), nil); err != nil {
+			return nil, errors.Wrap(err, "loading profile defaults")
+		}
+	}
+
+	if len(l.opts.defaults) > 0 {
+		if err := k.Load(confmap.Provider(l.opts.defaults, delim), nil); err != nil {
+			return nil, errors.Wrap(err, "loading defaults")
+		}
+	}
+
+	if l.opts.filePath != "" {
+		if err := loadFile(k, l.opts.filePath); err != nil {
+			return nil, err
+		}
+		if l.opts.env != "" {
+			path := profilePath(l.opts.filePath, l.opts.env)
+			if _, err := os.Stat(path); err == nil {
+				if err := loadFile(k, path); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if l.opts.envPrefix != "" {
+		if err := k.Load(env.Provider(l.opts.envPrefix, delim, l.envKey), nil); err != nil {
+			return nil, errors.Wrap(err, "loading environment")
+		}
+	}
+
+	if l.opts.flags != nil {
+		if !l.opts.flags.Parsed() {
+			return nil, errors.New("flags must be parsed before loading config")
+		}
+		if err := k.Load(confmap.Provider(flagValues(l.opts.flags), delim), nil); err != nil {
+			return nil, errors.Wrap(err, "loading flags")
+		}
+	}
+
+	return k, nil
+}
+
+func loadFile(k *koanf.Koanf, path string) error {
+	parser, err := parserFor(path)
+	if err != nil {
+		return err
+	}
+	if err := k.Load(file.Provider(path), parser); err != nil {
+		return errors.Wrapf(err, "loading config file %s", path)
+	}
+	return nil
+}
+
+func (l *Loader) envKey(s string) string {
+	key := strings.ToLower(strings.TrimPrefix(s, l.opts.envPrefix))
+	return strings.ReplaceAll(key, envDelim, delim)
+}
+
+func flagValues(fs *flag.FlagSet) map[string]any {
+	values := map[string]any{}
+	fs.Visit(func(f *flag.Flag) {
+		if g, ok := f.Value.(flag.Getter); ok {
+			values[f.Name] = g.Get()
+			return
+		}
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+func parserFor(path string) (koanf.Parser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return yaml.Parser(), nil
+	case ".json":
+		return json.Parser(), nil
+	default:
+		return nil, errors.Errorf("unsupported config file format: %s", path)
+	}
+}
+
+func unmarshal(k *koanf.Koanf, dst any) error {
+	err := k.UnmarshalWithConf("", dst, koanf.UnmarshalConf{
+		Tag: tagName,
+		DecoderConfig: &mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				mapstructure.StringToTimeDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.TextUnmarshallerHookFunc(),
+			),
+			Result:           dst,
+			TagName:          tagName,
+			WeaklyTypedInput: true,
+		},
+	})
+	return errors.Wrap(err, "decoding config")
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type testConfig struct {
+	Name     string         `koanf:"name"`
+	Interval time.Duration  `koanf:"interval"`
+	Tables   []string       `koanf:"tables"`
+	Workers  int            `koanf:"workers"`
+	Logger   *logger.Config `koanf:"logger"`
+}
+
+type validatedConfig struct {
+	Port int `koanf:"port"`
+}
+
+func (c *validatedConfig) Validate() error {
+	if c.Port <= 0 {
+		return errors.New("port must be greater than 0")
+	}
+	return nil
+}
+
+type ConfigTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *ConfigTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+}
+
+func (s *ConfigTestSuite) writeFile(name, contents string) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func (s *ConfigTestSuite) TestLoadFile() {
+	testCases := []struct {
+		name     string
+		file     string
+		contents string
+	}{
+		{
+			name: "YAML",
+			file: "config.yaml",
+			contents: `
+name: aggregator
+interval: 5m
+tables: [projects, der_data]
+logger:
+  level: DEBUG
+  format: json
+`,
+		},
+		{
+			name: "JSON",
+			file: "config.json",
+			contents: `{
+  "name": "aggregator",
+  "interval": "5m",
+  "tables": ["projects", "der_data"],
+  "logger": {"level": "DEBUG", "format": "json"}
+}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			var cfg testConfig
+			err := Load(&cfg, WithFile(s.writeFile(tc.file, tc.contents)))
+			s.NoError(err)
+			s.Equal("aggregator", cfg.Name)
+			s.Equal(5*time.Minute, cfg.Interval)
+			s.Equal([]string{"projects", "der_data"}, cfg.Tables)
+			s.Require().NotNil(cfg.Logger)
+			s.Equal("DEBUG", cfg.Logger.Level)
+			s.Equal("json", cfg.Logger.Format)
+		})
+	}
+}
+
+func (s *ConfigTestSuite) TestPrecedence() {
+	path := s.writeFile("config.yaml", "name: from-file\nworkers: 2\nlogger:\n  level: INFO\n")
+
+	s.T().Setenv("GS_WORKERS", "4")
+	s.T().Setenv("GS_LOGGER__LEVEL", "WARN")
+	s.T().Setenv("GS_TABLES", "projects,contracts")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("workers", 1, "")
+	fs.String("name", "flag-default", "")
+	s.Require().NoError(fs.Parse([]string{"-workers=8"}))
+
+	cfg := testConfig{Interval: time.Minute}
+	err := Load(&cfg,
+		WithDefaults(map[string]any{"name": "from-defaults", "logger.format": "text"}),
+		WithFile(path),
+		WithEnvPrefix("GS_"),
+		WithFlags(fs),
+	)
+	s.NoError(err)
+
+	s.Equal("from-file", cfg.Name, "unset flags must not override the file")
+	s.Equal(8, cfg.Workers, "flags override environment")
+	s.Equal(time.Minute, cfg.Interval, "pre-populated fields act as defaults")
+	s.Equal([]string{"projects", "contracts"}, cfg.Tables)
+	s.Equal("WARN", cfg.Logger.Level, "environment overrides file")
+	s.Equal("text", cfg.Logger.Format, "defaults fill missing keys")
+}
+
+func (s *ConfigTestSuite) TestValidate() {
+	var cfg validatedConfig
+	err := Load(&cfg, WithDefaults(map[string]any{"port": 0}))
+	s.Error(err)
+	s.Contains(err.Error(), "port must be greater than 0")
+
+	err = Load(&cfg, WithDefaults(map[string]any{"port": 8080}))
+	s.NoError(err)
+	s.Equal(8080, cfg.Port)
+}
+
+func (s *ConfigTestSuite) TestErrors() {
+	testCases := []struct {
+		name string
+		opts func() []Option
+	}{
+		{
+			name: "unsupported extension",
+			opts: func() []Option {
+				return []Option{WithFile(s.writeFile("config.toml", "name = 'x'"))}
+			},
+		},
+		{
+			name: "missing file",
+			opts: func() []Option {
+				return []Option{WithFile(filepath.Join(s.dir, "missing.yaml"))}
+			},
+		},
+		{
+			name: "malformed file",
+			opts: func() []Option {
+				return []Option{WithFile(s.writeFile("bad.json", "{"))}
+			},
+		},
+		{
+			name: "unparsed flags",
+			opts: func() []Option {
+				return []Option{WithFlags(flag.NewFlagSet("test", flag.ContinueOnError))}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			var cfg testConfig
+			s.Error(Load(&cfg, tc.opts()...))
+		})
+	}
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Env is a deployment environment. Each has a profile: defaults provided by
+// the Config structs through Profiler, and an optional profile file loaded
+// after the base config file.
+type Env string
+
+const (
+	Dev     Env = "dev"
+	Staging Env = "staging"
+	Prod    Env = "prod"
+)
+
+// EnvVar selects the environment of the running process.
+const EnvVar = "GS_ENV"
+
+// ParseEnv parses s case-insensitively, also accepting the long forms
+// development, stage and production.
+func ParseEnv(s string) (Env, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "dev", "development":
+		return Dev, nil
+	case "staging", "stage":
+		return Staging, nil
+	case "prod", "production":
+		return Prod, nil
+	default:
+		return "", errors.Errorf("unknown environment %q: want dev, staging or prod", s)
+	}
+}
+
+// Environment returns the environment named by GS_ENV, or Prod when it is
+// unset, so that a process started without it never picks up dev defaults
+// such as DEBUG logging. Packages that label their output with the
+// environment, such as metrics and otel, use it so every service reports the
+// same value. An unknown name is returned lower-cased as is; loading a
+// config WithEnvironment rejects it.
+func Environment() Env {
+	v := os.Getenv(EnvVar)
+	if v == "" {
+		return Prod
+	}
+	if env, err := ParseEnv(v); err == nil {
+		return env
+	}
+	return Env(strings.ToLower(v))
+}
+
+func (e Env) String() string {
+	return string(e)
+}
+
+// Profiler is implemented by Config structs with per-environment defaults,
+// such as logger.Config. ProfileDefaults returns defaults keyed by dotted
+// paths relative to the struct, for an environment given as a string so
+// packages below config can implement it.
+type Profiler interface {
+	ProfileDefaults(env string) map[string]any
+}
+
+// WithEnvironment loads the env profile. Every field of the destination
+// whose type implements Profiler gets its defaults for env beneath
+// WithDefaults, and the file given to WithFile is followed by its profile
+// file, config.prod.yaml for config.yaml, when that exists. A Watcher
+// reloads both files but only watches the base file.
+func WithEnvironment(env Env) Option {
+	return func(o *options) {
+		o.env = env
+	}
+}
+
+// profilePath returns the profile file for the config file at path.
+func profilePath(path string, env Env) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + string(env) + ext
+}
+
+var profilerType = reflect.TypeFor[Profiler]()
+
+// profileDefaults collects the defaults of every Profiler field reachable
+// from t, keyed by the field's full koanf path.
+func profileDefaults(t reflect.Type, env Env) map[string]any {
+	out := map[string]any{}
+	collectProfiles(out, "", t, env, map[reflect.Type]bool{})
+	return out
+}
+
+func collectProfiles(out map[string]any, path string, t reflect.Type, env Env, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+
+	if path != "" && reflect.PointerTo(t).Implements(profilerType) {
+		p := reflect.New(t).Interface().(Profiler)
+		for k, v := range p.ProfileDefaults(string(env)) {
+			out[path+delim+k] = v
+		}
+	}
+
+	seen[t] = true
+	defer delete(seen, t)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.IsExported() {
+			collectProfiles(out, joinKey(path, fieldName(field)), field.Type, env, seen)
+		}
+	}
+}
+
+func joinKey(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + delim + child
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type clusterConfig struct {
+	Replicas int `koanf:"replicas"`
+}
+
+func (c *clusterConfig) ProfileDefaults(env string) map[string]any {
+	if env == "prod" {
+		return map[string]any{"replicas": 3}
+	}
+	return map[string]any{"replicas": 1}
+}
+
+type profiledConfig struct {
+	Name    string         `koanf:"name"`
+	Logger  *logger.Config `koanf:"logger"`
+	Cluster struct {
+		Primary clusterConfig `koanf:"primary"`
+	} `koanf:"cluster"`
+}
+
+type EnvironmentTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (s *EnvironmentTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+}
+
+func (s *EnvironmentTestSuite) writeFile(name, contents string) string {
+	path := filepath.Join(s.dir, name)
+	s.Require().NoError(os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func (s *EnvironmentTestSuite) TestParseEnv() {
+	testCases := []struct {
+		in          string
+		want        Env
+		expectError bool
+	}{
+		{in: "dev", want: Dev},
+		{in: "Development", want: Dev},
+		{in: " staging ", want: Staging},
+		{in: "stage", want: Staging},
+		{in: "PROD", want: Prod},
+		{in: "production", want: Prod},
+		{in: "qa", expectError: true},
+		{in: "", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.in, func() {
+			env, err := ParseEnv(tc.in)
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+				s.Equal(tc.want, env)
+			}
+		})
+	}
+}
+
+func (s *EnvironmentTestSuite) TestEnvironment() {
+	s.T().Setenv(EnvVar, "")
+	s.Equal(Prod, Environment())
+	s.T().Setenv(EnvVar, "development")
+	s.Equal(Dev, Environment())
+	s.T().Setenv(EnvVar, "Production")
+	s.Equal(Prod, Environment())
+	s.T().Setenv(EnvVar, "QA")
+	s.Equal(Env("qa"), Environment())
+}
+
+func (s *EnvironmentTestSuite) TestProfileDefaults() {
+	var dev, prod profiledConfig
+	s.Require().NoError(Load(&dev, WithEnvironment(Dev)))
+	s.Require().NoError(Load(&prod, WithEnvironment(Prod), WithDefaults(map[string]any{"logger.level": "WARN"})))
+
+	s.Equal("DEBUG", dev.Logger.Level)
+	s.Equal("text", dev.Logger.Format)
+	s.Equal(1, dev.Cluster.Primary.Replicas, "nested profilers are found")
+
+	s.Equal("WARN", prod.Logger.Level, "WithDefaults overrides profile defaults")
+	s.Equal("json", prod.Logger.Format)
+	s.Equal(3, prod.Cluster.Primary.Replicas)
+}
+
+func (s *EnvironmentTestSuite) TestProfileFile() {
+	path := s.writeFile("config.yaml", "name: base\nlogger:\n  level: WARN\n")
+	s.writeFile("config.staging.yaml", "name: staging\n")
+
+	var staging profiledConfig
+	s.Require().NoError(Load(&staging, WithFile(path), WithEnvironment(Staging)))
+	s.Equal("staging", staging.Name)
+	s.Equal("WARN", staging.Logger.Level, "base file values are kept")
+	s.Equal("json", staging.Logger.Format)
+
+	var prod profiledConfig
+	s.Require().NoError(Load(&prod, WithFile(path), WithEnvironment(Prod)), "the profile file is optional")
+	s.Equal("base", prod.Name)
+}
+
+func (s *EnvironmentTestSuite) TestUnknownEnvironment() {
+	var cfg profiledConfig
+	s.ErrorContains(Load(&cfg, WithEnvironment("qa")), `unknown environment "qa"`)
+}
+
+func TestEnvironmentTestSuite(t *testing.T) {
+	suite.Run(t, new(EnvironmentTestSuite))
+}
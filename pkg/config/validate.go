@@ -0,0 +1,110 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/grid-stream-org/go-commons/pkg/validate"
+	"github.com/pkg/errors"
+)
+
+// Validatable is implemented by every Config in go-commons.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldError is a validation failure at a dotted config path.
+type FieldError = validate.FieldError
+
+// ValidationErrors collects every FieldError found by ValidateAll. It is a
+// validate.Errors reported as an invalid configuration.
+type ValidationErrors validate.Errors
+
+func (ve *ValidationErrors) Error() string {
+	messages := make([]string, len(ve.Errors))
+	for i, err := range ve.Errors {
+		messages[i] = err.Error()
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// ValidateAll validates each config and every nested struct field that
+// implements Validatable, returning all failures at once as a
+// *ValidationErrors. Nested failures are reported under the field's koanf
+// path, so Validate methods should only check their own fields.
+func ValidateAll(cfgs ...Validatable) error {
+	ve := &ValidationErrors{}
+	for _, cfg := range cfgs {
+		ve.collect("", reflect.ValueOf(cfg))
+	}
+	return ve.errOrNil()
+}
+
+func validateConfig(v any) error {
+	ve := &ValidationErrors{}
+	ve.collect("", reflect.ValueOf(v))
+	return ve.errOrNil()
+}
+
+func (ve *ValidationErrors) errOrNil() error {
+	if len(ve.Errors) == 0 {
+		return nil
+	}
+	return errors.WithStack(ve)
+}
+
+func (ve *ValidationErrors) collect(path string, v reflect.Value) {
+	if !v.IsValid() {
+		return
+	}
+
+	if v.CanInterface() {
+		if cfg, ok := v.Interface().(Validatable); ok && !isNilPointer(v) {
+			ve.add(path, cfg.Validate())
+		}
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && fv.CanAddr() {
+			fv = fv.Addr()
+		}
+		ve.collect(validate.JoinPath(path, fieldName(field)), fv)
+	}
+}
+
+// add records err at path, flattening both *ValidationErrors and the
+// *validate.Errors returned by validate.Struct.
+func (ve *ValidationErrors) add(path string, err error) {
+	var nested *ValidationErrors
+	if errors.As(err, &nested) {
+		err = (*validate.Errors)(nested)
+	}
+	(*validate.Errors)(ve).Add(path, err)
+}
+
+func isNilPointer(v reflect.Value) bool {
+	return (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil()
+}
+
+func fieldName(f reflect.StructField) string {
+	if tag, _, _ := strings.Cut(f.Tag.Get(tagName), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	return strings.ToLower(f.Name)
+}
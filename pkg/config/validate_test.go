@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/validate"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+var errPortRequired = errors.New("port must be greater than 0")
+
+type serverConfig struct {
+	Port int `koanf:"port"`
+}
+
+func (c *serverConfig) Validate() error {
+	if c.Port <= 0 {
+		return errPortRequired
+	}
+	return nil
+}
+
+type appConfig struct {
+	Name     string         `koanf:"name"`
+	Server   serverConfig   `koanf:"server"`
+	Logger   *logger.Config `koanf:"logger"`
+	Optional *serverConfig  `koanf:"optional"`
+}
+
+func (c *appConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("name required")
+	}
+	return nil
+}
+
+type ValidateTestSuite struct {
+	suite.Suite
+}
+
+func (s *ValidateTestSuite) TestValidateAllCollectsEveryError() {
+	cfg := &appConfig{
+		Logger: &logger.Config{Level: "LOUD", Format: "json"},
+	}
+
+	err := ValidateAll(cfg, &serverConfig{Port: -1})
+	s.Require().Error(err)
+
+	var ve *ValidationErrors
+	s.Require().True(errors.As(err, &ve))
+
+	paths := make([]string, len(ve.Errors))
+	for i, fe := range ve.Errors {
+		paths[i] = fe.Path
+	}
+	s.Equal([]string{"", "server", "logger", ""}, paths)
+
+	s.Contains(err.Error(), "name required")
+	s.Contains(err.Error(), "server: port must be greater than 0")
+	s.Contains(err.Error(), "logger: invalid log level: LOUD")
+	s.True(errors.Is(ve.Errors[1], errPortRequired))
+}
+
+func (s *ValidateTestSuite) TestValidateAllValid() {
+	cfg := &appConfig{
+		Name:   "aggregator",
+		Server: serverConfig{Port: 8080},
+		Logger: &logger.Config{Level: "INFO", Format: "text"},
+	}
+	s.NoError(ValidateAll(cfg))
+	s.NoError(ValidateAll())
+}
+
+func (s *ValidateTestSuite) TestNestedValidationErrorsArePrefixed() {
+	nested := &ValidationErrors{Errors: []*FieldError{
+		{Path: "port", Err: errPortRequired},
+	}}
+
+	ve := &ValidationErrors{}
+	ve.add("server", nested)
+	s.Require().Len(ve.Errors, 1)
+	s.Equal("server.port", ve.Errors[0].Path)
+
+	ve.add("db", &validate.Errors{Errors: []*validate.FieldError{{Path: "host", Err: errPortRequired}}})
+	s.Require().Len(ve.Errors, 2)
+	s.Equal("db.host", ve.Errors[1].Path)
+	s.Equal("invalid configuration: server.port: port must be greater than 0; db.host: port must be greater than 0", ve.Error())
+}
+
+func TestValidateSuite(t *testing.T) {
+	suite.Run(t, new(ValidateTestSuite))
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+	"github.com/pkg/errors"
+)
+
+// ChangeEvent describes a successful reload. Changed lists the dotted keys
+// whose values differ between Old and New.
+type ChangeEvent[T any] struct {
+	Old     *T
+	New     *T
+	Changed []string
+}
+
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	bus eventbus.EventBus
+	log *slog.Logger
+}
+
+// WithEventBus publishes every ChangeEvent onto bus in addition to invoking
+// the registered callbacks.
+func WithEventBus(bus eventbus.EventBus) WatchOption {
+	return func(o *watchOptions) {
+		o.bus = bus
+	}
+}
+
+// WithWatchLogger sets the logger used to report failed reloads.
+func WithWatchLogger(log *slog.Logger) WatchOption {
+	return func(o *watchOptions) {
+		o.log = log
+	}
+}
+
+// Watcher holds the current configuration and reloads it whenever the file
+// given to WithFile changes, including Kubernetes Secret and ConfigMap
+// updates that swap the mounted symlink. A reload that fails to parse or
+// validate is logged and the previous configuration is kept.
+type Watcher[T any] struct {
+	loader    *Loader
+	opts      watchOptions
+	mu        sync.RWMutex
+	current   *T
+	values    map[string]any
+	callbacks []func(ChangeEvent[T])
+}
+
+// NewWatcher loads the initial configuration using l. Call Watch to start
+// reacting to file changes.
+func NewWatcher[T any](l *Loader, opts ...WatchOption) (*Watcher[T], error) {
+	if l.opts.filePath == "" {
+		return nil, errors.New("config watcher requires a config file")
+	}
+
+	w := &Watcher[T]{
+		loader: l,
+		opts:   watchOptions{log: logger.Default()},
+	}
+	for _, opt := range opts {
+		opt(&w.opts)
+	}
+
+	cfg, k, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.current = cfg
+	w.values = k.All()
+	return w, nil
+}
+
+// Current returns the most recently loaded configuration. The returned value
+// must be treated as read-only.
+func (w *Watcher[T]) Current() *T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// OnChange registers fn to be called after each reload that changes at least
+// one value. Callbacks run sequentially on the watcher goroutine.
+func (w *Watcher[T]) OnChange(fn func(ChangeEvent[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, fn)
+}
+
+// Watch starts watching the config file and returns once the watch is
+// established. Watching stops when ctx is cancelled.
+func (w *Watcher[T]) Watch(ctx context.Context) error {
+	f := file.Provider(w.loader.opts.filePath)
+	err := f.Watch(func(_ any, err error) {
+		if err != nil {
+			w.opts.log.Error("config watch failed", "path", w.loader.opts.filePath, "error", err)
+			return
+		}
+		w.reload()
+	})
+	if err != nil {
+		return errors.Wrapf(err, "watching config file %s", w.loader.opts.filePath)
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = f.Unwatch()
+	}()
+	return nil
+}
+
+func (w *Watcher[T]) load() (*T, *koanf.Koanf, error) {
+	k, err := w.loader.read(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg := new(T)
+	if err := unmarshal(k, cfg); err != nil {
+		return nil, nil, err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return nil, nil, err
+	}
+	return cfg, k, nil
+}
+
+func (w *Watcher[T]) reload() {
+	cfg, k, err := w.load()
+	if err != nil {
+		w.opts.log.Error("config reload failed, keeping previous config", "path", w.loader.opts.filePath, "error", err)
+		return
+	}
+
+	values := k.All()
+
+	w.mu.Lock()
+	changed := changedKeys(w.values, values)
+	if len(changed) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	event := ChangeEvent[T]{Old: w.current, New: cfg, Changed: changed}
+	w.current = cfg
+	w.values = values
+	callbacks := slices.Clone(w.callbacks)
+	w.mu.Unlock()
+
+	w.opts.log.Info("config reloaded", "path", w.loader.opts.filePath, "changed", changed)
+
+	for _, fn := range callbacks {
+		fn(event)
+	}
+	if w.opts.bus != nil {
+		w.opts.bus.Publish(event)
+	}
+}
+
+func changedKeys(old, new map[string]any) []string {
+	var changed []string
+	for key, v := range new {
+		if ov, ok := old[key]; !ok || !reflect.DeepEqual(ov, v) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/stretchr/testify/suite"
+)
+
+type reloadConfig struct {
+	Level     string `koanf:"level"`
+	BatchSize int    `koanf:"batch_size"`
+}
+
+type WatchTestSuite struct {
+	suite.Suite
+	path string
+}
+
+func (s *WatchTestSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "config.yaml")
+	s.write("level: INFO\nbatch_size: 10\n")
+}
+
+func (s *WatchTestSuite) write(contents string) {
+	s.Require().NoError(os.WriteFile(s.path, []byte(contents), 0o600))
+}
+
+func (s *WatchTestSuite) TestReloadPublishesChanges() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	eb := eventbus.New()
+	defer eb.Close()
+	busCh := eb.Subscribe(1)
+
+	w, err := NewWatcher[reloadConfig](New(WithFile(s.path)), WithEventBus(eb))
+	s.Require().NoError(err)
+	s.Equal(10, w.Current().BatchSize)
+
+	events := make(chan ChangeEvent[reloadConfig], 1)
+	w.OnChange(func(e ChangeEvent[reloadConfig]) { events <- e })
+	s.Require().NoError(w.Watch(ctx))
+
+	s.write("level: INFO\nbatch_size: 50\n")
+
+	select {
+	case e := <-events:
+		s.Equal(10, e.Old.BatchSize)
+		s.Equal(50, e.New.BatchSize)
+		s.Equal([]string{"batch_size"}, e.Changed)
+	case <-time.After(2 * time.Second):
+		s.FailNow("expected change event")
+	}
+	s.Equal(50, w.Current().BatchSize)
+
+	select {
+	case e := <-busCh:
+		s.IsType(ChangeEvent[reloadConfig]{}, e)
+	case <-time.After(time.Second):
+		s.Fail("expected change event on the event bus")
+	}
+}
+
+func (s *WatchTestSuite) TestInvalidReloadKeepsPrevious() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := NewWatcher[reloadConfig](New(WithFile(s.path)))
+	s.Require().NoError(err)
+
+	events := make(chan ChangeEvent[reloadConfig], 1)
+	w.OnChange(func(e ChangeEvent[reloadConfig]) { events <- e })
+	s.Require().NoError(w.Watch(ctx))
+
+	s.write("level: [unterminated\n")
+
+	select {
+	case <-events:
+		s.Fail("invalid config must not produce a change event")
+	case <-time.After(300 * time.Millisecond):
+	}
+	s.Equal("INFO", w.Current().Level)
+}
+
+func (s *WatchTestSuite) TestRequiresFile() {
+	_, err := NewWatcher[reloadConfig](New())
+	s.Error(err)
+}
+
+func (s *WatchTestSuite) TestChangedKeys() {
+	old := map[string]any{"a": 1, "b": "x", "c": true}
+	new := map[string]any{"a": 1, "b": "y", "d": 2}
+	s.Equal([]string{"b", "c", "d"}, changedKeys(old, new))
+	s.Empty(changedKeys(old, old))
+}
+
+func TestWatchSuite(t *testing.T) {
+	suite.Run(t, new(WatchTestSuite))
+}
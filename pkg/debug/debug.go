@@ -0,0 +1,200 @@
+// Package debug serves runtime diagnostics on a separate port: pprof
+// profiles, expvar, a full goroutine dump and a JSON runtime summary.
+// httpserver runs it alongside the main server when Config.Debug is enabled,
+// so profiling a service needs only a config change, not a custom build.
+//
+//	go tool pprof http://localhost:6060/debug/pprof/heap
+//	curl -H "Authorization: Bearer $TOKEN" localhost:6060/debug/runtime
+package debug
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rdebug "runtime/debug"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/buildinfo"
+	"github.com/pkg/errors"
+)
+
+const (
+	PprofPath      = "/debug/pprof/"
+	VarsPath       = "/debug/vars"
+	GoroutinesPath = "/debug/goroutines"
+	RuntimePath    = "/debug/runtime"
+	GCPath         = "/debug/gc"
+)
+
+var started = time.Now()
+
+type Config struct {
+	Enabled bool `koanf:"enabled" json:"enabled" envconfig:"enabled"`
+	// Addr should normally bind to localhost or an internal interface, never
+	// the public listener.
+	Addr string `koanf:"addr" json:"addr" envconfig:"addr"`
+	// Token, when set, is required as a bearer token on every request.
+	Token string `koanf:"token" json:"token" envconfig:"token"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+	if c.Addr == "" {
+		return errors.New("debug server addr required")
+	}
+	return nil
+}
+
+// Handler returns the debug endpoints, requiring cfg.Token when set.
+func Handler(cfg *Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PprofPath, pprof.Index)
+	mux.HandleFunc(PprofPath+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(PprofPath+"profile", pprof.Profile)
+	mux.HandleFunc(PprofPath+"symbol", pprof.Symbol)
+	mux.HandleFunc(PprofPath+"trace", pprof.Trace)
+	mux.Handle(VarsPath, expvar.Handler())
+	mux.HandleFunc(GoroutinesPath, goroutines)
+	mux.HandleFunc(RuntimePath, runtimeStats)
+	mux.HandleFunc("POST "+GCPath, gc)
+
+	if cfg == nil || cfg.Token == "" {
+		return mux
+	}
+	return requireToken(cfg.Token, mux)
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// goroutines writes every goroutine's full stack, as in a crash dump.
+func goroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = runtimepprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// RuntimeStats is the summary served at RuntimePath.
+type RuntimeStats struct {
+	Uptime      string         `json:"uptime"`
+	Goroutines  int            `json:"goroutines"`
+	GOMAXPROCS  int            `json:"gomaxprocs"`
+	NumCPU      int            `json:"num_cpu"`
+	HeapAlloc   uint64         `json:"heap_alloc_bytes"`
+	HeapInuse   uint64         `json:"heap_inuse_bytes"`
+	HeapObjects uint64         `json:"heap_objects"`
+	Sys         uint64         `json:"sys_bytes"`
+	NumGC       uint32         `json:"num_gc"`
+	PauseTotal  string         `json:"gc_pause_total"`
+	LastGC      time.Time      `json:"last_gc"`
+	Build       buildinfo.Info `json:"build"`
+}
+
+// ReadRuntimeStats collects the current RuntimeStats. It stops the world
+// briefly to read memory statistics.
+func ReadRuntimeStats() RuntimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return RuntimeStats{
+		Uptime:      time.Since(started).Round(time.Second).String(),
+		Goroutines:  runtime.NumGoroutine(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		NumCPU:      runtime.NumCPU(),
+		HeapAlloc:   ms.HeapAlloc,
+		HeapInuse:   ms.HeapInuse,
+		HeapObjects: ms.HeapObjects,
+		Sys:         ms.Sys,
+		NumGC:       ms.NumGC,
+		PauseTotal:  time.Duration(ms.PauseTotalNs).String(),
+		LastGC:      time.Unix(0, int64(ms.LastGC)).UTC(),
+		Build:       buildinfo.Get(),
+	}
+}
+
+func runtimeStats(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(ReadRuntimeStats())
+}
+
+// gc forces a collection and returns freed memory to the OS, to tell live
+// heap growth apart from memory the runtime has not yet released.
+func gc(w http.ResponseWriter, _ *http.Request) {
+	rdebug.FreeOSMemory()
+	runtimeStats(w, nil)
+}
+
+// Server serves Handler on its own listener.
+type Server struct {
+	cfg *Config
+	log *slog.Logger
+	srv *http.Server
+}
+
+func New(cfg *Config, log *slog.Logger) (*Server, error) {
+	if cfg == nil {
+		return nil, errors.New("debug server configuration required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Server{
+		cfg: cfg,
+		log: log,
+		srv: &http.Server{
+			Addr:              cfg.Addr,
+			Handler:           Handler(cfg),
+			ReadHeaderTimeout: 5 * time.Second,
+		},
+	}, nil
+}
+
+// Run serves until ctx is done. Profiles can take as long as the client
+// asks, so shutdown does not wait for in-flight requests.
+func (s *Server) Run(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "listening on %s", s.cfg.Addr)
+	}
+	return s.Serve(ctx, l)
+}
+
+// Serve is Run on an existing listener.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.log.Info("debug server listening", "addr", l.Addr().String(), "auth", s.cfg.Token != "")
+	errCh := make(chan error, 1)
+	go func() {
+		err := s.srv.Serve(l)
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.WithStack(err)
+	case <-ctx.Done():
+	}
+	if err := s.srv.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(<-errCh)
+}
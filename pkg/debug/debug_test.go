@@ -0,0 +1,138 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type DebugTestSuite struct {
+	suite.Suite
+}
+
+func (s *DebugTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "nil", cfg: nil},
+		{name: "disabled", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Enabled: true, Addr: "localhost:6060"}},
+		{name: "missing addr", cfg: &Config{Enabled: true}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *DebugTestSuite) TestEndpoints() {
+	h := Handler(&Config{})
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		status   int
+		contains string
+	}{
+		{name: "pprof index", method: http.MethodGet, path: PprofPath, status: http.StatusOK, contains: "goroutine"},
+		{name: "heap profile", method: http.MethodGet, path: PprofPath + "heap?debug=1", status: http.StatusOK, contains: "heap profile"},
+		{name: "expvar", method: http.MethodGet, path: VarsPath, status: http.StatusOK, contains: "memstats"},
+		{name: "goroutines", method: http.MethodGet, path: GoroutinesPath, status: http.StatusOK, contains: "goroutine "},
+		{name: "runtime", method: http.MethodGet, path: RuntimePath, status: http.StatusOK, contains: "gomaxprocs"},
+		{name: "gc", method: http.MethodPost, path: GCPath, status: http.StatusOK, contains: "num_gc"},
+		{name: "gc requires post", method: http.MethodGet, path: GCPath, status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+			s.Equal(tc.status, rec.Code)
+			s.Contains(rec.Body.String(), tc.contains)
+		})
+	}
+}
+
+func (s *DebugTestSuite) TestRuntimeStats() {
+	rec := httptest.NewRecorder()
+	Handler(nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, RuntimePath, nil))
+	s.Equal("application/json", rec.Header().Get("Content-Type"))
+
+	var stats RuntimeStats
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &stats))
+	s.Positive(stats.Goroutines)
+	s.Positive(stats.NumCPU)
+	s.Positive(stats.HeapAlloc)
+}
+
+func (s *DebugTestSuite) TestToken() {
+	h := Handler(&Config{Token: "s3cret"})
+	testCases := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "wrong", header: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic s3cret", status: http.StatusUnauthorized},
+		{name: "valid", header: "Bearer s3cret", status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := httptest.NewRequest(http.MethodGet, RuntimePath, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			s.Equal(tc.status, rec.Code)
+		})
+	}
+}
+
+func (s *DebugTestSuite) TestServe() {
+	srv, err := New(&Config{Enabled: true, Addr: "127.0.0.1:0"}, logger.Default())
+	s.Require().NoError(err)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx, l) }()
+
+	resp, err := http.Get("http://" + l.Addr().String() + GoroutinesPath)
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.True(strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+
+	cancel()
+	select {
+	case err := <-done:
+		s.NoError(err)
+	case <-time.After(5 * time.Second):
+		s.Fail("debug server did not stop")
+	}
+}
+
+func TestDebugTestSuite(t *testing.T) {
+	suite.Run(t, new(DebugTestSuite))
+}
@@ -0,0 +1,125 @@
+// Package dedup suppresses duplicate keys seen within a time window, for
+// consumers of at-least-once transports.
+package dedup
+
+import (
+	"slices"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/cache"
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	// TTL is how long a key is remembered after it is first seen.
+	TTL time.Duration `koanf:"ttl" json:"ttl" envconfig:"ttl"`
+	// MaxEntries bounds memory by forgetting the oldest keys first. Zero
+	// means unbounded.
+	MaxEntries int `koanf:"max_entries" json:"max_entries" envconfig:"max_entries"`
+}
+
+type Option func(*Deduplicator)
+
+type Deduplicator struct {
+	cfg   *Config
+	store Store
+	now   func() time.Time
+	// seen maps keys to when they were first seen. Seen never marks an
+	// entry as used, so capacity eviction drops the oldest keys first.
+	seen *cache.LRU[string, time.Time]
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("dedup configuration required")
+	}
+	if c.TTL <= 0 {
+		return errors.New("dedup ttl must be greater than 0")
+	}
+	if c.MaxEntries < 0 {
+		return errors.New("dedup max entries must not be negative")
+	}
+	return nil
+}
+
+// WithStore restores previously seen keys from s on creation and writes them
+// back on Persist and Close, so duplicates are still caught across restarts.
+func WithStore(s Store) Option {
+	return func(d *Deduplicator) {
+		d.store = s
+	}
+}
+
+func New(cfg *Config, opts ...Option) (*Deduplicator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := &Deduplicator{cfg: cfg, now: time.Now}
+	d.seen = cache.New(cfg.MaxEntries, cfg.TTL, cache.WithClock[string, time.Time](func() time.Time { return d.now() }))
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.store != nil {
+		seen, err := d.store.Load()
+		if err != nil {
+			return nil, err
+		}
+		d.restore(seen)
+	}
+	return d, nil
+}
+
+// Seen reports whether key was already seen within the TTL. The first call
+// for a key records it and returns false.
+func (d *Deduplicator) Seen(key string) bool {
+	return !d.seen.AddIfAbsent(key, d.now())
+}
+
+// Forget removes key so that it is treated as new next time, for example
+// after processing it failed.
+func (d *Deduplicator) Forget(key string) {
+	d.seen.Delete(key)
+}
+
+func (d *Deduplicator) Len() int {
+	return d.seen.Len()
+}
+
+// Persist writes the unexpired keys to the store, if one is configured.
+func (d *Deduplicator) Persist() error {
+	if d.store == nil {
+		return nil
+	}
+
+	entries := d.seen.Entries()
+	seen := make(map[string]time.Time, len(entries))
+	for _, e := range entries {
+		seen[e.Key] = e.Value
+	}
+	return d.store.Save(seen)
+}
+
+func (d *Deduplicator) Close() error {
+	return d.Persist()
+}
+
+// restore adds the stored keys oldest first, so that capacity eviction
+// keeps the newest, each expiring TTL after it was first seen.
+func (d *Deduplicator) restore(seen map[string]time.Time) {
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return seen[a].Compare(seen[b])
+	})
+
+	now := d.now()
+	for _, key := range keys {
+		if ttl := d.cfg.TTL - now.Sub(seen[key]); ttl > 0 {
+			d.seen.SetWithTTL(key, seen[key], ttl)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package dedup
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DedupTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *DedupTestSuite) SetupTest() {
+	s.now = time.Now()
+}
+
+func (s *DedupTestSuite) newDedup(cfg *Config, opts ...Option) *Deduplicator {
+	d, err := New(cfg, opts...)
+	s.Require().NoError(err)
+	d.now = func() time.Time { return s.now }
+	return d
+}
+
+func (s *DedupTestSuite) TestSeen() {
+	d := s.newDedup(&Config{TTL: time.Minute})
+
+	s.False(d.Seen("der-1/12:00"))
+	s.True(d.Seen("der-1/12:00"))
+	s.False(d.Seen("der-2/12:00"))
+
+	s.now = s.now.Add(time.Minute)
+	s.False(d.Seen("der-1/12:00"))
+	s.Equal(1, d.Len())
+}
+
+func (s *DedupTestSuite) TestForget() {
+	d := s.newDedup(&Config{TTL: time.Minute})
+
+	s.False(d.Seen("a"))
+	d.Forget("a")
+	s.False(d.Seen("a"))
+}
+
+func (s *DedupTestSuite) TestMaxEntries() {
+	d := s.newDedup(&Config{TTL: time.Hour, MaxEntries: 3})
+
+	for i := range 5 {
+		s.False(d.Seen(fmt.Sprintf("k%d", i)))
+	}
+	s.Equal(3, d.Len())
+	s.False(d.Seen("k0"))
+	s.True(d.Seen("k4"))
+}
+
+func (s *DedupTestSuite) TestPersistence() {
+	store := NewFileStore(filepath.Join(s.T().TempDir(), "dedup.json"))
+	cfg := &Config{TTL: time.Minute}
+
+	d := s.newDedup(cfg, WithStore(store))
+	s.False(d.Seen("old"))
+	s.now = s.now.Add(30 * time.Second)
+	s.False(d.Seen("new"))
+	s.NoError(d.Close())
+
+	s.now = s.now.Add(45 * time.Second)
+	restored, err := New(cfg, WithStore(store))
+	s.Require().NoError(err)
+	restored.now = func() time.Time { return s.now }
+
+	s.True(restored.Seen("new"))
+	s.False(restored.Seen("old"))
+}
+
+func (s *DedupTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{TTL: time.Minute, MaxEntries: 1000}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing ttl", cfg: &Config{}, expectError: true},
+		{name: "negative max entries", cfg: &Config{TTL: time.Minute, MaxEntries: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestDedupSuite(t *testing.T) {
+	suite.Run(t, new(DedupTestSuite))
+}
@@ -0,0 +1,65 @@
+package dedup
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Store persists seen keys and the time each was first seen.
+type Store interface {
+	Load() (map[string]time.Time, error)
+	Save(seen map[string]time.Time) error
+}
+
+type fileStore struct {
+	path string
+}
+
+// NewFileStore stores keys as JSON at path. A missing file loads as empty.
+func NewFileStore(path string) Store {
+	return &fileStore{path: path}
+}
+
+func (s *fileStore) Load() (map[string]time.Time, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]time.Time{}, nil
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var seen map[string]time.Time
+	if err := json.Unmarshal(data, &seen); err != nil {
+		return nil, errors.Wrapf(err, "decoding dedup store %s", s.path)
+	}
+	return seen, nil
+}
+
+// Save writes to a temporary file and renames it so a crash never leaves a
+// truncated store behind.
+func (s *fileStore) Save(seen map[string]time.Time) error {
+	data, err := json.Marshal(seen)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp.Name(), s.path))
+}
@@ -0,0 +1,228 @@
+// Package drain shuts servers down gracefully: it stops accepting new
+// connections, waits for in-flight requests up to a deadline, and then
+// force-closes and reports whatever is left. httpserver and grpcserver use
+// it, and it works with any server that can be adapted to Server.
+//
+//	l := drain.Listen(ln)
+//	go srv.Serve(l)
+//	<-ctx.Done()
+//	res, err := d.Drain(context.WithoutCancel(ctx), srv, l)
+package drain
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const DefaultTimeout = 15 * time.Second
+
+// Server is a server that can stop gracefully. *http.Server implements it;
+// use GRPC for *grpc.Server.
+type Server interface {
+	// Shutdown stops accepting connections and returns once in-flight
+	// requests have finished or ctx is done.
+	Shutdown(ctx context.Context) error
+	// Close closes all remaining connections immediately.
+	Close() error
+}
+
+type Config struct {
+	// Timeout bounds how long in-flight requests may take to finish.
+	Timeout time.Duration `koanf:"timeout" json:"timeout" envconfig:"timeout"`
+	// Delay keeps accepting connections for a while after readiness starts
+	// failing, giving load balancers time to stop routing to this instance.
+	Delay time.Duration `koanf:"delay" json:"delay" envconfig:"delay"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("drain configuration required")
+	}
+	if c.Timeout < 0 || c.Delay < 0 {
+		return errors.New("drain durations must not be negative")
+	}
+	return nil
+}
+
+// Result describes how a drain went.
+type Result struct {
+	// Remaining is the number of connections still open when the timeout
+	// expired, zero after a clean drain or when no Listener was given.
+	Remaining int
+	// Forced reports whether connections had to be closed.
+	Forced   bool
+	Duration time.Duration
+}
+
+type Option func(*Drainer)
+
+// WithHealth marks h as shutting down when draining starts, so readiness
+// probes fail.
+func WithHealth(h *health.Health) Option {
+	return func(d *Drainer) {
+		d.health = h
+	}
+}
+
+type Drainer struct {
+	cfg    Config
+	log    *slog.Logger
+	health *health.Health
+}
+
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Drainer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := &Drainer{cfg: *cfg, log: log}
+	if d.cfg.Timeout == 0 {
+		d.cfg.Timeout = DefaultTimeout
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
+}
+
+// Drain gracefully stops srv. l, if not nil, is the tracked listener srv
+// serves on and is used to report remaining connections. ctx should not
+// already be cancelled; pass context.WithoutCancel of the run context.
+// Running out of time is not an error: the remaining connections are closed
+// and reported in the Result.
+func (d *Drainer) Drain(ctx context.Context, srv Server, l *Listener) (Result, error) {
+	start := time.Now()
+	if d.health != nil {
+		d.health.MarkShuttingDown()
+	}
+	if d.cfg.Delay > 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(d.cfg.Delay):
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+	res := Result{Duration: time.Since(start)}
+	if err == nil {
+		return res, nil
+	}
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return res, errors.Wrap(err, "shutting down")
+	}
+
+	res.Forced = true
+	if l != nil {
+		res.Remaining = l.Active()
+	}
+	d.log.Warn("drain timed out, closing remaining connections", "remaining", res.Remaining, "timeout", d.cfg.Timeout)
+	if l != nil {
+		// Servers do not always know about every connection, such as gRPC
+		// connections that have not completed their handshake.
+		l.closeAll()
+	}
+	if err := srv.Close(); err != nil {
+		return res, errors.Wrap(err, "closing remaining connections")
+	}
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+type grpcServer struct {
+	srv *grpc.Server
+}
+
+// GRPC adapts a gRPC server to Server.
+func GRPC(srv *grpc.Server) Server {
+	return grpcServer{srv: srv}
+}
+
+func (g grpcServer) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		g.srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the server, which also unblocks a pending GracefulStop.
+func (g grpcServer) Close() error {
+	g.srv.Stop()
+	return nil
+}
+
+// Listener tracks the connections accepted through it.
+type Listener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+}
+
+// Listen wraps l so that open connections can be counted.
+func Listen(l net.Listener) *Listener {
+	return &Listener{Listener: l, conns: map[*conn]struct{}{}}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	tc := &conn{Conn: c, l: l}
+	l.mu.Lock()
+	l.conns[tc] = struct{}{}
+	l.mu.Unlock()
+	return tc, nil
+}
+
+// Active returns the number of accepted connections not yet closed.
+func (l *Listener) Active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
+
+func (l *Listener) closeAll() {
+	l.mu.Lock()
+	conns := make([]*conn, 0, len(l.conns))
+	for c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.mu.Unlock()
+
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+type conn struct {
+	net.Conn
+	l    *Listener
+	once sync.Once
+}
+
+func (c *conn) Close() error {
+	c.once.Do(func() {
+		c.l.mu.Lock()
+		delete(c.l.conns, c)
+		c.l.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
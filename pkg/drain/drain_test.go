@@ -0,0 +1,130 @@
+package drain
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+)
+
+type DrainTestSuite struct {
+	suite.Suite
+}
+
+// serve starts an HTTP server whose handler blocks for hold, and returns it
+// once a request is in flight.
+func (s *DrainTestSuite) serve(hold time.Duration) (*http.Server, *Listener, <-chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	l := Listen(ln)
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-time.After(hold):
+		case <-r.Context().Done():
+		}
+	})}
+	go func() { _ = srv.Serve(l) }()
+
+	respErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		respErr <- err
+	}()
+	<-started
+	return srv, l, respErr
+}
+
+func (s *DrainTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "defaults", cfg: &Config{}, expectError: false},
+		{name: "negative timeout", cfg: &Config{Timeout: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *DrainTestSuite) TestClean() {
+	srv, l, respErr := s.serve(50 * time.Millisecond)
+	s.Equal(1, l.Active())
+
+	h := health.New()
+	d, err := New(&Config{Timeout: time.Second}, logger.Default(), WithHealth(h))
+	s.Require().NoError(err)
+
+	res, err := d.Drain(context.Background(), srv, l)
+	s.Require().NoError(err)
+	s.False(res.Forced)
+	s.Zero(res.Remaining)
+	s.NoError(<-respErr)
+	s.Equal(health.StatusFail, h.Readiness(context.Background()).Status)
+}
+
+func (s *DrainTestSuite) TestForced() {
+	srv, l, respErr := s.serve(time.Minute)
+
+	d, err := New(&Config{Timeout: 50 * time.Millisecond}, logger.Default())
+	s.Require().NoError(err)
+
+	res, err := d.Drain(context.Background(), srv, l)
+	s.Require().NoError(err)
+	s.True(res.Forced)
+	s.Equal(1, res.Remaining)
+	s.Error(<-respErr)
+	s.Eventually(func() bool { return l.Active() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func (s *DrainTestSuite) TestGRPC() {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	l := Listen(ln)
+
+	srv := grpc.NewServer()
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(l) }()
+
+	// A client that never completes the HTTP/2 handshake is invisible to
+	// the gRPC server, so only the listener can close it.
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	s.Require().NoError(err)
+	defer conn.Close()
+	s.Eventually(func() bool { return l.Active() == 1 }, time.Second, 5*time.Millisecond)
+
+	d, err := New(&Config{Timeout: 50 * time.Millisecond}, logger.Default())
+	s.Require().NoError(err)
+	res, err := d.Drain(context.Background(), GRPC(srv), l)
+	s.Require().NoError(err)
+	s.True(res.Forced)
+	s.Equal(1, res.Remaining)
+	s.NoError(<-done)
+	s.Zero(l.Active())
+}
+
+func TestDrainTestSuite(t *testing.T) {
+	suite.Run(t, new(DrainTestSuite))
+}
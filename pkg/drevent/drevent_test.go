@@ -0,0 +1,224 @@
+package drevent
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type DREventTestSuite struct {
+	suite.Suite
+	base time.Time
+}
+
+func (s *DREventTestSuite) SetupTest() {
+	s.base = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *DREventTestSuite) event(id, utility string, startHour, endHour int) Event {
+	return Event{
+		ID:        id,
+		UtilityID: utility,
+		Start:     s.base.Add(time.Duration(startHour) * time.Hour),
+		End:       s.base.Add(time.Duration(endHour) * time.Hour),
+		Lead:      2 * time.Hour,
+	}
+}
+
+func (s *DREventTestSuite) ids(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func (s *DREventTestSuite) TestPhase() {
+	e := s.event("e1", "u1", 17, 20)
+	testCases := []struct {
+		hour  float64
+		phase Phase
+	}{
+		{hour: 12, phase: Scheduled},
+		{hour: 15, phase: Notified},
+		{hour: 17, phase: Active},
+		{hour: 19.5, phase: Active},
+		{hour: 20, phase: Ended},
+	}
+
+	for _, tc := range testCases {
+		at := s.base.Add(time.Duration(tc.hour * float64(time.Hour)))
+		s.Equal(tc.phase, e.PhaseAt(at), "hour %v", tc.hour)
+		s.Equal(tc.phase == Active, e.Active(at))
+	}
+	s.True(e.MeetsLead(s.base.Add(15 * time.Hour)))
+	s.False(e.MeetsLead(s.base.Add(16 * time.Hour)))
+
+	m := e.Model()
+	s.NoError(m.Validate())
+	s.Equal(models.TableDREvents, m.Table())
+	s.Equal(e, FromModel(m, e.Lead))
+}
+
+func (s *DREventTestSuite) TestSchedule() {
+	var sched Schedule
+	s.Require().NoError(sched.Add(s.event("b", "u1", 17, 20)))
+	s.Require().NoError(sched.Add(s.event("a", "u1", 8, 10)))
+	s.Require().NoError(sched.Add(s.event("c", "u2", 18, 21)))
+	s.Require().NoError(sched.Add(s.event("d", "u1", 20, 22)), "adjacent events do not overlap")
+
+	err := sched.Add(s.event("x", "u1", 19, 23))
+	s.ErrorIs(err, ErrOverlap)
+	s.True(gserrors.Is(err, gserrors.Conflict))
+	s.True(gserrors.Is(sched.Add(s.event("y", "u1", 5, 5)), gserrors.InvalidInput))
+
+	s.Equal([]string{"a", "b", "c", "d"}, s.ids(sched.Events()))
+	s.Equal([]string{"b", "c"}, s.ids(sched.ActiveAt(s.base.Add(19*time.Hour))))
+
+	e, ok := sched.ActiveFor("u2", s.base.Add(20*time.Hour))
+	s.True(ok)
+	s.Equal("c", e.ID)
+	_, ok = sched.ActiveFor("u2", s.base.Add(9*time.Hour))
+	s.False(ok)
+
+	e, ok = sched.Next(s.base.Add(10 * time.Hour))
+	s.True(ok)
+	s.Equal("b", e.ID)
+	_, ok = sched.Next(s.base.Add(23 * time.Hour))
+	s.False(ok)
+
+	s.Equal([]string{"a", "b"}, s.ids(sched.Between(s.base.Add(9*time.Hour), s.base.Add(18*time.Hour))))
+	s.Equal([]string{"b", "c"}, s.ids(sched.DueForNotification(s.base.Add(14*time.Hour), s.base.Add(16*time.Hour))))
+	s.Empty(sched.Conflicts())
+}
+
+func (s *DREventTestSuite) TestConflicts() {
+	sched := NewSchedule(
+		s.event("a", "u1", 17, 20),
+		s.event("b", "u2", 17, 20),
+		s.event("c", "u1", 19, 21),
+		s.event("d", "u1", 20, 22),
+	)
+
+	conflicts := sched.Conflicts()
+	s.Require().Len(conflicts, 2)
+	s.Equal("a", conflicts[0].A.ID)
+	s.Equal("c", conflicts[0].B.ID)
+	s.Equal(timeutil.Window{Start: s.base.Add(19 * time.Hour), End: s.base.Add(20 * time.Hour)}, conflicts[0].Overlap)
+	s.Equal("c", conflicts[1].A.ID)
+	s.Equal("d", conflicts[1].B.ID)
+}
+
+func (s *DREventTestSuite) TestRule() {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	s.Require().NoError(err)
+	// Monday 2024-03-04; DST starts on Sunday 2024-03-10.
+	starts := time.Date(2024, 3, 4, 0, 0, 0, 0, la)
+
+	testCases := []struct {
+		name  string
+		rule  Rule
+		from  time.Time
+		to    time.Time
+		dates []string
+	}{
+		{
+			name:  "weekdays",
+			rule:  Rule{Frequency: Weekly, Weekdays: []time.Weekday{time.Monday, time.Wednesday, time.Friday}, At: 17 * time.Hour, Duration: 3 * time.Hour, Starts: starts},
+			from:  starts,
+			to:    starts.AddDate(0, 0, 14),
+			dates: []string{"03-04 17:00 PST", "03-06 17:00 PST", "03-08 17:00 PST", "03-11 17:00 PDT", "03-13 17:00 PDT", "03-15 17:00 PDT"},
+		},
+		{
+			name:  "every other week",
+			rule:  Rule{Frequency: Weekly, Interval: 2, At: 17 * time.Hour, Duration: time.Hour, Starts: starts},
+			from:  starts,
+			to:    starts.AddDate(0, 0, 35),
+			dates: []string{"03-04 17:00 PST", "03-18 17:00 PDT", "04-01 17:00 PDT"},
+		},
+		{
+			name:  "daily with count",
+			rule:  Rule{Frequency: Daily, Interval: 3, At: 14*time.Hour + 30*time.Minute, Duration: time.Hour, Starts: starts, Count: 4},
+			from:  starts.AddDate(0, 0, 4),
+			to:    starts.AddDate(1, 0, 0),
+			dates: []string{"03-10 14:30 PDT", "03-13 14:30 PDT"},
+		},
+		{
+			name:  "until",
+			rule:  Rule{Frequency: Daily, At: 12 * time.Hour, Duration: time.Hour, Starts: starts, Until: starts.AddDate(0, 0, 2).Add(12 * time.Hour)},
+			from:  starts,
+			to:    starts.AddDate(0, 1, 0),
+			dates: []string{"03-04 12:00 PST", "03-05 12:00 PST", "03-06 12:00 PST"},
+		},
+		{
+			name:  "occurrence in progress at from",
+			rule:  Rule{Frequency: Daily, At: 17 * time.Hour, Duration: 3 * time.Hour, Starts: starts},
+			from:  starts.Add(18 * time.Hour),
+			to:    starts.AddDate(0, 0, 1).Add(17 * time.Hour),
+			dates: []string{"03-04 17:00 PST"},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Require().NoError(tc.rule.Validate())
+			var dates []string
+			for w := range tc.rule.Occurrences(tc.from, tc.to) {
+				dates = append(dates, w.Start.Format("01-02 15:04 MST"))
+				s.Equal(tc.rule.Duration, w.Duration())
+			}
+			s.Equal(tc.dates, dates)
+		})
+	}
+}
+
+func (s *DREventTestSuite) TestRuleEvents() {
+	r := &Rule{Frequency: Daily, At: 17 * time.Hour, Duration: 3 * time.Hour, Lead: time.Hour, Starts: s.base}
+	events := r.Events("peak", "u1", s.base, s.base.AddDate(0, 0, 3))
+	s.Equal([]string{"peak-20240701", "peak-20240702", "peak-20240703"}, s.ids(events))
+	s.Equal(time.Hour, events[0].Lead)
+	s.Empty(NewSchedule(events...).Conflicts())
+
+	// Weekdays that can never match must not loop forever.
+	r = &Rule{Frequency: Weekly, Weekdays: []time.Weekday{9}, Duration: time.Hour, Starts: s.base}
+	s.Error(r.Validate())
+	s.Empty(slices.Collect(r.Occurrences(s.base, s.base.AddDate(1, 0, 0))))
+}
+
+func (s *DREventTestSuite) TestRuleValidate() {
+	valid := Rule{Frequency: Daily, Duration: time.Hour, Starts: s.base}
+	testCases := []struct {
+		name        string
+		modify      func(r *Rule)
+		expectError bool
+	}{
+		{name: "valid", modify: func(r *Rule) {}, expectError: false},
+		{name: "unknown frequency", modify: func(r *Rule) { r.Frequency = "hourly" }, expectError: true},
+		{name: "no duration", modify: func(r *Rule) { r.Duration = 0 }, expectError: true},
+		{name: "at past midnight", modify: func(r *Rule) { r.At = 25 * time.Hour }, expectError: true},
+		{name: "no start", modify: func(r *Rule) { r.Starts = time.Time{} }, expectError: true},
+		{name: "negative count", modify: func(r *Rule) { r.Count = -1 }, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			r := valid
+			tc.modify(&r)
+			err := r.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestDREventTestSuite(t *testing.T) {
+	suite.Run(t, new(DREventTestSuite))
+}
@@ -0,0 +1,104 @@
+// Package drevent models demand-response event windows: notification lead
+// time, the phase of an event at a timestamp, overlap detection, active-event
+// lookup and recurrence rules. The simulator, validator and API share it so
+// they agree on when an event is active.
+//
+// Events are half-open intervals [Start, End), matching timeutil.Window and
+// models.DREvent.Active.
+package drevent
+
+import (
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/pkg/errors"
+)
+
+// Phase is where an event stands relative to a point in time.
+type Phase string
+
+const (
+	// Scheduled events have not been announced to participants yet.
+	Scheduled Phase = "scheduled"
+	// Notified events have been announced and are about to start.
+	Notified Phase = "notified"
+	Active   Phase = "active"
+	Ended    Phase = "ended"
+)
+
+// Event is a DR event window for one utility.
+type Event struct {
+	ID        string
+	UtilityID string
+	Start     time.Time
+	End       time.Time
+	// Lead is how long before Start participants are notified.
+	Lead time.Duration
+}
+
+// FromModel converts a stored event, applying lead as its notification
+// lead time.
+func FromModel(e *models.DREvent, lead time.Duration) Event {
+	return Event{ID: e.ID, UtilityID: e.UtilityID, Start: e.StartTime, End: e.EndTime, Lead: lead}
+}
+
+// Model converts e to its stored form.
+func (e Event) Model() *models.DREvent {
+	return &models.DREvent{ID: e.ID, UtilityID: e.UtilityID, StartTime: e.Start, EndTime: e.End}
+}
+
+// Validate requires a positive duration and a non-negative lead time.
+func (e Event) Validate() error {
+	if !e.End.After(e.Start) {
+		return errors.Errorf("dr event %s must end after it starts", e.ID)
+	}
+	if e.Lead < 0 {
+		return errors.Errorf("dr event %s lead time must not be negative", e.ID)
+	}
+	return nil
+}
+
+func (e Event) Window() timeutil.Window {
+	return timeutil.Window{Start: e.Start, End: e.End}
+}
+
+func (e Event) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// NotifyAt is when participants should be notified.
+func (e Event) NotifyAt() time.Time {
+	return e.Start.Add(-e.Lead)
+}
+
+// Active reports whether t falls within the event.
+func (e Event) Active(t time.Time) bool {
+	return e.Window().Contains(t)
+}
+
+// PhaseAt returns the event's phase at t.
+func (e Event) PhaseAt(t time.Time) Phase {
+	switch {
+	case !t.Before(e.End):
+		return Ended
+	case !t.Before(e.Start):
+		return Active
+	case !t.Before(e.NotifyAt()):
+		return Notified
+	default:
+		return Scheduled
+	}
+}
+
+// Overlaps reports whether e and o share any instant.
+func (e Event) Overlaps(o Event) bool {
+	_, ok := e.Window().Overlap(o.Window())
+	return ok
+}
+
+// MeetsLead reports whether an event announced at notified gives
+// participants at least e.Lead of notice.
+func (e Event) MeetsLead(notified time.Time) bool {
+	return !notified.After(e.NotifyAt())
+}
@@ -0,0 +1,147 @@
+package drevent
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/pkg/errors"
+)
+
+// Frequency is the unit a Rule repeats in.
+type Frequency string
+
+const (
+	Daily  Frequency = "daily"
+	Weekly Frequency = "weekly"
+)
+
+// Rule describes a recurring event, such as weekday afternoons from 17:00
+// to 20:00 local time. Occurrences keep their local start time across DST
+// changes.
+type Rule struct {
+	Frequency Frequency `koanf:"frequency" json:"frequency" envconfig:"frequency"`
+	// Interval repeats every Interval days or weeks. Defaults to 1.
+	Interval int `koanf:"interval" json:"interval" envconfig:"interval"`
+	// Weekdays limits weekly rules to these days. Defaults to the weekday
+	// of Starts.
+	Weekdays []time.Weekday `koanf:"weekdays" json:"weekdays" envconfig:"weekdays"`
+	// At is the local time of day occurrences start, as an offset from
+	// midnight.
+	At       time.Duration `koanf:"at" json:"at" envconfig:"at"`
+	Duration time.Duration `koanf:"duration" json:"duration" envconfig:"duration"`
+	Lead     time.Duration `koanf:"lead" json:"lead" envconfig:"lead"`
+	// Starts is the first day of the rule. Its location sets the local time
+	// At is measured in.
+	Starts time.Time `koanf:"starts" json:"starts" envconfig:"starts"`
+	// Until, if set, is the last instant an occurrence may start.
+	Until time.Time `koanf:"until" json:"until" envconfig:"until"`
+	// Count, if set, limits the number of occurrences.
+	Count int `koanf:"count" json:"count" envconfig:"count"`
+}
+
+func (r *Rule) Validate() error {
+	if r == nil {
+		return errors.New("recurrence rule required")
+	}
+	switch r.Frequency {
+	case Daily, Weekly:
+	default:
+		return errors.Errorf("unknown recurrence frequency %q", r.Frequency)
+	}
+	if r.Interval < 0 || r.Count < 0 || r.Lead < 0 {
+		return errors.New("recurrence interval, count and lead must not be negative")
+	}
+	if r.At < 0 || r.At >= 24*time.Hour {
+		return errors.New("recurrence start time must be within the day")
+	}
+	if r.Duration <= 0 {
+		return errors.New("recurrence duration must be greater than 0")
+	}
+	if r.Starts.IsZero() {
+		return errors.New("recurrence start date required")
+	}
+	for _, wd := range r.Weekdays {
+		if wd < time.Sunday || wd > time.Saturday {
+			return errors.Errorf("invalid recurrence weekday %d", wd)
+		}
+	}
+	return nil
+}
+
+// Occurrences yields the occurrence windows overlapping [from, to) in
+// order. Count is applied from Starts, not from from.
+func (r *Rule) Occurrences(from, to time.Time) iter.Seq[timeutil.Window] {
+	return func(yield func(timeutil.Window) bool) {
+		interval := max(r.Interval, 1)
+		weekdays := r.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []time.Weekday{r.Starts.Weekday()}
+		}
+		if r.Frequency == Weekly && !slices.ContainsFunc(weekdays, func(wd time.Weekday) bool {
+			return wd >= time.Sunday && wd <= time.Saturday
+		}) {
+			return
+		}
+
+		first := timeutil.StartOfDay(r.Starts)
+		y, m, d := first.Date()
+		loc := first.Location()
+		// Weeks are counted from the Sunday on or before Starts.
+		startOffset := int(first.Weekday())
+		hour, minute, sec := int(r.At/time.Hour), int(r.At%time.Hour/time.Minute), int(r.At%time.Minute/time.Second)
+
+		n := 0
+		for i := 0; ; i++ {
+			day := time.Date(y, m, d+i, 0, 0, 0, 0, loc)
+			switch r.Frequency {
+			case Daily:
+				if i%interval != 0 {
+					continue
+				}
+			case Weekly:
+				if ((i+startOffset)/7)%interval != 0 || !slices.Contains(weekdays, day.Weekday()) {
+					continue
+				}
+			default:
+				return
+			}
+
+			dy, dm, dd := day.Date()
+			start := time.Date(dy, dm, dd, hour, minute, sec, 0, loc)
+			if start.Before(r.Starts) {
+				continue
+			}
+			if (!r.Until.IsZero() && start.After(r.Until)) || !start.Before(to) {
+				return
+			}
+			n++
+			if r.Count > 0 && n > r.Count {
+				return
+			}
+
+			w := timeutil.Window{Start: start, End: start.Add(r.Duration)}
+			if w.End.After(from) && !yield(w) {
+				return
+			}
+		}
+	}
+}
+
+// Events expands the rule into events for utilityID overlapping [from, to).
+// Each ID is prefix followed by the occurrence's local start date.
+func (r *Rule) Events(prefix, utilityID string, from, to time.Time) []Event {
+	var out []Event
+	for w := range r.Occurrences(from, to) {
+		out = append(out, Event{
+			ID:        fmt.Sprintf("%s-%s", prefix, w.Start.Format("20060102")),
+			UtilityID: utilityID,
+			Start:     w.Start,
+			End:       w.End,
+			Lead:      r.Lead,
+		})
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package drevent
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/pkg/errors"
+)
+
+// ErrOverlap is returned when an event overlaps another event of the same
+// utility.
+var ErrOverlap = gserrors.New(gserrors.Conflict, "dr event overlaps an existing event")
+
+// Conflict is a pair of overlapping events of the same utility.
+type Conflict struct {
+	A, B    Event
+	Overlap timeutil.Window
+}
+
+// Schedule is a set of events ordered by start time. The zero value is an
+// empty schedule. It is not safe for concurrent mutation.
+type Schedule struct {
+	events []Event
+}
+
+// NewSchedule returns a schedule of events. Unlike Add it accepts
+// overlapping events, so Conflicts can report them.
+func NewSchedule(events ...Event) *Schedule {
+	s := &Schedule{events: slices.Clone(events)}
+	slices.SortStableFunc(s.events, compareStart)
+	return s
+}
+
+func compareStart(a, b Event) int {
+	return cmp.Or(a.Start.Compare(b.Start), a.End.Compare(b.End))
+}
+
+// Add inserts e, rejecting invalid events and events that overlap another
+// event of the same utility.
+func (s *Schedule) Add(e Event) error {
+	if err := e.Validate(); err != nil {
+		return gserrors.WithCode(err, gserrors.InvalidInput)
+	}
+	for _, o := range s.events {
+		if o.UtilityID == e.UtilityID && o.Overlaps(e) {
+			return errors.Wrapf(ErrOverlap, "%s overlaps %s", e.ID, o.ID)
+		}
+	}
+	i, _ := slices.BinarySearchFunc(s.events, e, compareStart)
+	s.events = slices.Insert(s.events, i, e)
+	return nil
+}
+
+// Events returns every event in start order.
+func (s *Schedule) Events() []Event {
+	return slices.Clone(s.events)
+}
+
+// ActiveAt returns the events active at t.
+func (s *Schedule) ActiveAt(t time.Time) []Event {
+	var out []Event
+	for _, e := range s.events {
+		if e.Start.After(t) {
+			break
+		}
+		if e.Active(t) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// ActiveFor returns the event of utilityID active at t.
+func (s *Schedule) ActiveFor(utilityID string, t time.Time) (Event, bool) {
+	for _, e := range s.ActiveAt(t) {
+		if e.UtilityID == utilityID {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+// Next returns the first event starting after t.
+func (s *Schedule) Next(t time.Time) (Event, bool) {
+	for _, e := range s.events {
+		if e.Start.After(t) {
+			return e, true
+		}
+	}
+	return Event{}, false
+}
+
+// Between returns the events overlapping [from, to).
+func (s *Schedule) Between(from, to time.Time) []Event {
+	w := timeutil.Window{Start: from, End: to}
+	var out []Event
+	for _, e := range s.events {
+		if !e.Start.Before(to) {
+			break
+		}
+		if _, ok := w.Overlap(e.Window()); ok {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// DueForNotification returns the events whose notification time falls in
+// (after, upTo], for a notifier polling at upTo that last ran at after.
+func (s *Schedule) DueForNotification(after, upTo time.Time) []Event {
+	var out []Event
+	for _, e := range s.events {
+		at := e.NotifyAt()
+		if at.After(after) && !at.After(upTo) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Conflicts returns every pair of overlapping events of the same utility.
+func (s *Schedule) Conflicts() []Conflict {
+	var out []Conflict
+	for i, a := range s.events {
+		for _, b := range s.events[i+1:] {
+			if !b.Start.Before(a.End) {
+				break
+			}
+			if a.UtilityID != b.UtilityID {
+				continue
+			}
+			if ov, ok := a.Window().Overlap(b.Window()); ok {
+				out = append(out, Conflict{A: a, B: b, Overlap: ov})
+			}
+		}
+	}
+	return out
+}
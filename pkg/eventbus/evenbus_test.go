@@ -1,6 +1,7 @@
 package eventbus
 
 import (
+	"context"
 	"sync"
 	"testing"
 	"time"
@@ -94,6 +95,45 @@ func (s *EventBusTestSuite) TestPublish() {
 	}
 }
 
+func (s *EventBusTestSuite) TestPublishWait() {
+	eb := New()
+	defer eb.Close()
+	ch := eb.Subscribe(1)
+	ctx := context.Background()
+
+	s.Require().NoError(eb.PublishWait(ctx, 1))
+	done := make(chan error, 1)
+	go func() { done <- eb.PublishWait(ctx, 2) }()
+
+	select {
+	case <-done:
+		s.Fail("PublishWait should block on a full subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.Equal(1, <-ch)
+	s.NoError(<-done)
+	s.Equal(2, <-ch)
+
+	s.Require().NoError(eb.PublishWait(ctx, 3))
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(eb.PublishWait(timeout, 4), context.DeadlineExceeded)
+}
+
+func (s *EventBusTestSuite) TestUnsubscribeReleasesPublishWait() {
+	eb := New()
+	ch := eb.Subscribe(0)
+
+	done := make(chan error, 1)
+	go func() { done <- eb.PublishWait(context.Background(), "stuck") }()
+	time.Sleep(10 * time.Millisecond)
+
+	eb.Unsubscribe(ch)
+	s.NoError(<-done)
+	_, ok := <-ch
+	s.False(ok)
+}
+
 func (s *EventBusTestSuite) TestUnsubscribe() {
 	eb := New()
 
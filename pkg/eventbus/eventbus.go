@@ -1,23 +1,40 @@
 package eventbus
 
-import "sync"
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
 
 type EventBus interface {
 	Subscribe(capacity int) chan any
 	Publish(event any)
+	// PublishWait delivers event to every subscriber, waiting for room in
+	// full channels rather than dropping it, until ctx is done.
+	PublishWait(ctx context.Context, event any) error
 	Unsubscribe(ch chan any)
 	Subscribers() []chan any
 	Close()
 }
 
+type subscriber struct {
+	ch chan any
+	// gone is closed when the subscriber is removed, releasing PublishWait
+	// calls blocked on ch; sends counts those calls so ch is closed only
+	// after they return.
+	gone  chan struct{}
+	sends sync.WaitGroup
+}
+
 type eventBus struct {
-	subscribers []chan any
+	subscribers []*subscriber
 	mu          sync.Mutex
 }
 
 func New() EventBus {
 	return &eventBus{
-		subscribers: []chan any{},
+		subscribers: []*subscriber{},
 	}
 }
 
@@ -25,46 +42,91 @@ func (eb *eventBus) Subscribe(capacity int) chan any {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
-	ch := make(chan any, capacity)
-	eb.subscribers = append(eb.subscribers, ch)
-	return ch
+	sub := &subscriber{ch: make(chan any, capacity), gone: make(chan struct{})}
+	eb.subscribers = append(eb.subscribers, sub)
+	return sub.ch
 }
 
 func (eb *eventBus) Publish(event any) {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
-	for _, ch := range eb.subscribers {
+	for _, sub := range eb.subscribers {
 		select {
-		case ch <- event:
+		case sub.ch <- event:
 		default:
 		}
 	}
 }
 
-func (eb *eventBus) Unsubscribe(ch chan any) {
+func (eb *eventBus) PublishWait(ctx context.Context, event any) error {
 	eb.mu.Lock()
-	defer eb.mu.Unlock()
+	subs := make([]*subscriber, len(eb.subscribers))
+	copy(subs, eb.subscribers)
+	for _, sub := range subs {
+		sub.sends.Add(1)
+	}
+	eb.mu.Unlock()
 
+	var err error
+	for _, sub := range subs {
+		if err == nil {
+			select {
+			case sub.ch <- event:
+			case <-sub.gone:
+			case <-ctx.Done():
+				err = errors.WithStack(ctx.Err())
+			}
+		}
+		sub.sends.Done()
+	}
+	return err
+}
+
+func (eb *eventBus) Unsubscribe(ch chan any) {
+	eb.mu.Lock()
+	var removed *subscriber
 	for i, sub := range eb.subscribers {
-		if sub == ch {
+		if sub.ch == ch {
+			removed = sub
 			eb.subscribers = append(eb.subscribers[:i], eb.subscribers[i+1:]...)
-			close(ch)
 			break
 		}
 	}
+	eb.mu.Unlock()
+
+	if removed != nil {
+		removed.close()
+	}
 }
 
 func (eb *eventBus) Subscribers() []chan any {
-	return eb.subscribers
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	if eb.subscribers == nil {
+		return nil
+	}
+	chans := make([]chan any, len(eb.subscribers))
+	for i, sub := range eb.subscribers {
+		chans[i] = sub.ch
+	}
+	return chans
 }
 
 func (eb *eventBus) Close() {
 	eb.mu.Lock()
-	defer eb.mu.Unlock()
+	subs := eb.subscribers
+	eb.subscribers = nil
+	eb.mu.Unlock()
 
-	for _, ch := range eb.subscribers {
-		close(ch)
+	for _, sub := range subs {
+		sub.close()
 	}
-	eb.subscribers = nil
+}
+
+func (sub *subscriber) close() {
+	close(sub.gone)
+	sub.sends.Wait()
+	close(sub.ch)
 }
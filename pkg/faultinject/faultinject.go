@@ -0,0 +1,180 @@
+// Package faultinject adds latency, errors and dropped calls to clients so
+// degradation can be rehearsed in staging without changing service code.
+//
+// Wrap clients at construction time; with injection disabled the wrappers
+// are not installed at all:
+//
+//	inj, err := faultinject.FromEnv("GS_FAULTS_", log)
+//	...
+//	client = faultinject.BQClient(client, inj)
+//
+// Then enable it per environment, for example:
+//
+//	GS_FAULTS_ENABLED=true GS_FAULTS_ERROR_RATE=0.05 GS_FAULTS_LATENCY=200ms GS_FAULTS_OPERATIONS=bq.*
+package faultinject
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"path"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrInjected is returned by calls failed on purpose. It is Unavailable so
+// callers exercise their retry paths.
+var ErrInjected = gserrors.New(gserrors.Unavailable, "injected fault")
+
+type Config struct {
+	Enabled bool `koanf:"enabled" json:"enabled" envconfig:"enabled"`
+	// ErrorRate is the fraction of calls, from 0 to 1, that fail with
+	// ErrInjected.
+	ErrorRate float64 `koanf:"error_rate" json:"error_rate" envconfig:"error_rate"`
+	// DropRate is the fraction of writes and publishes that report success
+	// without being performed, simulating lost messages.
+	DropRate float64 `koanf:"drop_rate" json:"drop_rate" envconfig:"drop_rate"`
+	// Latency is added before every call, plus up to Jitter more.
+	Latency time.Duration `koanf:"latency" json:"latency" envconfig:"latency"`
+	Jitter  time.Duration `koanf:"jitter" json:"jitter" envconfig:"jitter"`
+	// Operations limits injection to operations matching these path.Match
+	// patterns, such as "bq.*" or "validator.SendAverages". Empty means all.
+	Operations []string `koanf:"operations" json:"operations" envconfig:"operations"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("fault injection configuration required")
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 || c.DropRate < 0 || c.DropRate > 1 {
+		return errors.New("fault injection rates must be between 0 and 1")
+	}
+	if c.Latency < 0 || c.Jitter < 0 {
+		return errors.New("fault injection latency must not be negative")
+	}
+	for _, op := range c.Operations {
+		if _, err := path.Match(op, ""); err != nil {
+			return errors.Wrapf(err, "fault injection operation pattern %q", op)
+		}
+	}
+	return nil
+}
+
+type Option func(*Injector)
+
+// WithMetrics counts injected faults by operation and kind.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(i *Injector) {
+		faults, err := m.NewCounterVec("faultinject", "faults_total", "Faults injected by operation and kind.", "operation", "kind")
+		if err != nil {
+			i.log.Warn("registering fault injection metrics", "error", err)
+			return
+		}
+		i.faults = faults
+	}
+}
+
+// Injector decides which calls to delay, fail or drop. A nil *Injector
+// injects nothing, and the wrappers return the client unchanged for it.
+type Injector struct {
+	cfg    *Config
+	log    *slog.Logger
+	faults *prometheus.CounterVec
+}
+
+// New returns an Injector for cfg, or nil if injection is disabled.
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Injector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	i := &Injector{cfg: cfg, log: log}
+	for _, opt := range opts {
+		opt(i)
+	}
+	log.Warn("fault injection enabled",
+		"error_rate", cfg.ErrorRate, "drop_rate", cfg.DropRate,
+		"latency", cfg.Latency, "jitter", cfg.Jitter, "operations", cfg.Operations)
+	return i, nil
+}
+
+// FromEnv loads a Config from environment variables starting with prefix
+// and calls New.
+func FromEnv(prefix string, log *slog.Logger, opts ...Option) (*Injector, error) {
+	cfg := &Config{}
+	if err := config.Load(cfg, config.WithEnvPrefix(prefix)); err != nil {
+		return nil, err
+	}
+	return New(cfg, log, opts...)
+}
+
+// Before is called before operation op. It waits for the configured latency,
+// then returns ErrInjected for a failed call or drop=true for a call that
+// should be skipped while reporting success. Callers that cannot skip treat
+// drop as false.
+func (i *Injector) Before(ctx context.Context, op string) (drop bool, err error) {
+	if i == nil || !i.matches(op) {
+		return false, nil
+	}
+
+	if delay := i.delay(); delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return false, errors.WithStack(ctx.Err())
+		case <-t.C:
+		}
+		i.count(op, "latency")
+	}
+
+	if i.cfg.ErrorRate > 0 && rand.Float64() < i.cfg.ErrorRate {
+		i.count(op, "error")
+		return false, errors.Wrap(ErrInjected, op)
+	}
+	if i.cfg.DropRate > 0 && rand.Float64() < i.cfg.DropRate {
+		i.count(op, "drop")
+		return true, nil
+	}
+	return false, nil
+}
+
+// Fail is Before for operations that cannot be dropped.
+func (i *Injector) Fail(ctx context.Context, op string) error {
+	_, err := i.Before(ctx, op)
+	return err
+}
+
+func (i *Injector) matches(op string) bool {
+	if len(i.cfg.Operations) == 0 {
+		return true
+	}
+	for _, pattern := range i.cfg.Operations {
+		if ok, _ := path.Match(pattern, op); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Injector) delay() time.Duration {
+	d := i.cfg.Latency
+	if i.cfg.Jitter > 0 {
+		d += rand.N(i.cfg.Jitter)
+	}
+	return d
+}
+
+func (i *Injector) count(op, kind string) {
+	if i.faults != nil {
+		i.faults.WithLabelValues(op, kind).Inc()
+	}
+}
@@ -0,0 +1,170 @@
+package faultinject
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeBQ counts the calls that reach it.
+type fakeBQ struct {
+	bqclient.BQClient
+	puts, gets int
+}
+
+func (f *fakeBQ) Put(context.Context, string, any) error {
+	f.puts++
+	return nil
+}
+
+func (f *fakeBQ) Get(context.Context, string, string, any) error {
+	f.gets++
+	return nil
+}
+
+type fakeValidator struct {
+	validator.ValidatorClient
+	sent int
+}
+
+func (f *fakeValidator) SendAverages(context.Context, []*pb.AverageOutput) error {
+	f.sent++
+	return nil
+}
+
+type FaultInjectTestSuite struct {
+	suite.Suite
+}
+
+func (s *FaultInjectTestSuite) injector(cfg *Config, opts ...Option) *Injector {
+	cfg.Enabled = true
+	inj, err := New(cfg, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return inj
+}
+
+func (s *FaultInjectTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "disabled", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Enabled: true, ErrorRate: 0.1, Latency: time.Second, Operations: []string{"bq.*"}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "rate above one", cfg: &Config{DropRate: 1.5}, expectError: true},
+		{name: "negative latency", cfg: &Config{Latency: -time.Second}, expectError: true},
+		{name: "bad pattern", cfg: &Config{Operations: []string{"bq.["}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *FaultInjectTestSuite) TestDisabled() {
+	inj, err := New(&Config{ErrorRate: 1}, logger.Default())
+	s.Require().NoError(err)
+	s.Nil(inj)
+
+	bq := &fakeBQ{}
+	s.Same(bq, BQClient(bq, inj))
+	drop, err := inj.Before(context.Background(), "bq.Put")
+	s.False(drop)
+	s.NoError(err)
+}
+
+func (s *FaultInjectTestSuite) TestFromEnv() {
+	s.T().Setenv("GS_FAULTS_ENABLED", "true")
+	s.T().Setenv("GS_FAULTS_ERROR_RATE", "1")
+	s.T().Setenv("GS_FAULTS_OPERATIONS", "bq.Put,validator.*")
+
+	inj, err := FromEnv("GS_FAULTS_", logger.Default())
+	s.Require().NoError(err)
+	s.Require().NotNil(inj)
+	s.Equal([]string{"bq.Put", "validator.*"}, inj.cfg.Operations)
+
+	s.Error(inj.Fail(context.Background(), "bq.Put"))
+	s.Error(inj.Fail(context.Background(), "validator.SendAverages"))
+	s.NoError(inj.Fail(context.Background(), "bq.Get"))
+}
+
+func (s *FaultInjectTestSuite) TestBQClient() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "faultinject"})
+	s.Require().NoError(err)
+	inj := s.injector(&Config{ErrorRate: 1, Operations: []string{"bq.Get"}}, WithMetrics(m))
+	bq := &fakeBQ{}
+	c := BQClient(bq, inj)
+
+	err = c.Get(context.Background(), "projects", "p1", nil)
+	s.ErrorIs(err, ErrInjected)
+	s.Equal(gserrors.Unavailable, gserrors.CodeOf(err))
+	s.Zero(bq.gets)
+
+	s.NoError(c.Put(context.Background(), "projects", nil))
+	s.Equal(1, bq.puts)
+	s.Equal(float64(1), testutil.ToFloat64(inj.faults.WithLabelValues("bq.Get", "error")))
+
+	inj = s.injector(&Config{ErrorRate: 1})
+	data, errs := BQClient(bq, inj).StreamRead(context.Background(), "der_data", nil)
+	_, ok := <-data
+	s.False(ok)
+	s.ErrorIs(<-errs, ErrInjected)
+}
+
+func (s *FaultInjectTestSuite) TestDrop() {
+	inj := s.injector(&Config{DropRate: 1})
+	bq := &fakeBQ{}
+	s.NoError(BQClient(bq, inj).Put(context.Background(), "projects", nil))
+	s.Zero(bq.puts, "dropped writes report success")
+
+	// Reads cannot be dropped.
+	s.NoError(BQClient(bq, inj).Get(context.Background(), "projects", "p1", nil))
+	s.Equal(1, bq.gets)
+
+	v := &fakeValidator{}
+	s.NoError(ValidatorClient(v, inj).SendAverages(context.Background(), nil))
+	s.Zero(v.sent)
+
+	bus := eventbus.New()
+	defer bus.Close()
+	ch := bus.Subscribe(1)
+	EventBus(bus, inj).Publish("reading")
+	s.Empty(ch)
+}
+
+func (s *FaultInjectTestSuite) TestLatency() {
+	inj := s.injector(&Config{Latency: 20 * time.Millisecond})
+	v := &fakeValidator{}
+
+	start := time.Now()
+	s.NoError(ValidatorClient(v, inj).SendAverages(context.Background(), nil))
+	s.GreaterOrEqual(time.Since(start), 20*time.Millisecond)
+	s.Equal(1, v.sent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(ValidatorClient(v, inj).SendAverages(ctx, nil), context.Canceled)
+	s.Equal(1, v.sent)
+}
+
+func TestFaultInjectTestSuite(t *testing.T) {
+	suite.Run(t, new(FaultInjectTestSuite))
+}
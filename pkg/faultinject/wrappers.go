@@ -0,0 +1,225 @@
+package faultinject
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
+)
+
+// BQClient wraps c with injected faults. Operations are named bq.<Method>.
+// Writes can be dropped; reads can only be delayed or failed. Methods added
+// to BQClient later pass through untouched until wrapped here.
+func BQClient(c bqclient.BQClient, inj *Injector) bqclient.BQClient {
+	if inj == nil {
+		return c
+	}
+	return &bqClient{BQClient: c, inj: inj}
+}
+
+type bqClient struct {
+	bqclient.BQClient
+	inj *Injector
+}
+
+func (c *bqClient) write(ctx context.Context, op string, fn func() error) error {
+	drop, err := c.inj.Before(ctx, op)
+	if err != nil || drop {
+		return err
+	}
+	return fn()
+}
+
+func (c *bqClient) Put(ctx context.Context, table string, data any) error {
+	return c.write(ctx, "bq.Put", func() error { return c.BQClient.Put(ctx, table, data) })
+}
+
+func (c *bqClient) StreamPut(ctx context.Context, table string, data any) error {
+	return c.write(ctx, "bq.StreamPut", func() error { return c.BQClient.StreamPut(ctx, table, data) })
+}
+
+func (c *bqClient) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
+	return c.write(ctx, "bq.StreamPutAll", func() error { return c.BQClient.StreamPutAll(ctx, inputs) })
+}
+
+func (c *bqClient) Update(ctx context.Context, table string, id string, updates map[string]any) error {
+	return c.write(ctx, "bq.Update", func() error { return c.BQClient.Update(ctx, table, id, updates) })
+}
+
+func (c *bqClient) ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error {
+	return c.write(ctx, "bq.ExecScript", func() error { return c.BQClient.ExecScript(ctx, script, params) })
+}
+
+func (c *bqClient) LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...bqclient.LoadOption) error {
+	return c.write(ctx, "bq.LoadFromGCS", func() error { return c.BQClient.LoadFromGCS(ctx, table, gcsURI, format, opts...) })
+}
+
+func (c *bqClient) CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	return c.write(ctx, "bq.CopyTable", func() error { return c.BQClient.CopyTable(ctx, srcTable, dstTable, writeDisposition) })
+}
+
+func (c *bqClient) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	return c.write(ctx, "bq.UpdateMulti", func() error { return c.BQClient.UpdateMulti(ctx, table, ids, updates) })
+}
+
+func (c *bqClient) Upsert(ctx context.Context, table string, id string, data any) error {
+	return c.write(ctx, "bq.Upsert", func() error { return c.BQClient.Upsert(ctx, table, id, data) })
+}
+
+func (c *bqClient) Delete(ctx context.Context, table string, id string) error {
+	return c.write(ctx, "bq.Delete", func() error { return c.BQClient.Delete(ctx, table, id) })
+}
+
+func (c *bqClient) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	return c.write(ctx, "bq.DeleteMulti", func() error { return c.BQClient.DeleteMulti(ctx, table, ids) })
+}
+
+func (c *bqClient) Query(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
+	if err := c.inj.Fail(ctx, "bq.Query"); err != nil {
+		return nil, err
+	}
+	return c.BQClient.Query(ctx, query, params)
+}
+
+func (c *bqClient) QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error {
+	if err := c.inj.Fail(ctx, "bq.QueryRow"); err != nil {
+		return err
+	}
+	return c.BQClient.QueryRow(ctx, query, params, dst)
+}
+
+func (c *bqClient) QueryPage(ctx context.Context, query string, params []bigquery.QueryParameter, pageSize int, pageToken string, dst any) (string, error) {
+	if err := c.inj.Fail(ctx, "bq.QueryPage"); err != nil {
+		return "", err
+	}
+	return c.BQClient.QueryPage(ctx, query, params, pageSize, pageToken, dst)
+}
+
+func (c *bqClient) DryRun(ctx context.Context, query string, params []bigquery.QueryParameter) (*bigquery.JobStatistics, error) {
+	if err := c.inj.Fail(ctx, "bq.DryRun"); err != nil {
+		return nil, err
+	}
+	return c.BQClient.DryRun(ctx, query, params)
+}
+
+func (c *bqClient) Get(ctx context.Context, table string, id string, dst any) error {
+	if err := c.inj.Fail(ctx, "bq.Get"); err != nil {
+		return err
+	}
+	return c.BQClient.Get(ctx, table, id, dst)
+}
+
+func (c *bqClient) GetMulti(ctx context.Context, table string, ids []string, dst any) error {
+	if err := c.inj.Fail(ctx, "bq.GetMulti"); err != nil {
+		return err
+	}
+	return c.BQClient.GetMulti(ctx, table, ids, dst)
+}
+
+func (c *bqClient) List(ctx context.Context, table string, filter map[string]any, opts bqclient.ListOptions, dst any) error {
+	if err := c.inj.Fail(ctx, "bq.List"); err != nil {
+		return err
+	}
+	return c.BQClient.List(ctx, table, filter, opts, dst)
+}
+
+func (c *bqClient) Count(ctx context.Context, table string, filter map[string]any) (int64, error) {
+	if err := c.inj.Fail(ctx, "bq.Count"); err != nil {
+		return 0, err
+	}
+	return c.BQClient.Count(ctx, table, filter)
+}
+
+func (c *bqClient) Exists(ctx context.Context, table string, id string) (bool, error) {
+	if err := c.inj.Fail(ctx, "bq.Exists"); err != nil {
+		return false, err
+	}
+	return c.BQClient.Exists(ctx, table, id)
+}
+
+func (c *bqClient) ExtractToGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat) error {
+	if err := c.inj.Fail(ctx, "bq.ExtractToGCS"); err != nil {
+		return err
+	}
+	return c.BQClient.ExtractToGCS(ctx, table, gcsURI, format)
+}
+
+func (c *bqClient) StreamRead(ctx context.Context, table string, filter *bqclient.ReadFilter) (<-chan []byte, <-chan error) {
+	if err := c.inj.Fail(ctx, "bq.StreamRead"); err != nil {
+		data := make(chan []byte)
+		errs := make(chan error, 1)
+		errs <- err
+		close(data)
+		close(errs)
+		return data, errs
+	}
+	return c.BQClient.StreamRead(ctx, table, filter)
+}
+
+func (c *bqClient) StreamReadRows(ctx context.Context, table string, filter *bqclient.ReadFilter, newRow func() any) (<-chan any, <-chan error) {
+	if err := c.inj.Fail(ctx, "bq.StreamReadRows"); err != nil {
+		rows := make(chan any)
+		errs := make(chan error, 1)
+		errs <- err
+		close(rows)
+		close(errs)
+		return rows, errs
+	}
+	return c.BQClient.StreamReadRows(ctx, table, filter, newRow)
+}
+
+// ValidatorClient wraps c with injected faults on validator.SendAverages,
+// which can be dropped.
+func ValidatorClient(c validator.ValidatorClient, inj *Injector) validator.ValidatorClient {
+	if inj == nil {
+		return c
+	}
+	return &validatorClient{ValidatorClient: c, inj: inj}
+}
+
+type validatorClient struct {
+	validator.ValidatorClient
+	inj *Injector
+}
+
+func (c *validatorClient) SendAverages(ctx context.Context, averages []*pb.AverageOutput) error {
+	drop, err := c.inj.Before(ctx, "validator.SendAverages")
+	if err != nil || drop {
+		return err
+	}
+	return c.ValidatorClient.SendAverages(ctx, averages)
+}
+
+// EventBus wraps b so eventbus.Publish and PublishWait are delayed and
+// dropped as configured. Publish cannot report errors, so injected errors
+// also drop the event; PublishWait returns them.
+func EventBus(b eventbus.EventBus, inj *Injector) eventbus.EventBus {
+	if inj == nil {
+		return b
+	}
+	return &eventBus{EventBus: b, inj: inj}
+}
+
+type eventBus struct {
+	eventbus.EventBus
+	inj *Injector
+}
+
+func (b *eventBus) Publish(event any) {
+	drop, err := b.inj.Before(context.Background(), "eventbus.Publish")
+	if err != nil || drop {
+		return
+	}
+	b.EventBus.Publish(event)
+}
+
+func (b *eventBus) PublishWait(ctx context.Context, event any) error {
+	drop, err := b.inj.Before(ctx, "eventbus.PublishWait")
+	if err != nil || drop {
+		return err
+	}
+	return b.EventBus.PublishWait(ctx, event)
+}
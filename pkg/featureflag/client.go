@@ -0,0 +1,170 @@
+package featureflag
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/pkg/errors"
+)
+
+// Change describes a flag that was added, modified or removed. Old is nil for
+// new flags and New is nil for removed ones.
+type Change struct {
+	Name string
+	Old  *Flag
+	New  *Flag
+}
+
+type Option func(*Client)
+
+// WithEventBus publishes every Change onto bus in addition to invoking the
+// registered callbacks.
+func WithEventBus(bus eventbus.EventBus) Option {
+	return func(c *Client) {
+		c.bus = bus
+	}
+}
+
+// Client merges flags from its sources, later sources overriding earlier
+// ones flag by flag, so a Firestore source can override file defaults.
+type Client struct {
+	sources []Source
+	log     *slog.Logger
+	bus     eventbus.EventBus
+
+	mu        sync.RWMutex
+	snapshots []map[string]Flag
+	flags     map[string]Flag
+	callbacks []func(Change)
+}
+
+// New loads every source once. Call Watch to keep flags up to date.
+func New(ctx context.Context, sources []Source, log *slog.Logger, opts ...Option) (*Client, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("at least one feature flag source required")
+	}
+
+	c := &Client{
+		sources:   sources,
+		log:       log,
+		snapshots: make([]map[string]Flag, len(sources)),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	for i, src := range sources {
+		flags, err := src.Load(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading feature flags from source %d", i)
+		}
+		c.snapshots[i] = flags
+	}
+	c.flags = merge(c.snapshots)
+	return c, nil
+}
+
+// Enabled reports whether the flag is on for everyone. Unknown flags are off.
+func (c *Client) Enabled(name string) bool {
+	f, ok := c.Get(name)
+	return ok && f.On()
+}
+
+// EnabledFor reports whether the flag is on for projectID.
+func (c *Client) EnabledFor(name, projectID string) bool {
+	f, ok := c.Get(name)
+	return ok && f.OnFor(name, projectID)
+}
+
+func (c *Client) Get(name string) (Flag, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.flags[name]
+	return f, ok
+}
+
+// Flags returns a copy of every known flag.
+func (c *Client) Flags() map[string]Flag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.flags)
+}
+
+// OnChange registers fn to be called for every flag change. Callbacks run on
+// the goroutine of the source that changed.
+func (c *Client) OnChange(fn func(Change)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, fn)
+}
+
+// Watch starts watching every source that supports it and returns once all
+// watches are established. Watching stops when ctx is cancelled.
+func (c *Client) Watch(ctx context.Context) error {
+	for i, src := range c.sources {
+		w, ok := src.(Watcher)
+		if !ok {
+			continue
+		}
+		err := w.Watch(ctx, func(flags map[string]Flag) {
+			c.update(i, flags)
+		})
+		if err != nil {
+			return errors.Wrapf(err, "watching feature flag source %d", i)
+		}
+	}
+	return nil
+}
+
+func (c *Client) update(source int, flags map[string]Flag) {
+	c.mu.Lock()
+	c.snapshots[source] = flags
+	merged := merge(c.snapshots)
+	changes := diff(c.flags, merged)
+	c.flags = merged
+	callbacks := slices.Clone(c.callbacks)
+	c.mu.Unlock()
+
+	for _, change := range changes {
+		c.log.Info("feature flag changed", "flag", change.Name, "old", change.Old, "new", change.New)
+		for _, fn := range callbacks {
+			fn(change)
+		}
+		if c.bus != nil {
+			c.bus.Publish(change)
+		}
+	}
+}
+
+func merge(snapshots []map[string]Flag) map[string]Flag {
+	merged := map[string]Flag{}
+	for _, flags := range snapshots {
+		maps.Copy(merged, flags)
+	}
+	return merged
+}
+
+func diff(old, new map[string]Flag) []Change {
+	var changes []Change
+	for name, f := range new {
+		if o, ok := old[name]; !ok {
+			changes = append(changes, Change{Name: name, New: &f})
+		} else if !o.equal(f) {
+			changes = append(changes, Change{Name: name, Old: &o, New: &f})
+		}
+	}
+	for name, o := range old {
+		if _, ok := new[name]; !ok {
+			changes = append(changes, Change{Name: name, Old: &o})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return changes
+}
@@ -0,0 +1,158 @@
+package featureflag
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/stretchr/testify/suite"
+)
+
+type staticSource struct {
+	flags  map[string]Flag
+	update func(map[string]Flag)
+}
+
+func (s *staticSource) Load(context.Context) (map[string]Flag, error) {
+	return s.flags, nil
+}
+
+func (s *staticSource) Watch(_ context.Context, update func(map[string]Flag)) error {
+	s.update = update
+	return nil
+}
+
+type FeatureFlagTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+}
+
+func (s *FeatureFlagTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *FeatureFlagTestSuite) TestEvaluation() {
+	s.True(Flag{Enabled: true}.On())
+	s.False(Flag{Enabled: true, Percentage: Percent(50)}.On())
+	s.False(Flag{Projects: []string{"p1"}}.On())
+
+	s.False(Flag{Enabled: true, Percentage: Percent(0)}.OnFor("algo", "p1"))
+	s.True(Flag{Enabled: true, Percentage: Percent(100)}.OnFor("algo", "p1"))
+
+	pinned := Flag{Projects: []string{"p1"}}
+	s.True(pinned.OnFor("algo", "p1"))
+	s.False(pinned.OnFor("algo", "p2"))
+
+	rollout := Flag{Enabled: true, Percentage: Percent(30)}
+	on := 0
+	for i := range 1000 {
+		key := fmt.Sprintf("project-%d", i)
+		if rollout.OnFor("algo", key) {
+			on++
+			// Growing the rollout never removes a project from it.
+			s.True(Flag{Enabled: true, Percentage: Percent(60)}.OnFor("algo", key))
+		}
+		s.Equal(rollout.OnFor("algo", key), rollout.OnFor("algo", key))
+	}
+	s.InDelta(300, on, 60)
+}
+
+func (s *FeatureFlagTestSuite) TestEnvSource() {
+	src := envSource{prefix: "FF_", environ: func() []string {
+		return []string{"FF_NEW_BASELINE=true", "FF_CANARY=25%", "FF_PILOT=p1, p2", "HOME=/root", "FF_=x"}
+	}}
+
+	flags, err := src.Load(context.Background())
+	s.Require().NoError(err)
+	s.Equal(map[string]Flag{
+		"new_baseline": {Enabled: true},
+		"canary":       {Enabled: true, Percentage: Percent(25)},
+		"pilot":        {Projects: []string{"p1", "p2"}},
+	}, flags)
+
+	for _, bad := range []string{"FF_X=150%", "FF_X=abc%", "FF_X="} {
+		src.environ = func() []string { return []string{bad} }
+		_, err := src.Load(context.Background())
+		s.Error(err, bad)
+	}
+}
+
+func (s *FeatureFlagTestSuite) TestMergeAndChanges() {
+	defaults := &staticSource{flags: map[string]Flag{"a": {Enabled: true}, "b": {}}}
+	overrides := &staticSource{flags: map[string]Flag{"b": {Enabled: true}}}
+
+	bus := eventbus.New()
+	events := bus.Subscribe(10)
+	c, err := New(context.Background(), []Source{defaults, overrides}, s.log, WithEventBus(bus))
+	s.Require().NoError(err)
+	s.True(c.Enabled("a"))
+	s.True(c.Enabled("b"))
+	s.False(c.Enabled("missing"))
+	s.False(c.EnabledFor("missing", "p1"))
+
+	var changes []Change
+	c.OnChange(func(ch Change) { changes = append(changes, ch) })
+	s.Require().NoError(c.Watch(context.Background()))
+
+	overrides.update(map[string]Flag{"b": {Enabled: true}, "c": {Projects: []string{"p1"}}})
+	overrides.update(map[string]Flag{"c": {Projects: []string{"p1"}}})
+
+	s.Require().Len(changes, 2)
+	s.Equal("c", changes[0].Name)
+	s.Nil(changes[0].Old)
+	s.Equal("b", changes[1].Name)
+	s.False(changes[1].New.Enabled)
+	s.False(c.Enabled("b"))
+	s.True(c.EnabledFor("c", "p1"))
+	s.Len(events, 2)
+	s.Len(c.Flags(), 3)
+}
+
+func (s *FeatureFlagTestSuite) TestFileSource() {
+	path := filepath.Join(s.T().TempDir(), "flags.yaml")
+	s.Require().NoError(os.WriteFile(path, []byte("flags:\n  algo:\n    enabled: true\n    percentage: 10\n"), 0o600))
+
+	src, err := FileSource(path)
+	s.Require().NoError(err)
+	c, err := New(context.Background(), []Source{src}, s.log)
+	s.Require().NoError(err)
+	f, ok := c.Get("algo")
+	s.True(ok)
+	s.Equal(Percent(10), f.Percentage)
+
+	changed := make(chan Change, 1)
+	c.OnChange(func(ch Change) { changed <- ch })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Require().NoError(c.Watch(ctx))
+	s.Require().NoError(os.WriteFile(path, []byte("flags:\n  algo:\n    enabled: true\n"), 0o600))
+
+	select {
+	case ch := <-changed:
+		s.Equal("algo", ch.Name)
+		s.True(c.Enabled("algo"))
+	case <-time.After(2 * time.Second):
+		s.Fail("flag change not observed")
+	}
+
+	bad := filepath.Join(s.T().TempDir(), "bad.yaml")
+	s.Require().NoError(os.WriteFile(bad, []byte("flags:\n  algo:\n    percentage: 200\n"), 0o600))
+	_, err = FileSource(bad)
+	s.Error(err)
+}
+
+func (s *FeatureFlagTestSuite) TestNoSources() {
+	_, err := New(context.Background(), nil, s.log)
+	s.Error(err)
+}
+
+func TestFeatureFlagSuite(t *testing.T) {
+	suite.Run(t, new(FeatureFlagTestSuite))
+}
@@ -0,0 +1,76 @@
+// Package featureflag evaluates boolean, percentage and per-project feature
+// flags loaded from the environment, a config file or Firestore, and notifies
+// subscribers when flags change at runtime.
+package featureflag
+
+import (
+	"hash/fnv"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// Flag is the stored definition of a flag. Its name is the key it is stored
+// under: the map key in a file, the document ID in Firestore.
+type Flag struct {
+	// Enabled turns the flag on, subject to Percentage.
+	Enabled bool `koanf:"enabled" json:"enabled" firestore:"enabled"`
+	// Percentage rolls an enabled flag out to a stable share of projects,
+	// from 0 to 100, where 0 is nobody. Unset means no gradual rollout:
+	// everyone gets the flag.
+	Percentage *float64 `koanf:"percentage" json:"percentage,omitempty" firestore:"percentage,omitempty"`
+	// Projects always have the flag on, even when Enabled is false.
+	Projects []string `koanf:"projects" json:"projects" firestore:"projects"`
+}
+
+func (f *Flag) Validate() error {
+	if p := f.Percentage; p != nil && (*p < 0 || *p > 100) {
+		return errors.Errorf("feature flag percentage %v must be between 0 and 100", *p)
+	}
+	return nil
+}
+
+// Percent returns p for use as a Flag's Percentage.
+func Percent(p float64) *float64 {
+	return &p
+}
+
+// On reports whether the flag is on for everyone.
+func (f Flag) On() bool {
+	return f.Enabled && fullRollout(f.Percentage)
+}
+
+// OnFor reports whether the flag named name is on for key, usually a project
+// ID. Percentage rollouts hash name and key together so a project stays in
+// or out of a rollout as the percentage grows, independently per flag.
+func (f Flag) OnFor(name, key string) bool {
+	if slices.Contains(f.Projects, key) {
+		return true
+	}
+	if !f.Enabled {
+		return false
+	}
+	if fullRollout(f.Percentage) {
+		return true
+	}
+	return bucket(name, key) < *f.Percentage
+}
+
+func (f Flag) equal(o Flag) bool {
+	samePct := f.Percentage == nil && o.Percentage == nil ||
+		f.Percentage != nil && o.Percentage != nil && *f.Percentage == *o.Percentage
+	return f.Enabled == o.Enabled && samePct && slices.Equal(f.Projects, o.Projects)
+}
+
+func fullRollout(pct *float64) bool {
+	return pct == nil || *pct >= 100
+}
+
+// bucket maps name and key to [0, 100) in steps of 0.01.
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{':'})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
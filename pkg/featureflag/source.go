@@ -0,0 +1,196 @@
+package featureflag
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/grid-stream-org/go-commons/pkg/firestore"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Source provides a complete set of flags.
+type Source interface {
+	Load(ctx context.Context) (map[string]Flag, error)
+}
+
+// Watcher is implemented by sources that can push updates. Watch returns once
+// the watch is established and then calls update with the complete set of
+// flags after every change until ctx is cancelled.
+type Watcher interface {
+	Watch(ctx context.Context, update func(map[string]Flag)) error
+}
+
+// EnvSource reads flags from environment variables starting with prefix. The
+// rest of the variable name, lowercased, is the flag name, and the value is
+// one of:
+//
+//	true, false      a boolean flag
+//	25%              enabled for 25% of projects
+//	proj-1,proj-2    enabled only for the listed projects
+//
+// For example FF_NEW_BASELINE=10% with prefix "FF_" rolls new_baseline out to
+// a tenth of projects.
+func EnvSource(prefix string) Source {
+	return envSource{prefix: prefix, environ: os.Environ}
+}
+
+type envSource struct {
+	prefix  string
+	environ func() []string
+}
+
+func (s envSource) Load(context.Context) (map[string]Flag, error) {
+	flags := map[string]Flag{}
+	for _, kv := range s.environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, s.prefix)
+		if !ok || name == "" {
+			continue
+		}
+
+		f, err := parseEnvFlag(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing %s", key)
+		}
+		flags[strings.ToLower(name)] = f
+	}
+	return flags, nil
+}
+
+func parseEnvFlag(value string) (Flag, error) {
+	value = strings.TrimSpace(value)
+	if b, err := strconv.ParseBool(value); err == nil {
+		return Flag{Enabled: b}, nil
+	}
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return Flag{}, errors.WithStack(err)
+		}
+		f := Flag{Enabled: true, Percentage: &p}
+		return f, f.Validate()
+	}
+	if value == "" {
+		return Flag{}, errors.New("empty value")
+	}
+
+	var projects []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			projects = append(projects, p)
+		}
+	}
+	return Flag{Projects: projects}, nil
+}
+
+type fileFlags struct {
+	Flags map[string]Flag `koanf:"flags"`
+}
+
+func (f *fileFlags) Validate() error {
+	for name, flag := range f.Flags {
+		if err := flag.Validate(); err != nil {
+			return errors.Wrapf(err, "flag %s", name)
+		}
+	}
+	return nil
+}
+
+// FileSource reads flags from the "flags" map of a YAML or JSON file and
+// reloads them when the file changes:
+//
+//	flags:
+//	  new_baseline:
+//	    enabled: true
+//	    percentage: 25
+//	    projects: [proj-1]
+func FileSource(path string) (Source, error) {
+	w, err := config.NewWatcher[fileFlags](config.New(config.WithFile(path)))
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{watcher: w}, nil
+}
+
+type fileSource struct {
+	watcher *config.Watcher[fileFlags]
+}
+
+func (s *fileSource) Load(context.Context) (map[string]Flag, error) {
+	return maps.Clone(s.watcher.Current().Flags), nil
+}
+
+func (s *fileSource) Watch(ctx context.Context, update func(map[string]Flag)) error {
+	s.watcher.OnChange(func(e config.ChangeEvent[fileFlags]) {
+		update(maps.Clone(e.New.Flags))
+	})
+	return s.watcher.Watch(ctx)
+}
+
+// FirestoreSource reads flags from a collection whose document IDs are flag
+// names, and listens for changes so rollouts apply without a redeploy.
+func FirestoreSource(c firestore.FirestoreClient, collection string, log *slog.Logger) Source {
+	return &firestoreSource{col: firestore.NewCollection[Flag](c, collection), log: log}
+}
+
+type firestoreSource struct {
+	col *firestore.Collection[Flag]
+	log *slog.Logger
+}
+
+func (s *firestoreSource) Load(ctx context.Context) (map[string]Flag, error) {
+	docs, err := s.col.Query(ctx, s.col.Ref().Query)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]Flag, len(docs))
+	for _, doc := range docs {
+		flags[doc.ID] = *doc.Value
+	}
+	return flags, nil
+}
+
+// Watch listens to full query snapshots rather than individual changes so a
+// slow consumer can never miss an update.
+func (s *firestoreSource) Watch(ctx context.Context, update func(map[string]Flag)) error {
+	it := s.col.Ref().Snapshots(ctx)
+
+	go func() {
+		defer it.Stop()
+		for {
+			snap, err := it.Next()
+			if err != nil {
+				if ctx.Err() == nil && status.Code(err) != codes.Canceled {
+					s.log.Error("feature flag listener stopped", "collection", s.col.Ref().ID, "error", err)
+				}
+				return
+			}
+
+			docs, err := snap.Documents.GetAll()
+			if err != nil {
+				s.log.Error("reading feature flag snapshot", "collection", s.col.Ref().ID, "error", err)
+				continue
+			}
+
+			flags := make(map[string]Flag, len(docs))
+			for _, doc := range docs {
+				var f Flag
+				if err := doc.DataTo(&f); err != nil {
+					s.log.Error("decoding feature flag", "flag", doc.Ref.ID, "error", err)
+					continue
+				}
+				flags[doc.Ref.ID] = f
+			}
+			update(flags)
+		}
+	}()
+	return nil
+}
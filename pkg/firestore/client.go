@@ -0,0 +1,91 @@
+package firestore
+
+import (
+	"context"
+	"log/slog"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrNotFound = errors.New("document not found")
+
+type FirestoreClient interface {
+	Client() *firestore.Client
+	RunTransaction(ctx context.Context, fn func(ctx context.Context, tx *firestore.Transaction) error) error
+	Close() error
+}
+
+type Config struct {
+	ProjectID  string `koanf:"project_id" json:"project_id" envconfig:"project_id"`
+	DatabaseID string `koanf:"database_id" json:"database_id" envconfig:"database_id"`
+	// CredsPath names a credentials file. Without it Application Default
+	// Credentials are used.
+	CredsPath string `koanf:"creds_path" json:"creds_path" envconfig:"creds_path"`
+}
+
+type firestoreClient struct {
+	cfg    *Config
+	client *firestore.Client
+	log    *slog.Logger
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("firestore configuration required")
+	}
+	if c.ProjectID == "" {
+		return errors.New("firestore project ID required")
+	}
+	return nil
+}
+
+func New(ctx context.Context, cfg *Config, log *slog.Logger) (FirestoreClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	databaseID := cfg.DatabaseID
+	if databaseID == "" {
+		databaseID = firestore.DefaultDatabaseID
+	}
+
+	var opts []option.ClientOption
+	if cfg.CredsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredsPath))
+	}
+	client, err := firestore.NewClientWithDatabase(ctx, cfg.ProjectID, databaseID, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &firestoreClient{
+		cfg:    cfg,
+		client: client,
+		log:    log,
+	}, nil
+}
+
+func (c *firestoreClient) Client() *firestore.Client {
+	return c.client
+}
+
+// RunTransaction runs fn in a read-write transaction, retrying it on
+// contention. fn must not have side effects outside the transaction.
+func (c *firestoreClient) RunTransaction(ctx context.Context, fn func(ctx context.Context, tx *firestore.Transaction) error) error {
+	return errors.WithStack(c.client.RunTransaction(ctx, fn))
+}
+
+func (c *firestoreClient) Close() error {
+	return errors.WithStack(c.client.Close())
+}
+
+func wrapError(err error, path string) error {
+	if status.Code(err) == codes.NotFound {
+		return errors.Wrap(ErrNotFound, path)
+	}
+	return errors.Wrap(err, path)
+}
@@ -0,0 +1,84 @@
+package firestore
+
+import (
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type FirestoreTestSuite struct {
+	suite.Suite
+}
+
+func (s *FirestoreTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{ProjectID: "p", CredsPath: "creds.json"}},
+		{name: "valid with database", cfg: &Config{ProjectID: "p", DatabaseID: "devices", CredsPath: "creds.json"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing project", cfg: &Config{CredsPath: "creds.json"}, expectError: true},
+		{name: "application default credentials", cfg: &Config{ProjectID: "p"}},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *FirestoreTestSuite) TestWrapError() {
+	testCases := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{name: "not found", err: status.Error(codes.NotFound, "missing"), notFound: true},
+		{name: "other status", err: status.Error(codes.Unavailable, "down")},
+		{name: "plain error", err: errors.New("boom")},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := wrapError(tc.err, "devices/der-1")
+			s.Equal(tc.notFound, errors.Is(err, ErrNotFound))
+			s.Contains(err.Error(), "devices/der-1")
+		})
+	}
+}
+
+func (s *FirestoreTestSuite) TestChangeKind() {
+	testCases := []struct {
+		in   firestore.DocumentChangeKind
+		want ChangeKind
+		str  string
+	}{
+		{in: firestore.DocumentAdded, want: Added, str: "added"},
+		{in: firestore.DocumentModified, want: Modified, str: "modified"},
+		{in: firestore.DocumentRemoved, want: Removed, str: "removed"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.str, func() {
+			kind := changeKind(tc.in)
+			s.Equal(tc.want, kind)
+			s.Equal(tc.str, kind.String())
+		})
+	}
+}
+
+func TestFirestoreSuite(t *testing.T) {
+	suite.Run(t, new(FirestoreTestSuite))
+}
@@ -0,0 +1,105 @@
+package firestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// Collection provides typed access to the documents of a single collection.
+// T is mapped to document fields using firestore struct tags.
+type Collection[T any] struct {
+	ref *firestore.CollectionRef
+}
+
+// Document pairs a decoded document with its ID.
+type Document[T any] struct {
+	ID    string
+	Value *T
+}
+
+func NewCollection[T any](c FirestoreClient, path string) *Collection[T] {
+	return &Collection[T]{ref: c.Client().Collection(path)}
+}
+
+func (c *Collection[T]) Ref() *firestore.CollectionRef {
+	return c.ref
+}
+
+func (c *Collection[T]) Get(ctx context.Context, id string) (*T, error) {
+	snap, err := c.ref.Doc(id).Get(ctx)
+	if err != nil {
+		return nil, wrapError(err, c.ref.Path+"/"+id)
+	}
+	return decode[T](snap)
+}
+
+func (c *Collection[T]) Set(ctx context.Context, id string, v *T) error {
+	if _, err := c.ref.Doc(id).Set(ctx, v); err != nil {
+		return wrapError(err, c.ref.Path+"/"+id)
+	}
+	return nil
+}
+
+// Update applies field updates to an existing document. It returns
+// ErrNotFound if the document does not exist.
+func (c *Collection[T]) Update(ctx context.Context, id string, updates []firestore.Update) error {
+	if _, err := c.ref.Doc(id).Update(ctx, updates); err != nil {
+		return wrapError(err, c.ref.Path+"/"+id)
+	}
+	return nil
+}
+
+func (c *Collection[T]) Delete(ctx context.Context, id string) error {
+	if _, err := c.ref.Doc(id).Delete(ctx); err != nil {
+		return wrapError(err, c.ref.Path+"/"+id)
+	}
+	return nil
+}
+
+// Query runs q, which must be built from Ref, and decodes every result.
+func (c *Collection[T]) Query(ctx context.Context, q firestore.Query) ([]*Document[T], error) {
+	it := q.Documents(ctx)
+	defer it.Stop()
+
+	var docs []*Document[T]
+	for {
+		snap, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "querying %s", c.ref.Path)
+		}
+		v, err := decode[T](snap)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &Document[T]{ID: snap.Ref.ID, Value: v})
+	}
+	return docs, nil
+}
+
+// GetTx reads a document inside a transaction.
+func (c *Collection[T]) GetTx(tx *firestore.Transaction, id string) (*T, error) {
+	snap, err := tx.Get(c.ref.Doc(id))
+	if err != nil {
+		return nil, wrapError(err, c.ref.Path+"/"+id)
+	}
+	return decode[T](snap)
+}
+
+// SetTx writes a document inside a transaction.
+func (c *Collection[T]) SetTx(tx *firestore.Transaction, id string, v *T) error {
+	return errors.WithStack(tx.Set(c.ref.Doc(id), v))
+}
+
+func decode[T any](snap *firestore.DocumentSnapshot) (*T, error) {
+	v := new(T)
+	if err := snap.DataTo(v); err != nil {
+		return nil, errors.Wrapf(err, "decoding %s", snap.Ref.Path)
+	}
+	return v, nil
+}
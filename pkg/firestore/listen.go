@@ -0,0 +1,82 @@
+package firestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Modified
+	Removed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// DocumentChange is published on the eventbus for every document that is
+// added to, modified in, or removed from a listened query.
+type DocumentChange[T any] struct {
+	Collection string
+	Kind       ChangeKind
+	ID         string
+	Value      *T
+}
+
+// Listen watches q and publishes a DocumentChange[T] onto bus for each change
+// until ctx is cancelled. The initial snapshot is delivered as Added changes.
+func (c *Collection[T]) Listen(ctx context.Context, q firestore.Query, bus eventbus.EventBus) error {
+	it := q.Snapshots(ctx)
+	defer it.Stop()
+
+	for {
+		snap, err := it.Next()
+		if err != nil {
+			if ctx.Err() != nil || status.Code(err) == codes.Canceled {
+				return nil
+			}
+			return errors.Wrapf(err, "listening to %s", c.ref.Path)
+		}
+
+		for _, change := range snap.Changes {
+			v, err := decode[T](change.Doc)
+			if err != nil {
+				return err
+			}
+			bus.Publish(DocumentChange[T]{
+				Collection: c.ref.ID,
+				Kind:       changeKind(change.Kind),
+				ID:         change.Doc.Ref.ID,
+				Value:      v,
+			})
+		}
+	}
+}
+
+func changeKind(k firestore.DocumentChangeKind) ChangeKind {
+	switch k {
+	case firestore.DocumentAdded:
+		return Added
+	case firestore.DocumentModified:
+		return Modified
+	default:
+		return Removed
+	}
+}
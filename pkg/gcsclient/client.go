@@ -0,0 +1,194 @@
+package gcsclient
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type GCSClient interface {
+	Upload(ctx context.Context, bucket, object string, data []byte, contentType string) error
+	Download(ctx context.Context, bucket, object string) ([]byte, error)
+	NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error)
+	NewWriter(ctx context.Context, bucket, object string, contentType string) io.WriteCloser
+	SignedURL(bucket, object, method string, expires time.Duration) (string, error)
+	List(ctx context.Context, bucket, prefix string) ([]*ObjectInfo, error)
+	Delete(ctx context.Context, bucket, object string) error
+	Close() error
+}
+
+type Config struct {
+	ProjectID string `koanf:"project_id" json:"project_id" envconfig:"project_id"`
+	// CredsPath names a service account key file. Without it Application
+	// Default Credentials are used.
+	CredsPath string       `koanf:"creds_path" json:"creds_path" envconfig:"creds_path"`
+	Retry     retry.Config `koanf:"retry" json:"retry" envconfig:"retry"`
+}
+
+type ObjectInfo struct {
+	Bucket      string
+	Name        string
+	Size        int64
+	ContentType string
+	Updated     time.Time
+}
+
+type gcsClient struct {
+	cfg    *Config
+	client *storage.Client
+	log    *slog.Logger
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("gcs configuration required")
+	}
+	if c.ProjectID == "" {
+		return errors.New("gcs project ID required")
+	}
+	return c.Retry.Validate()
+}
+
+func New(ctx context.Context, cfg *Config, log *slog.Logger) (GCSClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var opts []option.ClientOption
+	if cfg.CredsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredsPath))
+	}
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &gcsClient{
+		cfg:    cfg,
+		client: client,
+		log:    log,
+	}, nil
+}
+
+func (c *gcsClient) retryOptions(op, bucket, object string) []retry.Option {
+	return append(c.cfg.Retry.Options(),
+		retry.WithRetryIf(storage.ShouldRetry),
+		retry.WithOnRetry(func(attempt int, err error, delay time.Duration) {
+			c.log.Warn("retrying gcs operation", "op", op, "bucket", bucket, "object", object, "attempt", attempt, "delay", delay, "error", err)
+		}),
+	)
+}
+
+// Upload writes data to the object, replacing any existing content. The whole
+// upload is retried on transient errors.
+func (c *gcsClient) Upload(ctx context.Context, bucket, object string, data []byte, contentType string) error {
+	return retry.Do(ctx, func(ctx context.Context) error {
+		w := c.NewWriter(ctx, bucket, object, contentType)
+		if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+			_ = w.Close()
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(w.Close())
+	}, c.retryOptions("upload", bucket, object)...)
+}
+
+// Download reads the whole object into memory, retrying transient errors.
+func (c *gcsClient) Download(ctx context.Context, bucket, object string) ([]byte, error) {
+	return retry.DoValue(ctx, func(ctx context.Context) ([]byte, error) {
+		r, err := c.NewReader(ctx, bucket, object)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		return data, errors.WithStack(err)
+	}, c.retryOptions("download", bucket, object)...)
+}
+
+func (c *gcsClient) NewReader(ctx context.Context, bucket, object string) (io.ReadCloser, error) {
+	r, err := c.client.Bucket(bucket).Object(object).NewReader(ctx)
+	if err != nil {
+		return nil, wrapError(err, bucket, object)
+	}
+	return r, nil
+}
+
+// NewWriter returns a streaming writer. The object is only committed when
+// Close returns without error.
+func (c *gcsClient) NewWriter(ctx context.Context, bucket, object string, contentType string) io.WriteCloser {
+	w := c.client.Bucket(bucket).Object(object).NewWriter(ctx)
+	w.ContentType = contentType
+	return w
+}
+
+// SignedURL returns a V4 signed URL granting method access to the object
+// until expires elapses. Signing uses the key in CredsPath or, with
+// Application Default Credentials, the IAM signBlob API, which needs the
+// Service Account Token Creator role on the service's own account.
+func (c *gcsClient) SignedURL(bucket, object, method string, expires time.Duration) (string, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+	url, err := c.client.Bucket(bucket).SignedURL(object, &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(expires),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "signing url for gs://%s/%s", bucket, object)
+	}
+	return url, nil
+}
+
+func (c *gcsClient) List(ctx context.Context, bucket, prefix string) ([]*ObjectInfo, error) {
+	it := c.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+
+	var objects []*ObjectInfo
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing gs://%s/%s", bucket, prefix)
+		}
+		objects = append(objects, &ObjectInfo{
+			Bucket:      attrs.Bucket,
+			Name:        attrs.Name,
+			Size:        attrs.Size,
+			ContentType: attrs.ContentType,
+			Updated:     attrs.Updated,
+		})
+	}
+	return objects, nil
+}
+
+func (c *gcsClient) Delete(ctx context.Context, bucket, object string) error {
+	if err := c.client.Bucket(bucket).Object(object).Delete(ctx); err != nil {
+		return wrapError(err, bucket, object)
+	}
+	return nil
+}
+
+func (c *gcsClient) Close() error {
+	return errors.WithStack(c.client.Close())
+}
+
+func wrapError(err error, bucket, object string) error {
+	if errors.Is(err, storage.ErrObjectNotExist) || errors.Is(err, storage.ErrBucketNotExist) {
+		return errors.Wrapf(ErrNotFound, "gs://%s/%s", bucket, object)
+	}
+	return errors.Wrapf(err, "gs://%s/%s", bucket, object)
+}
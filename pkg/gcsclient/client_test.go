@@ -0,0 +1,116 @@
+package gcsclient
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type GCSClientTestSuite struct {
+	suite.Suite
+	credsPath string
+}
+
+func (s *GCSClientTestSuite) SetupTest() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	s.Require().NoError(err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	creds, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-1",
+		"private_key":    string(keyPEM),
+		"client_email":   "reports@test-project.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	})
+	s.Require().NoError(err)
+
+	s.credsPath = filepath.Join(s.T().TempDir(), "creds.json")
+	s.Require().NoError(os.WriteFile(s.credsPath, creds, 0o600))
+}
+
+func (s *GCSClientTestSuite) TestSignedURL() {
+	c, err := New(context.Background(), &Config{ProjectID: "test-project", CredsPath: s.credsPath}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.Require().NoError(err)
+	defer c.Close()
+
+	signed, err := c.SignedURL("reports", "2025/01/summary.csv", "", time.Hour)
+	s.Require().NoError(err)
+
+	u, err := url.Parse(signed)
+	s.Require().NoError(err)
+	s.Contains(u.Path, "/reports/2025/01/summary.csv")
+	s.Equal("GOOG4-RSA-SHA256", u.Query().Get("X-Goog-Algorithm"))
+	s.NotEmpty(u.Query().Get("X-Goog-Signature"))
+	s.Contains(u.Query().Get("X-Goog-Credential"), "reports@test-project.iam.gserviceaccount.com")
+
+	_, err = c.SignedURL("reports", "upload.csv", http.MethodPut, 15*time.Minute)
+	s.NoError(err)
+}
+
+func (s *GCSClientTestSuite) TestWrapError() {
+	testCases := []struct {
+		name     string
+		err      error
+		notFound bool
+	}{
+		{name: "missing object", err: storage.ErrObjectNotExist, notFound: true},
+		{name: "missing bucket", err: storage.ErrBucketNotExist, notFound: true},
+		{name: "other", err: errors.New("boom")},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := wrapError(tc.err, "reports", "a.csv")
+			s.Equal(tc.notFound, errors.Is(err, ErrNotFound))
+			s.Contains(err.Error(), "gs://reports/a.csv")
+		})
+	}
+}
+
+func (s *GCSClientTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{ProjectID: "p", CredsPath: "creds.json"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing project", cfg: &Config{CredsPath: "creds.json"}, expectError: true},
+		{name: "application default credentials", cfg: &Config{ProjectID: "p"}},
+		{name: "invalid retry", cfg: &Config{ProjectID: "p", CredsPath: "creds.json", Retry: retry.Config{MaxAttempts: -1}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestGCSClientSuite(t *testing.T) {
+	suite.Run(t, new(GCSClientTestSuite))
+}
@@ -0,0 +1,256 @@
+// Package grpcserver assembles the standard gRPC server used by grid-stream
+// services: listener, TLS, keepalive enforcement, the default interceptor
+// chain, reflection and graceful stop.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/drain"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/reflection"
+)
+
+const (
+	DefaultKeepaliveTime     = 2 * time.Hour
+	DefaultKeepaliveTimeout  = 20 * time.Second
+	DefaultMinPingInterval   = 5 * time.Minute
+	DefaultMaxConnectionIdle = 15 * time.Minute
+	DefaultShutdownTimeout   = 15 * time.Second
+)
+
+type Config struct {
+	Addr              string            `koanf:"addr" json:"addr" envconfig:"addr"`
+	Reflection        bool              `koanf:"reflection" json:"reflection" envconfig:"reflection"`
+	KeepaliveTime     time.Duration     `koanf:"keepalive_time" json:"keepalive_time" envconfig:"keepalive_time"`
+	KeepaliveTimeout  time.Duration     `koanf:"keepalive_timeout" json:"keepalive_timeout" envconfig:"keepalive_timeout"`
+	MinPingInterval   time.Duration     `koanf:"min_ping_interval" json:"min_ping_interval" envconfig:"min_ping_interval"`
+	MaxConnectionIdle time.Duration     `koanf:"max_connection_idle" json:"max_connection_idle" envconfig:"max_connection_idle"`
+	MaxConnectionAge  time.Duration     `koanf:"max_connection_age" json:"max_connection_age" envconfig:"max_connection_age"`
+	ShutdownTimeout   time.Duration     `koanf:"shutdown_timeout" json:"shutdown_timeout" envconfig:"shutdown_timeout"`
+	TLS               *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+}
+
+type Option func(*options)
+
+type options struct {
+	verifier      auth.Verifier
+	publicMethods map[string]bool
+	metrics       *metrics.Metrics
+	tracing       bool
+	unary         []grpc.UnaryServerInterceptor
+	stream        []grpc.StreamServerInterceptor
+	serverOptions []grpc.ServerOption
+	listener      net.Listener
+}
+
+type Server struct {
+	cfg      *Config
+	log      *slog.Logger
+	srv      *grpc.Server
+	listener net.Listener
+	drainer  *drain.Drainer
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("grpc server configuration required")
+	}
+	if c.Addr == "" {
+		return errors.New("grpc server addr required")
+	}
+	if c.KeepaliveTime < 0 || c.KeepaliveTimeout < 0 || c.MinPingInterval < 0 ||
+		c.MaxConnectionIdle < 0 || c.MaxConnectionAge < 0 || c.ShutdownTimeout < 0 {
+		return errors.New("grpc server durations must not be negative")
+	}
+	return c.TLS.Validate()
+}
+
+// WithAuth requires every call, except those to publicMethods, to carry a
+// bearer token accepted by v. Verified claims are available through
+// auth.FromContext.
+func WithAuth(v auth.Verifier, publicMethods ...string) Option {
+	return func(o *options) {
+		o.verifier = v
+		o.publicMethods = make(map[string]bool, len(publicMethods))
+		for _, m := range publicMethods {
+			o.publicMethods[m] = true
+		}
+	}
+}
+
+// WithMetrics records request counts and latencies in m.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithTracing creates a server span per call using the global OpenTelemetry
+// providers configured by the otel package.
+func WithTracing() Option {
+	return func(o *options) {
+		o.tracing = true
+	}
+}
+
+// WithUnaryInterceptors appends interceptors after the standard chain.
+func WithUnaryInterceptors(i ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) {
+		o.unary = append(o.unary, i...)
+	}
+}
+
+// WithStreamInterceptors appends interceptors after the standard chain.
+func WithStreamInterceptors(i ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) {
+		o.stream = append(o.stream, i...)
+	}
+}
+
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.serverOptions = append(o.serverOptions, opts...)
+	}
+}
+
+// WithListener serves on l instead of listening on Config.Addr.
+func WithListener(l net.Listener) Option {
+	return func(o *options) {
+		o.listener = l
+	}
+}
+
+// New builds the server. Register services on GRPC before calling Run.
+// Interceptors run in the order recovery, logging, metrics, auth, then any
+// added with WithUnaryInterceptors or WithStreamInterceptors.
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	unary := []grpc.UnaryServerInterceptor{UnaryRecovery(log), UnaryLogging(log)}
+	stream := []grpc.StreamServerInterceptor{StreamRecovery(log), StreamLogging(log)}
+	if o.metrics != nil {
+		m, err := newServerMetrics(o.metrics)
+		if err != nil {
+			return nil, err
+		}
+		unary = append(unary, m.unary())
+		stream = append(stream, m.stream())
+	}
+	if o.verifier != nil {
+		unary = append(unary, UnaryAuth(o.verifier, o.publicMethods))
+		stream = append(stream, StreamAuth(o.verifier, o.publicMethods))
+	}
+	unary = append(unary, o.unary...)
+	stream = append(stream, o.stream...)
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              orDefault(cfg.KeepaliveTime, DefaultKeepaliveTime),
+			Timeout:           orDefault(cfg.KeepaliveTimeout, DefaultKeepaliveTimeout),
+			MaxConnectionIdle: orDefault(cfg.MaxConnectionIdle, DefaultMaxConnectionIdle),
+			MaxConnectionAge:  cfg.MaxConnectionAge,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             orDefault(cfg.MinPingInterval, DefaultMinPingInterval),
+			PermitWithoutStream: true,
+		}),
+	}
+	if o.tracing {
+		serverOpts = append(serverOpts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+
+	tlsCfg, err := cfg.TLS.BuildServer()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	serverOpts = append(serverOpts, o.serverOptions...)
+
+	srv := grpc.NewServer(serverOpts...)
+	if cfg.Reflection {
+		reflection.Register(srv)
+	}
+
+	drainer, err := drain.New(&drain.Config{Timeout: orDefault(cfg.ShutdownTimeout, DefaultShutdownTimeout)}, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		cfg:      cfg,
+		log:      log,
+		srv:      srv,
+		listener: o.listener,
+		drainer:  drainer,
+	}, nil
+}
+
+// GRPC returns the underlying server for service registration.
+func (s *Server) GRPC() *grpc.Server {
+	return s.srv
+}
+
+// Run serves until ctx is cancelled and then stops gracefully, forcing the
+// remaining connections closed after Config.ShutdownTimeout. It returns nil
+// after a clean shutdown and an error when connections had to be forced.
+func (s *Server) Run(ctx context.Context) error {
+	l := s.listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", s.cfg.Addr); err != nil {
+			return errors.Wrapf(err, "listening on %s", s.cfg.Addr)
+		}
+	}
+
+	dl := drain.Listen(l)
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("grpc server listening", "addr", l.Addr().String())
+		errCh <- s.srv.Serve(dl)
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.WithStack(err)
+	case <-ctx.Done():
+	}
+
+	s.log.Info("grpc server shutting down")
+	res, err := s.drainer.Drain(context.WithoutCancel(ctx), drain.GRPC(s.srv), dl)
+	if err != nil {
+		return errors.Wrap(err, "shutting down grpc server")
+	}
+	if res.Forced {
+		return errors.Errorf("grpc server shutdown timed out with %d connections open", res.Remaining)
+	}
+	return errors.WithStack(<-errCh)
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
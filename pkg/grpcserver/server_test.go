@@ -0,0 +1,194 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeVerifier struct{}
+
+func (fakeVerifier) Verify(_ context.Context, token string) (*auth.Claims, error) {
+	switch token {
+	case "good":
+	case "down":
+		return nil, gserrors.Wrap(errors.New("fetching keys from 10.0.0.7"), gserrors.Unavailable, "key source unavailable")
+	case "broken":
+		return nil, errors.New("decoding keys from 10.0.0.7")
+	default:
+		return nil, auth.ErrUnauthenticated
+	}
+	return &auth.Claims{Subject: "user-1"}, nil
+}
+
+type GRPCServerTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+}
+
+func (s *GRPCServerTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *GRPCServerTestSuite) start(opts ...Option) (healthpb.HealthClient, *metrics.Metrics, func()) {
+	l := bufconn.Listen(1 << 20)
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc", Env: "dev"})
+	s.Require().NoError(err)
+
+	opts = append(opts, WithListener(l), WithMetrics(m))
+	srv, err := New(&Config{Addr: "bufconn", Reflection: true}, s.log, opts...)
+	s.Require().NoError(err)
+	healthpb.RegisterHealthServer(srv.GRPC(), health.NewServer())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+
+	return healthpb.NewHealthClient(conn), m, func() {
+		conn.Close()
+		cancel()
+		s.NoError(<-done)
+	}
+}
+
+func (s *GRPCServerTestSuite) TestAuth() {
+	client, _, stop := s.start(WithAuth(fakeVerifier{}, healthpb.Health_Watch_FullMethodName))
+	defer stop()
+
+	testCases := []struct {
+		name string
+		md   metadata.MD
+		code codes.Code
+	}{
+		{name: "valid token", md: metadata.Pairs("authorization", "Bearer good"), code: codes.OK},
+		{name: "invalid token", md: metadata.Pairs("authorization", "Bearer bad"), code: codes.Unauthenticated},
+		{name: "not bearer", md: metadata.Pairs("authorization", "Basic abc"), code: codes.Unauthenticated},
+		{name: "missing metadata", md: metadata.MD{}, code: codes.Unauthenticated},
+		{name: "verifier unavailable", md: metadata.Pairs("authorization", "Bearer down"), code: codes.Unavailable},
+		{name: "verifier failure", md: metadata.Pairs("authorization", "Bearer broken"), code: codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), tc.md), 5*time.Second)
+			defer cancel()
+			_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+			s.Equal(tc.code, status.Code(err))
+			s.NotContains(status.Convert(err).Message(), "10.0.0.7")
+		})
+	}
+}
+
+func (s *GRPCServerTestSuite) TestMetrics() {
+	client, m, stop := s.start()
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	s.NoError(err)
+
+	sm, err := newServerMetrics(m)
+	s.Require().NoError(err)
+	s.Equal(1.0, testutil.ToFloat64(sm.handled.WithLabelValues(healthpb.Health_Check_FullMethodName, "OK")))
+}
+
+func (s *GRPCServerTestSuite) TestUnaryRecovery() {
+	interceptor := UnaryRecovery(s.log)
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	s.Equal(codes.Internal, status.Code(err))
+}
+
+func (s *GRPCServerTestSuite) TestAuthContext() {
+	interceptor := UnaryAuth(fakeVerifier{}, nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer good"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, func(ctx context.Context, _ any) (any, error) {
+		claims, ok := auth.FromContext(ctx)
+		if !ok {
+			return nil, errors.New("claims missing")
+		}
+		s.Equal("user-1", claims.Subject)
+		return nil, nil
+	})
+	s.NoError(err)
+}
+
+func (s *GRPCServerTestSuite) TestForcedShutdown() {
+	l := bufconn.Listen(1 << 20)
+	srv, err := New(&Config{Addr: "bufconn", ShutdownTimeout: 50 * time.Millisecond}, s.log, WithListener(l))
+	s.Require().NoError(err)
+	healthpb.RegisterHealthServer(srv.GRPC(), health.NewServer())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+	defer conn.Close()
+
+	// A Watch stream stays open until the client ends it, so the graceful
+	// stop cannot finish.
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	s.Require().NoError(err)
+	_, err = stream.Recv()
+	s.Require().NoError(err)
+
+	cancel()
+	s.ErrorContains(<-done, "timed out")
+}
+
+func (s *GRPCServerTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Addr: ":9090"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing addr", cfg: &Config{}, expectError: true},
+		{name: "negative duration", cfg: &Config{Addr: ":9090", MinPingInterval: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestGRPCServerSuite(t *testing.T) {
+	suite.Run(t, new(GRPCServerTestSuite))
+}
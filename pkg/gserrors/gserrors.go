@@ -0,0 +1,158 @@
+// Package gserrors defines the error taxonomy shared by grid-stream
+// packages. An error carries a Code that maps to an HTTP status and a gRPC
+// code, so a failure raised deep in bqclient surfaces with the right status
+// at the API edge:
+//
+//	if errors.Is(err, bqclient.ErrNotFound) { ... }      // specific
+//	if gserrors.Is(err, gserrors.NotFound) { ... }       // by category
+//	http.Error(w, msg, gserrors.HTTPStatus(err))
+package gserrors
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code classifies an error.
+type Code string
+
+const (
+	// Internal is the code of errors that carry no other code.
+	Internal        Code = "internal"
+	NotFound        Code = "not_found"
+	InvalidInput    Code = "invalid_input"
+	Unavailable     Code = "unavailable"
+	Conflict        Code = "conflict"
+	Unauthenticated Code = "unauthenticated"
+	// PermissionDenied is for authenticated callers that may not perform
+	// the operation.
+	PermissionDenied Code = "permission_denied"
+)
+
+// Coder is implemented by errors that classify themselves. Packages whose
+// errors cannot embed *Error implement it to join the taxonomy.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Error is an error with a Code and a message that is safe to show to
+// callers. The cause, if any, is kept for logs and errors.Is.
+type Error struct {
+	Code    Code
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// GRPCStatus lets grpc-go convert the error when a handler returns it.
+// Internal errors hide their message.
+func (e *Error) GRPCStatus() *status.Status {
+	msg := e.Message
+	if e.Code == Internal || msg == "" {
+		msg = string(e.Code)
+	}
+	return status.New(GRPCCode(e), msg)
+}
+
+// New returns an error with code and message and a stack trace. It is also
+// used to declare sentinel errors matched with errors.Is.
+func New(code Code, msg string) error {
+	return errors.WithStack(&Error{Code: code, Message: msg})
+}
+
+func Newf(code Code, format string, args ...any) error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap classifies err with code and prefixes msg. The stack already
+// recorded on err is kept; one is added only if err has none. Wrap returns
+// nil if err is nil.
+func Wrap(err error, code Code, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return withStack(err, &Error{Code: code, Message: msg, Err: err})
+}
+
+func Wrapf(err error, code Code, format string, args ...any) error {
+	if err == nil {
+		return nil
+	}
+	return Wrap(err, code, fmt.Sprintf(format, args...))
+}
+
+// WithCode classifies err with code without changing its message.
+func WithCode(err error, code Code) error {
+	return Wrap(err, code, "")
+}
+
+type stackTracer interface {
+	StackTrace() errors.StackTrace
+}
+
+func withStack(cause error, err error) error {
+	var st stackTracer
+	if errors.As(cause, &st) {
+		return err
+	}
+	return errors.WithStack(err)
+}
+
+// CodeOf returns the code of the outermost classified error in err's chain.
+// Context errors are Unavailable, gRPC status errors are mapped from their
+// status code, and anything else is Internal. CodeOf(nil) is "".
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var c Coder
+	if errors.As(err, &c) {
+		return c.ErrorCode()
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return Unavailable
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		return fromGRPC(s.Code())
+	}
+	return Internal
+}
+
+// Is reports whether err is classified as code.
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
+
+// Message returns the caller-safe message of err: the message of the
+// outermost *Error, or the code itself for Internal errors and errors
+// without one.
+func Message(err error) string {
+	code := CodeOf(err)
+	var e *Error
+	if code != Internal && errors.As(err, &e) && e.Message != "" {
+		return e.Message
+	}
+	return string(code)
+}
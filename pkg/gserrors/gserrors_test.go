@@ -0,0 +1,99 @@
+package gserrors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type coded struct{}
+
+func (coded) Error() string   { return "coded" }
+func (coded) ErrorCode() Code { return Conflict }
+
+type GSErrorsTestSuite struct {
+	suite.Suite
+}
+
+func (s *GSErrorsTestSuite) TestCodeOf() {
+	sentinel := New(NotFound, "project not found")
+	testCases := []struct {
+		name string
+		err  error
+		code Code
+		http int
+		grpc codes.Code
+	}{
+		{name: "nil", err: nil, code: "", http: http.StatusOK, grpc: codes.OK},
+		{name: "plain", err: errors.New("boom"), code: Internal, http: http.StatusInternalServerError, grpc: codes.Internal},
+		{name: "sentinel", err: sentinel, code: NotFound, http: http.StatusNotFound, grpc: codes.NotFound},
+		{name: "wrapped sentinel", err: errors.Wrap(sentinel, "loading"), code: NotFound, http: http.StatusNotFound, grpc: codes.NotFound},
+		{name: "reclassified", err: Wrap(sentinel, InvalidInput, "bad reference"), code: InvalidInput, http: http.StatusBadRequest, grpc: codes.InvalidArgument},
+		{name: "coder", err: errors.Wrap(coded{}, "saving"), code: Conflict, http: http.StatusConflict, grpc: codes.AlreadyExists},
+		{name: "context", err: errors.WithStack(context.DeadlineExceeded), code: Unavailable, http: http.StatusServiceUnavailable, grpc: codes.Unavailable},
+		{name: "grpc status", err: status.Error(codes.Unauthenticated, "no token"), code: Unauthenticated, http: http.StatusUnauthorized, grpc: codes.Unauthenticated},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "not an operator"), code: PermissionDenied, http: http.StatusForbidden, grpc: codes.PermissionDenied},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.code, CodeOf(tc.err))
+			s.Equal(tc.http, HTTPStatus(tc.err))
+			s.Equal(tc.grpc, GRPCCode(tc.err))
+		})
+	}
+}
+
+func (s *GSErrorsTestSuite) TestWrap() {
+	s.Nil(Wrap(nil, NotFound, "x"))
+	s.Nil(WithCode(nil, NotFound))
+
+	cause := errors.New("no rows")
+	err := Wrapf(cause, NotFound, "project %s", "p1")
+	s.EqualError(err, "project p1: no rows")
+	s.ErrorIs(err, cause)
+	s.True(Is(err, NotFound))
+	s.Equal("project p1", Message(err))
+
+	// The cause's stack is kept rather than a new one being recorded.
+	var st stackTracer
+	s.Require().True(errors.As(err, &st))
+	s.Equal(cause.(stackTracer).StackTrace(), st.StackTrace())
+
+	err = WithCode(errors.New("timeout"), Unavailable)
+	s.EqualError(err, "timeout")
+	s.Equal("unavailable", Message(err))
+
+	s.Equal("internal", Message(Wrap(cause, Internal, "secret details")))
+}
+
+func (s *GSErrorsTestSuite) TestGRPCStatus() {
+	st, ok := status.FromError(errors.Wrap(New(Conflict, "already running"), "starting"))
+	s.Require().True(ok)
+	s.Equal(codes.AlreadyExists, st.Code())
+
+	st = status.Convert(GRPCStatus(errors.New("db password wrong")))
+	s.Equal(codes.Internal, st.Code())
+	s.Equal("internal", st.Message())
+}
+
+func (s *GSErrorsTestSuite) TestWriteHTTP() {
+	rec := httptest.NewRecorder()
+	WriteHTTP(rec, Wrap(errors.New("no rows"), NotFound, "project not found"))
+
+	s.Equal(http.StatusNotFound, rec.Code)
+	var body Body
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&body))
+	s.Equal(Body{Code: NotFound, Message: "project not found"}, body)
+}
+
+func TestGSErrorsTestSuite(t *testing.T) {
+	suite.Run(t, new(GSErrorsTestSuite))
+}
@@ -0,0 +1,92 @@
+package gserrors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var httpStatus = map[Code]int{
+	NotFound:         http.StatusNotFound,
+	InvalidInput:     http.StatusBadRequest,
+	Unavailable:      http.StatusServiceUnavailable,
+	Conflict:         http.StatusConflict,
+	Unauthenticated:  http.StatusUnauthorized,
+	PermissionDenied: http.StatusForbidden,
+	Internal:         http.StatusInternalServerError,
+}
+
+var grpcCode = map[Code]codes.Code{
+	NotFound:         codes.NotFound,
+	InvalidInput:     codes.InvalidArgument,
+	Unavailable:      codes.Unavailable,
+	Conflict:         codes.AlreadyExists,
+	Unauthenticated:  codes.Unauthenticated,
+	PermissionDenied: codes.PermissionDenied,
+	Internal:         codes.Internal,
+}
+
+// HTTPStatus maps err's code to an HTTP status. It returns 200 for nil.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if s, ok := httpStatus[CodeOf(err)]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode maps err's code to a gRPC code. It returns codes.OK for nil.
+func GRPCCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	if c, ok := grpcCode[CodeOf(err)]; ok {
+		return c
+	}
+	return codes.Internal
+}
+
+// GRPCStatus converts err to a gRPC status error carrying its caller-safe
+// message, for handlers returning errors that are not *Error.
+func GRPCStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(GRPCCode(err), Message(err))
+}
+
+func fromGRPC(c codes.Code) Code {
+	switch c {
+	case codes.NotFound:
+		return NotFound
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return InvalidInput
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.ResourceExhausted, codes.Aborted:
+		return Unavailable
+	case codes.AlreadyExists:
+		return Conflict
+	case codes.Unauthenticated:
+		return Unauthenticated
+	case codes.PermissionDenied:
+		return PermissionDenied
+	default:
+		return Internal
+	}
+}
+
+// Body is the JSON error body written by WriteHTTP.
+type Body struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message"`
+}
+
+// WriteHTTP writes err as a JSON Body with the mapped status.
+func WriteHTTP(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatus(err))
+	_ = json.NewEncoder(w).Encode(Body{Code: CodeOf(err), Message: Message(err)})
+}
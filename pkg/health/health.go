@@ -0,0 +1,212 @@
+// Package health serves Kubernetes liveness and readiness probes backed by
+// named checks registered by each component.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+
+	LivezPath  = "/livez"
+	ReadyzPath = "/readyz"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+var errShuttingDown = errors.New("shutting down")
+
+// Check reports whether a dependency is healthy by returning nil.
+type Check func(ctx context.Context) error
+
+// CheckResult is the outcome of a single check.
+type CheckResult struct {
+	Status   string        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the JSON body served by the probe endpoints.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type Option func(*Health)
+
+// WithTimeout bounds how long each check may run.
+func WithTimeout(d time.Duration) Option {
+	return func(h *Health) {
+		h.timeout = d
+	}
+}
+
+// Health holds the registered liveness and readiness checks.
+type Health struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	liveness     map[string]Check
+	readiness    map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func New(opts ...Option) *Health {
+	h := &Health{
+		timeout:   DefaultTimeout,
+		liveness:  map[string]Check{},
+		readiness: map[string]Check{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// AddLivenessCheck registers a check that, when failing, should cause the
+// process to be restarted. Keep these cheap and free of external
+// dependencies.
+func (h *Health) AddLivenessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness[name] = check
+}
+
+// AddReadinessCheck registers a check that, when failing, should take the
+// instance out of load balancing, such as BigQuery connectivity or a
+// validator ping.
+func (h *Health) AddReadinessCheck(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness[name] = check
+}
+
+// MarkShuttingDown makes the readiness probe fail so load balancers stop
+// routing new traffic while in-flight requests drain.
+func (h *Health) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Liveness runs every liveness check.
+func (h *Health) Liveness(ctx context.Context) Report {
+	return h.run(ctx, h.snapshot(h.liveness))
+}
+
+// Readiness runs every readiness check.
+func (h *Health) Readiness(ctx context.Context) Report {
+	checks := h.snapshot(h.readiness)
+	if h.shuttingDown.Load() {
+		checks["shutdown"] = func(context.Context) error { return errShuttingDown }
+	}
+	return h.run(ctx, checks)
+}
+
+// Handler serves LivezPath and ReadyzPath.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(LivezPath, h.LivenessHandler())
+	mux.Handle(ReadyzPath, h.ReadinessHandler())
+	return mux
+}
+
+func (h *Health) LivenessHandler() http.Handler {
+	return reportHandler(h.Liveness)
+}
+
+func (h *Health) ReadinessHandler() http.Handler {
+	return reportHandler(h.Readiness)
+}
+
+func reportHandler(run func(context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := run(r.Context())
+
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (h *Health) snapshot(checks map[string]Check) map[string]Check {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	out := make(map[string]Check, len(checks))
+	for name, c := range checks {
+		out[name] = c
+	}
+	return out
+}
+
+func (h *Health) run(ctx context.Context, checks map[string]Check) Report {
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			result := h.runCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (h *Health) runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = errors.Wrap(ctx.Err(), "check did not complete")
+	}
+
+	result := CheckResult{Status: StatusOK, Duration: time.Since(start)}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthTestSuite struct {
+	suite.Suite
+}
+
+func ok(context.Context) error { return nil }
+
+func (s *HealthTestSuite) get(h http.Handler, path string) (int, Report) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report Report
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &report))
+	s.Equal("application/json", rec.Header().Get("Content-Type"))
+	return rec.Code, report
+}
+
+func (s *HealthTestSuite) TestHandler() {
+	testCases := []struct {
+		name       string
+		setup      func(h *Health)
+		path       string
+		wantCode   int
+		wantStatus map[string]string
+	}{
+		{
+			name:       "no checks",
+			setup:      func(h *Health) {},
+			path:       ReadyzPath,
+			wantCode:   http.StatusOK,
+			wantStatus: map[string]string{},
+		},
+		{
+			name: "all passing",
+			setup: func(h *Health) {
+				h.AddReadinessCheck("bigquery", ok)
+				h.AddReadinessCheck("validator", ok)
+			},
+			path:       ReadyzPath,
+			wantCode:   http.StatusOK,
+			wantStatus: map[string]string{"bigquery": StatusOK, "validator": StatusOK},
+		},
+		{
+			name: "one failing",
+			setup: func(h *Health) {
+				h.AddReadinessCheck("bigquery", ok)
+				h.AddReadinessCheck("validator", func(context.Context) error { return errors.New("unreachable") })
+			},
+			path:       ReadyzPath,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"bigquery": StatusOK, "validator": StatusFail},
+		},
+		{
+			name: "liveness ignores readiness checks",
+			setup: func(h *Health) {
+				h.AddLivenessCheck("goroutines", ok)
+				h.AddReadinessCheck("validator", func(context.Context) error { return errors.New("unreachable") })
+			},
+			path:       LivezPath,
+			wantCode:   http.StatusOK,
+			wantStatus: map[string]string{"goroutines": StatusOK},
+		},
+		{
+			name: "shutting down",
+			setup: func(h *Health) {
+				h.AddReadinessCheck("bigquery", ok)
+				h.MarkShuttingDown()
+			},
+			path:       ReadyzPath,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"bigquery": StatusOK, "shutdown": StatusFail},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			h := New()
+			tc.setup(h)
+
+			code, report := s.get(h.Handler(), tc.path)
+			s.Equal(tc.wantCode, code)
+
+			got := map[string]string{}
+			for name, result := range report.Checks {
+				got[name] = result.Status
+			}
+			s.Equal(tc.wantStatus, got)
+		})
+	}
+}
+
+func (s *HealthTestSuite) TestTimeoutAndPanic() {
+	h := New(WithTimeout(20 * time.Millisecond))
+	h.AddReadinessCheck("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	h.AddReadinessCheck("panics", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	start := time.Now()
+	report := h.Readiness(context.Background())
+	s.Less(time.Since(start), 500*time.Millisecond)
+
+	s.Equal(StatusFail, report.Status)
+	s.Contains(report.Checks["slow"].Error, "check did not complete")
+	s.Contains(report.Checks["panics"].Error, "check panicked: boom")
+}
+
+func TestHealthSuite(t *testing.T) {
+	suite.Run(t, new(HealthTestSuite))
+}
@@ -0,0 +1,147 @@
+// Package heartbeat lets long-running components report liveness and lets a
+// watcher flag the ones that have gone quiet.
+//
+// Each component runs a Reporter that sends a Beat to a Sink every interval.
+// Beats go either to a BigQuery table, which a Watcher polls, or to a
+// Pub/Sub topic, whose subscription a Watcher consumes. A component that
+// shuts down cleanly sends a final StatusStopped beat so it is not reported
+// as stale.
+package heartbeat
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/buildinfo"
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/pkg/errors"
+)
+
+const (
+	StatusOK      = health.StatusOK
+	StatusFail    = health.StatusFail
+	StatusStopped = "stopped"
+
+	DefaultInterval = 30 * time.Second
+)
+
+// Beat is a single liveness report.
+type Beat struct {
+	Component string    `bigquery:"component" json:"component"`
+	Instance  string    `bigquery:"instance" json:"instance"`
+	Status    string    `bigquery:"status" json:"status"`
+	Version   string    `bigquery:"version" json:"version"`
+	SentAt    time.Time `bigquery:"sent_at" json:"sent_at"`
+}
+
+// Key identifies the reporting instance.
+func (b Beat) Key() string {
+	return b.Component + "/" + b.Instance
+}
+
+// Sink delivers beats.
+type Sink interface {
+	Report(ctx context.Context, beat Beat) error
+}
+
+type Config struct {
+	Component string        `koanf:"component" json:"component" envconfig:"component"`
+	Instance  string        `koanf:"instance" json:"instance" envconfig:"instance"`
+	Interval  time.Duration `koanf:"interval" json:"interval" envconfig:"interval"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("heartbeat configuration required")
+	}
+	if c.Component == "" {
+		return errors.New("heartbeat component required")
+	}
+	if c.Interval < 0 {
+		return errors.New("heartbeat interval must not be negative")
+	}
+	return nil
+}
+
+type Option func(*Reporter)
+
+// WithHealth derives each beat's status from h's liveness checks.
+func WithHealth(h *health.Health) Option {
+	return func(r *Reporter) {
+		r.health = h
+	}
+}
+
+// Reporter sends beats for one component instance.
+type Reporter struct {
+	cfg    Config
+	sink   Sink
+	log    *slog.Logger
+	health *health.Health
+}
+
+// New creates a reporter. Instance defaults to the hostname, which is the
+// pod name on Kubernetes.
+func New(cfg *Config, sink Sink, log *slog.Logger, opts ...Option) (*Reporter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Reporter{cfg: *cfg, sink: sink, log: log}
+	if r.cfg.Interval == 0 {
+		r.cfg.Interval = DefaultInterval
+	}
+	if r.cfg.Instance == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, errors.Wrap(err, "heartbeat instance required")
+		}
+		r.cfg.Instance = host
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Run sends a beat immediately and then every interval until ctx is
+// cancelled, when it sends a final StatusStopped beat. Failed sends are
+// logged and do not stop the reporter.
+func (r *Reporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.send(ctx, r.status(ctx))
+		select {
+		case <-ctx.Done():
+			stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.cfg.Interval)
+			defer cancel()
+			r.send(stopCtx, StatusStopped)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reporter) status(ctx context.Context) string {
+	if r.health == nil {
+		return StatusOK
+	}
+	return r.health.Liveness(ctx).Status
+}
+
+func (r *Reporter) send(ctx context.Context, status string) {
+	beat := Beat{
+		Component: r.cfg.Component,
+		Instance:  r.cfg.Instance,
+		Status:    status,
+		Version:   buildinfo.Get().Version,
+		SentAt:    time.Now().UTC(),
+	}
+	if err := r.sink.Report(ctx, beat); err != nil && ctx.Err() == nil {
+		r.log.Warn("sending heartbeat", "component", beat.Component, "error", err)
+	}
+}
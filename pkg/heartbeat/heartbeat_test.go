@@ -0,0 +1,180 @@
+package heartbeat
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeSink struct {
+	mu    sync.Mutex
+	beats []Beat
+}
+
+func (f *fakeSink) Report(_ context.Context, beat Beat) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.beats = append(f.beats, beat)
+	return nil
+}
+
+func (f *fakeSink) all() []Beat {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Beat(nil), f.beats...)
+}
+
+type fakeSource []Beat
+
+func (f fakeSource) Latest(context.Context, time.Time) ([]Beat, error) {
+	return f, nil
+}
+
+// fakePubSub delivers published messages straight to the subscribed handler.
+type fakePubSub struct {
+	pubsub.PubSubClient
+	handler pubsub.Handler
+}
+
+func (f *fakePubSub) Publish(ctx context.Context, _ string, msg *pubsub.Message) (string, error) {
+	return "1", f.handler(ctx, msg)
+}
+
+func (f *fakePubSub) Subscribe(_ context.Context, _ string, handler pubsub.Handler) error {
+	f.handler = handler
+	return nil
+}
+
+type HeartbeatTestSuite struct {
+	suite.Suite
+}
+
+func (s *HeartbeatTestSuite) watcher(opts ...WatcherOption) *Watcher {
+	w, err := NewWatcher(&WatcherConfig{StaleAfter: time.Minute, Forget: time.Hour}, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return w
+}
+
+func (s *HeartbeatTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "valid", cfg: &Config{Component: "aggregator"}, expectError: false},
+		{name: "missing component", cfg: &Config{}, expectError: true},
+		{name: "negative interval", cfg: &Config{Component: "aggregator", Interval: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *HeartbeatTestSuite) TestReporter() {
+	sink := &fakeSink{}
+	h := health.New()
+	h.AddLivenessCheck("mqtt", func(context.Context) error { return errors.New("disconnected") })
+	r, err := New(&Config{Component: "aggregator", Instance: "edge-1", Interval: 10 * time.Millisecond}, sink, logger.Default(), WithHealth(h))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 35*time.Millisecond)
+	defer cancel()
+	s.NoError(r.Run(ctx))
+
+	beats := sink.all()
+	s.Require().GreaterOrEqual(len(beats), 3)
+	for _, beat := range beats[:len(beats)-1] {
+		s.Equal("aggregator", beat.Component)
+		s.Equal("edge-1", beat.Instance)
+		s.Equal(StatusFail, beat.Status)
+	}
+	s.Equal(StatusStopped, beats[len(beats)-1].Status)
+}
+
+func (s *HeartbeatTestSuite) TestWatcher() {
+	now := time.Now()
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "heartbeat"})
+	s.Require().NoError(err)
+	w := s.watcher(WithWatcherMetrics(m), WithSource(fakeSource{
+		{Component: "aggregator", Instance: "edge-1", Status: StatusOK, SentAt: now.Add(-10 * time.Second)},
+		{Component: "aggregator", Instance: "edge-2", Status: StatusOK, SentAt: now.Add(-5 * time.Minute)},
+		{Component: "aggregator", Instance: "edge-3", Status: StatusStopped, SentAt: now.Add(-5 * time.Minute)},
+		{Component: "validator", Instance: "v-1", Status: StatusOK, SentAt: now.Add(-2 * time.Hour)},
+	}))
+
+	var changes []Instance
+	w.OnChange(func(inst Instance) { changes = append(changes, inst) })
+
+	s.Require().NoError(w.Check(context.Background(), now))
+	s.Len(w.Instances(), 3, "validator was forgotten")
+	stale := w.Stale()
+	s.Require().Len(stale, 1)
+	s.Equal("edge-2", stale[0].Last.Instance)
+	s.Equal(float64(1), testutil.ToFloat64(w.gauge.WithLabelValues("aggregator")))
+
+	// Polling returns the same beats again; nothing flaps.
+	s.Require().NoError(w.Check(context.Background(), now))
+	s.Len(changes, 1)
+
+	w.Observe(Beat{Component: "aggregator", Instance: "edge-2", Status: StatusOK, SentAt: now})
+	s.Empty(w.Stale())
+	s.Require().Len(changes, 2)
+	s.False(changes[1].Stale)
+}
+
+type recordingSource struct {
+	since []time.Time
+}
+
+func (r *recordingSource) Latest(_ context.Context, since time.Time) ([]Beat, error) {
+	r.since = append(r.since, since)
+	return nil, nil
+}
+
+func (s *HeartbeatTestSuite) TestWatcherNarrowsPolls() {
+	src := &recordingSource{}
+	w := s.watcher(WithSource(src))
+	now := time.Now()
+
+	s.Require().NoError(w.Check(context.Background(), now))
+	s.Require().NoError(w.Check(context.Background(), now.Add(time.Minute)))
+	s.Require().Len(src.since, 2)
+	s.Equal(now.Add(-w.lookback()), src.since[0])
+	s.Equal(now.Add(-w.cfg.StaleAfter), src.since[1])
+}
+
+func (s *HeartbeatTestSuite) TestPubSub() {
+	client := &fakePubSub{}
+	w := s.watcher()
+	s.Require().NoError(w.Subscribe(context.Background(), client, "heartbeats"))
+
+	sent := Beat{Component: "aggregator", Instance: "edge-1", Status: StatusOK, SentAt: time.Now().UTC().Truncate(time.Millisecond)}
+	s.Require().NoError(PubSub(client, "heartbeats").Report(context.Background(), sent))
+
+	instances := w.Instances()
+	s.Require().Len(instances, 1)
+	s.True(sent.SentAt.Equal(instances[0].Last.SentAt))
+	s.Equal(sent.Key(), instances[0].Last.Key())
+}
+
+func TestHeartbeatTestSuite(t *testing.T) {
+	suite.Run(t, new(HeartbeatTestSuite))
+}
@@ -0,0 +1,119 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+const DefaultTable = "heartbeats"
+
+type BigQueryConfig struct {
+	// Table must be allowed by the client, for example with
+	// bqclient.RegisterTable. Defaults to DefaultTable.
+	Table string `koanf:"table" json:"table" envconfig:"table"`
+}
+
+func (c *BigQueryConfig) Validate() error {
+	if c == nil {
+		return errors.New("heartbeat bigquery configuration required")
+	}
+	return nil
+}
+
+// BigQuery streams beats into a table and reads back the latest beat per
+// instance, so it serves as both a Sink and a Watcher Source. Streaming
+// avoids a DML job per beat, which BigQuery would soon rate limit.
+type BigQuery struct {
+	client    bqclient.BQClient
+	table     string
+	tableName string
+}
+
+func NewBigQuery(client bqclient.BQClient, cfg *BigQueryConfig) (*BigQuery, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	name := cfg.Table
+	if name == "" {
+		name = DefaultTable
+	}
+	table, err := client.TableName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &BigQuery{client: client, table: table, tableName: name}, nil
+}
+
+// CreateTable creates the heartbeat table if it does not exist.
+func (b *BigQuery) CreateTable(ctx context.Context) error {
+	return bqclient.Exec(ctx, b.client, `CREATE TABLE IF NOT EXISTS `+b.table+` (
+        component STRING NOT NULL,
+        instance STRING NOT NULL,
+        status STRING NOT NULL,
+        version STRING,
+        sent_at TIMESTAMP NOT NULL
+    )
+    PARTITION BY DATE(sent_at)
+    CLUSTER BY component, instance`, nil)
+}
+
+func (b *BigQuery) Report(ctx context.Context, beat Beat) error {
+	return errors.Wrap(b.client.StreamPut(ctx, b.tableName, &beat), "writing heartbeat")
+}
+
+// Latest returns the most recent beat of every instance heard from since.
+// Only the partitions from since on are scanned.
+func (b *BigQuery) Latest(ctx context.Context, since time.Time) ([]Beat, error) {
+	it, err := b.client.Query(ctx, `
+        SELECT component, instance, status, version, sent_at
+        FROM `+b.table+`
+        WHERE sent_at >= @since
+        QUALIFY ROW_NUMBER() OVER (PARTITION BY component, instance ORDER BY sent_at DESC) = 1`,
+		[]bigquery.QueryParameter{{Name: "since", Value: since}})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading heartbeats")
+	}
+
+	var beats []Beat
+	for {
+		var beat Beat
+		err := it.Next(&beat)
+		if err == iterator.Done {
+			return beats, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading heartbeats")
+		}
+		beats = append(beats, beat)
+	}
+}
+
+type pubSubSink struct {
+	client pubsub.PubSubClient
+	topic  string
+}
+
+// PubSub publishes beats to topic as JSON, ordered per instance.
+func PubSub(client pubsub.PubSubClient, topic string) Sink {
+	return &pubSubSink{client: client, topic: topic}
+}
+
+func (s *pubSubSink) Report(ctx context.Context, beat Beat) error {
+	data, err := json.Marshal(beat)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	_, err = s.client.Publish(ctx, s.topic, &pubsub.Message{
+		Data:        data,
+		Attributes:  map[string]string{"component": beat.Component},
+		OrderingKey: beat.Key(),
+	})
+	return err
+}
@@ -0,0 +1,279 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultStaleAfter    = 2 * time.Minute
+	DefaultCheckInterval = 30 * time.Second
+)
+
+// Source returns the latest beat of every instance heard from since a time.
+// BigQuery implements it.
+type Source interface {
+	Latest(ctx context.Context, since time.Time) ([]Beat, error)
+}
+
+type WatcherConfig struct {
+	// StaleAfter is how long an instance may go without a beat before it is
+	// flagged. It should be a few reporter intervals.
+	StaleAfter    time.Duration `koanf:"stale_after" json:"stale_after" envconfig:"stale_after"`
+	CheckInterval time.Duration `koanf:"check_interval" json:"check_interval" envconfig:"check_interval"`
+	// Forget drops instances that have not reported for this long.
+	// Zero keeps them forever.
+	Forget time.Duration `koanf:"forget" json:"forget" envconfig:"forget"`
+}
+
+func (c *WatcherConfig) Validate() error {
+	if c == nil {
+		return errors.New("heartbeat watcher configuration required")
+	}
+	if c.StaleAfter < 0 || c.CheckInterval < 0 || c.Forget < 0 {
+		return errors.New("heartbeat watcher durations must not be negative")
+	}
+	return nil
+}
+
+// Instance is the watcher's view of one reporting instance.
+type Instance struct {
+	Last  Beat `json:"last"`
+	Stale bool `json:"stale"`
+}
+
+type WatcherOption func(*Watcher)
+
+// WithSource polls src for beats on every check.
+func WithSource(src Source) WatcherOption {
+	return func(w *Watcher) {
+		w.source = src
+	}
+}
+
+// WithWatcherMetrics exports a gauge of stale instances per component.
+func WithWatcherMetrics(m *metrics.Metrics) WatcherOption {
+	return func(w *Watcher) {
+		g, err := m.NewGaugeVec("heartbeat", "stale_instances", "Number of instances whose heartbeat is overdue.", "component")
+		if err != nil {
+			w.log.Warn("registering heartbeat metrics", "error", err)
+			return
+		}
+		w.gauge = g
+	}
+}
+
+// Watcher tracks the latest beat of each instance and flags the ones that
+// have not reported within StaleAfter.
+type Watcher struct {
+	cfg      WatcherConfig
+	log      *slog.Logger
+	source   Source
+	gauge    *prometheus.GaugeVec
+	mu       sync.Mutex
+	seen     map[string]*Instance
+	onChange []func(Instance)
+	// polled is when the source was last read successfully.
+	polled time.Time
+}
+
+func NewWatcher(cfg *WatcherConfig, log *slog.Logger, opts ...WatcherOption) (*Watcher, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{cfg: *cfg, log: log, seen: map[string]*Instance{}}
+	if w.cfg.StaleAfter == 0 {
+		w.cfg.StaleAfter = DefaultStaleAfter
+	}
+	if w.cfg.CheckInterval == 0 {
+		w.cfg.CheckInterval = DefaultCheckInterval
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w, nil
+}
+
+// OnChange registers fn to be called when an instance becomes stale or
+// recovers. Callbacks run on the goroutine that noticed the change.
+func (w *Watcher) OnChange(fn func(Instance)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// Observe records a beat. Beats no newer than the one already held for the
+// instance are ignored, so a polled source can return the same beat
+// repeatedly.
+func (w *Watcher) Observe(beat Beat) {
+	w.mu.Lock()
+	inst, ok := w.seen[beat.Key()]
+	if ok && !beat.SentAt.After(inst.Last.SentAt) {
+		w.mu.Unlock()
+		return
+	}
+	if !ok {
+		inst = &Instance{}
+		w.seen[beat.Key()] = inst
+	}
+	inst.Last = beat
+	recovered := inst.Stale
+	inst.Stale = false
+	changed, callbacks := *inst, slices.Clone(w.onChange)
+	w.mu.Unlock()
+
+	if recovered {
+		w.log.Info("component heartbeat recovered", "component", beat.Component, "instance", beat.Instance)
+		for _, fn := range callbacks {
+			fn(changed)
+		}
+	}
+}
+
+// Instances returns every known instance, sorted by component and instance.
+func (w *Watcher) Instances() []Instance {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	out := make([]Instance, 0, len(w.seen))
+	for _, inst := range w.seen {
+		out = append(out, *inst)
+	}
+	slices.SortFunc(out, func(a, b Instance) int {
+		return strings.Compare(a.Last.Key(), b.Last.Key())
+	})
+	return out
+}
+
+// Stale returns the instances currently flagged as stale.
+func (w *Watcher) Stale() []Instance {
+	var stale []Instance
+	for _, inst := range w.Instances() {
+		if inst.Stale {
+			stale = append(stale, inst)
+		}
+	}
+	return stale
+}
+
+// Check polls the source, if any, and re-evaluates staleness as of now.
+// The first poll reads back as far as instances are kept; later ones only
+// read beats sent since the previous poll, less StaleAfter for beats that
+// arrive late.
+func (w *Watcher) Check(ctx context.Context, now time.Time) error {
+	if w.source != nil {
+		w.mu.Lock()
+		since := now.Add(-w.lookback())
+		if !w.polled.IsZero() {
+			since = later(since, w.polled.Add(-w.cfg.StaleAfter))
+		}
+		w.mu.Unlock()
+
+		beats, err := w.source.Latest(ctx, since)
+		if err != nil {
+			return err
+		}
+		for _, beat := range beats {
+			w.Observe(beat)
+		}
+		w.mu.Lock()
+		w.polled = later(w.polled, now)
+		w.mu.Unlock()
+	}
+
+	w.mu.Lock()
+	var changed []Instance
+	counts := map[string]float64{}
+	for key, inst := range w.seen {
+		age := now.Sub(inst.Last.SentAt)
+		if w.cfg.Forget > 0 && age > w.cfg.Forget {
+			delete(w.seen, key)
+			continue
+		}
+		if _, ok := counts[inst.Last.Component]; !ok {
+			counts[inst.Last.Component] = 0
+		}
+		stale := inst.Last.Status != StatusStopped && age > w.cfg.StaleAfter
+		if stale {
+			counts[inst.Last.Component]++
+		}
+		if stale != inst.Stale {
+			inst.Stale = stale
+			changed = append(changed, *inst)
+		}
+	}
+	callbacks := slices.Clone(w.onChange)
+	w.mu.Unlock()
+
+	if w.gauge != nil {
+		w.gauge.Reset()
+		for component, n := range counts {
+			w.gauge.WithLabelValues(component).Set(n)
+		}
+	}
+	for _, inst := range changed {
+		w.log.Warn("component heartbeat stale", "component", inst.Last.Component, "instance", inst.Last.Instance, "last_seen", inst.Last.SentAt)
+		for _, fn := range callbacks {
+			fn(inst)
+		}
+	}
+	return nil
+}
+
+// lookback bounds how far back the source is queried: far enough to keep
+// seeing stale instances until they are forgotten.
+func (w *Watcher) lookback() time.Duration {
+	if w.cfg.Forget > 0 {
+		return w.cfg.Forget
+	}
+	return 24 * time.Hour
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// Run checks every CheckInterval until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Check(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			w.log.Warn("checking heartbeats", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Subscribe feeds beats published by the PubSub sink into the watcher until
+// ctx is cancelled. Run must still be called to flag stale instances.
+func (w *Watcher) Subscribe(ctx context.Context, client pubsub.PubSubClient, subscription string) error {
+	return client.Subscribe(ctx, subscription, func(ctx context.Context, msg *pubsub.Message) error {
+		var beat Beat
+		if err := json.Unmarshal(msg.Data, &beat); err != nil {
+			return retry.Permanent(errors.Wrap(err, "decoding heartbeat"))
+		}
+		w.Observe(beat)
+		return nil
+	})
+}
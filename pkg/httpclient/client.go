@@ -0,0 +1,154 @@
+// Package httpclient builds *http.Client values for calling partner and
+// utility REST APIs with consistent timeouts, retries, circuit breaking,
+// logging and authentication.
+package httpclient
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/breaker"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTimeout               = 30 * time.Second
+	DefaultDialTimeout           = 5 * time.Second
+	DefaultTLSHandshakeTimeout   = 5 * time.Second
+	DefaultResponseHeaderTimeout = 15 * time.Second
+	DefaultIdleConnTimeout       = 90 * time.Second
+	DefaultMaxIdleConnsPerHost   = 10
+)
+
+type Config struct {
+	Timeout               time.Duration     `koanf:"timeout" json:"timeout" envconfig:"timeout"`
+	DialTimeout           time.Duration     `koanf:"dial_timeout" json:"dial_timeout" envconfig:"dial_timeout"`
+	TLSHandshakeTimeout   time.Duration     `koanf:"tls_handshake_timeout" json:"tls_handshake_timeout" envconfig:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration     `koanf:"response_header_timeout" json:"response_header_timeout" envconfig:"response_header_timeout"`
+	IdleConnTimeout       time.Duration     `koanf:"idle_conn_timeout" json:"idle_conn_timeout" envconfig:"idle_conn_timeout"`
+	MaxIdleConnsPerHost   int               `koanf:"max_idle_conns_per_host" json:"max_idle_conns_per_host" envconfig:"max_idle_conns_per_host"`
+	Retry                 retry.Config      `koanf:"retry" json:"retry" envconfig:"retry"`
+	Breaker               *breaker.Config   `koanf:"breaker" json:"breaker" envconfig:"breaker"`
+	TLS                   *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+}
+
+type Option func(*options)
+
+type options struct {
+	tokens auth.TokenManager
+	base   http.RoundTripper
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("http client configuration required")
+	}
+	if c.Timeout < 0 || c.DialTimeout < 0 || c.TLSHandshakeTimeout < 0 || c.ResponseHeaderTimeout < 0 || c.IdleConnTimeout < 0 {
+		return errors.New("http client timeouts must not be negative")
+	}
+	if c.MaxIdleConnsPerHost < 0 {
+		return errors.New("max idle conns per host must not be negative")
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return err
+	}
+	if c.Breaker != nil {
+		if err := c.Breaker.Validate(); err != nil {
+			return err
+		}
+	}
+	return c.TLS.Validate()
+}
+
+// WithTokenManager authenticates every request with a bearer token from tm
+// using auth.Transport.
+func WithTokenManager(tm auth.TokenManager) Option {
+	return func(o *options) {
+		o.tokens = tm
+	}
+}
+
+// WithBaseTransport replaces the transport built from Config, for tests or
+// custom dialing.
+func WithBaseTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.base = rt
+	}
+}
+
+// New returns a client whose transport, from the outside in, logs each
+// request, short-circuits hosts whose breaker is open, retries idempotent
+// requests, and adds authentication.
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*http.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rt := o.base
+	if rt == nil {
+		t, err := newTransport(cfg)
+		if err != nil {
+			return nil, err
+		}
+		rt = t
+	}
+
+	if o.tokens != nil {
+		rt = &auth.Transport{Tokens: o.tokens, Base: rt}
+	}
+	rt = &retryTransport{base: rt, opts: cfg.Retry.Options(), log: log}
+	if cfg.Breaker != nil {
+		rt = &breakerTransport{base: rt, breakers: breaker.NewRegistry(cfg.Breaker, breaker.WithOnStateChange(func(name string, from, to breaker.State) {
+			log.Warn("http client circuit breaker state changed", "host", name, "from", from.String(), "to", to.String())
+		}))}
+	}
+	rt = &loggingTransport{base: rt, log: log}
+
+	return &http.Client{
+		Transport: rt,
+		Timeout:   orDefault(cfg.Timeout, DefaultTimeout),
+	}, nil
+}
+
+func newTransport(cfg *Config) (*http.Transport, error) {
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	maxIdle := cfg.MaxIdleConnsPerHost
+	if maxIdle == 0 {
+		maxIdle = DefaultMaxIdleConnsPerHost
+	}
+
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   orDefault(cfg.DialTimeout, DefaultDialTimeout),
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   orDefault(cfg.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: orDefault(cfg.ResponseHeaderTimeout, DefaultResponseHeaderTimeout),
+		IdleConnTimeout:       orDefault(cfg.IdleConnTimeout, DefaultIdleConnTimeout),
+		MaxIdleConnsPerHost:   maxIdle,
+		ForceAttemptHTTP2:     true,
+	}, nil
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,192 @@
+package httpclient
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/breaker"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeTokenManager struct {
+	refreshed atomic.Bool
+}
+
+func (m *fakeTokenManager) GetToken() (string, error) {
+	if m.refreshed.Load() {
+		return "fresh", nil
+	}
+	return "stale", nil
+}
+
+func (m *fakeTokenManager) Refresh() (string, error) {
+	m.refreshed.Store(true)
+	return "fresh", nil
+}
+
+type HTTPClientTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	cfg *Config
+}
+
+func (s *HTTPClientTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.cfg = &Config{Retry: retry.Config{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond}}
+}
+
+func (s *HTTPClientTestSuite) TestRetry() {
+	testCases := []struct {
+		name          string
+		method        string
+		idempotentKey bool
+		failures      int32
+		wantStatus    int
+		wantCalls     int32
+	}{
+		{name: "get recovers", method: http.MethodGet, failures: 2, wantStatus: http.StatusOK, wantCalls: 3},
+		{name: "get exhausts retries", method: http.MethodGet, failures: 5, wantStatus: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "post not retried", method: http.MethodPost, failures: 1, wantStatus: http.StatusServiceUnavailable, wantCalls: 1},
+		{name: "post with idempotency key", method: http.MethodPost, idempotentKey: true, failures: 1, wantStatus: http.StatusOK, wantCalls: 2},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if calls.Add(1) <= tc.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, _ = w.Write(body)
+			}))
+			defer srv.Close()
+
+			client, err := New(s.cfg, s.log)
+			s.Require().NoError(err)
+
+			req, err := http.NewRequest(tc.method, srv.URL, strings.NewReader("payload"))
+			s.Require().NoError(err)
+			if tc.idempotentKey {
+				req.Header.Set(IdempotencyKeyHeader, "abc")
+			}
+
+			resp, err := client.Do(req)
+			s.Require().NoError(err)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			s.Equal(tc.wantStatus, resp.StatusCode)
+			s.Equal(tc.wantCalls, calls.Load())
+			if resp.StatusCode == http.StatusOK {
+				s.Equal("payload", string(body))
+			}
+		})
+	}
+}
+
+func (s *HTTPClientTestSuite) TestRetryNoBody() {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := New(s.cfg, s.log)
+	s.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, http.NoBody)
+	s.Require().NoError(err)
+	s.Require().Nil(req.GetBody)
+
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(2), calls.Load())
+}
+
+func (s *HTTPClientTestSuite) TestBreaker() {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s.cfg.Breaker = &breaker.Config{FailureThreshold: 2, OpenTimeout: time.Minute}
+	client, err := New(s.cfg, s.log)
+	s.Require().NoError(err)
+
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		s.Require().NoError(err)
+		resp.Body.Close()
+		s.Equal(http.StatusInternalServerError, resp.StatusCode)
+	}
+
+	_, err = client.Get(srv.URL)
+	s.True(errors.Is(err, breaker.ErrOpen))
+	s.Equal(int32(2), calls.Load())
+}
+
+func (s *HTTPClientTestSuite) TestTokenManager() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tm := &fakeTokenManager{}
+	client, err := New(s.cfg, s.log, WithTokenManager(tm))
+	s.Require().NoError(err)
+
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{}`))
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Equal(http.StatusNoContent, resp.StatusCode)
+	s.True(tm.refreshed.Load())
+}
+
+func (s *HTTPClientTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "negative timeout", cfg: &Config{Timeout: -time.Second}, expectError: true},
+		{name: "invalid retry", cfg: &Config{Retry: retry.Config{Jitter: 2}}, expectError: true},
+		{name: "invalid breaker", cfg: &Config{Breaker: &breaker.Config{FailureThreshold: -1}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestHTTPClientSuite(t *testing.T) {
+	suite.Run(t, new(HTTPClientTestSuite))
+}
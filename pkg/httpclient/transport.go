@@ -0,0 +1,155 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/breaker"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+)
+
+// IdempotencyKeyHeader marks a non-idempotent request as safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var errRetryableStatus = errors.New("retryable response status")
+
+type retryTransport struct {
+	base http.RoundTripper
+	opts []retry.Option
+	log  *slog.Logger
+}
+
+// RoundTrip retries transport errors and 429, 502, 503 and 504 responses for
+// idempotent requests whose body can be replayed. When retries run out on a
+// retryable status, the last response is returned as is.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	var (
+		last    *http.Response
+		attempt int
+	)
+	opts := append(append([]retry.Option{}, t.opts...), retry.WithOnRetry(func(attempt int, err error, delay time.Duration) {
+		t.log.Debug("retrying http request", "method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "attempt", attempt, "delay", delay, "error", err)
+	}))
+
+	resp, err := retry.DoValue(req.Context(), func(ctx context.Context) (*http.Response, error) {
+		attempt++
+		if last != nil {
+			drain(last)
+			last = nil
+		}
+
+		r := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, retry.Permanent(errors.WithStack(err))
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, retry.Permanent(err)
+			}
+			return nil, err
+		}
+		if retryableStatus(resp.StatusCode) {
+			last = resp
+			return nil, errors.Wrapf(errRetryableStatus, "status %d", resp.StatusCode)
+		}
+		return resp, nil
+	}, opts...)
+
+	if err != nil && last != nil {
+		if errors.Is(err, errRetryableStatus) {
+			return last, nil
+		}
+		drain(last)
+	}
+	return resp, err
+}
+
+func retryable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return req.Header.Get(IdempotencyKeyHeader) != ""
+	}
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+type breakerTransport struct {
+	base     http.RoundTripper
+	breakers *breaker.Registry
+}
+
+var errServerStatus = errors.New("server error status")
+
+// RoundTrip tracks transport errors and 5xx responses per host. While a host's
+// breaker is open, requests fail immediately with breaker.ErrOpen.
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := t.breakers.Get(req.URL.Host).Execute(req.Context(), func(context.Context) error {
+		var err error
+		resp, err = t.base.RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return errServerStatus
+		}
+		return nil
+	})
+	if errors.Is(err, errServerStatus) {
+		return resp, nil
+	}
+	return resp, err
+}
+
+type loggingTransport struct {
+	base http.RoundTripper
+	log  *slog.Logger
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	attrs := []any{"method", req.Method, "host", req.URL.Host, "path", req.URL.Path, "duration", time.Since(start)}
+	switch {
+	case err != nil:
+		t.log.Warn("http request failed", append(attrs, "error", err)...)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		t.log.Warn("http request", append(attrs, "status", resp.StatusCode)...)
+	default:
+		t.log.Debug("http request", append(attrs, "status", resp.StatusCode)...)
+	}
+	return resp, err
+}
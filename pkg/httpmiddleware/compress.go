@@ -0,0 +1,101 @@
+package httpmiddleware
+
+import (
+	"net/http"
+
+	"github.com/grid-stream-org/go-commons/pkg/compress"
+)
+
+// Gzip compresses responses for clients that accept gzip. Responses that
+// already set a Content-Encoding are left untouched.
+func Gzip() Middleware {
+	return Compress(compress.Gzip)
+}
+
+// Compress compresses responses with the first of encodings the client
+// prefers, as negotiated from Accept-Encoding. With no encodings it offers
+// zstd then gzip. Responses that already set a Content-Encoding are left
+// untouched.
+func Compress(encodings ...compress.Encoding) Middleware {
+	if len(encodings) == 0 {
+		encodings = []compress.Encoding{compress.Zstd, compress.Gzip}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			enc := compress.Negotiate(r.Header.Get("Accept-Encoding"), encodings...)
+			if enc == compress.Identity || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressResponseWriter{ResponseWriter: w, enc: enc}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+type compressResponseWriter struct {
+	http.ResponseWriter
+	enc         compress.Encoding
+	cw          *compress.Writer
+	wroteHeader bool
+}
+
+func (w *compressResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	h := w.Header()
+	if h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified {
+		cw, err := compress.NewWriter(w.ResponseWriter, w.enc)
+		if err == nil {
+			h.Set("Content-Encoding", string(w.enc))
+			h.Del("Content-Length")
+			w.cw = cw
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.cw == nil {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.cw.Write(b)
+}
+
+// Flush lets streaming handlers push compressed data to the client. A flush
+// before any write sends the headers, so they must be settled first.
+func (w *compressResponseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.cw != nil {
+		_ = w.cw.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressResponseWriter) close() {
+	if w.cw == nil {
+		return
+	}
+	_ = w.cw.Close()
+	w.cw = nil
+}
@@ -0,0 +1,75 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSConfig struct {
+	// AllowedOrigins lists exact origins, or "*" to allow any origin.
+	AllowedOrigins   []string      `koanf:"allowed_origins" json:"allowed_origins" envconfig:"allowed_origins"`
+	AllowedMethods   []string      `koanf:"allowed_methods" json:"allowed_methods" envconfig:"allowed_methods"`
+	AllowedHeaders   []string      `koanf:"allowed_headers" json:"allowed_headers" envconfig:"allowed_headers"`
+	ExposedHeaders   []string      `koanf:"exposed_headers" json:"exposed_headers" envconfig:"exposed_headers"`
+	AllowCredentials bool          `koanf:"allow_credentials" json:"allow_credentials" envconfig:"allow_credentials"`
+	MaxAge           time.Duration `koanf:"max_age" json:"max_age" envconfig:"max_age"`
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	defaultCORSHeaders = []string{"Authorization", "Content-Type", RequestIDHeader}
+)
+
+// CORS answers preflight requests and sets the Access-Control headers for
+// allowed origins. Requests from other origins pass through without CORS
+// headers, leaving the browser to block them.
+func CORS(cfg CORSConfig) Middleware {
+	methods := strings.Join(orDefault(cfg.AllowedMethods, defaultCORSMethods), ", ")
+	headers := strings.Join(orDefault(cfg.AllowedHeaders, defaultCORSHeaders), ", ")
+	exposed := strings.Join(append([]string{RequestIDHeader}, cfg.ExposedHeaders...), ", ")
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if origin == "" || (!anyOrigin && !slices.Contains(cfg.AllowedOrigins, origin)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Credentials cannot be combined with a wildcard origin.
+			if anyOrigin && !cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+				if cfg.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			w.Header().Set("Access-Control-Expose-Headers", exposed)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func orDefault(v, def []string) []string {
+	if len(v) > 0 {
+		return v
+	}
+	return def
+}
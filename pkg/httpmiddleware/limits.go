@@ -0,0 +1,32 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeout cancels the request context after d and responds with 503 if the
+// handler has not written a response by then. Handlers must honour context
+// cancellation for the timeout to free resources. Streaming endpoints should
+// not use it, since it buffers the response.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
+
+// MaxBodySize rejects request bodies larger than n bytes. Requests that
+// declare a larger Content-Length get 413 immediately; otherwise reads past
+// the limit fail with *http.MaxBytesError.
+func MaxBodySize(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/compress"
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	logs *bytes.Buffer
+	log  *slog.Logger
+}
+
+func (s *MiddlewareTestSuite) SetupTest() {
+	s.logs = &bytes.Buffer{}
+	s.log = slog.New(slog.NewTextHandler(s.logs, nil))
+}
+
+func (s *MiddlewareTestSuite) TestChainOrder() {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		order = append(order, "handler")
+	}), mw("a"), mw("b"))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Equal([]string{"a", "b", "handler"}, order)
+}
+
+func (s *MiddlewareTestSuite) TestRequestID() {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, RequestIDFromContext(r.Context()))
+	}), RequestID())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc")
+	h.ServeHTTP(rec, req)
+	s.Equal("abc", rec.Body.String())
+	s.Equal("abc", rec.Header().Get(RequestIDHeader))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Len(rec.Body.String(), 32)
+}
+
+func (s *MiddlewareTestSuite) TestRecoverAndLogging() {
+	h := Chain(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	}), RequestID(), Logging(s.log), Recover(s.log))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/ingest", nil))
+	s.Equal(http.StatusInternalServerError, rec.Code)
+	s.Contains(s.logs.String(), "http handler panic")
+	s.Contains(s.logs.String(), "status=500")
+	s.Contains(s.logs.String(), "path=/ingest")
+}
+
+func (s *MiddlewareTestSuite) TestCORS() {
+	h := CORS(CORSConfig{AllowedOrigins: []string{"https://dashboard.gridstream.io"}, MaxAge: time.Hour})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+
+	testCases := []struct {
+		name    string
+		method  string
+		origin  string
+		status  int
+		allowed string
+	}{
+		{name: "allowed origin", method: http.MethodGet, origin: "https://dashboard.gridstream.io", status: http.StatusOK, allowed: "https://dashboard.gridstream.io"},
+		{name: "other origin", method: http.MethodGet, origin: "https://evil.example", status: http.StatusOK},
+		{name: "no origin", method: http.MethodGet, status: http.StatusOK},
+		{name: "preflight", method: http.MethodOptions, origin: "https://dashboard.gridstream.io", status: http.StatusNoContent, allowed: "https://dashboard.gridstream.io"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			if tc.method == http.MethodOptions {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			s.Equal(tc.status, rec.Code)
+			s.Equal(tc.allowed, rec.Header().Get("Access-Control-Allow-Origin"))
+			if tc.method == http.MethodOptions {
+				s.Contains(rec.Header().Get("Access-Control-Allow-Methods"), http.MethodPost)
+				s.Equal("3600", rec.Header().Get("Access-Control-Max-Age"))
+			}
+		})
+	}
+}
+
+func (s *MiddlewareTestSuite) TestCORSWildcard() {
+	h := CORS(CORSConfig{AllowedOrigins: []string{"*"}})(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://anywhere.example")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	s.Equal("*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func (s *MiddlewareTestSuite) TestGzip() {
+	body := strings.Repeat("der_data ", 100)
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br, gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	s.Equal("gzip", rec.Header().Get("Content-Encoding"))
+	s.Contains(rec.Header().Get("Content-Type"), "text/plain")
+
+	zr, err := gzip.NewReader(rec.Body)
+	s.Require().NoError(err)
+	got, err := io.ReadAll(zr)
+	s.Require().NoError(err)
+	s.Equal(body, string(got))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Empty(rec.Header().Get("Content-Encoding"))
+	s.Equal(body, rec.Body.String())
+}
+
+func (s *MiddlewareTestSuite) TestGzipNoContent() {
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	s.Equal(http.StatusNoContent, rec.Code)
+	s.Empty(rec.Header().Get("Content-Encoding"))
+	s.Zero(rec.Body.Len())
+}
+
+func (s *MiddlewareTestSuite) TestCompressZstd() {
+	body := strings.Repeat("der_data ", 100)
+	h := Compress()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.8, zstd")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	s.Equal("zstd", rec.Header().Get("Content-Encoding"))
+
+	got, err := compress.Decompress(compress.Zstd, rec.Body.Bytes(), 0)
+	s.Require().NoError(err)
+	s.Equal(body, string(got))
+}
+
+func (s *MiddlewareTestSuite) TestCompressFlushBeforeWrite() {
+	body := strings.Repeat("der_data ", 100)
+	h := Gzip()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = io.WriteString(w, body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	// Result reports the headers as they were when first sent.
+	s.Equal("gzip", rec.Result().Header.Get("Content-Encoding"))
+
+	got, err := compress.Decompress(compress.Gzip, rec.Body.Bytes(), 0)
+	s.Require().NoError(err)
+	s.Equal(body, string(got))
+}
+
+func (s *MiddlewareTestSuite) TestTimeout() {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	s.Equal(http.StatusServiceUnavailable, rec.Code)
+}
+
+func (s *MiddlewareTestSuite) TestMaxBodySize() {
+	h := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("small")))
+	s.Equal(http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("far too large")))
+	s.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("far too large")))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	s.Equal(http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestMiddlewareSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
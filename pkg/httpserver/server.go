@@ -0,0 +1,233 @@
+// Package httpserver provides the standard HTTP server scaffold shared by
+// grid-stream API services: timeouts, TLS, default middleware, health and
+// metrics endpoints, an optional debug listener, and graceful shutdown when
+// the run context ends.
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/debug"
+	"github.com/grid-stream-org/go-commons/pkg/drain"
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/httpmiddleware"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultShutdownTimeout   = 15 * time.Second
+)
+
+type Config struct {
+	Addr              string            `koanf:"addr" json:"addr" envconfig:"addr"`
+	ReadHeaderTimeout time.Duration     `koanf:"read_header_timeout" json:"read_header_timeout" envconfig:"read_header_timeout"`
+	ReadTimeout       time.Duration     `koanf:"read_timeout" json:"read_timeout" envconfig:"read_timeout"`
+	WriteTimeout      time.Duration     `koanf:"write_timeout" json:"write_timeout" envconfig:"write_timeout"`
+	IdleTimeout       time.Duration     `koanf:"idle_timeout" json:"idle_timeout" envconfig:"idle_timeout"`
+	ShutdownTimeout   time.Duration     `koanf:"shutdown_timeout" json:"shutdown_timeout" envconfig:"shutdown_timeout"`
+	TLS               *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+	// Debug serves pprof and runtime endpoints on a separate address.
+	Debug *debug.Config `koanf:"debug" json:"debug" envconfig:"debug"`
+}
+
+type Option func(*Server)
+
+type Server struct {
+	cfg        *Config
+	log        *slog.Logger
+	srv        *http.Server
+	tls        *tls.Config
+	health     *health.Health
+	metrics    *metrics.Metrics
+	middleware []httpmiddleware.Middleware
+	listener   net.Listener
+	drainer    *drain.Drainer
+	debug      *debug.Server
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("http server configuration required")
+	}
+	if c.Addr == "" {
+		return errors.New("http server addr required")
+	}
+	if c.ReadHeaderTimeout < 0 || c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 {
+		return errors.New("http server timeouts must not be negative")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	return c.Debug.Validate()
+}
+
+// WithHealth mounts the liveness and readiness endpoints of h. The server
+// marks h as shutting down before it stops accepting connections.
+func WithHealth(h *health.Health) Option {
+	return func(s *Server) {
+		s.health = h
+	}
+}
+
+// WithMetrics mounts the Prometheus endpoint of m at metrics.Path.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = m
+	}
+}
+
+// WithMiddleware appends mw after the default request ID, recovery and logging
+// middleware. Middleware applies only to the application handler, not to the
+// health and metrics endpoints.
+func WithMiddleware(mw ...httpmiddleware.Middleware) Option {
+	return func(s *Server) {
+		s.middleware = append(s.middleware, mw...)
+	}
+}
+
+// WithListener serves on l instead of listening on Config.Addr.
+func WithListener(l net.Listener) Option {
+	return func(s *Server) {
+		s.listener = l
+	}
+}
+
+func New(cfg *Config, handler http.Handler, log *slog.Logger, opts ...Option) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	tlsCfg, err := cfg.TLS.BuildServer()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		cfg: cfg,
+		log: log,
+		tls: tlsCfg,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.drainer, err = drain.New(&drain.Config{Timeout: orDefault(cfg.ShutdownTimeout, DefaultShutdownTimeout)}, log, drain.WithHealth(s.health))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Debug != nil && cfg.Debug.Enabled {
+		if s.debug, err = debug.New(cfg.Debug, log); err != nil {
+			return nil, err
+		}
+	}
+
+	mw := append([]httpmiddleware.Middleware{
+		httpmiddleware.RequestID(),
+		httpmiddleware.Recover(log),
+		httpmiddleware.Logging(log),
+	}, s.middleware...)
+
+	mux := http.NewServeMux()
+	if s.health != nil {
+		hh := s.health.Handler()
+		mux.Handle(health.LivezPath, hh)
+		mux.Handle(health.ReadyzPath, hh)
+	}
+	if s.metrics != nil {
+		mux.Handle(metrics.Path, s.metrics.Handler())
+	}
+	mux.Handle("/", httpmiddleware.Chain(handler, mw...))
+
+	s.srv = &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           mux,
+		TLSConfig:         tlsCfg,
+		ReadHeaderTimeout: orDefault(cfg.ReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       orDefault(cfg.ReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      orDefault(cfg.WriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       orDefault(cfg.IdleTimeout, DefaultIdleTimeout),
+		ErrorLog:          slog.NewLogLogger(log.Handler(), slog.LevelWarn),
+	}
+	return s, nil
+}
+
+// Handler returns the fully assembled handler, including mounted endpoints.
+func (s *Server) Handler() http.Handler {
+	return s.srv.Handler
+}
+
+// Run serves until ctx is cancelled, typically by a sigctx signal, and then
+// shuts down gracefully, waiting up to Config.ShutdownTimeout for in-flight
+// requests. It returns nil after a clean shutdown. The debug server, if
+// configured, runs for as long as Run does; its failures are logged but do
+// not stop the service.
+func (s *Server) Run(ctx context.Context) error {
+	if s.debug != nil {
+		debugCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			if err := s.debug.Run(debugCtx); err != nil {
+				s.log.Error("debug server failed", "error", err)
+			}
+		}()
+	}
+
+	l := s.listener
+	if l == nil {
+		var err error
+		if l, err = net.Listen("tcp", s.cfg.Addr); err != nil {
+			return errors.Wrapf(err, "listening on %s", s.cfg.Addr)
+		}
+	}
+
+	dl := drain.Listen(l)
+	errCh := make(chan error, 1)
+	go func() {
+		s.log.Info("http server listening", "addr", l.Addr().String(), "tls", s.tls != nil)
+		var err error
+		if s.tls != nil {
+			err = s.srv.ServeTLS(dl, "", "")
+		} else {
+			err = s.srv.Serve(dl)
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			err = nil
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return errors.WithStack(err)
+	case <-ctx.Done():
+	}
+
+	s.log.Info("http server shutting down")
+	res, err := s.drainer.Drain(context.WithoutCancel(ctx), s.srv, dl)
+	if err != nil {
+		return errors.Wrap(err, "shutting down http server")
+	}
+	if res.Forced {
+		return errors.Errorf("http server shutdown timed out with %d connections open", res.Remaining)
+	}
+	return errors.WithStack(<-errCh)
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,126 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/debug"
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/httpmiddleware"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/stretchr/testify/suite"
+)
+
+type HTTPServerTestSuite struct {
+	suite.Suite
+	logs *bytes.Buffer
+	log  *slog.Logger
+}
+
+func (s *HTTPServerTestSuite) SetupTest() {
+	s.logs = &bytes.Buffer{}
+	s.log = slog.New(slog.NewTextHandler(s.logs, nil))
+}
+
+func (s *HTTPServerTestSuite) TestMounts() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc", Env: "dev"})
+	s.Require().NoError(err)
+
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, httpmiddleware.RequestIDFromContext(r.Context()))
+	})
+	srv, err := New(&Config{Addr: ":0"}, app, s.log, WithHealth(health.New()), WithMetrics(m))
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "liveness", path: health.LivezPath, status: http.StatusOK},
+		{name: "readiness", path: health.ReadyzPath, status: http.StatusOK},
+		{name: "metrics", path: metrics.Path, status: http.StatusOK},
+		{name: "application", path: "/api/projects", status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			s.Equal(tc.status, rec.Code)
+		})
+	}
+}
+
+func (s *HTTPServerTestSuite) TestRunGracefulShutdown() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+
+	h := health.New()
+	started := make(chan struct{})
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	srv, err := New(&Config{Addr: l.Addr().String()}, app, s.log, WithListener(l), WithHealth(h))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	respCh := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			respCh <- 0
+			return
+		}
+		resp.Body.Close()
+		respCh <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+	s.Equal(http.StatusAccepted, <-respCh)
+	s.NoError(<-done)
+	s.Equal(health.StatusFail, h.Readiness(context.Background()).Status)
+}
+
+func (s *HTTPServerTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Addr: ":8080"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing addr", cfg: &Config{}, expectError: true},
+		{name: "negative timeout", cfg: &Config{Addr: ":8080", WriteTimeout: -time.Second}, expectError: true},
+		{name: "debug disabled", cfg: &Config{Addr: ":8080", Debug: &debug.Config{}}},
+		{name: "debug missing addr", cfg: &Config{Addr: ":8080", Debug: &debug.Config{Enabled: true}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestHTTPServerSuite(t *testing.T) {
+	suite.Run(t, new(HTTPServerTestSuite))
+}
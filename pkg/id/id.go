@@ -0,0 +1,114 @@
+// Package id generates and parses the time-sortable identifiers used across
+// grid-stream: ULIDs for resources, event envelopes and BigQuery insert IDs,
+// and UUIDv7 where a system requires the UUID format. Resource IDs carry a
+// short type prefix, for example der_01JH2Q5V0ZB7C6S1X9K4M8N3PR.
+package id
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/pkg/errors"
+)
+
+type Prefix string
+
+const (
+	Project Prefix = "proj"
+	DER     Prefix = "der"
+	Event   Prefix = "evt"
+)
+
+const separator = "_"
+
+var ErrInvalid = errors.New("invalid id")
+
+// NewULID returns a ULID. IDs generated in the same millisecond by this
+// process are strictly increasing.
+func NewULID() string {
+	return ulid.Make().String()
+}
+
+// NewUUIDv7 returns a time-ordered RFC 9562 version 7 UUID.
+func NewUUIDv7() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// New returns a prefixed ULID such as evt_01JH2Q5V0ZB7C6S1X9K4M8N3PR.
+func New(p Prefix) string {
+	return string(p) + separator + NewULID()
+}
+
+// ID is a parsed identifier.
+type ID struct {
+	Prefix Prefix
+	Value  string
+	Time   time.Time
+}
+
+func (i ID) String() string {
+	if i.Prefix == "" {
+		return i.Value
+	}
+	return string(i.Prefix) + separator + i.Value
+}
+
+// Parse accepts a ULID or UUIDv7, optionally preceded by a prefix and an
+// underscore, and extracts the embedded timestamp.
+func Parse(s string) (ID, error) {
+	var id ID
+	value := s
+	if i := strings.LastIndex(s, separator); i >= 0 {
+		id.Prefix, value = Prefix(s[:i]), s[i+1:]
+		if id.Prefix == "" {
+			return ID{}, errors.Wrapf(ErrInvalid, "%q has an empty prefix", s)
+		}
+	}
+
+	switch len(value) {
+	case ulid.EncodedSize:
+		u, err := ulid.ParseStrict(value)
+		if err != nil {
+			return ID{}, errors.Wrapf(ErrInvalid, "%q: %v", s, err)
+		}
+		id.Value = u.String()
+		id.Time = ulid.Time(u.Time()).UTC()
+	case 36:
+		u, err := uuid.Parse(value)
+		if err != nil {
+			return ID{}, errors.Wrapf(ErrInvalid, "%q: %v", s, err)
+		}
+		if u.Version() != 7 {
+			return ID{}, errors.Wrapf(ErrInvalid, "%q is a version %d uuid, want 7", s, u.Version())
+		}
+		sec, nsec := u.Time().UnixTime()
+		id.Value = u.String()
+		id.Time = time.Unix(sec, nsec).UTC()
+	default:
+		return ID{}, errors.Wrapf(ErrInvalid, "%q is neither a ulid nor a uuid", s)
+	}
+	return id, nil
+}
+
+func MustParse(s string) ID {
+	id, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// Validate checks that s is a well-formed ID with prefix p. An empty p
+// requires an unprefixed ID.
+func Validate(s string, p Prefix) error {
+	id, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	if id.Prefix != p {
+		return errors.Wrapf(ErrInvalid, "%q has prefix %q, want %q", s, id.Prefix, p)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package id
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type IDTestSuite struct {
+	suite.Suite
+}
+
+func (s *IDTestSuite) TestULIDsAreSortable() {
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = NewULID()
+	}
+	s.True(slices.IsSorted(ids))
+
+	id := MustParse(ids[0])
+	s.Empty(id.Prefix)
+	s.WithinDuration(time.Now(), id.Time, time.Second)
+}
+
+func (s *IDTestSuite) TestUUIDv7() {
+	raw := NewUUIDv7()
+	id, err := Parse(raw)
+	s.Require().NoError(err)
+	s.Equal(raw, id.String())
+	s.WithinDuration(time.Now(), id.Time, time.Second)
+
+	_, err = Parse("9b2c5d3e-1f4a-4c6b-8d7e-0a1b2c3d4e5f")
+	s.ErrorIs(err, ErrInvalid)
+}
+
+func (s *IDTestSuite) TestPrefixed() {
+	raw := New(DER)
+	s.Regexp(`^der_[0-9A-HJKMNP-TV-Z]{26}$`, raw)
+
+	id, err := Parse(raw)
+	s.Require().NoError(err)
+	s.Equal(DER, id.Prefix)
+	s.Equal(raw, id.String())
+
+	s.NoError(Validate(raw, DER))
+	s.ErrorIs(Validate(raw, Event), ErrInvalid)
+	s.ErrorIs(Validate(NewULID(), Project), ErrInvalid)
+	s.NoError(Validate("proj_"+NewUUIDv7(), Project))
+}
+
+func (s *IDTestSuite) TestParseErrors() {
+	testCases := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"wrong length", "evt_123"},
+		{"empty prefix", "_" + NewULID()},
+		{"bad ulid characters", "evt_01JH2Q5V0ZB7C6S1X9K4M8N3PU"},
+		{"ulid overflow", "8ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{"bad uuid", "evt_zzzzzzzz-1f4a-7c6b-8d7e-0a1b2c3d4e5f"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := Parse(tc.in)
+			s.ErrorIs(err, ErrInvalid)
+		})
+	}
+	s.Panics(func() { MustParse("nope") })
+}
+
+func TestIDSuite(t *testing.T) {
+	suite.Run(t, new(IDTestSuite))
+}
@@ -0,0 +1,243 @@
+// Package jsonschema validates inbound JSON payloads, such as gateway
+// telemetry, against versioned JSON Schemas before they are written to
+// BigQuery.
+//
+// Schemas are laid out as <name>/<version>.json, for example
+// der_data/v1.json and der_data/v2.json, and are loaded from an embedded
+// filesystem or a GCS prefix. Schemas may $ref each other by relative path.
+// Remote references are not fetched.
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+	js "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const baseURL = "mem:///"
+
+// ErrUnknownSchema is returned when no schema matches a name and version.
+var ErrUnknownSchema = gserrors.New(gserrors.NotFound, "unknown schema")
+
+// Violation is a single schema failure.
+type Violation struct {
+	// Path is the JSON pointer of the offending value in the payload.
+	Path string `json:"path"`
+	// Keyword is the JSON pointer of the failing keyword in the schema.
+	Keyword string `json:"keyword"`
+	Message string `json:"message"`
+}
+
+// ValidationError reports every violation found in a payload.
+type ValidationError struct {
+	Schema     string      `json:"schema"`
+	Version    string      `json:"version"`
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		p := v.Path
+		if p == "" {
+			p = "/"
+		}
+		messages[i] = p + ": " + v.Message
+	}
+	return fmt.Sprintf("payload does not match schema %s/%s: %s", e.Schema, e.Version, strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) ErrorCode() gserrors.Code {
+	return gserrors.InvalidInput
+}
+
+// Registry holds the compiled schemas from a Source. It is safe for
+// concurrent use, including while Reload runs.
+type Registry struct {
+	src Source
+	log *slog.Logger
+
+	mu      sync.RWMutex
+	schemas map[string]map[string]*js.Schema
+}
+
+// New loads and compiles every schema in src.
+func New(ctx context.Context, src Source, log *slog.Logger) (*Registry, error) {
+	r := &Registry{src: src, log: log}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads src again and swaps in the new schemas. If any schema fails
+// to load or compile the current schemas are kept.
+func (r *Registry) Reload(ctx context.Context) error {
+	files, err := r.src.Files(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading schemas")
+	}
+
+	c := js.NewCompiler()
+	c.Draft = js.Draft2020
+	c.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, errors.Errorf("schema %s not found", s)
+	}
+	for p, data := range files {
+		if err := c.AddResource(baseURL+p, bytes.NewReader(data)); err != nil {
+			return errors.Wrapf(err, "adding schema %s", p)
+		}
+	}
+
+	schemas := map[string]map[string]*js.Schema{}
+	for p := range files {
+		name, version, ok := parsePath(p)
+		if !ok {
+			continue
+		}
+		s, err := c.Compile(baseURL + p)
+		if err != nil {
+			return errors.Wrapf(err, "compiling schema %s", p)
+		}
+		if schemas[name] == nil {
+			schemas[name] = map[string]*js.Schema{}
+		}
+		schemas[name][version] = s
+	}
+
+	r.mu.Lock()
+	r.schemas = schemas
+	r.mu.Unlock()
+	r.log.Info("json schemas loaded", "schemas", len(schemas), "files", len(files))
+	return nil
+}
+
+// Versions returns the versions of name, oldest first.
+func (r *Registry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := make([]string, 0, len(r.schemas[name]))
+	for v := range r.schemas[name] {
+		versions = append(versions, v)
+	}
+	slices.SortFunc(versions, compareVersions)
+	return versions
+}
+
+// Latest returns the newest version of name, or "" if it is unknown.
+func (r *Registry) Latest(name string) string {
+	versions := r.Versions(name)
+	if len(versions) == 0 {
+		return ""
+	}
+	return versions[len(versions)-1]
+}
+
+// Validate checks a JSON payload against version of schema name. An empty
+// version selects the latest. Payloads that are not valid JSON or do not
+// match return a *ValidationError.
+func (r *Registry) Validate(name, version string, payload []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v any
+	err := dec.Decode(&v)
+	if err == nil {
+		// A payload is one value; anything after it would be ignored by the
+		// check but not necessarily by the consumer.
+		if derr := dec.Decode(new(any)); derr != io.EOF {
+			err = errors.New("unexpected data after top-level value")
+		}
+	}
+	if err != nil {
+		if version == "" {
+			version = r.Latest(name)
+		}
+		return errors.WithStack(&ValidationError{
+			Schema:     name,
+			Version:    version,
+			Violations: []Violation{{Message: "invalid JSON: " + err.Error()}},
+		})
+	}
+	return r.validate(name, version, v)
+}
+
+// ValidateValue checks a Go value by round-tripping it through JSON.
+func (r *Registry) ValidateValue(name, version string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return r.Validate(name, version, data)
+}
+
+func (r *Registry) validate(name, version string, v any) error {
+	if version == "" {
+		version = r.Latest(name)
+	}
+
+	r.mu.RLock()
+	s, ok := r.schemas[name][version]
+	r.mu.RUnlock()
+	if !ok {
+		return errors.Wrapf(ErrUnknownSchema, "%s/%s", name, version)
+	}
+
+	err := s.Validate(v)
+	if err == nil {
+		return nil
+	}
+	var ve *js.ValidationError
+	if !errors.As(err, &ve) {
+		return errors.WithStack(err)
+	}
+
+	out := &ValidationError{Schema: name, Version: version}
+	for _, e := range ve.BasicOutput().Errors {
+		// Intermediate units only say that a subschema failed; the leaves
+		// carry the useful messages.
+		if strings.HasPrefix(e.Error, "doesn't validate with") {
+			continue
+		}
+		out.Violations = append(out.Violations, Violation{
+			Path:    e.InstanceLocation,
+			Keyword: e.KeywordLocation,
+			Message: e.Error,
+		})
+	}
+	return errors.WithStack(out)
+}
+
+// parsePath splits name/version.json.
+func parsePath(p string) (name, version string, ok bool) {
+	dir, file := path.Split(p)
+	name = strings.TrimSuffix(dir, "/")
+	version, isJSON := strings.CutSuffix(file, ".json")
+	if name == "" || strings.Contains(name, "/") || version == "" || !isJSON {
+		return "", "", false
+	}
+	return name, version, true
+}
+
+// compareVersions orders v1 < v2 < v10, falling back to string order for
+// versions that are not a number with an optional v prefix.
+func compareVersions(a, b string) int {
+	na, errA := strconv.Atoi(strings.TrimPrefix(a, "v"))
+	nb, errB := strconv.Atoi(strings.TrimPrefix(b, "v"))
+	if errA == nil && errB == nil {
+		return na - nb
+	}
+	return strings.Compare(a, b)
+}
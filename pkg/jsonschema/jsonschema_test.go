@@ -0,0 +1,153 @@
+package jsonschema
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/grid-stream-org/go-commons/pkg/gcsclient"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+var schemas = fstest.MapFS{
+	"common/v1.json": {Data: []byte(`{
+		"$defs": {"id": {"type": "string", "minLength": 1}}
+	}`)},
+	"der_data/v1.json": {Data: []byte(`{
+		"type": "object",
+		"required": ["der_id", "current_output"],
+		"properties": {
+			"der_id": {"$ref": "../common/v1.json#/$defs/id"},
+			"current_output": {"type": "number"}
+		}
+	}`)},
+	"der_data/v2.json": {Data: []byte(`{
+		"type": "object",
+		"required": ["der_id", "current_output", "timestamp"],
+		"properties": {
+			"der_id": {"$ref": "../common/v1.json#/$defs/id"},
+			"current_output": {"type": "number", "minimum": 0},
+			"timestamp": {"type": "string"}
+		}
+	}`)},
+	"der_data/v10.json": {Data: []byte(`{"type": "object"}`)},
+	"README.md":         {Data: []byte("not a schema")},
+}
+
+// fakeGCS serves objects from a map.
+type fakeGCS struct {
+	gcsclient.GCSClient
+	objects map[string][]byte
+}
+
+func (f *fakeGCS) List(_ context.Context, bucket, prefix string) ([]*gcsclient.ObjectInfo, error) {
+	var out []*gcsclient.ObjectInfo
+	for name := range f.objects {
+		out = append(out, &gcsclient.ObjectInfo{Bucket: bucket, Name: name})
+	}
+	return out, nil
+}
+
+func (f *fakeGCS) Download(_ context.Context, _, object string) ([]byte, error) {
+	return f.objects[object], nil
+}
+
+type JSONSchemaTestSuite struct {
+	suite.Suite
+	reg *Registry
+}
+
+func (s *JSONSchemaTestSuite) SetupTest() {
+	reg, err := New(context.Background(), FS(schemas), logger.Default())
+	s.Require().NoError(err)
+	s.reg = reg
+}
+
+func (s *JSONSchemaTestSuite) TestVersions() {
+	s.Equal([]string{"v1", "v2", "v10"}, s.reg.Versions("der_data"))
+	s.Equal("v10", s.reg.Latest("der_data"))
+	s.Empty(s.reg.Latest("projects"))
+}
+
+func (s *JSONSchemaTestSuite) TestValidate() {
+	testCases := []struct {
+		name       string
+		version    string
+		payload    string
+		violations []Violation
+	}{
+		{name: "valid v1", version: "v1", payload: `{"der_id": "d1", "current_output": 4.2}`},
+		{name: "valid v2", version: "v2", payload: `{"der_id": "d1", "current_output": 4.2, "timestamp": "2024-01-01T00:00:00Z"}`},
+		{
+			name: "wrong type", version: "v1", payload: `{"der_id": "d1", "current_output": "high"}`,
+			violations: []Violation{{Path: "/current_output", Keyword: "/properties/current_output/type", Message: "expected number, but got string"}},
+		},
+		{
+			name: "ref", version: "v1", payload: `{"der_id": "", "current_output": 1}`,
+			violations: []Violation{{Path: "/der_id", Keyword: "/properties/der_id/$ref/minLength", Message: "length must be >= 1, but got 0"}},
+		},
+		{
+			name: "missing field", version: "v2", payload: `{"der_id": "d1", "current_output": -1}`,
+			violations: []Violation{
+				{Path: "", Keyword: "/required", Message: "missing properties: 'timestamp'"},
+				{Path: "/current_output", Keyword: "/properties/current_output/minimum", Message: "must be >= 0 but found -1"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := s.reg.Validate("der_data", tc.version, []byte(tc.payload))
+			if tc.violations == nil {
+				s.NoError(err)
+				return
+			}
+			var ve *ValidationError
+			s.Require().True(errors.As(err, &ve), "got %v", err)
+			s.Equal("der_data", ve.Schema)
+			s.Equal(tc.version, ve.Version)
+			s.ElementsMatch(tc.violations, ve.Violations)
+			s.True(gserrors.Is(err, gserrors.InvalidInput))
+		})
+	}
+}
+
+func (s *JSONSchemaTestSuite) TestValidateErrors() {
+	err := s.reg.Validate("der_data", "v1", []byte(`{"der_id":`))
+	var ve *ValidationError
+	s.Require().True(errors.As(err, &ve))
+	s.Contains(ve.Violations[0].Message, "invalid JSON")
+
+	for _, payload := range []string{`{"der_id": "d1"} {"der_id": ""}`, `{"der_id": "d1"}]`} {
+		err = s.reg.Validate("der_data", "v1", []byte(payload))
+		s.Require().True(errors.As(err, &ve), payload)
+		s.Contains(ve.Violations[0].Message, "invalid JSON")
+	}
+
+	s.ErrorIs(s.reg.Validate("der_data", "v3", []byte(`{}`)), ErrUnknownSchema)
+	s.ErrorIs(s.reg.Validate("projects", "", []byte(`{}`)), ErrUnknownSchema)
+
+	s.NoError(s.reg.ValidateValue("der_data", "v1", map[string]any{"der_id": "d1", "current_output": 1}))
+}
+
+func (s *JSONSchemaTestSuite) TestGCS() {
+	gcs := &fakeGCS{objects: map[string][]byte{
+		"schemas/der_data/v1.json": schemas["der_data/v1.json"].Data,
+		"schemas/common/v1.json":   schemas["common/v1.json"].Data,
+	}}
+	reg, err := New(context.Background(), GCS(gcs, "bucket", "schemas"), logger.Default())
+	s.Require().NoError(err)
+	s.Error(reg.Validate("der_data", "", []byte(`{"der_id": ""}`)))
+
+	// A broken upload keeps the previous schemas.
+	gcs.objects["schemas/der_data/v2.json"] = []byte(`{"type": 5}`)
+	s.Error(reg.Reload(context.Background()))
+	s.Equal([]string{"v1"}, reg.Versions("der_data"))
+}
+
+func TestJSONSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(JSONSchemaTestSuite))
+}
@@ -0,0 +1,81 @@
+package jsonschema
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/grid-stream-org/go-commons/pkg/gcsclient"
+	"github.com/pkg/errors"
+)
+
+// Source provides schema files keyed by slash-separated path relative to
+// the schema root.
+type Source interface {
+	Files(ctx context.Context) (map[string][]byte, error)
+}
+
+type fsSource struct {
+	fsys fs.FS
+}
+
+// FS reads every .json file in fsys, typically an embed.FS narrowed with
+// fs.Sub.
+func FS(fsys fs.FS) Source {
+	return fsSource{fsys: fsys}
+}
+
+func (s fsSource) Files(context.Context) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := fs.WalkDir(s.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".json" {
+			return err
+		}
+		data, err := fs.ReadFile(s.fsys, p)
+		if err != nil {
+			return err
+		}
+		files[p] = data
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return files, nil
+}
+
+type gcsSource struct {
+	client gcsclient.GCSClient
+	bucket string
+	prefix string
+}
+
+// GCS reads every .json object under prefix in bucket. Paths are relative
+// to prefix.
+func GCS(client gcsclient.GCSClient, bucket, prefix string) Source {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return gcsSource{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s gcsSource) Files(ctx context.Context) (map[string][]byte, error) {
+	objects, err := s.client.List(ctx, s.bucket, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, obj := range objects {
+		if path.Ext(obj.Name) != ".json" {
+			continue
+		}
+		data, err := s.client.Download(ctx, s.bucket, obj.Name)
+		if err != nil {
+			return nil, err
+		}
+		files[strings.TrimPrefix(obj.Name, s.prefix)] = data
+	}
+	return files, nil
+}
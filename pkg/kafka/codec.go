@@ -0,0 +1,52 @@
+package kafka
+
+import (
+	"encoding/json"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+)
+
+// Codec converts between typed values and Kafka message payloads.
+type Codec[T any] interface {
+	Encode(v T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return data, errors.WithStack(err)
+}
+
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, errors.WithStack(err)
+}
+
+type AvroCodec[T any] struct {
+	schema avro.Schema
+}
+
+// NewAvroCodec parses schema and returns a codec that encodes T as raw Avro
+// binary. T is mapped to the schema using avro struct tags.
+func NewAvroCodec[T any](schema string) (*AvroCodec[T], error) {
+	s, err := avro.Parse(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing avro schema")
+	}
+	return &AvroCodec[T]{schema: s}, nil
+}
+
+func (c *AvroCodec[T]) Encode(v T) ([]byte, error) {
+	data, err := avro.Marshal(c.schema, v)
+	return data, errors.WithStack(err)
+}
+
+func (c *AvroCodec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := avro.Unmarshal(c.schema, data, &v)
+	return v, errors.WithStack(err)
+}
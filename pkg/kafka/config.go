@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+
+	DefaultDialTimeout = 10 * time.Second
+)
+
+type Config struct {
+	Brokers     []string          `koanf:"brokers" json:"brokers" envconfig:"brokers"`
+	ClientID    string            `koanf:"client_id" json:"client_id" envconfig:"client_id"`
+	DialTimeout time.Duration     `koanf:"dial_timeout" json:"dial_timeout" envconfig:"dial_timeout"`
+	TLS         *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+	SASL        *SASLConfig       `koanf:"sasl" json:"sasl" envconfig:"sasl"`
+}
+
+type SASLConfig struct {
+	Mechanism string `koanf:"mechanism" json:"mechanism" envconfig:"mechanism"`
+	Username  string `koanf:"username" json:"username" envconfig:"username"`
+	Password  string `koanf:"password" json:"password" envconfig:"password"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("kafka configuration required")
+	}
+	if len(c.Brokers) == 0 {
+		return errors.New("kafka brokers required")
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+	return c.SASL.Validate()
+}
+
+func (c *SASLConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Mechanism {
+	case SASLPlain, SASLScramSHA256, SASLScramSHA512:
+	default:
+		return errors.Errorf("unsupported sasl mechanism %q", c.Mechanism)
+	}
+	if c.Username == "" || c.Password == "" {
+		return errors.New("sasl username and password required")
+	}
+	return nil
+}
+
+func (c *SASLConfig) mechanism() (sasl.Mechanism, error) {
+	if c == nil {
+		return nil, nil
+	}
+	switch c.Mechanism {
+	case SASLPlain:
+		return plain.Mechanism{Username: c.Username, Password: c.Password}, nil
+	case SASLScramSHA256:
+		m, err := scram.Mechanism(scram.SHA256, c.Username, c.Password)
+		return m, errors.WithStack(err)
+	case SASLScramSHA512:
+		m, err := scram.Mechanism(scram.SHA512, c.Username, c.Password)
+		return m, errors.WithStack(err)
+	default:
+		return nil, errors.Errorf("unsupported sasl mechanism %q", c.Mechanism)
+	}
+}
+
+// dialer builds the dialer used by readers from the connection settings.
+func (c *Config) dialer() (*kafka.Dialer, error) {
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	mech, err := c.SASL.mechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.DialTimeout
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+
+	return &kafka.Dialer{
+		ClientID:      c.ClientID,
+		Timeout:       timeout,
+		DualStack:     true,
+		TLS:           tlsCfg,
+		SASLMechanism: mech,
+	}, nil
+}
+
+// transport builds the transport used by writers from the connection settings.
+func (c *Config) transport() (*kafka.Transport, error) {
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	mech, err := c.SASL.mechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := c.DialTimeout
+	if timeout <= 0 {
+		timeout = DefaultDialTimeout
+	}
+
+	return &kafka.Transport{
+		ClientID:    c.ClientID,
+		DialTimeout: timeout,
+		TLS:         tlsCfg,
+		SASL:        mech,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package kafka
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to messages forwarded to the dead letter topic.
+const (
+	HeaderDLQError     = "x-dlq-error"
+	HeaderDLQTopic     = "x-dlq-topic"
+	HeaderDLQPartition = "x-dlq-partition"
+	HeaderDLQOffset    = "x-dlq-offset"
+)
+
+// Handler processes a single message. Returning nil commits its offset.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+type Consumer interface {
+	// Run fetches messages and passes them to handler until ctx is cancelled.
+	Run(ctx context.Context, handler Handler) error
+	Close() error
+}
+
+type ConsumerConfig struct {
+	Topics         []string      `koanf:"topics" json:"topics" envconfig:"topics"`
+	GroupID        string        `koanf:"group_id" json:"group_id" envconfig:"group_id"`
+	StartOffset    string        `koanf:"start_offset" json:"start_offset" envconfig:"start_offset"`
+	SessionTimeout time.Duration `koanf:"session_timeout" json:"session_timeout" envconfig:"session_timeout"`
+	MaxWait        time.Duration `koanf:"max_wait" json:"max_wait" envconfig:"max_wait"`
+}
+
+type reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type ConsumerOption func(*consumer)
+
+type consumer struct {
+	r   reader
+	dlq Producer
+	log *slog.Logger
+}
+
+func (c *ConsumerConfig) Validate() error {
+	if c == nil {
+		return errors.New("kafka consumer configuration required")
+	}
+	if len(c.Topics) == 0 {
+		return errors.New("kafka consumer topics required")
+	}
+	if c.GroupID == "" {
+		return errors.New("kafka consumer group id required")
+	}
+	switch c.StartOffset {
+	case "", "earliest", "latest":
+	default:
+		return errors.Errorf("invalid start offset %q", c.StartOffset)
+	}
+	return nil
+}
+
+// WithDeadLetter forwards messages whose handler fails to p and commits them,
+// instead of stopping the consumer.
+func WithDeadLetter(p Producer) ConsumerOption {
+	return func(c *consumer) {
+		c.dlq = p
+	}
+}
+
+// NewConsumer joins cfg.GroupID and consumes ccfg.Topics. Offsets are
+// committed synchronously after each handled message, so a rebalance never
+// loses or replays more than the message in flight.
+func NewConsumer(ctx context.Context, cfg *Config, ccfg *ConsumerConfig, log *slog.Logger, opts ...ConsumerOption) (Consumer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ccfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	dialer, err := cfg.dialer()
+	if err != nil {
+		return nil, err
+	}
+
+	startOffset := kafka.FirstOffset
+	if ccfg.StartOffset == "latest" {
+		startOffset = kafka.LastOffset
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        cfg.Brokers,
+		GroupID:        ccfg.GroupID,
+		GroupTopics:    ccfg.Topics,
+		Dialer:         dialer,
+		StartOffset:    startOffset,
+		SessionTimeout: ccfg.SessionTimeout,
+		MaxWait:        ccfg.MaxWait,
+	})
+
+	log.Info("kafka consumer created", "brokers", cfg.Brokers, "group", ccfg.GroupID, "topics", ccfg.Topics)
+
+	return newConsumer(r, log, opts...), nil
+}
+
+func newConsumer(r reader, log *slog.Logger, opts ...ConsumerOption) *consumer {
+	c := &consumer{r: r, log: log}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run returns nil when ctx is cancelled. Without a dead letter producer a
+// handler error is returned and the message stays uncommitted, so it is
+// redelivered when the group next rebalances or the consumer restarts.
+func (c *consumer) Run(ctx context.Context, handler Handler) error {
+	for {
+		msg, err := c.r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "fetching kafka message")
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			if c.dlq == nil {
+				return errors.Wrapf(err, "handling message %s/%d@%d", msg.Topic, msg.Partition, msg.Offset)
+			}
+			if err := c.deadLetter(ctx, msg, err); err != nil {
+				return err
+			}
+		}
+
+		if err := c.r.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.Wrap(err, "committing kafka offset")
+		}
+	}
+}
+
+func (c *consumer) deadLetter(ctx context.Context, msg kafka.Message, cause error) error {
+	c.log.Warn("forwarding kafka message to dead letter topic",
+		"topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "error", cause)
+
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+
+	err := c.dlq.Publish(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers})
+	return errors.Wrap(err, "publishing to dead letter topic")
+}
+
+// Close leaves the consumer group so its partitions are reassigned promptly.
+func (c *consumer) Close() error {
+	return errors.WithStack(c.r.Close())
+}
+
+// TypedHandler decodes each message with codec before calling fn.
+func TypedHandler[T any](codec Codec[T], fn func(ctx context.Context, key []byte, v T) error) Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		v, err := codec.Decode(msg.Value)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, msg.Key, v)
+	}
+}
@@ -0,0 +1,208 @@
+package kafka
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeReader struct {
+	msgs      []kafka.Message
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(r.msgs) == 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	msg := r.msgs[0]
+	r.msgs = r.msgs[1:]
+	return msg, nil
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		r.committed = append(r.committed, m.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+type fakeProducer struct {
+	msgs []kafka.Message
+}
+
+func (p *fakeProducer) Publish(_ context.Context, msgs ...kafka.Message) error {
+	p.msgs = append(p.msgs, msgs...)
+	return nil
+}
+
+func (p *fakeProducer) Close() error { return nil }
+
+type reading struct {
+	DERID string  `json:"der_id" avro:"der_id"`
+	Power float64 `json:"power_kw" avro:"power_kw"`
+}
+
+const readingSchema = `{
+	"type": "record",
+	"name": "Reading",
+	"fields": [
+		{"name": "der_id", "type": "string"},
+		{"name": "power_kw", "type": "double"}
+	]
+}`
+
+type KafkaTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+}
+
+func (s *KafkaTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *KafkaTestSuite) messages() []kafka.Message {
+	return []kafka.Message{
+		{Topic: "readings", Offset: 1, Value: []byte(`{"der_id":"a","power_kw":1.5}`)},
+		{Topic: "readings", Offset: 2, Value: []byte(`not json`)},
+		{Topic: "readings", Offset: 3, Value: []byte(`{"der_id":"b","power_kw":2}`)},
+	}
+}
+
+func (s *KafkaTestSuite) TestRunWithDeadLetter() {
+	r := &fakeReader{msgs: s.messages()}
+	dlq := &fakeProducer{}
+	c := newConsumer(r, s.log, WithDeadLetter(dlq))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var got []reading
+	err := c.Run(ctx, TypedHandler[reading](JSONCodec[reading]{}, func(_ context.Context, _ []byte, v reading) error {
+		got = append(got, v)
+		if len(got) == 2 {
+			cancel()
+		}
+		return nil
+	}))
+	s.NoError(err)
+	s.Equal([]reading{{DERID: "a", Power: 1.5}, {DERID: "b", Power: 2}}, got)
+	s.Equal([]int64{1, 2, 3}, r.committed)
+	s.Require().Len(dlq.msgs, 1)
+	s.Equal([]byte("not json"), dlq.msgs[0].Value)
+
+	headers := map[string]string{}
+	for _, h := range dlq.msgs[0].Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	s.Equal("readings", headers[HeaderDLQTopic])
+	s.Equal("2", headers[HeaderDLQOffset])
+	s.NotEmpty(headers[HeaderDLQError])
+}
+
+func (s *KafkaTestSuite) TestRunWithoutDeadLetter() {
+	r := &fakeReader{msgs: s.messages()}
+	c := newConsumer(r, s.log)
+
+	err := c.Run(context.Background(), func(_ context.Context, msg kafka.Message) error {
+		if msg.Offset == 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	s.Error(err)
+	s.Equal([]int64{1}, r.committed)
+}
+
+func (s *KafkaTestSuite) TestCodecs() {
+	avroCodec, err := NewAvroCodec[reading](readingSchema)
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name  string
+		codec Codec[reading]
+	}{
+		{name: "json", codec: JSONCodec[reading]{}},
+		{name: "avro", codec: avroCodec},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			in := reading{DERID: "der-1", Power: 3.25}
+			data, err := tc.codec.Encode(in)
+			s.Require().NoError(err)
+			out, err := tc.codec.Decode(data)
+			s.NoError(err)
+			s.Equal(in, out)
+		})
+	}
+}
+
+func (s *KafkaTestSuite) TestPublish() {
+	p := &fakeProducer{}
+	err := Publish(context.Background(), p, JSONCodec[reading]{}, []byte("der-1"), reading{DERID: "der-1", Power: 1})
+	s.NoError(err)
+	s.Require().Len(p.msgs, 1)
+	s.Equal([]byte("der-1"), p.msgs[0].Key)
+	s.JSONEq(`{"der_id":"der-1","power_kw":1}`, string(p.msgs[0].Value))
+}
+
+func (s *KafkaTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Brokers: []string{"localhost:9092"}}},
+		{name: "valid sasl", cfg: &Config{Brokers: []string{"localhost:9092"}, SASL: &SASLConfig{Mechanism: SASLScramSHA512, Username: "u", Password: "p"}}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing brokers", cfg: &Config{}, expectError: true},
+		{name: "unknown sasl mechanism", cfg: &Config{Brokers: []string{"localhost:9092"}, SASL: &SASLConfig{Mechanism: "gssapi", Username: "u", Password: "p"}}, expectError: true},
+		{name: "sasl missing password", cfg: &Config{Brokers: []string{"localhost:9092"}, SASL: &SASLConfig{Mechanism: SASLPlain, Username: "u"}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *KafkaTestSuite) TestConsumerConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *ConsumerConfig
+		expectError bool
+	}{
+		{name: "valid", cfg: &ConsumerConfig{Topics: []string{"readings"}, GroupID: "ingest"}},
+		{name: "missing topics", cfg: &ConsumerConfig{GroupID: "ingest"}, expectError: true},
+		{name: "missing group", cfg: &ConsumerConfig{Topics: []string{"readings"}}, expectError: true},
+		{name: "bad start offset", cfg: &ConsumerConfig{Topics: []string{"readings"}, GroupID: "ingest", StartOffset: "middle"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestKafkaSuite(t *testing.T) {
+	suite.Run(t, new(KafkaTestSuite))
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+)
+
+type Producer interface {
+	Publish(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type ProducerConfig struct {
+	Topic string `koanf:"topic" json:"topic" envconfig:"topic"`
+	// Balancer selects partitions; messages with the same key always land on
+	// the same partition. Defaults to hashing the key.
+	Balancer kafka.Balancer `koanf:"-" json:"-" envconfig:"-"`
+}
+
+type writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type producer struct {
+	w   writer
+	log *slog.Logger
+}
+
+func (c *ProducerConfig) Validate() error {
+	if c == nil {
+		return errors.New("kafka producer configuration required")
+	}
+	if c.Topic == "" {
+		return errors.New("kafka producer topic required")
+	}
+	return nil
+}
+
+func NewProducer(ctx context.Context, cfg *Config, pcfg *ProducerConfig, log *slog.Logger) (Producer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := pcfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	transport, err := cfg.transport()
+	if err != nil {
+		return nil, err
+	}
+
+	balancer := pcfg.Balancer
+	if balancer == nil {
+		balancer = &kafka.Hash{}
+	}
+
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Brokers...),
+		Topic:        pcfg.Topic,
+		Balancer:     balancer,
+		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
+	}
+
+	log.Info("kafka producer created", "brokers", cfg.Brokers, "topic", pcfg.Topic)
+
+	return &producer{w: w, log: log}, nil
+}
+
+func (p *producer) Publish(ctx context.Context, msgs ...kafka.Message) error {
+	if err := p.w.WriteMessages(ctx, msgs...); err != nil {
+		return errors.Wrap(err, "writing kafka messages")
+	}
+	return nil
+}
+
+func (p *producer) Close() error {
+	return errors.WithStack(p.w.Close())
+}
+
+// Publish encodes v with codec and publishes it under key.
+func Publish[T any](ctx context.Context, p Producer, codec Codec[T], key []byte, v T) error {
+	data, err := codec.Encode(v)
+	if err != nil {
+		return err
+	}
+	return p.Publish(ctx, kafka.Message{Key: key, Value: data})
+}
@@ -0,0 +1,233 @@
+// Package leader elects a single leader among replicas using a lease, so
+// singleton work such as the reconciliation scheduler can run replicated
+// without duplicate execution:
+//
+//	e, err := leader.New(&leader.Config{Name: "reconciler"}, leader.FirestoreStore(fs, "leases"), log)
+//	err = e.RunWhenLeader(ctx, sched.Run)
+//
+// The leader renews its lease every RenewInterval. If a renewal fails or
+// the lease is lost, the context passed to the leader's function is
+// cancelled and the replica goes back to campaigning. Another replica can
+// only take over once the lease expires, so the function must stop promptly
+// when its context is cancelled.
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/id"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewInterval = 5 * time.Second
+	DefaultRetryInterval = 2 * time.Second
+)
+
+type Config struct {
+	// Name identifies the election; replicas sharing a name compete for it.
+	Name string `koanf:"name" json:"name" envconfig:"name"`
+	// Identity names this replica. Defaults to the hostname, which is the
+	// pod name on Kubernetes, plus a random suffix.
+	Identity      string        `koanf:"identity" json:"identity" envconfig:"identity"`
+	LeaseDuration time.Duration `koanf:"lease_duration" json:"lease_duration" envconfig:"lease_duration"`
+	RenewInterval time.Duration `koanf:"renew_interval" json:"renew_interval" envconfig:"renew_interval"`
+	RetryInterval time.Duration `koanf:"retry_interval" json:"retry_interval" envconfig:"retry_interval"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("leader election configuration required")
+	}
+	if c.Name == "" {
+		return errors.New("leader election name required")
+	}
+	if c.LeaseDuration < 0 || c.RenewInterval < 0 || c.RetryInterval < 0 {
+		return errors.New("leader election durations must not be negative")
+	}
+	lease, renew := orDefault(c.LeaseDuration, DefaultLeaseDuration), orDefault(c.RenewInterval, DefaultRenewInterval)
+	if renew >= lease {
+		return errors.New("leader election renew interval must be shorter than the lease duration")
+	}
+	return nil
+}
+
+type Option func(*Elector)
+
+// WithMetrics exports a gauge that is 1 while this replica leads.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(e *Elector) {
+		g, err := m.NewGaugeVec("leader", "is_leader", "Whether this replica holds the leader lease.", "name")
+		if err != nil {
+			e.log.Warn("registering leader metrics", "error", err)
+			return
+		}
+		e.gauge = g.WithLabelValues(e.cfg.Name)
+	}
+}
+
+type Elector struct {
+	cfg    Config
+	store  Store
+	log    *slog.Logger
+	gauge  prometheus.Gauge
+	leader atomic.Bool
+}
+
+func New(cfg *Config, store Store, log *slog.Logger, opts ...Option) (*Elector, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	e := &Elector{cfg: *cfg, store: store}
+	e.cfg.LeaseDuration = orDefault(cfg.LeaseDuration, DefaultLeaseDuration)
+	e.cfg.RenewInterval = orDefault(cfg.RenewInterval, DefaultRenewInterval)
+	e.cfg.RetryInterval = orDefault(cfg.RetryInterval, DefaultRetryInterval)
+	if e.cfg.Identity == "" {
+		host, _ := os.Hostname()
+		e.cfg.Identity = host + "-" + id.NewULID()
+	}
+	e.log = log.With("election", e.cfg.Name, "identity", e.cfg.Identity)
+
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e, nil
+}
+
+// Identity returns the name this replica campaigns under.
+func (e *Elector) Identity() string {
+	return e.cfg.Identity
+}
+
+// IsLeader reports whether this replica currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// RunWhenLeader campaigns for leadership and runs fn while it is held. If
+// leadership is lost, fn's context is cancelled and campaigning resumes once
+// fn returns. RunWhenLeader returns when ctx is cancelled, or when fn returns
+// on its own while still leader, with fn's error. The lease is released on
+// return so another replica can take over immediately.
+func (e *Elector) RunWhenLeader(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		if err := e.campaign(ctx); err != nil {
+			return nil
+		}
+
+		done, err := e.lead(ctx, fn)
+		if done {
+			return err
+		}
+	}
+}
+
+// campaign blocks until the lease is acquired or ctx is cancelled.
+func (e *Elector) campaign(ctx context.Context) error {
+	for {
+		ok, err := e.store.TryAcquire(ctx, e.cfg.Name, e.cfg.Identity, e.cfg.LeaseDuration)
+		if err != nil && ctx.Err() == nil {
+			e.log.Warn("leader election attempt failed", "error", err)
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(e.cfg.RetryInterval):
+		}
+	}
+}
+
+// lead runs fn while renewing the lease. It reports whether RunWhenLeader
+// should return.
+func (e *Elector) lead(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	e.setLeader(true)
+	e.log.Info("acquired leadership")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() { result <- fn(leaderCtx) }()
+
+	ticker := time.NewTicker(e.cfg.RenewInterval)
+	defer ticker.Stop()
+	renewed := time.Now()
+
+	for {
+		select {
+		case err := <-result:
+			e.resign()
+			return true, err
+		case <-ctx.Done():
+			cancel()
+			err := <-result
+			e.resign()
+			if errors.Is(err, context.Canceled) {
+				err = nil
+			}
+			return true, err
+		case <-ticker.C:
+			ok, err := e.store.TryAcquire(ctx, e.cfg.Name, e.cfg.Identity, e.cfg.LeaseDuration)
+			if ok {
+				renewed = time.Now()
+				continue
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+			// A failed renewal keeps leadership while the lease cannot have
+			// expired before the next attempt.
+			if err != nil && time.Since(renewed)+e.cfg.RenewInterval < e.cfg.LeaseDuration {
+				e.log.Warn("renewing leadership failed, retrying", "error", err)
+				continue
+			}
+			e.log.Warn("lost leadership", "error", err)
+			cancel()
+			<-result
+			e.setLeader(false)
+			return false, nil
+		}
+	}
+}
+
+func (e *Elector) resign() {
+	e.setLeader(false)
+	// The run context may already be cancelled, so release with a fresh one.
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.RenewInterval)
+	defer cancel()
+	if err := e.store.Release(ctx, e.cfg.Name, e.cfg.Identity); err != nil {
+		e.log.Warn("releasing leadership", "error", err)
+		return
+	}
+	e.log.Info("released leadership")
+}
+
+func (e *Elector) setLeader(v bool) {
+	e.leader.Store(v)
+	if e.gauge != nil {
+		if v {
+			e.gauge.Set(1)
+		} else {
+			e.gauge.Set(0)
+		}
+	}
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,201 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type LeaderTestSuite struct {
+	suite.Suite
+	store *MemoryStore
+}
+
+func (s *LeaderTestSuite) SetupTest() {
+	s.store = NewMemoryStore()
+}
+
+func (s *LeaderTestSuite) elector(identity string, opts ...Option) *Elector {
+	e, err := New(&Config{
+		Name:          "reconciler",
+		Identity:      identity,
+		LeaseDuration: 100 * time.Millisecond,
+		RenewInterval: 20 * time.Millisecond,
+		RetryInterval: 10 * time.Millisecond,
+	}, s.store, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return e
+}
+
+func (s *LeaderTestSuite) TestAcquire() {
+	now := time.Now()
+	testCases := []struct {
+		name string
+		cur  *Lease
+		ok   bool
+	}{
+		{name: "free", cur: nil, ok: true},
+		{name: "held by other", cur: &Lease{Holder: "b", ExpiresAt: now.Add(time.Second)}, ok: false},
+		{name: "expired", cur: &Lease{Holder: "b", ExpiresAt: now.Add(-time.Second)}, ok: true},
+		{name: "renew", cur: &Lease{Holder: "a", AcquiredAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Second)}, ok: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			next, ok := acquire(tc.cur, "a", now, time.Minute)
+			s.Equal(tc.ok, ok)
+			if ok {
+				s.Equal("a", next.Holder)
+				s.Equal(now.Add(time.Minute), next.ExpiresAt)
+			}
+		})
+	}
+
+	next, _ := acquire(testCases[3].cur, "a", now, time.Minute)
+	s.Equal(now.Add(-time.Hour), next.AcquiredAt)
+}
+
+func (s *LeaderTestSuite) TestSingleLeader() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var running, maxRunning atomic.Int32
+	work := func(ctx context.Context) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-ctx.Done()
+		running.Add(-1)
+		return ctx.Err()
+	}
+
+	electors := []*Elector{s.elector("a"), s.elector("b"), s.elector("c")}
+	var wg sync.WaitGroup
+	for _, e := range electors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.NoError(e.RunWhenLeader(ctx, work))
+		}()
+	}
+
+	s.Eventually(func() bool { return running.Load() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	leaders := 0
+	for _, e := range electors {
+		if e.IsLeader() {
+			leaders++
+			s.Equal(e.Identity(), s.store.Holder("reconciler"))
+		}
+	}
+	s.Equal(1, leaders)
+
+	cancel()
+	wg.Wait()
+	s.Equal(int32(1), maxRunning.Load())
+	s.Empty(s.store.Holder("reconciler"))
+}
+
+func (s *LeaderTestSuite) TestFailover() {
+	first, firstCancel := context.WithCancel(context.Background())
+	defer firstCancel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, b := s.elector("a"), s.elector("b")
+	aDone := make(chan error, 1)
+	go func() { aDone <- a.RunWhenLeader(first, func(ctx context.Context) error { <-ctx.Done(); return nil }) }()
+	s.Eventually(a.IsLeader, time.Second, 5*time.Millisecond)
+
+	bLed := make(chan struct{})
+	go func() {
+		_ = b.RunWhenLeader(ctx, func(ctx context.Context) error { close(bLed); <-ctx.Done(); return nil })
+	}()
+
+	firstCancel()
+	s.NoError(<-aDone)
+	select {
+	case <-bLed:
+	case <-time.After(time.Second):
+		s.Fail("b did not take over")
+	}
+}
+
+func (s *LeaderTestSuite) TestLostLease() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "leader"})
+	s.Require().NoError(err)
+	e := s.elector("a", WithMetrics(m))
+
+	var runs atomic.Int32
+	go func() {
+		_ = e.RunWhenLeader(ctx, func(ctx context.Context) error {
+			runs.Add(1)
+			<-ctx.Done()
+			return nil
+		})
+	}()
+	s.Eventually(e.IsLeader, time.Second, 5*time.Millisecond)
+	s.Equal(float64(1), testutil.ToFloat64(e.gauge))
+
+	// Another replica steals the lease, for example after a long pause.
+	s.store.mu.Lock()
+	s.store.leases["reconciler"] = &Lease{Holder: "z", ExpiresAt: time.Now().Add(50 * time.Millisecond)}
+	s.store.mu.Unlock()
+
+	s.Eventually(func() bool { return runs.Load() == 2 }, 2*time.Second, 5*time.Millisecond)
+}
+
+func (s *LeaderTestSuite) TestFnReturns() {
+	e := s.elector("a")
+	err := e.RunWhenLeader(context.Background(), func(ctx context.Context) error {
+		return errors.New("reconcile failed")
+	})
+	s.EqualError(err, "reconcile failed")
+	s.False(e.IsLeader())
+	s.Empty(s.store.Holder("reconciler"))
+}
+
+func (s *LeaderTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Name: "reconciler"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing name", cfg: &Config{}, expectError: true},
+		{name: "renew not shorter than lease", cfg: &Config{Name: "r", LeaseDuration: time.Second, RenewInterval: time.Second}, expectError: true},
+		{name: "negative", cfg: &Config{Name: "r", RetryInterval: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestLeaderSuite(t *testing.T) {
+	suite.Run(t, new(LeaderTestSuite))
+}
@@ -0,0 +1,129 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gsfirestore "github.com/grid-stream-org/go-commons/pkg/firestore"
+	"github.com/pkg/errors"
+)
+
+// Lease is the record of who holds leadership for a name.
+type Lease struct {
+	Holder     string    `firestore:"holder" json:"holder"`
+	AcquiredAt time.Time `firestore:"acquired_at" json:"acquired_at"`
+	ExpiresAt  time.Time `firestore:"expires_at" json:"expires_at"`
+}
+
+// Store persists leases. TryAcquire must atomically take the lease for
+// holder if it is free, expired or already held by holder, extending it to
+// now+ttl, and report whether holder now holds it.
+type Store interface {
+	TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, holder string) error
+}
+
+// acquire applies the lease rules to the current lease, which may be nil.
+func acquire(cur *Lease, holder string, now time.Time, ttl time.Duration) (*Lease, bool) {
+	if cur != nil && cur.Holder != holder && now.Before(cur.ExpiresAt) {
+		return cur, false
+	}
+	next := &Lease{Holder: holder, AcquiredAt: now, ExpiresAt: now.Add(ttl)}
+	if cur != nil && cur.Holder == holder {
+		next.AcquiredAt = cur.AcquiredAt
+	}
+	return next, true
+}
+
+type firestoreStore struct {
+	client gsfirestore.FirestoreClient
+	leases *gsfirestore.Collection[Lease]
+}
+
+// FirestoreStore keeps one lease document per name in collection, updated in
+// transactions. Expiry uses each replica's clock, so keep the lease duration
+// well above the expected clock skew.
+func FirestoreStore(c gsfirestore.FirestoreClient, collection string) Store {
+	return &firestoreStore{client: c, leases: gsfirestore.NewCollection[Lease](c, collection)}
+}
+
+func (s *firestoreStore) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	var ok bool
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cur, err := s.leases.GetTx(tx, name)
+		if err != nil && !errors.Is(err, gsfirestore.ErrNotFound) {
+			return err
+		}
+
+		var next *Lease
+		next, ok = acquire(cur, holder, time.Now(), ttl)
+		if !ok {
+			return nil
+		}
+		return s.leases.SetTx(tx, name, next)
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "acquiring lease %s", name)
+	}
+	return ok, nil
+}
+
+func (s *firestoreStore) Release(ctx context.Context, name, holder string) error {
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cur, err := s.leases.GetTx(tx, name)
+		if errors.Is(err, gsfirestore.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if cur.Holder != holder {
+			return nil
+		}
+		return errors.WithStack(tx.Delete(s.leases.Ref().Doc(name)))
+	})
+	return errors.Wrapf(err, "releasing lease %s", name)
+}
+
+// MemoryStore holds leases in memory. It coordinates electors within one
+// process, which is useful in tests and local development.
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]*Lease
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{leases: map[string]*Lease{}}
+}
+
+func (s *MemoryStore) TryAcquire(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, ok := acquire(s.leases[name], holder, time.Now(), ttl)
+	s.leases[name] = next
+	return ok, nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.leases[name]; ok && cur.Holder == holder {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+// Holder returns the current, unexpired holder of name.
+func (s *MemoryStore) Holder(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.leases[name]; ok && time.Now().Before(cur.ExpiresAt) {
+		return cur.Holder
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+package lock
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	gsfirestore "github.com/grid-stream-org/go-commons/pkg/firestore"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// record is the stored state of a lock. It is kept after release so the
+// fencing token keeps increasing.
+type record struct {
+	Owner     string    `firestore:"owner"`
+	Token     int64     `firestore:"token"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+func (r *record) held(now time.Time) bool {
+	return r != nil && r.Owner != "" && now.Before(r.ExpiresAt)
+}
+
+type firestoreBackend struct {
+	client gsfirestore.FirestoreClient
+	locks  *gsfirestore.Collection[record]
+}
+
+// Firestore stores one document per lock name in collection.
+func Firestore(c gsfirestore.FirestoreClient, collection string) Backend {
+	return &firestoreBackend{client: c, locks: gsfirestore.NewCollection[record](c, collection)}
+}
+
+func (b *firestoreBackend) update(ctx context.Context, name string, fn func(cur *record) (*record, error)) error {
+	return b.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		cur, err := b.locks.GetTx(tx, name)
+		if err != nil && !errors.Is(err, gsfirestore.ErrNotFound) {
+			return err
+		}
+		next, err := fn(cur)
+		if err != nil || next == nil {
+			return err
+		}
+		return b.locks.SetTx(tx, name, next)
+	})
+}
+
+func (b *firestoreBackend) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (uint64, error) {
+	var token int64
+	err := b.update(ctx, name, func(cur *record) (*record, error) {
+		now := time.Now()
+		if cur.held(now) {
+			return nil, errors.Wrapf(ErrLocked, "held by %s", cur.Owner)
+		}
+		if cur != nil {
+			token = cur.Token
+		}
+		token++
+		return &record{Owner: owner, Token: token, ExpiresAt: now.Add(ttl)}, nil
+	})
+	return uint64(token), err
+}
+
+func (b *firestoreBackend) Renew(ctx context.Context, name string, token uint64, ttl time.Duration) error {
+	return b.update(ctx, name, func(cur *record) (*record, error) {
+		if cur == nil || cur.Owner == "" || uint64(cur.Token) != token {
+			return nil, ErrLost
+		}
+		cur.ExpiresAt = time.Now().Add(ttl)
+		return cur, nil
+	})
+}
+
+func (b *firestoreBackend) Release(ctx context.Context, name string, token uint64) error {
+	return b.update(ctx, name, func(cur *record) (*record, error) {
+		if cur == nil || cur.Owner == "" || uint64(cur.Token) != token {
+			return nil, ErrLost
+		}
+		return &record{Token: cur.Token, ExpiresAt: time.Now()}, nil
+	})
+}
+
+var (
+	redisAcquire = redis.NewScript(`
+local owner = redis.call('HGET', KEYS[1], 'owner')
+if owner then
+  return {0, owner}
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('HSET', KEYS[1], 'owner', ARGV[1], 'token', token)
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return {token, ARGV[1]}
+`)
+	redisRenew = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'token') == ARGV[1] then
+  return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+	redisRelease = redis.NewScript(`
+if redis.call('HGET', KEYS[1], 'token') == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+type redisBackend struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// Redis stores locks as expiring hashes under prefix+"lock:"+name, with the
+// fencing counter in a separate persistent key.
+func Redis(client redis.UniversalClient, prefix string) Backend {
+	return &redisBackend{client: client, prefix: prefix}
+}
+
+func (b *redisBackend) keys(name string) []string {
+	key := b.prefix + "lock:" + name
+	return []string{key, key + ":fence"}
+}
+
+func (b *redisBackend) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (uint64, error) {
+	res, err := redisAcquire.Run(ctx, b.client, b.keys(name), owner, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	token, _ := res[0].(int64)
+	if token == 0 {
+		return 0, errors.Wrapf(ErrLocked, "held by %v", res[1])
+	}
+	return uint64(token), nil
+}
+
+func (b *redisBackend) Renew(ctx context.Context, name string, token uint64, ttl time.Duration) error {
+	n, err := redisRenew.Run(ctx, b.client, b.keys(name)[:1], strconv.FormatUint(token, 10), ttl.Milliseconds()).Int()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+func (b *redisBackend) Release(ctx context.Context, name string, token uint64) error {
+	n, err := redisRelease.Run(ctx, b.client, b.keys(name)[:1], strconv.FormatUint(token, 10)).Int()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if n == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+// MemoryBackend holds locks in memory, coordinating lockers within one
+// process. It is intended for tests.
+type MemoryBackend struct {
+	mu    sync.Mutex
+	locks map[string]*record
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{locks: map[string]*record{}}
+}
+
+func (b *MemoryBackend) Acquire(_ context.Context, name, owner string, ttl time.Duration) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	cur := b.locks[name]
+	if cur.held(now) {
+		return 0, errors.Wrapf(ErrLocked, "held by %s", cur.Owner)
+	}
+	next := &record{Owner: owner, Token: 1, ExpiresAt: now.Add(ttl)}
+	if cur != nil {
+		next.Token = cur.Token + 1
+	}
+	b.locks[name] = next
+	return uint64(next.Token), nil
+}
+
+func (b *MemoryBackend) Renew(_ context.Context, name string, token uint64, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cur := b.locks[name]
+	if cur == nil || cur.Owner == "" || uint64(cur.Token) != token {
+		return ErrLost
+	}
+	cur.ExpiresAt = time.Now().Add(ttl)
+	return nil
+}
+
+func (b *MemoryBackend) Release(_ context.Context, name string, token uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cur := b.locks[name]
+	if cur == nil || cur.Owner == "" || uint64(cur.Token) != token {
+		return ErrLost
+	}
+	cur.Owner = ""
+	return nil
+}
@@ -0,0 +1,257 @@
+// Package lock provides named distributed locks with fencing tokens, so jobs
+// such as der_data backfills cannot run concurrently from two places:
+//
+//	l, err := locker.Acquire(ctx, "backfill:der_data")
+//	if err != nil {
+//		return err
+//	}
+//	defer l.Release(context.Background())
+//	return backfill(l.Context(), l.Token())
+//
+// A lock is a lease with a TTL that is renewed in the background while it is
+// held. If renewal fails for longer than the TTL the lock is considered lost
+// and its Context is cancelled. Every acquisition gets a larger fencing token
+// than the previous one for the same name; pass it to the protected resource
+// and reject writes carrying a smaller token to guard against a holder that
+// paused past its lease.
+package lock
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/id"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTTL           = 30 * time.Second
+	DefaultRetryInterval = time.Second
+)
+
+var (
+	// ErrLocked is returned when the lock is held by someone else.
+	ErrLocked = errors.New("lock held by another owner")
+	// ErrLost is returned by a Backend when the lease being renewed or
+	// released is no longer held.
+	ErrLost = errors.New("lock lost")
+)
+
+// Backend stores leases. Implementations must assign fencing tokens that
+// increase with every successful Acquire of a name.
+type Backend interface {
+	// Acquire takes the lease for owner if it is free or expired and
+	// returns its fencing token. It returns ErrLocked otherwise.
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (uint64, error)
+	// Renew extends the lease identified by token, or returns ErrLost.
+	Renew(ctx context.Context, name string, token uint64, ttl time.Duration) error
+	// Release frees the lease identified by token if it is still held.
+	Release(ctx context.Context, name string, token uint64) error
+}
+
+type Config struct {
+	TTL           time.Duration `koanf:"ttl" json:"ttl" envconfig:"ttl"`
+	RetryInterval time.Duration `koanf:"retry_interval" json:"retry_interval" envconfig:"retry_interval"`
+	// Owner identifies this process in lock records. Defaults to
+	// user@hostname plus a random suffix.
+	Owner string `koanf:"owner" json:"owner" envconfig:"owner"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("lock configuration required")
+	}
+	if c.TTL < 0 || c.RetryInterval < 0 {
+		return errors.New("lock durations must not be negative")
+	}
+	return nil
+}
+
+type Locker struct {
+	cfg     Config
+	backend Backend
+	log     *slog.Logger
+}
+
+func New(cfg *Config, backend Backend, log *slog.Logger) (*Locker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	l := &Locker{cfg: *cfg, backend: backend, log: log}
+	if l.cfg.TTL == 0 {
+		l.cfg.TTL = DefaultTTL
+	}
+	if l.cfg.RetryInterval == 0 {
+		l.cfg.RetryInterval = DefaultRetryInterval
+	}
+	if l.cfg.Owner == "" {
+		l.cfg.Owner = defaultOwner()
+	}
+	return l, nil
+}
+
+// TryAcquire takes the lock once, returning an error wrapping ErrLocked if
+// it is held.
+func (l *Locker) TryAcquire(ctx context.Context, name string) (*Lock, error) {
+	token, err := l.backend.Acquire(ctx, name, l.cfg.Owner, l.cfg.TTL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "acquiring lock %s", name)
+	}
+	return l.hold(name, token), nil
+}
+
+// Acquire waits until the lock is taken or ctx is cancelled.
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lock, error) {
+	for {
+		lock, err := l.TryAcquire(ctx, name)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, ErrLocked) && ctx.Err() == nil {
+			l.log.Warn("acquiring lock failed, retrying", "lock", name, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "waiting for lock %s", name)
+		case <-time.After(l.cfg.RetryInterval):
+		}
+	}
+}
+
+// Do runs fn while holding the lock, waiting for it first. fn's context is
+// cancelled if the lock is lost.
+func (l *Locker) Do(ctx context.Context, name string, fn func(ctx context.Context, token uint64) error) error {
+	lock, err := l.Acquire(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), l.cfg.TTL)
+		defer cancel()
+		if err := lock.Release(releaseCtx); err != nil {
+			l.log.Warn("releasing lock", "lock", name, "error", err)
+		}
+	}()
+
+	runCtx, cancel := mergeCancel(ctx, lock.Context())
+	defer cancel()
+	return fn(runCtx, lock.Token())
+}
+
+func (l *Locker) hold(name string, token uint64) *Lock {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	lock := &Lock{
+		locker: l,
+		name:   name,
+		token:  token,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go lock.renew()
+	l.log.Debug("lock acquired", "lock", name, "owner", l.cfg.Owner, "token", token)
+	return lock
+}
+
+// Lock is a held lock.
+type Lock struct {
+	locker *Locker
+	name   string
+	token  uint64
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	once   sync.Once
+}
+
+// Token returns the fencing token of this acquisition.
+func (l *Lock) Token() uint64 {
+	return l.token
+}
+
+// Context is cancelled when the lock is released or lost. context.Cause
+// returns ErrLost in the latter case.
+func (l *Lock) Context() context.Context {
+	return l.ctx
+}
+
+// Release stops renewal and frees the lock. It is safe to call more than
+// once.
+func (l *Lock) Release(ctx context.Context) error {
+	var err error
+	l.once.Do(func() {
+		l.cancel(context.Canceled)
+		<-l.done
+		if context.Cause(l.ctx) == ErrLost {
+			return
+		}
+		err = l.locker.backend.Release(ctx, l.name, l.token)
+		if errors.Is(err, ErrLost) {
+			err = nil
+		}
+	})
+	return errors.Wrapf(err, "releasing lock %s", l.name)
+}
+
+func (l *Lock) renew() {
+	defer close(l.done)
+
+	ttl := l.locker.cfg.TTL
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(l.ctx, ttl/3)
+			err := l.locker.backend.Renew(ctx, l.name, l.token, ttl)
+			cancel()
+			if err == nil {
+				renewed = time.Now()
+				continue
+			}
+			if l.ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, ErrLost) && time.Since(renewed)+ttl/3 < ttl {
+				l.locker.log.Warn("renewing lock failed, retrying", "lock", l.name, "error", err)
+				continue
+			}
+			l.locker.log.Error("lock lost", "lock", l.name, "token", l.token, "error", err)
+			l.cancel(ErrLost)
+			return
+		}
+	}
+}
+
+// mergeCancel returns a context derived from parent that is also cancelled
+// when other is.
+func mergeCancel(parent, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := context.AfterFunc(other, func() {
+		cancel(context.Cause(other))
+	})
+	return ctx, func() {
+		stop()
+		cancel(context.Canceled)
+	}
+}
+
+func defaultOwner() string {
+	host, _ := os.Hostname()
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return fmt.Sprintf("%s@%s-%s", name, host, id.NewULID())
+}
@@ -0,0 +1,185 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockTestSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+}
+
+func (s *LockTestSuite) SetupTest() {
+	s.redis = miniredis.RunT(s.T())
+}
+
+func (s *LockTestSuite) backends() map[string]Backend {
+	client := redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.T().Cleanup(func() { _ = client.Close() })
+	return map[string]Backend{
+		"memory": NewMemoryBackend(),
+		"redis":  Redis(client, "test:"),
+	}
+}
+
+func (s *LockTestSuite) locker(backend Backend, owner string) *Locker {
+	l, err := New(&Config{TTL: 90 * time.Millisecond, RetryInterval: 10 * time.Millisecond, Owner: owner}, backend, logger.Default())
+	s.Require().NoError(err)
+	return l
+}
+
+func (s *LockTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "defaults", cfg: &Config{}, expectError: false},
+		{name: "negative ttl", cfg: &Config{TTL: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *LockTestSuite) TestBackend() {
+	ctx := context.Background()
+	for name, b := range s.backends() {
+		s.Run(name, func() {
+			first, err := b.Acquire(ctx, "backfill", "a", time.Minute)
+			s.Require().NoError(err)
+
+			_, err = b.Acquire(ctx, "backfill", "b", time.Minute)
+			s.ErrorIs(err, ErrLocked)
+			s.ErrorContains(err, "held by a")
+
+			other, err := b.Acquire(ctx, "other", "b", time.Minute)
+			s.Require().NoError(err)
+			s.NotZero(other)
+
+			s.NoError(b.Renew(ctx, "backfill", first, time.Minute))
+			s.NoError(b.Release(ctx, "backfill", first))
+			s.ErrorIs(b.Renew(ctx, "backfill", first, time.Minute), ErrLost)
+			s.ErrorIs(b.Release(ctx, "backfill", first), ErrLost)
+
+			second, err := b.Acquire(ctx, "backfill", "b", time.Minute)
+			s.Require().NoError(err)
+			s.Greater(second, first)
+			s.ErrorIs(b.Release(ctx, "backfill", first), ErrLost)
+		})
+	}
+}
+
+func (s *LockTestSuite) TestRedisExpiry() {
+	ctx := context.Background()
+	b := s.backends()["redis"]
+
+	first, err := b.Acquire(ctx, "backfill", "a", time.Second)
+	s.Require().NoError(err)
+
+	s.redis.FastForward(2 * time.Second)
+	second, err := b.Acquire(ctx, "backfill", "b", time.Second)
+	s.Require().NoError(err)
+	s.Greater(second, first)
+	s.ErrorIs(b.Renew(ctx, "backfill", first, time.Second), ErrLost)
+}
+
+func (s *LockTestSuite) TestAcquireWaits() {
+	backend := NewMemoryBackend()
+	a, b := s.locker(backend, "a"), s.locker(backend, "b")
+	ctx := context.Background()
+
+	held, err := a.Acquire(ctx, "backfill")
+	s.Require().NoError(err)
+
+	_, err = b.TryAcquire(ctx, "backfill")
+	s.ErrorIs(err, ErrLocked)
+
+	// Outlive several TTLs to check the lock is renewed.
+	time.Sleep(200 * time.Millisecond)
+	s.NoError(held.Context().Err())
+	_, err = b.TryAcquire(ctx, "backfill")
+	s.ErrorIs(err, ErrLocked)
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = held.Release(ctx)
+	}()
+	next, err := b.Acquire(ctx, "backfill")
+	s.Require().NoError(err)
+	s.Greater(next.Token(), held.Token())
+	s.ErrorIs(held.Context().Err(), context.Canceled)
+	s.NoError(held.Release(ctx))
+	s.NoError(next.Release(ctx))
+}
+
+func (s *LockTestSuite) TestAcquireCancelled() {
+	backend := NewMemoryBackend()
+	held, err := s.locker(backend, "a").TryAcquire(context.Background(), "backfill")
+	s.Require().NoError(err)
+	defer held.Release(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.locker(backend, "b").Acquire(ctx, "backfill")
+	s.ErrorIs(err, context.DeadlineExceeded)
+}
+
+func (s *LockTestSuite) TestLost() {
+	backend := NewMemoryBackend()
+	held, err := s.locker(backend, "a").TryAcquire(context.Background(), "backfill")
+	s.Require().NoError(err)
+
+	// Simulate another owner taking over after the lease expired.
+	backend.mu.Lock()
+	backend.locks["backfill"].Owner = ""
+	backend.mu.Unlock()
+	_, err = backend.Acquire(context.Background(), "backfill", "b", time.Minute)
+	s.Require().NoError(err)
+
+	select {
+	case <-held.Context().Done():
+	case <-time.After(time.Second):
+		s.FailNow("lock was not reported lost")
+	}
+	s.ErrorIs(context.Cause(held.Context()), ErrLost)
+	s.NoError(held.Release(context.Background()))
+}
+
+func (s *LockTestSuite) TestDo() {
+	backend := NewMemoryBackend()
+	l := s.locker(backend, "a")
+
+	var token uint64
+	err := l.Do(context.Background(), "backfill", func(ctx context.Context, t uint64) error {
+		token = t
+		return errors.New("boom")
+	})
+	s.EqualError(err, "boom")
+	s.NotZero(token)
+
+	// The lock is released afterwards.
+	_, err = s.locker(backend, "b").TryAcquire(context.Background(), "backfill")
+	s.NoError(err)
+}
+
+func TestLockTestSuite(t *testing.T) {
+	suite.Run(t, new(LockTestSuite))
+}
@@ -11,9 +11,9 @@ import (
 )
 
 type Config struct {
-	Level  string `envconfig:"level" json:"level"`
-	Format string `envconfig:"format" json:"format"`
-	Output string `envconfig:"output" json:"output"`
+	Level  string `koanf:"level" json:"level" envconfig:"level"`
+	Format string `koanf:"format" json:"format" envconfig:"format"`
+	Output string `koanf:"output" json:"output" envconfig:"output"`
 }
 
 var (
@@ -74,6 +74,19 @@ func (c *Config) SlogHandler(ow io.Writer) slog.Handler {
 	}
 }
 
+// ProfileDefaults implements config.Profiler: text logs at DEBUG in dev,
+// JSON everywhere else, at DEBUG in staging and INFO in prod.
+func (c *Config) ProfileDefaults(env string) map[string]any {
+	switch env {
+	case "dev":
+		return map[string]any{"level": "DEBUG", "format": "text"}
+	case "staging":
+		return map[string]any{"level": "DEBUG", "format": "json"}
+	default:
+		return map[string]any{"level": "INFO", "format": "json"}
+	}
+}
+
 func (c *Config) Validate() error {
 	if !slices.Contains([]string{"DEBUG", "INFO", "WARN", "ERROR"}, strings.ToUpper(c.Level)) {
 		return errors.Errorf("invalid log level: %s", c.Level)
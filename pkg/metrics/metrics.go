@@ -0,0 +1,150 @@
+// Package metrics bootstraps a Prometheus registry with the standard Go and
+// process collectors and constant service/env labels, and offers helpers for
+// registering instrumentation consistently across go-commons.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const Path = "/metrics"
+
+type Config struct {
+	Namespace string `koanf:"namespace" json:"namespace" envconfig:"namespace"`
+	Service   string `koanf:"service" json:"service" envconfig:"service"`
+	// Env defaults to config.Environment().
+	Env string `koanf:"env" json:"env" envconfig:"env"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("metrics configuration required")
+	}
+	if c.Namespace == "" {
+		return errors.New("metrics namespace required")
+	}
+	if c.Service == "" {
+		return errors.New("metrics service required")
+	}
+	return nil
+}
+
+// Metrics owns a registry. Every collector registered through it carries the
+// service and env labels.
+type Metrics struct {
+	cfg        *Config
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer
+}
+
+func New(cfg *Config) (*Metrics, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	labels := prometheus.Labels{"service": cfg.Service, "env": cfg.Env}
+	if cfg.Env == "" {
+		labels["env"] = config.Environment().String()
+	}
+
+	registry := prometheus.NewRegistry()
+	m := &Metrics{
+		cfg:        cfg,
+		registry:   registry,
+		registerer: prometheus.WrapRegistererWith(labels, registry),
+	}
+
+	if err := m.Register(collectors.NewGoCollector()); err != nil {
+		return nil, err
+	}
+	if err := m.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Registry exposes the underlying registry, for example to gather metrics in
+// tests.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Registerer returns the labelled registerer for libraries that accept a
+// prometheus.Registerer.
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registerer
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Register adds c to the registry. Registering an identical collector twice
+// is not an error.
+func (m *Metrics) Register(c prometheus.Collector) error {
+	if err := m.registerer.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+	return nil
+}
+
+// NewCounterVec creates and registers a counter under the configured
+// namespace, returning the existing one if it was already registered.
+func (m *Metrics) NewCounterVec(subsystem, name, help string, labels ...string) (*prometheus.CounterVec, error) {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.cfg.Namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	return registerOrExisting(m.registerer, c)
+}
+
+// NewGaugeVec creates and registers a gauge under the configured namespace.
+func (m *Metrics) NewGaugeVec(subsystem, name, help string, labels ...string) (*prometheus.GaugeVec, error) {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.cfg.Namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	return registerOrExisting(m.registerer, g)
+}
+
+// NewHistogramVec creates and registers a histogram under the configured
+// namespace. Nil buckets use prometheus.DefBuckets.
+func (m *Metrics) NewHistogramVec(subsystem, name, help string, buckets []float64, labels ...string) (*prometheus.HistogramVec, error) {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.cfg.Namespace,
+		Subsystem: subsystem,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, labels)
+	return registerOrExisting(m.registerer, h)
+}
+
+func registerOrExisting[C prometheus.Collector](r prometheus.Registerer, c C) (C, error) {
+	if err := r.Register(c); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		var zero C
+		return zero, errors.WithStack(err)
+	}
+	return c, nil
+}
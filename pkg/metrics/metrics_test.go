@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	m *Metrics
+}
+
+func (s *MetricsTestSuite) SetupTest() {
+	m, err := New(&Config{Namespace: "gridstream", Service: "aggregator", Env: "test"})
+	s.Require().NoError(err)
+	s.m = m
+}
+
+func (s *MetricsTestSuite) TestHandler() {
+	counter, err := s.m.NewCounterVec("bq", "rows_inserted_total", "Rows inserted.", "table")
+	s.Require().NoError(err)
+	counter.WithLabelValues("der_data").Add(3)
+
+	rec := httptest.NewRecorder()
+	s.m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+	s.Equal(http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	s.Require().NoError(err)
+	s.Contains(string(body), `gridstream_bq_rows_inserted_total{env="test",service="aggregator",table="der_data"} 3`)
+	s.Contains(string(body), "go_goroutines")
+	s.Contains(string(body), "process_start_time_seconds")
+}
+
+func (s *MetricsTestSuite) TestHelpersReturnExisting() {
+	a, err := s.m.NewHistogramVec("bq", "query_seconds", "Query latency.", nil, "op")
+	s.Require().NoError(err)
+	b, err := s.m.NewHistogramVec("bq", "query_seconds", "Query latency.", nil, "op")
+	s.Require().NoError(err)
+	s.Same(a, b)
+
+	g, err := s.m.NewGaugeVec("pool", "workers", "Active workers.")
+	s.Require().NoError(err)
+	g.WithLabelValues().Set(4)
+	s.Equal(float64(4), testutil.ToFloat64(g))
+
+	_, err = s.m.NewGaugeVec("bq", "query_seconds", "Conflicting type.", "op")
+	s.Error(err)
+}
+
+func (s *MetricsTestSuite) TestRegister() {
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "custom_total", Help: "Custom."})
+	s.NoError(s.m.Register(c))
+	s.NoError(s.m.Register(c))
+}
+
+func (s *MetricsTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{"valid", &Config{Namespace: "gridstream", Service: "api"}, false},
+		{"nil", nil, true},
+		{"missing namespace", &Config{Service: "api"}, true},
+		{"missing service", &Config{Namespace: "gridstream"}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
@@ -0,0 +1,191 @@
+// Package models defines the canonical grid-stream entities shared by every
+// service, with the tags needed to read and write them through bqclient,
+// serve them as JSON and load them from config files.
+//
+// Each model knows its BigQuery table and validates itself with the rules in
+// its validate tags:
+//
+//	ev := &models.DREvent{ID: id, UtilityID: "u1", StartTime: start, EndTime: end}
+//	if err := ev.Validate(); err != nil {
+//		return err
+//	}
+//	err := client.Put(ctx, ev.Table(), ev)
+//
+// Customer-identifying fields carry anonymize tags: project and DER IDs are
+// hashed, so anonymize.Copy output still joins across tables, and locations
+// are redacted.
+package models
+
+import (
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/validate"
+	"github.com/pkg/errors"
+)
+
+// BigQuery table names. bqclient only accepts these.
+const (
+	TableProjects        = "projects"
+	TableContracts       = "contracts"
+	TableDERMetadata     = "der_metadata"
+	TableDERData         = "der_data"
+	TableProjectAverages = "project_averages"
+	TableUtilities       = "utilities"
+	TableDREvents        = "dr_events"
+)
+
+// Tables lists every table backing a model.
+func Tables() []string {
+	return []string{
+		TableProjects,
+		TableContracts,
+		TableDERMetadata,
+		TableDERData,
+		TableProjectAverages,
+		TableUtilities,
+		TableDREvents,
+	}
+}
+
+// Model is implemented by every entity in this package.
+type Model interface {
+	Table() string
+	Validate() error
+}
+
+// Contract statuses.
+const (
+	ContractStatusPending  = "pending"
+	ContractStatusActive   = "active"
+	ContractStatusExpired  = "expired"
+	ContractStatusCanceled = "canceled"
+)
+
+// DER types.
+const (
+	DERTypeSolar   = "solar"
+	DERTypeBattery = "battery"
+	DERTypeEV      = "ev"
+	DERTypeWind    = "wind"
+	DERTypeHVAC    = "hvac"
+)
+
+// Utility is a grid operator that issues demand response events.
+type Utility struct {
+	ID          string `bigquery:"id" json:"id" koanf:"id" validate:"required"`
+	DisplayName string `bigquery:"display_name" json:"display_name" koanf:"display_name" validate:"required,max=256"`
+}
+
+func (u *Utility) Table() string   { return TableUtilities }
+func (u *Utility) Validate() error { return validate.Struct(u) }
+
+// Project is a customer site enrolled with a utility, grouping its DERs.
+type Project struct {
+	ID                string    `bigquery:"id" json:"id" koanf:"id" validate:"required" anonymize:"hash"`
+	UtilityID         string    `bigquery:"utility_id" json:"utility_id" koanf:"utility_id" validate:"required"`
+	UserID            string    `bigquery:"user_id" json:"user_id" koanf:"user_id" validate:"required" anonymize:"hash"`
+	Location          string    `bigquery:"location" json:"location" koanf:"location" validate:"max=512" anonymize:"redact"`
+	ConnectionStartAt time.Time `bigquery:"connection_start_at" json:"connection_start_at" koanf:"connection_start_at" validate:"required"`
+}
+
+func (p *Project) Table() string   { return TableProjects }
+func (p *Project) Validate() error { return validate.Struct(p) }
+
+// Contract commits a project to reduce load below ContractThreshold, in kW,
+// during demand response events between StartDate and EndDate.
+type Contract struct {
+	ID                string    `bigquery:"id" json:"id" koanf:"id" validate:"required"`
+	ProjectID         string    `bigquery:"project_id" json:"project_id" koanf:"project_id" validate:"required" anonymize:"hash"`
+	ContractThreshold float64   `bigquery:"contract_threshold" json:"contract_threshold" koanf:"contract_threshold" validate:"min=0"`
+	StartDate         time.Time `bigquery:"start_date" json:"start_date" koanf:"start_date" validate:"required"`
+	EndDate           time.Time `bigquery:"end_date" json:"end_date" koanf:"end_date" validate:"required"`
+	Status            string    `bigquery:"status" json:"status" koanf:"status" validate:"required,oneof=pending active expired canceled"`
+}
+
+func (c *Contract) Table() string { return TableContracts }
+
+func (c *Contract) Validate() error {
+	return validateRange(c, "end_date", c.StartDate, c.EndDate)
+}
+
+// Active reports whether the contract is active and t falls within its term.
+func (c *Contract) Active(t time.Time) bool {
+	return c.Status == ContractStatusActive && !t.Before(c.StartDate) && t.Before(c.EndDate)
+}
+
+// DERMetadata describes a distributed energy resource installed at a project.
+// Capacities are in kW.
+type DERMetadata struct {
+	ID                string  `bigquery:"id" json:"id" koanf:"id" validate:"required" anonymize:"hash"`
+	ProjectID         string  `bigquery:"project_id" json:"project_id" koanf:"project_id" validate:"required" anonymize:"hash"`
+	Type              string  `bigquery:"type" json:"type" koanf:"type" validate:"required,oneof=solar battery ev wind hvac"`
+	NameplateCapacity float64 `bigquery:"nameplate_capacity" json:"nameplate_capacity" koanf:"nameplate_capacity" validate:"min=0"`
+	PowerCapacity     float64 `bigquery:"power_capacity" json:"power_capacity" koanf:"power_capacity" validate:"min=0"`
+	IsStandalone      bool    `bigquery:"is_standalone" json:"is_standalone" koanf:"is_standalone"`
+}
+
+func (d *DERMetadata) Table() string   { return TableDERMetadata }
+func (d *DERMetadata) Validate() error { return validate.Struct(d) }
+
+// DERData is a single telemetry reading from a DER. Outputs are in kW and
+// CurrentSOC is the battery state of charge as a fraction.
+type DERData struct {
+	DERID                 string    `bigquery:"der_id" json:"der_id" koanf:"der_id" validate:"required" anonymize:"hash"`
+	ProjectID             string    `bigquery:"project_id" json:"project_id" koanf:"project_id" validate:"required" anonymize:"hash"`
+	Timestamp             time.Time `bigquery:"timestamp" json:"timestamp" koanf:"timestamp" validate:"required"`
+	CurrentOutput         float64   `bigquery:"current_output" json:"current_output" koanf:"current_output"`
+	PowerMeterMeasurement float64   `bigquery:"power_meter_measurement" json:"power_meter_measurement" koanf:"power_meter_measurement"`
+	Baseline              float64   `bigquery:"baseline" json:"baseline" koanf:"baseline"`
+	CurrentSOC            float64   `bigquery:"current_soc" json:"current_soc" koanf:"current_soc" validate:"min=0,max=1"`
+	IsOnline              bool      `bigquery:"is_online" json:"is_online" koanf:"is_online"`
+}
+
+func (d *DERData) Table() string   { return TableDERData }
+func (d *DERData) Validate() error { return validate.Struct(d) }
+
+// ProjectAverage is a project's average output over a window, as sent to the
+// validator. It mirrors the validator's AverageOutput message.
+type ProjectAverage struct {
+	ProjectID         string    `bigquery:"project_id" json:"project_id" koanf:"project_id" validate:"required" anonymize:"hash"`
+	ContractThreshold float64   `bigquery:"contract_threshold" json:"contract_threshold" koanf:"contract_threshold" validate:"min=0"`
+	Baseline          float64   `bigquery:"baseline" json:"baseline" koanf:"baseline"`
+	AverageOutput     float64   `bigquery:"average_output" json:"average_output" koanf:"average_output"`
+	StartTime         time.Time `bigquery:"start_time" json:"start_time" koanf:"start_time" validate:"required"`
+	EndTime           time.Time `bigquery:"end_time" json:"end_time" koanf:"end_time" validate:"required"`
+}
+
+func (p *ProjectAverage) Table() string { return TableProjectAverages }
+
+func (p *ProjectAverage) Validate() error {
+	return validateRange(p, "end_time", p.StartTime, p.EndTime)
+}
+
+// DREvent is a demand response event issued by a utility.
+type DREvent struct {
+	ID        string    `bigquery:"id" json:"id" koanf:"id" validate:"required"`
+	UtilityID string    `bigquery:"utility_id" json:"utility_id" koanf:"utility_id" validate:"required"`
+	StartTime time.Time `bigquery:"start_time" json:"start_time" koanf:"start_time" validate:"required"`
+	EndTime   time.Time `bigquery:"end_time" json:"end_time" koanf:"end_time" validate:"required"`
+}
+
+func (e *DREvent) Table() string { return TableDREvents }
+
+func (e *DREvent) Validate() error {
+	return validateRange(e, "end_time", e.StartTime, e.EndTime)
+}
+
+// Active reports whether t falls within the event.
+func (e *DREvent) Active(t time.Time) bool {
+	return !t.Before(e.StartTime) && t.Before(e.EndTime)
+}
+
+// validateRange runs the tag rules on v and also requires end to be after
+// start when both are set.
+func validateRange(v any, endPath string, start, end time.Time) error {
+	ve := &validate.Errors{}
+	ve.Add("", validate.Struct(v))
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		ve.Add(endPath, errors.New("must be after the start"))
+	}
+	return ve.Err()
+}
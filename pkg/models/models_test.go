@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/validate"
+	"github.com/stretchr/testify/suite"
+)
+
+type ModelsTestSuite struct {
+	suite.Suite
+	start time.Time
+	end   time.Time
+}
+
+func (s *ModelsTestSuite) SetupTest() {
+	s.start = time.Date(2025, 7, 14, 17, 0, 0, 0, time.UTC)
+	s.end = s.start.Add(2 * time.Hour)
+}
+
+func (s *ModelsTestSuite) TestValidate() {
+	testCases := []struct {
+		name        string
+		model       Model
+		expectPaths []string
+	}{
+		{name: "utility", model: &Utility{ID: "u1", DisplayName: "Ontario IESO"}},
+		{name: "utility missing name", model: &Utility{ID: "u1"}, expectPaths: []string{"display_name"}},
+		{name: "project", model: &Project{ID: "p1", UtilityID: "u1", UserID: "usr1", ConnectionStartAt: s.start}},
+		{name: "project missing fields", model: &Project{ID: "p1"}, expectPaths: []string{"utility_id", "user_id", "connection_start_at"}},
+		{name: "contract", model: &Contract{ID: "c1", ProjectID: "p1", ContractThreshold: 50, StartDate: s.start, EndDate: s.end, Status: ContractStatusActive}},
+		{name: "contract bad status and range", model: &Contract{ID: "c1", ProjectID: "p1", StartDate: s.end, EndDate: s.start, Status: "paused"}, expectPaths: []string{"status", "end_date"}},
+		{name: "der metadata", model: &DERMetadata{ID: "d1", ProjectID: "p1", Type: DERTypeBattery, NameplateCapacity: 13.5}},
+		{name: "der metadata bad type", model: &DERMetadata{ID: "d1", ProjectID: "p1", Type: "nuclear"}, expectPaths: []string{"type"}},
+		{name: "der data", model: &DERData{DERID: "d1", ProjectID: "p1", Timestamp: s.start, CurrentOutput: 4.2, CurrentSOC: 0.8}},
+		{name: "der data bad soc", model: &DERData{DERID: "d1", ProjectID: "p1", Timestamp: s.start, CurrentSOC: 80}, expectPaths: []string{"current_soc"}},
+		{name: "project average", model: &ProjectAverage{ProjectID: "p1", StartTime: s.start, EndTime: s.end}},
+		{name: "project average empty range", model: &ProjectAverage{ProjectID: "p1", StartTime: s.start, EndTime: s.start}, expectPaths: []string{"end_time"}},
+		{name: "dr event", model: &DREvent{ID: "e1", UtilityID: "u1", StartTime: s.start, EndTime: s.end}},
+		{name: "dr event missing end", model: &DREvent{ID: "e1", UtilityID: "u1", StartTime: s.start}, expectPaths: []string{"end_time"}},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.model.Validate()
+			if len(tc.expectPaths) == 0 {
+				s.NoError(err)
+				return
+			}
+
+			var ve *validate.Errors
+			s.Require().ErrorAs(err, &ve)
+			var paths []string
+			for _, fe := range ve.Errors {
+				paths = append(paths, fe.Path)
+			}
+			s.ElementsMatch(tc.expectPaths, paths)
+		})
+	}
+}
+
+func (s *ModelsTestSuite) TestTables() {
+	models := []Model{&Utility{}, &Project{}, &Contract{}, &DERMetadata{}, &DERData{}, &ProjectAverage{}, &DREvent{}}
+	var tables []string
+	for _, m := range models {
+		tables = append(tables, m.Table())
+	}
+	s.ElementsMatch(Tables(), tables)
+}
+
+func (s *ModelsTestSuite) TestActive() {
+	ev := &DREvent{StartTime: s.start, EndTime: s.end}
+	s.True(ev.Active(s.start))
+	s.False(ev.Active(s.end))
+
+	c := &Contract{StartDate: s.start, EndDate: s.end, Status: ContractStatusActive}
+	s.True(c.Active(s.start.Add(time.Minute)))
+	c.Status = ContractStatusExpired
+	s.False(c.Active(s.start.Add(time.Minute)))
+}
+
+func (s *ModelsTestSuite) TestJSON() {
+	b, err := json.Marshal(&DERData{DERID: "d1", ProjectID: "p1", Timestamp: s.start, CurrentOutput: 4.2})
+	s.Require().NoError(err)
+	s.JSONEq(`{
+		"der_id": "d1",
+		"project_id": "p1",
+		"timestamp": "2025-07-14T17:00:00Z",
+		"current_output": 4.2,
+		"power_meter_measurement": 0,
+		"baseline": 0,
+		"current_soc": 0,
+		"is_online": false
+	}`, string(b))
+}
+
+func TestModelsSuite(t *testing.T) {
+	suite.Run(t, new(ModelsTestSuite))
+}
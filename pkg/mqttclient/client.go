@@ -0,0 +1,298 @@
+package mqttclient
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultKeepAlive            = 30 * time.Second
+	DefaultConnectTimeout       = 10 * time.Second
+	DefaultMaxReconnectInterval = time.Minute
+	DefaultDisconnectQuiesce    = 250 * time.Millisecond
+)
+
+type MQTTClient interface {
+	Publish(ctx context.Context, topic string, qos byte, retained bool, payload []byte) error
+	Subscribe(ctx context.Context, topic string, qos byte, handler Handler) error
+	Unsubscribe(ctx context.Context, topics ...string) error
+	IsConnected() bool
+	Close() error
+}
+
+type Config struct {
+	Broker               string            `koanf:"broker" json:"broker" envconfig:"broker"`
+	ClientID             string            `koanf:"client_id" json:"client_id" envconfig:"client_id"`
+	Username             string            `koanf:"username" json:"username" envconfig:"username"`
+	Password             string            `koanf:"password" json:"password" envconfig:"password"`
+	CleanSession         bool              `koanf:"clean_session" json:"clean_session" envconfig:"clean_session"`
+	KeepAlive            time.Duration     `koanf:"keep_alive" json:"keep_alive" envconfig:"keep_alive"`
+	ConnectTimeout       time.Duration     `koanf:"connect_timeout" json:"connect_timeout" envconfig:"connect_timeout"`
+	MaxReconnectInterval time.Duration     `koanf:"max_reconnect_interval" json:"max_reconnect_interval" envconfig:"max_reconnect_interval"`
+	TLS                  *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+}
+
+// Message is an MQTT message delivered to a Handler.
+type Message struct {
+	Topic     string
+	Payload   []byte
+	QoS       byte
+	Retained  bool
+	Duplicate bool
+}
+
+// Handler processes a message. Returned errors are logged; MQTT has no
+// negative acknowledgement, so the message is not redelivered.
+type Handler func(ctx context.Context, msg *Message) error
+
+type Option func(*mqttClient)
+
+// WithTokenManager authenticates with a fresh Firebase custom token as the
+// password on every connect and reconnect, using Config.Username (or the
+// client ID when unset) as the username.
+func WithTokenManager(tm auth.TokenManager) Option {
+	return func(c *mqttClient) {
+		c.tokens = tm
+	}
+}
+
+type subscription struct {
+	qos     byte
+	handler Handler
+}
+
+type mqttClient struct {
+	cfg    *Config
+	client mqtt.Client
+	log    *slog.Logger
+	tokens auth.TokenManager
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.Mutex
+	subs map[string]subscription
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("mqtt configuration required")
+	}
+	if c.Broker == "" {
+		return errors.New("mqtt broker required")
+	}
+	if c.ClientID == "" {
+		return errors.New("mqtt client ID required")
+	}
+	if c.KeepAlive < 0 || c.ConnectTimeout < 0 || c.MaxReconnectInterval < 0 {
+		return errors.New("mqtt durations must not be negative")
+	}
+	return c.TLS.Validate()
+}
+
+// New connects to the broker, retrying with backoff until ctx is done. Once
+// connected, the client reconnects automatically and restores its
+// subscriptions.
+func New(ctx context.Context, cfg *Config, log *slog.Logger, opts ...Option) (MQTTClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	lifetime, cancel := context.WithCancel(context.Background())
+	c := &mqttClient{
+		cfg:    cfg,
+		log:    log,
+		ctx:    lifetime,
+		cancel: cancel,
+		subs:   map[string]subscription{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	clientOpts, err := c.clientOptions()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.client = mqtt.NewClient(clientOpts)
+
+	if err := wait(ctx, c.client.Connect()); err != nil {
+		c.client.Disconnect(0)
+		cancel()
+		return nil, errors.Wrapf(err, "connecting to mqtt broker %s", cfg.Broker)
+	}
+
+	log.Info("mqtt client connected", "broker", cfg.Broker, "clientID", cfg.ClientID)
+	return c, nil
+}
+
+func (c *mqttClient) clientOptions() (*mqtt.ClientOptions, error) {
+	o := mqtt.NewClientOptions().
+		AddBroker(c.cfg.Broker).
+		SetClientID(c.cfg.ClientID).
+		SetCleanSession(c.cfg.CleanSession).
+		SetKeepAlive(durationOr(c.cfg.KeepAlive, DefaultKeepAlive)).
+		SetConnectTimeout(durationOr(c.cfg.ConnectTimeout, DefaultConnectTimeout)).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(durationOr(c.cfg.MaxReconnectInterval, DefaultMaxReconnectInterval)).
+		SetOrderMatters(false).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			c.log.Warn("mqtt connection lost", "broker", c.cfg.Broker, "error", err)
+		}).
+		SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+			c.log.Info("mqtt reconnecting", "broker", c.cfg.Broker)
+		})
+
+	if c.tokens != nil {
+		o.SetCredentialsProvider(c.credentials)
+	} else if c.cfg.Username != "" {
+		o.SetUsername(c.cfg.Username).SetPassword(c.cfg.Password)
+	}
+
+	tlsCfg, err := c.cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		o.SetTLSConfig(tlsCfg)
+	}
+	return o, nil
+}
+
+func (c *mqttClient) credentials() (string, string) {
+	username := c.cfg.Username
+	if username == "" {
+		username = c.cfg.ClientID
+	}
+
+	token, err := c.tokens.GetToken()
+	if err != nil {
+		c.log.Error("failed to get mqtt auth token", "error", err)
+	}
+	return username, token
+}
+
+// onConnect restores subscriptions after a reconnect. Brokers drop them when
+// the session is clean or has expired.
+func (c *mqttClient) onConnect(client mqtt.Client) {
+	c.mu.Lock()
+	subs := make(map[string]subscription, len(c.subs))
+	for topic, sub := range c.subs {
+		subs[topic] = sub
+	}
+	c.mu.Unlock()
+
+	for topic, sub := range subs {
+		token := client.Subscribe(topic, sub.qos, c.messageHandler(sub.handler))
+		go func(topic string) {
+			if err := wait(c.ctx, token); err != nil {
+				c.log.Error("mqtt resubscribe failed", "topic", topic, "error", err)
+			}
+		}(topic)
+	}
+}
+
+func (c *mqttClient) Publish(ctx context.Context, topic string, qos byte, retained bool, payload []byte) error {
+	if err := wait(ctx, c.client.Publish(topic, qos, retained, payload)); err != nil {
+		return errors.Wrapf(err, "publishing to %s", topic)
+	}
+	return nil
+}
+
+func (c *mqttClient) Subscribe(ctx context.Context, topic string, qos byte, handler Handler) error {
+	if err := wait(ctx, c.client.Subscribe(topic, qos, c.messageHandler(handler))); err != nil {
+		return errors.Wrapf(err, "subscribing to %s", topic)
+	}
+
+	c.mu.Lock()
+	c.subs[topic] = subscription{qos: qos, handler: handler}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *mqttClient) Unsubscribe(ctx context.Context, topics ...string) error {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.subs, topic)
+	}
+	c.mu.Unlock()
+
+	if err := wait(ctx, c.client.Unsubscribe(topics...)); err != nil {
+		return errors.Wrapf(err, "unsubscribing from %v", topics)
+	}
+	return nil
+}
+
+func (c *mqttClient) IsConnected() bool {
+	return c.client.IsConnectionOpen()
+}
+
+// Close cancels handler contexts and disconnects, giving in-flight work a
+// short quiesce period.
+func (c *mqttClient) Close() error {
+	c.cancel()
+	c.client.Disconnect(uint(DefaultDisconnectQuiesce / time.Millisecond))
+	return nil
+}
+
+func (c *mqttClient) messageHandler(h Handler) mqtt.MessageHandler {
+	return func(_ mqtt.Client, m mqtt.Message) {
+		msg := &Message{
+			Topic:     m.Topic(),
+			Payload:   m.Payload(),
+			QoS:       m.Qos(),
+			Retained:  m.Retained(),
+			Duplicate: m.Duplicate(),
+		}
+		if err := h(c.ctx, msg); err != nil {
+			c.log.Error("mqtt message handler failed", "topic", msg.Topic, "error", err)
+		}
+	}
+}
+
+// PublishJSON encodes v as JSON and publishes it.
+func PublishJSON(ctx context.Context, c MQTTClient, topic string, qos byte, retained bool, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return c.Publish(ctx, topic, qos, retained, payload)
+}
+
+// JSONHandler adapts a typed callback into a Handler that decodes each
+// payload as JSON into a new T.
+func JSONHandler[T any](fn func(ctx context.Context, topic string, v *T) error) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		v := new(T)
+		if err := json.Unmarshal(msg.Payload, v); err != nil {
+			return errors.Wrapf(err, "decoding message on %s", msg.Topic)
+		}
+		return fn(ctx, msg.Topic, v)
+	}
+}
+
+func wait(ctx context.Context, token mqtt.Token) error {
+	select {
+	case <-token.Done():
+		return errors.WithStack(token.Error())
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func durationOr(d, fallback time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return fallback
+}
@@ -0,0 +1,196 @@
+package mqttclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeToken struct {
+	done chan struct{}
+	err  error
+}
+
+func newToken(err error) *fakeToken {
+	t := &fakeToken{done: make(chan struct{}), err: err}
+	close(t.done)
+	return t
+}
+
+func (t *fakeToken) Wait() bool                       { <-t.done; return true }
+func (t *fakeToken) WaitTimeout(d time.Duration) bool { return t.Wait() }
+func (t *fakeToken) Done() <-chan struct{}            { return t.done }
+func (t *fakeToken) Error() error                     { return t.err }
+
+type mockPahoClient struct {
+	mock.Mock
+	mqtt.Client
+	handlers map[string]mqtt.MessageHandler
+}
+
+func (m *mockPahoClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	args := m.Called(topic, qos, retained, payload)
+	return args.Get(0).(mqtt.Token)
+}
+
+func (m *mockPahoClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	args := m.Called(topic, qos)
+	if m.handlers == nil {
+		m.handlers = map[string]mqtt.MessageHandler{}
+	}
+	m.handlers[topic] = callback
+	return args.Get(0).(mqtt.Token)
+}
+
+func (m *mockPahoClient) Unsubscribe(topics ...string) mqtt.Token {
+	args := m.Called(topics)
+	return args.Get(0).(mqtt.Token)
+}
+
+type fakeMessage struct {
+	mqtt.Message
+	topic   string
+	payload []byte
+}
+
+func (m *fakeMessage) Topic() string   { return m.topic }
+func (m *fakeMessage) Payload() []byte { return m.payload }
+func (m *fakeMessage) Qos() byte       { return 1 }
+func (m *fakeMessage) Retained() bool  { return false }
+func (m *fakeMessage) Duplicate() bool { return false }
+
+type fakeTokenManager struct{}
+
+func (fakeTokenManager) GetToken() (string, error) { return "token-1", nil }
+func (fakeTokenManager) Refresh() (string, error)  { return "token-2", nil }
+
+type reading struct {
+	DERID string  `json:"der_id"`
+	Power float64 `json:"power_kw"`
+}
+
+type MQTTClientTestSuite struct {
+	suite.Suite
+	paho   *mockPahoClient
+	client *mqttClient
+	ctx    context.Context
+}
+
+func (s *MQTTClientTestSuite) SetupTest() {
+	s.paho = new(mockPahoClient)
+	s.ctx = context.Background()
+	lifetime, cancel := context.WithCancel(context.Background())
+	s.client = &mqttClient{
+		cfg:    &Config{Broker: "tcp://localhost:1883", ClientID: "aggregator"},
+		client: s.paho,
+		log:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+		ctx:    lifetime,
+		cancel: cancel,
+		subs:   map[string]subscription{},
+	}
+}
+
+func (s *MQTTClientTestSuite) TestPublish() {
+	s.paho.On("Publish", "der/1", byte(1), false, []byte("hi")).Return(newToken(nil)).Once()
+	s.NoError(s.client.Publish(s.ctx, "der/1", 1, false, []byte("hi")))
+
+	s.paho.On("Publish", "der/2", byte(1), false, mock.Anything).Return(newToken(errors.New("not connected"))).Once()
+	s.Error(s.client.Publish(s.ctx, "der/2", 1, false, []byte("hi")))
+
+	s.paho.On("Publish", "der/3", byte(0), true, []byte(`{"der_id":"a","power_kw":1.5}`)).Return(newToken(nil)).Once()
+	s.NoError(PublishJSON(s.ctx, s.client, "der/3", 0, true, reading{DERID: "a", Power: 1.5}))
+
+	s.paho.AssertExpectations(s.T())
+}
+
+func (s *MQTTClientTestSuite) TestPublishContextCancelled() {
+	pending := &fakeToken{done: make(chan struct{})}
+	s.paho.On("Publish", "der/1", byte(1), false, mock.Anything).Return(pending)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+	s.ErrorIs(s.client.Publish(ctx, "der/1", 1, false, nil), context.Canceled)
+}
+
+func (s *MQTTClientTestSuite) TestSubscribeJSON() {
+	s.paho.On("Subscribe", "der/+/telemetry", byte(1)).Return(newToken(nil))
+
+	got := make(chan *reading, 1)
+	err := s.client.Subscribe(s.ctx, "der/+/telemetry", 1, JSONHandler(func(ctx context.Context, topic string, r *reading) error {
+		got <- r
+		return nil
+	}))
+	s.Require().NoError(err)
+	s.Contains(s.client.subs, "der/+/telemetry")
+
+	s.paho.handlers["der/+/telemetry"](s.paho, &fakeMessage{topic: "der/1/telemetry", payload: []byte(`{"der_id":"1","power_kw":4.2}`)})
+	r := <-got
+	s.Equal("1", r.DERID)
+	s.Equal(4.2, r.Power)
+
+	// Malformed payloads are logged rather than delivered.
+	s.paho.handlers["der/+/telemetry"](s.paho, &fakeMessage{topic: "der/1/telemetry", payload: []byte("{")})
+	s.Empty(got)
+}
+
+func (s *MQTTClientTestSuite) TestResubscribeOnConnect() {
+	s.paho.On("Subscribe", "a", byte(1)).Return(newToken(nil)).Twice()
+	s.paho.On("Unsubscribe", []string{"a"}).Return(newToken(nil)).Once()
+
+	handler := func(context.Context, *Message) error { return nil }
+	s.Require().NoError(s.client.Subscribe(s.ctx, "a", 1, handler))
+
+	s.client.onConnect(s.paho)
+	s.paho.AssertNumberOfCalls(s.T(), "Subscribe", 2)
+
+	s.Require().NoError(s.client.Unsubscribe(s.ctx, "a"))
+	s.client.onConnect(s.paho)
+	s.paho.AssertNumberOfCalls(s.T(), "Subscribe", 2)
+}
+
+func (s *MQTTClientTestSuite) TestCredentials() {
+	s.client.tokens = fakeTokenManager{}
+	user, pass := s.client.credentials()
+	s.Equal("aggregator", user)
+	s.Equal("token-1", pass)
+
+	s.client.cfg.Username = "gateway"
+	user, _ = s.client.credentials()
+	s.Equal("gateway", user)
+}
+
+func (s *MQTTClientTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{"valid", &Config{Broker: "ssl://broker:8883", ClientID: "agg"}, false},
+		{"nil", nil, true},
+		{"missing broker", &Config{ClientID: "agg"}, true},
+		{"missing client id", &Config{Broker: "tcp://broker:1883"}, true},
+		{"negative keepalive", &Config{Broker: "tcp://broker:1883", ClientID: "agg", KeepAlive: -time.Second}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestMQTTClientSuite(t *testing.T) {
+	suite.Run(t, new(MQTTClientTestSuite))
+}
@@ -0,0 +1,45 @@
+package natsclient
+
+import (
+	"context"
+)
+
+// ToEventBus consumes from a JetStream consumer and publishes each decoded
+// message onto publish, typically an eventbus.EventBus Publish method. A
+// decode error marked with retry.Permanent terminates the message; other
+// decode errors cause redelivery. It blocks until ctx is cancelled.
+func ToEventBus(ctx context.Context, c NATSClient, stream, consumer string, publish func(event any), decode func(*Message) (any, error)) error {
+	return c.Consume(ctx, stream, consumer, func(ctx context.Context, msg *Message) error {
+		event, err := decode(msg)
+		if err != nil {
+			return err
+		}
+		publish(event)
+		return nil
+	})
+}
+
+// FromEventBus publishes every event received on events to JetStream using
+// encode to choose the subject and payload, until events is closed or ctx is
+// cancelled. Pass a channel from eventbus.EventBus.Subscribe. Failures are
+// reported to onError, which may be nil.
+func FromEventBus(ctx context.Context, c NATSClient, events <-chan any, encode func(event any) (subject string, data []byte, err error), onError func(event any, err error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			subject, data, err := encode(event)
+			if err == nil {
+				err = c.Publish(ctx, subject, data)
+			}
+			if err != nil && onError != nil {
+				onError(event, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package natsclient
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultMaxReconnects = -1
+	DefaultReconnectWait = 2 * time.Second
+	DefaultNakDelay      = time.Second
+	DefaultDrainTimeout  = 30 * time.Second
+)
+
+type NATSClient interface {
+	Publish(ctx context.Context, subject string, data []byte) error
+	EnsureStream(ctx context.Context, cfg jetstream.StreamConfig) error
+	EnsureConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) error
+	Consume(ctx context.Context, stream string, consumer string, handler Handler) error
+	JetStream() jetstream.JetStream
+	Close() error
+}
+
+type Config struct {
+	URL           string        `koanf:"url" json:"url" envconfig:"url"`
+	Name          string        `koanf:"name" json:"name" envconfig:"name"`
+	CredsFile     string        `koanf:"creds_file" json:"creds_file" envconfig:"creds_file"`
+	MaxReconnects int           `koanf:"max_reconnects" json:"max_reconnects" envconfig:"max_reconnects"`
+	ReconnectWait time.Duration `koanf:"reconnect_wait" json:"reconnect_wait" envconfig:"reconnect_wait"`
+	NakDelay      time.Duration `koanf:"nak_delay" json:"nak_delay" envconfig:"nak_delay"`
+	// DrainTimeout bounds how long Consume keeps handling buffered messages
+	// after its context is cancelled.
+	DrainTimeout time.Duration     `koanf:"drain_timeout" json:"drain_timeout" envconfig:"drain_timeout"`
+	TLS          *tlsconfig.Config `koanf:"tls" json:"tls" envconfig:"tls"`
+}
+
+// Message is a JetStream message delivered to a Handler.
+type Message struct {
+	Subject string
+	Data    []byte
+	Headers nats.Header
+}
+
+// Handler processes a message. Returning nil acknowledges it, an error marked
+// with retry.Permanent terminates it so it is never redelivered, and any other
+// error negatively acknowledges it for redelivery after Config.NakDelay.
+type Handler func(ctx context.Context, msg *Message) error
+
+type natsClient struct {
+	cfg  *Config
+	conn *nats.Conn
+	js   jetstream.JetStream
+	log  *slog.Logger
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("nats configuration required")
+	}
+	if c.URL == "" {
+		return errors.New("nats url required")
+	}
+	if c.ReconnectWait < 0 || c.NakDelay < 0 || c.DrainTimeout < 0 {
+		return errors.New("nats durations must not be negative")
+	}
+	return c.TLS.Validate()
+}
+
+func New(ctx context.Context, cfg *Config, log *slog.Logger) (NATSClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []nats.Option{
+		nats.Name(cfg.Name),
+		nats.MaxReconnects(DefaultMaxReconnects),
+		nats.ReconnectWait(DefaultReconnectWait),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			log.Warn("nats disconnected", "url", cfg.URL, "error", err)
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.Info("nats reconnected", "url", nc.ConnectedUrl())
+		}),
+	}
+	if cfg.MaxReconnects != 0 {
+		opts = append(opts, nats.MaxReconnects(cfg.MaxReconnects))
+	}
+	if cfg.ReconnectWait > 0 {
+		opts = append(opts, nats.ReconnectWait(cfg.ReconnectWait))
+	}
+	if cfg.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredsFile))
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil {
+		opts = append(opts, nats.Secure(tlsCfg))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "connecting to nats %s", cfg.URL)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	log.Info("nats client connected", "url", conn.ConnectedUrl())
+
+	return &natsClient{
+		cfg:  cfg,
+		conn: conn,
+		js:   js,
+		log:  log,
+	}, nil
+}
+
+// Publish publishes to JetStream and waits for the server acknowledgement.
+func (c *natsClient) Publish(ctx context.Context, subject string, data []byte) error {
+	if _, err := c.js.Publish(ctx, subject, data); err != nil {
+		return errors.Wrapf(err, "publishing to %s", subject)
+	}
+	return nil
+}
+
+func (c *natsClient) EnsureStream(ctx context.Context, cfg jetstream.StreamConfig) error {
+	if _, err := c.js.CreateOrUpdateStream(ctx, cfg); err != nil {
+		return errors.Wrapf(err, "ensuring stream %s", cfg.Name)
+	}
+	return nil
+}
+
+// EnsureConsumer creates or updates a durable consumer. cfg.Durable must be
+// set so the consumer survives restarts.
+func (c *natsClient) EnsureConsumer(ctx context.Context, stream string, cfg jetstream.ConsumerConfig) error {
+	if cfg.Durable == "" {
+		return errors.New("durable consumer name required")
+	}
+	if _, err := c.js.CreateOrUpdateConsumer(ctx, stream, cfg); err != nil {
+		return errors.Wrapf(err, "ensuring consumer %s on stream %s", cfg.Durable, stream)
+	}
+	return nil
+}
+
+// Consume delivers messages from an existing consumer to handler until ctx is
+// cancelled, then drains buffered messages before returning. Handlers see a
+// context that outlives ctx by up to Config.DrainTimeout so the drained
+// messages can still be processed.
+func (c *natsClient) Consume(ctx context.Context, stream string, consumer string, handler Handler) error {
+	cons, err := c.js.Consumer(ctx, stream, consumer)
+	if err != nil {
+		return errors.Wrapf(err, "looking up consumer %s on stream %s", consumer, stream)
+	}
+
+	handlerCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	cc, err := cons.Consume(func(m jetstream.Msg) {
+		c.handle(handlerCtx, m, handler)
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	<-ctx.Done()
+	timer := time.AfterFunc(c.drainTimeout(), cancel)
+	defer timer.Stop()
+
+	cc.Drain()
+	select {
+	case <-cc.Closed():
+	case <-handlerCtx.Done():
+		c.log.Warn("nats consumer drain timed out", "stream", stream, "consumer", consumer)
+		cc.Stop()
+		<-cc.Closed()
+	}
+	return nil
+}
+
+func (c *natsClient) handle(ctx context.Context, m jetstream.Msg, handler Handler) {
+	msg := &Message{
+		Subject: m.Subject(),
+		Data:    m.Data(),
+		Headers: m.Headers(),
+	}
+
+	var ackErr error
+	err := handler(ctx, msg)
+	switch {
+	case err == nil:
+		ackErr = m.Ack()
+	case retry.IsPermanent(err):
+		c.log.Error("nats message rejected", "subject", msg.Subject, "error", err)
+		ackErr = m.Term()
+	default:
+		c.log.Warn("nats message handler failed, redelivering", "subject", msg.Subject, "error", err)
+		ackErr = m.NakWithDelay(c.nakDelay())
+	}
+	if ackErr != nil {
+		c.log.Error("nats acknowledgement failed", "subject", msg.Subject, "error", ackErr)
+	}
+}
+
+func (c *natsClient) nakDelay() time.Duration {
+	if c.cfg.NakDelay > 0 {
+		return c.cfg.NakDelay
+	}
+	return DefaultNakDelay
+}
+
+func (c *natsClient) drainTimeout() time.Duration {
+	if c.cfg.DrainTimeout > 0 {
+		return c.cfg.DrainTimeout
+	}
+	return DefaultDrainTimeout
+}
+
+func (c *natsClient) JetStream() jetstream.JetStream {
+	return c.js
+}
+
+// Close drains the connection so pending publishes and subscriptions finish.
+func (c *natsClient) Close() error {
+	if err := c.conn.Drain(); err != nil {
+		return errors.WithStack(err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package natsclient
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/grid-stream-org/go-commons/pkg/tlsconfig"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeMsg struct {
+	jetstream.Msg
+	subject  string
+	data     []byte
+	acked    bool
+	termed   bool
+	nakDelay time.Duration
+}
+
+func (m *fakeMsg) Subject() string { return m.subject }
+func (m *fakeMsg) Data() []byte    { return m.data }
+func (m *fakeMsg) Headers() nats.Header {
+	return nil
+}
+func (m *fakeMsg) Ack() error  { m.acked = true; return nil }
+func (m *fakeMsg) Term() error { m.termed = true; return nil }
+func (m *fakeMsg) NakWithDelay(d time.Duration) error {
+	m.nakDelay = d
+	return nil
+}
+
+type fakeClient struct {
+	NATSClient
+	published map[string][]byte
+	messages  []*Message
+}
+
+func (c *fakeClient) Publish(_ context.Context, subject string, data []byte) error {
+	if subject == "" {
+		return errors.New("empty subject")
+	}
+	c.published[subject] = data
+	return nil
+}
+
+func (c *fakeClient) Consume(ctx context.Context, _ string, _ string, handler Handler) error {
+	for _, msg := range c.messages {
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type NATSClientTestSuite struct {
+	suite.Suite
+	client *natsClient
+}
+
+func (s *NATSClientTestSuite) SetupTest() {
+	s.client = &natsClient{
+		cfg: &Config{URL: "nats://localhost:4222", NakDelay: 5 * time.Second},
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func (s *NATSClientTestSuite) TestHandleAcknowledgement() {
+	testCases := []struct {
+		name       string
+		handlerErr error
+		acked      bool
+		termed     bool
+		nakDelay   time.Duration
+	}{
+		{name: "success acks", acked: true},
+		{name: "permanent error terminates", handlerErr: retry.Permanent(errors.New("bad payload")), termed: true},
+		{name: "transient error naks", handlerErr: errors.New("unavailable"), nakDelay: 5 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			m := &fakeMsg{subject: "der.readings", data: []byte("{}")}
+			s.client.handle(context.Background(), m, func(_ context.Context, msg *Message) error {
+				s.Equal("der.readings", msg.Subject)
+				return tc.handlerErr
+			})
+			s.Equal(tc.acked, m.acked)
+			s.Equal(tc.termed, m.termed)
+			s.Equal(tc.nakDelay, m.nakDelay)
+		})
+	}
+}
+
+func (s *NATSClientTestSuite) TestToEventBus() {
+	c := &fakeClient{messages: []*Message{{Subject: "a", Data: []byte("1")}, {Subject: "b", Data: []byte("2")}}}
+
+	var events []any
+	err := ToEventBus(context.Background(), c, "stream", "durable", func(event any) {
+		events = append(events, event)
+	}, func(msg *Message) (any, error) {
+		return string(msg.Data), nil
+	})
+	s.NoError(err)
+	s.Equal([]any{"1", "2"}, events)
+}
+
+func (s *NATSClientTestSuite) TestFromEventBus() {
+	c := &fakeClient{published: map[string][]byte{}}
+	events := make(chan any, 2)
+	events <- "readings"
+	events <- ""
+	close(events)
+
+	var failed []any
+	FromEventBus(context.Background(), c, events, func(event any) (string, []byte, error) {
+		subject := event.(string)
+		return subject, []byte(subject), nil
+	}, func(event any, err error) {
+		failed = append(failed, event)
+	})
+	s.Equal(map[string][]byte{"readings": []byte("readings")}, c.published)
+	s.Equal([]any{""}, failed)
+}
+
+func (s *NATSClientTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{URL: "nats://localhost:4222"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing url", cfg: &Config{}, expectError: true},
+		{name: "negative nak delay", cfg: &Config{URL: "nats://localhost:4222", NakDelay: -time.Second}, expectError: true},
+		{name: "negative drain timeout", cfg: &Config{URL: "nats://localhost:4222", DrainTimeout: -time.Second}, expectError: true},
+		{name: "invalid tls", cfg: &Config{URL: "nats://localhost:4222", TLS: &tlsconfig.Config{Enabled: true, CertFile: "cert.pem"}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestNATSClientSuite(t *testing.T) {
+	suite.Run(t, new(NATSClientTestSuite))
+}
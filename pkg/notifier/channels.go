@@ -0,0 +1,309 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/ratelimit"
+	"github.com/pkg/errors"
+)
+
+const DefaultSendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+type SlackConfig struct {
+	WebhookURL string            `koanf:"webhook_url" json:"webhook_url" envconfig:"webhook_url"`
+	RateLimit  *ratelimit.Config `koanf:"rate_limit" json:"rate_limit" envconfig:"rate_limit"`
+}
+
+func (c *SlackConfig) Validate() error {
+	if c.WebhookURL == "" {
+		return errors.New("slack webhook url required")
+	}
+	return validateRate(c.RateLimit)
+}
+
+// Slack posts to a Slack incoming webhook.
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlack(cfg *SlackConfig, client *http.Client) *Slack {
+	return &Slack{url: cfg.WebhookURL, client: client}
+}
+
+func (s *Slack) Name() string { return "slack" }
+
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Color  string  `json:"color"`
+		Fields []field `json:"fields,omitempty"`
+	}
+	payload := struct {
+		Text        string       `json:"text"`
+		Attachments []attachment `json:"attachments,omitempty"`
+	}{Text: "*" + msg.Subject + "*\n" + msg.Text}
+
+	if len(msg.Fields) > 0 {
+		a := attachment{Color: slackColor(msg.Severity)}
+		for _, name := range sortedKeys(msg.Fields) {
+			a.Fields = append(a.Fields, field{Title: name, Value: msg.Fields[name], Short: true})
+		}
+		payload.Attachments = []attachment{a}
+	}
+	return postJSON(ctx, s.client, s.url, nil, payload)
+}
+
+func slackColor(sev Severity) string {
+	switch sev {
+	case Critical:
+		return "danger"
+	case Warning:
+		return "warning"
+	default:
+		return "good"
+	}
+}
+
+type WebhookConfig struct {
+	URL string `koanf:"url" json:"url" envconfig:"url"`
+	// Headers are added to every request, for example an API key.
+	Headers   map[string]string `koanf:"headers" json:"headers" envconfig:"headers"`
+	RateLimit *ratelimit.Config `koanf:"rate_limit" json:"rate_limit" envconfig:"rate_limit"`
+}
+
+func (c *WebhookConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("webhook url required")
+	}
+	return validateRate(c.RateLimit)
+}
+
+// Webhook posts the Message as JSON.
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhook(cfg *WebhookConfig, client *http.Client) *Webhook {
+	return &Webhook{url: cfg.URL, headers: cfg.Headers, client: client}
+}
+
+func (w *Webhook) Name() string { return "webhook" }
+
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, w.client, w.url, w.headers, msg)
+}
+
+type SMTPConfig struct {
+	Host      string            `koanf:"host" json:"host" envconfig:"host"`
+	Port      int               `koanf:"port" json:"port" envconfig:"port"`
+	Username  string            `koanf:"username" json:"username" envconfig:"username"`
+	Password  string            `koanf:"password" json:"password" envconfig:"password"`
+	From      string            `koanf:"from" json:"from" envconfig:"from"`
+	To        []string          `koanf:"to" json:"to" envconfig:"to"`
+	RateLimit *ratelimit.Config `koanf:"rate_limit" json:"rate_limit" envconfig:"rate_limit"`
+}
+
+func (c *SMTPConfig) Validate() error {
+	if c.Host == "" || c.Port <= 0 {
+		return errors.New("smtp host and port required")
+	}
+	if err := validateEmail(c.From, c.To); err != nil {
+		return err
+	}
+	return validateRate(c.RateLimit)
+}
+
+// SMTP sends plain-text email through an SMTP relay, using STARTTLS when the
+// server offers it.
+type SMTP struct {
+	cfg  *SMTPConfig
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTP(cfg *SMTPConfig) *SMTP {
+	return &SMTP{cfg: cfg, send: smtp.SendMail}
+}
+
+func (s *SMTP) Name() string { return "smtp" }
+
+// Send ignores ctx cancellation once the SMTP exchange has started;
+// net/smtp has no context support.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	var a smtp.Auth
+	if s.cfg.Username != "" {
+		a = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	addr := net.JoinHostPort(s.cfg.Host, fmt.Sprint(s.cfg.Port))
+	body := buildEmail(s.cfg.From, s.cfg.To, msg)
+	return errors.Wrap(s.send(addr, a, s.cfg.From, s.cfg.To, body), "sending email")
+}
+
+func buildEmail(from string, to []string, msg Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", encodeSubject(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", msg.SentAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(emailText(msg))
+	return b.Bytes()
+}
+
+// encodeSubject folds line breaks, which would otherwise start new headers,
+// and encodes non-ASCII text as an RFC 2047 word.
+func encodeSubject(subject string) string {
+	subject = strings.Join(strings.FieldsFunc(subject, func(r rune) bool {
+		return r == '\r' || r == '\n'
+	}), " ")
+	return mime.QEncoding.Encode("utf-8", subject)
+}
+
+func emailText(msg Message) string {
+	var b strings.Builder
+	b.WriteString(msg.Text)
+	if len(msg.Fields) > 0 {
+		b.WriteString("\n\n")
+		for _, name := range sortedKeys(msg.Fields) {
+			fmt.Fprintf(&b, "%s: %s\n", name, msg.Fields[name])
+		}
+	}
+	return b.String()
+}
+
+type SendGridConfig struct {
+	APIKey string `koanf:"api_key" json:"api_key" envconfig:"api_key"`
+	// Endpoint defaults to DefaultSendGridEndpoint.
+	Endpoint  string            `koanf:"endpoint" json:"endpoint" envconfig:"endpoint"`
+	From      string            `koanf:"from" json:"from" envconfig:"from"`
+	To        []string          `koanf:"to" json:"to" envconfig:"to"`
+	RateLimit *ratelimit.Config `koanf:"rate_limit" json:"rate_limit" envconfig:"rate_limit"`
+}
+
+func (c *SendGridConfig) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("sendgrid api key required")
+	}
+	if err := validateEmail(c.From, c.To); err != nil {
+		return err
+	}
+	return validateRate(c.RateLimit)
+}
+
+// SendGrid sends email through the SendGrid v3 mail API.
+type SendGrid struct {
+	cfg      *SendGridConfig
+	endpoint string
+	client   *http.Client
+}
+
+func NewSendGrid(cfg *SendGridConfig, client *http.Client) *SendGrid {
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = DefaultSendGridEndpoint
+	}
+	return &SendGrid{cfg: cfg, endpoint: endpoint, client: client}
+}
+
+func (s *SendGrid) Name() string { return "sendgrid" }
+
+func (s *SendGrid) Send(ctx context.Context, msg Message) error {
+	type address struct {
+		Email string `json:"email"`
+	}
+	type content struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	to := make([]address, len(s.cfg.To))
+	for i, addr := range s.cfg.To {
+		to[i] = address{Email: addr}
+	}
+	payload := map[string]any{
+		"personalizations": []map[string]any{{"to": to}},
+		"from":             address{Email: s.cfg.From},
+		"subject":          msg.Subject,
+		"content":          []content{{Type: "text/plain", Value: emailText(msg)}},
+	}
+	return postJSON(ctx, s.client, s.endpoint, map[string]string{"Authorization": "Bearer " + s.cfg.APIKey}, payload)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "encoding notification")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("notification endpoint returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func validateEmail(from string, to []string) error {
+	if from == "" {
+		return errors.New("email from address required")
+	}
+	if len(to) == 0 {
+		return errors.New("email recipients required")
+	}
+	for _, addr := range append([]string{from}, to...) {
+		if a, err := mail.ParseAddress(addr); err != nil || a.Address != addr {
+			return errors.Errorf("email address %q must be a plain address such as ops@example.com", addr)
+		}
+	}
+	return nil
+}
+
+func validateRate(rl *ratelimit.Config) error {
+	if rl == nil {
+		return nil
+	}
+	return rl.Validate()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
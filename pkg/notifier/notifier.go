@@ -0,0 +1,312 @@
+// Package notifier turns events into human-facing notifications, such as
+// validation-failure alerts or DR-event announcements, and delivers them to
+// Slack, email or generic webhooks.
+//
+// Routes pair an event type with a Template and the channels to send it to.
+// Run consumes events from an eventbus and dispatches every matching route:
+//
+//	n, err := notifier.New(cfg, httpClient, log)
+//	...
+//	notifier.On[ValidationFailed](n, notifier.MustTemplate(
+//	    "Validation failed for {{.ProjectID}}",
+//	    "{{.Count}} readings rejected: {{.Reason}}",
+//	), notifier.Critical, "slack", "sendgrid")
+//	go n.Run(ctx, bus)
+//
+// Each channel has its own rate limit. Notifications over the limit are
+// dropped and counted rather than queued, so an alert storm cannot flood a
+// channel or delay later alerts.
+package notifier
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/ratelimit"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const DefaultBufferSize = 64
+
+type Severity string
+
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
+// Message is a rendered notification.
+type Message struct {
+	Subject  string            `json:"subject"`
+	Text     string            `json:"text"`
+	Severity Severity          `json:"severity"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	SentAt   time.Time         `json:"sent_at"`
+}
+
+// Channel delivers messages to one destination.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Slack    *SlackConfig    `koanf:"slack" json:"slack" envconfig:"slack"`
+	SMTP     *SMTPConfig     `koanf:"smtp" json:"smtp" envconfig:"smtp"`
+	SendGrid *SendGridConfig `koanf:"sendgrid" json:"sendgrid" envconfig:"sendgrid"`
+	Webhook  *WebhookConfig  `koanf:"webhook" json:"webhook" envconfig:"webhook"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("notifier configuration required")
+	}
+	if c.Slack != nil {
+		if err := c.Slack.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.SMTP != nil {
+		if err := c.SMTP.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.SendGrid != nil {
+		if err := c.SendGrid.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Webhook != nil {
+		if err := c.Webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Option func(*Notifier)
+
+// WithChannel adds a channel alongside those built from Config. A nil
+// limiter leaves the channel unlimited.
+func WithChannel(ch Channel, limiter ratelimit.Limiter) Option {
+	return func(n *Notifier) {
+		n.channels[ch.Name()] = &channel{Channel: ch, limiter: limiter}
+	}
+}
+
+// WithMetrics counts notifications by channel and result: sent, failed or
+// limited.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(n *Notifier) {
+		sent, err := m.NewCounterVec("notifier", "notifications_total", "Notifications by channel and result.", "channel", "result")
+		if err != nil {
+			n.log.Warn("registering notifier metrics", "error", err)
+			return
+		}
+		n.sent = sent
+	}
+}
+
+// WithClock replaces time.Now for Message.SentAt.
+func WithClock(now func() time.Time) Option {
+	return func(n *Notifier) {
+		n.now = now
+	}
+}
+
+type channel struct {
+	Channel
+	limiter ratelimit.Limiter
+}
+
+type route struct {
+	match    func(event any) bool
+	tmpl     *Template
+	severity Severity
+	channels []string
+}
+
+type Notifier struct {
+	log      *slog.Logger
+	channels map[string]*channel
+	sent     *prometheus.CounterVec
+	now      func() time.Time
+
+	mu     sync.RWMutex
+	routes []route
+}
+
+// New builds the channels configured in cfg. Slack, webhook and SendGrid
+// requests are made with client.
+func New(cfg *Config, client *http.Client, log *slog.Logger, opts ...Option) (*Notifier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	n := &Notifier{log: log, channels: map[string]*channel{}, now: time.Now}
+	add := func(ch Channel, rl *ratelimit.Config) error {
+		var limiter ratelimit.Limiter
+		if rl != nil {
+			var err error
+			if limiter, err = ratelimit.NewFromConfig(rl); err != nil {
+				return errors.Wrapf(err, "%s rate limit", ch.Name())
+			}
+		}
+		n.channels[ch.Name()] = &channel{Channel: ch, limiter: limiter}
+		return nil
+	}
+	if cfg.Slack != nil {
+		if err := add(NewSlack(cfg.Slack, client), cfg.Slack.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SMTP != nil {
+		if err := add(NewSMTP(cfg.SMTP), cfg.SMTP.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SendGrid != nil {
+		if err := add(NewSendGrid(cfg.SendGrid, client), cfg.SendGrid.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Webhook != nil {
+		if err := add(NewWebhook(cfg.Webhook, client), cfg.Webhook.RateLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n, nil
+}
+
+// Channels returns the sorted names of the registered channels.
+func (n *Notifier) Channels() []string {
+	names := make([]string, 0, len(n.channels))
+	for name := range n.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Route sends events accepted by match, rendered with tmpl, to the named
+// channels. Routing to an unknown channel is an error.
+func (n *Notifier) Route(match func(event any) bool, tmpl *Template, severity Severity, channels ...string) error {
+	if len(channels) == 0 {
+		return errors.New("notifier route requires at least one channel")
+	}
+	for _, name := range channels {
+		if _, ok := n.channels[name]; !ok {
+			return errors.Errorf("unknown notifier channel %q", name)
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.routes = append(n.routes, route{match: match, tmpl: tmpl, severity: severity, channels: channels})
+	return nil
+}
+
+// On routes events of type E, or *E, to the named channels.
+func On[E any](n *Notifier, tmpl *Template, severity Severity, channels ...string) error {
+	return n.Route(func(event any) bool {
+		switch event.(type) {
+		case E, *E:
+			return true
+		}
+		return false
+	}, tmpl, severity, channels...)
+}
+
+// Notify renders event for every matching route and sends it. Delivery
+// failures are logged and the first is returned; rate-limited sends are not
+// errors.
+func (n *Notifier) Notify(ctx context.Context, event any) error {
+	n.mu.RLock()
+	routes := n.routes
+	n.mu.RUnlock()
+
+	var first error
+	for _, r := range routes {
+		if !r.match(event) {
+			continue
+		}
+		msg, err := r.tmpl.Render(event)
+		if err != nil {
+			n.log.Error("rendering notification", "error", err)
+			first = firstErr(first, err)
+			continue
+		}
+		msg.Severity = r.severity
+		msg.SentAt = n.now()
+		for _, name := range r.channels {
+			if err := n.Send(ctx, name, msg); err != nil {
+				first = firstErr(first, err)
+			}
+		}
+	}
+	return first
+}
+
+// Send delivers msg to one channel, subject to its rate limit.
+func (n *Notifier) Send(ctx context.Context, name string, msg Message) error {
+	ch, ok := n.channels[name]
+	if !ok {
+		return errors.Errorf("unknown notifier channel %q", name)
+	}
+	if ch.limiter != nil && !ch.limiter.Allow() {
+		n.log.Warn("notification rate limited", "channel", name, "subject", msg.Subject)
+		n.count(name, "limited")
+		return nil
+	}
+	if err := ch.Send(ctx, msg); err != nil {
+		n.log.Error("sending notification", "channel", name, "subject", msg.Subject, "error", err)
+		n.count(name, "failed")
+		return errors.Wrapf(err, "sending notification to %s", name)
+	}
+	n.count(name, "sent")
+	return nil
+}
+
+// Run notifies for every event published on bus until ctx is done.
+func (n *Notifier) Run(ctx context.Context, bus eventbus.EventBus) error {
+	events := bus.Subscribe(DefaultBufferSize)
+	defer bus.Unsubscribe(events)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			_ = n.Notify(ctx, event)
+		}
+	}
+}
+
+func (n *Notifier) count(name, result string) {
+	if n.sent != nil {
+		n.sent.WithLabelValues(name, result).Inc()
+	}
+}
+
+func firstErr(first, err error) error {
+	if first != nil {
+		return first
+	}
+	return err
+}
@@ -0,0 +1,279 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/ratelimit"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type validationFailed struct {
+	ProjectID string
+	Count     int
+	Reason    string
+}
+
+type drAnnounced struct {
+	EventID string
+	Start   time.Time
+}
+
+// fakeChannel records the messages it is sent.
+type fakeChannel struct {
+	name string
+	err  error
+
+	mu   sync.Mutex
+	sent []Message
+}
+
+func (f *fakeChannel) Name() string { return f.name }
+
+func (f *fakeChannel) Send(_ context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, msg)
+	return f.err
+}
+
+func (f *fakeChannel) messages() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
+
+type NotifierTestSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func (s *NotifierTestSuite) SetupTest() {
+	s.now = time.Date(2026, 7, 1, 17, 0, 0, 0, time.UTC)
+}
+
+func (s *NotifierTestSuite) notifier(opts ...Option) *Notifier {
+	opts = append([]Option{WithClock(func() time.Time { return s.now })}, opts...)
+	n, err := New(&Config{}, nil, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *NotifierTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "empty", cfg: &Config{}},
+		{name: "valid", cfg: &Config{
+			Slack:    &SlackConfig{WebhookURL: "https://hooks.slack.test/x", RateLimit: &ratelimit.Config{Rate: 1, Burst: 5}},
+			SMTP:     &SMTPConfig{Host: "smtp.test", Port: 587, From: "alerts@gs.test", To: []string{"ops@gs.test"}},
+			SendGrid: &SendGridConfig{APIKey: "key", From: "alerts@gs.test", To: []string{"ops@gs.test"}},
+			Webhook:  &WebhookConfig{URL: "https://example.test/hook"},
+		}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "slack without url", cfg: &Config{Slack: &SlackConfig{}}, expectError: true},
+		{name: "smtp without recipients", cfg: &Config{SMTP: &SMTPConfig{Host: "smtp.test", Port: 25, From: "a@gs.test"}}, expectError: true},
+		{name: "invalid from", cfg: &Config{SMTP: &SMTPConfig{Host: "smtp.test", Port: 25, From: "alerts", To: []string{"b@gs.test"}}}, expectError: true},
+		{name: "recipient with header", cfg: &Config{SMTP: &SMTPConfig{Host: "smtp.test", Port: 25, From: "a@gs.test", To: []string{"b@gs.test\r\nBcc: c@evil.test"}}}, expectError: true},
+		{name: "sendgrid without key", cfg: &Config{SendGrid: &SendGridConfig{From: "a@gs.test", To: []string{"b@gs.test"}}}, expectError: true},
+		{name: "bad rate limit", cfg: &Config{Webhook: &WebhookConfig{URL: "https://example.test", RateLimit: &ratelimit.Config{}}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *NotifierTestSuite) TestTemplate() {
+	tmpl, err := NewTemplate("Validation failed for {{.ProjectID | upper}}", "{{.Count}} readings rejected: {{.Reason}}")
+	s.Require().NoError(err)
+	_, err = tmpl.WithField("project", "{{.ProjectID}}")
+	s.Require().NoError(err)
+
+	msg, err := tmpl.Render(validationFailed{ProjectID: "p1", Count: 3, Reason: "negative load"})
+	s.Require().NoError(err)
+	s.Equal("Validation failed for P1", msg.Subject)
+	s.Equal("3 readings rejected: negative load", msg.Text)
+	s.Equal(map[string]string{"project": "p1"}, msg.Fields)
+
+	_, err = NewTemplate("{{.Oops", "")
+	s.Error(err)
+
+	missing := MustTemplate("{{.Missing}}", "")
+	_, err = missing.Render(map[string]any{})
+	s.Error(err, "missing keys are errors")
+}
+
+func (s *NotifierTestSuite) TestRouting() {
+	slack := &fakeChannel{name: "slack"}
+	email := &fakeChannel{name: "email"}
+	n := s.notifier(WithChannel(slack, nil), WithChannel(email, nil))
+	s.Equal([]string{"email", "slack"}, n.Channels())
+
+	s.Require().NoError(On[validationFailed](n, MustTemplate("failed {{.ProjectID}}", "{{.Reason}}"), Critical, "slack", "email"))
+	s.Require().NoError(On[drAnnounced](n, MustTemplate("DR {{.EventID}}", "starts {{rfc3339 .Start}}"), Info, "email"))
+	s.Error(On[drAnnounced](n, MustTemplate("", ""), Info, "pager"))
+	s.Error(n.Route(func(any) bool { return true }, MustTemplate("", ""), Info))
+
+	ctx := context.Background()
+	s.NoError(n.Notify(ctx, validationFailed{ProjectID: "p1", Reason: "gap"}))
+	s.NoError(n.Notify(ctx, &drAnnounced{EventID: "e1", Start: s.now}))
+	s.NoError(n.Notify(ctx, "unrouted"))
+
+	s.Require().Len(slack.messages(), 1)
+	s.Equal(Message{Subject: "failed p1", Text: "gap", Severity: Critical, SentAt: s.now}, slack.messages()[0])
+	s.Require().Len(email.messages(), 2)
+	s.Equal("starts 2026-07-01T17:00:00Z", email.messages()[1].Text)
+}
+
+func (s *NotifierTestSuite) TestRateLimitAndFailures() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "notifier"})
+	s.Require().NoError(err)
+	slack := &fakeChannel{name: "slack"}
+	broken := &fakeChannel{name: "webhook", err: errors.New("boom")}
+	n := s.notifier(
+		WithChannel(slack, ratelimit.NewTokenBucket(0.001, 2)),
+		WithChannel(broken, nil),
+		WithMetrics(m),
+	)
+	s.Require().NoError(On[validationFailed](n, MustTemplate("failed", ""), Critical, "slack"))
+	s.Require().NoError(On[drAnnounced](n, MustTemplate("dr", ""), Info, "webhook"))
+
+	for range 5 {
+		s.NoError(n.Notify(context.Background(), validationFailed{}))
+	}
+	s.Len(slack.messages(), 2)
+	s.Equal(float64(2), testutil.ToFloat64(n.sent.WithLabelValues("slack", "sent")))
+	s.Equal(float64(3), testutil.ToFloat64(n.sent.WithLabelValues("slack", "limited")))
+
+	err = n.Notify(context.Background(), drAnnounced{})
+	s.ErrorContains(err, "boom")
+	s.Equal(float64(1), testutil.ToFloat64(n.sent.WithLabelValues("webhook", "failed")))
+}
+
+// subscribeSignal closes subscribed once Run has subscribed to the bus.
+type subscribeSignal struct {
+	eventbus.EventBus
+	subscribed chan struct{}
+}
+
+func (b *subscribeSignal) Subscribe(capacity int) chan any {
+	ch := b.EventBus.Subscribe(capacity)
+	close(b.subscribed)
+	return ch
+}
+
+func (s *NotifierTestSuite) TestRun() {
+	ch := &fakeChannel{name: "slack"}
+	n := s.notifier(WithChannel(ch, nil))
+	s.Require().NoError(On[validationFailed](n, MustTemplate("failed {{.ProjectID}}", ""), Critical, "slack"))
+
+	bus := &subscribeSignal{EventBus: eventbus.New(), subscribed: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- n.Run(ctx, bus) }()
+
+	<-bus.subscribed
+	bus.Publish(validationFailed{ProjectID: "p9"})
+	s.Eventually(func() bool { return len(ch.messages()) == 1 }, time.Second, 5*time.Millisecond)
+	s.Equal("failed p9", ch.messages()[0].Subject)
+
+	cancel()
+	s.NoError(<-done)
+}
+
+func (s *NotifierTestSuite) TestHTTPChannels() {
+	type request struct {
+		path   string
+		header http.Header
+		body   map[string]any
+	}
+	requests := make(chan request, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var decoded map[string]any
+		_ = json.Unmarshal(body, &decoded)
+		requests <- request{path: r.URL.Path, header: r.Header, body: decoded}
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	msg := Message{Subject: "DR event", Text: "starts at 17:00", Severity: Warning, Fields: map[string]string{"site": "s1"}, SentAt: s.now}
+	ctx := context.Background()
+
+	s.Require().NoError(NewSlack(&SlackConfig{WebhookURL: srv.URL + "/slack"}, srv.Client()).Send(ctx, msg))
+	req := <-requests
+	s.Equal("*DR event*\nstarts at 17:00", req.body["text"])
+	s.Equal("warning", req.body["attachments"].([]any)[0].(map[string]any)["color"])
+
+	hook := NewWebhook(&WebhookConfig{URL: srv.URL + "/hook", Headers: map[string]string{"X-Api-Key": "k"}}, srv.Client())
+	s.Require().NoError(hook.Send(ctx, msg))
+	req = <-requests
+	s.Equal("k", req.header.Get("X-Api-Key"))
+	s.Equal("warning", req.body["severity"])
+	s.Equal("2026-07-01T17:00:00Z", req.body["sent_at"])
+
+	sg := NewSendGrid(&SendGridConfig{APIKey: "sg", Endpoint: srv.URL + "/mail", From: "alerts@gs.test", To: []string{"ops@gs.test"}}, srv.Client())
+	s.Require().NoError(sg.Send(ctx, msg))
+	req = <-requests
+	s.Equal("Bearer sg", req.header.Get("Authorization"))
+	s.Equal("DR event", req.body["subject"])
+	s.Equal("starts at 17:00\n\nsite: s1\n", req.body["content"].([]any)[0].(map[string]any)["value"])
+
+	err := NewWebhook(&WebhookConfig{URL: srv.URL + "/fail"}, srv.Client()).Send(ctx, msg)
+	s.ErrorContains(err, "502")
+	<-requests
+}
+
+func (s *NotifierTestSuite) TestSMTP() {
+	cfg := &SMTPConfig{Host: "smtp.test", Port: 587, Username: "u", Password: "p", From: "alerts@gs.test", To: []string{"a@gs.test", "b@gs.test"}}
+	ch := NewSMTP(cfg)
+	var gotAddr string
+	var gotBody []byte
+	ch.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotBody = addr, msg
+		s.NotNil(a)
+		s.Equal(cfg.To, to)
+		return nil
+	}
+
+	s.Require().NoError(ch.Send(context.Background(), Message{Subject: "Alert", Text: "body", SentAt: s.now}))
+	s.Equal("smtp.test:587", gotAddr)
+	s.Contains(string(gotBody), "To: a@gs.test, b@gs.test\r\n")
+	s.Contains(string(gotBody), "Subject: Alert\r\n")
+	s.Contains(string(gotBody), "\r\n\r\nbody")
+
+	s.Require().NoError(ch.Send(context.Background(), Message{Subject: "Alert\r\nBcc: x@evil.test", Text: "body", SentAt: s.now}))
+	s.Contains(string(gotBody), "Subject: Alert Bcc: x@evil.test\r\n")
+	s.NotContains(string(gotBody), "\r\nBcc:")
+
+	s.Require().NoError(ch.Send(context.Background(), Message{Subject: "Écart de données", Text: "body", SentAt: s.now}))
+	s.Contains(string(gotBody), "Subject: =?utf-8?q?")
+}
+
+func TestNotifierTestSuite(t *testing.T) {
+	suite.Run(t, new(NotifierTestSuite))
+}
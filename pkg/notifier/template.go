@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Funcs are available to every Template in addition to the text/template
+// builtins.
+var Funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join":  strings.Join,
+	"time": func(layout string, t time.Time) string {
+		return t.UTC().Format(layout)
+	},
+	"rfc3339": func(t time.Time) string {
+		return t.UTC().Format(time.RFC3339)
+	},
+}
+
+// Template renders an event into a Message. Both parts are text/template
+// sources executed with the event as dot.
+type Template struct {
+	subject *template.Template
+	text    *template.Template
+	fields  map[string]*template.Template
+}
+
+func NewTemplate(subject, text string) (*Template, error) {
+	t := &Template{}
+	var err error
+	if t.subject, err = parse("subject", subject); err != nil {
+		return nil, err
+	}
+	if t.text, err = parse("text", text); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// MustTemplate is NewTemplate for templates fixed at compile time. It panics
+// on a parse error.
+func MustTemplate(subject, text string) *Template {
+	t, err := NewTemplate(subject, text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// WithField adds a structured field, rendered from src, that channels show
+// separately from the text, such as a Slack attachment field.
+func (t *Template) WithField(name, src string) (*Template, error) {
+	f, err := parse(name, src)
+	if err != nil {
+		return nil, err
+	}
+	if t.fields == nil {
+		t.fields = map[string]*template.Template{}
+	}
+	t.fields[name] = f
+	return t, nil
+}
+
+// Render executes the template with event as dot.
+func (t *Template) Render(event any) (Message, error) {
+	var msg Message
+	var err error
+	if msg.Subject, err = execute(t.subject, event); err != nil {
+		return Message{}, err
+	}
+	if msg.Text, err = execute(t.text, event); err != nil {
+		return Message{}, err
+	}
+	if len(t.fields) > 0 {
+		msg.Fields = make(map[string]string, len(t.fields))
+		for name, f := range t.fields {
+			if msg.Fields[name], err = execute(f, event); err != nil {
+				return Message{}, err
+			}
+		}
+	}
+	return msg, nil
+}
+
+func parse(name, src string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing notification %s template", name)
+	}
+	return t, nil
+}
+
+func execute(t *template.Template, event any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, event); err != nil {
+		return "", errors.Wrapf(err, "rendering notification %s", t.Name())
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,191 @@
+// Package otel configures OpenTelemetry trace and metric providers that
+// export over OTLP/gRPC and installs them as the global providers.
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/config"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const DefaultMetricInterval = time.Minute
+
+type Config struct {
+	Endpoint       string `koanf:"endpoint" json:"endpoint" envconfig:"endpoint"`
+	Insecure       bool   `koanf:"insecure" json:"insecure" envconfig:"insecure"`
+	ServiceName    string `koanf:"service_name" json:"service_name" envconfig:"service_name"`
+	ServiceVersion string `koanf:"service_version" json:"service_version" envconfig:"service_version"`
+	// Environment defaults to config.Environment().
+	Environment string `koanf:"environment" json:"environment" envconfig:"environment"`
+	// SampleRatio is the share of root spans sampled, from 0 for none to 1
+	// for all. Unset samples all.
+	SampleRatio    *float64          `koanf:"sample_ratio" json:"sample_ratio" envconfig:"sample_ratio"`
+	Traces         bool              `koanf:"traces" json:"traces" envconfig:"traces"`
+	Metrics        bool              `koanf:"metrics" json:"metrics" envconfig:"metrics"`
+	MetricInterval time.Duration     `koanf:"metric_interval" json:"metric_interval" envconfig:"metric_interval"`
+	Attributes     map[string]string `koanf:"attributes" json:"attributes" envconfig:"attributes"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("otel configuration required")
+	}
+	if c.ServiceName == "" {
+		return errors.New("otel service name required")
+	}
+	if (c.Traces || c.Metrics) && c.Endpoint == "" {
+		return errors.New("otel endpoint required when traces or metrics are enabled")
+	}
+	if r := c.SampleRatio; r != nil && (*r < 0 || *r > 1) {
+		return errors.New("otel sample ratio must be between 0 and 1")
+	}
+	if c.MetricInterval < 0 {
+		return errors.New("otel metric interval must not be negative")
+	}
+	return nil
+}
+
+// ShutdownFunc flushes and stops the providers created by Setup. It is safe
+// to call with a context carrying the service's shutdown deadline.
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup creates the providers enabled in cfg, registers them globally along
+// with W3C trace context and baggage propagation, and returns a ShutdownFunc
+// that flushes pending telemetry.
+func Setup(ctx context.Context, cfg *Config) (ShutdownFunc, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	res, err := newResource(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var shutdowns []ShutdownFunc
+	shutdown := func(ctx context.Context) error {
+		var first error
+		for i := len(shutdowns) - 1; i >= 0; i-- {
+			if err := shutdowns[i](ctx); err != nil && first == nil {
+				first = errors.WithStack(err)
+			}
+		}
+		return first
+	}
+
+	if cfg.Traces {
+		tp, err := newTracerProvider(ctx, cfg, res)
+		if err != nil {
+			return nil, err
+		}
+		otel.SetTracerProvider(tp)
+		shutdowns = append(shutdowns, tp.Shutdown)
+	}
+
+	if cfg.Metrics {
+		mp, err := newMeterProvider(ctx, cfg, res)
+		if err != nil {
+			_ = shutdown(ctx)
+			return nil, err
+		}
+		otel.SetMeterProvider(mp)
+		shutdowns = append(shutdowns, mp.Shutdown)
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return shutdown, nil
+}
+
+func newResource(ctx context.Context, cfg *Config) (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{semconv.ServiceName(cfg.ServiceName)}
+	if cfg.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(cfg.ServiceVersion))
+	}
+	env := cfg.Environment
+	if env == "" {
+		env = config.Environment().String()
+	}
+	attrs = append(attrs, semconv.DeploymentEnvironment(env))
+	for k, v := range cfg.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(attrs...),
+	)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return res, nil
+}
+
+func newTracerProvider(ctx context.Context, cfg *Config, res *resource.Resource) (*sdktrace.TracerProvider, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler(cfg)),
+	), nil
+}
+
+func newMeterProvider(ctx context.Context, cfg *Config, res *resource.Resource) (*sdkmetric.MeterProvider, error) {
+	opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	interval := cfg.MetricInterval
+	if interval == 0 {
+		interval = DefaultMetricInterval
+	}
+
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(res),
+	), nil
+}
+
+// sampler honours the parent's sampling decision and samples root spans at
+// cfg.SampleRatio, or all of them when it is unset.
+func sampler(cfg *Config) sdktrace.Sampler {
+	switch r := cfg.SampleRatio; {
+	case r == nil || *r >= 1:
+		return sdktrace.ParentBased(sdktrace.AlwaysSample())
+	case *r <= 0:
+		return sdktrace.ParentBased(sdktrace.NeverSample())
+	default:
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*r))
+	}
+}
@@ -0,0 +1,93 @@
+package otel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+type OtelTestSuite struct {
+	suite.Suite
+}
+
+func (s *OtelTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{"valid", &Config{ServiceName: "aggregator", Endpoint: "localhost:4317", Traces: true}, false},
+		{"disabled without endpoint", &Config{ServiceName: "aggregator"}, false},
+		{"nil", nil, true},
+		{"missing service", &Config{Endpoint: "localhost:4317"}, true},
+		{"missing endpoint", &Config{ServiceName: "aggregator", Metrics: true}, true},
+		{"bad ratio", &Config{ServiceName: "aggregator", SampleRatio: ratio(1.5)}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *OtelTestSuite) TestSetup() {
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, &Config{
+		ServiceName: "aggregator",
+		Environment: "test",
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		Traces:      true,
+		Metrics:     true,
+		Attributes:  map[string]string{"region": "on-prem"},
+	})
+	s.Require().NoError(err)
+
+	s.IsType(&sdktrace.TracerProvider{}, otel.GetTracerProvider())
+	s.IsType(&sdkmetric.MeterProvider{}, otel.GetMeterProvider())
+	s.ElementsMatch([]string{"traceparent", "tracestate", "baggage"}, otel.GetTextMapPropagator().Fields())
+
+	// No collector is listening, so the final metric flush is expected to fail.
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	s.Error(shutdown(ctx))
+}
+
+func (s *OtelTestSuite) TestSetupTracesOnly() {
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, &Config{
+		ServiceName: "aggregator",
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		Traces:      true,
+		SampleRatio: ratio(0.5),
+	})
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	s.NoError(shutdown(ctx))
+}
+
+func (s *OtelTestSuite) TestSampler() {
+	s.Contains(sampler(&Config{}).Description(), "AlwaysOnSampler")
+	s.Contains(sampler(&Config{SampleRatio: ratio(0.25)}).Description(), "TraceIDRatioBased{0.25}")
+	s.Contains(sampler(&Config{SampleRatio: ratio(0)}).Description(), "AlwaysOffSampler")
+}
+
+func ratio(r float64) *float64 { return &r }
+
+func TestOtelSuite(t *testing.T) {
+	suite.Run(t, new(OtelTestSuite))
+}
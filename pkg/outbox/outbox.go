@@ -0,0 +1,246 @@
+// Package outbox implements the transactional outbox pattern on BigQuery.
+// Write inserts a data row and the events describing it in one BigQuery
+// transaction, so either both are stored or neither is. A Relay then reads
+// unpublished events in creation order, publishes them and marks each one
+// published exactly once.
+//
+// Publishing itself is at least once: if the relay stops between publishing
+// and marking an event, it is published again on the next run. Every message
+// carries its outbox ID in the AttributeID attribute so consumers can
+// deduplicate, for example with pkg/dedup. Run a single relay per outbox
+// table, for example behind leader election.
+//
+// An event that fails RelayConfig.MaxAttempts times is dead-lettered: its
+// dead_lettered_at column is set and the relay skips it from then on, which
+// also releases later events with the same ordering key. Resetting the
+// column and attempts requeues it.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/id"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTable = "outbox"
+
+	// AttributeID is the message attribute holding the outbox event ID.
+	AttributeID = "outbox_id"
+)
+
+type Config struct {
+	// Table holds the events and must be allowed by the client. Defaults to
+	// DefaultTable.
+	Table string `koanf:"table" json:"table" envconfig:"table"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("outbox configuration required")
+	}
+	return nil
+}
+
+// Event is a message to publish once the data it describes is stored. Payload
+// is JSON-encoded unless it is already a []byte.
+type Event struct {
+	Topic       string
+	OrderingKey string
+	Attributes  map[string]string
+	Payload     any
+}
+
+// Record is a stored event.
+type Record struct {
+	ID          string
+	Topic       string
+	OrderingKey string
+	Attributes  map[string]string
+	Payload     []byte
+	CreatedAt   time.Time
+	Attempts    int
+}
+
+// row is the BigQuery representation of a Record.
+type row struct {
+	ID          string    `bigquery:"id"`
+	Topic       string    `bigquery:"topic"`
+	OrderingKey string    `bigquery:"ordering_key"`
+	Attributes  string    `bigquery:"attributes"`
+	Payload     string    `bigquery:"payload"`
+	CreatedAt   time.Time `bigquery:"created_at"`
+	Attempts    int64     `bigquery:"attempts"`
+}
+
+func (r row) record() (*Record, error) {
+	rec := &Record{
+		ID:          r.ID,
+		Topic:       r.Topic,
+		OrderingKey: r.OrderingKey,
+		Payload:     []byte(r.Payload),
+		CreatedAt:   r.CreatedAt,
+		Attempts:    int(r.Attempts),
+	}
+	if r.Attributes != "" {
+		if err := json.Unmarshal([]byte(r.Attributes), &rec.Attributes); err != nil {
+			return nil, errors.Wrapf(err, "decoding attributes of outbox event %s", r.ID)
+		}
+	}
+	return rec, nil
+}
+
+type Option func(*Outbox)
+
+// WithClock replaces time.Now for the created_at column of events.
+func WithClock(now func() time.Time) Option {
+	return func(o *Outbox) {
+		o.now = now
+	}
+}
+
+// Outbox writes data rows together with their events.
+type Outbox struct {
+	cfg    *Config
+	client bqclient.BQClient
+	table  string
+	store  store
+	log    *slog.Logger
+	now    func() time.Time
+}
+
+func New(cfg *Config, client bqclient.BQClient, log *slog.Logger, opts ...Option) (*Outbox, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.New("outbox bigquery client required")
+	}
+	name := cfg.Table
+	if name == "" {
+		name = DefaultTable
+	}
+	table, err := client.TableName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &Outbox{cfg: cfg, client: client, table: table, store: &bqStore{client: client, table: table}, log: log, now: time.Now}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o, nil
+}
+
+// CreateTable creates the outbox table if it does not exist.
+func (o *Outbox) CreateTable(ctx context.Context) error {
+	return o.store.exec(ctx, `CREATE TABLE IF NOT EXISTS `+o.table+` (
+        id STRING NOT NULL,
+        topic STRING NOT NULL,
+        ordering_key STRING,
+        attributes STRING,
+        payload STRING NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        published_at TIMESTAMP,
+        attempts INT64 NOT NULL,
+        last_error STRING,
+        dead_lettered_at TIMESTAMP
+    )
+    PARTITION BY DATE(created_at)`, nil)
+}
+
+// Write inserts data into table, which must be allowed by the client, and
+// appends events to the outbox in a single transaction. It returns the IDs
+// assigned to the events.
+func (o *Outbox) Write(ctx context.Context, table string, data any, events ...Event) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("outbox write requires at least one event")
+	}
+	qualified, err := o.client.TableName(table)
+	if err != nil {
+		return nil, err
+	}
+
+	query, params, err := bqclient.InsertQuery(qualified, "", data)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := []string{"BEGIN TRANSACTION", query}
+	ids := make([]string, len(events))
+	now := o.now().UTC()
+	for i, ev := range events {
+		r, err := newRow(ev, now)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = r.ID
+
+		q, p, err := bqclient.InsertQuery(o.table, fmt.Sprintf("outbox%d_", i), r)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, q)
+		params = append(params, p...)
+	}
+	stmts = append(stmts, "COMMIT TRANSACTION")
+
+	if err := o.store.exec(ctx, strings.Join(stmts, ";\n")+";", params); err != nil {
+		return nil, errors.Wrap(err, "writing outbox transaction")
+	}
+	return ids, nil
+}
+
+func newRow(ev Event, now time.Time) (row, error) {
+	if ev.Topic == "" {
+		return row{}, errors.New("outbox event topic required")
+	}
+
+	payload, ok := ev.Payload.([]byte)
+	if !ok {
+		var err error
+		if payload, err = json.Marshal(ev.Payload); err != nil {
+			return row{}, errors.Wrap(err, "encoding outbox payload")
+		}
+	}
+
+	var attrs []byte
+	if len(ev.Attributes) > 0 {
+		var err error
+		if attrs, err = json.Marshal(ev.Attributes); err != nil {
+			return row{}, errors.WithStack(err)
+		}
+	}
+
+	return row{
+		ID:          id.New(id.Event),
+		Topic:       ev.Topic,
+		OrderingKey: ev.OrderingKey,
+		Attributes:  string(attrs),
+		Payload:     string(payload),
+		CreatedAt:   now,
+	}, nil
+}
+
+// store abstracts the outbox table so the relay can be tested without
+// BigQuery.
+type store interface {
+	exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error
+	pending(ctx context.Context, limit int) ([]row, error)
+	markPublished(ctx context.Context, ids []string) error
+	markFailed(ctx context.Context, failures []failure, maxAttempts int) error
+}
+
+// failure is a failed publish of the event with the given ID.
+type failure struct {
+	ID    string `bigquery:"id"`
+	Error string `bigquery:"error"`
+}
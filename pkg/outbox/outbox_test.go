@@ -0,0 +1,276 @@
+package outbox
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/models"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeClient allows the models tables and the outbox table in dataset gs.
+type fakeClient struct {
+	bqclient.BQClient
+}
+
+func (fakeClient) TableName(table string) (string, error) {
+	if table != DefaultTable && !slices.Contains(models.Tables(), table) {
+		return "", errors.Errorf("invalid table %s", table)
+	}
+	return "gs." + table, nil
+}
+
+type fakeStore struct {
+	sql       string
+	params    []bigquery.QueryParameter
+	rows      []row
+	published []string
+	failed    map[string]int
+	dead      []string
+	marks     int
+}
+
+func (f *fakeStore) exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error {
+	f.sql, f.params = sql, params
+	return nil
+}
+
+func (f *fakeStore) pending(ctx context.Context, limit int) ([]row, error) {
+	var out []row
+	for _, r := range f.rows {
+		if len(out) < limit && !f.isPublished(r.ID) && !slices.Contains(f.dead, r.ID) {
+			r.Attempts = int64(f.failed[r.ID])
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) isPublished(id string) bool {
+	for _, p := range f.published {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeStore) markPublished(ctx context.Context, ids []string) error {
+	f.marks++
+	f.published = append(f.published, ids...)
+	return nil
+}
+
+func (f *fakeStore) markFailed(ctx context.Context, failures []failure, maxAttempts int) error {
+	f.marks++
+	for _, fl := range failures {
+		f.failed[fl.ID]++
+		if f.failed[fl.ID] >= maxAttempts {
+			f.dead = append(f.dead, fl.ID)
+		}
+	}
+	return nil
+}
+
+type OutboxTestSuite struct {
+	suite.Suite
+	now    time.Time
+	store  *fakeStore
+	outbox *Outbox
+}
+
+func (s *OutboxTestSuite) SetupTest() {
+	s.now = time.Date(2026, 7, 1, 17, 0, 0, 0, time.UTC)
+	o, err := New(&Config{}, fakeClient{}, logger.Default(), WithClock(func() time.Time { return s.now }))
+	s.Require().NoError(err)
+	s.store = &fakeStore{failed: map[string]int{}}
+	o.store = s.store
+	s.outbox = o
+}
+
+func (s *OutboxTestSuite) param(name string) any {
+	for _, p := range s.store.params {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return nil
+}
+
+func (s *OutboxTestSuite) TestWrite() {
+	ev := &models.DREvent{ID: "e1", UtilityID: "u1", StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}
+	ids, err := s.outbox.Write(context.Background(), models.TableDREvents, ev,
+		Event{Topic: "dr-events", OrderingKey: "u1", Payload: ev, Attributes: map[string]string{"type": "created"}},
+		Event{Topic: "audit", Payload: []byte(`{"raw":true}`)},
+	)
+	s.Require().NoError(err)
+	s.Len(ids, 2)
+	s.True(strings.HasPrefix(ids[0], "evt_"))
+
+	s.True(strings.HasPrefix(s.store.sql, "BEGIN TRANSACTION;"))
+	s.True(strings.HasSuffix(s.store.sql, "COMMIT TRANSACTION;"))
+	s.Contains(s.store.sql, "INSERT INTO gs.dr_events")
+	s.Equal(2, strings.Count(s.store.sql, "INSERT INTO gs.outbox"))
+	s.Equal("e1", s.param("id"))
+	s.Equal(ids[0], s.param("outbox0_id"))
+	s.Equal("dr-events", s.param("outbox0_topic"))
+	s.Equal(`{"type":"created"}`, s.param("outbox0_attributes"))
+	s.Equal(`{"raw":true}`, s.param("outbox1_payload"))
+	s.Equal(s.now, s.param("outbox1_created_at"))
+}
+
+func (s *OutboxTestSuite) TestWriteErrors() {
+	ctx := context.Background()
+	_, err := s.outbox.Write(ctx, "users", &models.Utility{}, Event{Topic: "t"})
+	s.Error(err)
+	_, err = s.outbox.Write(ctx, models.TableUtilities, &models.Utility{})
+	s.Error(err)
+	_, err = s.outbox.Write(ctx, models.TableUtilities, &models.Utility{}, Event{})
+	s.Error(err)
+
+	_, err = New(&Config{Table: "events"}, fakeClient{}, logger.Default())
+	s.Error(err)
+}
+
+func (s *OutboxTestSuite) TestRelay() {
+	s.store.rows = []row{
+		{ID: "evt_1", Topic: "dr-events", Payload: `{"n":1}`, Attributes: `{"type":"created"}`},
+		{ID: "evt_2", Topic: "dr-events", Payload: `{"n":2}`},
+	}
+	bus := eventbus.New()
+	sub := bus.Subscribe(4)
+
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "outbox"})
+	s.Require().NoError(err)
+	relay, err := s.outbox.NewRelay(&RelayConfig{}, EventBus(bus), WithMetrics(m))
+	s.Require().NoError(err)
+
+	n, err := relay.RelayOnce(context.Background())
+	s.Require().NoError(err)
+	s.Equal(2, n)
+	s.Equal([]string{"evt_1", "evt_2"}, s.store.published)
+	s.Equal(1, s.store.marks)
+
+	rec := (<-sub).(*Record)
+	s.Equal("evt_1", rec.ID)
+	s.Equal(map[string]string{"type": "created"}, rec.Attributes)
+	s.Equal(`{"n":1}`, string(rec.Payload))
+	s.Equal(float64(2), testutil.ToFloat64(relay.events.WithLabelValues("dr-events", "published")))
+
+	n, err = relay.RelayOnce(context.Background())
+	s.Require().NoError(err)
+	s.Zero(n)
+}
+
+func (s *OutboxTestSuite) TestRelayHoldsBackOrderingKey() {
+	s.store.rows = []row{
+		{ID: "evt_1", Topic: "t", OrderingKey: "p1"},
+		{ID: "evt_2", Topic: "t", OrderingKey: "p1"},
+		{ID: "evt_3", Topic: "t", OrderingKey: "p2"},
+	}
+	fail := true
+	relay, err := s.outbox.NewRelay(&RelayConfig{}, PublisherFunc(func(ctx context.Context, rec *Record) error {
+		if rec.ID == "evt_1" && fail {
+			return errors.New("unavailable")
+		}
+		return nil
+	}))
+	s.Require().NoError(err)
+
+	_, err = relay.RelayOnce(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{"evt_3"}, s.store.published)
+	s.Equal(1, s.store.failed["evt_1"])
+
+	fail = false
+	_, err = relay.RelayOnce(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{"evt_3", "evt_1", "evt_2"}, s.store.published)
+}
+
+func (s *OutboxTestSuite) TestRelayDeadLetters() {
+	s.store.rows = []row{
+		{ID: "evt_1", Topic: "t", OrderingKey: "p1"},
+		{ID: "evt_2", Topic: "t", OrderingKey: "p1"},
+	}
+	relay, err := s.outbox.NewRelay(&RelayConfig{MaxAttempts: 2}, PublisherFunc(func(ctx context.Context, rec *Record) error {
+		if rec.ID == "evt_1" {
+			return errors.New("rejected")
+		}
+		return nil
+	}))
+	s.Require().NoError(err)
+
+	for range 2 {
+		_, err = relay.RelayOnce(context.Background())
+		s.Require().NoError(err)
+	}
+	s.Equal([]string{"evt_1"}, s.store.dead)
+	s.Empty(s.store.published)
+
+	_, err = relay.RelayOnce(context.Background())
+	s.Require().NoError(err)
+	s.Equal([]string{"evt_2"}, s.store.published)
+	s.Equal(2, s.store.failed["evt_1"])
+}
+
+func (s *OutboxTestSuite) TestRunBacksOff() {
+	s.store.rows = []row{{ID: "evt_1", Topic: "t"}}
+	relay, err := s.outbox.NewRelay(&RelayConfig{BatchSize: 1, PollInterval: 20 * time.Millisecond}, PublisherFunc(func(context.Context, *Record) error {
+		return errors.New("unavailable")
+	}))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.NoError(relay.Run(ctx))
+	s.Less(s.store.failed["evt_1"], 5)
+}
+
+func (s *OutboxTestSuite) TestRun() {
+	s.store.rows = []row{{ID: "evt_1", Topic: "t"}}
+	relay, err := s.outbox.NewRelay(&RelayConfig{PollInterval: 10 * time.Millisecond}, PublisherFunc(func(context.Context, *Record) error { return nil }))
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.NoError(relay.Run(ctx))
+	s.Equal([]string{"evt_1"}, s.store.published)
+}
+
+func (s *OutboxTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{}},
+		{name: "custom table", cfg: &Config{Table: "events"}},
+		{name: "nil config", cfg: nil, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestOutboxSuite(t *testing.T) {
+	suite.Run(t, new(OutboxTestSuite))
+}
@@ -0,0 +1,241 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 10
+
+	// maxBackoff caps how long Run waits after a batch in which every
+	// publish failed.
+	maxBackoff = 5 * time.Minute
+	// markTimeout bounds recording the results of a batch once ctx is done.
+	markTimeout = 30 * time.Second
+)
+
+// Publisher delivers a stored event. It must be safe to call again with the
+// same record.
+type Publisher interface {
+	Publish(ctx context.Context, rec *Record) error
+}
+
+type PublisherFunc func(ctx context.Context, rec *Record) error
+
+func (f PublisherFunc) Publish(ctx context.Context, rec *Record) error {
+	return f(ctx, rec)
+}
+
+// PubSub publishes each record to the Pub/Sub topic named by its Topic.
+func PubSub(c pubsub.PubSubClient) Publisher {
+	return PublisherFunc(func(ctx context.Context, rec *Record) error {
+		attrs := maps.Clone(rec.Attributes)
+		if attrs == nil {
+			attrs = map[string]string{}
+		}
+		attrs[AttributeID] = rec.ID
+		_, err := c.Publish(ctx, rec.Topic, &pubsub.Message{
+			Data:        rec.Payload,
+			Attributes:  attrs,
+			OrderingKey: rec.OrderingKey,
+		})
+		return err
+	})
+}
+
+// EventBus publishes each *Record onto bus. The eventbus never blocks, so
+// records are dropped for subscribers that are full.
+func EventBus(bus eventbus.EventBus) Publisher {
+	return PublisherFunc(func(_ context.Context, rec *Record) error {
+		bus.Publish(rec)
+		return nil
+	})
+}
+
+type RelayConfig struct {
+	BatchSize    int           `koanf:"batch_size" json:"batch_size" envconfig:"batch_size"`
+	PollInterval time.Duration `koanf:"poll_interval" json:"poll_interval" envconfig:"poll_interval"`
+	// MaxAttempts is how many times an event is published before it is
+	// dead-lettered. Defaults to DefaultMaxAttempts.
+	MaxAttempts int `koanf:"max_attempts" json:"max_attempts" envconfig:"max_attempts"`
+}
+
+func (c *RelayConfig) Validate() error {
+	if c == nil {
+		return errors.New("outbox relay configuration required")
+	}
+	if c.BatchSize < 0 || c.PollInterval < 0 || c.MaxAttempts < 0 {
+		return errors.New("outbox relay settings must not be negative")
+	}
+	return nil
+}
+
+type RelayOption func(*Relay)
+
+// WithMetrics records published and failed events, labelled by topic.
+func WithMetrics(m *metrics.Metrics) RelayOption {
+	return func(r *Relay) {
+		events, err := m.NewCounterVec("outbox", "events_total", "Outbox events relayed by result.", "topic", "result")
+		if err != nil {
+			r.log.Warn("registering outbox metrics", "error", err)
+			return
+		}
+		r.events = events
+	}
+}
+
+// Relay publishes unpublished outbox events.
+type Relay struct {
+	outbox      *Outbox
+	pub         Publisher
+	log         *slog.Logger
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	events      *prometheus.CounterVec
+}
+
+func (o *Outbox) NewRelay(cfg *RelayConfig, pub Publisher, opts ...RelayOption) (*Relay, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Relay{
+		outbox:      o,
+		pub:         pub,
+		log:         o.log,
+		batchSize:   cfg.BatchSize,
+		interval:    cfg.PollInterval,
+		maxAttempts: cfg.MaxAttempts,
+	}
+	if r.batchSize == 0 {
+		r.batchSize = DefaultBatchSize
+	}
+	if r.interval == 0 {
+		r.interval = DefaultPollInterval
+	}
+	if r.maxAttempts == 0 {
+		r.maxAttempts = DefaultMaxAttempts
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Run relays events every poll interval until ctx is cancelled. A full batch
+// is followed immediately by the next one. After a batch in which nothing
+// could be published, Run backs off exponentially, up to five minutes.
+func (r *Relay) Run(ctx context.Context) error {
+	var failed int
+	for {
+		read, published, err := r.relay(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.log.Error("outbox relay failed", "error", err)
+		}
+
+		wait := r.interval
+		switch {
+		case err == nil && read > 0 && published == 0:
+			failed++
+			wait = retry.Backoff(failed, retry.WithBackoff(r.interval, max(r.interval, maxBackoff)))
+		case err == nil && read == r.batchSize:
+			failed = 0
+			wait = 0
+		default:
+			failed = 0
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RelayOnce publishes one batch of events in creation order and returns how
+// many were read. When an event fails, later events with the same ordering
+// key are held back until the next run so they are not delivered out of
+// order. The results of the batch are recorded with one update for the
+// published events and one for the failed.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	read, _, err := r.relay(ctx)
+	return read, err
+}
+
+// relay is RelayOnce, also returning how many events were published.
+func (r *Relay) relay(ctx context.Context) (int, int, error) {
+	rows, err := r.outbox.store.pending(ctx, r.batchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var published []string
+	var failures []failure
+	blocked := map[string]bool{}
+	for _, row := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		if row.OrderingKey != "" && blocked[row.OrderingKey] {
+			continue
+		}
+
+		rec, err := row.record()
+		if err == nil {
+			err = r.pub.Publish(ctx, rec)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			r.count(row.Topic, "error")
+			attempt := int(row.Attempts) + 1
+			if attempt >= r.maxAttempts {
+				r.log.Error("dead-lettering outbox event", "id", row.ID, "topic", row.Topic, "attempt", attempt, "error", err)
+			} else {
+				r.log.Warn("publishing outbox event", "id", row.ID, "topic", row.Topic, "attempt", attempt, "error", err)
+			}
+			if row.OrderingKey != "" {
+				blocked[row.OrderingKey] = true
+			}
+			failures = append(failures, failure{ID: row.ID, Error: err.Error()})
+			continue
+		}
+		published = append(published, row.ID)
+		r.count(row.Topic, "published")
+	}
+
+	// Record what was done even if ctx was cancelled part way, so that the
+	// published events are not published again.
+	mctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), markTimeout)
+	defer cancel()
+	if len(published) > 0 {
+		if err := r.outbox.store.markPublished(mctx, published); err != nil {
+			return len(rows), 0, errors.Wrapf(err, "marking %d outbox events published", len(published))
+		}
+	}
+	if len(failures) > 0 {
+		if err := r.outbox.store.markFailed(mctx, failures, r.maxAttempts); err != nil {
+			return len(rows), len(published), errors.Wrapf(err, "recording %d outbox event failures", len(failures))
+		}
+	}
+	return len(rows), len(published), ctx.Err()
+}
+
+func (r *Relay) count(topic, result string) {
+	if r.events != nil {
+		r.events.WithLabelValues(topic, result).Inc()
+	}
+}
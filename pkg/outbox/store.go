@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+type bqStore struct {
+	client bqclient.BQClient
+	table  string
+}
+
+func (s *bqStore) exec(ctx context.Context, sql string, params []bigquery.QueryParameter) error {
+	return bqclient.Exec(ctx, s.client, sql, params)
+}
+
+func (s *bqStore) pending(ctx context.Context, limit int) ([]row, error) {
+	it, err := s.client.Query(ctx, `
+        SELECT id, topic, ordering_key, attributes, payload, created_at, attempts
+        FROM `+s.table+`
+        WHERE published_at IS NULL AND dead_lettered_at IS NULL
+        ORDER BY created_at, id
+        LIMIT `+strconv.Itoa(limit), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading outbox")
+	}
+
+	var rows []row
+	for {
+		var r row
+		err := it.Next(&r)
+		if err == iterator.Done {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading outbox")
+		}
+		rows = append(rows, r)
+	}
+}
+
+// markPublished only updates unpublished rows, so marking is idempotent.
+func (s *bqStore) markPublished(ctx context.Context, ids []string) error {
+	return s.exec(ctx, `
+        UPDATE `+s.table+`
+        SET published_at = CURRENT_TIMESTAMP(), attempts = attempts + 1, last_error = NULL
+        WHERE id IN UNNEST(@ids) AND published_at IS NULL`, []bigquery.QueryParameter{
+		{Name: "ids", Value: ids},
+	})
+}
+
+// markFailed records a failed attempt of each event and dead-letters those
+// that have reached maxAttempts.
+func (s *bqStore) markFailed(ctx context.Context, failures []failure, maxAttempts int) error {
+	return s.exec(ctx, `
+        UPDATE `+s.table+` o
+        SET attempts = o.attempts + 1, last_error = f.error,
+            dead_lettered_at = IF(o.attempts + 1 >= @max_attempts, CURRENT_TIMESTAMP(), NULL)
+        FROM UNNEST(@failures) f
+        WHERE o.id = f.id AND o.published_at IS NULL`, []bigquery.QueryParameter{
+		{Name: "failures", Value: failures},
+		{Name: "max_attempts", Value: maxAttempts},
+	})
+}
@@ -0,0 +1,77 @@
+// Package pagination gives grid-stream list APIs one cursor format and one set
+// of request and response types. Cursors are opaque to clients: the key
+// fields of the last item, or an upstream page token such as BigQuery's,
+// encoded as base64 JSON and signed with HMAC-SHA256 so clients cannot forge
+// or edit them.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TokenCursor wraps a page token from a backend that paginates itself, such
+// as bqclient, so it is signed like any other cursor.
+type TokenCursor struct {
+	Token string `json:"t"`
+}
+
+// Codec signs and verifies cursors with a shared secret. Every replica of a
+// service must use the same secret.
+type Codec struct {
+	secret []byte
+}
+
+func NewCodec(secret string) (*Codec, error) {
+	if len(secret) < 16 {
+		return nil, errors.New("pagination secret must be at least 16 bytes")
+	}
+	return &Codec{secret: []byte(secret)}, nil
+}
+
+// Encode returns the cursor for key, which must marshal to JSON.
+func Encode[K any](c *Codec, key K) (string, error) {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return "", errors.Wrap(err, "encoding cursor")
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies cursor and unmarshals its key. Any malformed or tampered
+// cursor yields ErrInvalidCursor, which APIs should report as a 400.
+func Decode[K any](c *Codec, cursor string) (K, error) {
+	var key K
+	enc := base64.RawURLEncoding
+
+	p, s, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return key, ErrInvalidCursor
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return key, ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, c.sign(payload)) {
+		return key, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &key); err != nil {
+		return key, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	return key, nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
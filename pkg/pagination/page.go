@@ -0,0 +1,135 @@
+package pagination
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	PageSizeParam = "page_size"
+	CursorParam   = "cursor"
+)
+
+type Config struct {
+	Secret          string `koanf:"secret" json:"secret" envconfig:"secret"`
+	DefaultPageSize int    `koanf:"default_page_size" json:"default_page_size" envconfig:"default_page_size"`
+	MaxPageSize     int    `koanf:"max_page_size" json:"max_page_size" envconfig:"max_page_size"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("pagination configuration required")
+	}
+	if len(c.Secret) < 16 {
+		return errors.New("pagination secret must be at least 16 bytes")
+	}
+	if c.DefaultPageSize <= 0 {
+		return errors.New("pagination default page size must be greater than 0")
+	}
+	if c.MaxPageSize < c.DefaultPageSize {
+		return errors.New("pagination max page size must not be less than the default page size")
+	}
+	return nil
+}
+
+// PageRequest is a client's request for one page. An empty Cursor asks for
+// the first page.
+type PageRequest struct {
+	Size   int    `json:"page_size"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Page is the response envelope for list APIs. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Paginator applies a service's page size limits and cursor secret.
+type Paginator struct {
+	cfg   *Config
+	codec *Codec
+}
+
+func New(cfg *Config) (*Paginator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	codec, err := NewCodec(cfg.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &Paginator{cfg: cfg, codec: codec}, nil
+}
+
+func (p *Paginator) Codec() *Codec {
+	return p.codec
+}
+
+// ParseQuery reads page_size and cursor from URL query parameters. A missing
+// size uses the default and sizes above the maximum are clamped.
+func (p *Paginator) ParseQuery(q url.Values) (PageRequest, error) {
+	req := PageRequest{Size: p.cfg.DefaultPageSize, Cursor: q.Get(CursorParam)}
+	if v := q.Get(PageSizeParam); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return PageRequest{}, errors.Errorf("%s must be a positive integer", PageSizeParam)
+		}
+		req.Size = min(n, p.cfg.MaxPageSize)
+	}
+	return req, nil
+}
+
+// Cursor decodes req.Cursor into a key. ok is false for the first page.
+func Cursor[K any](p *Paginator, req PageRequest) (key K, ok bool, err error) {
+	if req.Cursor == "" {
+		return key, false, nil
+	}
+	key, err = Decode[K](p.codec, req.Cursor)
+	return key, err == nil, err
+}
+
+// NewPage builds a page from up to req.Size+1 fetched items. Fetching one
+// extra item tells whether another page exists without a count query; when
+// it does, the cursor is built from the last returned item by keyFn.
+func NewPage[T, K any](p *Paginator, req PageRequest, items []T, keyFn func(T) K) (Page[T], error) {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= req.Size {
+		return Page[T]{Items: items}, nil
+	}
+
+	items = items[:req.Size]
+	next, err := Encode(p.codec, keyFn(items[len(items)-1]))
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Items: items, NextCursor: next}, nil
+}
+
+// NewTokenPage builds a page for backends that return their own next page
+// token, wrapping the token in a signed TokenCursor.
+func NewTokenPage[T any](p *Paginator, items []T, nextToken string) (Page[T], error) {
+	if items == nil {
+		items = []T{}
+	}
+	if nextToken == "" {
+		return Page[T]{Items: items}, nil
+	}
+	next, err := Encode(p.codec, TokenCursor{Token: nextToken})
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Items: items, NextCursor: next}, nil
+}
+
+// Token returns the backend page token carried by req.Cursor, or "" for the
+// first page.
+func (p *Paginator) Token(req PageRequest) (string, error) {
+	c, _, err := Cursor[TokenCursor](p, req)
+	return c.Token, err
+}
@@ -0,0 +1,163 @@
+package pagination
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const secret = "0123456789abcdef"
+
+type derKey struct {
+	Timestamp time.Time `json:"ts"`
+	DERID     string    `json:"der_id"`
+}
+
+type PaginationTestSuite struct {
+	suite.Suite
+	p *Paginator
+}
+
+func (s *PaginationTestSuite) SetupTest() {
+	p, err := New(&Config{Secret: secret, DefaultPageSize: 2, MaxPageSize: 10})
+	s.Require().NoError(err)
+	s.p = p
+}
+
+func (s *PaginationTestSuite) TestCursorRoundTrip() {
+	key := derKey{Timestamp: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC), DERID: "der-1"}
+	cursor, err := Encode(s.p.Codec(), key)
+	s.Require().NoError(err)
+	s.NotContains(cursor, "der-1")
+
+	got, err := Decode[derKey](s.p.Codec(), cursor)
+	s.Require().NoError(err)
+	s.Equal(key, got)
+}
+
+func (s *PaginationTestSuite) TestTamperedCursors() {
+	cursor, err := Encode(s.p.Codec(), derKey{DERID: "der-1"})
+	s.Require().NoError(err)
+	payload, sig, _ := strings.Cut(cursor, ".")
+
+	other, err := NewCodec("another-secret-value")
+	s.Require().NoError(err)
+	forged, err := Encode(other, derKey{DERID: "der-9"})
+	s.Require().NoError(err)
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	for _, c := range []string{"", "abc", payload, payload + ".", forged, forgedPayload + "." + sig, "!!." + sig} {
+		_, err := Decode[derKey](s.p.Codec(), c)
+		s.ErrorIs(err, ErrInvalidCursor, c)
+	}
+
+	_, err = NewCodec("short")
+	s.Error(err)
+}
+
+func (s *PaginationTestSuite) TestParseQuery() {
+	req, err := s.p.ParseQuery(url.Values{})
+	s.Require().NoError(err)
+	s.Equal(PageRequest{Size: 2}, req)
+
+	req, err = s.p.ParseQuery(url.Values{PageSizeParam: {"50"}, CursorParam: {"abc"}})
+	s.Require().NoError(err)
+	s.Equal(PageRequest{Size: 10, Cursor: "abc"}, req)
+
+	for _, bad := range []string{"0", "-1", "ten"} {
+		_, err := s.p.ParseQuery(url.Values{PageSizeParam: {bad}})
+		s.Error(err, bad)
+	}
+}
+
+func (s *PaginationTestSuite) TestKeysetPages() {
+	ders := []string{"der-1", "der-2", "der-3", "der-4", "der-5"}
+	keyFn := func(id string) derKey { return derKey{DERID: id} }
+
+	// Simulate a store returning up to Size+1 rows after the cursor key.
+	fetch := func(req PageRequest) Page[string] {
+		after, ok, err := Cursor[derKey](s.p, req)
+		s.Require().NoError(err)
+		start := 0
+		if ok {
+			for i, id := range ders {
+				if id == after.DERID {
+					start = i + 1
+				}
+			}
+		}
+		end := min(start+req.Size+1, len(ders))
+		page, err := NewPage(s.p, req, ders[start:end], keyFn)
+		s.Require().NoError(err)
+		return page
+	}
+
+	var seen []string
+	req := PageRequest{Size: 2}
+	for {
+		page := fetch(req)
+		s.LessOrEqual(len(page.Items), 2)
+		seen = append(seen, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+	s.Equal(ders, seen)
+
+	empty, err := NewPage(s.p, PageRequest{Size: 2}, nil, keyFn)
+	s.Require().NoError(err)
+	b, _ := json.Marshal(empty)
+	s.JSONEq(`{"items":[]}`, string(b))
+}
+
+func (s *PaginationTestSuite) TestTokenPages() {
+	page, err := NewTokenPage(s.p, []int{1, 2}, "bq-token-123")
+	s.Require().NoError(err)
+	s.NotEmpty(page.NextCursor)
+
+	token, err := s.p.Token(PageRequest{Cursor: page.NextCursor})
+	s.Require().NoError(err)
+	s.Equal("bq-token-123", token)
+
+	token, err = s.p.Token(PageRequest{})
+	s.NoError(err)
+	s.Empty(token)
+
+	last, err := NewTokenPage(s.p, []int{3}, "")
+	s.Require().NoError(err)
+	s.Empty(last.NextCursor)
+}
+
+func (s *PaginationTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Secret: secret, DefaultPageSize: 50, MaxPageSize: 500}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "short secret", cfg: &Config{Secret: "abc", DefaultPageSize: 50, MaxPageSize: 500}, expectError: true},
+		{name: "no default size", cfg: &Config{Secret: secret, MaxPageSize: 500}, expectError: true},
+		{name: "max below default", cfg: &Config{Secret: secret, DefaultPageSize: 50, MaxPageSize: 10}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestPaginationSuite(t *testing.T) {
+	suite.Run(t, new(PaginationTestSuite))
+}
@@ -0,0 +1,256 @@
+// Package pipeline reads a BigQuery table through the Storage read API and
+// publishes its decoded rows on an event bus, replacing the read, decode,
+// batch and publish goroutines each service would otherwise write:
+//
+//	p, err := pipeline.New[DERRow](cfg.Pipeline, bq, bus, log,
+//		pipeline.WithCheckpoint[DERRow](store))
+//	...
+//	err = p.Run(ctx)
+//
+// Subscribers receive Event values and pick theirs out by type and Topic:
+//
+//	for ev := range bus.Subscribe(64) {
+//		if ev, ok := ev.(pipeline.Event[DERRow]); ok && ev.Topic == "der_data" {
+//			...
+//		}
+//	}
+//
+// Rows are decoded with the read session's schema into T, whose fields are
+// matched to columns by bigquery tag. Events are published with
+// PublishWait, so a full subscriber slows the read down rather than losing
+// rows.
+//
+// A failed read is retried from the start of the table, skipping the rows
+// already published. With a checkpoint store the published row count is
+// saved too, so a restarted pipeline resumes instead of republishing. Both
+// rely on every read returning the same rows in the same order, so the
+// pipeline reads a single stream at the snapshot time of its first run,
+// saved as the checkpoint's Watermark, whatever the client's ReadStreams.
+// BigQuery keeps snapshots for seven days, so an older checkpoint cannot be
+// resumed and must be deleted to start over.
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/batcher"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/checkpoint"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+)
+
+const DefaultCheckpointInterval = 5 * time.Second
+
+type Config struct {
+	// Table is read with StreamReadRows, filtered to ProjectIDs when set.
+	Table      string   `koanf:"table" json:"table" envconfig:"table"`
+	ProjectIDs []string `koanf:"project_ids" json:"project_ids" envconfig:"project_ids"`
+	// Topic is set on every published Event.
+	Topic string `koanf:"topic" json:"topic" envconfig:"topic"`
+	// Batch groups rows into events. Without it each row is published as
+	// its own event.
+	Batch *batcher.Config `koanf:"batch" json:"batch" envconfig:"batch"`
+	// Retry restarts a failed read.
+	Retry retry.Config `koanf:"retry" json:"retry" envconfig:"retry"`
+	// Checkpoint names the checkpoint saved to the store given with
+	// WithCheckpoint. It defaults to "pipeline-<table>-<topic>".
+	Checkpoint         string        `koanf:"checkpoint" json:"checkpoint" envconfig:"checkpoint"`
+	CheckpointInterval time.Duration `koanf:"checkpoint_interval" json:"checkpoint_interval" envconfig:"checkpoint_interval"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("pipeline configuration required")
+	}
+	if c.Table == "" {
+		return errors.New("pipeline table required")
+	}
+	if c.Topic == "" {
+		return errors.New("pipeline topic required")
+	}
+	if c.Batch != nil {
+		if err := c.Batch.Validate(); err != nil {
+			return errors.Wrap(err, "pipeline batch")
+		}
+	}
+	if err := c.Retry.Validate(); err != nil {
+		return errors.Wrap(err, "pipeline retry")
+	}
+	if c.CheckpointInterval < 0 {
+		return errors.New("pipeline checkpoint interval must not be negative")
+	}
+	return nil
+}
+
+// Event is published for each group of rows.
+type Event[T any] struct {
+	Topic string
+	Rows  []T
+}
+
+type Option[T any] func(*Pipeline[T])
+
+// WithCheckpoint saves progress to store and resumes from it in Run.
+func WithCheckpoint[T any](store checkpoint.Store) Option[T] {
+	return func(p *Pipeline[T]) {
+		p.store = store
+	}
+}
+
+type Pipeline[T any] struct {
+	cfg   *Config
+	bq    bqclient.BQClient
+	bus   eventbus.EventBus
+	log   *slog.Logger
+	store checkpoint.Store
+
+	// snapshot is the time every read of the table is pinned to.
+	snapshot time.Time
+	// read counts the rows handed on for publishing and published those
+	// actually published; they differ by the rows held in the batcher.
+	read      int64
+	mu        sync.Mutex
+	published int64
+	committer *checkpoint.Committer
+}
+
+func New[T any](cfg *Config, bq bqclient.BQClient, bus eventbus.EventBus, log *slog.Logger, opts ...Option[T]) (*Pipeline[T], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline[T]{cfg: cfg, bq: bq, bus: bus, log: log}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
+
+// Run reads the table once, publishing every row, and returns when the read
+// completes, fails after its retries, or ctx is done. Rows already published
+// according to the checkpoint are skipped. A Pipeline must not be run
+// concurrently with itself.
+func (p *Pipeline[T]) Run(ctx context.Context) (err error) {
+	p.snapshot = time.Now().UTC()
+	if p.store != nil {
+		name := p.cfg.Checkpoint
+		if name == "" {
+			name = "pipeline-" + p.cfg.Table + "-" + p.cfg.Topic
+		}
+		cp, lerr := checkpoint.LoadOrZero(ctx, p.store, name)
+		if lerr != nil {
+			return errors.Wrap(lerr, "loading pipeline checkpoint")
+		}
+		p.read, p.published = cp.Offset, cp.Offset
+		if cp.Offset > 0 && !cp.Watermark.IsZero() {
+			p.snapshot = cp.Watermark
+		}
+
+		interval := p.cfg.CheckpointInterval
+		if interval == 0 {
+			interval = DefaultCheckpointInterval
+		}
+		p.committer = checkpoint.NewCommitter(p.store, name, interval, p.log)
+		commitCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() { done <- p.committer.Run(commitCtx) }()
+		defer func() {
+			cancel()
+			if cerr := <-done; err == nil {
+				err = cerr
+			}
+		}()
+	}
+
+	// The batcher flushes with a background context, so deliveries use
+	// ctx to stop waiting on subscribers once Run is cancelled.
+	publish := func(_ context.Context, rows []T) error {
+		return p.publish(ctx, rows)
+	}
+	if p.cfg.Batch != nil {
+		b, berr := batcher.New(p.cfg.Batch, publish, p.log)
+		if berr != nil {
+			return berr
+		}
+		defer func() {
+			if cerr := b.Close(context.WithoutCancel(ctx)); err == nil {
+				err = cerr
+			}
+		}()
+		publish = func(ctx context.Context, rows []T) error {
+			for _, row := range rows {
+				if err := b.Add(ctx, row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	opts := append(p.cfg.Retry.Options(), retry.WithOnRetry(func(attempt int, err error, delay time.Duration) {
+		p.log.Warn("pipeline read failed, retrying", "table", p.cfg.Table, "attempt", attempt, "retry_in", delay, "error", err)
+	}))
+	err = retry.Do(ctx, func(ctx context.Context) error {
+		return p.stream(ctx, publish)
+	}, opts...)
+	if err != nil {
+		return errors.Wrapf(err, "streaming %s to %s", p.cfg.Table, p.cfg.Topic)
+	}
+	p.log.Info("pipeline read complete", "table", p.cfg.Table, "topic", p.cfg.Topic, "rows", p.read)
+	return nil
+}
+
+// stream makes one pass over the table, skipping the first p.read rows.
+func (p *Pipeline[T]) stream(ctx context.Context, publish batcher.FlushFunc[T]) error {
+	ctx, cancel := context.WithCancel(bqclient.WithReadStreams(bqclient.WithSnapshotTime(ctx, p.snapshot), 1))
+	var filter *bqclient.ReadFilter
+	if len(p.cfg.ProjectIDs) > 0 {
+		filter = bqclient.Filter("project_id", bqclient.In, p.cfg.ProjectIDs)
+	}
+	rows, errs := p.bq.StreamReadRows(ctx, p.cfg.Table, filter, func() any { return new(T) })
+	// Drain on an early return so the reader is not left blocked on a send.
+	defer func() {
+		cancel()
+		for range rows {
+		}
+	}()
+
+	var seen int64
+	for v := range rows {
+		seen++
+		if seen <= p.read {
+			continue
+		}
+		row, ok := v.(*T)
+		if !ok {
+			return retry.Permanent(errors.Errorf("unexpected row type %T", v))
+		}
+		if err := publish(ctx, []T{*row}); err != nil {
+			return err
+		}
+		p.read++
+	}
+	return <-errs
+}
+
+// publish delivers rows to every subscriber and only then counts them as
+// published, so a checkpoint never covers rows that were not delivered.
+func (p *Pipeline[T]) publish(ctx context.Context, rows []T) error {
+	if err := p.bus.PublishWait(ctx, Event[T]{Topic: p.cfg.Topic, Rows: rows}); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.published += int64(len(rows))
+	offset := p.published
+	p.mu.Unlock()
+	if p.committer != nil {
+		p.committer.Mark(checkpoint.Checkpoint{Offset: offset, Watermark: p.snapshot})
+	}
+	return nil
+}
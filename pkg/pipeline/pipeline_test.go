@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/batcher"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/checkpoint"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type row struct {
+	DERID         string  `bigquery:"der_id"`
+	CurrentOutput float64 `bigquery:"current_output"`
+}
+
+// fakeBQ serves rows from StreamReadRows. The nth read fails with
+// failures[n], if any, once failAfter[n] rows have been sent.
+type fakeBQ struct {
+	bqclient.BQClient
+	rows      []row
+	failAfter []int
+	failures  []error
+	reads     int
+}
+
+func (f *fakeBQ) StreamReadRows(ctx context.Context, table string, filter *bqclient.ReadFilter, newRow func() any) (<-chan any, <-chan error) {
+	rows := make(chan any)
+	errs := make(chan error, 1)
+	fail, failure := -1, errors.New("stream reset")
+	if f.reads < len(f.failAfter) {
+		fail = f.failAfter[f.reads]
+	}
+	if f.reads < len(f.failures) {
+		failure = f.failures[f.reads]
+	}
+	f.reads++
+
+	go func() {
+		defer close(rows)
+		defer close(errs)
+		for i, r := range f.rows {
+			if i == fail {
+				errs <- failure
+				return
+			}
+			v := newRow().(*row)
+			*v = r
+			select {
+			case rows <- v:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return rows, errs
+}
+
+type PipelineTestSuite struct {
+	suite.Suite
+	bq  *fakeBQ
+	bus eventbus.EventBus
+	sub chan any
+}
+
+func (s *PipelineTestSuite) SetupTest() {
+	s.bq = &fakeBQ{rows: []row{{"der-1", 1}, {"der-2", 2}, {"der-3", 3}, {"der-4", 4}, {"der-5", 5}}}
+	s.bus = eventbus.New()
+	s.sub = s.bus.Subscribe(100)
+}
+
+func (s *PipelineTestSuite) config() *Config {
+	return &Config{
+		Table: "der_data",
+		Topic: "der",
+		Retry: retry.Config{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: time.Millisecond},
+	}
+}
+
+func (s *PipelineTestSuite) run(cfg *Config, opts ...Option[row]) error {
+	return s.runContext(context.Background(), cfg, opts...)
+}
+
+func (s *PipelineTestSuite) runContext(ctx context.Context, cfg *Config, opts ...Option[row]) error {
+	p, err := New(cfg, s.bq, s.bus, slog.New(slog.NewTextHandler(io.Discard, nil)), opts...)
+	s.Require().NoError(err)
+	return p.Run(ctx)
+}
+
+// events returns the events published so far.
+func (s *PipelineTestSuite) events() [][]string {
+	var out [][]string
+	for {
+		select {
+		case ev := <-s.sub:
+			out = append(out, s.ids(ev))
+		default:
+			return out
+		}
+	}
+}
+
+// next waits for the next event.
+func (s *PipelineTestSuite) next() []string {
+	select {
+	case ev := <-s.sub:
+		return s.ids(ev)
+	case <-time.After(time.Second):
+		s.FailNow("no event published")
+		return nil
+	}
+}
+
+func (s *PipelineTestSuite) ids(ev any) []string {
+	e := ev.(Event[row])
+	s.Equal("der", e.Topic)
+	ids := make([]string, len(e.Rows))
+	for i, r := range e.Rows {
+		ids[i] = r.DERID
+	}
+	return ids
+}
+
+func (s *PipelineTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Table: "der_data", Topic: "der"}},
+		{name: "batched", cfg: &Config{Table: "der_data", Topic: "der", Batch: &batcher.Config{MaxItems: 10}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing table", cfg: &Config{Topic: "der"}, expectError: true},
+		{name: "missing topic", cfg: &Config{Table: "der_data"}, expectError: true},
+		{name: "invalid batch", cfg: &Config{Table: "der_data", Topic: "der", Batch: &batcher.Config{}}, expectError: true},
+		{name: "invalid retry", cfg: &Config{Table: "der_data", Topic: "der", Retry: retry.Config{Jitter: 2}}, expectError: true},
+		{name: "negative interval", cfg: &Config{Table: "der_data", Topic: "der", CheckpointInterval: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *PipelineTestSuite) TestPublishesRows() {
+	s.Require().NoError(s.run(s.config()))
+	s.Equal([][]string{{"der-1"}, {"der-2"}, {"der-3"}, {"der-4"}, {"der-5"}}, s.events())
+}
+
+func (s *PipelineTestSuite) TestBatches() {
+	cfg := s.config()
+	cfg.Batch = &batcher.Config{MaxItems: 2}
+	s.Require().NoError(s.run(cfg))
+	s.Equal([][]string{{"der-1", "der-2"}, {"der-3", "der-4"}, {"der-5"}}, s.events())
+}
+
+func (s *PipelineTestSuite) TestRetrySkipsPublishedRows() {
+	s.bq.failAfter = []int{2, 3}
+	s.Require().NoError(s.run(s.config()))
+	s.Equal(3, s.bq.reads)
+	s.Equal([][]string{{"der-1"}, {"der-2"}, {"der-3"}, {"der-4"}, {"der-5"}}, s.events())
+}
+
+func (s *PipelineTestSuite) TestRetriesExhausted() {
+	s.bq.failAfter = []int{0, 0, 0}
+	s.ErrorContains(s.run(s.config()), "giving up after 3 attempts: stream reset")
+	s.Empty(s.events())
+}
+
+func (s *PipelineTestSuite) TestPermanentErrorStopsRetries() {
+	s.bq.failAfter = []int{0}
+	s.bq.failures = []error{retry.Permanent(errors.New("decoding rows: bad block"))}
+	s.ErrorContains(s.run(s.config()), "decoding rows")
+	s.Equal(1, s.bq.reads)
+}
+
+func (s *PipelineTestSuite) TestFullSubscriberLosesNoRows() {
+	ctx := context.Background()
+	store := checkpoint.NewMemoryStore()
+	s.bus = eventbus.New()
+	s.sub = s.bus.Subscribe(1)
+
+	// Stop the run while the pipeline waits on the full subscriber.
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- s.runContext(runCtx, s.config(), WithCheckpoint[row](store)) }()
+	got := [][]string{s.next(), s.next()}
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	s.Error(<-done)
+	got = append(got, s.events()...)
+
+	cp, err := store.Load(ctx, "pipeline-der_data-der")
+	s.Require().NoError(err)
+	s.Equal(int64(len(got)), cp.Offset, "only delivered rows are checkpointed")
+
+	// The restarted pipeline delivers the rest while a slow reader drains.
+	done = make(chan error, 1)
+	go func() { done <- s.runContext(ctx, s.config(), WithCheckpoint[row](store)) }()
+	for len(got) < len(s.bq.rows) {
+		time.Sleep(time.Millisecond)
+		got = append(got, s.next())
+	}
+	s.Require().NoError(<-done)
+	s.Equal([][]string{{"der-1"}, {"der-2"}, {"der-3"}, {"der-4"}, {"der-5"}}, got)
+	s.Empty(s.events())
+}
+
+func (s *PipelineTestSuite) TestCheckpointResume() {
+	ctx := context.Background()
+	store := checkpoint.NewMemoryStore()
+	s.Require().NoError(store.Save(ctx, &checkpoint.Checkpoint{Name: "pipeline-der_data-der", Offset: 3}))
+
+	s.Require().NoError(s.run(s.config(), WithCheckpoint[row](store)))
+	s.Equal([][]string{{"der-4"}, {"der-5"}}, s.events())
+
+	cp, err := store.Load(ctx, "pipeline-der_data-der")
+	s.Require().NoError(err)
+	s.Equal(int64(5), cp.Offset)
+}
+
+func (s *PipelineTestSuite) TestCheckpointPinsSnapshot() {
+	ctx := context.Background()
+	store := checkpoint.NewMemoryStore()
+	s.Require().NoError(s.run(s.config(), WithCheckpoint[row](store)))
+	cp, err := store.Load(ctx, "pipeline-der_data-der")
+	s.Require().NoError(err)
+	s.False(cp.Watermark.IsZero())
+
+	snapshot := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	s.Require().NoError(store.Save(ctx, &checkpoint.Checkpoint{Name: "pipeline-der_data-der", Offset: 3, Watermark: snapshot}))
+	s.Require().NoError(s.run(s.config(), WithCheckpoint[row](store)))
+	cp, err = store.Load(ctx, "pipeline-der_data-der")
+	s.Require().NoError(err)
+	s.True(snapshot.Equal(cp.Watermark))
+}
+
+func (s *PipelineTestSuite) TestCheckpointAfterBatchFlush() {
+	ctx := context.Background()
+	store := checkpoint.NewMemoryStore()
+	cfg := s.config()
+	cfg.Checkpoint = "der-backfill"
+	cfg.Batch = &batcher.Config{MaxItems: 4}
+
+	s.Require().NoError(s.run(cfg, WithCheckpoint[row](store)))
+	s.Equal([][]string{{"der-1", "der-2", "der-3", "der-4"}, {"der-5"}}, s.events())
+
+	cp, err := store.Load(ctx, "der-backfill")
+	s.Require().NoError(err)
+	s.Equal(int64(5), cp.Offset)
+}
+
+func TestPipelineTestSuite(t *testing.T) {
+	suite.Run(t, new(PipelineTestSuite))
+}
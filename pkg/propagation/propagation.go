@@ -0,0 +1,125 @@
+// Package propagation carries W3C trace context across hops that are not
+// HTTP or gRPC: eventbus events, Pub/Sub message attributes and MQTT 5 user
+// properties. It uses the global propagator installed by otel.Setup.
+//
+//	msg := &pubsub.Message{Data: data}
+//	propagation.InjectPubSub(ctx, msg)
+//	...
+//	ctx = propagation.ExtractPubSub(ctx, msg)
+package propagation
+
+import (
+	"context"
+
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"go.opentelemetry.io/otel"
+	otelprop "go.opentelemetry.io/otel/propagation"
+)
+
+// Inject writes the trace context in ctx to carrier.
+func Inject(ctx context.Context, carrier otelprop.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// Extract returns ctx with the trace context read from carrier.
+func Extract(ctx context.Context, carrier otelprop.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// InjectAttributes writes the trace context in ctx to attrs, allocating it if
+// nil, and returns it.
+func InjectAttributes(ctx context.Context, attrs map[string]string) map[string]string {
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+	Inject(ctx, otelprop.MapCarrier(attrs))
+	return attrs
+}
+
+// ExtractAttributes returns ctx with the trace context read from attrs.
+func ExtractAttributes(ctx context.Context, attrs map[string]string) context.Context {
+	return Extract(ctx, otelprop.MapCarrier(attrs))
+}
+
+// InjectPubSub adds the trace context in ctx to msg's attributes.
+func InjectPubSub(ctx context.Context, msg *pubsub.Message) {
+	msg.Attributes = InjectAttributes(ctx, msg.Attributes)
+}
+
+// ExtractPubSub returns ctx with the trace context from msg's attributes.
+func ExtractPubSub(ctx context.Context, msg *pubsub.Message) context.Context {
+	return ExtractAttributes(ctx, msg.Attributes)
+}
+
+// Envelope wraps an eventbus event with the trace context of its publisher.
+type Envelope struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Event   any               `json:"event"`
+}
+
+// Wrap returns event in an Envelope carrying the trace context in ctx.
+func Wrap(ctx context.Context, event any) *Envelope {
+	return &Envelope{Headers: InjectAttributes(ctx, nil), Event: event}
+}
+
+// Unwrap returns the event inside v and ctx with its trace context. Values
+// that are not an *Envelope are returned unchanged with ctx.
+func Unwrap(ctx context.Context, v any) (context.Context, any) {
+	env, ok := v.(*Envelope)
+	if !ok {
+		return ctx, v
+	}
+	return ExtractAttributes(ctx, env.Headers), env.Event
+}
+
+// UserProperty is an MQTT 5 user property.
+type UserProperty struct {
+	Key   string
+	Value string
+}
+
+// UserProperties is a TextMapCarrier over MQTT 5 user properties. mqttclient
+// speaks MQTT 3.1.1, which has no properties, so this is for services using
+// an MQTT 5 client; convert to and from the client's own property type.
+type UserProperties []UserProperty
+
+func (p *UserProperties) Get(key string) string {
+	for _, prop := range *p {
+		if prop.Key == key {
+			return prop.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces any existing properties named key. MQTT allows repeated keys,
+// but trace context fields must be unique.
+func (p *UserProperties) Set(key, value string) {
+	props := (*p)[:0]
+	for _, prop := range *p {
+		if prop.Key != key {
+			props = append(props, prop)
+		}
+	}
+	*p = append(props, UserProperty{Key: key, Value: value})
+}
+
+func (p *UserProperties) Keys() []string {
+	keys := make([]string, len(*p))
+	for i, prop := range *p {
+		keys[i] = prop.Key
+	}
+	return keys
+}
+
+// InjectUserProperties adds the trace context in ctx to props and returns
+// the result.
+func InjectUserProperties(ctx context.Context, props UserProperties) UserProperties {
+	Inject(ctx, &props)
+	return props
+}
+
+// ExtractUserProperties returns ctx with the trace context read from props.
+func ExtractUserProperties(ctx context.Context, props UserProperties) context.Context {
+	return Extract(ctx, &props)
+}
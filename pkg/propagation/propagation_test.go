@@ -0,0 +1,75 @@
+package propagation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/pubsub"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	otelprop "go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type PropagationTestSuite struct {
+	suite.Suite
+	ctx  context.Context
+	span trace.SpanContext
+}
+
+func (s *PropagationTestSuite) SetupSuite() {
+	otel.SetTextMapPropagator(otelprop.TraceContext{})
+}
+
+func (s *PropagationTestSuite) SetupTest() {
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "publish")
+	s.T().Cleanup(func() { span.End() })
+	s.ctx = ctx
+	s.span = span.SpanContext()
+}
+
+func (s *PropagationTestSuite) assertTrace(ctx context.Context) {
+	got := trace.SpanContextFromContext(ctx)
+	s.True(got.IsRemote())
+	s.Equal(s.span.TraceID(), got.TraceID())
+	s.Equal(s.span.SpanID(), got.SpanID())
+}
+
+func (s *PropagationTestSuite) TestPubSub() {
+	msg := &pubsub.Message{Attributes: map[string]string{"type": "der"}}
+	InjectPubSub(s.ctx, msg)
+	s.Equal("der", msg.Attributes["type"])
+	s.Contains(msg.Attributes, "traceparent")
+	s.assertTrace(ExtractPubSub(context.Background(), msg))
+
+	s.NotNil(InjectAttributes(s.ctx, nil))
+}
+
+func (s *PropagationTestSuite) TestEventBus() {
+	bus := eventbus.New()
+	defer bus.Close()
+	ch := bus.Subscribe(1)
+
+	bus.Publish(Wrap(s.ctx, "reading"))
+	ctx, event := Unwrap(context.Background(), <-ch)
+	s.Equal("reading", event)
+	s.assertTrace(ctx)
+
+	ctx, event = Unwrap(context.Background(), 42)
+	s.Equal(42, event)
+	s.False(trace.SpanContextFromContext(ctx).IsValid())
+}
+
+func (s *PropagationTestSuite) TestUserProperties() {
+	props := UserProperties{{Key: "traceparent", Value: "stale"}, {Key: "unit", Value: "kW"}}
+	props = InjectUserProperties(s.ctx, props)
+	s.Len(props, 2)
+	s.Equal("kW", props.Get("unit"))
+	s.assertTrace(ExtractUserProperties(context.Background(), props))
+}
+
+func TestPropagationTestSuite(t *testing.T) {
+	suite.Run(t, new(PropagationTestSuite))
+}
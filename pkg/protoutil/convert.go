@@ -0,0 +1,279 @@
+package protoutil
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+)
+
+// ToProto copies the fields of src, a struct or pointer to struct, into dst.
+// Struct fields are matched to proto fields by their bigquery tag, falling
+// back to the json tag. Fields without a counterpart are ignored; nil
+// pointers leave the proto field unset.
+//
+// time.Time maps to google.protobuf.Timestamp, or to an RFC 3339 string for
+// protos such as AverageOutput that carry times as strings.
+func ToProto(src any, dst proto.Message) error {
+	sv := reflect.Indirect(reflect.ValueOf(src))
+	if sv.Kind() != reflect.Struct {
+		return errors.Errorf("protoutil: source must be a struct, got %T", src)
+	}
+
+	m := dst.ProtoReflect()
+	for _, f := range fieldsFor(sv.Type(), m.Descriptor()) {
+		v := sv.FieldByIndex(f.index)
+		if v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				continue
+			}
+			v = v.Elem()
+		}
+
+		pv, err := toProtoValue(f.desc, v)
+		if err != nil {
+			return errors.Wrapf(err, "field %s", f.desc.Name())
+		}
+		m.Set(f.desc, pv)
+	}
+	return nil
+}
+
+// FromProto is the inverse of ToProto. dst must be a pointer to struct.
+// Pointer fields are only set when the proto field is populated.
+func FromProto(src proto.Message, dst any) error {
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Pointer || dv.Elem().Kind() != reflect.Struct {
+		return errors.Errorf("protoutil: destination must be a pointer to struct, got %T", dst)
+	}
+	dv = dv.Elem()
+
+	m := src.ProtoReflect()
+	for _, f := range fieldsFor(dv.Type(), m.Descriptor()) {
+		v := dv.FieldByIndex(f.index)
+		if v.Kind() == reflect.Pointer {
+			if !m.Has(f.desc) {
+				v.SetZero()
+				continue
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+			v = v.Elem()
+		}
+
+		if err := fromProtoValue(m.Get(f.desc), f.desc, v); err != nil {
+			return errors.Wrapf(err, "field %s", f.desc.Name())
+		}
+	}
+	return nil
+}
+
+// ToProtos converts a slice of structs, for example rows read from BigQuery,
+// into protos ready to send.
+func ToProtos[M proto.Message, T any](rows []T, newMsg func() M) ([]M, error) {
+	msgs := make([]M, len(rows))
+	for i, row := range rows {
+		msg := newMsg()
+		if err := ToProto(row, msg); err != nil {
+			return nil, errors.Wrapf(err, "row %d", i)
+		}
+		msgs[i] = msg
+	}
+	return msgs, nil
+}
+
+func FromProtos[T any, M proto.Message](msgs []M) ([]T, error) {
+	rows := make([]T, len(msgs))
+	for i, msg := range msgs {
+		if err := FromProto(msg, &rows[i]); err != nil {
+			return nil, errors.Wrapf(err, "message %d", i)
+		}
+	}
+	return rows, nil
+}
+
+type field struct {
+	index []int
+	desc  protoreflect.FieldDescriptor
+}
+
+type mappingKey struct {
+	typ  reflect.Type
+	name protoreflect.FullName
+}
+
+var mappings sync.Map // mappingKey -> []field
+
+func fieldsFor(t reflect.Type, md protoreflect.MessageDescriptor) []field {
+	key := mappingKey{typ: t, name: md.FullName()}
+	if fs, ok := mappings.Load(key); ok {
+		return fs.([]field)
+	}
+
+	var fs []field
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := columnName(sf)
+		if name == "" {
+			continue
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.IsList() || fd.IsMap() {
+			continue
+		}
+		fs = append(fs, field{index: sf.Index, desc: fd})
+	}
+
+	mappings.Store(key, fs)
+	return fs
+}
+
+func columnName(sf reflect.StructField) string {
+	for _, tag := range []string{"bigquery", "json"} {
+		if v, ok := sf.Tag.Lookup(tag); ok {
+			name, _, _ := strings.Cut(v, ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func toProtoValue(fd protoreflect.FieldDescriptor, v reflect.Value) (protoreflect.Value, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		switch {
+		case fd.Kind() == protoreflect.StringKind:
+			return protoreflect.ValueOfString(t.Format(time.RFC3339Nano)), nil
+		case fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == timestampName:
+			return protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect()), nil
+		}
+		return protoreflect.Value{}, errors.Errorf("cannot convert time.Time to %s", fd.Kind())
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if v.Kind() == reflect.String {
+			return protoreflect.ValueOfString(v.String()), nil
+		}
+	case protoreflect.BoolKind:
+		if v.Kind() == reflect.Bool {
+			return protoreflect.ValueOfBool(v.Bool()), nil
+		}
+	case protoreflect.DoubleKind:
+		if f, ok := asFloat(v); ok {
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	case protoreflect.FloatKind:
+		if f, ok := asFloat(v); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if v.CanInt() {
+			return protoreflect.ValueOfInt32(int32(v.Int())), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if v.CanInt() {
+			return protoreflect.ValueOfInt64(v.Int()), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if v.CanUint() {
+			return protoreflect.ValueOfUint32(uint32(v.Uint())), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v.CanUint() {
+			return protoreflect.ValueOfUint64(v.Uint()), nil
+		}
+	case protoreflect.BytesKind:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return protoreflect.ValueOfBytes(v.Bytes()), nil
+		}
+	}
+	return protoreflect.Value{}, errors.Errorf("cannot convert %s to %s", v.Type(), fd.Kind())
+}
+
+func fromProtoValue(pv protoreflect.Value, fd protoreflect.FieldDescriptor, v reflect.Value) error {
+	if v.Type() == timeType {
+		switch {
+		case fd.Kind() == protoreflect.StringKind:
+			if pv.String() == "" {
+				v.SetZero()
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339Nano, pv.String())
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.Set(reflect.ValueOf(t))
+			return nil
+		case fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() == timestampName:
+			ts, ok := pv.Message().Interface().(*timestamppb.Timestamp)
+			if !ok {
+				return errors.Errorf("unexpected timestamp type %T", pv.Message().Interface())
+			}
+			v.Set(reflect.ValueOf(ts.AsTime()))
+			return nil
+		}
+		return errors.Errorf("cannot convert %s to time.Time", fd.Kind())
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if v.Kind() == reflect.String {
+			v.SetString(pv.String())
+			return nil
+		}
+	case protoreflect.BoolKind:
+		if v.Kind() == reflect.Bool {
+			v.SetBool(pv.Bool())
+			return nil
+		}
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		if v.CanFloat() {
+			v.SetFloat(pv.Float())
+			return nil
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if v.CanInt() {
+			v.SetInt(pv.Int())
+			return nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if v.CanUint() {
+			v.SetUint(pv.Uint())
+			return nil
+		}
+	case protoreflect.BytesKind:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(pv.Bytes())
+			return nil
+		}
+	}
+	return errors.Errorf("cannot convert %s to %s", fd.Kind(), v.Type())
+}
+
+func asFloat(v reflect.Value) (float64, bool) {
+	switch {
+	case v.CanFloat():
+		return v.Float(), true
+	case v.CanInt():
+		return float64(v.Int()), true
+	}
+	return 0, false
+}
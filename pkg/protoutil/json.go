@@ -0,0 +1,34 @@
+// Package protoutil converts between grid-stream protos and the
+// BigQuery-tagged structs services persist, and fixes the protojson options
+// used whenever a proto crosses a JSON boundary.
+package protoutil
+
+import (
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// MarshalOptions emits snake_case field names, matching the BigQuery
+	// columns, and includes zero values so consumers never have to guess
+	// whether a field was dropped.
+	MarshalOptions = protojson.MarshalOptions{
+		UseProtoNames:   true,
+		EmitUnpopulated: true,
+	}
+
+	// UnmarshalOptions tolerates fields added by newer proto versions.
+	UnmarshalOptions = protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}
+)
+
+func MarshalJSON(m proto.Message) ([]byte, error) {
+	b, err := MarshalOptions.Marshal(m)
+	return b, errors.WithStack(err)
+}
+
+func UnmarshalJSON(b []byte, m proto.Message) error {
+	return errors.WithStack(UnmarshalOptions.Unmarshal(b, m))
+}
@@ -0,0 +1,101 @@
+package protoutil
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
+	"github.com/stretchr/testify/suite"
+)
+
+type projectAverage struct {
+	ProjectID         string    `bigquery:"project_id" json:"project_id"`
+	ContractThreshold float64   `bigquery:"contract_threshold"`
+	Baseline          *float64  `bigquery:"baseline"`
+	AverageOutput     float64   `bigquery:"average_output"`
+	StartTime         time.Time `bigquery:"start_time"`
+	EndTime           time.Time `json:"end_time"`
+	Internal          string    `bigquery:"-"`
+}
+
+type ProtoUtilTestSuite struct {
+	suite.Suite
+}
+
+func (s *ProtoUtilTestSuite) average() projectAverage {
+	baseline := 12.5
+	return projectAverage{
+		ProjectID:         "p1",
+		ContractThreshold: 10,
+		Baseline:          &baseline,
+		AverageOutput:     3.25,
+		StartTime:         time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		EndTime:           time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC),
+		Internal:          "ignored",
+	}
+}
+
+func (s *ProtoUtilTestSuite) TestRoundTrip() {
+	msg := &pb.AverageOutput{}
+	s.Require().NoError(ToProto(s.average(), msg))
+	s.Equal("p1", msg.ProjectId)
+	s.Equal(12.5, msg.Baseline)
+	s.Equal("2025-01-15T12:00:00Z", msg.StartTime)
+	s.Equal("2025-01-15T12:05:00Z", msg.EndTime)
+
+	var got projectAverage
+	s.Require().NoError(FromProto(msg, &got))
+	want := s.average()
+	want.Internal = ""
+	s.Equal(want, got)
+}
+
+func (s *ProtoUtilTestSuite) TestNilPointerLeavesFieldUnset() {
+	avg := s.average()
+	avg.Baseline = nil
+
+	msg := &pb.AverageOutput{}
+	s.Require().NoError(ToProto(&avg, msg))
+	s.Zero(msg.Baseline)
+
+	var got projectAverage
+	s.Require().NoError(FromProto(msg, &got))
+	s.Nil(got.Baseline)
+}
+
+func (s *ProtoUtilTestSuite) TestSlices() {
+	msgs, err := ToProtos([]projectAverage{s.average(), s.average()}, func() *pb.AverageOutput { return &pb.AverageOutput{} })
+	s.Require().NoError(err)
+	s.Len(msgs, 2)
+
+	rows, err := FromProtos[projectAverage](msgs)
+	s.Require().NoError(err)
+	s.Len(rows, 2)
+	s.Equal("p1", rows[1].ProjectID)
+}
+
+func (s *ProtoUtilTestSuite) TestErrors() {
+	s.Error(ToProto("not a struct", &pb.AverageOutput{}))
+	s.Error(FromProto(&pb.AverageOutput{}, projectAverage{}))
+	s.Error(FromProto(&pb.AverageOutput{StartTime: "yesterday"}, &projectAverage{}))
+
+	type mismatched struct {
+		ProjectID int `bigquery:"project_id"`
+	}
+	s.Error(ToProto(mismatched{ProjectID: 1}, &pb.AverageOutput{}))
+}
+
+func (s *ProtoUtilTestSuite) TestJSON() {
+	b, err := MarshalJSON(&pb.AverageOutput{ProjectId: "p1"})
+	s.Require().NoError(err)
+	s.Contains(string(b), `"project_id"`)
+	s.Contains(string(b), `"baseline"`)
+
+	var msg pb.AverageOutput
+	s.Require().NoError(UnmarshalJSON([]byte(`{"project_id":"p2","added_later":true}`), &msg))
+	s.Equal("p2", msg.ProjectId)
+}
+
+func TestProtoUtilSuite(t *testing.T) {
+	suite.Run(t, new(ProtoUtilTestSuite))
+}
@@ -0,0 +1,209 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"google.golang.org/api/option"
+)
+
+type PubSubClient interface {
+	Publish(ctx context.Context, topic string, msg *Message) (string, error)
+	Subscribe(ctx context.Context, subscription string, handler Handler) error
+	Close() error
+}
+
+type Config struct {
+	ProjectID string `koanf:"project_id" json:"project_id" envconfig:"project_id"`
+	// CredsPath names a credentials file. Without it Application Default
+	// Credentials are used.
+	CredsPath              string        `koanf:"creds_path" json:"creds_path" envconfig:"creds_path"`
+	MaxOutstandingMessages int           `koanf:"max_outstanding_messages" json:"max_outstanding_messages" envconfig:"max_outstanding_messages"`
+	NumGoroutines          int           `koanf:"num_goroutines" json:"num_goroutines" envconfig:"num_goroutines"`
+	MaxExtension           time.Duration `koanf:"max_extension" json:"max_extension" envconfig:"max_extension"`
+	MaxExtensionPeriod     time.Duration `koanf:"max_extension_period" json:"max_extension_period" envconfig:"max_extension_period"`
+}
+
+// Message is published to or received from a topic. Messages sharing an
+// OrderingKey are delivered in publish order to subscriptions with message
+// ordering enabled.
+type Message struct {
+	ID          string
+	Data        []byte
+	Attributes  map[string]string
+	OrderingKey string
+	PublishTime time.Time
+	Attempt     int
+}
+
+// Handler processes a received message. Returning nil acks it, an error
+// marked with retry.Permanent acks and drops it, and any other error nacks it
+// for redelivery. The ack deadline is extended automatically while the
+// handler runs, up to Config.MaxExtension.
+type Handler func(ctx context.Context, msg *Message) error
+
+type pubSubClient struct {
+	cfg    *Config
+	client *pubsub.Client
+	log    *slog.Logger
+
+	mu     sync.Mutex
+	topics map[string]*pubsub.Topic
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("pubsub configuration required")
+	}
+	if c.ProjectID == "" {
+		return errors.New("pubsub project ID required")
+	}
+	if c.MaxOutstandingMessages < 0 || c.NumGoroutines < 0 {
+		return errors.New("pubsub receive settings must not be negative")
+	}
+	return nil
+}
+
+func New(ctx context.Context, cfg *Config, log *slog.Logger) (PubSubClient, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var opts []option.ClientOption
+	if cfg.CredsPath != "" {
+		opts = append(opts, option.WithCredentialsFile(cfg.CredsPath))
+	}
+	client, err := pubsub.NewClient(ctx, cfg.ProjectID, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return newPubSubClient(cfg, client, log), nil
+}
+
+func newPubSubClient(cfg *Config, client *pubsub.Client, log *slog.Logger) *pubSubClient {
+	return &pubSubClient{
+		cfg:    cfg,
+		client: client,
+		log:    log,
+		topics: make(map[string]*pubsub.Topic),
+	}
+}
+
+func (c *pubSubClient) topic(name string) *pubsub.Topic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.topics[name]
+	if !ok {
+		t = c.client.Topic(name)
+		t.EnableMessageOrdering = true
+		c.topics[name] = t
+	}
+	return t
+}
+
+// Publish blocks until the server has accepted the message and returns its ID.
+func (c *pubSubClient) Publish(ctx context.Context, topic string, msg *Message) (string, error) {
+	t := c.topic(topic)
+
+	id, err := t.Publish(ctx, &pubsub.Message{
+		Data:        msg.Data,
+		Attributes:  msg.Attributes,
+		OrderingKey: msg.OrderingKey,
+	}).Get(ctx)
+	if err != nil {
+		// A failed ordered publish pauses its key until explicitly resumed.
+		if msg.OrderingKey != "" {
+			t.ResumePublish(msg.OrderingKey)
+		}
+		return "", errors.Wrapf(err, "publishing to %s", topic)
+	}
+	return id, nil
+}
+
+// Subscribe receives messages until ctx is cancelled, such as by a sigctx
+// signal, and returns once in-flight handlers have finished.
+func (c *pubSubClient) Subscribe(ctx context.Context, subscription string, handler Handler) error {
+	sub := c.client.Subscription(subscription)
+	if c.cfg.MaxOutstandingMessages > 0 {
+		sub.ReceiveSettings.MaxOutstandingMessages = c.cfg.MaxOutstandingMessages
+	}
+	if c.cfg.NumGoroutines > 0 {
+		sub.ReceiveSettings.NumGoroutines = c.cfg.NumGoroutines
+	}
+	if c.cfg.MaxExtension > 0 {
+		sub.ReceiveSettings.MaxExtension = c.cfg.MaxExtension
+	}
+	if c.cfg.MaxExtensionPeriod > 0 {
+		sub.ReceiveSettings.MaxExtensionPeriod = c.cfg.MaxExtensionPeriod
+	}
+
+	err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		c.handle(ctx, m, handler)
+	})
+	return errors.Wrapf(err, "receiving from %s", subscription)
+}
+
+func (c *pubSubClient) handle(ctx context.Context, m *pubsub.Message, handler Handler) {
+	msg := &Message{
+		ID:          m.ID,
+		Data:        m.Data,
+		Attributes:  m.Attributes,
+		OrderingKey: m.OrderingKey,
+		PublishTime: m.PublishTime,
+	}
+	if m.DeliveryAttempt != nil {
+		msg.Attempt = *m.DeliveryAttempt
+	}
+
+	err := handler(ctx, msg)
+	switch {
+	case err == nil:
+		m.Ack()
+	case retry.IsPermanent(err):
+		c.log.Error("pubsub message dropped", "id", m.ID, "error", err)
+		m.Ack()
+	default:
+		c.log.Warn("pubsub message handler failed, redelivering", "id", m.ID, "error", err)
+		m.Nack()
+	}
+}
+
+// Close flushes pending publishes and closes the underlying client.
+func (c *pubSubClient) Close() error {
+	c.mu.Lock()
+	for _, t := range c.topics {
+		t.Stop()
+	}
+	c.mu.Unlock()
+
+	return errors.WithStack(c.client.Close())
+}
+
+func PublishJSON(ctx context.Context, c PubSubClient, topic string, orderingKey string, v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return c.Publish(ctx, topic, &Message{Data: data, OrderingKey: orderingKey})
+}
+
+// JSONHandler adapts a typed callback into a Handler that decodes each
+// message as JSON into a new T. Undecodable messages are dropped rather than
+// redelivered.
+func JSONHandler[T any](fn func(ctx context.Context, msg *Message, v *T) error) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		v := new(T)
+		if err := json.Unmarshal(msg.Data, v); err != nil {
+			return retry.Permanent(errors.Wrapf(err, "decoding message %s", msg.ID))
+		}
+		return fn(ctx, msg, v)
+	}
+}
@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type reading struct {
+	DERID string  `json:"der_id"`
+	Power float64 `json:"power_kw"`
+}
+
+type PubSubTestSuite struct {
+	suite.Suite
+	srv    *pstest.Server
+	raw    *pubsub.Client
+	client *pubSubClient
+}
+
+func (s *PubSubTestSuite) SetupTest() {
+	ctx := context.Background()
+	s.srv = pstest.NewServer()
+
+	conn, err := grpc.NewClient(s.srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s.Require().NoError(err)
+
+	s.raw, err = pubsub.NewClient(ctx, "test-project", option.WithGRPCConn(conn))
+	s.Require().NoError(err)
+
+	topic, err := s.raw.CreateTopic(ctx, "readings")
+	s.Require().NoError(err)
+	_, err = s.raw.CreateSubscription(ctx, "readings-sub", pubsub.SubscriptionConfig{
+		Topic:                 topic,
+		EnableMessageOrdering: true,
+	})
+	s.Require().NoError(err)
+
+	cfg := &Config{ProjectID: "test-project", CredsPath: "unused"}
+	s.client = newPubSubClient(cfg, s.raw, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func (s *PubSubTestSuite) TearDownTest() {
+	s.NoError(s.client.Close())
+	s.NoError(s.srv.Close())
+}
+
+func (s *PubSubTestSuite) TestPublishSubscribe() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, r := range []reading{{DERID: "a", Power: 1}, {DERID: "a", Power: 2}, {DERID: "a", Power: 3}} {
+		_, err := PublishJSON(ctx, s.client, "readings", "der-a", r)
+		s.Require().NoError(err)
+	}
+
+	var (
+		mu  sync.Mutex
+		got []float64
+	)
+	err := s.client.Subscribe(ctx, "readings-sub", JSONHandler(func(_ context.Context, msg *Message, v *reading) error {
+		mu.Lock()
+		defer mu.Unlock()
+		s.Equal("der-a", msg.OrderingKey)
+		got = append(got, v.Power)
+		if len(got) == 3 {
+			cancel()
+		}
+		return nil
+	}))
+	s.NoError(err)
+	s.Equal([]float64{1, 2, 3}, got)
+}
+
+func (s *PubSubTestSuite) TestHandlerOutcomes() {
+	testCases := []struct {
+		name       string
+		handlerErr error
+		acked      bool
+	}{
+		{name: "success acks", acked: true},
+		{name: "permanent error acks", handlerErr: retry.Permanent(errors.New("bad payload")), acked: true},
+		{name: "transient error nacks", handlerErr: errors.New("unavailable"), acked: false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			id, err := s.client.Publish(ctx, "readings", &Message{Data: []byte(tc.name)})
+			s.Require().NoError(err)
+
+			err = s.client.Subscribe(ctx, "readings-sub", func(_ context.Context, msg *Message) error {
+				if msg.ID == id {
+					cancel()
+					return tc.handlerErr
+				}
+				return nil
+			})
+			s.NoError(err)
+
+			s.Eventually(func() bool {
+				m := s.srv.Message(id)
+				return m != nil && (m.Acks > 0) == tc.acked
+			}, 5*time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func (s *PubSubTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{ProjectID: "p", CredsPath: "creds.json"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "missing project", cfg: &Config{CredsPath: "creds.json"}, expectError: true},
+		{name: "application default credentials", cfg: &Config{ProjectID: "p"}},
+		{name: "negative outstanding", cfg: &Config{ProjectID: "p", CredsPath: "creds.json", MaxOutstandingMessages: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestPubSubSuite(t *testing.T) {
+	suite.Run(t, new(PubSubTestSuite))
+}
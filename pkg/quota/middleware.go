@@ -0,0 +1,71 @@
+package quota
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPMiddleware charges one APICalls unit to the subject returned by
+// subjectFn and rejects the request with 429 Too Many Requests once a limit
+// is reached. Retry-After gives the seconds until the limit resets. Requests
+// for which subjectFn returns false are not counted. Store failures are
+// logged and the request is let through.
+func HTTPMiddleware(t *Tracker, subjectFn func(*http.Request) (Subject, bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, ok := subjectFn(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if exceeded := t.admit(r.Context(), s); exceeded != nil {
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter(exceeded.ResetAt, t.now())))
+				http.Error(w, exceeded.Error(), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor is HTTPMiddleware for gRPC, rejecting calls with
+// codes.ResourceExhausted.
+func UnaryServerInterceptor(t *Tracker, subjectFn func(ctx context.Context, fullMethod string) (Subject, bool)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if s, ok := subjectFn(ctx, info.FullMethod); ok {
+			if exceeded := t.admit(ctx, s); exceeded != nil {
+				return nil, status.Error(codes.ResourceExhausted, exceeded.Error())
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// admit consumes one API call, failing open on store errors.
+func (t *Tracker) admit(ctx context.Context, s Subject) *ExceededError {
+	err := t.Consume(ctx, s, APICalls, 1)
+	if err == nil {
+		return nil
+	}
+	var exceeded *ExceededError
+	if errors.As(err, &exceeded) {
+		return exceeded
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.log.Error("checking api quota", "utility", s.Utility, "project", s.Project, "error", err)
+	}
+	return nil
+}
+
+// retryAfter is the whole seconds until reset, at least one.
+func retryAfter(reset, now time.Time) int {
+	return max(int(math.Ceil(reset.Sub(now).Seconds())), 1)
+}
@@ -0,0 +1,391 @@
+// Package quota tracks per-utility and per-project usage, such as API calls
+// and rows ingested, and enforces the ingestion quotas in utility contracts.
+//
+// A Tracker counts usage in daily and monthly buckets at both utility and
+// project level, in a Store shared by every replica:
+//
+//	t, err := quota.New(cfg, quota.Redis(rdb, "quota:"), log)
+//	...
+//	err = t.Consume(ctx, quota.Subject{Utility: "u1", Project: "p1"}, quota.RowsIngested, int64(len(rows)))
+//	if errors.Is(err, quota.ErrExceeded) {
+//	    // reject the batch
+//	}
+//
+// HTTPMiddleware and UnaryServerInterceptor enforce an API call quota per
+// request.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrExceeded matches the *ExceededError returned by Consume when usage
+// would exceed a limit.
+var ErrExceeded = errors.New("quota exceeded")
+
+// ExceededError describes a rejected Consume.
+type ExceededError struct {
+	Subject Subject
+	Limit   Limit
+	// Used is the usage before the rejected request.
+	Used    int64
+	ResetAt time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s/%s: %d used of %s", e.Subject.Utility, e.Subject.Project, e.Used, e.Limit.String())
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrExceeded
+}
+
+// Metric names a kind of usage.
+type Metric string
+
+const (
+	APICalls     Metric = "api_calls"
+	RowsIngested Metric = "rows_ingested"
+)
+
+// Period is the length of a usage bucket. Buckets are aligned to UTC
+// calendar days and months.
+type Period string
+
+const (
+	Daily   Period = "day"
+	Monthly Period = "month"
+)
+
+// Periods are the buckets every usage is counted in.
+var Periods = []Period{Daily, Monthly}
+
+func (p Period) Validate() error {
+	switch p {
+	case Daily, Monthly:
+		return nil
+	}
+	return errors.Errorf("unknown quota period %q", p)
+}
+
+// Start returns the start of the bucket containing t.
+func (p Period) Start(t time.Time) time.Time {
+	t = t.UTC()
+	if p == Monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// End returns the start of the bucket after the one containing t.
+func (p Period) End(t time.Time) time.Time {
+	start := p.Start(t)
+	if p == Monthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// Scope selects whether a limit applies to a utility's total usage or to
+// each of its projects separately.
+type Scope string
+
+const (
+	UtilityScope Scope = "utility"
+	ProjectScope Scope = "project"
+)
+
+// Subject is who usage is charged to.
+type Subject struct {
+	Utility string
+	Project string
+}
+
+type Limit struct {
+	Metric Metric `koanf:"metric" json:"metric" envconfig:"metric"`
+	Period Period `koanf:"period" json:"period" envconfig:"period"`
+	Max    int64  `koanf:"max" json:"max" envconfig:"max"`
+	// Scope defaults to UtilityScope.
+	Scope Scope `koanf:"scope" json:"scope" envconfig:"scope"`
+	// Utility and Project restrict the limit to one utility or project. Empty
+	// applies it to every one.
+	Utility string `koanf:"utility" json:"utility" envconfig:"utility"`
+	Project string `koanf:"project" json:"project" envconfig:"project"`
+}
+
+func (l *Limit) Validate() error {
+	if l.Metric == "" {
+		return errors.New("quota limit metric required")
+	}
+	if err := l.Period.Validate(); err != nil {
+		return err
+	}
+	if l.Max < 0 {
+		return errors.New("quota limit max must not be negative")
+	}
+	switch l.Scope {
+	case "", UtilityScope:
+		if l.Project != "" {
+			return errors.New("quota limit with a project must have project scope")
+		}
+	case ProjectScope:
+	default:
+		return errors.Errorf("unknown quota scope %q", l.Scope)
+	}
+	return nil
+}
+
+func (l *Limit) applies(s Subject, metric Metric) bool {
+	if l.Metric != metric || (l.Utility != "" && l.Utility != s.Utility) {
+		return false
+	}
+	if l.Scope == ProjectScope {
+		return s.Project != "" && (l.Project == "" || l.Project == s.Project)
+	}
+	return true
+}
+
+func (l *Limit) String() string {
+	scope := l.Scope
+	if scope == "" {
+		scope = UtilityScope
+	}
+	return fmt.Sprintf("%d %s per %s per %s", l.Max, l.Metric, l.Period, scope)
+}
+
+type Config struct {
+	Limits []Limit `koanf:"limits" json:"limits" envconfig:"limits"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("quota configuration required")
+	}
+	for i := range c.Limits {
+		if err := c.Limits[i].Validate(); err != nil {
+			return errors.Wrapf(err, "quota limit %d", i)
+		}
+	}
+	return nil
+}
+
+// Increment adds N to the counter Key. Stores may drop the counter after
+// ExpireAt.
+type Increment struct {
+	Key      string
+	N        int64
+	ExpireAt time.Time
+}
+
+// Store holds usage counters.
+type Store interface {
+	// Add applies the increments and returns the new value of each counter.
+	Add(ctx context.Context, incs []Increment) ([]int64, error)
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+type Option func(*Tracker)
+
+// WithMetrics counts usage and rejections by utility and metric.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(t *Tracker) {
+		usage, err := m.NewCounterVec("quota", "usage_total", "Usage recorded by utility and metric.", "utility", "metric")
+		if err != nil {
+			t.log.Warn("registering quota metrics", "error", err)
+			return
+		}
+		exceeded, err := m.NewCounterVec("quota", "exceeded_total", "Usage rejected for exceeding a quota.", "utility", "metric")
+		if err != nil {
+			t.log.Warn("registering quota metrics", "error", err)
+			return
+		}
+		t.usage, t.exceeded = usage, exceeded
+	}
+}
+
+// WithExceededHook calls fn whenever Consume rejects usage, for example to
+// notify the utility's account owner.
+func WithExceededHook(fn func(ctx context.Context, s Subject, limit Limit, used int64)) Option {
+	return func(t *Tracker) {
+		t.onExceeded = fn
+	}
+}
+
+// WithClock replaces time.Now for choosing buckets.
+func WithClock(now func() time.Time) Option {
+	return func(t *Tracker) {
+		t.now = now
+	}
+}
+
+// KeepPeriods is how many past buckets counters are retained for reporting.
+const KeepPeriods = 2
+
+type Tracker struct {
+	limits     []Limit
+	store      Store
+	log        *slog.Logger
+	now        func() time.Time
+	onExceeded func(ctx context.Context, s Subject, limit Limit, used int64)
+	usage      *prometheus.CounterVec
+	exceeded   *prometheus.CounterVec
+}
+
+func New(cfg *Config, store Store, log *slog.Logger, opts ...Option) (*Tracker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if store == nil {
+		return nil, errors.New("quota store required")
+	}
+	t := &Tracker{limits: cfg.Limits, store: store, log: log, now: time.Now}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t, nil
+}
+
+// Key returns the counter key for a subject's usage of metric in the bucket
+// containing at. An empty project gives the utility-wide counter.
+func Key(utility, project string, metric Metric, period Period, at time.Time) string {
+	var layout string
+	if period == Monthly {
+		layout = "200601"
+	} else {
+		layout = "20060102"
+	}
+	key := fmt.Sprintf("%s:%s:%s:%s", metric, period, period.Start(at).Format(layout), utility)
+	if project != "" {
+		key += "/" + project
+	}
+	return key
+}
+
+// keyStart returns the start of the bucket named by a key from Key.
+func keyStart(key string) (time.Time, bool) {
+	parts := strings.SplitN(key, ":", 4)
+	if len(parts) != 4 {
+		return time.Time{}, false
+	}
+	layout := "20060102"
+	if Period(parts[1]) == Monthly {
+		layout = "200601"
+	}
+	t, err := time.Parse(layout, parts[2])
+	return t, err == nil
+}
+
+func (t *Tracker) increments(s Subject, metric Metric, n int64, now time.Time) []Increment {
+	var incs []Increment
+	for _, p := range Periods {
+		expire := p.End(now)
+		for range KeepPeriods {
+			expire = p.End(expire)
+		}
+		incs = append(incs, Increment{Key: Key(s.Utility, "", metric, p, now), N: n, ExpireAt: expire})
+		if s.Project != "" {
+			incs = append(incs, Increment{Key: Key(s.Utility, s.Project, metric, p, now), N: n, ExpireAt: expire})
+		}
+	}
+	return incs
+}
+
+func (t *Tracker) limitKey(l *Limit, s Subject, now time.Time) string {
+	if l.Scope == ProjectScope {
+		return Key(s.Utility, s.Project, l.Metric, l.Period, now)
+	}
+	return Key(s.Utility, "", l.Metric, l.Period, now)
+}
+
+// Record adds usage without enforcing limits, for usage that has already
+// happened.
+func (t *Tracker) Record(ctx context.Context, s Subject, metric Metric, n int64) error {
+	if _, err := t.store.Add(ctx, t.increments(s, metric, n, t.now())); err != nil {
+		return errors.Wrap(err, "recording quota usage")
+	}
+	t.countUsage(s, metric, n)
+	return nil
+}
+
+// Consume adds usage if it fits within every applicable limit. Otherwise it
+// leaves usage unchanged and returns an *ExceededError, which matches
+// ErrExceeded.
+func (t *Tracker) Consume(ctx context.Context, s Subject, metric Metric, n int64) error {
+	now := t.now()
+	incs := t.increments(s, metric, n, now)
+	totals, err := t.store.Add(ctx, incs)
+	if err != nil {
+		return errors.Wrap(err, "recording quota usage")
+	}
+	byKey := make(map[string]int64, len(incs))
+	for i, inc := range incs {
+		byKey[inc.Key] = totals[i]
+	}
+
+	for i := range t.limits {
+		l := &t.limits[i]
+		if !l.applies(s, metric) {
+			continue
+		}
+		used := byKey[t.limitKey(l, s, now)]
+		if used <= l.Max {
+			continue
+		}
+
+		for j := range incs {
+			incs[j].N = -n
+		}
+		if _, err := t.store.Add(ctx, incs); err != nil {
+			t.log.Error("rolling back quota usage", "utility", s.Utility, "project", s.Project, "metric", metric, "error", err)
+		}
+		if t.exceeded != nil {
+			t.exceeded.WithLabelValues(s.Utility, string(metric)).Inc()
+		}
+		if t.onExceeded != nil {
+			t.onExceeded(ctx, s, *l, used-n)
+		}
+		return errors.WithStack(&ExceededError{Subject: s, Limit: *l, Used: used - n, ResetAt: l.Period.End(now)})
+	}
+	t.countUsage(s, metric, n)
+	return nil
+}
+
+// Usage returns a subject's usage of metric in the bucket containing at. An
+// empty project gives the utility-wide total.
+func (t *Tracker) Usage(ctx context.Context, s Subject, metric Metric, period Period, at time.Time) (int64, error) {
+	n, err := t.store.Get(ctx, Key(s.Utility, s.Project, metric, period, at))
+	return n, errors.Wrap(err, "reading quota usage")
+}
+
+// Remaining returns how much of each applicable limit is left in the current
+// bucket.
+func (t *Tracker) Remaining(ctx context.Context, s Subject, metric Metric) (map[Limit]int64, error) {
+	now := t.now()
+	out := map[Limit]int64{}
+	for i := range t.limits {
+		l := &t.limits[i]
+		if !l.applies(s, metric) {
+			continue
+		}
+		used, err := t.store.Get(ctx, t.limitKey(l, s, now))
+		if err != nil {
+			return nil, errors.Wrap(err, "reading quota usage")
+		}
+		out[*l] = max(l.Max-used, 0)
+	}
+	return out, nil
+}
+
+func (t *Tracker) countUsage(s Subject, metric Metric, n int64) {
+	if t.usage != nil {
+		t.usage.WithLabelValues(s.Utility, string(metric)).Add(float64(n))
+	}
+}
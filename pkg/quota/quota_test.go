@@ -0,0 +1,254 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type QuotaTestSuite struct {
+	suite.Suite
+	redis *miniredis.Miniredis
+	now   time.Time
+}
+
+func (s *QuotaTestSuite) SetupTest() {
+	s.redis = miniredis.RunT(s.T())
+	s.now = time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	// EXPIREAT is relative to the server clock.
+	s.redis.SetTime(s.now)
+}
+
+func (s *QuotaTestSuite) memoryStore() *MemoryStore {
+	m := NewMemoryStore()
+	m.now = func() time.Time { return s.now }
+	return m
+}
+
+func (s *QuotaTestSuite) stores() map[string]Store {
+	rdb := redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	s.T().Cleanup(func() { _ = rdb.Close() })
+	return map[string]Store{
+		"memory": s.memoryStore(),
+		"redis":  Redis(rdb, "quota:"),
+	}
+}
+
+func (s *QuotaTestSuite) tracker(store Store, limits []Limit, opts ...Option) *Tracker {
+	opts = append([]Option{WithClock(func() time.Time { return s.now })}, opts...)
+	t, err := New(&Config{Limits: limits}, store, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return t
+}
+
+func (s *QuotaTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "empty", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Limits: []Limit{
+			{Metric: RowsIngested, Period: Monthly, Max: 1_000_000},
+			{Metric: APICalls, Period: Daily, Max: 10_000, Scope: ProjectScope, Utility: "u1", Project: "p1"},
+		}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing metric", cfg: &Config{Limits: []Limit{{Period: Daily, Max: 1}}}, expectError: true},
+		{name: "bad period", cfg: &Config{Limits: []Limit{{Metric: APICalls, Period: "week", Max: 1}}}, expectError: true},
+		{name: "negative max", cfg: &Config{Limits: []Limit{{Metric: APICalls, Period: Daily, Max: -1}}}, expectError: true},
+		{name: "project without scope", cfg: &Config{Limits: []Limit{{Metric: APICalls, Period: Daily, Project: "p1"}}}, expectError: true},
+		{name: "bad scope", cfg: &Config{Limits: []Limit{{Metric: APICalls, Period: Daily, Scope: "site"}}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *QuotaTestSuite) TestPeriods() {
+	at := time.Date(2026, 12, 31, 18, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	s.Equal(time.Date(2026, 12, 31, 23, 30, 0, 0, time.UTC), at.UTC())
+	s.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC), Daily.Start(at))
+	s.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Daily.End(at))
+	s.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), Monthly.End(at))
+	s.Equal("rows_ingested:month:202612:u1/p1", Key("u1", "p1", RowsIngested, Monthly, at))
+	s.Equal("api_calls:day:20261231:u1", Key("u1", "", APICalls, Daily, at))
+}
+
+func (s *QuotaTestSuite) TestKeyStart() {
+	start, ok := keyStart(Key("u1", "p1", APICalls, Daily, s.now))
+	s.True(ok)
+	s.Equal(Daily.Start(s.now), start)
+
+	start, ok = keyStart(Key("u1", "", RowsIngested, Monthly, s.now))
+	s.True(ok)
+	s.Equal(Monthly.Start(s.now), start)
+
+	_, ok = keyStart("custom")
+	s.False(ok)
+}
+
+func (s *QuotaTestSuite) TestConsume() {
+	for name, store := range s.stores() {
+		s.Run(name, func() {
+			var hooked []int64
+			t := s.tracker(store, []Limit{
+				{Metric: RowsIngested, Period: Daily, Max: 100},
+				{Metric: RowsIngested, Period: Monthly, Max: 1000, Utility: "other"},
+			}, WithExceededHook(func(_ context.Context, _ Subject, _ Limit, used int64) {
+				hooked = append(hooked, used)
+			}))
+			ctx := context.Background()
+			p1 := Subject{Utility: "u1", Project: "p1"}
+			p2 := Subject{Utility: "u1", Project: "p2"}
+
+			s.Require().NoError(t.Consume(ctx, p1, RowsIngested, 60))
+			s.Require().NoError(t.Consume(ctx, p2, RowsIngested, 40))
+
+			err := t.Consume(ctx, p1, RowsIngested, 1)
+			s.ErrorIs(err, ErrExceeded)
+			var exceeded *ExceededError
+			s.Require().ErrorAs(err, &exceeded)
+			s.Equal(int64(100), exceeded.Used)
+			s.Equal(Daily, exceeded.Limit.Period)
+			s.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), exceeded.ResetAt)
+			s.Equal([]int64{100}, hooked)
+
+			used, err := t.Usage(ctx, Subject{Utility: "u1"}, RowsIngested, Daily, s.now)
+			s.Require().NoError(err)
+			s.Equal(int64(100), used, "rejected usage is rolled back")
+			used, err = t.Usage(ctx, p1, RowsIngested, Monthly, s.now)
+			s.Require().NoError(err)
+			s.Equal(int64(60), used)
+
+			// Record counts usage that already happened, even over the limit.
+			s.NoError(t.Record(ctx, p1, RowsIngested, 5))
+			used, err = t.Usage(ctx, p1, RowsIngested, Daily, s.now)
+			s.Require().NoError(err)
+			s.Equal(int64(65), used)
+
+			// The next day starts a new bucket.
+			s.now = s.now.Add(2 * time.Hour)
+			s.NoError(t.Consume(ctx, p1, RowsIngested, 100))
+			s.now = s.now.Add(-2 * time.Hour)
+		})
+	}
+}
+
+func (s *QuotaTestSuite) TestProjectScope() {
+	t := s.tracker(s.memoryStore(), []Limit{
+		{Metric: APICalls, Period: Daily, Max: 2, Scope: ProjectScope},
+		{Metric: APICalls, Period: Daily, Max: 5, Scope: ProjectScope, Project: "big"},
+	})
+	ctx := context.Background()
+
+	for _, project := range []string{"a", "b"} {
+		s.NoError(t.Consume(ctx, Subject{Utility: "u1", Project: project}, APICalls, 2))
+		s.ErrorIs(t.Consume(ctx, Subject{Utility: "u1", Project: project}, APICalls, 1), ErrExceeded)
+	}
+	s.NoError(t.Consume(ctx, Subject{Utility: "u1"}, APICalls, 10), "project limits skip utility-wide usage")
+
+	remaining, err := t.Remaining(ctx, Subject{Utility: "u1", Project: "big"}, APICalls)
+	s.Require().NoError(err)
+	s.Len(remaining, 2)
+	s.Require().NoError(t.Consume(ctx, Subject{Utility: "u1", Project: "big"}, APICalls, 2))
+	remaining, err = t.Remaining(ctx, Subject{Utility: "u1", Project: "big"}, APICalls)
+	s.Require().NoError(err)
+	s.Equal(int64(0), remaining[Limit{Metric: APICalls, Period: Daily, Max: 2, Scope: ProjectScope}])
+	s.Equal(int64(3), remaining[Limit{Metric: APICalls, Period: Daily, Max: 5, Scope: ProjectScope, Project: "big"}])
+}
+
+func (s *QuotaTestSuite) TestRedisExpiry() {
+	rdb := redis.NewClient(&redis.Options{Addr: s.redis.Addr()})
+	defer rdb.Close()
+	t := s.tracker(Redis(rdb, "quota:"), nil)
+	s.Require().NoError(t.Record(context.Background(), Subject{Utility: "u1"}, APICalls, 1))
+
+	// The daily bucket ends at midnight and is kept for two more days.
+	ttl := s.redis.TTL("quota:" + Key("u1", "", APICalls, Daily, s.now))
+	s.Equal(49*time.Hour, ttl)
+}
+
+func (s *QuotaTestSuite) TestHTTPMiddleware() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "quota"})
+	s.Require().NoError(err)
+	t := s.tracker(s.memoryStore(), []Limit{{Metric: APICalls, Period: Daily, Max: 1}}, WithMetrics(m))
+	h := HTTPMiddleware(t, func(r *http.Request) (Subject, bool) {
+		u := r.Header.Get("X-Utility")
+		return Subject{Utility: u}, u != ""
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	do := func(utility string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/readings", nil)
+		if utility != "" {
+			req.Header.Set("X-Utility", utility)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	s.Equal(http.StatusOK, do("u1").Code)
+	rec := do("u1")
+	s.Equal(http.StatusTooManyRequests, rec.Code)
+	s.Equal("3600", rec.Header().Get("Retry-After"))
+	s.Equal(http.StatusOK, do("u2").Code)
+	s.Equal(http.StatusOK, do("").Code)
+	s.Equal(http.StatusOK, do("").Code)
+
+	s.Equal(float64(1), testutil.ToFloat64(t.usage.WithLabelValues("u1", string(APICalls))))
+	s.Equal(float64(1), testutil.ToFloat64(t.exceeded.WithLabelValues("u1", string(APICalls))))
+}
+
+type failingStore struct{}
+
+func (failingStore) Add(context.Context, []Increment) ([]int64, error) {
+	return nil, errors.New("store down")
+}
+
+func (failingStore) Get(context.Context, string) (int64, error) {
+	return 0, errors.New("store down")
+}
+
+func (s *QuotaTestSuite) TestUnaryServerInterceptor() {
+	t := s.tracker(s.memoryStore(), []Limit{{Metric: APICalls, Period: Monthly, Max: 1}})
+	subject := func(context.Context, string) (Subject, bool) { return Subject{Utility: "u1"}, true }
+	interceptor := UnaryServerInterceptor(t, subject)
+	info := &grpc.UnaryServerInfo{FullMethod: "/validator.v1.Validator/SendAverages"}
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	resp, err := interceptor(context.Background(), nil, info, handler)
+	s.Require().NoError(err)
+	s.Equal("ok", resp)
+	_, err = interceptor(context.Background(), nil, info, handler)
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+
+	// Store failures fail open.
+	down := UnaryServerInterceptor(s.tracker(failingStore{}, t.limits), subject)
+	_, err = down(context.Background(), nil, info, handler)
+	s.NoError(err)
+}
+
+func TestQuotaTestSuite(t *testing.T) {
+	suite.Run(t, new(QuotaTestSuite))
+}
@@ -0,0 +1,243 @@
+package quota
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/api/iterator"
+)
+
+// MemoryStore keeps counters in process, for tests and single-replica
+// services.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+}
+
+type memoryCounter struct {
+	n        int64
+	expireAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{now: time.Now, counters: map[string]*memoryCounter{}}
+}
+
+func (s *MemoryStore) Add(_ context.Context, incs []Increment) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, c := range s.counters {
+		if !c.expireAt.IsZero() && !now.Before(c.expireAt) {
+			delete(s.counters, key)
+		}
+	}
+
+	out := make([]int64, len(incs))
+	for i, inc := range incs {
+		c, ok := s.counters[inc.Key]
+		if !ok {
+			c = &memoryCounter{}
+			s.counters[inc.Key] = c
+		}
+		c.n += inc.N
+		c.expireAt = inc.ExpireAt
+		out[i] = c.n
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.counters[key]; ok {
+		return c.n, nil
+	}
+	return 0, nil
+}
+
+type redisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// Redis keeps counters in Redis under prefix, updating all counters of a call
+// in one MULTI transaction.
+func Redis(client redis.UniversalClient, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+func (s *redisStore) Add(ctx context.Context, incs []Increment) ([]int64, error) {
+	cmds := make([]*redis.IntCmd, len(incs))
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for i, inc := range incs {
+			key := s.prefix + inc.Key
+			cmds[i] = p.IncrBy(ctx, key, inc.N)
+			if !inc.ExpireAt.IsZero() {
+				p.ExpireAt(ctx, key, inc.ExpireAt)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	out := make([]int64, len(cmds))
+	for i, cmd := range cmds {
+		out[i] = cmd.Val()
+	}
+	return out, nil
+}
+
+func (s *redisStore) Get(ctx context.Context, key string) (int64, error) {
+	n, err := s.client.Get(ctx, s.prefix+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return n, errors.WithStack(err)
+}
+
+const DefaultTable = "quota_usage"
+
+// retentionDays keeps usage partitions for the current month and the
+// KeepPeriods before it.
+const retentionDays = 31 * (KeepPeriods + 1)
+
+type BigQueryConfig struct {
+	// Table must be allowed by the client. Defaults to DefaultTable.
+	Table string `koanf:"table" json:"table" envconfig:"table"`
+}
+
+func (c *BigQueryConfig) Validate() error {
+	if c == nil {
+		return errors.New("quota bigquery configuration required")
+	}
+	return nil
+}
+
+type usageRow struct {
+	Key        string    `bigquery:"key"`
+	N          int64     `bigquery:"n"`
+	RecordedAt time.Time `bigquery:"recorded_at"`
+}
+
+// BigQuery is an append-only usage ledger: every increment is a streamed row
+// and counters are summed at read time. It keeps a durable, auditable history
+// for billing, but each Add costs a query, so use it for batch usage such as
+// ingested rows rather than per-request API calls. Partitions expire once no
+// retained bucket can include them. Concurrent Consume calls
+// can each see the other's usage and both be rejected, or both admitted.
+type BigQuery struct {
+	client    bqclient.BQClient
+	table     string
+	tableName string
+}
+
+func NewBigQuery(client bqclient.BQClient, cfg *BigQueryConfig) (*BigQuery, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	name := cfg.Table
+	if name == "" {
+		name = DefaultTable
+	}
+	table, err := client.TableName(name)
+	if err != nil {
+		return nil, err
+	}
+	return &BigQuery{client: client, table: table, tableName: name}, nil
+}
+
+// CreateTable creates the usage table if it does not exist.
+func (b *BigQuery) CreateTable(ctx context.Context) error {
+	err := bqclient.Exec(ctx, b.client, `CREATE TABLE IF NOT EXISTS `+b.table+` (
+        key STRING NOT NULL,
+        n INT64 NOT NULL,
+        recorded_at TIMESTAMP NOT NULL
+    )
+    PARTITION BY DATE(recorded_at)
+    CLUSTER BY key
+    OPTIONS (partition_expiration_days = `+strconv.Itoa(retentionDays)+`)`, nil)
+	return errors.Wrap(err, "creating quota usage table")
+}
+
+func (b *BigQuery) Add(ctx context.Context, incs []Increment) ([]int64, error) {
+	now := time.Now().UTC()
+	rows := make([]*usageRow, len(incs))
+	keys := make([]string, len(incs))
+	for i, inc := range incs {
+		rows[i] = &usageRow{Key: inc.Key, N: inc.N, RecordedAt: now}
+		keys[i] = inc.Key
+	}
+	if err := b.client.StreamPut(ctx, b.tableName, rows); err != nil {
+		return nil, errors.Wrap(err, "writing quota usage")
+	}
+
+	totals, err := b.sums(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int64, len(incs))
+	for i, inc := range incs {
+		out[i] = totals[inc.Key]
+	}
+	return out, nil
+}
+
+func (b *BigQuery) Get(ctx context.Context, key string) (int64, error) {
+	totals, err := b.sums(ctx, []string{key})
+	if err != nil {
+		return 0, err
+	}
+	return totals[key], nil
+}
+
+// sums totals the usage of keys. Rows of a key are all recorded within its
+// bucket, so only partitions from the earliest bucket start are scanned.
+// Keys not made by Key scan the whole retention window.
+func (b *BigQuery) sums(ctx context.Context, keys []string) (map[string]int64, error) {
+	var since time.Time
+	for i, key := range keys {
+		start, ok := keyStart(key)
+		if !ok {
+			start = time.Now().UTC().AddDate(0, 0, -retentionDays)
+		}
+		if i == 0 || start.Before(since) {
+			since = start
+		}
+	}
+	it, err := b.client.Query(ctx, `
+        SELECT key, SUM(n) AS n
+        FROM `+b.table+`
+        WHERE key IN UNNEST(@keys) AND recorded_at >= @since
+        GROUP BY key`,
+		[]bigquery.QueryParameter{{Name: "keys", Value: keys}, {Name: "since", Value: since}})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading quota usage")
+	}
+
+	totals := make(map[string]int64, len(keys))
+	for {
+		var row struct {
+			Key string `bigquery:"key"`
+			N   int64  `bigquery:"n"`
+		}
+		err := it.Next(&row)
+		if err == iterator.Done {
+			return totals, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "reading quota usage")
+		}
+		totals[row.Key] = row.N
+	}
+}
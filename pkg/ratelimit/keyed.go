@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultIdleTTL is how long a Keyed limiter may go unused before it is
+// removed, unless WithIdleTTL says otherwise.
+const DefaultIdleTTL = time.Hour
+
+type keyedEntry struct {
+	limiter  Limiter
+	lastUsed time.Time
+}
+
+type keyedOptions struct {
+	idleTTL time.Duration
+}
+
+type KeyedOption func(*keyedOptions)
+
+// WithIdleTTL removes limiters unused for longer than d, checked at most
+// once per d as keys are used. d should be longer than the limiters take to
+// refill, since a removed limiter starts again with a full burst. Zero
+// disables removal, leaving it to Prune.
+func WithIdleTTL(d time.Duration) KeyedOption {
+	return func(o *keyedOptions) {
+		o.idleTTL = d
+	}
+}
+
+// Keyed maintains an independent Limiter per key, such as a project or
+// utility ID, creating limiters on first use and removing them once idle.
+type Keyed[K comparable] struct {
+	newLimiter func() Limiter
+	idleTTL    time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	limiters  map[K]*keyedEntry
+	lastPrune time.Time
+}
+
+func NewKeyed[K comparable](newLimiter func() Limiter, opts ...KeyedOption) *Keyed[K] {
+	o := keyedOptions{idleTTL: DefaultIdleTTL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Keyed[K]{
+		newLimiter: newLimiter,
+		idleTTL:    o.idleTTL,
+		now:        time.Now,
+		limiters:   map[K]*keyedEntry{},
+	}
+}
+
+// Get returns the limiter for key.
+func (k *Keyed[K]) Get(key K) Limiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	if k.idleTTL > 0 && now.Sub(k.lastPrune) >= k.idleTTL {
+		k.prune(now.Add(-k.idleTTL))
+		k.lastPrune = now
+	}
+
+	e, ok := k.limiters[key]
+	if !ok {
+		e = &keyedEntry{limiter: k.newLimiter()}
+		k.limiters[key] = e
+	}
+	e.lastUsed = now
+	return e.limiter
+}
+
+func (k *Keyed[K]) Allow(key K) bool {
+	return k.Get(key).Allow()
+}
+
+func (k *Keyed[K]) Wait(ctx context.Context, key K) error {
+	return k.Get(key).Wait(ctx)
+}
+
+func (k *Keyed[K]) Reserve(key K) *Reservation {
+	return k.Get(key).Reserve()
+}
+
+// Prune removes limiters that have not been used for longer than idle and
+// returns how many were removed.
+func (k *Keyed[K]) Prune(idle time.Duration) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.prune(k.now().Add(-idle))
+}
+
+// prune must be called with k.mu held.
+func (k *Keyed[K]) prune(cutoff time.Time) int {
+	removed := 0
+	for key, e := range k.limiters {
+		if e.lastUsed.Before(cutoff) {
+			delete(k.limiters, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (k *Keyed[K]) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
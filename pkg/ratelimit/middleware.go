@@ -0,0 +1,43 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPMiddleware rejects requests with 429 Too Many Requests once the limiter
+// for the key returned by keyFn is exhausted. The Retry-After header tells the
+// client how long to back off.
+func HTTPMiddleware(limiters *Keyed[string], keyFn func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res := limiters.Reserve(keyFn(r))
+			if !res.OK() || res.Delay() > 0 {
+				res.Cancel()
+				if res.OK() {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(res.Delay().Seconds()))))
+				}
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor rejects gRPC calls with codes.ResourceExhausted once
+// the limiter for the key returned by keyFn is exhausted.
+func UnaryServerInterceptor(limiters *Keyed[string], keyFn func(ctx context.Context, fullMethod string) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !limiters.Allow(keyFn(ctx, info.FullMethod)) {
+			return nil, status.Error(codes.ResourceExhausted, ErrLimitExceeded.Error())
+		}
+		return handler(ctx, req)
+	}
+}
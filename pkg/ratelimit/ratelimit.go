@@ -0,0 +1,132 @@
+// Package ratelimit provides context-aware rate limiters, per-key limiter maps
+// and HTTP/gRPC middleware built on them.
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// ErrLimitExceeded is returned when a request cannot be admitted, either
+// because the limiter is exhausted or because waiting would outlast the
+// context deadline.
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// Limiter admits events at a bounded rate.
+type Limiter interface {
+	// Allow reports whether an event may happen now, consuming capacity if so.
+	Allow() bool
+	// Wait blocks until an event may happen or ctx is done.
+	Wait(ctx context.Context) error
+	// Reserve claims capacity for a future event and reports how long the
+	// caller must wait before acting on it.
+	Reserve() *Reservation
+}
+
+// Reservation is capacity claimed ahead of time by Limiter.Reserve.
+type Reservation struct {
+	ok     bool
+	delay  time.Duration
+	cancel func()
+}
+
+// OK reports whether the limiter can ever grant the reservation.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay is how long to wait before acting on the reservation.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel returns the reserved capacity to the limiter.
+func (r *Reservation) Cancel() {
+	if r.ok && r.cancel != nil {
+		r.cancel()
+	}
+}
+
+type Config struct {
+	Rate  float64 `koanf:"rate" json:"rate" envconfig:"rate"`
+	Burst int     `koanf:"burst" json:"burst" envconfig:"burst"`
+}
+
+func (c *Config) Validate() error {
+	if c.Rate <= 0 {
+		return errors.New("rate must be greater than 0")
+	}
+	if c.Burst <= 0 {
+		return errors.New("burst must be greater than 0")
+	}
+	return nil
+}
+
+type tokenBucket struct {
+	l *rate.Limiter
+}
+
+// NewTokenBucket returns a Limiter that refills perSecond tokens every second
+// up to burst.
+func NewTokenBucket(perSecond float64, burst int) Limiter {
+	return &tokenBucket{l: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// NewFromConfig returns a token bucket Limiter described by cfg.
+func NewFromConfig(cfg *Config) (Limiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return NewTokenBucket(cfg.Rate, cfg.Burst), nil
+}
+
+func (tb *tokenBucket) Allow() bool {
+	return tb.l.Allow()
+}
+
+func (tb *tokenBucket) Wait(ctx context.Context) error {
+	if err := tb.l.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return errors.WithStack(ctx.Err())
+		}
+		return errors.Wrap(ErrLimitExceeded, err.Error())
+	}
+	return nil
+}
+
+func (tb *tokenBucket) Reserve() *Reservation {
+	r := tb.l.Reserve()
+	return &Reservation{
+		ok:     r.OK(),
+		delay:  r.Delay(),
+		cancel: r.Cancel,
+	}
+}
+
+// wait sleeps for the reservation delay, cancelling the reservation if ctx
+// finishes first or its deadline is too close to ever be met.
+func wait(ctx context.Context, r *Reservation) error {
+	if !r.OK() {
+		return ErrLimitExceeded
+	}
+	if r.Delay() == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.Delay() {
+		r.Cancel()
+		return errors.WithStack(ErrLimitExceeded)
+	}
+
+	timer := time.NewTimer(r.Delay())
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		r.Cancel()
+		return errors.WithStack(ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type RateLimitTestSuite struct {
+	suite.Suite
+}
+
+func (s *RateLimitTestSuite) TestTokenBucket() {
+	l := NewTokenBucket(1, 2)
+	s.True(l.Allow())
+	s.True(l.Allow())
+	s.False(l.Allow())
+
+	r := l.Reserve()
+	s.True(r.OK())
+	s.Greater(r.Delay(), time.Duration(0))
+	r.Cancel()
+}
+
+func (s *RateLimitTestSuite) TestTokenBucketWaitDeadline() {
+	l := NewTokenBucket(0.1, 1)
+	s.True(l.Allow())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(l.Wait(ctx), ErrLimitExceeded)
+}
+
+func (s *RateLimitTestSuite) TestSlidingWindow() {
+	now := time.Now()
+	sw := NewSlidingWindow(2, time.Second).(*slidingWindow)
+	sw.now = func() time.Time { return now }
+
+	s.True(sw.Allow())
+	now = now.Add(500 * time.Millisecond)
+	s.True(sw.Allow())
+	s.False(sw.Allow())
+
+	r := sw.Reserve()
+	s.True(r.OK())
+	s.Equal(500*time.Millisecond, r.Delay())
+	r.Cancel()
+	s.Len(sw.events, 2)
+
+	now = now.Add(500 * time.Millisecond)
+	s.True(sw.Allow(), "first event has left the window")
+	s.False(sw.Allow())
+}
+
+func (s *RateLimitTestSuite) TestSlidingWindowWait() {
+	sw := NewSlidingWindow(1, 20*time.Millisecond)
+	s.True(sw.Allow())
+
+	start := time.Now()
+	s.NoError(sw.Wait(context.Background()))
+	s.GreaterOrEqual(time.Since(start), 15*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(sw.Wait(ctx), context.Canceled)
+}
+
+func (s *RateLimitTestSuite) TestSlidingWindowZeroLimit() {
+	sw := NewSlidingWindow(0, time.Second)
+	s.False(sw.Allow())
+	s.False(sw.Reserve().OK())
+	s.ErrorIs(sw.Wait(context.Background()), ErrLimitExceeded)
+}
+
+func (s *RateLimitTestSuite) TestKeyed() {
+	k := NewKeyed[string](func() Limiter { return NewSlidingWindow(1, time.Minute) })
+
+	s.True(k.Allow("project-a"))
+	s.False(k.Allow("project-a"))
+	s.True(k.Allow("project-b"))
+	s.Equal(2, k.Len())
+
+	s.Equal(0, k.Prune(time.Hour))
+	s.Equal(2, k.Prune(0))
+	s.Equal(0, k.Len())
+}
+
+func (s *RateLimitTestSuite) TestKeyedEvictsIdle() {
+	now := time.Now()
+	k := NewKeyed[string](func() Limiter { return NewSlidingWindow(1, time.Minute) }, WithIdleTTL(time.Minute))
+	k.now = func() time.Time { return now }
+
+	s.True(k.Allow("project-a"))
+	now = now.Add(30 * time.Second)
+	s.True(k.Allow("project-b"))
+	now = now.Add(31 * time.Second)
+	s.False(k.Allow("project-b"))
+	s.Equal(1, k.Len(), "project-a was idle for over a minute")
+
+	now = now.Add(2 * time.Minute)
+	s.True(k.Allow("project-c"))
+	s.Equal(1, k.Len())
+
+	k = NewKeyed[string](func() Limiter { return NewSlidingWindow(1, time.Minute) }, WithIdleTTL(0))
+	k.now = func() time.Time { return now }
+	s.True(k.Allow("project-a"))
+	now = now.Add(24 * time.Hour)
+	s.True(k.Allow("project-b"))
+	s.Equal(2, k.Len())
+}
+
+func (s *RateLimitTestSuite) TestHTTPMiddleware() {
+	k := NewKeyed[string](func() Limiter { return NewSlidingWindow(1, time.Minute) })
+	h := HTTPMiddleware(k, func(r *http.Request) string {
+		return r.Header.Get("X-Project")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	do := func(project string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Project", project)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	s.Equal(http.StatusNoContent, do("a").Code)
+	rec := do("a")
+	s.Equal(http.StatusTooManyRequests, rec.Code)
+	s.Equal("60", rec.Header().Get("Retry-After"))
+	s.Equal(http.StatusNoContent, do("b").Code)
+}
+
+func (s *RateLimitTestSuite) TestUnaryServerInterceptor() {
+	k := NewKeyed[string](func() Limiter { return NewSlidingWindow(1, time.Minute) })
+	interceptor := UnaryServerInterceptor(k, func(ctx context.Context, method string) string { return method })
+	info := &grpc.UnaryServerInfo{FullMethod: "/validator.v1.ValidatorService/ValidateAverageOutputs"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	res, err := interceptor(context.Background(), nil, info, handler)
+	s.NoError(err)
+	s.Equal("ok", res)
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	s.Equal(codes.ResourceExhausted, status.Code(err))
+}
+
+func (s *RateLimitTestSuite) TestConfig() {
+	s.NoError((&Config{Rate: 10, Burst: 5}).Validate())
+	s.Error((&Config{Rate: 0, Burst: 5}).Validate())
+	s.Error((&Config{Rate: 10}).Validate())
+
+	_, err := NewFromConfig(&Config{})
+	s.Error(err)
+	l, err := NewFromConfig(&Config{Rate: 10, Burst: 1})
+	s.NoError(err)
+	s.True(l.Allow())
+	s.False(errors.Is(err, ErrLimitExceeded))
+}
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitTestSuite))
+}
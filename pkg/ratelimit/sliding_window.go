@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	events []time.Time
+}
+
+// NewSlidingWindow returns a Limiter that admits at most limit events in any
+// window-long interval. Unlike a token bucket it never allows a burst above
+// limit at a window boundary.
+func NewSlidingWindow(limit int, window time.Duration) Limiter {
+	return &slidingWindow{
+		limit:  limit,
+		window: window,
+		now:    time.Now,
+		events: make([]time.Time, 0, limit),
+	}
+}
+
+func (sw *slidingWindow) Allow() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	now := sw.now()
+	sw.prune(now)
+	if len(sw.events) >= sw.limit {
+		return false
+	}
+	sw.events = append(sw.events, now)
+	return true
+}
+
+func (sw *slidingWindow) Wait(ctx context.Context) error {
+	return wait(ctx, sw.Reserve())
+}
+
+func (sw *slidingWindow) Reserve() *Reservation {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.limit <= 0 {
+		return &Reservation{}
+	}
+
+	now := sw.now()
+	sw.prune(now)
+
+	at := now
+	if len(sw.events) >= sw.limit {
+		at = sw.events[len(sw.events)-sw.limit].Add(sw.window)
+	}
+	sw.events = append(sw.events, at)
+
+	return &Reservation{
+		ok:     true,
+		delay:  at.Sub(now),
+		cancel: func() { sw.release(at) },
+	}
+}
+
+// prune drops events that have left the window. Must be called with sw.mu held.
+func (sw *slidingWindow) prune(now time.Time) {
+	cutoff := now.Add(-sw.window)
+	i := 0
+	for i < len(sw.events) && !sw.events[i].After(cutoff) {
+		i++
+	}
+	sw.events = sw.events[i:]
+}
+
+func (sw *slidingWindow) release(at time.Time) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if i := slices.Index(sw.events, at); i >= 0 {
+		sw.events = slices.Delete(sw.events, i, i+1)
+	}
+}
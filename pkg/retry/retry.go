@@ -0,0 +1,244 @@
+// Package retry runs operations with exponential backoff and jitter.
+package retry
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultMaxAttempts     = 3
+	DefaultInitialInterval = 100 * time.Millisecond
+	DefaultMaxInterval     = 10 * time.Second
+	DefaultMultiplier      = 2.0
+	DefaultJitter          = 0.2
+)
+
+// Config is a retry policy that can be embedded in client Configs.
+type Config struct {
+	MaxAttempts     int           `koanf:"max_attempts" json:"max_attempts" envconfig:"max_attempts"`
+	InitialInterval time.Duration `koanf:"initial_interval" json:"initial_interval" envconfig:"initial_interval"`
+	MaxInterval     time.Duration `koanf:"max_interval" json:"max_interval" envconfig:"max_interval"`
+	MaxElapsed      time.Duration `koanf:"max_elapsed" json:"max_elapsed" envconfig:"max_elapsed"`
+	Multiplier      float64       `koanf:"multiplier" json:"multiplier" envconfig:"multiplier"`
+	Jitter          float64       `koanf:"jitter" json:"jitter" envconfig:"jitter"`
+}
+
+func (c *Config) Validate() error {
+	if c.MaxAttempts < 0 {
+		return errors.New("max attempts must not be negative")
+	}
+	if c.InitialInterval < 0 || c.MaxInterval < 0 || c.MaxElapsed < 0 {
+		return errors.New("retry intervals must not be negative")
+	}
+	if c.Multiplier != 0 && c.Multiplier < 1 {
+		return errors.New("multiplier must be at least 1")
+	}
+	if c.Jitter < 0 || c.Jitter > 1 {
+		return errors.New("jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// Options converts the non-zero fields of c into Options. Zero fields keep
+// the package defaults.
+func (c *Config) Options() []Option {
+	var opts []Option
+	if c.MaxAttempts > 0 {
+		opts = append(opts, WithMaxAttempts(c.MaxAttempts))
+	}
+	if c.InitialInterval > 0 || c.MaxInterval > 0 {
+		opts = append(opts, WithBackoff(c.InitialInterval, c.MaxInterval))
+	}
+	if c.MaxElapsed > 0 {
+		opts = append(opts, WithMaxElapsed(c.MaxElapsed))
+	}
+	if c.Multiplier > 0 {
+		opts = append(opts, WithMultiplier(c.Multiplier))
+	}
+	if c.Jitter > 0 {
+		opts = append(opts, WithJitter(c.Jitter))
+	}
+	return opts
+}
+
+type Option func(*policy)
+
+type policy struct {
+	maxAttempts int
+	initial     time.Duration
+	max         time.Duration
+	maxElapsed  time.Duration
+	multiplier  float64
+	jitter      float64
+	retryIf     func(error) bool
+	onRetry     func(attempt int, err error, delay time.Duration)
+}
+
+// WithMaxAttempts limits the total number of calls, including the first.
+func WithMaxAttempts(n int) Option {
+	return func(p *policy) {
+		p.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the cap on any delay.
+// A zero value keeps the corresponding default.
+func WithBackoff(initial, max time.Duration) Option {
+	return func(p *policy) {
+		if initial > 0 {
+			p.initial = initial
+		}
+		if max > 0 {
+			p.max = max
+		}
+	}
+}
+
+// WithMultiplier sets the factor the delay grows by after each attempt.
+func WithMultiplier(m float64) Option {
+	return func(p *policy) {
+		p.multiplier = m
+	}
+}
+
+// WithJitter randomises each delay by up to the given fraction in either
+// direction, so 0.2 yields delays between 80% and 120% of the backoff.
+func WithJitter(fraction float64) Option {
+	return func(p *policy) {
+		p.jitter = fraction
+	}
+}
+
+// WithMaxElapsed stops retrying once the total time spent exceeds d.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(p *policy) {
+		p.maxElapsed = d
+	}
+}
+
+// WithRetryIf classifies errors. Only errors for which fn returns true are
+// retried; by default every error that is not Permanent is retried.
+func WithRetryIf(fn func(error) bool) Option {
+	return func(p *policy) {
+		p.retryIf = fn
+	}
+}
+
+// WithOnRetry registers a hook called before sleeping ahead of each retry.
+func WithOnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(p *policy) {
+		p.onRetry = fn
+	}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as not retryable regardless of the classification hook.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// Do calls fn until it succeeds, returns a non-retryable error, the attempt or
+// elapsed-time budget is exhausted, or ctx is done.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	_, err := DoValue(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	}, opts...)
+	return err
+}
+
+// DoValue is like Do but returns the value produced by a successful call.
+func DoValue[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	p := newPolicy(opts...)
+	start := time.Now()
+
+	var zero T
+	for attempt := 1; ; attempt++ {
+		v, err := fn(ctx)
+		if err == nil {
+			return v, nil
+		}
+
+		var pe *permanentError
+		if errors.As(err, &pe) {
+			return zero, pe.err
+		}
+		if p.retryIf != nil && !p.retryIf(err) {
+			return zero, err
+		}
+		if p.maxAttempts > 0 && attempt >= p.maxAttempts {
+			return zero, errors.Wrapf(err, "giving up after %d attempts", attempt)
+		}
+
+		delay := p.delay(attempt)
+		if p.maxElapsed > 0 && time.Since(start)+delay > p.maxElapsed {
+			return zero, errors.Wrapf(err, "giving up after %s", time.Since(start).Round(time.Millisecond))
+		}
+
+		if p.onRetry != nil {
+			p.onRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, errors.Wrapf(ctx.Err(), "retry aborted after %d attempts: %v", attempt, err)
+		case <-timer.C:
+		}
+	}
+}
+
+// Backoff returns the jittered delay Do would wait after the given attempt,
+// for callers that drive their own retry loop.
+func Backoff(attempt int, opts ...Option) time.Duration {
+	return newPolicy(opts...).delay(attempt)
+}
+
+func newPolicy(opts ...Option) *policy {
+	p := &policy{
+		maxAttempts: DefaultMaxAttempts,
+		initial:     DefaultInitialInterval,
+		max:         DefaultMaxInterval,
+		multiplier:  DefaultMultiplier,
+		jitter:      DefaultJitter,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// delay returns the jittered backoff to wait after the given attempt, never
+// more than the maximum.
+func (p *policy) delay(attempt int) time.Duration {
+	backoff := float64(p.initial) * math.Pow(p.multiplier, float64(attempt-1))
+	if p.jitter > 0 {
+		backoff *= 1 + p.jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(min(backoff, float64(p.max)))
+}
@@ -0,0 +1,173 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+var errTransient = errors.New("transient")
+
+type RetryTestSuite struct {
+	suite.Suite
+}
+
+func fastOpts(opts ...Option) []Option {
+	return append([]Option{WithBackoff(time.Millisecond, 5*time.Millisecond), WithJitter(0)}, opts...)
+}
+
+func (s *RetryTestSuite) TestDo() {
+	testCases := []struct {
+		name      string
+		failures  int
+		opts      []Option
+		wantCalls int
+		wantErr   bool
+	}{
+		{"succeeds first time", 0, nil, 1, false},
+		{"succeeds after retries", 2, nil, 3, false},
+		{"exhausts attempts", 5, []Option{WithMaxAttempts(3)}, 3, true},
+		{"unlimited attempts", 6, []Option{WithMaxAttempts(0)}, 7, false},
+		{"non-retryable error", 5, []Option{WithRetryIf(func(err error) bool { return false })}, 1, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			calls := 0
+			err := Do(context.Background(), func(ctx context.Context) error {
+				calls++
+				if calls <= tc.failures {
+					return errTransient
+				}
+				return nil
+			}, fastOpts(tc.opts...)...)
+
+			s.Equal(tc.wantCalls, calls)
+			if tc.wantErr {
+				s.Error(err)
+				s.True(errors.Is(err, errTransient))
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *RetryTestSuite) TestPermanent() {
+	calls := 0
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(errTransient)
+	}, fastOpts()...)
+
+	s.Equal(1, calls)
+	s.Equal(errTransient, err)
+	s.Nil(Permanent(nil))
+	s.True(IsPermanent(errors.Wrap(Permanent(errTransient), "wrapped")))
+}
+
+func (s *RetryTestSuite) TestDoValue() {
+	calls := 0
+	v, err := DoValue(context.Background(), func(ctx context.Context) (int, error) {
+		calls++
+		if calls < 2 {
+			return 0, errTransient
+		}
+		return 42, nil
+	}, fastOpts()...)
+
+	s.NoError(err)
+	s.Equal(42, v)
+}
+
+func (s *RetryTestSuite) TestContextCancellation() {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := Do(ctx, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return errTransient
+	}, WithBackoff(time.Second, time.Second), WithMaxAttempts(10))
+
+	s.Equal(1, calls)
+	s.True(errors.Is(err, context.Canceled))
+}
+
+func (s *RetryTestSuite) TestMaxElapsed() {
+	calls := 0
+	err := Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errTransient
+	}, WithBackoff(20*time.Millisecond, 20*time.Millisecond), WithJitter(0), WithMaxAttempts(0), WithMaxElapsed(50*time.Millisecond))
+
+	s.Error(err)
+	s.LessOrEqual(calls, 3)
+}
+
+func (s *RetryTestSuite) TestOnRetry() {
+	var attempts []int
+	_ = Do(context.Background(), func(ctx context.Context) error {
+		return errTransient
+	}, fastOpts(WithMaxAttempts(3), WithOnRetry(func(attempt int, err error, delay time.Duration) {
+		attempts = append(attempts, attempt)
+	}))...)
+
+	s.Equal([]int{1, 2}, attempts)
+}
+
+func (s *RetryTestSuite) TestDelay() {
+	p := newPolicy(WithBackoff(100*time.Millisecond, time.Second), WithJitter(0))
+	s.Equal(100*time.Millisecond, p.delay(1))
+	s.Equal(200*time.Millisecond, p.delay(2))
+	s.Equal(400*time.Millisecond, p.delay(3))
+	s.Equal(time.Second, p.delay(10))
+	s.Equal(200*time.Millisecond, Backoff(2, WithBackoff(100*time.Millisecond, time.Second), WithJitter(0)))
+
+	p = newPolicy(WithBackoff(100*time.Millisecond, time.Second), WithJitter(0.5))
+	for i := 0; i < 100; i++ {
+		d := p.delay(1)
+		s.GreaterOrEqual(d, 50*time.Millisecond)
+		s.LessOrEqual(d, 150*time.Millisecond)
+	}
+	for i := 0; i < 100; i++ {
+		s.LessOrEqual(p.delay(10), time.Second)
+	}
+}
+
+func (s *RetryTestSuite) TestConfig() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{"zero config", &Config{}, false},
+		{"valid config", &Config{MaxAttempts: 5, InitialInterval: time.Second, Multiplier: 1.5, Jitter: 0.1}, false},
+		{"negative attempts", &Config{MaxAttempts: -1}, true},
+		{"small multiplier", &Config{Multiplier: 0.5}, true},
+		{"large jitter", &Config{Jitter: 2}, true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+
+	cfg := &Config{MaxAttempts: 7, InitialInterval: 5 * time.Millisecond}
+	p := newPolicy(cfg.Options()...)
+	s.Equal(7, p.maxAttempts)
+	s.Equal(5*time.Millisecond, p.initial)
+	s.Equal(DefaultMaxInterval, p.max)
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
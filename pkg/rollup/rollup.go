@@ -0,0 +1,264 @@
+// Package rollup computes rolling statistics over sliding windows of
+// time-series samples, keyed by project, DER or any comparable key.
+//
+// Windows are Length long and start every Step, so with a 15 minute length
+// and 5 minute step each sample falls in three windows. Each window is the
+// half-open interval [Start, End), aligned with timeutil.Floor. A window is
+// closed, and passed to the close callback, once the watermark (the latest
+// sample time, or the time given to Advance) reaches its End plus Lateness.
+// Samples for windows that have already closed are rejected with ErrLate.
+//
+//	agg, err := rollup.New(&rollup.Config{Length: 15 * time.Minute, Step: 5 * time.Minute},
+//		func(r rollup.Result[string]) {
+//			publish(r.Key, r.Window, r.Stats.Mean())
+//		})
+//	...
+//	err := agg.Add(reading.ProjectID, reading.Timestamp, reading.CurrentOutput)
+package rollup
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/timeutil"
+	"github.com/pkg/errors"
+)
+
+var ErrLate = errors.New("sample is older than every open window")
+
+type Config struct {
+	Length time.Duration `koanf:"length" json:"length" envconfig:"length"`
+	// Step is how often a window starts. Zero means Length, giving
+	// back-to-back tumbling windows.
+	Step time.Duration `koanf:"step" json:"step" envconfig:"step"`
+	// Lateness is how long to wait past a window's end for stragglers
+	// before closing it.
+	Lateness time.Duration `koanf:"lateness" json:"lateness" envconfig:"lateness"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("rollup configuration required")
+	}
+	if c.Length <= 0 {
+		return errors.New("rollup length must be greater than 0")
+	}
+	step := c.step()
+	if err := timeutil.ValidateSize(step); err != nil {
+		return errors.Wrap(err, "rollup step")
+	}
+	if c.Length%step != 0 {
+		return errors.Errorf("rollup length %s must be a multiple of step %s", c.Length, step)
+	}
+	if c.Lateness < 0 {
+		return errors.New("rollup lateness must not be negative")
+	}
+	return nil
+}
+
+func (c *Config) step() time.Duration {
+	if c.Step == 0 {
+		return c.Length
+	}
+	return c.Step
+}
+
+// Result is a closed window for one key. Windows with no samples are not
+// reported.
+type Result[K comparable] struct {
+	Key    K
+	Window timeutil.Window
+	Stats  Stats
+}
+
+// series holds one key's samples in Step sized buckets, by bucket start.
+type series struct {
+	buckets map[int64]Stats
+	// next is the end of the earliest window not yet closed.
+	next time.Time
+}
+
+type Aggregator[K comparable] struct {
+	length   time.Duration
+	step     time.Duration
+	lateness time.Duration
+	onClose  func(Result[K])
+
+	mu        sync.Mutex
+	watermark time.Time
+	series    map[K]*series
+
+	// emitMu keeps callbacks in window order across concurrent callers.
+	emitMu sync.Mutex
+}
+
+// New returns an Aggregator that calls onClose with each closed window, in
+// order of window end. Windows closing together are reported in no
+// particular key order. onClose is called synchronously from Add, Advance
+// and Flush, without internal locks held.
+func New[K comparable](cfg *Config, onClose func(Result[K])) (*Aggregator[K], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if onClose == nil {
+		return nil, errors.New("rollup close callback required")
+	}
+	return &Aggregator[K]{
+		length:   cfg.Length,
+		step:     cfg.step(),
+		lateness: cfg.Lateness,
+		onClose:  onClose,
+		series:   map[K]*series{},
+	}, nil
+}
+
+// Add records a sample of key taken at t, advancing the watermark to t if
+// it is later.
+func (a *Aggregator[K]) Add(key K, t time.Time, v float64) error {
+	a.mu.Lock()
+	bucket := timeutil.Floor(t, a.step).UTC()
+	if a.closed(bucket.Add(a.step)) {
+		a.mu.Unlock()
+		return errors.Wrapf(ErrLate, "sample at %s, watermark %s", t, a.watermark)
+	}
+
+	s, ok := a.series[key]
+	if !ok {
+		s = &series{buckets: map[int64]Stats{}, next: bucket.Add(a.step)}
+		a.series[key] = s
+	}
+	st := s.buckets[bucket.UnixNano()]
+	st.Add(t, v)
+	s.buckets[bucket.UnixNano()] = st
+
+	var results []Result[K]
+	if t.After(a.watermark) {
+		a.watermark = t
+		results = a.advance(false)
+	}
+	a.emit(results)
+	return nil
+}
+
+// Advance moves the watermark to now, closing windows for keys that have
+// stopped reporting. Earlier times are ignored.
+func (a *Aggregator[K]) Advance(now time.Time) {
+	a.mu.Lock()
+	var results []Result[K]
+	if now.After(a.watermark) {
+		a.watermark = now
+		results = a.advance(false)
+	}
+	a.emit(results)
+}
+
+// Flush closes every window holding samples, regardless of the watermark,
+// leaving the aggregator empty. It is meant for shutdown: later samples for
+// the flushed windows would start them afresh.
+func (a *Aggregator[K]) Flush() {
+	a.mu.Lock()
+	results := a.advance(true)
+	a.emit(results)
+}
+
+// Current returns the statistics so far for the window of key ending with
+// the step containing at, which may still be open.
+func (a *Aggregator[K]) Current(key K, at time.Time) Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, ok := a.series[key]
+	if !ok {
+		return Stats{}
+	}
+	end := timeutil.Floor(at, a.step).UTC().Add(a.step)
+	return s.window(end, a.length, a.step)
+}
+
+// Watermark returns the time up to which windows are being closed.
+func (a *Aggregator[K]) Watermark() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.watermark
+}
+
+// Len returns the number of keys with open windows.
+func (a *Aggregator[K]) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.series)
+}
+
+// closed reports whether the window ending at end has been closed.
+func (a *Aggregator[K]) closed(end time.Time) bool {
+	return !a.watermark.IsZero() && !end.Add(a.lateness).After(a.watermark)
+}
+
+// advance closes due windows, or all windows if all is set. a.mu must be
+// held.
+func (a *Aggregator[K]) advance(all bool) []Result[K] {
+	var results []Result[K]
+	for key, s := range a.series {
+		for all || a.closed(s.next) {
+			end := s.next
+			if st := s.window(end, a.length, a.step); st.Count > 0 {
+				results = append(results, Result[K]{
+					Key:    key,
+					Window: timeutil.Window{Start: end.Add(-a.length), End: end},
+					Stats:  st,
+				})
+			}
+
+			s.next = end.Add(a.step)
+			earliest := s.prune(s.next.Add(-a.length))
+			if len(s.buckets) == 0 {
+				delete(a.series, key)
+				break
+			}
+			// Skip windows in a gap between samples.
+			if first := earliest.Add(a.step); first.After(s.next) {
+				s.next = first
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Window.End.Before(results[j].Window.End)
+	})
+	return results
+}
+
+// emit releases a.mu and calls onClose with results in order.
+func (a *Aggregator[K]) emit(results []Result[K]) {
+	a.emitMu.Lock()
+	a.mu.Unlock()
+	defer a.emitMu.Unlock()
+	for _, r := range results {
+		a.onClose(r)
+	}
+}
+
+// window merges the buckets in the length window ending at end.
+func (s *series) window(end time.Time, length, step time.Duration) Stats {
+	var st Stats
+	for b := end.Add(-length); b.Before(end); b = b.Add(step) {
+		st.Merge(s.buckets[b.UnixNano()])
+	}
+	return st
+}
+
+// prune drops buckets starting before cutoff and returns the earliest
+// remaining bucket start.
+func (s *series) prune(cutoff time.Time) time.Time {
+	var earliest int64
+	for b := range s.buckets {
+		if b < cutoff.UnixNano() {
+			delete(s.buckets, b)
+			continue
+		}
+		if earliest == 0 || b < earliest {
+			earliest = b
+		}
+	}
+	return time.Unix(0, earliest).UTC()
+}
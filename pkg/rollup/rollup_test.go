@@ -0,0 +1,175 @@
+package rollup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RollupTestSuite struct {
+	suite.Suite
+	base    time.Time
+	results []Result[string]
+}
+
+func (s *RollupTestSuite) SetupTest() {
+	s.base = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.results = nil
+}
+
+func (s *RollupTestSuite) at(d time.Duration) time.Time {
+	return s.base.Add(d)
+}
+
+func (s *RollupTestSuite) aggregator(cfg *Config) *Aggregator[string] {
+	a, err := New(cfg, func(r Result[string]) { s.results = append(s.results, r) })
+	s.Require().NoError(err)
+	return a
+}
+
+func (s *RollupTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "tumbling", cfg: &Config{Length: 5 * time.Minute}},
+		{name: "sliding", cfg: &Config{Length: 15 * time.Minute, Step: 5 * time.Minute, Lateness: time.Minute}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "missing length", cfg: &Config{}, expectError: true},
+		{name: "length not a multiple", cfg: &Config{Length: 12 * time.Minute, Step: 5 * time.Minute}, expectError: true},
+		{name: "step not dividing a day", cfg: &Config{Length: 14 * time.Minute, Step: 7 * time.Minute}, expectError: true},
+		{name: "negative lateness", cfg: &Config{Length: time.Minute, Lateness: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+
+	_, err := New[string](&Config{Length: time.Minute}, nil)
+	s.Error(err)
+}
+
+func (s *RollupTestSuite) TestTumblingBoundaries() {
+	a := s.aggregator(&Config{Length: 5 * time.Minute})
+
+	s.Require().NoError(a.Add("p1", s.at(0), 1))
+	s.Require().NoError(a.Add("p1", s.at(5*time.Minute-time.Nanosecond), 3))
+	s.Empty(s.results)
+
+	// A sample exactly on the boundary belongs to the next window and closes
+	// the previous one.
+	s.Require().NoError(a.Add("p1", s.at(5*time.Minute), 10))
+	s.Require().Len(s.results, 1)
+	r := s.results[0]
+	s.Equal("p1", r.Key)
+	s.Equal(s.at(0), r.Window.Start)
+	s.Equal(s.at(5*time.Minute), r.Window.End)
+	s.Equal(2, r.Stats.Count)
+	s.Equal(2.0, r.Stats.Mean())
+	s.Equal(1.0, r.Stats.Min)
+	s.Equal(3.0, r.Stats.Max)
+	s.Equal(s.at(5*time.Minute-time.Nanosecond), r.Stats.Last)
+
+	s.ErrorIs(a.Add("p1", s.at(4*time.Minute), 1), ErrLate)
+	s.ErrorIs(a.Add("p2", s.at(4*time.Minute), 1), ErrLate)
+}
+
+func (s *RollupTestSuite) TestSliding() {
+	a := s.aggregator(&Config{Length: 15 * time.Minute, Step: 5 * time.Minute})
+
+	s.Require().NoError(a.Add("p1", s.at(time.Minute), 3))
+	s.Require().NoError(a.Add("p1", s.at(6*time.Minute), 6))
+	s.Require().Len(s.results, 1)
+
+	a.Advance(s.at(time.Hour))
+	var ends []time.Duration
+	var means []float64
+	for _, r := range s.results {
+		s.Equal(15*time.Minute, r.Window.Duration())
+		ends = append(ends, r.Window.End.Sub(s.base))
+		means = append(means, r.Stats.Mean())
+	}
+	s.Equal([]time.Duration{5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 20 * time.Minute}, ends)
+	s.Equal([]float64{3, 4.5, 4.5, 6}, means)
+	s.Zero(a.Len())
+}
+
+func (s *RollupTestSuite) TestLateness() {
+	a := s.aggregator(&Config{Length: 5 * time.Minute, Lateness: time.Minute})
+
+	s.Require().NoError(a.Add("p1", s.at(0), 1))
+	s.Require().NoError(a.Add("p2", s.at(5*time.Minute+30*time.Second), 1))
+	s.Require().NoError(a.Add("p1", s.at(4*time.Minute), 2), "within lateness")
+	s.Empty(s.results)
+
+	a.Advance(s.at(6 * time.Minute))
+	s.Require().Len(s.results, 1)
+	s.Equal(2, s.results[0].Stats.Count)
+	s.ErrorIs(a.Add("p1", s.at(4*time.Minute), 2), ErrLate)
+}
+
+func (s *RollupTestSuite) TestGap() {
+	a := s.aggregator(&Config{Length: 10 * time.Minute, Step: 5 * time.Minute})
+
+	s.Require().NoError(a.Add("p1", s.at(0), 1))
+	s.Require().NoError(a.Add("p1", s.at(6*time.Hour), 2))
+	s.Require().Len(s.results, 2, "only windows holding samples are reported")
+
+	a.Flush()
+	s.Require().Len(s.results, 4)
+	s.Equal(s.at(6*time.Hour+5*time.Minute), s.results[2].Window.End)
+	s.Equal(s.at(6*time.Hour+10*time.Minute), s.results[3].Window.End)
+	s.Zero(a.Len())
+}
+
+func (s *RollupTestSuite) TestOrderAcrossKeys() {
+	a := s.aggregator(&Config{Length: 5 * time.Minute})
+	s.Require().NoError(a.Add("a", s.at(0), 1))
+	s.Require().NoError(a.Add("b", s.at(5*time.Minute), 1))
+	s.Require().NoError(a.Add("a", s.at(10*time.Minute), 1))
+	a.Advance(s.at(time.Hour))
+
+	var got []string
+	for _, r := range s.results {
+		got = append(got, r.Key+"@"+r.Window.End.Sub(s.base).String())
+	}
+	s.Equal([]string{"a@5m0s", "b@10m0s", "a@15m0s"}, got)
+}
+
+func (s *RollupTestSuite) TestCurrent() {
+	a := s.aggregator(&Config{Length: 15 * time.Minute, Step: 5 * time.Minute})
+	s.Require().NoError(a.Add("p1", s.at(time.Minute), 2))
+	s.Require().NoError(a.Add("p1", s.at(7*time.Minute), 4))
+
+	cur := a.Current("p1", s.at(8*time.Minute))
+	s.Equal(2, cur.Count)
+	s.Equal(3.0, cur.Mean())
+	s.Zero(a.Current("p2", s.at(8*time.Minute)).Count)
+	s.Equal(s.at(7*time.Minute), a.Watermark())
+}
+
+func (s *RollupTestSuite) TestLocalAlignment() {
+	// Windows align to the sample's own offset, so an hour window in a
+	// +05:30 zone starts on the local hour.
+	a := s.aggregator(&Config{Length: time.Hour})
+	loc := time.FixedZone("IST", 5*3600+1800)
+	t := time.Date(2024, 6, 1, 10, 15, 0, 0, loc)
+	s.Require().NoError(a.Add("p1", t, 1))
+	a.Flush()
+
+	s.Require().Len(s.results, 1)
+	s.Equal(time.Date(2024, 6, 1, 10, 0, 0, 0, loc).Unix(), s.results[0].Window.Start.Unix())
+}
+
+func TestRollupTestSuite(t *testing.T) {
+	suite.Run(t, new(RollupTestSuite))
+}
@@ -0,0 +1,64 @@
+package rollup
+
+import (
+	"math"
+	"time"
+)
+
+// Stats summarises the samples in a window. The zero value is empty.
+type Stats struct {
+	Count int
+	Sum   float64
+	Min   float64
+	Max   float64
+	// First and Last are the earliest and latest sample times.
+	First time.Time
+	Last  time.Time
+}
+
+// Add includes a sample taken at t.
+func (s *Stats) Add(t time.Time, v float64) {
+	if s.Count == 0 {
+		*s = Stats{Count: 1, Sum: v, Min: v, Max: v, First: t, Last: t}
+		return
+	}
+	s.Count++
+	s.Sum += v
+	s.Min = math.Min(s.Min, v)
+	s.Max = math.Max(s.Max, v)
+	if t.Before(s.First) {
+		s.First = t
+	}
+	if t.After(s.Last) {
+		s.Last = t
+	}
+}
+
+// Merge includes every sample summarised by o.
+func (s *Stats) Merge(o Stats) {
+	if o.Count == 0 {
+		return
+	}
+	if s.Count == 0 {
+		*s = o
+		return
+	}
+	s.Count += o.Count
+	s.Sum += o.Sum
+	s.Min = math.Min(s.Min, o.Min)
+	s.Max = math.Max(s.Max, o.Max)
+	if o.First.Before(s.First) {
+		s.First = o.First
+	}
+	if o.Last.After(s.Last) {
+		s.Last = o.Last
+	}
+}
+
+// Mean returns the average sample, or 0 if there are none.
+func (s Stats) Mean() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / float64(s.Count)
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Component is a long-lived part of a service. Start returns once the
+// component is ready to serve and Stop releases it, honouring ctx's deadline.
+type Component interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Failer is implemented by components whose background work can fail after
+// Start has returned. A value received from Err stops the whole runner.
+type Failer interface {
+	Err() <-chan error
+}
+
+// Func adapts a pair of functions to Component. Either may be nil.
+func Func(start, stop func(ctx context.Context) error) Component {
+	return funcComponent{start: start, stop: stop}
+}
+
+type funcComponent struct {
+	start, stop func(ctx context.Context) error
+}
+
+func (f funcComponent) Start(ctx context.Context) error {
+	if f.start == nil {
+		return nil
+	}
+	return f.start(ctx)
+}
+
+func (f funcComponent) Stop(ctx context.Context) error {
+	if f.stop == nil {
+		return nil
+	}
+	return f.stop(ctx)
+}
+
+// Service adapts a blocking run function, such as httpserver.Server.Run or
+// scheduler.Scheduler.Run, that returns when its context is cancelled. A
+// non-nil error returned before Stop is reported as fatal.
+func Service(run func(ctx context.Context) error) Component {
+	return &service{run: run}
+}
+
+type service struct {
+	run    func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+	errs   chan error
+	once   sync.Once
+}
+
+func (s *service) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	s.errs = make(chan error, 1)
+
+	go func() {
+		defer close(s.done)
+		if err := s.run(ctx); err != nil && ctx.Err() == nil {
+			s.errs <- err
+		}
+	}()
+	return nil
+}
+
+func (s *service) Stop(ctx context.Context) error {
+	s.once.Do(s.cancel)
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func (s *service) Err() <-chan error {
+	return s.errs
+}
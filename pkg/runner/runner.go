@@ -0,0 +1,241 @@
+// Package runner starts a service's components in dependency order, watches
+// them for fatal errors, and stops them in reverse order when the service
+// context is cancelled (typically by sigctx) or a component fails.
+package runner
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	StartTimeout time.Duration `koanf:"start_timeout" json:"start_timeout" envconfig:"start_timeout"`
+	StopTimeout  time.Duration `koanf:"stop_timeout" json:"stop_timeout" envconfig:"stop_timeout"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("runner configuration required")
+	}
+	if c.StartTimeout < 0 {
+		return errors.New("runner start timeout must not be negative")
+	}
+	if c.StopTimeout < 0 {
+		return errors.New("runner stop timeout must not be negative")
+	}
+	return nil
+}
+
+type ComponentOption func(*entry)
+
+// WithDependsOn starts the component after the named components and stops it
+// before them.
+func WithDependsOn(names ...string) ComponentOption {
+	return func(e *entry) {
+		e.deps = append(e.deps, names...)
+	}
+}
+
+// WithStartTimeout overrides Config.StartTimeout for one component.
+func WithStartTimeout(d time.Duration) ComponentOption {
+	return func(e *entry) {
+		e.startTimeout = d
+	}
+}
+
+// WithStopTimeout overrides Config.StopTimeout for one component.
+func WithStopTimeout(d time.Duration) ComponentOption {
+	return func(e *entry) {
+		e.stopTimeout = d
+	}
+}
+
+type Runner struct {
+	cfg     *Config
+	log     *slog.Logger
+	entries []*entry
+	byName  map[string]*entry
+}
+
+type entry struct {
+	name         string
+	component    Component
+	deps         []string
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+func New(cfg *Config, log *slog.Logger) (*Runner, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Runner{cfg: cfg, log: log, byName: map[string]*entry{}}, nil
+}
+
+func (r *Runner) Add(name string, c Component, opts ...ComponentOption) error {
+	if _, ok := r.byName[name]; ok {
+		return errors.Errorf("component %s already registered", name)
+	}
+
+	e := &entry{
+		name:         name,
+		component:    c,
+		startTimeout: r.cfg.StartTimeout,
+		stopTimeout:  r.cfg.StopTimeout,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	r.entries = append(r.entries, e)
+	r.byName[name] = e
+	return nil
+}
+
+// Run starts every component and blocks until ctx is done or a component
+// fails, then stops the started components in reverse order. It returns the
+// error that caused an early shutdown, or nil if ctx was cancelled. Stop
+// failures are logged and returned only when there is no other error.
+func (r *Runner) Run(ctx context.Context) error {
+	order, err := r.order()
+	if err != nil {
+		return err
+	}
+
+	var started []*entry
+	var fatal error
+	for _, e := range order {
+		if err := r.start(ctx, e); err != nil {
+			fatal = err
+			break
+		}
+		started = append(started, e)
+	}
+
+	if fatal == nil {
+		fatal = r.wait(ctx, started)
+	}
+
+	stopErr := r.stopAll(started)
+	if fatal != nil {
+		return fatal
+	}
+	return stopErr
+}
+
+func (r *Runner) start(ctx context.Context, e *entry) error {
+	if e.startTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.startTimeout)
+		defer cancel()
+	}
+
+	r.log.Info("starting component", "component", e.name)
+	if err := e.component.Start(ctx); err != nil {
+		return errors.Wrapf(err, "starting %s", e.name)
+	}
+	return nil
+}
+
+// wait blocks until ctx is done or one of the started components reports a
+// fatal error.
+func (r *Runner) wait(ctx context.Context, started []*entry) error {
+	cases := []reflect.SelectCase{{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}}
+	var failers []*entry
+	for _, e := range started {
+		if f, ok := e.component.(Failer); ok {
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.Err())})
+			failers = append(failers, e)
+		}
+	}
+
+	for {
+		i, v, ok := reflect.Select(cases)
+		if i == 0 {
+			r.log.Info("shutting down", "reason", context.Cause(ctx))
+			return nil
+		}
+		if !ok || v.IsNil() {
+			// A closed or nil error channel means that component has nothing
+			// more to report.
+			cases[i].Chan = reflect.Value{}
+			continue
+		}
+
+		e := failers[i-1]
+		err := errors.Wrapf(v.Interface().(error), "component %s failed", e.name)
+		r.log.Error("component failed, shutting down", "component", e.name, "error", err)
+		return err
+	}
+}
+
+func (r *Runner) stopAll(started []*entry) error {
+	var first error
+	for i := len(started) - 1; i >= 0; i-- {
+		e := started[i]
+		if err := r.stop(e); err != nil {
+			r.log.Error("stopping component", "component", e.name, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (r *Runner) stop(e *entry) error {
+	ctx := context.Background()
+	if e.stopTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.stopTimeout)
+		defer cancel()
+	}
+
+	r.log.Info("stopping component", "component", e.name)
+	return errors.Wrapf(e.component.Stop(ctx), "stopping %s", e.name)
+}
+
+// order returns the entries sorted so every component follows its
+// dependencies, keeping registration order otherwise.
+func (r *Runner) order() ([]*entry, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	order := make([]*entry, 0, len(r.entries))
+
+	var visit func(e *entry, path []string) error
+	visit = func(e *entry, path []string) error {
+		switch state[e.name] {
+		case done:
+			return nil
+		case visiting:
+			return errors.Errorf("dependency cycle: %v", append(path, e.name))
+		}
+		state[e.name] = visiting
+		for _, dep := range e.deps {
+			d, ok := r.byName[dep]
+			if !ok {
+				return errors.Errorf("component %s depends on unknown component %s", e.name, dep)
+			}
+			if err := visit(d, append(path, e.name)); err != nil {
+				return err
+			}
+		}
+		state[e.name] = done
+		order = append(order, e)
+		return nil
+	}
+
+	for _, e := range r.entries {
+		if err := visit(e, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
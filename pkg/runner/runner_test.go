@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return Func(func(context.Context) error {
+		r.add("start " + name)
+		return startErr
+	}, func(context.Context) error {
+		r.add("stop " + name)
+		return nil
+	})
+}
+
+type RunnerTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	rec *recorder
+}
+
+func (s *RunnerTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.rec = &recorder{}
+}
+
+func (s *RunnerTestSuite) newRunner() *Runner {
+	r, err := New(&Config{StartTimeout: time.Second, StopTimeout: time.Second}, s.log)
+	s.Require().NoError(err)
+	return r
+}
+
+func (s *RunnerTestSuite) TestDependencyOrder() {
+	r := s.newRunner()
+	s.Require().NoError(r.Add("server", s.rec.component("server", nil), WithDependsOn("db", "cache")))
+	s.Require().NoError(r.Add("cache", s.rec.component("cache", nil), WithDependsOn("db")))
+	s.Require().NoError(r.Add("db", s.rec.component("db", nil)))
+	s.Error(r.Add("db", s.rec.component("db", nil)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.NoError(r.Run(ctx))
+
+	s.Equal([]string{"start db", "start cache", "start server", "stop server", "stop cache", "stop db"}, s.rec.events)
+}
+
+func (s *RunnerTestSuite) TestStartFailureStopsStarted() {
+	r := s.newRunner()
+	s.Require().NoError(r.Add("db", s.rec.component("db", nil)))
+	s.Require().NoError(r.Add("broker", s.rec.component("broker", errors.New("connection refused"))))
+	s.Require().NoError(r.Add("server", s.rec.component("server", nil)))
+
+	err := r.Run(context.Background())
+	s.ErrorContains(err, "starting broker")
+	s.Equal([]string{"start db", "start broker", "stop db"}, s.rec.events)
+}
+
+func (s *RunnerTestSuite) TestFatalErrorStopsRunner() {
+	r := s.newRunner()
+	s.Require().NoError(r.Add("db", s.rec.component("db", nil)))
+	s.Require().NoError(r.Add("consumer", Service(func(ctx context.Context) error {
+		return errors.New("stream closed")
+	}), WithDependsOn("db")))
+
+	done := make(chan error, 1)
+	go func() { done <- r.Run(context.Background()) }()
+
+	select {
+	case err := <-done:
+		s.ErrorContains(err, "component consumer failed")
+	case <-time.After(time.Second):
+		s.Fail("runner did not stop on fatal error")
+	}
+	s.Equal([]string{"start db", "stop db"}, s.rec.events)
+}
+
+func (s *RunnerTestSuite) TestServiceStopsOnCancel() {
+	r := s.newRunner()
+	stopped := make(chan struct{})
+	s.Require().NoError(r.Add("server", Service(func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.NoError(r.Run(ctx))
+	<-stopped
+}
+
+func (s *RunnerTestSuite) TestStopTimeout() {
+	r := s.newRunner()
+	s.Require().NoError(r.Add("stuck", Func(nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), WithStopTimeout(10*time.Millisecond)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(r.Run(ctx), context.DeadlineExceeded)
+}
+
+func (s *RunnerTestSuite) TestInvalidDependencies() {
+	r := s.newRunner()
+	s.Require().NoError(r.Add("a", s.rec.component("a", nil), WithDependsOn("b")))
+	s.Require().NoError(r.Add("b", s.rec.component("b", nil), WithDependsOn("a")))
+	s.ErrorContains(r.Run(context.Background()), "dependency cycle")
+
+	r = s.newRunner()
+	s.Require().NoError(r.Add("a", s.rec.component("a", nil), WithDependsOn("missing")))
+	s.ErrorContains(r.Run(context.Background()), "unknown component missing")
+	s.Empty(s.rec.events)
+}
+
+func (s *RunnerTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{StartTimeout: time.Second, StopTimeout: 10 * time.Second}},
+		{name: "no timeouts", cfg: &Config{}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "negative start timeout", cfg: &Config{StartTimeout: -time.Second}, expectError: true},
+		{name: "negative stop timeout", cfg: &Config{StopTimeout: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestRunnerSuite(t *testing.T) {
+	suite.Run(t, new(RunnerTestSuite))
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule reports the next activation strictly after t. It is satisfied by
+// cron.Schedule.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Cron parses a standard five field expression, an optional leading seconds
+// field, or a descriptor such as @hourly. Activations are computed in the
+// scheduler's location unless the expression sets CRON_TZ.
+func Cron(expr string) (Schedule, error) {
+	s, err := parser.Parse(expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing cron expression %q", expr)
+	}
+	return s, nil
+}
+
+func MustCron(expr string) Schedule {
+	s, err := Cron(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Every fires on multiples of d counted from midnight in the scheduler's
+// location, so Every(5*time.Minute) runs at :00, :05, :10 regardless of when
+// the scheduler started. d should divide a day evenly; Add rejects a d that
+// is not positive.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	d := time.Duration(e)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	elapsed := t.Sub(midnight)
+	return midnight.Add((elapsed/d + 1) * d)
+}
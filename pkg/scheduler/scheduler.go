@@ -0,0 +1,249 @@
+// Package scheduler runs named jobs on cron expressions or wall-clock aligned
+// intervals. A job never overlaps itself: an activation that arrives while the
+// previous run is still going is skipped.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Job is the work run on each activation. Its context is cancelled when the
+// scheduler stops or the job's timeout elapses.
+type Job func(ctx context.Context) error
+
+type Config struct {
+	// Location is an IANA time zone name used to evaluate schedules. Empty
+	// means UTC.
+	Location string `koanf:"location" json:"location" envconfig:"location"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("scheduler configuration required")
+	}
+	if _, err := time.LoadLocation(c.Location); err != nil {
+		return errors.Wrapf(err, "invalid scheduler location %q", c.Location)
+	}
+	return nil
+}
+
+type Option func(*Scheduler)
+
+// WithMetrics records runs by job and result, run duration, and the time of
+// each job's last successful run.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Scheduler) {
+		runs, err := m.NewCounterVec("scheduler", "runs_total", "Scheduled job runs by result.", "job", "result")
+		if err != nil {
+			s.log.Warn("registering scheduler metrics", "error", err)
+			return
+		}
+		duration, err := m.NewHistogramVec("scheduler", "run_duration_seconds", "Scheduled job run duration.", prometheus.DefBuckets, "job")
+		if err != nil {
+			s.log.Warn("registering scheduler metrics", "error", err)
+			return
+		}
+		lastSuccess, err := m.NewGaugeVec("scheduler", "last_success_timestamp_seconds", "Unix time of the last successful run.", "job")
+		if err != nil {
+			s.log.Warn("registering scheduler metrics", "error", err)
+			return
+		}
+		s.metrics = &schedulerMetrics{runs: runs, duration: duration, lastSuccess: lastSuccess}
+	}
+}
+
+type JobOption func(*job)
+
+// WithJitter delays each activation by a random duration in [0, d), spreading
+// load when many instances share a schedule.
+func WithJitter(d time.Duration) JobOption {
+	return func(j *job) {
+		j.jitter = d
+	}
+}
+
+// WithTimeout bounds a single run.
+func WithTimeout(d time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = d
+	}
+}
+
+type Scheduler struct {
+	loc     *time.Location
+	log     *slog.Logger
+	metrics *schedulerMetrics
+	now     func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	running bool
+	wg      sync.WaitGroup
+}
+
+type schedulerMetrics struct {
+	runs        *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+	lastSuccess *prometheus.GaugeVec
+}
+
+type job struct {
+	name     string
+	schedule Schedule
+	fn       Job
+	jitter   time.Duration
+	timeout  time.Duration
+	active   atomic.Bool
+}
+
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Scheduler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	loc, _ := time.LoadLocation(cfg.Location)
+
+	s := &Scheduler{
+		loc:  loc,
+		log:  log,
+		now:  time.Now,
+		jobs: map[string]*job{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Add registers a job. Jobs must be added before Run.
+func (s *Scheduler) Add(name string, schedule Schedule, fn Job, opts ...JobOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return errors.Errorf("scheduler already running, cannot add job %s", name)
+	}
+	if _, ok := s.jobs[name]; ok {
+		return errors.Errorf("job %s already registered", name)
+	}
+	if d, ok := schedule.(every); ok && d <= 0 {
+		return errors.Errorf("job %s interval must be positive, got %s", name, time.Duration(d))
+	}
+
+	j := &job{name: name, schedule: schedule, fn: fn}
+	for _, opt := range opts {
+		opt(j)
+	}
+	s.jobs[name] = j
+	return nil
+}
+
+// Run schedules every registered job and blocks until ctx is done, then waits
+// for in-flight runs to return. Their contexts are cancelled along with ctx.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("scheduler already running")
+	}
+	s.running = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, j)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.wg.Done()
+
+	for {
+		next := j.schedule.Next(s.now().In(s.loc))
+		if next.IsZero() {
+			s.log.Warn("job has no further activations", "job", j.name)
+			return
+		}
+
+		wait := next.Sub(s.now())
+		if j.jitter > 0 {
+			wait += rand.N(j.jitter)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !j.active.CompareAndSwap(false, true) {
+			s.log.Warn("skipping job, previous run still in progress", "job", j.name)
+			s.record(j.name, "skipped", 0)
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer j.active.Store(false)
+			s.run(ctx, j)
+		}()
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j *job) {
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := safeRun(ctx, j.fn)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		s.log.Error("scheduled job failed", "job", j.name, "duration", elapsed, "error", err)
+		s.record(j.name, "error", elapsed)
+		return
+	}
+	s.log.Debug("scheduled job finished", "job", j.name, "duration", elapsed)
+	s.record(j.name, "ok", elapsed)
+}
+
+func (s *Scheduler) record(name, result string, elapsed time.Duration) {
+	if s.metrics == nil {
+		return
+	}
+	s.metrics.runs.WithLabelValues(name, result).Inc()
+	if result == "skipped" {
+		return
+	}
+	s.metrics.duration.WithLabelValues(name).Observe(elapsed.Seconds())
+	if result == "ok" {
+		s.metrics.lastSuccess.WithLabelValues(name).SetToCurrentTime()
+	}
+}
+
+func safeRun(ctx context.Context, fn Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("scheduled job panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchedulerTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+}
+
+func (s *SchedulerTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *SchedulerTestSuite) TestEveryAlignsToWallClock() {
+	loc, err := time.LoadLocation("America/Toronto")
+	s.Require().NoError(err)
+
+	testCases := []struct {
+		name string
+		d    time.Duration
+		at   time.Time
+		want time.Time
+	}{
+		{"mid window", 5 * time.Minute, time.Date(2025, 1, 15, 12, 3, 27, 0, time.UTC), time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC)},
+		{"on boundary", 5 * time.Minute, time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC), time.Date(2025, 1, 15, 12, 10, 0, 0, time.UTC)},
+		{"crosses midnight", time.Hour, time.Date(2025, 1, 15, 23, 30, 0, 0, time.UTC), time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"local zone", 15 * time.Minute, time.Date(2025, 7, 1, 8, 52, 0, 0, loc), time.Date(2025, 7, 1, 9, 0, 0, 0, loc)},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.True(tc.want.Equal(Every(tc.d).Next(tc.at)), "got %s", Every(tc.d).Next(tc.at))
+		})
+	}
+}
+
+func (s *SchedulerTestSuite) TestCron() {
+	sched, err := Cron("*/5 * * * *")
+	s.Require().NoError(err)
+	at := time.Date(2025, 1, 15, 12, 3, 0, 0, time.UTC)
+	s.Equal(time.Date(2025, 1, 15, 12, 5, 0, 0, time.UTC), sched.Next(at))
+
+	sched, err = Cron("30 0 * * * *")
+	s.Require().NoError(err)
+	s.Equal(time.Date(2025, 1, 15, 13, 0, 30, 0, time.UTC), sched.Next(at))
+
+	_, err = Cron("every five minutes")
+	s.Error(err)
+}
+
+func (s *SchedulerTestSuite) TestRunsAndSkipsOverlap() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc"})
+	s.Require().NoError(err)
+
+	sch, err := New(&Config{}, s.log, WithMetrics(m))
+	s.Require().NoError(err)
+
+	var fast, slow atomic.Int32
+	s.Require().NoError(sch.Add("fast", Every(10*time.Millisecond), func(context.Context) error {
+		fast.Add(1)
+		return nil
+	}))
+	s.Require().NoError(sch.Add("slow", Every(10*time.Millisecond), func(ctx context.Context) error {
+		slow.Add(1)
+		<-ctx.Done()
+		return nil
+	}))
+	s.Error(sch.Add("fast", Every(time.Second), nil))
+	s.Error(sch.Add("zero", Every(0), func(context.Context) error { return nil }))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	s.NoError(sch.Run(ctx))
+
+	s.GreaterOrEqual(fast.Load(), int32(3))
+	s.Equal(int32(1), slow.Load())
+	s.Greater(testutil.ToFloat64(sch.metrics.runs.WithLabelValues("slow", "skipped")), 0.0)
+	s.Error(sch.Add("late", Every(time.Second), nil))
+}
+
+func (s *SchedulerTestSuite) TestFailuresAndTimeouts() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc"})
+	s.Require().NoError(err)
+
+	sch, err := New(&Config{Location: "UTC"}, s.log, WithMetrics(m))
+	s.Require().NoError(err)
+
+	timedOut := make(chan error, 1)
+	s.Require().NoError(sch.Add("panics", Every(10*time.Millisecond), func(context.Context) error { panic("boom") }))
+	s.Require().NoError(sch.Add("fails", Every(10*time.Millisecond), func(context.Context) error { return errors.New("failed") }))
+	s.Require().NoError(sch.Add("bounded", Every(10*time.Millisecond), func(ctx context.Context) error {
+		<-ctx.Done()
+		select {
+		case timedOut <- ctx.Err():
+		default:
+		}
+		return ctx.Err()
+	}, WithTimeout(5*time.Millisecond), WithJitter(time.Millisecond)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	s.NoError(sch.Run(ctx))
+
+	s.ErrorIs(<-timedOut, context.DeadlineExceeded)
+	s.Greater(testutil.ToFloat64(sch.metrics.runs.WithLabelValues("panics", "error")), 0.0)
+	s.Greater(testutil.ToFloat64(sch.metrics.runs.WithLabelValues("fails", "error")), 0.0)
+	s.Equal(0.0, testutil.ToFloat64(sch.metrics.lastSuccess.WithLabelValues("fails")))
+}
+
+func (s *SchedulerTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "default location", cfg: &Config{}},
+		{name: "named location", cfg: &Config{Location: "America/Toronto"}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "unknown location", cfg: &Config{Location: "Mars/Olympus"}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestSchedulerSuite(t *testing.T) {
+	suite.Run(t, new(SchedulerTestSuite))
+}
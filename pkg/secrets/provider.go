@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	secretmanager "google.golang.org/api/secretmanager/v1"
+)
+
+// Provider fetches the value behind a reference with its scheme removed.
+type Provider interface {
+	Fetch(ctx context.Context, ref string) (string, error)
+}
+
+// ProviderFunc adapts a function to Provider.
+type ProviderFunc func(ctx context.Context, ref string) (string, error)
+
+func (f ProviderFunc) Fetch(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// Env resolves env://NAME to the value of the environment variable NAME.
+func Env() Provider {
+	return ProviderFunc(func(_ context.Context, name string) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.Wrapf(ErrNotFound, "environment variable %s", name)
+		}
+		return v, nil
+	})
+}
+
+// File resolves file:///path/to/secret to the file's contents with one
+// trailing newline removed, matching how Kubernetes mounts secrets.
+func File() Provider {
+	return ProviderFunc(func(_ context.Context, path string) (string, error) {
+		b, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.Wrapf(ErrNotFound, "file %s", path)
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+	})
+}
+
+// SecretManager resolves gcp-sm:// references against Google Secret Manager.
+// References are either a full version name,
+// gcp-sm://projects/p/secrets/s/versions/3, or the short form
+// gcp-sm://p/s[/version], where version defaults to latest.
+func SecretManager(ctx context.Context, opts ...option.ClientOption) (Provider, error) {
+	svc, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating secret manager client")
+	}
+	return &secretManager{versions: svc.Projects.Secrets.Versions}, nil
+}
+
+type secretManager struct {
+	versions *secretmanager.ProjectsSecretsVersionsService
+}
+
+func (s *secretManager) Fetch(ctx context.Context, ref string) (string, error) {
+	name, err := versionName(ref)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := s.versions.Access(name).Context(ctx).Do()
+	if err != nil {
+		var gerr *googleapi.Error
+		if errors.As(err, &gerr) && gerr.Code == 404 {
+			return "", errors.Wrapf(ErrNotFound, "secret %s", name)
+		}
+		return "", errors.Wrapf(err, "accessing secret %s", name)
+	}
+	if res.Payload == nil {
+		return "", errors.Errorf("secret %s has no payload", name)
+	}
+
+	data, err := decodeBase64(res.Payload.Data)
+	if err != nil {
+		return "", errors.Wrapf(err, "decoding secret %s", name)
+	}
+	return data, nil
+}
+
+func versionName(ref string) (string, error) {
+	if strings.HasPrefix(ref, "projects/") {
+		return ref, nil
+	}
+
+	parts := strings.Split(ref, "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return "projects/" + parts[0] + "/secrets/" + parts[1] + "/versions/latest", nil
+	case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		return "projects/" + parts[0] + "/secrets/" + parts[1] + "/versions/" + parts[2], nil
+	}
+	return "", errors.Errorf("invalid secret manager reference %q", ref)
+}
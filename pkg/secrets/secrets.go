@@ -0,0 +1,282 @@
+// Package secrets resolves secret references such as env://API_KEY,
+// file:///var/run/secrets/db-password and gcp-sm://project/secret into their
+// values. References are usually found in Config fields, so a service loads
+// its configuration and then resolves it in place:
+//
+//	if err := config.Load(&cfg, config.WithFile(path)); err != nil { ... }
+//	if err := resolver.ResolveStruct(ctx, &cfg); err != nil { ... }
+//
+// Strings without a registered scheme are left untouched, so plain values keep
+// working.
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	SchemeEnv           = "env"
+	SchemeFile          = "file"
+	SchemeSecretManager = "gcp-sm"
+)
+
+var ErrNotFound = errors.New("secret not found")
+
+type Config struct {
+	// CacheTTL bounds how long a resolved value is reused before Resolve
+	// fetches it again. Zero caches values until Refresh.
+	CacheTTL time.Duration `koanf:"cache_ttl" json:"cache_ttl" envconfig:"cache_ttl"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("secrets configuration required")
+	}
+	if c.CacheTTL < 0 {
+		return errors.New("secrets cache ttl must not be negative")
+	}
+	return nil
+}
+
+type Option func(*Resolver)
+
+// WithProvider registers p for scheme, replacing any existing provider. The
+// env and file schemes are registered by default; gcp-sm is registered by
+// passing the result of SecretManager.
+func WithProvider(scheme string, p Provider) Option {
+	return func(r *Resolver) {
+		r.providers[scheme] = p
+	}
+}
+
+type Resolver struct {
+	cfg       *Config
+	log       *slog.Logger
+	providers map[string]Provider
+	now       func() time.Time
+
+	mu        sync.Mutex
+	cache     map[string]cached
+	callbacks []func(ref string)
+}
+
+type cached struct {
+	value     string
+	fetchedAt time.Time
+}
+
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Resolver, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{
+		cfg: cfg,
+		log: log,
+		providers: map[string]Provider{
+			SchemeEnv:  Env(),
+			SchemeFile: File(),
+		},
+		now:   time.Now,
+		cache: map[string]cached{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// IsReference reports whether s uses a registered scheme.
+func (r *Resolver) IsReference(s string) bool {
+	_, _, ok := r.split(s)
+	return ok
+}
+
+// Resolve returns the value s refers to, or s itself if it is not a
+// reference.
+func (r *Resolver) Resolve(ctx context.Context, s string) (string, error) {
+	p, ref, ok := r.split(s)
+	if !ok {
+		return s, nil
+	}
+
+	r.mu.Lock()
+	c, hit := r.cache[s]
+	r.mu.Unlock()
+	if hit && (r.cfg.CacheTTL == 0 || r.now().Sub(c.fetchedAt) < r.cfg.CacheTTL) {
+		return c.value, nil
+	}
+
+	value, err := p.Fetch(ctx, ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving %s", redact(s))
+	}
+
+	r.mu.Lock()
+	r.cache[s] = cached{value: value, fetchedAt: r.now()}
+	r.mu.Unlock()
+	return value, nil
+}
+
+// ResolveStruct replaces every reference in the exported string fields of the
+// struct v points to, descending into nested structs, pointers, slices and
+// string-valued maps. All failures are reported together.
+func (r *Resolver) ResolveStruct(ctx context.Context, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.Errorf("secrets: ResolveStruct requires a non-nil pointer, got %T", v)
+	}
+
+	var failures []string
+	r.walk(ctx, "", rv, &failures)
+	if len(failures) > 0 {
+		return errors.Errorf("resolving secrets: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (r *Resolver) walk(ctx context.Context, path string, v reflect.Value, failures *[]string) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			r.walk(ctx, path, v.Elem(), failures)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			if f := t.Field(i); f.IsExported() {
+				r.walk(ctx, joinPath(path, f.Name), v.Field(i), failures)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			r.walk(ctx, path+"["+strconv.Itoa(i)+"]", v.Index(i), failures)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			resolved, err := r.Resolve(ctx, iter.Value().String())
+			if err != nil {
+				*failures = append(*failures, path+"["+iter.Key().String()+"]: "+err.Error())
+				continue
+			}
+			v.SetMapIndex(iter.Key(), reflect.ValueOf(resolved).Convert(v.Type().Elem()))
+		}
+	case reflect.String:
+		if !v.CanSet() || !r.IsReference(v.String()) {
+			return
+		}
+		resolved, err := r.Resolve(ctx, v.String())
+		if err != nil {
+			*failures = append(*failures, path+": "+err.Error())
+			return
+		}
+		v.SetString(resolved)
+	}
+}
+
+// OnRotate registers fn to be called with the reference whose value changed
+// during Refresh. Services use it to rebuild clients holding the old value.
+func (r *Resolver) OnRotate(fn func(ref string)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// Refresh fetches every cached reference again and notifies OnRotate
+// callbacks of those whose value changed. A failed fetch keeps the cached
+// value.
+func (r *Resolver) Refresh(ctx context.Context) {
+	r.mu.Lock()
+	refs := make([]string, 0, len(r.cache))
+	for ref := range r.cache {
+		refs = append(refs, ref)
+	}
+	r.mu.Unlock()
+	slices.Sort(refs)
+
+	for _, s := range refs {
+		p, ref, _ := r.split(s)
+		value, err := p.Fetch(ctx, ref)
+		if err != nil {
+			r.log.Warn("refreshing secret failed, keeping cached value", "ref", redact(s), "error", err)
+			continue
+		}
+
+		r.mu.Lock()
+		old := r.cache[s]
+		r.cache[s] = cached{value: value, fetchedAt: r.now()}
+		callbacks := slices.Clone(r.callbacks)
+		r.mu.Unlock()
+
+		if old.value != value {
+			r.log.Info("secret rotated", "ref", redact(s))
+			for _, fn := range callbacks {
+				fn(s)
+			}
+		}
+	}
+}
+
+// Watch calls Refresh every interval until ctx is cancelled.
+func (r *Resolver) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+func (r *Resolver) split(s string) (Provider, string, bool) {
+	scheme, ref, ok := strings.Cut(s, "://")
+	if !ok {
+		return nil, "", false
+	}
+	p, ok := r.providers[scheme]
+	return p, ref, ok
+}
+
+// redact keeps the scheme and the last path element, enough to identify a
+// reference in logs without echoing full paths.
+func redact(s string) string {
+	scheme, ref, _ := strings.Cut(s, "://")
+	if i := strings.LastIndex(ref, "/"); i >= 0 {
+		ref = "…" + ref[i:]
+	}
+	return scheme + "://" + ref
+}
+
+func joinPath(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func decodeBase64(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,187 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/api/option"
+)
+
+type dbConfig struct {
+	User     string
+	Password string
+}
+
+type serviceConfig struct {
+	Name     string
+	APIKey   string
+	DB       *dbConfig
+	Replicas []dbConfig
+	Headers  map[string]string
+	unset    string
+}
+
+type SecretsTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+	ctx context.Context
+}
+
+func (s *SecretsTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.ctx = context.Background()
+}
+
+func (s *SecretsTestSuite) TestResolveStruct() {
+	s.T().Setenv("TEST_API_KEY", "key-123")
+	path := filepath.Join(s.T().TempDir(), "db-password")
+	s.Require().NoError(os.WriteFile(path, []byte("hunter2\n"), 0o600))
+
+	r, err := New(&Config{}, s.log)
+	s.Require().NoError(err)
+
+	cfg := &serviceConfig{
+		Name:     "aggregator",
+		APIKey:   "env://TEST_API_KEY",
+		DB:       &dbConfig{User: "gs", Password: "file://" + path},
+		Replicas: []dbConfig{{Password: "env://TEST_API_KEY"}},
+		Headers:  map[string]string{"X-Api-Key": "env://TEST_API_KEY"},
+		unset:    "env://TEST_API_KEY",
+	}
+	s.Require().NoError(r.ResolveStruct(s.ctx, cfg))
+	s.Equal("aggregator", cfg.Name)
+	s.Equal("key-123", cfg.APIKey)
+	s.Equal("hunter2", cfg.DB.Password)
+	s.Equal("key-123", cfg.Replicas[0].Password)
+	s.Equal("key-123", cfg.Headers["X-Api-Key"])
+	s.Equal("env://TEST_API_KEY", cfg.unset)
+
+	err = r.ResolveStruct(s.ctx, &serviceConfig{APIKey: "env://TEST_MISSING", DB: &dbConfig{Password: "file:///nope"}})
+	s.ErrorContains(err, "APIKey")
+	s.ErrorContains(err, "DB.Password")
+	s.Error(r.ResolveStruct(s.ctx, serviceConfig{}))
+}
+
+func (s *SecretsTestSuite) TestPlainValuesPassThrough() {
+	r, err := New(&Config{}, s.log)
+	s.Require().NoError(err)
+
+	for _, v := range []string{"", "plain", "https://example.com", "/etc/creds.json"} {
+		got, err := r.Resolve(s.ctx, v)
+		s.NoError(err)
+		s.Equal(v, got)
+	}
+}
+
+func (s *SecretsTestSuite) TestCachingAndRotation() {
+	value, calls := "v1", 0
+	vault := ProviderFunc(func(_ context.Context, ref string) (string, error) {
+		calls++
+		if ref == "broken" {
+			return "", errors.New("unavailable")
+		}
+		return value, nil
+	})
+
+	r, err := New(&Config{CacheTTL: time.Minute}, s.log, WithProvider("vault", vault))
+	s.Require().NoError(err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	got, err := r.Resolve(s.ctx, "vault://db")
+	s.Require().NoError(err)
+	s.Equal("v1", got)
+	_, _ = r.Resolve(s.ctx, "vault://db")
+	s.Equal(1, calls)
+
+	now = now.Add(2 * time.Minute)
+	_, _ = r.Resolve(s.ctx, "vault://db")
+	s.Equal(2, calls)
+
+	var rotated []string
+	r.OnRotate(func(ref string) { rotated = append(rotated, ref) })
+	r.Refresh(s.ctx)
+	s.Empty(rotated)
+
+	value = "v2"
+	r.Refresh(s.ctx)
+	s.Equal([]string{"vault://db"}, rotated)
+	got, _ = r.Resolve(s.ctx, "vault://db")
+	s.Equal("v2", got)
+
+	_, err = r.Resolve(s.ctx, "vault://broken")
+	s.Error(err)
+}
+
+func (s *SecretsTestSuite) TestSecretManager() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.Contains(req.URL.Path, "projects/gs/secrets/api-key/versions/") {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":404,"message":"not found"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"name":    strings.TrimPrefix(strings.TrimSuffix(req.URL.Path, ":access"), "/v1/"),
+			"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte("sm-secret"))},
+		})
+	}))
+	defer srv.Close()
+
+	sm, err := SecretManager(s.ctx, option.WithEndpoint(srv.URL), option.WithoutAuthentication())
+	s.Require().NoError(err)
+	r, err := New(&Config{}, s.log, WithProvider(SchemeSecretManager, sm))
+	s.Require().NoError(err)
+
+	got, err := r.Resolve(s.ctx, "gcp-sm://gs/api-key")
+	s.Require().NoError(err)
+	s.Equal("sm-secret", got)
+
+	got, err = r.Resolve(s.ctx, "gcp-sm://projects/gs/secrets/api-key/versions/2")
+	s.Require().NoError(err)
+	s.Equal("sm-secret", got)
+
+	_, err = r.Resolve(s.ctx, "gcp-sm://gs/other")
+	s.ErrorIs(err, ErrNotFound)
+	_, err = r.Resolve(s.ctx, "gcp-sm://just-a-name")
+	s.Error(err)
+}
+
+func (s *SecretsTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{CacheTTL: 5 * time.Minute}},
+		{name: "cache forever", cfg: &Config{}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "negative ttl", cfg: &Config{CacheTTL: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestSecretsSuite(t *testing.T) {
+	suite.Run(t, new(SecretsTestSuite))
+}
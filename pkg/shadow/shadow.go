@@ -0,0 +1,254 @@
+// Package shadow mirrors calls made to a primary implementation onto a
+// secondary one in the background, and logs where their results differ. It
+// is used to migrate between backends safely, such as from streaming inserts
+// to the Storage Write API or from validator v1 to v2: the primary still
+// serves every caller while the secondary receives the same traffic.
+//
+// Wrap clients at construction time; with shadowing disabled the wrappers
+// return the primary unchanged:
+//
+//	sh, err := shadow.New(cfg, log, shadow.WithMetrics(m))
+//	...
+//	client = shadow.BQClient(client, writeAPIClient, sh)
+//	defer client.Close()
+//
+// Secondary calls run after the primary call returns, with a context that
+// keeps ctx's values but not its cancellation, so they never slow down or
+// fail the caller. Arguments are shared with the secondary call and must not
+// be modified after the primary call returns.
+package shadow
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"path"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultMaxInFlight = 100
+)
+
+// Comparison results, used as the result metric label.
+const (
+	ResultMatch    = "match"
+	ResultMismatch = "mismatch"
+	ResultError    = "error"
+	ResultSkipped  = "skipped"
+)
+
+type Config struct {
+	Enabled bool `koanf:"enabled" json:"enabled" envconfig:"enabled"`
+	// SampleRate is the fraction of calls, from 0 to 1, that are mirrored.
+	// Zero mirrors every call.
+	SampleRate float64 `koanf:"sample_rate" json:"sample_rate" envconfig:"sample_rate"`
+	// Timeout bounds each secondary call. It defaults to DefaultTimeout.
+	Timeout time.Duration `koanf:"timeout" json:"timeout" envconfig:"timeout"`
+	// MaxInFlight bounds concurrent secondary calls; calls beyond it are
+	// skipped rather than queued. It defaults to DefaultMaxInFlight.
+	MaxInFlight int `koanf:"max_in_flight" json:"max_in_flight" envconfig:"max_in_flight"`
+	// Operations limits mirroring to operations matching these path.Match
+	// patterns, such as "bq.Stream*" or "validator.SendAverages". Empty
+	// means all.
+	Operations []string `koanf:"operations" json:"operations" envconfig:"operations"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("shadow configuration required")
+	}
+	if c.SampleRate < 0 || c.SampleRate > 1 {
+		return errors.New("shadow sample rate must be between 0 and 1")
+	}
+	if c.Timeout < 0 {
+		return errors.New("shadow timeout must not be negative")
+	}
+	if c.MaxInFlight < 0 {
+		return errors.New("shadow max in flight must not be negative")
+	}
+	for _, op := range c.Operations {
+		if _, err := path.Match(op, ""); err != nil {
+			return errors.Wrapf(err, "shadow operation pattern %q", op)
+		}
+	}
+	return nil
+}
+
+// Result is the outcome of one side of a call. Value is nil for calls that
+// only return an error. Failed calls match when their gserrors codes and
+// values are equal, so a wrapper can set Value to the details that matter,
+// such as the projects a validator rejected.
+type Result struct {
+	Value any
+	Err   error
+}
+
+type Option func(*Shadow)
+
+// WithMetrics counts mirrored calls by operation and result: match,
+// mismatch, error or skipped.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Shadow) {
+		calls, err := m.NewCounterVec("shadow", "calls_total", "Mirrored calls by operation and result.", "operation", "result")
+		if err != nil {
+			s.log.Warn("registering shadow metrics", "error", err)
+			return
+		}
+		s.calls = calls
+	}
+}
+
+// WithEqual replaces reflect.DeepEqual for comparing the values of
+// successful calls, for backends that legitimately differ in, say,
+// timestamp precision or row order.
+func WithEqual(equal func(op string, primary, secondary any) bool) Option {
+	return func(s *Shadow) {
+		s.equal = equal
+	}
+}
+
+// Shadow runs and compares secondary calls. A nil *Shadow mirrors nothing,
+// and the wrappers return the primary unchanged for it.
+type Shadow struct {
+	cfg   *Config
+	log   *slog.Logger
+	equal func(op string, primary, secondary any) bool
+	calls *prometheus.CounterVec
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New returns a Shadow for cfg, or nil if shadowing is disabled.
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Shadow, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight == 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	s := &Shadow{
+		cfg:   cfg,
+		log:   log,
+		equal: func(_ string, primary, secondary any) bool { return reflect.DeepEqual(primary, secondary) },
+		slots: make(chan struct{}, maxInFlight),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	log.Info("shadow traffic enabled", "sample_rate", cfg.SampleRate, "operations", cfg.Operations)
+	return s, nil
+}
+
+// Mirror runs secondary in the background and compares its result with
+// primary, the result the caller already received. It never blocks: when
+// MaxInFlight secondary calls are running the call is skipped.
+func (s *Shadow) Mirror(ctx context.Context, op string, primary Result, secondary func(ctx context.Context) Result) {
+	if s == nil || !s.matches(op) {
+		return
+	}
+	if s.cfg.SampleRate > 0 && rand.Float64() >= s.cfg.SampleRate {
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.count(op, ResultSkipped)
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+
+		timeout := s.cfg.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		s.compare(op, primary, secondary(ctx))
+	}()
+}
+
+// Wait blocks until every running secondary call has finished or ctx is
+// done. Call it before closing the secondary.
+func (s *Shadow) Wait(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+func (s *Shadow) compare(op string, primary, secondary Result) {
+	switch {
+	case primary.Err == nil && secondary.Err != nil:
+		s.log.Warn("shadow call failed", "operation", op, "error", secondary.Err)
+		s.count(op, ResultError)
+	case primary.Err != nil || secondary.Err != nil:
+		if secondary.Err == nil || gserrors.CodeOf(primary.Err) != gserrors.CodeOf(secondary.Err) ||
+			!s.equal(op, primary.Value, secondary.Value) {
+			s.log.Warn("shadow result mismatch", "operation", op,
+				"primary_error", errString(primary.Err), "secondary_error", errString(secondary.Err))
+			s.count(op, ResultMismatch)
+			return
+		}
+		s.count(op, ResultMatch)
+	case !s.equal(op, primary.Value, secondary.Value):
+		s.log.Warn("shadow result mismatch", "operation", op,
+			"primary", primary.Value, "secondary", secondary.Value)
+		s.count(op, ResultMismatch)
+	default:
+		s.count(op, ResultMatch)
+	}
+}
+
+func (s *Shadow) matches(op string) bool {
+	if len(s.cfg.Operations) == 0 {
+		return true
+	}
+	for _, pattern := range s.cfg.Operations {
+		if ok, _ := path.Match(pattern, op); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Shadow) count(op, result string) {
+	if s.calls != nil {
+		s.calls.WithLabelValues(op, result).Inc()
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,261 @@
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type row struct {
+	ID    string
+	Value float64
+}
+
+// fakeBQ stores rows by ID and fails every call with err when it is set.
+type fakeBQ struct {
+	bqclient.BQClient
+	err error
+
+	mu     sync.Mutex
+	rows   map[string]row
+	closed bool
+}
+
+func newFakeBQ() *fakeBQ {
+	return &fakeBQ{rows: map[string]row{}}
+}
+
+func (f *fakeBQ) Put(_ context.Context, _ string, data any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := data.(row)
+	f.rows[r.ID] = r
+	return nil
+}
+
+func (f *fakeBQ) Get(_ context.Context, _ string, id string, dst any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*dst.(*row) = f.rows[id]
+	return nil
+}
+
+func (f *fakeBQ) Close() error {
+	f.closed = true
+	return nil
+}
+
+func (f *fakeBQ) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.rows)
+}
+
+type fakeValidator struct {
+	validator.ValidatorClient
+	rejected []string
+}
+
+func (f *fakeValidator) SendAverages(context.Context, []*pb.AverageOutput) error {
+	if len(f.rejected) == 0 {
+		return nil
+	}
+	ve := &validator.ValidationErrors{NotValid: true}
+	for _, id := range f.rejected {
+		ve.Errors = append(ve.Errors, &pb.ValidationError{ProjectId: id, Message: "below threshold"})
+	}
+	return ve
+}
+
+func (f *fakeValidator) Close() error { return nil }
+
+type ShadowTestSuite struct {
+	suite.Suite
+	logs *bytes.Buffer
+	m    *metrics.Metrics
+}
+
+func (s *ShadowTestSuite) SetupTest() {
+	s.logs = &bytes.Buffer{}
+	var err error
+	s.m, err = metrics.New(&metrics.Config{Namespace: "test", Service: "shadow"})
+	s.Require().NoError(err)
+}
+
+func (s *ShadowTestSuite) shadow(cfg *Config, opts ...Option) *Shadow {
+	cfg.Enabled = true
+	opts = append([]Option{WithMetrics(s.m)}, opts...)
+	sh, err := New(cfg, slog.New(slog.NewJSONHandler(s.logs, nil)), opts...)
+	s.Require().NoError(err)
+	return sh
+}
+
+func (s *ShadowTestSuite) calls(sh *Shadow, op, result string) float64 {
+	s.Require().NoError(sh.Wait(context.Background()))
+	return testutil.ToFloat64(sh.calls.WithLabelValues(op, result))
+}
+
+func (s *ShadowTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "disabled", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Enabled: true, SampleRate: 0.1, Timeout: time.Second, Operations: []string{"bq.*"}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "rate above one", cfg: &Config{SampleRate: 1.5}, expectError: true},
+		{name: "negative timeout", cfg: &Config{Timeout: -time.Second}, expectError: true},
+		{name: "negative max in flight", cfg: &Config{MaxInFlight: -1}, expectError: true},
+		{name: "bad pattern", cfg: &Config{Operations: []string{"bq.["}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *ShadowTestSuite) TestDisabled() {
+	sh, err := New(&Config{}, slog.Default())
+	s.Require().NoError(err)
+	s.Nil(sh)
+
+	primary := newFakeBQ()
+	s.Same(bqclient.BQClient(primary), BQClient(primary, newFakeBQ(), sh))
+	s.NoError(sh.Wait(context.Background()))
+}
+
+func (s *ShadowTestSuite) TestBQClient() {
+	sh := s.shadow(&Config{})
+	primary, secondary := newFakeBQ(), newFakeBQ()
+	client := BQClient(primary, secondary, sh)
+	ctx := context.Background()
+
+	s.Require().NoError(client.Put(ctx, "t", row{ID: "a", Value: 1}))
+	s.Equal(float64(1), s.calls(sh, "bq.Put", ResultMatch))
+	s.Equal(1, secondary.count(), "dual write")
+
+	var got row
+	s.Require().NoError(client.Get(ctx, "t", "a", &got))
+	s.Equal(row{ID: "a", Value: 1}, got)
+	got.Value = 99 // the caller reusing dst must not affect the comparison
+	s.Equal(float64(1), s.calls(sh, "bq.Get", ResultMatch))
+
+	secondary.rows["a"] = row{ID: "a", Value: 2}
+	s.Require().NoError(client.Get(ctx, "t", "a", &got))
+	s.Equal(float64(1), s.calls(sh, "bq.Get", ResultMismatch))
+	s.Contains(s.logs.String(), "shadow result mismatch")
+
+	secondary.err = errors.New("write api unavailable")
+	s.NoError(client.Put(ctx, "t", row{ID: "b"}), "secondary failures never reach the caller")
+	s.Equal(float64(1), s.calls(sh, "bq.Put", ResultError))
+
+	primary.err = gserrors.New(gserrors.Unavailable, "down")
+	secondary.err = gserrors.New(gserrors.Unavailable, "also down")
+	s.Error(client.Put(ctx, "t", row{ID: "c"}))
+	s.Equal(float64(2), s.calls(sh, "bq.Put", ResultMatch), "same error code")
+
+	s.Require().NoError(client.Close())
+	s.True(primary.closed)
+	s.True(secondary.closed)
+}
+
+func (s *ShadowTestSuite) TestOperations() {
+	sh := s.shadow(&Config{Operations: []string{"bq.Get"}})
+	secondary := newFakeBQ()
+	client := BQClient(newFakeBQ(), secondary, sh)
+	s.Require().NoError(client.Put(context.Background(), "t", row{ID: "a"}))
+	s.Require().NoError(sh.Wait(context.Background()))
+	s.Zero(secondary.count())
+}
+
+func (s *ShadowTestSuite) TestWithEqual() {
+	sh := s.shadow(&Config{}, WithEqual(func(_ string, primary, secondary any) bool {
+		return primary.(row).ID == secondary.(row).ID
+	}))
+	primary, secondary := newFakeBQ(), newFakeBQ()
+	primary.rows["a"] = row{ID: "a", Value: 1}
+	secondary.rows["a"] = row{ID: "a", Value: 1.0000001}
+
+	var got row
+	s.Require().NoError(BQClient(primary, secondary, sh).Get(context.Background(), "t", "a", &got))
+	s.Equal(float64(1), s.calls(sh, "bq.Get", ResultMatch))
+}
+
+func (s *ShadowTestSuite) TestMaxInFlight() {
+	sh := s.shadow(&Config{MaxInFlight: 1})
+	release := make(chan struct{})
+	ctx := context.Background()
+
+	sh.Mirror(ctx, "slow", Result{}, func(context.Context) Result {
+		<-release
+		return Result{}
+	})
+	sh.Mirror(ctx, "slow", Result{}, func(context.Context) Result { return Result{} })
+	close(release)
+
+	s.Equal(float64(1), s.calls(sh, "slow", ResultSkipped))
+	s.Equal(float64(1), s.calls(sh, "slow", ResultMatch))
+}
+
+func (s *ShadowTestSuite) TestTimeout() {
+	sh := s.shadow(&Config{Timeout: 10 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	sh.Mirror(ctx, "op", Result{}, func(ctx context.Context) Result {
+		<-ctx.Done()
+		return Result{Err: ctx.Err()}
+	})
+	cancel() // the caller's cancellation does not reach the secondary
+	s.Equal(float64(1), s.calls(sh, "op", ResultError))
+	s.Contains(s.logs.String(), "deadline exceeded")
+}
+
+func (s *ShadowTestSuite) TestValidatorClient() {
+	sh := s.shadow(&Config{})
+	v1 := &fakeValidator{rejected: []string{"p1", "p2"}}
+	v2 := &fakeValidator{rejected: []string{"p2", "p1"}}
+	client := ValidatorClient(v1, v2, sh)
+	ctx := context.Background()
+
+	var ve *validator.ValidationErrors
+	s.ErrorAs(client.SendAverages(ctx, nil), &ve)
+	s.Equal(float64(1), s.calls(sh, "validator.SendAverages", ResultMatch))
+
+	v2.rejected = []string{"p1"}
+	s.Error(client.SendAverages(ctx, nil))
+	s.Equal(float64(1), s.calls(sh, "validator.SendAverages", ResultMismatch))
+
+	v1.rejected, v2.rejected = nil, nil
+	s.NoError(client.SendAverages(ctx, nil))
+	s.Equal(float64(2), s.calls(sh, "validator.SendAverages", ResultMatch))
+	s.NoError(client.Close())
+}
+
+func TestShadowTestSuite(t *testing.T) {
+	suite.Run(t, new(ShadowTestSuite))
+}
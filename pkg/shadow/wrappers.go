@@ -0,0 +1,244 @@
+package shadow
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
+	"github.com/pkg/errors"
+)
+
+// closeTimeout bounds how long Close waits for running secondary calls.
+const closeTimeout = 10 * time.Second
+
+// BQClient mirrors writes and row reads on primary to secondary. Operations
+// are named bq.<Method>. Get, GetMulti, List and QueryRow compare the rows
+// read, and Count and Exists their results; Query and the StreamRead methods
+// return iterators and channels that cannot be read twice, QueryPage tokens
+// name primary's jobs and ExtractToGCS writes files rather than rows, so
+// they, like methods added to BQClient later, go to primary only.
+// Close waits for running secondary calls, then closes both clients.
+func BQClient(primary, secondary bqclient.BQClient, s *Shadow) bqclient.BQClient {
+	if s == nil {
+		return primary
+	}
+	return &bqClient{BQClient: primary, secondary: secondary, s: s}
+}
+
+type bqClient struct {
+	bqclient.BQClient
+	secondary bqclient.BQClient
+	s         *Shadow
+}
+
+func (c *bqClient) write(ctx context.Context, op string, err error, fn func(ctx context.Context) error) error {
+	c.s.Mirror(ctx, op, Result{Err: err}, func(ctx context.Context) Result {
+		return Result{Err: fn(ctx)}
+	})
+	return err
+}
+
+func (c *bqClient) Put(ctx context.Context, table string, data any) error {
+	return c.write(ctx, "bq.Put", c.BQClient.Put(ctx, table, data), func(ctx context.Context) error {
+		return c.secondary.Put(ctx, table, data)
+	})
+}
+
+func (c *bqClient) StreamPut(ctx context.Context, table string, data any) error {
+	return c.write(ctx, "bq.StreamPut", c.BQClient.StreamPut(ctx, table, data), func(ctx context.Context) error {
+		return c.secondary.StreamPut(ctx, table, data)
+	})
+}
+
+func (c *bqClient) StreamPutAll(ctx context.Context, inputs map[string][]any) error {
+	return c.write(ctx, "bq.StreamPutAll", c.BQClient.StreamPutAll(ctx, inputs), func(ctx context.Context) error {
+		return c.secondary.StreamPutAll(ctx, inputs)
+	})
+}
+
+func (c *bqClient) Update(ctx context.Context, table string, id string, updates map[string]any) error {
+	return c.write(ctx, "bq.Update", c.BQClient.Update(ctx, table, id, updates), func(ctx context.Context) error {
+		return c.secondary.Update(ctx, table, id, updates)
+	})
+}
+
+func (c *bqClient) ExecScript(ctx context.Context, script string, params []bigquery.QueryParameter) error {
+	return c.write(ctx, "bq.ExecScript", c.BQClient.ExecScript(ctx, script, params), func(ctx context.Context) error {
+		return c.secondary.ExecScript(ctx, script, params)
+	})
+}
+
+func (c *bqClient) LoadFromGCS(ctx context.Context, table string, gcsURI string, format bigquery.DataFormat, opts ...bqclient.LoadOption) error {
+	return c.write(ctx, "bq.LoadFromGCS", c.BQClient.LoadFromGCS(ctx, table, gcsURI, format, opts...), func(ctx context.Context) error {
+		return c.secondary.LoadFromGCS(ctx, table, gcsURI, format, opts...)
+	})
+}
+
+func (c *bqClient) CopyTable(ctx context.Context, srcTable string, dstTable string, writeDisposition bigquery.TableWriteDisposition) error {
+	return c.write(ctx, "bq.CopyTable", c.BQClient.CopyTable(ctx, srcTable, dstTable, writeDisposition), func(ctx context.Context) error {
+		return c.secondary.CopyTable(ctx, srcTable, dstTable, writeDisposition)
+	})
+}
+
+func (c *bqClient) UpdateMulti(ctx context.Context, table string, ids []string, updates map[string]any) error {
+	return c.write(ctx, "bq.UpdateMulti", c.BQClient.UpdateMulti(ctx, table, ids, updates), func(ctx context.Context) error {
+		return c.secondary.UpdateMulti(ctx, table, ids, updates)
+	})
+}
+
+func (c *bqClient) Upsert(ctx context.Context, table string, id string, data any) error {
+	return c.write(ctx, "bq.Upsert", c.BQClient.Upsert(ctx, table, id, data), func(ctx context.Context) error {
+		return c.secondary.Upsert(ctx, table, id, data)
+	})
+}
+
+func (c *bqClient) Delete(ctx context.Context, table string, id string) error {
+	return c.write(ctx, "bq.Delete", c.BQClient.Delete(ctx, table, id), func(ctx context.Context) error {
+		return c.secondary.Delete(ctx, table, id)
+	})
+}
+
+func (c *bqClient) DeleteMulti(ctx context.Context, table string, ids []string) error {
+	return c.write(ctx, "bq.DeleteMulti", c.BQClient.DeleteMulti(ctx, table, ids), func(ctx context.Context) error {
+		return c.secondary.DeleteMulti(ctx, table, ids)
+	})
+}
+
+func (c *bqClient) Get(ctx context.Context, table string, id string, dst any) error {
+	err := c.BQClient.Get(ctx, table, id, dst)
+	c.read(ctx, "bq.Get", dst, err, func(ctx context.Context, dst any) error {
+		return c.secondary.Get(ctx, table, id, dst)
+	})
+	return err
+}
+
+func (c *bqClient) GetMulti(ctx context.Context, table string, ids []string, dst any) error {
+	err := c.BQClient.GetMulti(ctx, table, ids, dst)
+	c.read(ctx, "bq.GetMulti", dst, err, func(ctx context.Context, dst any) error {
+		return c.secondary.GetMulti(ctx, table, ids, dst)
+	})
+	return err
+}
+
+func (c *bqClient) List(ctx context.Context, table string, filter map[string]any, opts bqclient.ListOptions, dst any) error {
+	err := c.BQClient.List(ctx, table, filter, opts, dst)
+	c.read(ctx, "bq.List", dst, err, func(ctx context.Context, dst any) error {
+		return c.secondary.List(ctx, table, filter, opts, dst)
+	})
+	return err
+}
+
+func (c *bqClient) Count(ctx context.Context, table string, filter map[string]any) (int64, error) {
+	n, err := c.BQClient.Count(ctx, table, filter)
+	c.s.Mirror(ctx, "bq.Count", Result{Value: n, Err: err}, func(ctx context.Context) Result {
+		n, err := c.secondary.Count(ctx, table, filter)
+		return Result{Value: n, Err: err}
+	})
+	return n, err
+}
+
+func (c *bqClient) Exists(ctx context.Context, table string, id string) (bool, error) {
+	found, err := c.BQClient.Exists(ctx, table, id)
+	c.s.Mirror(ctx, "bq.Exists", Result{Value: found, Err: err}, func(ctx context.Context) Result {
+		found, err := c.secondary.Exists(ctx, table, id)
+		return Result{Value: found, Err: err}
+	})
+	return found, err
+}
+
+func (c *bqClient) QueryRow(ctx context.Context, query string, params []bigquery.QueryParameter, dst any) error {
+	err := c.BQClient.QueryRow(ctx, query, params, dst)
+	c.read(ctx, "bq.QueryRow", dst, err, func(ctx context.Context, dst any) error {
+		return c.secondary.QueryRow(ctx, query, params, dst)
+	})
+	return err
+}
+
+// read mirrors a read into dst. The primary row is copied before returning
+// so the caller can reuse dst, and the secondary reads into a new value of
+// the same type.
+func (c *bqClient) read(ctx context.Context, op string, dst any, err error, fn func(ctx context.Context, dst any) error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return
+	}
+	primary := Result{Err: err}
+	if err == nil {
+		primary.Value = v.Elem().Interface()
+	}
+	c.s.Mirror(ctx, op, primary, func(ctx context.Context) Result {
+		out := reflect.New(v.Type().Elem())
+		if err := fn(ctx, out.Interface()); err != nil {
+			return Result{Err: err}
+		}
+		return Result{Value: out.Elem().Interface()}
+	})
+}
+
+func (c *bqClient) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	err := c.BQClient.Close()
+	if werr := c.s.Wait(ctx); werr != nil {
+		c.s.log.Warn("shadow calls still running at close", "error", werr)
+	}
+	if serr := c.secondary.Close(); serr != nil && err == nil {
+		err = errors.Wrap(serr, "closing shadow client")
+	}
+	return err
+}
+
+// ValidatorClient mirrors validator.SendAverages on primary to secondary.
+// Failed calls match only when both reject the same projects with the same
+// messages.
+func ValidatorClient(primary, secondary validator.ValidatorClient, s *Shadow) validator.ValidatorClient {
+	if s == nil {
+		return primary
+	}
+	return &validatorClient{ValidatorClient: primary, secondary: secondary, s: s}
+}
+
+type validatorClient struct {
+	validator.ValidatorClient
+	secondary validator.ValidatorClient
+	s         *Shadow
+}
+
+func (c *validatorClient) SendAverages(ctx context.Context, averages []*pb.AverageOutput) error {
+	err := c.ValidatorClient.SendAverages(ctx, averages)
+	c.s.Mirror(ctx, "validator.SendAverages", validationResult(err), func(ctx context.Context) Result {
+		return validationResult(c.secondary.SendAverages(ctx, averages))
+	})
+	return err
+}
+
+func validationResult(err error) Result {
+	var ve *validator.ValidationErrors
+	if !errors.As(err, &ve) {
+		return Result{Err: err}
+	}
+	rejected := make([]string, 0, len(ve.Errors))
+	for _, e := range ve.Errors {
+		rejected = append(rejected, e.GetProjectId()+": "+e.GetMessage())
+	}
+	slices.Sort(rejected)
+	return Result{Value: rejected, Err: err}
+}
+
+func (c *validatorClient) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	err := c.ValidatorClient.Close()
+	if werr := c.s.Wait(ctx); werr != nil {
+		c.s.log.Warn("shadow calls still running at close", "error", werr)
+	}
+	if serr := c.secondary.Close(); serr != nil && err == nil {
+		err = errors.Wrap(serr, "closing shadow client")
+	}
+	return err
+}
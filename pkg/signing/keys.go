@@ -0,0 +1,159 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm identifies how an envelope is signed.
+type Algorithm string
+
+const (
+	// HS256 is HMAC-SHA256 with a shared secret.
+	HS256 Algorithm = "HS256"
+	// EdDSA is Ed25519. Gateways only need the public key to verify.
+	EdDSA Algorithm = "EdDSA"
+)
+
+// Key is a named signing or verification key. HMAC keys use Secret. Ed25519
+// keys use PrivateKey to sign and PublicKey to verify; a key with only a
+// PublicKey can verify but not sign.
+type Key struct {
+	ID         string
+	Algorithm  Algorithm
+	Secret     []byte
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+// KeyConfig describes a Key with base64 encoded material, so keys can be
+// loaded from config files or resolved through the secrets package.
+type KeyConfig struct {
+	ID        string    `koanf:"id" json:"id" envconfig:"id"`
+	Algorithm Algorithm `koanf:"algorithm" json:"algorithm" envconfig:"algorithm"`
+
+	// Secret is the HMAC secret, or the 32 byte Ed25519 seed.
+	Secret string `koanf:"secret" json:"secret" envconfig:"secret"`
+
+	// PublicKey is the Ed25519 public key for verify-only keys.
+	PublicKey string `koanf:"public_key" json:"public_key" envconfig:"public_key"`
+}
+
+// Key decodes the configured key material.
+func (c *KeyConfig) Key() (*Key, error) {
+	if c.ID == "" {
+		return nil, errors.New("signing key ID required")
+	}
+	k := &Key{ID: c.ID, Algorithm: c.Algorithm}
+
+	var secret []byte
+	if c.Secret != "" {
+		b, err := base64.StdEncoding.DecodeString(c.Secret)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decoding secret for signing key %s", c.ID)
+		}
+		secret = b
+	}
+
+	switch c.Algorithm {
+	case HS256:
+		k.Secret = secret
+	case EdDSA:
+		if secret != nil {
+			if len(secret) != ed25519.SeedSize {
+				return nil, errors.Errorf("signing key %s: ed25519 seed must be %d bytes", c.ID, ed25519.SeedSize)
+			}
+			k.PrivateKey = ed25519.NewKeyFromSeed(secret)
+			k.PublicKey = k.PrivateKey.Public().(ed25519.PublicKey)
+		}
+		if c.PublicKey != "" {
+			b, err := base64.StdEncoding.DecodeString(c.PublicKey)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decoding public key for signing key %s", c.ID)
+			}
+			k.PublicKey = b
+		}
+	}
+	return k, k.Validate()
+}
+
+func (k *Key) Validate() error {
+	if k.ID == "" {
+		return errors.New("signing key ID required")
+	}
+	switch k.Algorithm {
+	case HS256:
+		if len(k.Secret) < sha256.Size {
+			return errors.Errorf("signing key %s: hmac secret must be at least %d bytes", k.ID, sha256.Size)
+		}
+	case EdDSA:
+		if len(k.PublicKey) != ed25519.PublicKeySize {
+			return errors.Errorf("signing key %s: ed25519 public key required", k.ID)
+		}
+		if k.PrivateKey != nil && len(k.PrivateKey) != ed25519.PrivateKeySize {
+			return errors.Errorf("signing key %s: invalid ed25519 private key", k.ID)
+		}
+	default:
+		return errors.Errorf("signing key %s: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+	return nil
+}
+
+// CanSign reports whether k holds the material needed to sign.
+func (k *Key) CanSign() bool {
+	return k.Algorithm == HS256 || k.PrivateKey != nil
+}
+
+// Public returns a verify-only copy of an Ed25519 key, suitable for
+// distributing to gateways. HMAC keys have no public form.
+func (k *Key) Public() (*Key, error) {
+	if k.Algorithm != EdDSA {
+		return nil, errors.Errorf("signing key %s: %s keys have no public form", k.ID, k.Algorithm)
+	}
+	return &Key{ID: k.ID, Algorithm: EdDSA, PublicKey: k.PublicKey}, nil
+}
+
+func (k *Key) sign(msg []byte) ([]byte, error) {
+	switch {
+	case k.Algorithm == HS256:
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(msg)
+		return mac.Sum(nil), nil
+	case k.PrivateKey != nil:
+		return ed25519.Sign(k.PrivateKey, msg), nil
+	default:
+		return nil, errors.Errorf("signing key %s cannot sign", k.ID)
+	}
+}
+
+func (k *Key) verify(msg, sig []byte) bool {
+	if k.Algorithm == HS256 {
+		mac := hmac.New(sha256.New, k.Secret)
+		mac.Write(msg)
+		return hmac.Equal(mac.Sum(nil), sig)
+	}
+	return ed25519.Verify(k.PublicKey, msg, sig)
+}
+
+// GenerateHMAC returns a new HMAC key with a random 32 byte secret.
+func GenerateHMAC(id string) (*Key, error) {
+	secret := make([]byte, sha256.Size)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Key{ID: id, Algorithm: HS256, Secret: secret}, nil
+}
+
+// GenerateEd25519 returns a new Ed25519 key pair.
+func GenerateEd25519(id string) (*Key, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &Key{ID: id, Algorithm: EdDSA, PrivateKey: priv, PublicKey: pub}, nil
+}
@@ -0,0 +1,270 @@
+// Package signing authenticates event and telemetry payloads end to end,
+// independent of transport security. A Keyring signs with its active key and
+// verifies with any key it holds, so keys can be rotated by adding the new
+// key everywhere, activating it on senders, then removing the old one.
+//
+//	env, err := ring.SignJSON(cmd)
+//	...
+//	err := ring.VerifyJSON(env, &cmd)
+//
+// Verification bounds replay to MaxAge but does not prevent it: an envelope
+// verifies any number of times until it is too old. Receivers that act on
+// commands must drop repeats themselves, for example with pkg/dedup keyed
+// on the signature and a TTL of at least MaxAge plus ClockSkew.
+package signing
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultMaxAge    = 5 * time.Minute
+	DefaultClockSkew = 30 * time.Second
+)
+
+var (
+	ErrUnknownKey       = gserrors.New(gserrors.Unauthenticated, "unknown signing key")
+	ErrInvalidSignature = gserrors.New(gserrors.Unauthenticated, "invalid signature")
+	ErrExpired          = gserrors.New(gserrors.Unauthenticated, "signature expired")
+)
+
+// Envelope is a signed payload.
+type Envelope struct {
+	KeyID     string    `json:"kid"`
+	Algorithm Algorithm `json:"alg"`
+	SignedAt  time.Time `json:"signed_at"`
+	Payload   []byte    `json:"payload"`
+	Signature []byte    `json:"sig"`
+}
+
+// signingInput binds the key, algorithm and time to the payload so none of
+// them can be swapped without invalidating the signature.
+func (e *Envelope) signingInput() []byte {
+	b := make([]byte, 0, len(e.Payload)+64)
+	b = append(b, "v1\n"...)
+	b = append(b, e.Algorithm...)
+	b = append(b, '\n')
+	b = append(b, e.KeyID...)
+	b = append(b, '\n')
+	b = strconv.AppendInt(b, e.SignedAt.UnixNano(), 10)
+	b = append(b, '\n')
+	return append(b, e.Payload...)
+}
+
+type Config struct {
+	// ActiveKeyID names the key used to sign. Verify-only keyrings leave it
+	// empty.
+	ActiveKeyID string      `koanf:"active_key_id" json:"active_key_id" envconfig:"active_key_id"`
+	Keys        []KeyConfig `koanf:"keys" json:"keys" envconfig:"keys"`
+
+	// MaxAge rejects envelopes signed longer ago than this, limiting replay.
+	// Defaults to DefaultMaxAge.
+	MaxAge time.Duration `koanf:"max_age" json:"max_age" envconfig:"max_age"`
+
+	// ClockSkew is how far in the future SignedAt may be. Defaults to
+	// DefaultClockSkew.
+	ClockSkew time.Duration `koanf:"clock_skew" json:"clock_skew" envconfig:"clock_skew"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("signing configuration required")
+	}
+	if len(c.Keys) == 0 {
+		return errors.New("signing keys required")
+	}
+	if c.MaxAge < 0 || c.ClockSkew < 0 {
+		return errors.New("signing durations must not be negative")
+	}
+
+	seen := map[string]bool{}
+	var active *Key
+	for i := range c.Keys {
+		k, err := c.Keys[i].Key()
+		if err != nil {
+			return err
+		}
+		if seen[k.ID] {
+			return errors.Errorf("duplicate signing key %s", k.ID)
+		}
+		seen[k.ID] = true
+		if k.ID == c.ActiveKeyID {
+			active = k
+		}
+	}
+	if c.ActiveKeyID != "" {
+		if active == nil {
+			return errors.Errorf("active signing key %s not found", c.ActiveKeyID)
+		}
+		if !active.CanSign() {
+			return errors.Errorf("active signing key %s cannot sign", c.ActiveKeyID)
+		}
+	}
+	return nil
+}
+
+type Option func(*Keyring)
+
+// WithClock overrides the time source used to stamp and check envelopes.
+func WithClock(now func() time.Time) Option {
+	return func(r *Keyring) {
+		r.now = now
+	}
+}
+
+type Keyring struct {
+	maxAge time.Duration
+	skew   time.Duration
+	now    func() time.Time
+
+	mu     sync.RWMutex
+	keys   map[string]*Key
+	active string
+}
+
+// New builds a Keyring from cfg.
+func New(cfg *Config, opts ...Option) (*Keyring, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := &Keyring{
+		maxAge: cfg.MaxAge,
+		skew:   cfg.ClockSkew,
+		now:    time.Now,
+		keys:   map[string]*Key{},
+		active: cfg.ActiveKeyID,
+	}
+	if r.maxAge == 0 {
+		r.maxAge = DefaultMaxAge
+	}
+	if r.skew == 0 {
+		r.skew = DefaultClockSkew
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	for i := range cfg.Keys {
+		k, err := cfg.Keys[i].Key()
+		if err != nil {
+			return nil, err
+		}
+		r.keys[k.ID] = k
+	}
+	return r, nil
+}
+
+// Add adds k, replacing any key with the same ID.
+func (r *Keyring) Add(k *Key) error {
+	if err := k.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[k.ID] = k
+	return nil
+}
+
+// Remove removes the key with the given ID. The active key cannot be
+// removed.
+func (r *Keyring) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == r.active {
+		return errors.Errorf("cannot remove active signing key %s", id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// Activate makes the key with the given ID the one used to sign.
+func (r *Keyring) Activate(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return errors.Wrapf(ErrUnknownKey, "activating %s", id)
+	}
+	if !k.CanSign() {
+		return errors.Errorf("signing key %s cannot sign", id)
+	}
+	r.active = id
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key used to sign, or "" if none.
+func (r *Keyring) ActiveKeyID() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// Sign signs payload with the active key.
+func (r *Keyring) Sign(payload []byte) (*Envelope, error) {
+	r.mu.RLock()
+	k := r.keys[r.active]
+	r.mu.RUnlock()
+	if k == nil {
+		return nil, errors.New("no active signing key")
+	}
+
+	env := &Envelope{
+		KeyID:     k.ID,
+		Algorithm: k.Algorithm,
+		SignedAt:  r.now().UTC(),
+		Payload:   payload,
+	}
+	sig, err := k.sign(env.signingInput())
+	if err != nil {
+		return nil, err
+	}
+	env.Signature = sig
+	return env, nil
+}
+
+// Verify checks env's signature and age and returns its payload.
+func (r *Keyring) Verify(env *Envelope) ([]byte, error) {
+	r.mu.RLock()
+	k := r.keys[env.KeyID]
+	r.mu.RUnlock()
+	if k == nil {
+		return nil, errors.Wrapf(ErrUnknownKey, "key %s", env.KeyID)
+	}
+	if env.Algorithm != k.Algorithm || !k.verify(env.signingInput(), env.Signature) {
+		return nil, errors.WithStack(ErrInvalidSignature)
+	}
+
+	now := r.now()
+	if env.SignedAt.After(now.Add(r.skew)) {
+		return nil, errors.Wrapf(ErrExpired, "signed in the future at %s", env.SignedAt)
+	}
+	if now.Sub(env.SignedAt) > r.maxAge {
+		return nil, errors.Wrapf(ErrExpired, "signed at %s", env.SignedAt)
+	}
+	return env.Payload, nil
+}
+
+// SignJSON encodes v as JSON and signs it.
+func (r *Keyring) SignJSON(v any) (*Envelope, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return r.Sign(payload)
+}
+
+// VerifyJSON verifies env and decodes its payload into v.
+func (r *Keyring) VerifyJSON(env *Envelope, v any) error {
+	payload, err := r.Verify(env)
+	if err != nil {
+		return err
+	}
+	return errors.WithStack(json.Unmarshal(payload, v))
+}
@@ -0,0 +1,194 @@
+package signing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/stretchr/testify/suite"
+)
+
+type SigningTestSuite struct {
+	suite.Suite
+	now  time.Time
+	hmac *Key
+	ed   *Key
+}
+
+func (s *SigningTestSuite) SetupTest() {
+	s.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var err error
+	s.hmac, err = GenerateHMAC("h1")
+	s.Require().NoError(err)
+	s.ed, err = GenerateEd25519("e1")
+	s.Require().NoError(err)
+}
+
+func (s *SigningTestSuite) keyring(cfg *Config) *Keyring {
+	r, err := New(cfg, WithClock(func() time.Time { return s.now }))
+	s.Require().NoError(err)
+	return r
+}
+
+func (s *SigningTestSuite) hmacConfig() KeyConfig {
+	return KeyConfig{ID: s.hmac.ID, Algorithm: HS256, Secret: base64.StdEncoding.EncodeToString(s.hmac.Secret)}
+}
+
+func (s *SigningTestSuite) TestConfigValidate() {
+	seed := base64.StdEncoding.EncodeToString(s.ed.PrivateKey.Seed())
+	pub := base64.StdEncoding.EncodeToString(s.ed.PublicKey)
+
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}}},
+		{name: "ed25519 seed", cfg: &Config{ActiveKeyID: "e1", Keys: []KeyConfig{{ID: "e1", Algorithm: EdDSA, Secret: seed}}}},
+		{name: "verify only", cfg: &Config{Keys: []KeyConfig{{ID: "e1", Algorithm: EdDSA, PublicKey: pub}}}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "no keys", cfg: &Config{}, expectError: true},
+		{name: "short secret", cfg: &Config{Keys: []KeyConfig{{ID: "h", Algorithm: HS256, Secret: "c2hvcnQ="}}}, expectError: true},
+		{name: "bad base64", cfg: &Config{Keys: []KeyConfig{{ID: "h", Algorithm: HS256, Secret: "!"}}}, expectError: true},
+		{name: "unknown algorithm", cfg: &Config{Keys: []KeyConfig{{ID: "h", Algorithm: "RS256", Secret: seed}}}, expectError: true},
+		{name: "duplicate", cfg: &Config{Keys: []KeyConfig{s.hmacConfig(), s.hmacConfig()}}, expectError: true},
+		{name: "missing active", cfg: &Config{ActiveKeyID: "x", Keys: []KeyConfig{s.hmacConfig()}}, expectError: true},
+		{name: "active verify only", cfg: &Config{ActiveKeyID: "e1", Keys: []KeyConfig{{ID: "e1", Algorithm: EdDSA, PublicKey: pub}}}, expectError: true},
+		{name: "negative max age", cfg: &Config{Keys: []KeyConfig{s.hmacConfig()}, MaxAge: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *SigningTestSuite) TestSignVerify() {
+	for _, key := range []*Key{s.hmac, s.ed} {
+		s.Run(string(key.Algorithm), func() {
+			r := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}})
+			s.Require().NoError(r.Add(key))
+			s.Require().NoError(r.Activate(key.ID))
+
+			env, err := r.SignJSON(map[string]float64{"setpoint_kw": 4.5})
+			s.Require().NoError(err)
+			s.Equal(key.ID, env.KeyID)
+
+			// Round trip through the wire format.
+			b, err := json.Marshal(env)
+			s.Require().NoError(err)
+			var got Envelope
+			s.Require().NoError(json.Unmarshal(b, &got))
+
+			var cmd map[string]float64
+			s.Require().NoError(r.VerifyJSON(&got, &cmd))
+			s.Equal(4.5, cmd["setpoint_kw"])
+
+			got.Payload = []byte(`{"setpoint_kw":100}`)
+			_, err = r.Verify(&got)
+			s.ErrorIs(err, ErrInvalidSignature)
+			s.Equal(gserrors.Unauthenticated, gserrors.CodeOf(err))
+		})
+	}
+}
+
+func (s *SigningTestSuite) TestVerifyOnly() {
+	signer := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}})
+	s.Require().NoError(signer.Add(s.ed))
+	s.Require().NoError(signer.Activate("e1"))
+	env, err := signer.Sign([]byte("curtail"))
+	s.Require().NoError(err)
+
+	pub, err := s.ed.Public()
+	s.Require().NoError(err)
+	gateway := s.keyring(&Config{Keys: []KeyConfig{{ID: "e1", Algorithm: EdDSA, PublicKey: base64.StdEncoding.EncodeToString(pub.PublicKey)}}})
+
+	payload, err := gateway.Verify(env)
+	s.Require().NoError(err)
+	s.Equal("curtail", string(payload))
+
+	_, err = gateway.Sign([]byte("x"))
+	s.Error(err)
+	s.Error(gateway.Activate("e1"))
+}
+
+func (s *SigningTestSuite) TestTampering() {
+	r := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}})
+	env, err := r.Sign([]byte("curtail"))
+	s.Require().NoError(err)
+
+	moved := *env
+	moved.SignedAt = moved.SignedAt.Add(time.Minute)
+	_, err = r.Verify(&moved)
+	s.ErrorIs(err, ErrInvalidSignature)
+
+	swapped := *env
+	swapped.Algorithm = EdDSA
+	_, err = r.Verify(&swapped)
+	s.ErrorIs(err, ErrInvalidSignature)
+
+	unknown := *env
+	unknown.KeyID = "old"
+	_, err = r.Verify(&unknown)
+	s.ErrorIs(err, ErrUnknownKey)
+}
+
+func (s *SigningTestSuite) TestAge() {
+	r := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}, MaxAge: time.Minute})
+	env, err := r.Sign([]byte("curtail"))
+	s.Require().NoError(err)
+
+	s.now = s.now.Add(time.Minute)
+	_, err = r.Verify(env)
+	s.NoError(err)
+
+	s.now = s.now.Add(time.Second)
+	_, err = r.Verify(env)
+	s.ErrorIs(err, ErrExpired)
+
+	s.now = s.now.Add(-2 * time.Minute)
+	_, err = r.Verify(env)
+	s.ErrorIs(err, ErrExpired, "signed beyond the allowed clock skew")
+}
+
+func (s *SigningTestSuite) TestDefaultMaxAge() {
+	r := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}})
+	env, err := r.Sign([]byte("curtail"))
+	s.Require().NoError(err)
+
+	s.now = s.now.Add(DefaultMaxAge + time.Second)
+	_, err = r.Verify(env)
+	s.ErrorIs(err, ErrExpired)
+}
+
+func (s *SigningTestSuite) TestRotation() {
+	r := s.keyring(&Config{ActiveKeyID: "h1", Keys: []KeyConfig{s.hmacConfig()}})
+	old, err := r.Sign([]byte("a"))
+	s.Require().NoError(err)
+
+	next, err := GenerateHMAC("h2")
+	s.Require().NoError(err)
+	s.Require().NoError(r.Add(next))
+	s.Require().NoError(r.Activate("h2"))
+	s.Equal("h2", r.ActiveKeyID())
+	s.Error(r.Remove("h2"))
+
+	// Envelopes signed before rotation verify until the old key is removed.
+	_, err = r.Verify(old)
+	s.NoError(err)
+	s.Require().NoError(r.Remove("h1"))
+	_, err = r.Verify(old)
+	s.ErrorIs(err, ErrUnknownKey)
+}
+
+func TestSigningTestSuite(t *testing.T) {
+	suite.Run(t, new(SigningTestSuite))
+}
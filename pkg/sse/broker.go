@@ -0,0 +1,144 @@
+package sse
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+)
+
+const (
+	DefaultHistorySize = 256
+	DefaultBufferSize  = 64
+)
+
+type BrokerOption func(*Broker)
+
+// WithHistorySize sets how many recent events are kept for replay.
+func WithHistorySize(n int) BrokerOption {
+	return func(b *Broker) {
+		b.historySize = n
+	}
+}
+
+// WithBufferSize sets each client's queue length. A client whose queue is
+// full when an event is published is disconnected.
+func WithBufferSize(n int) BrokerOption {
+	return func(b *Broker) {
+		b.bufferSize = n
+	}
+}
+
+// Broker fans published events out to every subscribed client and keeps a
+// bounded history so reconnecting clients resume where they left off. Event
+// IDs are assigned by the broker as increasing integers.
+type Broker struct {
+	historySize int
+	bufferSize  int
+
+	mu      sync.Mutex
+	seq     uint64
+	history []Event
+	clients map[chan Event]struct{}
+}
+
+func NewBroker(opts ...BrokerOption) *Broker {
+	b := &Broker{
+		historySize: DefaultHistorySize,
+		bufferSize:  DefaultBufferSize,
+		clients:     map[chan Event]struct{}{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish assigns the next ID to an event and sends it to every client.
+func (b *Broker) Publish(event string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	ev := Event{ID: strconv.FormatUint(b.seq, 10), Event: event, Data: data}
+
+	if b.historySize > 0 {
+		if len(b.history) == b.historySize {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, ev)
+	}
+
+	for ch := range b.clients {
+		select {
+		case ch <- ev:
+		default:
+			// Disconnect rather than silently skip, so the client reconnects
+			// and replays what it missed.
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe implements Subscribe. Events after lastEventID that are still in
+// the history are replayed first.
+func (b *Broker) Subscribe(ctx context.Context, lastEventID string) (<-chan Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if last, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+		for _, ev := range b.history {
+			if id, _ := strconv.ParseUint(ev.ID, 10, 64); id > last {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	ch := make(chan Event, b.bufferSize+len(replay))
+	for _, ev := range replay {
+		ch <- ev
+	}
+	b.clients[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.clients[ch]; ok {
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+// Clients returns the number of subscribed clients.
+func (b *Broker) Clients() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.clients)
+}
+
+// Feed publishes events from bus until ctx is cancelled. fn names each event
+// and may return false to skip it.
+func (b *Broker) Feed(ctx context.Context, bus eventbus.EventBus, capacity int, fn func(any) (event string, data any, ok bool)) {
+	sub := bus.Subscribe(capacity)
+	defer bus.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case v, ok := <-sub:
+			if !ok {
+				return
+			}
+			if event, data, keep := fn(v); keep {
+				b.Publish(event, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Package sse streams events to browsers as server-sent events. A Handler
+// pulls events for each request from a Subscribe function, writes them in the
+// text/event-stream format and sends a comment as a heartbeat whenever the
+// stream is idle so proxies keep the connection open.
+//
+// Use a Broker to fan events out to every client with replay: it numbers
+// events, keeps the most recent ones, and resumes reconnecting clients from
+// their Last-Event-ID. A client that falls too far behind is disconnected and
+// catches up from the history on reconnect. FromEventBus adapts a plain
+// eventbus subscription when replay is not needed.
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultHeartbeatInterval = 15 * time.Second
+	DefaultWriteTimeout      = 10 * time.Second
+
+	LastEventIDHeader = "Last-Event-ID"
+)
+
+type Config struct {
+	HeartbeatInterval time.Duration `koanf:"heartbeat_interval" json:"heartbeat_interval" envconfig:"heartbeat_interval"`
+	WriteTimeout      time.Duration `koanf:"write_timeout" json:"write_timeout" envconfig:"write_timeout"`
+	// Retry tells clients how long to wait before reconnecting. Zero leaves
+	// the browser default.
+	Retry time.Duration `koanf:"retry" json:"retry" envconfig:"retry"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("sse configuration required")
+	}
+	if c.HeartbeatInterval < 0 || c.WriteTimeout < 0 || c.Retry < 0 {
+		return errors.New("sse durations must not be negative")
+	}
+	return nil
+}
+
+// Event is a single server-sent event. Data is written as is when it is a
+// string or []byte and JSON-encoded otherwise.
+type Event struct {
+	ID    string
+	Event string
+	Data  any
+}
+
+// Subscribe returns the events for one client, resuming after lastEventID
+// when it is set. The channel must be closed, or ctx cancelled, to end the
+// stream; ctx is cancelled when the client disconnects.
+type Subscribe func(ctx context.Context, lastEventID string) (<-chan Event, error)
+
+// Handler serves the events returned by subscribe.
+func Handler(cfg *Config, subscribe Subscribe, log *slog.Logger) (http.Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	heartbeat := orDefault(cfg.HeartbeatInterval, DefaultHeartbeatInterval)
+	writeTimeout := orDefault(cfg.WriteTimeout, DefaultWriteTimeout)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		lastID := r.Header.Get(LastEventIDHeader)
+		if lastID == "" {
+			lastID = r.URL.Query().Get("last_event_id")
+		}
+		events, err := subscribe(ctx, lastID)
+		if err != nil {
+			log.Error("sse subscribe failed", "error", err)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("Connection", "keep-alive")
+		// Stop nginx-style proxies from buffering the stream.
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if cfg.Retry > 0 {
+			fmt.Fprintf(w, "retry: %d\n\n", cfg.Retry.Milliseconds())
+		}
+		if err := rc.Flush(); err != nil {
+			log.Error("sse flush unsupported", "error", err)
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+
+		for {
+			var write func() error
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				write = func() error { return WriteEvent(w, ev) }
+			case <-ticker.C:
+				write = func() error {
+					_, err := io.WriteString(w, ": heartbeat\n\n")
+					return err
+				}
+			}
+
+			// A client that stops reading would otherwise block the handler,
+			// and with it the producer, indefinitely.
+			_ = rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := write(); err != nil {
+				log.Debug("sse write failed", "error", err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				log.Debug("sse flush failed", "error", err)
+				return
+			}
+		}
+	}), nil
+}
+
+// WriteEvent writes ev in the text/event-stream format.
+func WriteEvent(w io.Writer, ev Event) error {
+	var data string
+	switch d := ev.Data.(type) {
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return errors.Wrap(err, "encoding sse data")
+		}
+		data = string(b)
+	}
+
+	var sb strings.Builder
+	if ev.ID != "" {
+		sb.WriteString("id: " + singleLine(ev.ID) + "\n")
+	}
+	if ev.Event != "" {
+		sb.WriteString("event: " + singleLine(ev.Event) + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	sb.WriteString("\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// FromEventBus subscribes each client to bus with a buffer of capacity. The
+// eventbus drops events for clients whose buffer is full. fn maps bus events
+// to SSE events and may return false to skip one. Last-Event-ID is ignored.
+func FromEventBus(bus eventbus.EventBus, capacity int, fn func(any) (Event, bool)) Subscribe {
+	return func(ctx context.Context, _ string) (<-chan Event, error) {
+		sub := bus.Subscribe(capacity)
+		out := make(chan Event)
+		go func() {
+			defer close(out)
+			defer bus.Unsubscribe(sub)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-sub:
+					if !ok {
+						return
+					}
+					ev, keep := fn(v)
+					if !keep {
+						continue
+					}
+					select {
+					case out <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+		return out, nil
+	}
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,214 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type SSETestSuite struct {
+	suite.Suite
+}
+
+// readEvents reads n events, skipping comments and retry lines.
+func (s *SSETestSuite) readEvents(r *bufio.Reader, n int) []Event {
+	var events []Event
+	var ev Event
+	for len(events) < n {
+		line, err := r.ReadString('\n')
+		s.Require().NoError(err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if ev.Data != nil {
+				events = append(events, ev)
+			}
+			ev = Event{}
+		case strings.HasPrefix(line, "id: "):
+			ev.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.Event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.Data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+func (s *SSETestSuite) connect(h http.Handler, lastID string) (*bufio.Reader, context.CancelFunc) {
+	srv := httptest.NewServer(h)
+	s.T().Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	s.Require().NoError(err)
+	if lastID != "" {
+		req.Header.Set(LastEventIDHeader, lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { resp.Body.Close() })
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	return bufio.NewReader(resp.Body), cancel
+}
+
+func (s *SSETestSuite) TestWriteEvent() {
+	testCases := []struct {
+		name     string
+		event    Event
+		expected string
+	}{
+		{name: "string", event: Event{Data: "hello"}, expected: "data: hello\n\n"},
+		{name: "multiline", event: Event{ID: "7", Event: "note", Data: "a\nb"}, expected: "id: 7\nevent: note\ndata: a\ndata: b\n\n"},
+		{name: "json", event: Event{Data: map[string]float64{"kw": 1.5}}, expected: "data: {\"kw\":1.5}\n\n"},
+		{name: "newline in id", event: Event{ID: "1\n2", Data: []byte("x")}, expected: "id: 12\ndata: x\n\n"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			var buf bytes.Buffer
+			s.Require().NoError(WriteEvent(&buf, tc.event))
+			s.Equal(tc.expected, buf.String())
+		})
+	}
+}
+
+func (s *SSETestSuite) TestBrokerStreamAndReplay() {
+	b := NewBroker(WithHistorySize(3))
+	h, err := Handler(&Config{Retry: time.Second}, b.Subscribe, logger.Default())
+	s.Require().NoError(err)
+
+	r, cancel := s.connect(h, "")
+	s.Eventually(func() bool { return b.Clients() == 1 }, time.Second, 5*time.Millisecond)
+
+	for _, v := range []string{"a", "b", "c", "d"} {
+		b.Publish("der", v)
+	}
+	events := s.readEvents(r, 4)
+	s.Equal(Event{ID: "1", Event: "der", Data: "a"}, events[0])
+	s.Equal("4", events[3].ID)
+	cancel()
+	s.Eventually(func() bool { return b.Clients() == 0 }, time.Second, 5*time.Millisecond)
+
+	// Reconnecting after event 2 replays what is left in the history.
+	r, _ = s.connect(h, "2")
+	events = s.readEvents(r, 2)
+	s.Equal("3", events[0].ID)
+	s.Equal("4", events[1].ID)
+}
+
+func (s *SSETestSuite) TestBrokerDisconnectsSlowClient() {
+	b := NewBroker(WithBufferSize(1))
+	ch, err := b.Subscribe(context.Background(), "")
+	s.Require().NoError(err)
+
+	b.Publish("der", 1)
+	b.Publish("der", 2)
+	s.Zero(b.Clients())
+
+	<-ch
+	_, ok := <-ch
+	s.False(ok)
+}
+
+func (s *SSETestSuite) TestHeartbeat() {
+	h, err := Handler(&Config{HeartbeatInterval: 10 * time.Millisecond}, func(ctx context.Context, _ string) (<-chan Event, error) {
+		return make(chan Event), nil
+	}, logger.Default())
+	s.Require().NoError(err)
+
+	r, _ := s.connect(h, "")
+	line, err := r.ReadString('\n')
+	s.Require().NoError(err)
+	s.Equal(": heartbeat\n", line)
+}
+
+func (s *SSETestSuite) TestFromEventBus() {
+	bus := eventbus.New()
+	h, err := Handler(&Config{}, FromEventBus(bus, 8, func(v any) (Event, bool) {
+		n, ok := v.(int)
+		return Event{Event: "reading", Data: n}, ok
+	}), logger.Default())
+	s.Require().NoError(err)
+
+	r, _ := s.connect(h, "")
+
+	// The handler subscribes asynchronously, so publish until it is listening.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			bus.Publish("skip")
+			bus.Publish(42)
+			select {
+			case <-done:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+	}()
+
+	events := s.readEvents(r, 1)
+	s.Equal(Event{Event: "reading", Data: "42"}, events[0])
+}
+
+func (s *SSETestSuite) TestFeed() {
+	bus := eventbus.New()
+	b := NewBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := b.Subscribe(ctx, "")
+	s.Require().NoError(err)
+	go b.Feed(ctx, bus, 8, func(v any) (string, any, bool) { return "evt", v, true })
+
+	// Feed subscribes asynchronously, so publish until it is listening.
+	var ev Event
+	s.Eventually(func() bool {
+		bus.Publish("x")
+		select {
+		case ev = <-ch:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, 5*time.Millisecond)
+	s.Equal("evt", ev.Event)
+	s.Equal("x", ev.Data)
+}
+
+func (s *SSETestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "defaults", cfg: &Config{}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "negative heartbeat", cfg: &Config{HeartbeatInterval: -time.Second}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestSSESuite(t *testing.T) {
+	suite.Run(t, new(SSETestSuite))
+}
@@ -0,0 +1,257 @@
+// Package supervisor runs goroutine-based components and restarts them
+// according to a policy when they return or panic, instead of letting them
+// die silently.
+//
+// A Supervisor is itself a blocking run function, so it plugs into runner as
+// runner.Service(sup.Run) and stops cleanly when its context, typically from
+// sigctx, is cancelled. A child that exhausts its restart budget stops the
+// supervisor with an error, which runner treats as fatal.
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/grid-stream-org/go-commons/pkg/runner"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Policy decides whether a child is restarted after it returns.
+type Policy string
+
+const (
+	// Always restarts the child whenever it returns, even without error.
+	Always Policy = "always"
+	// OnFailure restarts the child only when it returns an error or panics.
+	OnFailure Policy = "on-failure"
+	// Never lets the child finish; a failure stops the supervisor.
+	Never Policy = "never"
+)
+
+// PanicError is returned in place of a panic raised by a child.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+type Config struct {
+	Policy Policy `koanf:"policy" json:"policy" envconfig:"policy"`
+	// MaxRestarts is how many consecutive restarts are allowed before the
+	// supervisor gives up. Zero means unlimited.
+	MaxRestarts int `koanf:"max_restarts" json:"max_restarts" envconfig:"max_restarts"`
+	// ResetAfter is how long a child must run for its restart count and
+	// backoff to reset.
+	ResetAfter time.Duration `koanf:"reset_after" json:"reset_after" envconfig:"reset_after"`
+	// Backoff is the delay between restarts. MaxAttempts and MaxElapsed are
+	// ignored.
+	Backoff retry.Config `koanf:"backoff" json:"backoff" envconfig:"backoff"`
+}
+
+const DefaultResetAfter = time.Minute
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("supervisor configuration required")
+	}
+	switch c.Policy {
+	case "", Always, OnFailure, Never:
+	default:
+		return errors.Errorf("unknown restart policy %q", c.Policy)
+	}
+	if c.MaxRestarts < 0 || c.ResetAfter < 0 {
+		return errors.New("supervisor limits must not be negative")
+	}
+	return errors.Wrap(c.Backoff.Validate(), "supervisor backoff")
+}
+
+type Option func(*Supervisor)
+
+// WithMetrics counts restarts per child.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(s *Supervisor) {
+		c, err := m.NewCounterVec("supervisor", "restarts_total", "Number of times a supervised component was restarted.", "child", "reason")
+		if err != nil {
+			s.log.Warn("registering supervisor metrics", "error", err)
+			return
+		}
+		s.restarts = c
+	}
+}
+
+type ChildOption func(*child)
+
+// WithPolicy overrides Config.Policy for one child.
+func WithPolicy(p Policy) ChildOption {
+	return func(c *child) {
+		c.policy = p
+	}
+}
+
+// WithMaxRestarts overrides Config.MaxRestarts for one child.
+func WithMaxRestarts(n int) ChildOption {
+	return func(c *child) {
+		c.maxRestarts = n
+	}
+}
+
+type child struct {
+	name        string
+	run         func(ctx context.Context) error
+	policy      Policy
+	maxRestarts int
+}
+
+type Supervisor struct {
+	cfg      Config
+	log      *slog.Logger
+	backoff  []retry.Option
+	restarts *prometheus.CounterVec
+	children []*child
+	names    map[string]bool
+}
+
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Supervisor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	s := &Supervisor{cfg: *cfg, log: log, names: map[string]bool{}}
+	if s.cfg.Policy == "" {
+		s.cfg.Policy = OnFailure
+	}
+	if s.cfg.ResetAfter == 0 {
+		s.cfg.ResetAfter = DefaultResetAfter
+	}
+	s.backoff = s.cfg.Backoff.Options()
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Add registers a child. run should block until ctx is cancelled or it
+// fails. Children must be added before Run.
+func (s *Supervisor) Add(name string, run func(ctx context.Context) error, opts ...ChildOption) error {
+	if s.names[name] {
+		return errors.Errorf("child %s already registered", name)
+	}
+
+	c := &child{name: name, run: run, policy: s.cfg.Policy, maxRestarts: s.cfg.MaxRestarts}
+	for _, opt := range opts {
+		opt(c)
+	}
+	s.children = append(s.children, c)
+	s.names[name] = true
+	return nil
+}
+
+// Component returns the supervisor as a runner component.
+func (s *Supervisor) Component() runner.Component {
+	return runner.Service(s.Run)
+}
+
+// Run starts every child and blocks until ctx is cancelled or a child gives
+// up, in which case the remaining children are cancelled and the child's last
+// error is returned.
+func (s *Supervisor) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		once   sync.Once
+		failed error
+	)
+	for _, c := range s.children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.supervise(ctx, c); err != nil {
+				once.Do(func() {
+					failed = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// supervise runs c until ctx is done, returning an error only when c gives
+// up.
+func (s *Supervisor) supervise(ctx context.Context, c *child) error {
+	restarts := 0
+	for {
+		started := time.Now()
+		err := call(ctx, c.run)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if time.Since(started) >= s.cfg.ResetAfter {
+			restarts = 0
+		}
+
+		reason := "failed"
+		switch {
+		case err == nil && c.policy != Always:
+			s.log.Info("component finished", "child", c.name)
+			return nil
+		case err == nil:
+			reason = "exited"
+		case c.policy == Never:
+			s.log.Error("component failed", "child", c.name, "error", err)
+			return errors.Wrapf(err, "child %s failed", c.name)
+		}
+		var pe *PanicError
+		if errors.As(err, &pe) {
+			reason = "panic"
+			s.log.Error("component panicked", "child", c.name, "panic", pe.Value, "stack", string(pe.Stack))
+		}
+
+		if c.maxRestarts > 0 && restarts >= c.maxRestarts {
+			s.log.Error("component exceeded restart limit", "child", c.name, "restarts", restarts, "error", err)
+			if err == nil {
+				err = errors.New("exited")
+			}
+			return errors.Wrapf(err, "child %s gave up after %d restarts", c.name, restarts)
+		}
+		restarts++
+
+		delay := retry.Backoff(restarts, s.backoff...)
+		s.log.Warn("restarting component", "child", c.name, "reason", reason, "error", err, "attempt", restarts, "delay", delay)
+		if s.restarts != nil {
+			s.restarts.WithLabelValues(c.name, reason).Inc()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// call runs fn, converting a panic into a PanicError.
+func call(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,148 @@
+package supervisor
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/grid-stream-org/go-commons/pkg/runner"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type SupervisorTestSuite struct {
+	suite.Suite
+}
+
+func (s *SupervisorTestSuite) supervisor(cfg Config, opts ...Option) *Supervisor {
+	cfg.Backoff = retry.Config{InitialInterval: time.Millisecond, MaxInterval: 5 * time.Millisecond}
+	sup, err := New(&cfg, logger.Default(), opts...)
+	s.Require().NoError(err)
+	return sup
+}
+
+func (s *SupervisorTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "defaults", cfg: &Config{}, expectError: false},
+		{name: "always", cfg: &Config{Policy: Always, MaxRestarts: 3}, expectError: false},
+		{name: "unknown policy", cfg: &Config{Policy: "sometimes"}, expectError: true},
+		{name: "negative restarts", cfg: &Config{MaxRestarts: -1}, expectError: true},
+		{name: "bad backoff", cfg: &Config{Backoff: retry.Config{Jitter: 2}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *SupervisorTestSuite) TestRestartsPanics() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "supervisor"})
+	s.Require().NoError(err)
+	sup := s.supervisor(Config{}, WithMetrics(m))
+
+	var calls atomic.Int32
+	s.Require().NoError(sup.Add("mqtt", func(ctx context.Context) error {
+		if calls.Add(1) <= 2 {
+			panic("connection reset")
+		}
+		<-ctx.Done()
+		return nil
+	}))
+	s.Error(sup.Add("mqtt", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sup.Run(ctx) }()
+
+	s.Eventually(func() bool { return calls.Load() == 3 }, time.Second, time.Millisecond)
+	cancel()
+	s.NoError(<-done)
+	s.Equal(float64(2), testutil.ToFloat64(sup.restarts.WithLabelValues("mqtt", "panic")))
+}
+
+func (s *SupervisorTestSuite) TestPolicies() {
+	testCases := []struct {
+		name      string
+		policy    Policy
+		result    error
+		wantCalls int32
+		wantError bool
+	}{
+		{name: "on-failure clean exit", policy: OnFailure, result: nil, wantCalls: 1},
+		{name: "on-failure error", policy: OnFailure, result: errors.New("boom"), wantCalls: 3, wantError: true},
+		{name: "always clean exit", policy: Always, result: nil, wantCalls: 3, wantError: true},
+		{name: "never error", policy: Never, result: errors.New("boom"), wantCalls: 1, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			sup := s.supervisor(Config{Policy: tc.policy, MaxRestarts: 2})
+			var calls atomic.Int32
+			s.Require().NoError(sup.Add("worker", func(context.Context) error {
+				calls.Add(1)
+				return tc.result
+			}))
+
+			err := sup.Run(context.Background())
+			if tc.wantError {
+				s.ErrorContains(err, "child worker")
+			} else {
+				s.NoError(err)
+			}
+			s.Equal(tc.wantCalls, calls.Load())
+		})
+	}
+}
+
+func (s *SupervisorTestSuite) TestGiveUpStopsSiblings() {
+	sup := s.supervisor(Config{})
+	s.Require().NoError(sup.Add("flaky", func(context.Context) error {
+		return errors.New("boom")
+	}, WithPolicy(Never)))
+
+	var stopped atomic.Bool
+	s.Require().NoError(sup.Add("steady", func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped.Store(true)
+		return nil
+	}))
+
+	s.ErrorContains(sup.Run(context.Background()), "child flaky failed: boom")
+	s.True(stopped.Load())
+}
+
+func (s *SupervisorTestSuite) TestRunner() {
+	sup := s.supervisor(Config{})
+	s.Require().NoError(sup.Add("worker", func(context.Context) error {
+		return errors.New("boom")
+	}, WithMaxRestarts(1)))
+
+	r, err := runner.New(&runner.Config{}, logger.Default())
+	s.Require().NoError(err)
+	s.Require().NoError(r.Add("supervisor", sup.Component()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.ErrorContains(r.Run(ctx), "gave up after 1 restarts")
+}
+
+func TestSupervisorTestSuite(t *testing.T) {
+	suite.Run(t, new(SupervisorTestSuite))
+}
@@ -0,0 +1,105 @@
+package testutil
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/mqttclient"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Images are variables so CI can pin or mirror them.
+var (
+	BigQueryImage = "ghcr.io/goccy/bigquery-emulator:0.6.6"
+	FirebaseImage = "andreysenov/firebase-tools:13.29.1"
+	MQTTImage     = "eclipse-mosquitto:2.0.20"
+)
+
+const (
+	bigQueryPort     = "9050/tcp"
+	bigQueryGRPCPort = "9060/tcp"
+	firebaseAuthPort = "9099/tcp"
+	mqttPort         = "1883/tcp"
+)
+
+// BigQuery starts the BigQuery emulator with an empty dataset and returns a
+// bqclient Config for it. Create tables through the client before use.
+func BigQuery(t testing.TB, projectID, datasetID string) *bqclient.Config {
+	t.Helper()
+
+	c := start(t, testcontainers.ContainerRequest{
+		Image:        BigQueryImage,
+		Cmd:          []string{"--project=" + projectID, "--dataset=" + datasetID},
+		ExposedPorts: []string{bigQueryPort, bigQueryGRPCPort},
+		WaitingFor: wait.ForAll(
+			wait.ForListeningPort(bigQueryPort),
+			wait.ForListeningPort(bigQueryGRPCPort),
+		),
+	})
+
+	return &bqclient.Config{
+		ProjectID:        projectID,
+		DatasetID:        datasetID,
+		EmulatorHost:     endpoint(t, c, bigQueryPort),
+		EmulatorGRPCHost: endpoint(t, c, bigQueryGRPCPort),
+	}
+}
+
+const firebaseJSON = `{"emulators": {"auth": {"host": "0.0.0.0", "port": 9099}}}`
+
+// FirebaseAuth starts the Firebase Auth emulator and sets
+// FIREBASE_AUTH_EMULATOR_HOST for the rest of the test, which the Firebase
+// Admin SDK used by pkg/auth honours. Emulator ID tokens are unsigned, so
+// this is for exercising flows, not signature checks.
+func FirebaseAuth(t testing.TB, projectID string) *auth.Config {
+	t.Helper()
+
+	c := start(t, testcontainers.ContainerRequest{
+		Image:        FirebaseImage,
+		Cmd:          []string{"firebase", "emulators:start", "--only", "auth", "--project", projectID},
+		ExposedPorts: []string{firebaseAuthPort},
+		Files: []testcontainers.ContainerFile{{
+			Reader:            strings.NewReader(firebaseJSON),
+			ContainerFilePath: "/home/node/firebase.json",
+			FileMode:          0o644,
+		}},
+		WaitingFor: wait.ForLog("All emulators ready"),
+	})
+
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", endpoint(t, c, firebaseAuthPort))
+	return &auth.Config{ProjectID: projectID}
+}
+
+// MQTT starts a Mosquitto broker that accepts anonymous clients and returns
+// an mqttclient Config with a client ID unique to the test.
+func MQTT(t testing.TB) *mqttclient.Config {
+	t.Helper()
+
+	c := start(t, testcontainers.ContainerRequest{
+		Image:        MQTTImage,
+		Cmd:          []string{"mosquitto", "-c", "/mosquitto-no-auth.conf"},
+		ExposedPorts: []string{mqttPort},
+		WaitingFor:   wait.ForListeningPort(mqttPort),
+	})
+
+	return &mqttclient.Config{
+		Broker:   "tcp://" + endpoint(t, c, mqttPort),
+		ClientID: clientID(t),
+	}
+}
+
+func clientID(t testing.TB) string {
+	id := strings.NewReplacer("/", "-", " ", "_").Replace(t.Name())
+	if len(id) > 23 {
+		// MQTT 3.1 brokers may reject client IDs longer than 23 bytes.
+		h := fnv.New32a()
+		h.Write([]byte(t.Name()))
+		id = fmt.Sprintf("%s-%08x", id[:14], h.Sum32())
+	}
+	return id
+}
@@ -0,0 +1,84 @@
+// Package testutil starts throwaway emulators and brokers in Docker for
+// integration tests and returns client Configs already pointing at them:
+//
+//	func TestStore(t *testing.T) {
+//		cfg := testutil.BigQuery(t, "test-project", "grid_stream")
+//		client, err := bqclient.New(ctx, cfg)
+//		...
+//	}
+//
+// Containers are removed when the test finishes. Tests are skipped when no
+// Docker daemon is available, so they are safe to run everywhere.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// StartupTimeout bounds how long a container may take to become ready.
+var StartupTimeout = 2 * time.Minute
+
+// start runs req, skipping t when Docker is unavailable and failing it if the
+// container does not become ready. The container is terminated on cleanup.
+func start(t testing.TB, req testcontainers.ContainerRequest) testcontainers.Container {
+	t.Helper()
+	skipWithoutDocker(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), StartupTimeout)
+	defer cancel()
+
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	testcontainers.CleanupContainer(t, c)
+	if err != nil {
+		t.Fatalf("starting %s: %v", req.Image, err)
+	}
+	return c
+}
+
+// skipWithoutDocker skips t when no healthy Docker daemon is reachable.
+func skipWithoutDocker(t testing.TB) {
+	t.Helper()
+
+	if err := dockerHealth(); err != nil {
+		t.Skipf("docker unavailable: %v", err)
+	}
+}
+
+func dockerHealth() (err error) {
+	// testcontainers panics when it cannot find any Docker host.
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%v", p)
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+	return provider.Health(context.Background())
+}
+
+// endpoint returns host:port for the container's mapped port.
+func endpoint(t testing.TB, c testcontainers.Container, port string) string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ep, err := c.PortEndpoint(ctx, nat.Port(port), "")
+	if err != nil {
+		t.Fatalf("resolving port %s: %v", port, err)
+	}
+	return ep
+}
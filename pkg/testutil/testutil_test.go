@@ -0,0 +1,74 @@
+package testutil
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/mqttclient"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestUtilTestSuite struct {
+	suite.Suite
+}
+
+func (s *TestUtilTestSuite) TestClientID() {
+	id := clientID(s.T())
+	s.Len(id, 23)
+	s.True(strings.HasPrefix(id, "TestTestUtilSu-"), id)
+	s.Equal(id, clientID(s.T()))
+}
+
+func (s *TestUtilTestSuite) TestBigQuery() {
+	cfg := BigQuery(s.T(), "test-project", "grid_stream")
+	s.Require().NoError(cfg.Validate())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := bqclient.New(ctx, cfg)
+	s.Require().NoError(err)
+	defer client.Close()
+
+	it, err := client.Query(ctx, "SELECT 1 AS one", nil)
+	s.Require().NoError(err)
+	s.EqualValues(1, it.TotalRows)
+}
+
+func (s *TestUtilTestSuite) TestMQTT() {
+	cfg := MQTT(s.T())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := mqttclient.New(ctx, cfg, logger.Default())
+	s.Require().NoError(err)
+	defer client.Close()
+
+	got := make(chan []byte, 1)
+	s.Require().NoError(client.Subscribe(ctx, "der/1", 1, func(_ context.Context, m *mqttclient.Message) error {
+		got <- m.Payload
+		return nil
+	}))
+	s.Require().NoError(client.Publish(ctx, "der/1", 1, false, []byte("hello")))
+
+	select {
+	case payload := <-got:
+		s.Equal("hello", string(payload))
+	case <-ctx.Done():
+		s.Fail("message not received")
+	}
+}
+
+func (s *TestUtilTestSuite) TestFirebaseAuth() {
+	cfg := FirebaseAuth(s.T(), "demo-grid-stream")
+	s.NoError(cfg.Validate())
+	s.NotEmpty(os.Getenv("FIREBASE_AUTH_EMULATOR_HOST"))
+}
+
+func TestTestUtilSuite(t *testing.T) {
+	suite.Run(t, new(TestUtilTestSuite))
+}
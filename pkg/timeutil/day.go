@@ -0,0 +1,23 @@
+package timeutil
+
+import "time"
+
+// StartOfDay returns local midnight of t's day in t's location.
+func StartOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Day returns the local calendar day containing t. On DST transition days it
+// is 23 or 25 hours long.
+func Day(t time.Time) Window {
+	start := StartOfDay(t)
+	y, m, d := start.Date()
+	return Window{Start: start, End: time.Date(y, m, d+1, 0, 0, 0, 0, t.Location())}
+}
+
+// IsDSTTransition reports whether the local day containing t is not 24 hours
+// long.
+func IsDSTTransition(t time.Time) bool {
+	return Day(t).Duration() != 24*time.Hour
+}
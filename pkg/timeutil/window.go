@@ -0,0 +1,117 @@
+// Package timeutil aligns timestamps to aggregation windows and computes
+// window overlaps.
+//
+// Alignment is done on wall-clock time in the timestamp's own location, so a
+// one hour window in a +05:30 zone starts at :00 local time rather than at
+// :30. Window sizes must evenly divide 24 hours.
+package timeutil
+
+import (
+	"iter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const FiveMinutes = 5 * time.Minute
+
+// Window is the half-open interval [Start, End).
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ValidateSize reports whether d can be used as a window size.
+func ValidateSize(d time.Duration) error {
+	if d <= 0 {
+		return errors.New("window size must be greater than 0")
+	}
+	if (24*time.Hour)%d != 0 {
+		return errors.Errorf("window size %s does not evenly divide 24h", d)
+	}
+	return nil
+}
+
+// Floor returns the start of the size window containing t. The alignment uses
+// t's UTC offset, so during a DST transition the boundary is the one in the
+// offset t itself is expressed in.
+func Floor(t time.Time, size time.Duration) time.Time {
+	_, offset := t.Zone()
+	shift := time.Duration(offset) * time.Second
+	return t.Add(shift).Truncate(size).Add(-shift)
+}
+
+// Ceil returns t if it is on a size boundary and the next boundary otherwise.
+func Ceil(t time.Time, size time.Duration) time.Time {
+	f := Floor(t, size)
+	if f.Equal(t) {
+		return f
+	}
+	return Floor(f.Add(size), size)
+}
+
+// WindowAt returns the size window containing t.
+func WindowAt(t time.Time, size time.Duration) Window {
+	start := Floor(t, size)
+	return Window{Start: start, End: start.Add(size)}
+}
+
+// Windows yields the consecutive size windows covering [start, end). The
+// first window starts at Floor(start). Windows are contiguous in absolute
+// time, so across a DST change a local hour is never skipped or repeated.
+func Windows(start, end time.Time, size time.Duration) iter.Seq[Window] {
+	return func(yield func(Window) bool) {
+		for w := Floor(start, size); w.Before(end); {
+			next := Floor(w.Add(size), size)
+			if !next.After(w) {
+				next = w.Add(size)
+			}
+			if !yield(Window{Start: w, End: next}) {
+				return
+			}
+			w = next
+		}
+	}
+}
+
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Overlap returns the intersection of w and o and whether it is non-empty.
+func (w Window) Overlap(o Window) (Window, bool) {
+	start := w.Start
+	if o.Start.After(start) {
+		start = o.Start
+	}
+	end := w.End
+	if o.End.Before(end) {
+		end = o.End
+	}
+	if !start.Before(end) {
+		return Window{}, false
+	}
+	return Window{Start: start, End: end}, true
+}
+
+// OverlapDuration is how long w and o overlap, such as the portion of an
+// aggregation window covered by a DR event.
+func (w Window) OverlapDuration(o Window) time.Duration {
+	ov, ok := w.Overlap(o)
+	if !ok {
+		return 0
+	}
+	return ov.Duration()
+}
+
+// OverlapFraction is the fraction of w covered by o, between 0 and 1.
+func (w Window) OverlapFraction(o Window) float64 {
+	if w.Duration() <= 0 {
+		return 0
+	}
+	return float64(w.OverlapDuration(o)) / float64(w.Duration())
+}
@@ -0,0 +1,160 @@
+package timeutil
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TimeUtilTestSuite struct {
+	suite.Suite
+	eastern *time.Location
+	india   *time.Location
+}
+
+func (s *TimeUtilTestSuite) SetupTest() {
+	var err error
+	s.eastern, err = time.LoadLocation("America/Toronto")
+	s.Require().NoError(err)
+	s.india, err = time.LoadLocation("Asia/Kolkata")
+	s.Require().NoError(err)
+}
+
+func (s *TimeUtilTestSuite) TestFloorCeil() {
+	testCases := []struct {
+		name  string
+		t     time.Time
+		size  time.Duration
+		floor time.Time
+		ceil  time.Time
+	}{
+		{
+			name:  "five minutes utc",
+			t:     time.Date(2025, 3, 1, 10, 7, 30, 0, time.UTC),
+			size:  FiveMinutes,
+			floor: time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC),
+			ceil:  time.Date(2025, 3, 1, 10, 10, 0, 0, time.UTC),
+		},
+		{
+			name:  "on boundary",
+			t:     time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC),
+			size:  FiveMinutes,
+			floor: time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC),
+			ceil:  time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name:  "hour in half hour offset zone",
+			t:     time.Date(2025, 3, 1, 10, 20, 0, 0, s.india),
+			size:  time.Hour,
+			floor: time.Date(2025, 3, 1, 10, 0, 0, 0, s.india),
+			ceil:  time.Date(2025, 3, 1, 11, 0, 0, 0, s.india),
+		},
+		{
+			name:  "day in local time",
+			t:     time.Date(2025, 7, 1, 22, 0, 0, 0, s.eastern),
+			size:  24 * time.Hour,
+			floor: time.Date(2025, 7, 1, 0, 0, 0, 0, s.eastern),
+			ceil:  time.Date(2025, 7, 2, 0, 0, 0, 0, s.eastern),
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.True(tc.floor.Equal(Floor(tc.t, tc.size)), "floor %s", Floor(tc.t, tc.size))
+			s.True(tc.ceil.Equal(Ceil(tc.t, tc.size)), "ceil %s", Ceil(tc.t, tc.size))
+		})
+	}
+}
+
+func (s *TimeUtilTestSuite) TestWindowsAcrossDST() {
+	testCases := []struct {
+		name  string
+		start time.Time
+		hours int
+	}{
+		{name: "spring forward", start: time.Date(2025, 3, 9, 0, 0, 0, 0, s.eastern), hours: 23},
+		{name: "fall back", start: time.Date(2025, 11, 2, 0, 0, 0, 0, s.eastern), hours: 25},
+		{name: "normal day", start: time.Date(2025, 6, 1, 0, 0, 0, 0, s.eastern), hours: 24},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			day := Day(tc.start)
+			windows := slices.Collect(Windows(day.Start, day.End, time.Hour))
+			s.Len(windows, tc.hours)
+			s.Equal(tc.hours != 24, IsDSTTransition(tc.start))
+			for i, w := range windows {
+				s.Equal(time.Hour, w.Duration())
+				if i > 0 {
+					s.True(windows[i-1].End.Equal(w.Start))
+				}
+			}
+			s.True(windows[len(windows)-1].End.Equal(day.End))
+		})
+	}
+}
+
+func (s *TimeUtilTestSuite) TestWindowsPartialRange() {
+	start := time.Date(2025, 3, 1, 10, 2, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 1, 10, 11, 0, 0, time.UTC)
+
+	var starts []int
+	for w := range Windows(start, end, FiveMinutes) {
+		starts = append(starts, w.Start.Minute())
+	}
+	s.Equal([]int{0, 5, 10}, starts)
+}
+
+func (s *TimeUtilTestSuite) TestOverlap() {
+	at := func(h, m int) time.Time { return time.Date(2025, 3, 1, h, m, 0, 0, time.UTC) }
+	window := Window{Start: at(14, 0), End: at(14, 5)}
+
+	testCases := []struct {
+		name     string
+		event    Window
+		duration time.Duration
+		fraction float64
+	}{
+		{name: "event covers window", event: Window{Start: at(13, 0), End: at(15, 0)}, duration: 5 * time.Minute, fraction: 1},
+		{name: "event starts mid window", event: Window{Start: at(14, 3), End: at(15, 0)}, duration: 2 * time.Minute, fraction: 0.4},
+		{name: "event ends at window start", event: Window{Start: at(13, 0), End: at(14, 0)}},
+		{name: "disjoint", event: Window{Start: at(16, 0), End: at(17, 0)}},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			s.Equal(tc.duration, window.OverlapDuration(tc.event))
+			s.InDelta(tc.fraction, window.OverlapFraction(tc.event), 1e-9)
+		})
+	}
+}
+
+func (s *TimeUtilTestSuite) TestValidateSize() {
+	testCases := []struct {
+		name        string
+		size        time.Duration
+		expectError bool
+	}{
+		{name: "five minutes", size: FiveMinutes},
+		{name: "day", size: 24 * time.Hour},
+		{name: "zero", size: 0, expectError: true},
+		{name: "seven minutes", size: 7 * time.Minute, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := ValidateSize(tc.size)
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestTimeUtilSuite(t *testing.T) {
+	suite.Run(t, new(TimeUtilTestSuite))
+}
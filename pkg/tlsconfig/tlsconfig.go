@@ -0,0 +1,87 @@
+// Package tlsconfig builds *tls.Config values from file-based settings shared
+// by the client and server packages in go-commons.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+type Config struct {
+	Enabled            bool   `koanf:"enabled" json:"enabled" envconfig:"enabled"`
+	CAFile             string `koanf:"ca_file" json:"ca_file" envconfig:"ca_file"`
+	CertFile           string `koanf:"cert_file" json:"cert_file" envconfig:"cert_file"`
+	KeyFile            string `koanf:"key_file" json:"key_file" envconfig:"key_file"`
+	ServerName         string `koanf:"server_name" json:"server_name" envconfig:"server_name"`
+	InsecureSkipVerify bool   `koanf:"insecure_skip_verify" json:"insecure_skip_verify" envconfig:"insecure_skip_verify"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil || !c.Enabled {
+		return nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("tls cert file and key file must be set together")
+	}
+	return nil
+}
+
+// Build returns the tls.Config described by c, or nil when TLS is disabled.
+// CAFile replaces the system roots for verifying peers; when building a
+// server config it is used to verify client certificates instead.
+func (c *Config) Build() (*tls.Config, error) {
+	if c == nil || !c.Enabled {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify, //nolint:gosec // opt-in for local development only
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+		cfg.ClientCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// BuildServer is like Build but additionally requires and verifies client
+// certificates when a CA file is configured.
+func (c *Config) BuildServer() (*tls.Config, error) {
+	cfg, err := c.Build()
+	if err != nil || cfg == nil {
+		return cfg, err
+	}
+	if len(cfg.Certificates) == 0 {
+		return nil, errors.New("tls server requires a cert file and key file")
+	}
+	if cfg.ClientCAs != nil {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
@@ -0,0 +1,95 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TLSConfigTestSuite struct {
+	suite.Suite
+	certFile string
+	keyFile  string
+}
+
+func (s *TLSConfigTestSuite) SetupSuite() {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	s.Require().NoError(err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		DNSNames:              []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	s.Require().NoError(err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	s.Require().NoError(err)
+
+	dir := s.T().TempDir()
+	s.certFile = filepath.Join(dir, "cert.pem")
+	s.keyFile = filepath.Join(dir, "key.pem")
+	s.Require().NoError(os.WriteFile(s.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	s.Require().NoError(os.WriteFile(s.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func (s *TLSConfigTestSuite) TestBuild() {
+	cfg, err := (&Config{}).Build()
+	s.NoError(err)
+	s.Nil(cfg, "disabled TLS yields nil")
+
+	cfg, err = (&Config{Enabled: true, CAFile: s.certFile, CertFile: s.certFile, KeyFile: s.keyFile, ServerName: "localhost"}).Build()
+	s.Require().NoError(err)
+	s.Equal(uint16(tls.VersionTLS12), cfg.MinVersion)
+	s.NotNil(cfg.RootCAs)
+	s.Len(cfg.Certificates, 1)
+	s.Equal("localhost", cfg.ServerName)
+}
+
+func (s *TLSConfigTestSuite) TestBuildServer() {
+	cfg, err := (&Config{Enabled: true, CAFile: s.certFile, CertFile: s.certFile, KeyFile: s.keyFile}).BuildServer()
+	s.Require().NoError(err)
+	s.Equal(tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+
+	_, err = (&Config{Enabled: true}).BuildServer()
+	s.Error(err)
+}
+
+func (s *TLSConfigTestSuite) TestErrors() {
+	testCases := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"cert without key", &Config{Enabled: true, CertFile: s.certFile}},
+		{"missing ca", &Config{Enabled: true, CAFile: "missing.pem"}},
+		{"ca without certs", &Config{Enabled: true, CAFile: s.keyFile}},
+		{"missing key pair", &Config{Enabled: true, CertFile: "missing.pem", KeyFile: "missing.key"}},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := tc.cfg.Build()
+			s.Error(err)
+		})
+	}
+}
+
+func TestTLSConfigSuite(t *testing.T) {
+	suite.Run(t, new(TLSConfigTestSuite))
+}
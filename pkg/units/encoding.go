@@ -0,0 +1,48 @@
+package units
+
+// Text encodings carry the unit, for example "12.5 kW". They are used by
+// encoding/json, so JSON payloads hold strings rather than bare numbers.
+
+func (p Power) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
+
+func (p *Power) UnmarshalText(text []byte) error {
+	v, err := ParsePower(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+func (e Energy) MarshalText() ([]byte, error) { return []byte(e.String()), nil }
+
+func (e *Energy) UnmarshalText(text []byte) error {
+	v, err := ParseEnergy(string(text))
+	if err != nil {
+		return err
+	}
+	*e = v
+	return nil
+}
+
+func (i Current) MarshalText() ([]byte, error) { return []byte(i.String()), nil }
+
+func (i *Current) UnmarshalText(text []byte) error {
+	v, err := ParseCurrent(string(text))
+	if err != nil {
+		return err
+	}
+	*i = v
+	return nil
+}
+
+func (v Voltage) MarshalText() ([]byte, error) { return []byte(v.String()), nil }
+
+func (v *Voltage) UnmarshalText(text []byte) error {
+	parsed, err := ParseVoltage(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
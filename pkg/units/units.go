@@ -0,0 +1,162 @@
+// Package units provides typed electrical quantities. Each quantity is stored
+// in its SI base unit and has its own type, so mixing them up, such as adding
+// energy to power, fails to compile. Text and JSON encodings always carry the
+// unit, which prevents kW and W being confused between services.
+package units
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Power in watts.
+type Power float64
+
+// Energy in watt-hours.
+type Energy float64
+
+// Current in amperes.
+type Current float64
+
+// Voltage in volts.
+type Voltage float64
+
+const (
+	Watt     Power = 1
+	Kilowatt Power = 1e3
+	Megawatt Power = 1e6
+
+	WattHour     Energy = 1
+	KilowattHour Energy = 1e3
+	MegawattHour Energy = 1e6
+
+	Milliampere Current = 1e-3
+	Ampere      Current = 1
+
+	Volt     Voltage = 1
+	Kilovolt Voltage = 1e3
+)
+
+var (
+	powerUnits = []unit{
+		{symbol: "MW", scale: float64(Megawatt)},
+		{symbol: "kW", scale: float64(Kilowatt)},
+		{symbol: "W", scale: float64(Watt)},
+	}
+	energyUnits = []unit{
+		{symbol: "MWh", scale: float64(MegawattHour)},
+		{symbol: "kWh", scale: float64(KilowattHour)},
+		{symbol: "Wh", scale: float64(WattHour)},
+	}
+	currentUnits = []unit{
+		{symbol: "A", scale: float64(Ampere)},
+		{symbol: "mA", scale: float64(Milliampere)},
+	}
+	voltageUnits = []unit{
+		{symbol: "kV", scale: float64(Kilovolt)},
+		{symbol: "V", scale: float64(Volt)},
+	}
+)
+
+func (p Power) Watts() float64     { return float64(p) }
+func (p Power) Kilowatts() float64 { return float64(p / Kilowatt) }
+func (p Power) Megawatts() float64 { return float64(p / Megawatt) }
+
+// Over returns the energy delivered by sustaining p for d.
+func (p Power) Over(d time.Duration) Energy {
+	return Energy(float64(p) * d.Hours())
+}
+
+func (e Energy) WattHours() float64     { return float64(e) }
+func (e Energy) KilowattHours() float64 { return float64(e / KilowattHour) }
+func (e Energy) MegawattHours() float64 { return float64(e / MegawattHour) }
+
+// Per returns the average power needed to deliver e over d.
+func (e Energy) Per(d time.Duration) Power {
+	if d <= 0 {
+		return 0
+	}
+	return Power(float64(e) / d.Hours())
+}
+
+func (i Current) Amperes() float64 { return float64(i) }
+
+// Times returns the power drawn at current i and voltage v.
+func (i Current) Times(v Voltage) Power { return Power(float64(i) * float64(v)) }
+
+func (v Voltage) Volts() float64     { return float64(v) }
+func (v Voltage) Kilovolts() float64 { return float64(v / Kilovolt) }
+
+func (p Power) String() string   { return format(float64(p), powerUnits) }
+func (e Energy) String() string  { return format(float64(e), energyUnits) }
+func (i Current) String() string { return format(float64(i), currentUnits) }
+func (v Voltage) String() string { return format(float64(v), voltageUnits) }
+
+// ParsePower parses values such as "12.5kW", "3 MW" or "-400 W". A unit is
+// required.
+func ParsePower(s string) (Power, error) {
+	v, err := parse(s, powerUnits)
+	return Power(v), err
+}
+
+func ParseEnergy(s string) (Energy, error) {
+	v, err := parse(s, energyUnits)
+	return Energy(v), err
+}
+
+func ParseCurrent(s string) (Current, error) {
+	v, err := parse(s, currentUnits)
+	return Current(v), err
+}
+
+func ParseVoltage(s string) (Voltage, error) {
+	v, err := parse(s, voltageUnits)
+	return Voltage(v), err
+}
+
+type unit struct {
+	symbol string
+	scale  float64
+}
+
+// format picks the largest unit in which |v| is at least 1.
+func format(v float64, units []unit) string {
+	u := units[len(units)-1]
+	for _, candidate := range units {
+		if math.Abs(v) >= candidate.scale {
+			u = candidate
+			break
+		}
+	}
+	return strconv.FormatFloat(v/u.scale, 'f', -1, 64) + " " + u.symbol
+}
+
+// parse matches the longest symbol first so "kWh" is never read as "kW".
+// Symbols are case-sensitive because "mW" and "MW" differ by nine orders of
+// magnitude.
+func parse(s string, units []unit) (float64, error) {
+	s = strings.TrimSpace(s)
+	var match *unit
+	for i := range units {
+		if strings.HasSuffix(s, units[i].symbol) && (match == nil || len(units[i].symbol) > len(match.symbol)) {
+			match = &units[i]
+		}
+	}
+	if match == nil {
+		return 0, errors.Errorf("missing or unknown unit in %q", s)
+	}
+
+	num := strings.TrimSpace(strings.TrimSuffix(s, match.symbol))
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "parsing %q", s)
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.Errorf("invalid quantity %q", s)
+	}
+	return v * match.scale, nil
+}
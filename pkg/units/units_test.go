@@ -0,0 +1,95 @@
+package units
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UnitsTestSuite struct {
+	suite.Suite
+}
+
+func (s *UnitsTestSuite) TestParsePower() {
+	testCases := []struct {
+		input       string
+		want        Power
+		expectError bool
+	}{
+		{input: "12.5kW", want: 12.5 * Kilowatt},
+		{input: "3 MW", want: 3 * Megawatt},
+		{input: "-400 W", want: -400 * Watt},
+		{input: " 0.5 kW ", want: 500 * Watt},
+		{input: "12.5", expectError: true},
+		{input: "12.5 kWh", expectError: true},
+		{input: "12.5 kw", expectError: true},
+		{input: "abc kW", expectError: true},
+		{input: "NaN kW", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.input, func() {
+			got, err := ParsePower(tc.input)
+			if tc.expectError {
+				s.Error(err)
+				return
+			}
+			s.NoError(err)
+			s.InDelta(float64(tc.want), float64(got), 1e-9)
+		})
+	}
+}
+
+func (s *UnitsTestSuite) TestParseEnergyPrefersLongestSymbol() {
+	e, err := ParseEnergy("2.5 kWh")
+	s.NoError(err)
+	s.Equal(2.5, e.KilowattHours())
+
+	_, err = ParseEnergy("2.5 kW")
+	s.Error(err)
+}
+
+func (s *UnitsTestSuite) TestString() {
+	s.Equal("12.5 kW", (12.5 * Kilowatt).String())
+	s.Equal("1.2 MW", (1200 * Kilowatt).String())
+	s.Equal("400 W", (400 * Watt).String())
+	s.Equal("-2 kW", (-2 * Kilowatt).String())
+	s.Equal("0 W", Power(0).String())
+	s.Equal("3 kWh", (3 * KilowattHour).String())
+	s.Equal("250 mA", (250 * Milliampere).String())
+	s.Equal("13.8 kV", (13.8 * Kilovolt).String())
+}
+
+func (s *UnitsTestSuite) TestConversions() {
+	s.Equal(1.5, (1500 * Watt).Kilowatts())
+	s.Equal(2.0, (2000 * Kilowatt).Megawatts())
+	s.Equal(2.5*KilowattHour, (10 * Kilowatt).Over(15*time.Minute))
+	s.Equal(10*Kilowatt, (2.5 * KilowattHour).Per(15*time.Minute))
+	s.Equal(Power(0), KilowattHour.Per(0))
+	s.Equal(2.4*Kilowatt, (10 * Ampere).Times(240*Volt))
+}
+
+func (s *UnitsTestSuite) TestJSON() {
+	type reading struct {
+		Power  Power  `json:"power"`
+		Energy Energy `json:"energy"`
+	}
+
+	data, err := json.Marshal(reading{Power: 12.5 * Kilowatt, Energy: 3 * KilowattHour})
+	s.Require().NoError(err)
+	s.JSONEq(`{"power":"12.5 kW","energy":"3 kWh"}`, string(data))
+
+	var r reading
+	s.NoError(json.Unmarshal([]byte(`{"power":"400 W","energy":"1.5 MWh"}`), &r))
+	s.Equal(400*Watt, r.Power)
+	s.Equal(1.5*MegawattHour, r.Energy)
+
+	s.Error(json.Unmarshal([]byte(`{"power":400}`), &r))
+	s.Error(json.Unmarshal([]byte(`{"power":"400"}`), &r))
+}
+
+func TestUnitsSuite(t *testing.T) {
+	suite.Run(t, new(UnitsTestSuite))
+}
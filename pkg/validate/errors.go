@@ -0,0 +1,80 @@
+package validate
+
+import (
+	"strings"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/pkg/errors"
+)
+
+// FieldError is a validation failure at a dotted field path.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors collects every FieldError found in a value so callers can report
+// all problems at once.
+type Errors struct {
+	Errors []*FieldError
+}
+
+func (ve *Errors) Error() string {
+	messages := make([]string, len(ve.Errors))
+	for i, err := range ve.Errors {
+		messages[i] = err.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func (ve *Errors) ErrorCode() gserrors.Code {
+	return gserrors.InvalidInput
+}
+
+// Add records err at path. Nested *Errors are flattened with their paths
+// prefixed by path. A nil err is ignored.
+func (ve *Errors) Add(path string, err error) {
+	if err == nil {
+		return
+	}
+
+	var nested *Errors
+	if errors.As(err, &nested) {
+		for _, fe := range nested.Errors {
+			ve.Errors = append(ve.Errors, &FieldError{Path: JoinPath(path, fe.Path), Err: fe.Err})
+		}
+		return
+	}
+	ve.Errors = append(ve.Errors, &FieldError{Path: path, Err: err})
+}
+
+// Err returns ve, or nil if nothing was recorded.
+func (ve *Errors) Err() error {
+	if len(ve.Errors) == 0 {
+		return nil
+	}
+	return errors.WithStack(ve)
+}
+
+// JoinPath joins dotted path segments, skipping empty ones.
+func JoinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	default:
+		return parent + "." + child
+	}
+}
@@ -0,0 +1,276 @@
+// Package validate checks structs against rules declared in `validate` struct
+// tags and reports every failure with its field path:
+//
+//	type Config struct {
+//		Addr    string        `koanf:"addr" validate:"required"`
+//		Workers int           `koanf:"workers" validate:"min=1,max=64"`
+//		Mode    string        `koanf:"mode" validate:"oneof=batch stream"`
+//		Timeout time.Duration `koanf:"timeout" validate:"min=1s,max=5m"`
+//	}
+//
+//	func (c *Config) Validate() error { return validate.Struct(c) }
+//
+// Rules:
+//
+//	required   the value is not the zero value; slices and maps are non-empty
+//	min=N      numbers are >= N; strings, slices and maps have length >= N
+//	max=N      numbers are <= N; strings, slices and maps have length <= N
+//	oneof=a b  the value is one of the space-separated options
+//
+// For time.Duration fields N is a duration such as 30s. Rules other than
+// required are skipped for zero values, so optional fields only need to be
+// valid when set. Nested structs, pointers to structs and slices of structs
+// are validated recursively unless they have their own Validate method. Paths
+// use the koanf tag, then the json tag, then the field name.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const tagName = "validate"
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// Struct validates v, a struct or pointer to struct. It returns nil or an
+// *Errors. Malformed tags are reported as errors on their field.
+func Struct(v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errors.New("validate: nil value")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.Errorf("validate: expected a struct, got %T", v)
+	}
+
+	ve := &Errors{}
+	walk(ve, "", rv)
+	return ve.Err()
+}
+
+type field struct {
+	index []int
+	name  string
+	rules []rule
+	err   error
+}
+
+type rule struct {
+	name string
+	arg  string
+}
+
+var fieldCache sync.Map // reflect.Type -> []field
+
+func fieldsOf(t reflect.Type) []field {
+	if fs, ok := fieldCache.Load(t); ok {
+		return fs.([]field)
+	}
+
+	var fs []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f := field{index: sf.Index, name: fieldName(sf)}
+		f.rules, f.err = parseRules(sf.Tag.Get(tagName))
+		fs = append(fs, f)
+	}
+
+	fieldCache.Store(t, fs)
+	return fs
+}
+
+func parseRules(tag string) ([]rule, error) {
+	if tag == "" || tag == "-" {
+		return nil, nil
+	}
+
+	var rules []rule
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "required":
+		case "min", "max", "oneof":
+			if arg == "" {
+				return nil, errors.Errorf("invalid validate tag %q: %s needs an argument", tag, name)
+			}
+		default:
+			return nil, errors.Errorf("invalid validate tag %q: unknown rule %s", tag, name)
+		}
+		rules = append(rules, rule{name: name, arg: arg})
+	}
+	return rules, nil
+}
+
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"koanf", "json"} {
+		if name, _, _ := strings.Cut(sf.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func walk(ve *Errors, path string, v reflect.Value) {
+	for _, f := range fieldsOf(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		fpath := JoinPath(path, f.name)
+
+		if f.err != nil {
+			ve.Add(fpath, f.err)
+			continue
+		}
+		for _, r := range f.rules {
+			if err := check(r, fv); err != nil {
+				ve.Add(fpath, err)
+				break
+			}
+		}
+		descend(ve, fpath, fv)
+	}
+}
+
+func descend(ve *Errors, path string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			descend(ve, path, v.Elem())
+		}
+	case reflect.Struct:
+		if v.Type() != reflect.TypeFor[time.Time]() && !selfValidating(v) {
+			walk(ve, path, v)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			descend(ve, path+"["+strconv.Itoa(i)+"]", v.Index(i))
+		}
+	}
+}
+
+// selfValidating reports whether v has its own Validate method, in which case
+// that method is responsible for its fields. This keeps a Config that calls
+// Struct from reporting a nested Config's errors twice under config.ValidateAll.
+func selfValidating(v reflect.Value) bool {
+	type validatable interface{ Validate() error }
+	if v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.CanInterface() {
+		return false
+	}
+	_, ok := v.Interface().(validatable)
+	return ok
+}
+
+func check(r rule, v reflect.Value) error {
+	if r.name == "required" {
+		if isEmpty(v) {
+			return errors.New("is required")
+		}
+		return nil
+	}
+
+	if isEmpty(v) {
+		return nil
+	}
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch r.name {
+	case "min", "max":
+		return checkBound(r, v)
+	case "oneof":
+		options := strings.Fields(r.arg)
+		if !slices.Contains(options, fmt.Sprint(v.Interface())) {
+			return errors.Errorf("must be one of [%s]", strings.Join(options, ", "))
+		}
+	}
+	return nil
+}
+
+func checkBound(r rule, v reflect.Value) error {
+	cmp, desc, err := compare(v, r.arg)
+	if err != nil {
+		return err
+	}
+	switch {
+	case r.name == "min" && cmp < 0:
+		return errors.Errorf("must be at least %s%s", r.arg, desc)
+	case r.name == "max" && cmp > 0:
+		return errors.Errorf("must be at most %s%s", r.arg, desc)
+	}
+	return nil
+}
+
+// compare returns the sign of v - arg, and a suffix describing what was
+// compared when it was a length.
+func compare(v reflect.Value, arg string) (int, string, error) {
+	switch {
+	case v.Type() == durationType:
+		bound, err := time.ParseDuration(arg)
+		if err != nil {
+			return 0, "", errors.Errorf("invalid duration bound %q", arg)
+		}
+		return sign(float64(v.Int() - int64(bound))), "", nil
+	case v.CanInt(), v.CanUint(), v.CanFloat():
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return 0, "", errors.Errorf("invalid numeric bound %q", arg)
+		}
+		var n float64
+		switch {
+		case v.CanInt():
+			n = float64(v.Int())
+		case v.CanUint():
+			n = float64(v.Uint())
+		default:
+			n = v.Float()
+		}
+		return sign(n - bound), "", nil
+	case v.Kind() == reflect.String, v.Kind() == reflect.Slice, v.Kind() == reflect.Map, v.Kind() == reflect.Array:
+		bound, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, "", errors.Errorf("invalid length bound %q", arg)
+		}
+		n := v.Len()
+		if v.Kind() == reflect.String {
+			n = len([]rune(v.String()))
+		}
+		return sign(float64(n - bound)), " in length", nil
+	}
+	return 0, "", errors.Errorf("cannot apply bounds to %s", v.Type())
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,152 @@
+package validate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+)
+
+type tlsConfig struct {
+	CertFile string `koanf:"cert_file" validate:"required"`
+}
+
+func (c *tlsConfig) Validate() error {
+	return Struct(c)
+}
+
+type endpoint struct {
+	Host string `json:"host" validate:"required"`
+	Port int    `json:"port" validate:"min=1,max=65535"`
+}
+
+type serviceConfig struct {
+	Name     string        `koanf:"name" validate:"required,max=8"`
+	Workers  int           `koanf:"workers" validate:"min=1,max=64"`
+	Ratio    float64       `koanf:"ratio" validate:"max=1"`
+	Mode     string        `koanf:"mode" validate:"oneof=batch stream"`
+	Level    int           `koanf:"level" validate:"oneof=1 2 3"`
+	Timeout  time.Duration `koanf:"timeout" validate:"min=1s,max=5m"`
+	Tags     []string      `koanf:"tags" validate:"max=2"`
+	Primary  *endpoint     `koanf:"primary" validate:"required"`
+	Replicas []endpoint    `koanf:"replicas"`
+	TLS      *tlsConfig    `koanf:"tls"`
+	Since    time.Time     `koanf:"since"`
+}
+
+type ValidateTestSuite struct {
+	suite.Suite
+}
+
+func (s *ValidateTestSuite) valid() *serviceConfig {
+	return &serviceConfig{
+		Name:     "agg",
+		Workers:  4,
+		Mode:     "stream",
+		Timeout:  30 * time.Second,
+		Primary:  &endpoint{Host: "db", Port: 5432},
+		Replicas: []endpoint{{Host: "replica", Port: 5433}},
+	}
+}
+
+func (s *ValidateTestSuite) TestValid() {
+	s.NoError(Struct(s.valid()))
+	s.NoError(Struct(*s.valid()))
+}
+
+func (s *ValidateTestSuite) TestRules() {
+	testCases := []struct {
+		name   string
+		mutate func(*serviceConfig)
+		path   string
+		msg    string
+	}{
+		{"required string", func(c *serviceConfig) { c.Name = "" }, "name", "is required"},
+		{"string length", func(c *serviceConfig) { c.Name = "aggregator" }, "name", "must be at most 8 in length"},
+		{"int min", func(c *serviceConfig) { c.Workers = -1 }, "workers", "must be at least 1"},
+		{"int max", func(c *serviceConfig) { c.Workers = 100 }, "workers", "must be at most 64"},
+		{"float max", func(c *serviceConfig) { c.Ratio = 1.5 }, "ratio", "must be at most 1"},
+		{"oneof string", func(c *serviceConfig) { c.Mode = "realtime" }, "mode", "must be one of [batch, stream]"},
+		{"oneof int", func(c *serviceConfig) { c.Level = 7 }, "level", "must be one of [1, 2, 3]"},
+		{"duration min", func(c *serviceConfig) { c.Timeout = time.Millisecond }, "timeout", "must be at least 1s"},
+		{"duration max", func(c *serviceConfig) { c.Timeout = time.Hour }, "timeout", "must be at most 5m"},
+		{"slice length", func(c *serviceConfig) { c.Tags = []string{"a", "b", "c"} }, "tags", "must be at most 2 in length"},
+		{"required pointer", func(c *serviceConfig) { c.Primary = nil }, "primary", "is required"},
+		{"nested pointer", func(c *serviceConfig) { c.Primary.Host = "" }, "primary.host", "is required"},
+		{"nested slice", func(c *serviceConfig) { c.Replicas[0].Port = 70000 }, "replicas[0].port", "must be at most 65535"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			cfg := s.valid()
+			tc.mutate(cfg)
+
+			err := Struct(cfg)
+			var ve *Errors
+			s.Require().True(errors.As(err, &ve), "expected *Errors, got %v", err)
+			s.Require().Len(ve.Errors, 1, err.Error())
+			s.Equal(tc.path, ve.Errors[0].Path)
+			s.Equal(tc.msg, ve.Errors[0].Err.Error())
+		})
+	}
+}
+
+func (s *ValidateTestSuite) TestCollectsEveryError() {
+	err := Struct(&serviceConfig{Workers: 100, Primary: &endpoint{}})
+	var ve *Errors
+	s.Require().True(errors.As(err, &ve))
+
+	paths := make([]string, len(ve.Errors))
+	for i, fe := range ve.Errors {
+		paths[i] = fe.Path
+	}
+	s.Equal([]string{"name", "workers", "primary.host"}, paths)
+	s.Contains(err.Error(), "validation failed: name: is required")
+}
+
+func (s *ValidateTestSuite) TestSelfValidatingStructsAreSkipped() {
+	cfg := s.valid()
+	cfg.TLS = &tlsConfig{}
+	s.NoError(Struct(cfg))
+	s.Error(cfg.TLS.Validate())
+}
+
+func (s *ValidateTestSuite) TestInvalidTags() {
+	type badRule struct {
+		Name string `validate:"sometimes"`
+	}
+	type missingArg struct {
+		Name string `validate:"min"`
+	}
+	type badBound struct {
+		Timeout time.Duration `validate:"max=soon"`
+	}
+	type wrongType struct {
+		Enabled bool `validate:"min=1"`
+	}
+
+	s.ErrorContains(Struct(badRule{}), "unknown rule sometimes")
+	s.ErrorContains(Struct(missingArg{}), "needs an argument")
+	s.ErrorContains(Struct(badBound{Timeout: time.Second}), "invalid duration bound")
+	s.ErrorContains(Struct(wrongType{Enabled: true}), "cannot apply bounds")
+	s.Error(Struct("not a struct"))
+	s.Error(Struct((*serviceConfig)(nil)))
+}
+
+func (s *ValidateTestSuite) TestErrorsAdd() {
+	nested := &Errors{Errors: []*FieldError{{Path: "port", Err: errors.New("bad")}}}
+
+	ve := &Errors{}
+	ve.Add("server", nested)
+	ve.Add("name", errors.New("required"))
+	ve.Add("ignored", nil)
+	s.Require().Len(ve.Errors, 2)
+	s.Equal("server.port", ve.Errors[0].Path)
+	s.Error(ve.Err())
+	s.NoError((&Errors{}).Err())
+}
+
+func TestValidateSuite(t *testing.T) {
+	suite.Run(t, new(ValidateTestSuite))
+}
@@ -6,12 +6,16 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
 	pb "github.com/grid-stream-org/grid-stream-protos/gen/validator/v1"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// DefaultPort is the conventional gRPC port the validator listens on.
+const DefaultPort = 50051
+
 type ValidatorClient interface {
 	SendAverages(ctx context.Context, averages []*pb.AverageOutput) error
 	Close() error
@@ -41,6 +45,20 @@ func (ve *ValidationErrors) Error() string {
 	return "validation failed: " + strings.Join(messages, "; ")
 }
 
+func (ve *ValidationErrors) ErrorCode() gserrors.Code {
+	return gserrors.InvalidInput
+}
+
+// ProfileDefaults implements config.Profiler. The port defaults to
+// DefaultPort, and in dev the host to localhost.
+func (c *Config) ProfileDefaults(env string) map[string]any {
+	defaults := map[string]any{"port": DefaultPort}
+	if env == "dev" {
+		defaults["host"] = "localhost"
+	}
+	return defaults
+}
+
 func (c *Config) Validate() error {
 	if c.Port <= 0 {
 		return errors.New("port must be greater than 0")
@@ -0,0 +1,138 @@
+package waitfor
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/mqttclient"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// BigQuery passes once datasetID can be queried through client, which proves
+// both the credentials and the dataset.
+func BigQuery(client bqclient.BQClient, datasetID string) health.Check {
+	return func(ctx context.Context) error {
+		it, err := client.Query(ctx, "SELECT table_name FROM `"+datasetID+"`.INFORMATION_SCHEMA.TABLES LIMIT 1", nil)
+		if err != nil {
+			return errors.Wrapf(err, "querying dataset %s", datasetID)
+		}
+		var row []bigquery.Value
+		if err := it.Next(&row); err != nil && err != iterator.Done {
+			return errors.Wrapf(err, "querying dataset %s", datasetID)
+		}
+		return nil
+	}
+}
+
+// GRPCHealth passes once the gRPC health service at target reports service
+// as serving. An empty service asks about the server as a whole.
+func GRPCHealth(target, service string, opts ...grpc.DialOption) health.Check {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	return func(ctx context.Context) error {
+		conn, err := grpc.NewClient(target, opts...)
+		if err != nil {
+			return errors.Wrapf(err, "dialing %s", target)
+		}
+		defer conn.Close()
+
+		res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return errors.Wrapf(err, "checking health of %s", target)
+		}
+		if res.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return errors.Errorf("%s is %s", target, res.GetStatus())
+		}
+		return nil
+	}
+}
+
+// Validator passes once the validator's gRPC health check is serving.
+func Validator(cfg *validator.Config) health.Check {
+	return GRPCHealth(net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), "")
+}
+
+// MQTT passes once the broker accepts a TCP connection, completing the TLS
+// handshake when cfg.TLS is set. It does not authenticate.
+func MQTT(cfg *mqttclient.Config) health.Check {
+	return func(ctx context.Context) error {
+		u, err := url.Parse(cfg.Broker)
+		if err != nil {
+			return errors.Wrapf(err, "parsing mqtt broker %s", cfg.Broker)
+		}
+		tlsCfg, err := cfg.TLS.Build()
+		if err != nil {
+			return err
+		}
+		addr := u.Host
+		if u.Port() == "" {
+			port := "1883"
+			if tlsCfg != nil || u.Scheme == "ssl" || u.Scheme == "tls" || u.Scheme == "mqtts" {
+				port = "8883"
+			}
+			addr = net.JoinHostPort(u.Hostname(), port)
+		}
+
+		if tlsCfg != nil {
+			tlsCfg = tlsCfg.Clone()
+			if tlsCfg.ServerName == "" {
+				tlsCfg.ServerName = u.Hostname()
+			}
+			d := &tls.Dialer{Config: tlsCfg}
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return errors.Wrapf(err, "connecting to mqtt broker %s", addr)
+			}
+			return conn.Close()
+		}
+		return TCP(addr)(ctx)
+	}
+}
+
+// TCP passes once addr accepts a TCP connection.
+func TCP(addr string) health.Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return errors.Wrapf(err, "connecting to %s", addr)
+		}
+		return conn.Close()
+	}
+}
+
+// HTTP passes once a GET of url returns a 2xx status, such as another
+// service's readiness probe.
+func HTTP(client *http.Client, url string) health.Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return errors.Wrapf(err, "requesting %s", url)
+		}
+		defer res.Body.Close()
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return errors.Errorf("%s returned %s", url, res.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+// Package waitfor blocks service startup until the dependencies it declares
+// are reachable, so a service that starts before BigQuery, the validator or
+// the MQTT broker waits with clear log lines instead of crash-looping on
+// its first call.
+//
+//	w, err := waitfor.New(cfg.WaitFor, log)
+//	...
+//	w.Add("bigquery", waitfor.BigQuery(bq, cfg.Database.DatasetID))
+//	w.Add("validator", waitfor.Validator(cfg.Validator))
+//	w.Add("mqtt", waitfor.MQTT(cfg.MQTT))
+//	if err := w.Wait(ctx); err != nil {
+//		return err
+//	}
+//
+// Dependencies are checked concurrently. Each is retried with backoff until
+// it passes or its timeout expires; Wait returns an *Error naming every
+// dependency that never became ready, with its last error.
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/health"
+	"github.com/grid-stream-org/go-commons/pkg/retry"
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultTimeout         = 2 * time.Minute
+	DefaultAttemptTimeout  = 10 * time.Second
+	DefaultInitialInterval = 500 * time.Millisecond
+	DefaultMaxInterval     = 15 * time.Second
+)
+
+type Config struct {
+	// Timeout bounds the wait for each dependency. It defaults to
+	// DefaultTimeout.
+	Timeout time.Duration `koanf:"timeout" json:"timeout" envconfig:"timeout"`
+	// Timeouts overrides Timeout for the dependencies it names.
+	Timeouts map[string]time.Duration `koanf:"timeouts" json:"timeouts" envconfig:"timeouts"`
+	// AttemptTimeout bounds each check. It defaults to
+	// DefaultAttemptTimeout.
+	AttemptTimeout time.Duration `koanf:"attempt_timeout" json:"attempt_timeout" envconfig:"attempt_timeout"`
+	// InitialInterval and MaxInterval shape the backoff between checks.
+	InitialInterval time.Duration `koanf:"initial_interval" json:"initial_interval" envconfig:"initial_interval"`
+	MaxInterval     time.Duration `koanf:"max_interval" json:"max_interval" envconfig:"max_interval"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("waitfor configuration required")
+	}
+	if c.Timeout < 0 || c.AttemptTimeout < 0 || c.InitialInterval < 0 || c.MaxInterval < 0 {
+		return errors.New("waitfor durations must not be negative")
+	}
+	for name, d := range c.Timeouts {
+		if d <= 0 {
+			return errors.Errorf("waitfor timeout for %s must be positive", name)
+		}
+	}
+	return nil
+}
+
+// Failure is a dependency that did not become ready.
+type Failure struct {
+	Name     string
+	Attempts int
+	Elapsed  time.Duration
+	Err      error
+}
+
+// Error is returned by Wait when dependencies are not ready. It is
+// Unavailable.
+type Error struct {
+	Failures []Failure
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("%s: not ready after %d attempts in %s: %v",
+			f.Name, f.Attempts, f.Elapsed.Round(time.Millisecond), f.Err)
+	}
+	return "dependencies not ready: " + strings.Join(parts, "; ")
+}
+
+func (e *Error) ErrorCode() gserrors.Code {
+	return gserrors.Unavailable
+}
+
+type dependency struct {
+	name  string
+	check health.Check
+}
+
+type Waiter struct {
+	cfg  *Config
+	log  *slog.Logger
+	deps []dependency
+}
+
+func New(cfg *Config, log *slog.Logger) (*Waiter, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Waiter{cfg: cfg, log: log}, nil
+}
+
+// Add declares a dependency. Checks are the same as health readiness checks,
+// so a service can register one function for both.
+func (w *Waiter) Add(name string, check health.Check) {
+	w.deps = append(w.deps, dependency{name: name, check: check})
+}
+
+// Wait checks every dependency until all are ready. It returns an *Error if
+// any is still failing at its timeout, or ctx's error if ctx is done first.
+func (w *Waiter) Wait(ctx context.Context) error {
+	start := time.Now()
+	failures := make([]*Failure, len(w.deps))
+
+	var wg sync.WaitGroup
+	for i, dep := range w.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failures[i] = w.wait(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "waiting for dependencies")
+	}
+	e := &Error{}
+	for _, f := range failures {
+		if f != nil {
+			e.Failures = append(e.Failures, *f)
+		}
+	}
+	if len(e.Failures) > 0 {
+		return e
+	}
+	if len(w.deps) > 0 {
+		w.log.Info("all dependencies ready", "count", len(w.deps), "elapsed", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func (w *Waiter) wait(ctx context.Context, dep dependency) *Failure {
+	timeout := durationOr(w.cfg.Timeouts[dep.name], durationOr(w.cfg.Timeout, DefaultTimeout))
+	attemptTimeout := durationOr(w.cfg.AttemptTimeout, DefaultAttemptTimeout)
+	backoff := retry.WithBackoff(
+		durationOr(w.cfg.InitialInterval, DefaultInitialInterval),
+		durationOr(w.cfg.MaxInterval, DefaultMaxInterval),
+	)
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, min(attemptTimeout, time.Until(deadline)))
+		err := dep.check(attemptCtx)
+		cancel()
+
+		elapsed := time.Since(start)
+		if err == nil {
+			w.log.Info("dependency ready", "dependency", dep.name, "attempts", attempt, "elapsed", elapsed.Round(time.Millisecond))
+			return nil
+		}
+
+		delay := retry.Backoff(attempt, backoff)
+		if ctx.Err() != nil || time.Now().Add(delay).After(deadline) {
+			w.log.Error("dependency not ready", "dependency", dep.name, "attempts", attempt, "elapsed", elapsed.Round(time.Millisecond), "error", err)
+			return &Failure{Name: dep.name, Attempts: attempt, Elapsed: elapsed, Err: err}
+		}
+		w.log.Warn("waiting for dependency", "dependency", dep.name, "attempt", attempt, "retry_in", delay.Round(time.Millisecond), "error", err)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return &Failure{Name: dep.name, Attempts: attempt, Elapsed: time.Since(start), Err: err}
+		case <-t.C:
+		}
+	}
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,192 @@
+package waitfor
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/grid-stream-org/go-commons/pkg/bqclient"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/mqttclient"
+	"github.com/grid-stream-org/go-commons/pkg/validator"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type failingBQ struct {
+	bqclient.BQClient
+}
+
+func (failingBQ) Query(context.Context, string, []bigquery.QueryParameter) (*bigquery.RowIterator, error) {
+	return nil, errors.New("dataset not found")
+}
+
+type WaitForTestSuite struct {
+	suite.Suite
+	logs *bytes.Buffer
+}
+
+func (s *WaitForTestSuite) SetupTest() {
+	s.logs = &bytes.Buffer{}
+}
+
+func (s *WaitForTestSuite) waiter(cfg *Config) *Waiter {
+	if cfg.InitialInterval == 0 {
+		cfg.InitialInterval = time.Millisecond
+	}
+	if cfg.MaxInterval == 0 {
+		cfg.MaxInterval = 5 * time.Millisecond
+	}
+	w, err := New(cfg, slog.New(slog.NewJSONHandler(s.logs, nil)))
+	s.Require().NoError(err)
+	return w
+}
+
+// flaky fails the first n checks.
+func flaky(n int32) (func(context.Context) error, *atomic.Int32) {
+	var calls atomic.Int32
+	return func(context.Context) error {
+		if calls.Add(1) <= n {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, &calls
+}
+
+func (s *WaitForTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "empty", cfg: &Config{}},
+		{name: "valid", cfg: &Config{Timeout: time.Minute, Timeouts: map[string]time.Duration{"bigquery": 5 * time.Minute}}},
+		{name: "nil", cfg: nil, expectError: true},
+		{name: "negative timeout", cfg: &Config{Timeout: -time.Second}, expectError: true},
+		{name: "zero override", cfg: &Config{Timeouts: map[string]time.Duration{"mqtt": 0}}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func (s *WaitForTestSuite) TestWaitRetriesUntilReady() {
+	w := s.waiter(&Config{Timeout: time.Second})
+	check, calls := flaky(3)
+	w.Add("validator", check)
+	other, _ := flaky(0)
+	w.Add("mqtt", other)
+
+	s.Require().NoError(w.Wait(context.Background()))
+	s.Equal(int32(4), calls.Load())
+	s.Contains(s.logs.String(), `"msg":"waiting for dependency","dependency":"validator","attempt":3`)
+	s.Contains(s.logs.String(), "all dependencies ready")
+}
+
+func (s *WaitForTestSuite) TestPerDependencyTimeout() {
+	w := s.waiter(&Config{
+		Timeout:  time.Second,
+		Timeouts: map[string]time.Duration{"bigquery": 20 * time.Millisecond},
+	})
+	w.Add("bigquery", BigQuery(failingBQ{}, "grid"))
+	ready, _ := flaky(2)
+	w.Add("validator", ready)
+
+	start := time.Now()
+	err := w.Wait(context.Background())
+	s.Less(time.Since(start), 500*time.Millisecond)
+
+	var we *Error
+	s.Require().ErrorAs(err, &we)
+	s.Require().Len(we.Failures, 1)
+	f := we.Failures[0]
+	s.Equal("bigquery", f.Name)
+	s.Greater(f.Attempts, 1)
+	s.ErrorContains(f.Err, "dataset not found")
+	s.Contains(err.Error(), "dependencies not ready: bigquery: not ready after")
+	s.Equal(gserrors.Unavailable, gserrors.CodeOf(err))
+}
+
+func (s *WaitForTestSuite) TestAttemptTimeout() {
+	w := s.waiter(&Config{Timeout: 50 * time.Millisecond, AttemptTimeout: 5 * time.Millisecond})
+	w.Add("hangs", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	var we *Error
+	s.Require().ErrorAs(w.Wait(context.Background()), &we)
+	s.ErrorIs(we.Failures[0].Err, context.DeadlineExceeded)
+}
+
+func (s *WaitForTestSuite) TestContextCanceled() {
+	w := s.waiter(&Config{Timeout: time.Minute})
+	w.Add("down", func(context.Context) error { return errors.New("down") })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(w.Wait(ctx), context.DeadlineExceeded)
+}
+
+func (s *WaitForTestSuite) TestNetworkChecks() {
+	ctx := context.Background()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	addr := l.Addr().String()
+	s.NoError(TCP(addr)(ctx))
+	s.NoError(MQTT(&mqttclient.Config{Broker: "tcp://" + addr})(ctx))
+	s.Require().NoError(l.Close())
+	s.Error(TCP(addr)(ctx))
+	s.Error(MQTT(&mqttclient.Config{Broker: "tcp://" + addr})(ctx))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	s.NoError(HTTP(nil, srv.URL+"/readyz")(ctx))
+	s.ErrorContains(HTTP(srv.Client(), srv.URL+"/other")(ctx), "503")
+}
+
+func (s *WaitForTestSuite) TestGRPCHealth() {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	srv := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	check := Validator(&validator.Config{Host: "127.0.0.1", Port: port})
+	ctx := context.Background()
+	s.NoError(check(ctx))
+
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	s.ErrorContains(check(ctx), "NOT_SERVING")
+
+	s.Error(GRPCHealth("127.0.0.1:"+strconv.Itoa(port), "validator.v2")(ctx), "unknown service")
+}
+
+func TestWaitForTestSuite(t *testing.T) {
+	suite.Run(t, new(WaitForTestSuite))
+}
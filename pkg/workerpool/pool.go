@@ -0,0 +1,247 @@
+// Package workerpool runs tasks on a fixed number of goroutines fed by a
+// bounded queue.
+package workerpool
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	ErrClosed    = errors.New("worker pool closed")
+	ErrQueueFull = errors.New("worker pool queue full")
+)
+
+// Task is a unit of work. The context passed to it is cancelled when Close
+// gives up waiting for the pool to drain.
+type Task func(ctx context.Context) error
+
+type Config struct {
+	Workers   int `koanf:"workers" json:"workers" envconfig:"workers"`
+	QueueSize int `koanf:"queue_size" json:"queue_size" envconfig:"queue_size"`
+}
+
+type Option func(*Pool)
+
+type Pool struct {
+	name    string
+	log     *slog.Logger
+	tasks   chan Task
+	onError func(error)
+	metrics *poolMetrics
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// closing is closed when Close starts, waking Submit calls blocked on
+	// a full queue so Close can take mu.
+	closing   chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type poolMetrics struct {
+	queued    prometheus.Gauge
+	completed *prometheus.CounterVec
+	duration  prometheus.Observer
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("worker pool configuration required")
+	}
+	if c.Workers <= 0 {
+		return errors.New("worker pool workers must be greater than 0")
+	}
+	if c.QueueSize < 0 {
+		return errors.New("worker pool queue size must not be negative")
+	}
+	return nil
+}
+
+// WithErrorHandler is called with every error returned by a task, including
+// recovered panics. Errors are logged when no handler is set.
+func WithErrorHandler(fn func(error)) Option {
+	return func(p *Pool) {
+		p.onError = fn
+	}
+}
+
+// WithMetrics records queue depth, completed tasks by result, and task
+// duration, labelled with the pool name.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(p *Pool) {
+		queued, err := m.NewGaugeVec("workerpool", "queued_tasks", "Tasks waiting in the worker pool queue.", "pool")
+		if err != nil {
+			p.log.Warn("registering worker pool metrics", "pool", p.name, "error", err)
+			return
+		}
+		completed, err := m.NewCounterVec("workerpool", "tasks_total", "Tasks completed by the worker pool.", "pool", "result")
+		if err != nil {
+			p.log.Warn("registering worker pool metrics", "pool", p.name, "error", err)
+			return
+		}
+		duration, err := m.NewHistogramVec("workerpool", "task_duration_seconds", "Worker pool task duration.", prometheus.DefBuckets, "pool")
+		if err != nil {
+			p.log.Warn("registering worker pool metrics", "pool", p.name, "error", err)
+			return
+		}
+		p.metrics = &poolMetrics{
+			queued:    queued.WithLabelValues(p.name),
+			completed: completed.MustCurryWith(prometheus.Labels{"pool": p.name}),
+			duration:  duration.WithLabelValues(p.name),
+		}
+	}
+}
+
+// New starts cfg.Workers goroutines that run submitted tasks in order. A nil
+// log discards the pool's logs.
+func New(name string, cfg *Config, log *slog.Logger, opts ...Option) (*Pool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		name:    name,
+		log:     log,
+		tasks:   make(chan Task, cfg.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		closing: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.wg.Add(cfg.Workers)
+	for range cfg.Workers {
+		go p.worker()
+	}
+	return p, nil
+}
+
+// Submit queues task, blocking while the queue is full until ctx is done or
+// the pool is closed.
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.tasks <- task:
+		p.queued(1)
+		return nil
+	case <-p.closing:
+		return ErrClosed
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// TrySubmit queues task without blocking, returning ErrQueueFull if there is
+// no room.
+func (p *Pool) TrySubmit(task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	select {
+	case p.tasks <- task:
+		p.queued(1)
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting tasks and waits for queued and running tasks to
+// finish. If ctx is done first, running tasks are cancelled and ctx's error
+// is returned.
+func (p *Pool) Close(ctx context.Context) error {
+	p.closeOnce.Do(func() { close(p.closing) })
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return errors.Wrapf(ctx.Err(), "draining worker pool %s", p.name)
+	}
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		p.queued(-1)
+		p.run(task)
+	}
+}
+
+func (p *Pool) run(task Task) {
+	start := time.Now()
+	err := p.safeRun(task)
+
+	if p.metrics != nil {
+		result := "ok"
+		if err != nil {
+			result = "error"
+		}
+		p.metrics.completed.WithLabelValues(result).Inc()
+		p.metrics.duration.Observe(time.Since(start).Seconds())
+	}
+
+	if err != nil {
+		if p.onError != nil {
+			p.onError(err)
+		} else {
+			p.log.Error("worker pool task failed", "pool", p.name, "error", err)
+		}
+	}
+}
+
+func (p *Pool) safeRun(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("worker pool task panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return task(p.ctx)
+}
+
+func (p *Pool) queued(delta float64) {
+	if p.metrics != nil {
+		p.metrics.queued.Add(delta)
+	}
+}
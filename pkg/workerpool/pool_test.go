@@ -0,0 +1,180 @@
+package workerpool
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type WorkerPoolTestSuite struct {
+	suite.Suite
+	log *slog.Logger
+}
+
+func (s *WorkerPoolTestSuite) SetupTest() {
+	s.log = slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func (s *WorkerPoolTestSuite) TestBoundedConcurrency() {
+	p, err := New("test", &Config{Workers: 3, QueueSize: 10}, s.log)
+	s.Require().NoError(err)
+
+	var running, peak, done atomic.Int32
+	for range 10 {
+		s.Require().NoError(p.Submit(context.Background(), func(context.Context) error {
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			done.Add(1)
+			return nil
+		}))
+	}
+
+	s.NoError(p.Close(context.Background()))
+	s.Equal(int32(10), done.Load())
+	s.LessOrEqual(peak.Load(), int32(3))
+	s.ErrorIs(p.Submit(context.Background(), func(context.Context) error { return nil }), ErrClosed)
+}
+
+func (s *WorkerPoolTestSuite) TestTrySubmitQueueFull() {
+	p, err := New("test", &Config{Workers: 1, QueueSize: 1}, s.log)
+	s.Require().NoError(err)
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	s.NoError(p.TrySubmit(func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}))
+	<-started
+	s.NoError(p.TrySubmit(func(context.Context) error { return nil }))
+	s.ErrorIs(p.TrySubmit(func(context.Context) error { return nil }), ErrQueueFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	s.Error(p.Submit(ctx, func(context.Context) error { return nil }))
+
+	close(release)
+	s.NoError(p.Close(context.Background()))
+}
+
+func (s *WorkerPoolTestSuite) TestErrorsAndPanics() {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "svc", Env: "dev"})
+	s.Require().NoError(err)
+
+	p, err := New("ingest", &Config{Workers: 2}, s.log, WithMetrics(m), WithErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	s.NoError(p.Submit(ctx, func(context.Context) error { return errors.New("failed") }))
+	s.NoError(p.Submit(ctx, func(context.Context) error { panic("boom") }))
+	s.NoError(p.Submit(ctx, func(context.Context) error { return nil }))
+	s.NoError(p.Close(ctx))
+
+	s.Len(errs, 2)
+	s.Equal(2.0, testutil.ToFloat64(p.metrics.completed.WithLabelValues("error")))
+	s.Equal(1.0, testutil.ToFloat64(p.metrics.completed.WithLabelValues("ok")))
+	s.Equal(0.0, testutil.ToFloat64(p.metrics.queued))
+}
+
+func (s *WorkerPoolTestSuite) TestNilLogger() {
+	p, err := New("test", &Config{Workers: 1}, nil)
+	s.Require().NoError(err)
+
+	s.NoError(p.Submit(context.Background(), func(context.Context) error { return errors.New("failed") }))
+	s.NoError(p.Close(context.Background()))
+}
+
+func (s *WorkerPoolTestSuite) TestCloseTimeoutCancelsTasks() {
+	p, err := New("test", &Config{Workers: 1}, s.log)
+	s.Require().NoError(err)
+
+	cancelled := make(chan struct{})
+	s.NoError(p.Submit(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	s.ErrorIs(p.Close(ctx), context.DeadlineExceeded)
+	<-cancelled
+}
+
+func (s *WorkerPoolTestSuite) TestCloseWakesBlockedSubmit() {
+	p, err := New("test", &Config{Workers: 1}, s.log)
+	s.Require().NoError(err)
+
+	release := make(chan struct{})
+	s.NoError(p.Submit(context.Background(), func(context.Context) error {
+		<-release
+		return nil
+	}))
+
+	// The worker is busy and the queue has no room, so this blocks.
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(context.Background(), func(context.Context) error { return nil })
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- p.Close(context.Background()) }()
+	s.ErrorIs(<-submitted, ErrClosed)
+	close(release)
+	s.NoError(<-closed)
+}
+
+func (s *WorkerPoolTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "valid", cfg: &Config{Workers: 4, QueueSize: 100}},
+		{name: "unbuffered queue", cfg: &Config{Workers: 1}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "no workers", cfg: &Config{}, expectError: true},
+		{name: "negative queue", cfg: &Config{Workers: 1, QueueSize: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestWorkerPoolSuite(t *testing.T) {
+	suite.Run(t, new(WorkerPoolTestSuite))
+}
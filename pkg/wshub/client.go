@@ -0,0 +1,128 @@
+package wshub
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type client struct {
+	// ctx is the context of the upgraded request, which lives as long as
+	// the connection.
+	ctx  context.Context
+	hub  *Hub
+	conn *websocket.Conn
+	send chan []byte
+
+	mu     sync.RWMutex
+	topics map[string]struct{}
+}
+
+// command is a subscription change sent by a client.
+type command struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+}
+
+func newClient(ctx context.Context, h *Hub, conn *websocket.Conn, topics []string) *client {
+	c := &client{
+		ctx:    ctx,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, h.cfg.SendQueueSize),
+		topics: map[string]struct{}{},
+	}
+	for _, t := range topics {
+		c.topics[t] = struct{}{}
+	}
+	return c
+}
+
+func (c *client) subscribed(topic string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.topics[topic]
+	return ok
+}
+
+// readLoop handles subscription commands and pong frames until the
+// connection fails or the client goes quiet for two ping intervals.
+func (c *client) readLoop() {
+	defer c.hub.unregister(c)
+
+	deadline := 2 * c.hub.cfg.PingInterval
+	c.conn.SetReadLimit(c.hub.cfg.MaxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(deadline))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				c.hub.log.Debug("websocket read failed", "remote_addr", c.conn.RemoteAddr().String(), "error", err)
+			}
+			return
+		}
+
+		var cmd command
+		if err := json.Unmarshal(b, &cmd); err != nil || cmd.Topic == "" {
+			continue
+		}
+		if cmd.Action == "subscribe" {
+			if err := c.hub.authorized(c.ctx, cmd.Topic); err != nil {
+				c.hub.log.Debug("websocket subscription denied", "remote_addr", c.conn.RemoteAddr().String(), "topic", cmd.Topic, "error", err)
+				continue
+			}
+		}
+		c.mu.Lock()
+		switch cmd.Action {
+		case "subscribe":
+			c.topics[cmd.Topic] = struct{}{}
+		case "unsubscribe":
+			delete(c.topics, cmd.Topic)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// writeLoop is the only goroutine writing to the connection. It exits, closing
+// the connection, when the send queue is closed or a write fails.
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(c.hub.cfg.PingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		select {
+		case b, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				c.hub.unregister(c)
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.hub.cfg.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.hub.unregister(c)
+				return
+			}
+		}
+	}
+}
+
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == host
+}
@@ -0,0 +1,351 @@
+// Package wshub serves live updates to websocket clients. Each client has a
+// bounded send queue drained by its own writer goroutine, so one slow browser
+// cannot stall the others; a client whose queue fills up is disconnected and
+// expected to reconnect.
+//
+// Clients choose topics with the "topic" query parameter, which may be
+// repeated, and can change them later by sending
+//
+//	{"action": "subscribe", "topic": "der:proj_123"}
+//	{"action": "unsubscribe", "topic": "der:proj_123"}
+//
+// A client with no topics receives only broadcasts. With WithAuthorize, a
+// connection asking for a topic it may not see is refused, and such
+// subscribe commands are ignored.
+package wshub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	DefaultSendQueueSize  = 64
+	DefaultPingInterval   = 30 * time.Second
+	DefaultWriteTimeout   = 10 * time.Second
+	DefaultMaxMessageSize = 4096
+)
+
+var ErrClosed = errors.New("websocket hub closed")
+
+type Config struct {
+	SendQueueSize int           `koanf:"send_queue_size" json:"send_queue_size" envconfig:"send_queue_size"`
+	PingInterval  time.Duration `koanf:"ping_interval" json:"ping_interval" envconfig:"ping_interval"`
+	WriteTimeout  time.Duration `koanf:"write_timeout" json:"write_timeout" envconfig:"write_timeout"`
+	// MaxMessageSize limits messages read from clients, which only carry
+	// subscription changes.
+	MaxMessageSize int64 `koanf:"max_message_size" json:"max_message_size" envconfig:"max_message_size"`
+	// AllowedOrigins lists browser origins allowed to connect. Empty means
+	// same-origin only; "*" allows any origin.
+	AllowedOrigins []string `koanf:"allowed_origins" json:"allowed_origins" envconfig:"allowed_origins"`
+	// Compression negotiates permessage-deflate with clients that support it,
+	// trading CPU for bandwidth on constrained links.
+	Compression bool `koanf:"compression" json:"compression" envconfig:"compression"`
+}
+
+func (c *Config) Validate() error {
+	if c == nil {
+		return errors.New("websocket hub configuration required")
+	}
+	if c.SendQueueSize < 0 || c.PingInterval < 0 || c.WriteTimeout < 0 || c.MaxMessageSize < 0 {
+		return errors.New("websocket hub settings must not be negative")
+	}
+	return nil
+}
+
+// Message is the JSON envelope written to clients.
+type Message struct {
+	Topic string `json:"topic,omitempty"`
+	Data  any    `json:"data"`
+}
+
+// TopicFunc maps an eventbus event to the topic it is published on. Returning
+// false drops the event; an empty topic broadcasts it.
+type TopicFunc func(event any) (topic string, ok bool)
+
+// AuthorizeFunc reports whether the caller may receive topic. Claims are
+// nil when the request carries none. A non-nil error denies the topic; it
+// is written to the client with gserrors.WriteHTTP when it refuses a
+// connection, so it should carry a code such as gserrors.PermissionDenied.
+type AuthorizeFunc func(ctx context.Context, claims *auth.Claims, topic string) error
+
+type Option func(*Hub)
+
+// WithAuthorize checks every topic a client subscribes to with fn. Claims
+// are read from the request context with auth.FromContext, so Handler must
+// sit behind middleware that verifies the caller and stores them there.
+func WithAuthorize(fn AuthorizeFunc) Option {
+	return func(h *Hub) {
+		h.authorize = fn
+	}
+}
+
+// WithEventBus makes Run forward events published on bus to clients, routed
+// by topic.
+func WithEventBus(bus eventbus.EventBus, topic TopicFunc) Option {
+	return func(h *Hub) {
+		h.bus = bus
+		h.topic = topic
+	}
+}
+
+// WithMetrics records connected clients and messages sent or dropped.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(h *Hub) {
+		clients, err := m.NewGaugeVec("wshub", "clients", "Connected websocket clients.")
+		if err != nil {
+			h.log.Warn("registering websocket hub metrics", "error", err)
+			return
+		}
+		messages, err := m.NewCounterVec("wshub", "messages_total", "Messages queued to websocket clients by result.", "result")
+		if err != nil {
+			h.log.Warn("registering websocket hub metrics", "error", err)
+			return
+		}
+		h.metrics = &hubMetrics{clients: clients.WithLabelValues(), messages: messages}
+	}
+}
+
+type hubMetrics struct {
+	clients  prometheus.Gauge
+	messages *prometheus.CounterVec
+}
+
+type Hub struct {
+	cfg       Config
+	log       *slog.Logger
+	upgrader  websocket.Upgrader
+	bus       eventbus.EventBus
+	topic     TopicFunc
+	authorize AuthorizeFunc
+	metrics   *hubMetrics
+
+	mu      sync.RWMutex
+	clients map[*client]struct{}
+	closed  bool
+}
+
+func New(cfg *Config, log *slog.Logger, opts ...Option) (*Hub, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	h := &Hub{
+		cfg:     *cfg,
+		log:     log,
+		clients: map[*client]struct{}{},
+	}
+	if h.cfg.SendQueueSize == 0 {
+		h.cfg.SendQueueSize = DefaultSendQueueSize
+	}
+	if h.cfg.PingInterval == 0 {
+		h.cfg.PingInterval = DefaultPingInterval
+	}
+	if h.cfg.WriteTimeout == 0 {
+		h.cfg.WriteTimeout = DefaultWriteTimeout
+	}
+	if h.cfg.MaxMessageSize == 0 {
+		h.cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	h.upgrader = websocket.Upgrader{CheckOrigin: h.checkOrigin, EnableCompression: h.cfg.Compression}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// Handler upgrades requests to websocket connections and registers them with
+// the hub until the connection closes.
+func (h *Hub) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		topics := r.URL.Query()["topic"]
+		for _, topic := range topics {
+			if err := h.authorized(r.Context(), topic); err != nil {
+				gserrors.WriteHTTP(w, err)
+				return
+			}
+		}
+
+		conn, err := h.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrade has already written an error response.
+			h.log.Debug("websocket upgrade failed", "error", err)
+			return
+		}
+
+		c := newClient(r.Context(), h, conn, topics)
+		if !h.register(c) {
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(h.cfg.WriteTimeout))
+			_ = conn.Close()
+			return
+		}
+		go c.writeLoop()
+		c.readLoop()
+	})
+}
+
+// Broadcast sends data to every client.
+func (h *Hub) Broadcast(data any) error {
+	return h.send(Message{Data: data})
+}
+
+// Publish sends data to clients subscribed to topic.
+func (h *Hub) Publish(topic string, data any) error {
+	return h.send(Message{Topic: topic, Data: data})
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// Run forwards events from the eventbus given to WithEventBus, if any, until
+// ctx is cancelled, then disconnects every client. Handler refuses new
+// connections afterwards.
+func (h *Hub) Run(ctx context.Context) error {
+	var events chan any
+	if h.bus != nil {
+		events = h.bus.Subscribe(h.cfg.SendQueueSize)
+		defer h.bus.Unsubscribe(events)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.close()
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			topic := ""
+			if h.topic != nil {
+				var keep bool
+				if topic, keep = h.topic(ev); !keep {
+					continue
+				}
+			}
+			if err := h.send(Message{Topic: topic, Data: ev}); err != nil {
+				h.log.Error("encoding websocket event", "topic", topic, "error", err)
+			}
+		}
+	}
+}
+
+// authorized checks topic with the function given to WithAuthorize, if any.
+func (h *Hub) authorized(ctx context.Context, topic string) error {
+	if h.authorize == nil {
+		return nil
+	}
+	claims, _ := auth.FromContext(ctx)
+	return h.authorize(ctx, claims, topic)
+}
+
+func (h *Hub) send(msg Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "encoding websocket message")
+	}
+
+	h.mu.RLock()
+	if h.closed {
+		h.mu.RUnlock()
+		return ErrClosed
+	}
+	var slow []*client
+	for c := range h.clients {
+		if msg.Topic != "" && !c.subscribed(msg.Topic) {
+			continue
+		}
+		select {
+		case c.send <- b:
+			h.count("sent")
+		default:
+			h.count("dropped")
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		h.log.Warn("websocket client too slow, disconnecting", "remote_addr", c.conn.RemoteAddr().String())
+		h.unregister(c)
+	}
+	return nil
+}
+
+func (h *Hub) register(c *client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return false
+	}
+	h.clients[c] = struct{}{}
+	if h.metrics != nil {
+		h.metrics.clients.Inc()
+	}
+	return true
+}
+
+// unregister removes c and closes its send queue, which makes its writer
+// close the connection. It is safe to call more than once.
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	close(c.send)
+	if h.metrics != nil {
+		h.metrics.clients.Dec()
+	}
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	h.closed = true
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	for _, c := range clients {
+		h.unregister(c)
+	}
+}
+
+func (h *Hub) count(result string) {
+	if h.metrics != nil {
+		h.metrics.messages.WithLabelValues(result).Inc()
+	}
+}
+
+func (h *Hub) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if len(h.cfg.AllowedOrigins) == 0 {
+		return sameOrigin(origin, r.Host)
+	}
+	return slices.Contains(h.cfg.AllowedOrigins, "*") || slices.Contains(h.cfg.AllowedOrigins, origin)
+}
@@ -0,0 +1,256 @@
+package wshub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/grid-stream-org/go-commons/pkg/auth"
+	"github.com/grid-stream-org/go-commons/pkg/eventbus"
+	"github.com/grid-stream-org/go-commons/pkg/gserrors"
+	"github.com/grid-stream-org/go-commons/pkg/logger"
+	"github.com/grid-stream-org/go-commons/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type WSHubTestSuite struct {
+	suite.Suite
+	bus    eventbus.EventBus
+	hub    *Hub
+	srv    *httptest.Server
+	cancel context.CancelFunc
+	done   chan error
+}
+
+type derEvent struct {
+	ProjectID string  `json:"project_id"`
+	PowerKW   float64 `json:"power_kw"`
+}
+
+func (s *WSHubTestSuite) SetupTest() {
+	s.bus = eventbus.New()
+	hub, err := New(&Config{SendQueueSize: 4}, logger.Default(), WithEventBus(s.bus, func(ev any) (string, bool) {
+		e, ok := ev.(derEvent)
+		return "der:" + e.ProjectID, ok
+	}))
+	s.Require().NoError(err)
+	s.hub = hub
+	s.srv = httptest.NewServer(hub.Handler())
+
+	var ctx context.Context
+	ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan error, 1)
+	go func() { s.done <- hub.Run(ctx) }()
+}
+
+func (s *WSHubTestSuite) TearDownTest() {
+	s.cancel()
+	s.NoError(<-s.done)
+	s.srv.Close()
+}
+
+func (s *WSHubTestSuite) dial(query string) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	s.Require().NoError(err)
+	s.T().Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func (s *WSHubTestSuite) waitClients(n int) {
+	s.Eventually(func() bool { return s.hub.Clients() == n }, time.Second, 5*time.Millisecond)
+}
+
+func (s *WSHubTestSuite) read(conn *websocket.Conn) map[string]any {
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	var msg map[string]any
+	s.Require().NoError(conn.ReadJSON(&msg))
+	return msg
+}
+
+func (s *WSHubTestSuite) TestBroadcast() {
+	a := s.dial("")
+	b := s.dial("?topic=der:p1")
+	s.waitClients(2)
+
+	s.Require().NoError(s.hub.Broadcast("maintenance at 02:00"))
+	s.Equal("maintenance at 02:00", s.read(a)["data"])
+	s.Equal("maintenance at 02:00", s.read(b)["data"])
+}
+
+func (s *WSHubTestSuite) TestTopicsFromEventBus() {
+	p1 := s.dial("?topic=der:p1")
+	p2 := s.dial("?topic=der:p2")
+	s.waitClients(2)
+
+	s.bus.Publish(derEvent{ProjectID: "p2", PowerKW: 4.2})
+	s.bus.Publish("ignored")
+	s.bus.Publish(derEvent{ProjectID: "p1", PowerKW: 1.5})
+
+	msg := s.read(p1)
+	s.Equal("der:p1", msg["topic"])
+	s.Equal(map[string]any{"project_id": "p1", "power_kw": 1.5}, msg["data"])
+	s.Equal("der:p2", s.read(p2)["topic"])
+}
+
+func (s *WSHubTestSuite) TestSubscribeCommand() {
+	conn := s.dial("")
+	s.waitClients(1)
+
+	s.Require().NoError(conn.WriteJSON(command{Action: "subscribe", Topic: "der:p3"}))
+	s.Eventually(func() bool {
+		for c := range s.clients() {
+			return c.subscribed("der:p3")
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+
+	s.Require().NoError(s.hub.Publish("der:p3", 7))
+	s.Equal(float64(7), s.read(conn)["data"])
+}
+
+func (s *WSHubTestSuite) TestAuthorize() {
+	hub, err := New(&Config{}, logger.Default(), WithAuthorize(func(ctx context.Context, claims *auth.Claims, topic string) error {
+		if claims == nil || topic != "der:"+claims.Subject {
+			return gserrors.New(gserrors.PermissionDenied, "topic not allowed")
+		}
+		return nil
+	}))
+	s.Require().NoError(err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sub := r.Header.Get("X-Subject"); sub != "" {
+			r = r.WithContext(auth.NewContext(r.Context(), &auth.Claims{Subject: sub}))
+		}
+		hub.Handler().ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/"
+	header := http.Header{"X-Subject": {"p1"}}
+
+	_, resp, err := websocket.DefaultDialer.Dial(url+"?topic=der:p2", header)
+	s.Require().Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?topic=der:p1", nil)
+	s.Require().Error(err)
+	s.Equal(http.StatusForbidden, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	s.Require().NoError(err)
+	defer conn.Close()
+	s.Require().NoError(conn.WriteJSON(command{Action: "subscribe", Topic: "der:p2"}))
+	s.Require().NoError(conn.WriteJSON(command{Action: "subscribe", Topic: "der:p1"}))
+
+	// Commands are handled in order, so once der:p1 is subscribed the
+	// der:p2 command has been handled too.
+	s.Eventually(func() bool {
+		hub.mu.RLock()
+		defer hub.mu.RUnlock()
+		for c := range hub.clients {
+			return c.subscribed("der:p1")
+		}
+		return false
+	}, time.Second, 5*time.Millisecond)
+	s.Require().NoError(hub.Publish("der:p2", "other project"))
+	s.Require().NoError(hub.Publish("der:p1", "own project"))
+	s.Equal("own project", s.read(conn)["data"])
+}
+
+func (s *WSHubTestSuite) clients() map[*client]struct{} {
+	s.hub.mu.RLock()
+	defer s.hub.mu.RUnlock()
+	out := map[*client]struct{}{}
+	for c := range s.hub.clients {
+		out[c] = struct{}{}
+	}
+	return out
+}
+
+func (s *WSHubTestSuite) TestSlowClientDisconnected() {
+	m, err := metrics.New(&metrics.Config{Namespace: "test", Service: "wshub"})
+	s.Require().NoError(err)
+	WithMetrics(m)(s.hub)
+
+	_ = s.dial("")
+	s.waitClients(1)
+
+	// The client never reads, so its queue and socket buffers eventually fill.
+	payload := strings.Repeat("x", 64*1024)
+	s.Eventually(func() bool {
+		_ = s.hub.Broadcast(payload)
+		return s.hub.Clients() == 0
+	}, 5*time.Second, time.Millisecond)
+	s.Positive(testutil.ToFloat64(s.hub.metrics.messages.WithLabelValues("dropped")))
+}
+
+func (s *WSHubTestSuite) TestRunClosesClients() {
+	conn := s.dial("")
+	s.waitClients(1)
+
+	s.cancel()
+	s.NoError(<-s.done)
+	s.done <- nil
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	s.True(websocket.IsCloseError(err, websocket.CloseGoingAway))
+	s.ErrorIs(s.hub.Broadcast("late"), ErrClosed)
+}
+
+func (s *WSHubTestSuite) TestCheckOrigin() {
+	testCases := []struct {
+		name    string
+		allowed []string
+		origin  string
+		ok      bool
+	}{
+		{name: "no origin", ok: true},
+		{name: "same origin", origin: "http://example.com", ok: true},
+		{name: "cross origin", origin: "http://evil.example"},
+		{name: "allowed", allowed: []string{"http://dash.example"}, origin: "http://dash.example", ok: true},
+		{name: "wildcard", allowed: []string{"*"}, origin: "http://evil.example", ok: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			h, err := New(&Config{AllowedOrigins: tc.allowed}, logger.Default())
+			s.Require().NoError(err)
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/ws", nil)
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			s.Equal(tc.ok, h.checkOrigin(r))
+		})
+	}
+}
+
+func (s *WSHubTestSuite) TestConfigValidate() {
+	testCases := []struct {
+		name        string
+		cfg         *Config
+		expectError bool
+	}{
+		{name: "defaults", cfg: &Config{}},
+		{name: "nil config", cfg: nil, expectError: true},
+		{name: "negative queue", cfg: &Config{SendQueueSize: -1}, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := tc.cfg.Validate()
+			if tc.expectError {
+				s.Error(err)
+			} else {
+				s.NoError(err)
+			}
+		})
+	}
+}
+
+func TestWSHubSuite(t *testing.T) {
+	suite.Run(t, new(WSHubTestSuite))
+}